@@ -0,0 +1,94 @@
+package bloomfilter
+
+import (
+	"fmt"
+	"math"
+	"sync/atomic"
+)
+
+// Adaptive probing
+//
+// A Contains that probes only the first k' of the k bit positions can never
+// return a false negative, since an added key has all k bits set. It only raises
+// the false positive rate from p^k to p^k', where p is the fraction of bits set.
+// On a healthy filter that costs orders of magnitude; on a saturated one (p close
+// to 1) the two are nearly equal, because the rate is already bad, while every
+// probe is still a likely cache miss. Adaptive probing drops probes exactly when
+// the relative increase p^(k'-k) - 1 stays within a configured bound.
+//
+// k' = k - d probes are used once p >= (1+maxIncrease)^(-1/d). With a 10% bound,
+// the first probe is dropped at p ≈ 0.91 and half of k=8 probes at p ≈ 0.98.
+// Adds always set all k bits, so a filter that is cleared or rebuilt returns to
+// full probing.
+
+// adaptiveProbing holds the thresholds of an enabled adaptive mode
+type adaptiveProbing struct {
+	maxIncrease float64
+	// minBits[d-1] is the set-bit count from which k-d probes suffice, ascending
+	minBits []uint64
+	// Lookups that used fewer than k probes
+	reduced atomic.Uint64
+}
+
+// AdaptiveProbingStats reports the state of adaptive probing
+type AdaptiveProbingStats struct {
+	Enabled        bool
+	MaxFPRIncrease float64
+	// Probes is the number of bits Contains currently checks (HashCount when not reduced)
+	Probes uint32
+	// ReducedLookups counts Contains calls that checked fewer than HashCount bits
+	ReducedLookups uint64
+}
+
+// EnableAdaptiveProbing lets Contains check fewer bits on a saturated filter as
+// long as the estimated false positive rate rises by at most the fraction
+// maxFPRIncrease (0.1 allows 10%). It never introduces false negatives. It enables
+// incremental load tracking, which adds a shared counter update to every Add.
+//
+// Panics if maxFPRIncrease is not positive and finite.
+func (bf *CacheOptimizedBloomFilter) EnableAdaptiveProbing(maxFPRIncrease float64) {
+	if !(maxFPRIncrease > 0) || math.IsInf(maxFPRIncrease, 0) {
+		panic(fmt.Sprintf("bloomfilter: maxFPRIncrease must be positive and finite, got %f", maxFPRIncrease))
+	}
+
+	a := &adaptiveProbing{maxIncrease: maxFPRIncrease}
+	for d := 1; d < int(bf.hashCount); d++ {
+		p := math.Pow(1+maxFPRIncrease, -1/float64(d))
+		a.minBits = append(a.minBits, uint64(math.Ceil(p*float64(bf.bitCount))))
+	}
+	bf.enableBitTracking()
+	bf.adaptive.Store(a)
+}
+
+// DisableAdaptiveProbing returns Contains to checking all HashCount bits
+func (bf *CacheOptimizedBloomFilter) DisableAdaptiveProbing() {
+	bf.adaptive.Store(nil)
+}
+
+// AdaptiveProbing returns the state of adaptive probing
+func (bf *CacheOptimizedBloomFilter) AdaptiveProbing() AdaptiveProbingStats {
+	a := bf.adaptive.Load()
+	if a == nil {
+		return AdaptiveProbingStats{Probes: bf.hashCount}
+	}
+	return AdaptiveProbingStats{
+		Enabled:        true,
+		MaxFPRIncrease: a.maxIncrease,
+		Probes:         bf.containsProbes(),
+		ReducedLookups: a.reduced.Load(),
+	}
+}
+
+// containsProbes returns the number of bits Contains checks at the current load
+func (bf *CacheOptimizedBloomFilter) containsProbes() uint32 {
+	a := bf.adaptive.Load()
+	if a == nil {
+		return bf.hashCount
+	}
+	bits := bf.bitsSet.Load()
+	d := 0
+	for d < len(a.minBits) && bits >= a.minBits[d] {
+		d++
+	}
+	return bf.hashCount - uint32(d)
+}
@@ -0,0 +1,96 @@
+package bloomfilter
+
+import (
+	"fmt"
+	"math"
+	"testing"
+)
+
+// TestAdaptiveProbing tests that probes drop only once the filter is saturated,
+// without false negatives and within the configured FPR increase
+func TestAdaptiveProbing(t *testing.T) {
+	bf := NewCacheOptimizedBloomFilter(1000, 0.01)
+	bf.EnableAdaptiveProbing(0.1)
+	k := bf.HashCount()
+
+	// At design load nothing changes
+	for i := 0; i < 1000; i++ {
+		bf.AddUint64(uint64(i))
+	}
+	if s := bf.AdaptiveProbing(); s.Probes != k || !s.Enabled {
+		t.Fatalf("Probes reduced at design load: %+v", s)
+	}
+
+	// 40x overloaded: the load is about 0.99 and probes are dropped
+	for i := 1000; i < 40000; i++ {
+		bf.AddUint64(uint64(i))
+	}
+	probes := bf.AdaptiveProbing().Probes
+	if probes >= k || probes == 0 {
+		t.Fatalf("Expected fewer than %d probes at load %.3f, got %d", k, bf.GetCacheStats().LoadFactor, probes)
+	}
+	if bf.GetCacheStats().ContainsProbes != probes {
+		t.Error("CacheStats.ContainsProbes disagrees with AdaptiveProbing")
+	}
+
+	for i := 0; i < 40000; i++ {
+		if !bf.ContainsUint64(uint64(i)) {
+			t.Fatalf("False negative for %d with %d probes", i, probes)
+		}
+	}
+	p := bf.GetCacheStats().LoadFactor
+	if increase := math.Pow(p, float64(probes))/math.Pow(p, float64(k)) - 1; increase > 0.1 {
+		t.Errorf("Estimated FPR rose by %.1f%%, bound is 10%%", increase*100)
+	}
+	if s := bf.AdaptiveProbing(); s.ReducedLookups != 40000 {
+		t.Errorf("Expected 40000 reduced lookups, got %d", s.ReducedLookups)
+	}
+
+	// Reset clears the counter and returns to full probing; Disable always does
+	bf.Reset()
+	if s := bf.AdaptiveProbing(); s.Probes != k || s.ReducedLookups != 0 || !s.Enabled {
+		t.Errorf("After Reset: %+v", s)
+	}
+	for i := 0; i < 40000; i++ {
+		bf.AddString(fmt.Sprint(i))
+	}
+	bf.DisableAdaptiveProbing()
+	if s := bf.AdaptiveProbing(); s.Enabled || s.Probes != k {
+		t.Errorf("After Disable: %+v", s)
+	}
+}
+
+// TestAdaptiveProbingValidation tests invalid bounds
+func TestAdaptiveProbingValidation(t *testing.T) {
+	for _, v := range []float64{0, -1, math.NaN(), math.Inf(1)} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("Expected panic for maxFPRIncrease %v", v)
+				}
+			}()
+			NewCacheOptimizedBloomFilter(100, 0.01).EnableAdaptiveProbing(v)
+		}()
+	}
+}
+
+// TestAdaptiveProbingExplain tests that Explain agrees with Contains under
+// adaptive probing
+func TestAdaptiveProbingExplain(t *testing.T) {
+	bf := NewCacheOptimizedBloomFilter(100, 0.01)
+	for i := 0; i < 5000; i++ {
+		bf.AddUint64(uint64(i))
+	}
+	bf.EnableAdaptiveProbing(0.1)
+
+	for i := 0; i < 10000; i++ {
+		key := []byte(fmt.Sprintf("absent_%d", i))
+		exp := bf.Explain(key)
+		if exp.Checked != int(bf.AdaptiveProbing().Probes) || len(exp.Probes) != int(bf.HashCount()) {
+			t.Fatalf("Checked %d of %d probes", exp.Checked, len(exp.Probes))
+		}
+		if exp.Present != bf.Contains(key) {
+			t.Fatalf("Explain and Contains disagree on %s:\n%s", key, exp)
+		}
+	}
+}
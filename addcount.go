@@ -0,0 +1,78 @@
+package bloomfilter
+
+import "unsafe"
+
+// EnableAddCounting starts classifying every Add by the bits it set: an add that
+// set at least one new bit is definitely a new item, while one that found all k
+// bits already set is a probable duplicate (or a false positive). The counts are
+// reported by NewItems and ProbableDuplicates and in CacheStats, giving a cheap
+// dedup-rate metric. Counting is off by default because the shared counters cost
+// an atomic increment per Add; adds dropped by load shedding are not counted.
+//
+// Two goroutines adding the same new key at the same time may both count it as
+// new, since each may set some of its bits.
+func (bf *CacheOptimizedBloomFilter) EnableAddCounting() {
+	bf.countAdds.Store(true)
+}
+
+// DisableAddCounting stops counting; the counters keep their values
+func (bf *CacheOptimizedBloomFilter) DisableAddCounting() {
+	bf.countAdds.Store(false)
+}
+
+// NewItems returns the number of counted adds that set at least one new bit
+func (bf *CacheOptimizedBloomFilter) NewItems() uint64 {
+	return bf.newItems.Load()
+}
+
+// ProbableDuplicates returns the number of counted adds whose bits were all set already
+func (bf *CacheOptimizedBloomFilter) ProbableDuplicates() uint64 {
+	return bf.duplicates.Load()
+}
+
+// ResetAddCounts zeroes NewItems and ProbableDuplicates
+func (bf *CacheOptimizedBloomFilter) ResetAddCounts() {
+	bf.newItems.Store(0)
+	bf.duplicates.Store(0)
+}
+
+// countAdd classifies one add by the number of bits it flipped
+func (bf *CacheOptimizedBloomFilter) countAdd(flipped uint64) {
+	if !bf.countAdds.Load() {
+		return
+	}
+	if flipped == 0 {
+		bf.duplicates.Add(1)
+	} else {
+		bf.newItems.Add(1)
+	}
+}
+
+// TestAndAdd adds data and reports whether it was already present, that is
+// whether all of its bits were set before the add. It hashes once and checks
+// and sets the bits in the same pass, replacing the Contains-then-Add pattern
+// of dedup pipelines at the cost of one Add.
+//
+// Each bit is set by exactly one caller, so of several goroutines adding the
+// same new key at the same time at least one reports it absent; more than one
+// may, as each may set some of its bits. When load shedding drops the add,
+// TestAndAdd reports what Contains would.
+func (bf *CacheOptimizedBloomFilter) TestAndAdd(data []byte) bool {
+	h1, h2 := bf.hashKey(data)
+	if bf.overloaded() {
+		bf.dropped.Add(1)
+		return bf.containsHashed(data, h1, h2)
+	}
+	return bf.addHashed(data, h1, h2, true) == 0
+}
+
+// TestAndAddString is TestAndAdd for a string element
+func (bf *CacheOptimizedBloomFilter) TestAndAddString(s string) bool {
+	return bf.TestAndAdd(stringBytes(s))
+}
+
+// TestAndAddUint64 is TestAndAdd for a uint64 element
+func (bf *CacheOptimizedBloomFilter) TestAndAddUint64(n uint64) bool {
+	data := (*[8]byte)(unsafe.Pointer(&n))[:]
+	return bf.TestAndAdd(data)
+}
@@ -0,0 +1,111 @@
+package bloomfilter
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+// TestAddCounting tests new vs. duplicate classification for single and batch adds
+func TestAddCounting(t *testing.T) {
+	bf := NewCacheOptimizedBloomFilter(10000, 0.001)
+	bf.AddString("before")
+	bf.EnableAddCounting()
+
+	keys := make([]string, 1000)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("key_%d", i)
+	}
+	for _, k := range keys {
+		bf.AddString(k)
+	}
+	bf.AddString("before")
+
+	// New items can only be undercounted by keys whose bits were all set by
+	// earlier keys (a false positive at add time)
+	if n := bf.NewItems(); n < 990 || n > 1000 {
+		t.Errorf("NewItems = %d, expected ~1000", n)
+	}
+	if d := bf.ProbableDuplicates(); d < 1 || d+bf.NewItems() != 1001 {
+		t.Errorf("ProbableDuplicates = %d, NewItems = %d", d, bf.NewItems())
+	}
+
+	bf.ResetAddCounts()
+	bf.AddStrings(keys)
+	if bf.ProbableDuplicates() != 1000 || bf.NewItems() != 0 {
+		t.Errorf("Re-adding via AddStrings: dup=%d new=%d", bf.ProbableDuplicates(), bf.NewItems())
+	}
+	stats := bf.GetCacheStats()
+	if stats.ProbableDuplicates != 1000 || stats.NewItems != 0 {
+		t.Errorf("CacheStats counts: %+v", stats)
+	}
+
+	bf.DisableAddCounting()
+	bf.AddString("after")
+	if bf.NewItems() != 0 {
+		t.Error("Counting continued after DisableAddCounting")
+	}
+}
+
+// TestTestAndAdd tests that TestAndAdd reports what Contains would have
+// before adding, including under load shedding and concurrent use
+func TestTestAndAdd(t *testing.T) {
+	bf := NewCacheOptimizedBloomFilter(10000, 0.001)
+	reference := bf.Clone()
+	for i := 0; i < 5000; i++ {
+		key := fmt.Sprintf("key_%d", i%3000) // the last 2000 are repeats
+		want := reference.ContainsString(key)
+		reference.AddString(key)
+		if got := bf.TestAndAddString(key); got != want {
+			t.Fatalf("TestAndAddString(%q) = %t, Contains before Add = %t", key, got, want)
+		}
+	}
+	if !bf.Equal(reference) {
+		t.Fatal("TestAndAdd set different bits than Add")
+	}
+	if bf.TestAndAddUint64(7) || !bf.TestAndAddUint64(7) {
+		t.Error("TestAndAddUint64 should report absent, then present")
+	}
+
+	// Dropped adds answer like Contains and leave the filter unchanged
+	full := NewCacheOptimizedBloomFilter(100, 0.01)
+	full.SetMaxLoadFactor(0.01)
+	for i := 0; full.TryAdd([]byte(fmt.Sprint(i))) == nil; i++ {
+	}
+	before := full.PopCount()
+	if full.TestAndAddString("dropped") != full.ContainsString("dropped") || full.PopCount() != before {
+		t.Error("Shed TestAndAdd must not add")
+	}
+	if full.Dropped() == 0 {
+		t.Error("Shed TestAndAdd not counted as dropped")
+	}
+}
+
+// TestTestAndAddConcurrent tests that of concurrent callers adding the same
+// new key, at least one reports it absent
+func TestTestAndAddConcurrent(t *testing.T) {
+	bf := NewCacheOptimizedBloomFilter(100000, 0.001)
+	const keys, workers = 2000, 8
+
+	var absent [keys]atomic.Int32
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for k := 0; k < keys; k++ {
+				if !bf.TestAndAddUint64(uint64(k)) {
+					absent[k].Add(1)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	for k := range absent {
+		if absent[k].Load() == 0 {
+			t.Errorf("Key %d reported present by every caller", k)
+		}
+	}
+}
@@ -0,0 +1,206 @@
+package bloomfilter
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// AgingConfig configures an AgingBloomFilter
+type AgingConfig struct {
+	// ExpectedElements is the number of keys added per rotation interval
+	ExpectedElements uint64
+	// FalsePositiveRate is the target rate of Contains across all generations;
+	// each generation is sized for FalsePositiveRate/Generations
+	FalsePositiveRate float64
+	// Generations is the number of filters kept, newest first. Defaults to 2.
+	Generations int
+	// RotateEvery, if positive, rotates in a background goroutine at this
+	// interval until Close. Otherwise the caller calls Rotate.
+	RotateEvery time.Duration
+	// Clock drives background rotation. Defaults to SystemClock.
+	Clock Clock
+}
+
+// AgingStats reports the state of an AgingBloomFilter
+type AgingStats struct {
+	Generations  int
+	Rotations    uint64
+	LastRotation time.Time // Creation time before the first rotation
+	// NewestLoadFactor is the fraction of bits set in the generation receiving adds
+	NewestLoadFactor float64
+	// EstimatedFPP is the false positive rate of Contains across all generations
+	EstimatedFPP float64
+}
+
+// AgingBloomFilter is a time-decaying filter made of rotating generations:
+// Add goes to the newest generation, Contains checks them all, and Rotate
+// drops the oldest and starts a new empty one. A key is therefore forgotten
+// between Generations-1 and Generations rotations after it was last added; with
+// 2 generations rotated every 10 minutes, keys expire after 10 to 20 minutes.
+//
+// Add, Contains and Rotate are safe for concurrent use. An add that races with
+// Rotate lands in either the new or the previous newest generation.
+type AgingBloomFilter struct {
+	cfg            AgingConfig
+	cacheLineCount uint64
+	hashCount      uint32
+	seed           uint64
+
+	mu          sync.Mutex // serializes rotations
+	generations atomic.Pointer[[]*CacheOptimizedBloomFilter]
+	rotations   atomic.Uint64
+	lastRotated atomic.Int64 // UnixNano
+
+	stop      chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// NewAgingBloomFilter creates an aging filter, starting background rotation if
+// cfg.RotateEvery is positive.
+// Returns an error if the sizing is invalid, Generations is negative or 1, or
+// RotateEvery is negative.
+func NewAgingBloomFilter(cfg AgingConfig) (*AgingBloomFilter, error) {
+	if cfg.Generations == 0 {
+		cfg.Generations = 2
+	}
+	if cfg.Generations < 2 {
+		return nil, fmt.Errorf("bloomfilter: an aging filter needs at least 2 generations, got %d", cfg.Generations)
+	}
+	if cfg.RotateEvery < 0 {
+		return nil, fmt.Errorf("bloomfilter: RotateEvery must not be negative, got %v", cfg.RotateEvery)
+	}
+	if err := checkSizing(cfg.ExpectedElements, cfg.FalsePositiveRate); err != nil {
+		return nil, err
+	}
+	perGeneration := cfg.FalsePositiveRate / float64(cfg.Generations)
+	if err := checkSizing(cfg.ExpectedElements, perGeneration); err != nil {
+		return nil, fmt.Errorf("%w (per generation)", err)
+	}
+	cfg.Clock = clockOrSystem(cfg.Clock)
+
+	a := &AgingBloomFilter{cfg: cfg, seed: RandomSeed()}
+	a.cacheLineCount, a.hashCount = optimalGeometry(cfg.ExpectedElements, perGeneration)
+	gens := make([]*CacheOptimizedBloomFilter, cfg.Generations)
+	for i := range gens {
+		gens[i] = a.newGeneration()
+	}
+	a.generations.Store(&gens)
+	a.lastRotated.Store(cfg.Clock.Now().UnixNano())
+
+	if cfg.RotateEvery > 0 {
+		a.stop = make(chan struct{})
+		a.done = make(chan struct{})
+		go a.rotateLoop(cfg.Clock.NewTicker(cfg.RotateEvery))
+	}
+	return a, nil
+}
+
+func (a *AgingBloomFilter) rotateLoop(t Ticker) {
+	defer close(a.done)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C():
+			a.Rotate()
+		case <-a.stop:
+			return
+		}
+	}
+}
+
+// newGeneration creates an empty generation
+func (a *AgingBloomFilter) newGeneration() *CacheOptimizedBloomFilter {
+	bf := newFilter(a.cacheLineCount, a.hashCount)
+	bf.seed = a.seed
+	return bf
+}
+
+// Rotate drops the oldest generation and starts a new, empty newest one
+func (a *AgingBloomFilter) Rotate() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	old := *a.generations.Load()
+	gens := make([]*CacheOptimizedBloomFilter, len(old))
+	gens[0] = a.newGeneration()
+	copy(gens[1:], old)
+	a.generations.Store(&gens)
+	a.rotations.Add(1)
+	a.lastRotated.Store(a.cfg.Clock.Now().UnixNano())
+}
+
+// Add adds data to the newest generation
+func (a *AgingBloomFilter) Add(data []byte) {
+	(*a.generations.Load())[0].Add(data)
+}
+
+// Contains checks whether data was added within the retained generations
+func (a *AgingBloomFilter) Contains(data []byte) bool {
+	for _, g := range *a.generations.Load() {
+		if g.Contains(data) {
+			return true
+		}
+	}
+	return false
+}
+
+// AddString adds a string element
+func (a *AgingBloomFilter) AddString(s string) {
+	a.Add(stringBytes(s))
+}
+
+// ContainsString checks a string element
+func (a *AgingBloomFilter) ContainsString(s string) bool {
+	return a.Contains(stringBytes(s))
+}
+
+// AddUint64 adds a uint64 element
+func (a *AgingBloomFilter) AddUint64(n uint64) {
+	(*a.generations.Load())[0].AddUint64(n)
+}
+
+// ContainsUint64 checks a uint64 element
+func (a *AgingBloomFilter) ContainsUint64(n uint64) bool {
+	for _, g := range *a.generations.Load() {
+		if g.ContainsUint64(n) {
+			return true
+		}
+	}
+	return false
+}
+
+// Stats returns the filter's rotation state and combined false positive rate
+func (a *AgingBloomFilter) Stats() AgingStats {
+	gens := *a.generations.Load()
+	pass := 1.0
+	for _, g := range gens {
+		pass *= 1 - g.EstimatedFPP()
+	}
+	newest := gens[0]
+	return AgingStats{
+		Generations:      len(gens),
+		Rotations:        a.rotations.Load(),
+		LastRotation:     time.Unix(0, a.lastRotated.Load()),
+		NewestLoadFactor: float64(newest.PopCount()) / float64(newest.BitCount()),
+		EstimatedFPP:     math.Max(0, 1-pass),
+	}
+}
+
+// Close stops background rotation (implements io.Closer). The filter keeps
+// working and can still be rotated by calling Rotate. Calling Close more than
+// once, or on a filter without background rotation, is a no-op.
+func (a *AgingBloomFilter) Close() error {
+	if a.stop == nil {
+		return nil
+	}
+	a.closeOnce.Do(func() {
+		close(a.stop)
+		<-a.done
+	})
+	return nil
+}
+
+var _ Filter = (*AgingBloomFilter)(nil)
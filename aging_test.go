@@ -0,0 +1,143 @@
+package bloomfilter
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestAgingBloomFilterRotate tests that keys expire after Generations rotations
+func TestAgingBloomFilterRotate(t *testing.T) {
+	for _, gens := range []int{2, 3} {
+		t.Run(fmt.Sprintf("Generations_%d", gens), func(t *testing.T) {
+			a, err := NewAgingBloomFilter(AgingConfig{ExpectedElements: 1000, FalsePositiveRate: 0.01, Generations: gens})
+			if err != nil {
+				t.Fatal(err)
+			}
+			a.AddString("old")
+			a.AddUint64(42)
+			for i := 1; i < gens; i++ {
+				a.Rotate()
+				if !a.ContainsString("old") || !a.ContainsUint64(42) {
+					t.Fatalf("Key expired after %d of %d rotations", i, gens)
+				}
+			}
+			a.AddString("refreshed")
+			a.Rotate()
+			if a.ContainsString("old") || a.ContainsUint64(42) {
+				t.Error("Key survived all generations")
+			}
+			if !a.ContainsString("refreshed") {
+				t.Error("Key added in the newest generation expired early")
+			}
+			if s := a.Stats(); s.Rotations != uint64(gens) || s.Generations != gens {
+				t.Errorf("Stats: %+v", s)
+			}
+		})
+	}
+}
+
+// TestAgingBloomFilterBackground tests rotation driven by the clock
+func TestAgingBloomFilterBackground(t *testing.T) {
+	clock := NewManualClock(time.Unix(1000, 0))
+	a, err := NewAgingBloomFilter(AgingConfig{
+		ExpectedElements: 1000, FalsePositiveRate: 0.01, RotateEvery: 10 * time.Minute, Clock: clock,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.Close()
+
+	a.AddString("key")
+	for i := uint64(1); i <= 2; i++ {
+		clock.Advance(10 * time.Minute)
+		deadline := time.Now().Add(5 * time.Second)
+		for a.Stats().Rotations < i {
+			if time.Now().After(deadline) {
+				t.Fatalf("Rotation %d did not happen", i)
+			}
+			time.Sleep(time.Millisecond)
+		}
+		if got := a.ContainsString("key"); got != (i == 1) {
+			t.Errorf("After %d rotations Contains = %v", i, got)
+		}
+	}
+	if s := a.Stats(); !s.LastRotation.Equal(time.Unix(1000, 0).Add(20 * time.Minute)) {
+		t.Errorf("LastRotation %v", s.LastRotation)
+	}
+
+	a.Close()
+	a.Close()
+	clock.Advance(time.Hour)
+	time.Sleep(10 * time.Millisecond)
+	if r := a.Stats().Rotations; r != 2 {
+		t.Errorf("Rotated after Close: %d", r)
+	}
+}
+
+// TestAgingBloomFilterFPR tests that the combined rate stays near the target
+// with every generation full
+func TestAgingBloomFilterFPR(t *testing.T) {
+	a, _ := NewAgingBloomFilter(AgingConfig{ExpectedElements: 10000, FalsePositiveRate: 0.01, Generations: 4})
+	for g := 0; g < 4; g++ {
+		for i := 0; i < 10000; i++ {
+			a.AddString(fmt.Sprint(g, "_", i))
+		}
+		if g < 3 {
+			a.Rotate()
+		}
+	}
+	var fp int
+	for i := 0; i < 100_000; i++ {
+		if a.ContainsString(fmt.Sprint("absent_", i)) {
+			fp++
+		}
+	}
+	rate := float64(fp) / 100_000
+	if rate > 0.015 {
+		t.Errorf("Combined FPR %g above target 0.01", rate)
+	}
+	if est := a.Stats().EstimatedFPP; est < 0.005 || est > 0.015 {
+		t.Errorf("EstimatedFPP %g, measured %g", est, rate)
+	}
+}
+
+// TestAgingBloomFilterConfig tests configuration validation
+func TestAgingBloomFilterConfig(t *testing.T) {
+	for name, cfg := range map[string]AgingConfig{
+		"OneGeneration": {ExpectedElements: 100, FalsePositiveRate: 0.01, Generations: 1},
+		"Negative":      {ExpectedElements: 100, FalsePositiveRate: 0.01, Generations: -2},
+		"Interval":      {ExpectedElements: 100, FalsePositiveRate: 0.01, RotateEvery: -time.Second},
+		"Sizing":        {FalsePositiveRate: 0.01},
+		"Rate":          {ExpectedElements: 100, FalsePositiveRate: 1},
+	} {
+		if _, err := NewAgingBloomFilter(cfg); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+// TestAgingBloomFilterConcurrent tests adds and lookups racing with rotations
+func TestAgingBloomFilterConcurrent(t *testing.T) {
+	a, _ := NewAgingBloomFilter(AgingConfig{ExpectedElements: 10000, FalsePositiveRate: 0.01, Generations: 3})
+	var wg sync.WaitGroup
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 2000; i++ {
+				a.AddUint64(uint64(g<<20 | i))
+				a.ContainsUint64(uint64(i))
+			}
+		}(g)
+	}
+	for i := 0; i < 50; i++ {
+		a.Rotate()
+	}
+	wg.Wait()
+	a.AddString("after")
+	if !a.ContainsString("after") {
+		t.Error("Lost an add after concurrent rotations")
+	}
+}
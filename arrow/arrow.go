@@ -0,0 +1,203 @@
+// Package arrow converts bloom filters to and from Apache Arrow record batches,
+// so analytics jobs can treat filters as columns (for example to compute set
+// overlap with a vectorized AND + popcount in a dataframe pipeline).
+//
+// It is a separate module so that the core package does not pull Arrow into
+// the module graph of users who do not need it.
+//
+// A filter is encoded as a record batch with a single non-nullable uint64 column
+// named "word" holding the bit array in the layout of
+// CacheOptimizedBloomFilter.Words (bit i is bit i%64 of row i/64). The filter
+// parameters are stored in the schema metadata.
+package arrow
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/ipc"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+
+	bloomfilter "github.com/shaia/BloomFilter"
+)
+
+const (
+	// WordColumn is the name of the column holding the bit array
+	WordColumn = "word"
+
+	// Schema metadata keys
+	MetaFormatVersion = "bloomfilter.format_version"
+	MetaBitCount      = "bloomfilter.bit_count"
+	MetaHashCount     = "bloomfilter.hash_count"
+	MetaSeed          = "bloomfilter.seed"
+	MetaProbeScheme   = "bloomfilter.probe_scheme"
+	MetaHashVersion   = "bloomfilter.hash_version"
+
+	// FormatVersion is the version of the record layout written by ToRecord for
+	// filters using HashV2, which records the hash version. HashV1 filters are
+	// written as version 3 if they use EnhancedDoubleHashing, which records the
+	// probe scheme, as version 2 if seeded and as version 1, which has no seed,
+	// otherwise, so readers that predate seeds, probe schemes or hash versions
+	// still accept them and reject the records they would misread.
+	FormatVersion = 4
+)
+
+// ToRecord encodes a filter as an Arrow record batch. The caller must Release
+// the returned record. A nil allocator uses memory.DefaultAllocator.
+func ToRecord(bf *bloomfilter.CacheOptimizedBloomFilter, mem memory.Allocator) arrow.Record {
+	if mem == nil {
+		mem = memory.DefaultAllocator
+	}
+
+	words := bf.Words()
+	builder := array.NewUint64Builder(mem)
+	defer builder.Release()
+	builder.AppendValues(words, nil)
+	column := builder.NewArray()
+	defer column.Release()
+
+	keys := []string{MetaFormatVersion, MetaBitCount, MetaHashCount}
+	values := []string{
+		"1",
+		strconv.FormatUint(bf.BitCount(), 10),
+		strconv.FormatUint(uint64(bf.HashCount()), 10),
+	}
+	v2 := bf.HashVersion() != bloomfilter.HashV1
+	if bf.Seed() != 0 || bf.ProbeScheme() != bloomfilter.DoubleHashing || v2 {
+		values[0] = "2"
+		keys = append(keys, MetaSeed)
+		values = append(values, strconv.FormatUint(bf.Seed(), 10))
+	}
+	if bf.ProbeScheme() != bloomfilter.DoubleHashing || v2 {
+		values[0] = "3"
+		keys = append(keys, MetaProbeScheme)
+		values = append(values, bf.ProbeScheme().String())
+	}
+	if v2 {
+		values[0] = strconv.Itoa(FormatVersion)
+		keys = append(keys, MetaHashVersion)
+		values = append(values, bf.HashVersion().String())
+	}
+	metadata := arrow.NewMetadata(keys, values)
+	schema := arrow.NewSchema([]arrow.Field{
+		{Name: WordColumn, Type: arrow.PrimitiveTypes.Uint64, Nullable: false},
+	}, &metadata)
+
+	return array.NewRecord(schema, []arrow.Array{column}, int64(len(words)))
+}
+
+// FromRecord decodes a filter from a record batch produced by ToRecord.
+// The bit array is copied; the record can be released afterwards.
+func FromRecord(rec arrow.Record) (*bloomfilter.CacheOptimizedBloomFilter, error) {
+	metadata := rec.Schema().Metadata()
+
+	version, err := metaUint(metadata, MetaFormatVersion)
+	if err != nil {
+		return nil, err
+	}
+	if version < 1 || version > FormatVersion {
+		return nil, fmt.Errorf("arrow: unsupported format version %d", version)
+	}
+	bitCount, err := metaUint(metadata, MetaBitCount)
+	if err != nil {
+		return nil, err
+	}
+	hashCount, err := metaUint(metadata, MetaHashCount)
+	if err != nil {
+		return nil, err
+	}
+	var seed uint64
+	if version >= 2 {
+		if seed, err = metaUint(metadata, MetaSeed); err != nil {
+			return nil, err
+		}
+	}
+	probe := bloomfilter.DoubleHashing
+	if version >= 3 {
+		name, ok := metadata.GetValue(MetaProbeScheme)
+		if !ok {
+			return nil, fmt.Errorf("arrow: missing schema metadata %q", MetaProbeScheme)
+		}
+		if probe, err = bloomfilter.ParseProbeScheme(name); err != nil {
+			return nil, err
+		}
+	}
+	hashVersion := bloomfilter.HashV1
+	if version >= 4 {
+		name, ok := metadata.GetValue(MetaHashVersion)
+		if !ok {
+			return nil, fmt.Errorf("arrow: missing schema metadata %q", MetaHashVersion)
+		}
+		if hashVersion, err = bloomfilter.ParseHashVersion(name); err != nil {
+			return nil, err
+		}
+	}
+
+	indices := rec.Schema().FieldIndices(WordColumn)
+	if len(indices) != 1 {
+		return nil, fmt.Errorf("arrow: record must have exactly one %q column", WordColumn)
+	}
+	column, ok := rec.Column(indices[0]).(*array.Uint64)
+	if !ok {
+		return nil, fmt.Errorf("arrow: column %q must be uint64, got %s", WordColumn, rec.Column(indices[0]).DataType())
+	}
+	if column.NullN() != 0 {
+		return nil, fmt.Errorf("arrow: column %q must not contain nulls", WordColumn)
+	}
+	if uint64(column.Len())*64 != bitCount {
+		return nil, fmt.Errorf("arrow: %d words do not match bit count %d", column.Len(), bitCount)
+	}
+
+	bf, err := bloomfilter.NewFromWordsWithSeed(column.Uint64Values(), uint32(hashCount), seed)
+	if err != nil {
+		return nil, err
+	}
+	bf.SetProbeScheme(probe)
+	bf.SetHashVersion(hashVersion)
+	return bf, nil
+}
+
+// Write encodes a filter as an Arrow IPC stream (readable by pyarrow, Polars, etc.)
+func Write(w io.Writer, bf *bloomfilter.CacheOptimizedBloomFilter) error {
+	rec := ToRecord(bf, nil)
+	defer rec.Release()
+
+	writer := ipc.NewWriter(w, ipc.WithSchema(rec.Schema()))
+	if err := writer.Write(rec); err != nil {
+		writer.Close()
+		return err
+	}
+	return writer.Close()
+}
+
+// Read decodes a filter from the first record batch of an Arrow IPC stream
+func Read(r io.Reader) (*bloomfilter.CacheOptimizedBloomFilter, error) {
+	reader, err := ipc.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Release()
+
+	if !reader.Next() {
+		if err := reader.Err(); err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("arrow: stream contains no record batch")
+	}
+	return FromRecord(reader.Record())
+}
+
+func metaUint(metadata arrow.Metadata, key string) (uint64, error) {
+	idx := metadata.FindKey(key)
+	if idx < 0 {
+		return 0, fmt.Errorf("arrow: missing schema metadata %q", key)
+	}
+	v, err := strconv.ParseUint(metadata.Values()[idx], 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("arrow: invalid schema metadata %q: %w", key, err)
+	}
+	return v, nil
+}
@@ -0,0 +1,107 @@
+package arrow
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/apache/arrow-go/v18/arrow/memory"
+
+	bloomfilter "github.com/shaia/BloomFilter"
+)
+
+// TestRecordRoundTrip tests encoding a filter as a record batch and back
+func TestRecordRoundTrip(t *testing.T) {
+	mem := memory.NewCheckedAllocator(memory.NewGoAllocator())
+	defer mem.AssertSize(t, 0)
+
+	bf := bloomfilter.NewCacheOptimizedBloomFilter(1000, 0.01)
+	for i := 0; i < 500; i++ {
+		bf.AddString(fmt.Sprintf("key_%d", i))
+	}
+
+	rec := ToRecord(bf, mem)
+	if rec.NumRows() != int64(bf.BitCount()/64) {
+		t.Errorf("Expected %d rows, got %d", bf.BitCount()/64, rec.NumRows())
+	}
+
+	restored, err := FromRecord(rec)
+	rec.Release()
+	if err != nil {
+		t.Fatalf("FromRecord failed: %v", err)
+	}
+	if restored.HashCount() != bf.HashCount() || restored.PopCount() != bf.PopCount() {
+		t.Fatalf("Restored filter differs: k=%d/%d popcount=%d/%d",
+			restored.HashCount(), bf.HashCount(), restored.PopCount(), bf.PopCount())
+	}
+	for i := 0; i < 500; i++ {
+		if !restored.ContainsString(fmt.Sprintf("key_%d", i)) {
+			t.Fatalf("Restored filter is missing key_%d", i)
+		}
+	}
+}
+
+// TestIPCRoundTrip tests the IPC stream helpers
+func TestIPCRoundTrip(t *testing.T) {
+	bf := bloomfilter.NewCacheOptimizedBloomFilter(10_000, 0.01)
+	bf.AddString("hello")
+
+	var buf bytes.Buffer
+	if err := Write(&buf, bf); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	restored, err := Read(&buf)
+	if err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+	if !restored.ContainsString("hello") || restored.BitCount() != bf.BitCount() {
+		t.Error("Restored filter differs from the original")
+	}
+
+	if _, err := Read(bytes.NewReader([]byte("not arrow"))); err == nil {
+		t.Error("Expected error for invalid stream")
+	}
+}
+
+// TestSeededRoundTrip tests that seeded filters keep their seed and probe
+// scheme and that DoubleHashing filters are still written in the version 1 or
+// 2 layout
+func TestSeededRoundTrip(t *testing.T) {
+	cases := []struct {
+		seed        uint64
+		probe       bloomfilter.ProbeScheme
+		hash        bloomfilter.HashVersion
+		wantVersion string
+	}{
+		{0, bloomfilter.DoubleHashing, bloomfilter.HashV1, "1"},
+		{1 << 60, bloomfilter.DoubleHashing, bloomfilter.HashV1, "2"},
+		{0, bloomfilter.EnhancedDoubleHashing, bloomfilter.HashV1, "3"},
+		{1 << 60, bloomfilter.EnhancedDoubleHashing, bloomfilter.HashV1, "3"},
+		{0, bloomfilter.DoubleHashing, bloomfilter.HashV2, "4"},
+		{1 << 60, bloomfilter.EnhancedDoubleHashing, bloomfilter.HashV2, "4"},
+	}
+	for _, c := range cases {
+		bf, err := bloomfilter.NewWithOptions(bloomfilter.WithExpectedElements(1000), bloomfilter.WithSeed(c.seed),
+			bloomfilter.WithProbeScheme(c.probe), bloomfilter.WithHashVersion(c.hash))
+		if err != nil {
+			t.Fatal(err)
+		}
+		bf.AddString("hello")
+
+		rec := ToRecord(bf, nil)
+		version := rec.Schema().Metadata().Values()[rec.Schema().Metadata().FindKey(MetaFormatVersion)]
+		restored, err := FromRecord(rec)
+		rec.Release()
+		if err != nil {
+			t.Fatalf("Seed %#x, %s: %v", c.seed, c.probe, err)
+		}
+		if version != c.wantVersion {
+			t.Errorf("Seed %#x, %s: written as version %s, want %s", c.seed, c.probe, version, c.wantVersion)
+		}
+		if restored.Seed() != c.seed || restored.ProbeScheme() != c.probe || restored.HashVersion() != c.hash ||
+			!restored.ContainsString("hello") || !restored.Equal(bf) {
+			t.Errorf("Seed %#x, %s %s: restored seed %#x, %s and %s", c.seed, c.probe, c.hash, restored.Seed(),
+				restored.ProbeScheme(), restored.HashVersion())
+		}
+	}
+}
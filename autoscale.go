@@ -0,0 +1,287 @@
+package bloomfilter
+
+import (
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// AutoScalingConfig configures an AutoScalingFilter
+type AutoScalingConfig struct {
+	// ExpectedElements is the initial capacity
+	ExpectedElements uint64
+	// FalsePositiveRate is the target rate, kept across rebuilds
+	FalsePositiveRate float64
+	// MaxLoadFactor is the fraction of set bits that triggers a rebuild. Defaults
+	// to 0.5, the load of an optimally sized filter at its design capacity.
+	MaxLoadFactor float64
+	// GrowthFactor multiplies the capacity on every rebuild. Defaults to 2.
+	GrowthFactor float64
+	// Keys records every added key and replays them into the larger filter.
+	// Required; use NewMemoryKeyLog for an in-memory log.
+	Keys KeyRecorder
+	// OnRebuild, if set, is called from the rebuild goroutine after each swap
+	OnRebuild func(RebuildEvent)
+	// Clock times rebuilds. Defaults to SystemClock.
+	Clock Clock
+	// SkewCheckInterval is the number of adds between background skew checks
+	// (see CacheOptimizedBloomFilter.Skew). 0 disables skew detection.
+	SkewCheckInterval uint64
+	// SkewThreshold is the dispersion at which the filter is rebuilt at the same
+	// capacity with a new random hash seed. Defaults to 4.
+	SkewThreshold float64
+}
+
+// RebuildReason is the cause of a rebuild
+type RebuildReason int
+
+const (
+	// RebuildGrow is a rebuild at a larger capacity after the load limit was reached
+	RebuildGrow RebuildReason = iota
+	// RebuildReseed is a rebuild at the same capacity with a new hash seed after
+	// skew was detected
+	RebuildReseed
+)
+
+func (r RebuildReason) String() string {
+	if r == RebuildReseed {
+		return "reseed"
+	}
+	return "grow"
+}
+
+// RebuildEvent describes a completed background rebuild
+type RebuildEvent struct {
+	Reason      RebuildReason
+	OldCapacity uint64
+	NewCapacity uint64
+	Seed        uint64     // Hash seed of the new filter
+	Skew        SkewReport // Skew that triggered a reseed, zero for growth
+	Duration    time.Duration
+}
+
+// AutoScalingStats reports the state of an AutoScalingFilter
+type AutoScalingStats struct {
+	Capacity     uint64
+	LoadFactor   float64
+	Rebuilds     uint64 // Completed rebuilds of either kind
+	Reseeds      uint64 // Rebuilds caused by skew
+	Rebuilding   bool
+	RecordErrors uint64
+}
+
+// AutoScalingFilter is a filter that never needs to be sized up front. Every Add
+// is recorded in a KeyRecorder; once the active filter's load factor passes the
+// configured maximum, a background goroutine builds a filter with GrowthFactor
+// times the capacity, replays the recorded keys into it and atomically swaps it
+// in. Adds and lookups continue against the old filter during the rebuild, and
+// adds are mirrored into the new one, so no key is ever missed.
+//
+// With skew detection enabled, the filter is also rebuilt at the same capacity
+// with a new random seed when its keys crowd into a few cache lines.
+type AutoScalingFilter struct {
+	cfg AutoScalingConfig
+
+	current  atomic.Pointer[CacheOptimizedBloomFilter]
+	next     atomic.Pointer[CacheOptimizedBloomFilter]
+	capacity atomic.Uint64
+
+	rebuilding   atomic.Bool
+	rebuilds     atomic.Uint64
+	reseeds      atomic.Uint64
+	recordErrors atomic.Uint64
+	adds         atomic.Uint64
+	wg           sync.WaitGroup
+
+	errMu   sync.Mutex
+	lastErr error
+}
+
+// NewAutoScalingFilter creates an auto-scaling filter.
+// Returns an error if the configuration is invalid.
+func NewAutoScalingFilter(cfg AutoScalingConfig) (*AutoScalingFilter, error) {
+	if cfg.Keys == nil {
+		return nil, fmt.Errorf("bloomfilter: auto-scaling filter requires a KeyRecorder")
+	}
+	if err := checkSizing(cfg.ExpectedElements, cfg.FalsePositiveRate); err != nil {
+		return nil, err
+	}
+	if cfg.MaxLoadFactor == 0 {
+		cfg.MaxLoadFactor = 0.5
+	}
+	if !(cfg.MaxLoadFactor > 0 && cfg.MaxLoadFactor < 1) {
+		return nil, fmt.Errorf("bloomfilter: MaxLoadFactor must be in range (0, 1), got %f", cfg.MaxLoadFactor)
+	}
+	cfg.Clock = clockOrSystem(cfg.Clock)
+	if cfg.GrowthFactor == 0 {
+		cfg.GrowthFactor = 2
+	}
+	if !(cfg.GrowthFactor > 1) || math.IsInf(cfg.GrowthFactor, 0) {
+		return nil, fmt.Errorf("bloomfilter: GrowthFactor must be greater than 1, got %f", cfg.GrowthFactor)
+	}
+	if cfg.SkewThreshold == 0 {
+		cfg.SkewThreshold = 4
+	}
+	if !(cfg.SkewThreshold > 1) {
+		return nil, fmt.Errorf("bloomfilter: SkewThreshold must be greater than 1, got %f", cfg.SkewThreshold)
+	}
+
+	a := &AutoScalingFilter{cfg: cfg}
+	a.current.Store(a.newGeneration(cfg.ExpectedElements, RandomSeed()))
+	a.capacity.Store(cfg.ExpectedElements)
+	return a, nil
+}
+
+// newGeneration creates a filter of the given capacity with O(1) load tracking
+func (a *AutoScalingFilter) newGeneration(capacity, seed uint64) *CacheOptimizedBloomFilter {
+	bf := NewCacheOptimizedBloomFilter(capacity, a.cfg.FalsePositiveRate)
+	bf.seed = seed
+	bf.enableBitTracking()
+	return bf
+}
+
+// Add records the key and adds it to the active filter, starting a background
+// rebuild if the filter is saturated. A failure to record the key is counted and
+// reported by Err; the key is still added to the active filter, but would be
+// missing after the next rebuild.
+func (a *AutoScalingFilter) Add(data []byte) {
+	if err := a.cfg.Keys.Record(data); err != nil {
+		a.recordErrors.Add(1)
+		a.errMu.Lock()
+		a.lastErr = err
+		a.errMu.Unlock()
+	}
+
+	// Load next before current. A nil next means either no rebuild has started
+	// yet (so the key recorded above is in the rebuild's replay snapshot) or the
+	// rebuild has already swapped (so current is the new filter).
+	next := a.next.Load()
+	cur := a.current.Load()
+	cur.Add(data)
+	if next != nil && next != cur {
+		next.Add(data)
+	}
+
+	if cur.trackedLoadFactor() >= a.cfg.MaxLoadFactor {
+		if a.rebuilding.CompareAndSwap(false, true) {
+			a.wg.Add(1)
+			go func() {
+				defer a.wg.Done()
+				a.rebuildLoop(nil)
+			}()
+		}
+	} else if interval := a.cfg.SkewCheckInterval; interval > 0 && a.adds.Add(1)%interval == 0 {
+		if a.rebuilding.CompareAndSwap(false, true) {
+			a.wg.Add(1)
+			go func() {
+				defer a.wg.Done()
+				a.checkSkew()
+			}()
+		}
+	}
+}
+
+// checkSkew measures the active filter's skew and reseeds it if needed. The
+// rebuilding flag is held on entry.
+func (a *AutoScalingFilter) checkSkew() {
+	report := a.current.Load().Skew()
+	if report.Dispersion < a.cfg.SkewThreshold {
+		a.rebuilding.Store(false)
+		return
+	}
+	a.rebuildLoop(&report)
+}
+
+// Contains checks membership in the active filter
+func (a *AutoScalingFilter) Contains(data []byte) bool {
+	return a.current.Load().Contains(data)
+}
+
+// rebuildLoop rebuilds until the active filter is below the maximum load. Adds
+// that arrive during a rebuild can saturate the new generation too, and they
+// cannot trigger another rebuild while this one holds the rebuilding flag.
+// The first rebuild reseeds if skew is non-nil; any further ones grow.
+func (a *AutoScalingFilter) rebuildLoop(skew *SkewReport) {
+	for {
+		a.rebuild(skew)
+		skew = nil
+		a.rebuilding.Store(false)
+		if a.current.Load().trackedLoadFactor() < a.cfg.MaxLoadFactor ||
+			!a.rebuilding.CompareAndSwap(false, true) {
+			return
+		}
+	}
+}
+
+// rebuild builds the next generation from the recorded keys and swaps it in.
+// It grows the capacity, or keeps it and picks a new seed if skew is non-nil.
+func (a *AutoScalingFilter) rebuild(skew *SkewReport) {
+	start := a.cfg.Clock.Now()
+
+	event := RebuildEvent{Reason: RebuildGrow, OldCapacity: a.capacity.Load()}
+	event.NewCapacity = event.OldCapacity
+	event.Seed = a.current.Load().seed
+	if skew != nil {
+		event.Reason = RebuildReseed
+		event.Skew = *skew
+		for seed := event.Seed; event.Seed == seed; {
+			event.Seed = rand.Uint64()
+		}
+	} else {
+		// Growth keeps the seed, so a reseeded filter stays rebalanced
+		event.NewCapacity = uint64(float64(event.OldCapacity) * a.cfg.GrowthFactor)
+		if event.NewCapacity <= event.OldCapacity {
+			event.NewCapacity = event.OldCapacity + 1
+		}
+	}
+
+	next := a.newGeneration(event.NewCapacity, event.Seed)
+	a.next.Store(next) // mirror concurrent adds from here on
+	next.addSeq(a.cfg.Keys.Keys())
+	a.current.Store(next)
+	a.next.Store(nil)
+	a.capacity.Store(event.NewCapacity)
+	a.rebuilds.Add(1)
+	if skew != nil {
+		a.reseeds.Add(1)
+	}
+
+	if a.cfg.OnRebuild != nil {
+		event.Duration = a.cfg.Clock.Now().Sub(start)
+		a.cfg.OnRebuild(event)
+	}
+}
+
+// Wait blocks until any in-flight background rebuild has completed
+func (a *AutoScalingFilter) Wait() {
+	a.wg.Wait()
+}
+
+// Filter returns the active filter. It is replaced, not modified, by rebuilds.
+func (a *AutoScalingFilter) Filter() *CacheOptimizedBloomFilter {
+	return a.current.Load()
+}
+
+// Err returns the most recent error from recording a key, or nil
+func (a *AutoScalingFilter) Err() error {
+	a.errMu.Lock()
+	defer a.errMu.Unlock()
+	return a.lastErr
+}
+
+// Stats returns the current capacity, load and rebuild counters
+func (a *AutoScalingFilter) Stats() AutoScalingStats {
+	return AutoScalingStats{
+		Capacity:     a.capacity.Load(),
+		LoadFactor:   a.current.Load().trackedLoadFactor(),
+		Rebuilds:     a.rebuilds.Load(),
+		Reseeds:      a.reseeds.Load(),
+		Rebuilding:   a.rebuilding.Load(),
+		RecordErrors: a.recordErrors.Load(),
+	}
+}
+
+var _ Filter = (*AutoScalingFilter)(nil)
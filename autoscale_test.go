@@ -0,0 +1,122 @@
+package bloomfilter
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+)
+
+// TestAutoScalingFilter tests that the filter grows and keeps every key across rebuilds
+func TestAutoScalingFilter(t *testing.T) {
+	var events []RebuildEvent
+	var mu sync.Mutex
+	a, err := NewAutoScalingFilter(AutoScalingConfig{
+		ExpectedElements:  1000,
+		FalsePositiveRate: 0.01,
+		Keys:              NewMemoryKeyLog(),
+		OnRebuild: func(e RebuildEvent) {
+			mu.Lock()
+			events = append(events, e)
+			mu.Unlock()
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const n = 20_000
+	for i := 0; i < n; i++ {
+		a.Add([]byte(fmt.Sprintf("key_%d", i)))
+	}
+	a.Wait()
+
+	stats := a.Stats()
+	if stats.Rebuilds == 0 || stats.Capacity <= 1000 {
+		t.Fatalf("Expected the filter to grow, got %+v", stats)
+	}
+	if len(events) != int(stats.Rebuilds) || events[0].NewCapacity != 2*events[0].OldCapacity {
+		t.Errorf("Unexpected rebuild events: %+v", events)
+	}
+	for i := 0; i < n; i++ {
+		if !a.Contains([]byte(fmt.Sprintf("key_%d", i))) {
+			t.Fatalf("key_%d missing after %d rebuilds", i, stats.Rebuilds)
+		}
+	}
+
+	// The FPR stays near the target instead of exploding with 20x overload
+	fp := 0
+	for i := 0; i < 10_000; i++ {
+		if a.Contains([]byte(fmt.Sprintf("absent_%d", i))) {
+			fp++
+		}
+	}
+	if rate := float64(fp) / 10_000; rate > 0.05 {
+		t.Errorf("False positive rate %.4f too high after scaling", rate)
+	}
+	t.Logf("Stats: %+v, FPR %.4f", stats, float64(fp)/10_000)
+}
+
+// TestAutoScalingConcurrent tests that concurrent adds during rebuilds are never lost
+func TestAutoScalingConcurrent(t *testing.T) {
+	a, err := NewAutoScalingFilter(AutoScalingConfig{
+		ExpectedElements:  500,
+		FalsePositiveRate: 0.01,
+		Keys:              NewMemoryKeyLog(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 2000; i++ {
+				a.Add([]byte(fmt.Sprintf("g%d_%d", g, i)))
+			}
+		}(g)
+	}
+	wg.Wait()
+	a.Wait()
+
+	for g := 0; g < 8; g++ {
+		for i := 0; i < 2000; i++ {
+			if !a.Contains([]byte(fmt.Sprintf("g%d_%d", g, i))) {
+				t.Fatalf("g%d_%d missing", g, i)
+			}
+		}
+	}
+}
+
+type failingRecorder struct{ *MemoryKeyLog }
+
+func (failingRecorder) Record([]byte) error { return errors.New("disk full") }
+
+// TestAutoScalingConfigValidation tests configuration errors and record failures
+func TestAutoScalingConfigValidation(t *testing.T) {
+	bad := []AutoScalingConfig{
+		{ExpectedElements: 10, FalsePositiveRate: 0.01},
+		{ExpectedElements: 0, FalsePositiveRate: 0.01, Keys: NewMemoryKeyLog()},
+		{ExpectedElements: 10, FalsePositiveRate: 1, Keys: NewMemoryKeyLog()},
+		{ExpectedElements: 10, FalsePositiveRate: 0.01, MaxLoadFactor: 1, Keys: NewMemoryKeyLog()},
+		{ExpectedElements: 10, FalsePositiveRate: 0.01, GrowthFactor: 0.5, Keys: NewMemoryKeyLog()},
+	}
+	for i, cfg := range bad {
+		if _, err := NewAutoScalingFilter(cfg); err == nil {
+			t.Errorf("Config %d: expected error", i)
+		}
+	}
+
+	a, err := NewAutoScalingFilter(AutoScalingConfig{
+		ExpectedElements: 10, FalsePositiveRate: 0.01, Keys: failingRecorder{NewMemoryKeyLog()},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	a.Add([]byte("x"))
+	if a.Err() == nil || a.Stats().RecordErrors != 1 || !a.Contains([]byte("x")) {
+		t.Errorf("Expected a counted record error with the key still added, got %+v err=%v", a.Stats(), a.Err())
+	}
+}
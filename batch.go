@@ -0,0 +1,334 @@
+package bloomfilter
+
+import (
+	"unsafe"
+
+	"github.com/shaia/BloomFilter/internal/hash"
+	"github.com/shaia/BloomFilter/internal/simd"
+)
+
+const (
+	// batchKeys is the number of keys hashed together before their probes are applied
+	batchKeys = 32
+	// batchMaxHashCount is the largest hashCount handled by the stack-buffered batch path;
+	// larger values fall back to per-key operations
+	batchMaxHashCount = 16
+)
+
+// Batch operations hash a whole batch of keys before touching the bit array, so
+// the probes of different keys are independent loads that the CPU can overlap.
+// On filters larger than the CPU caches this is ~1.5x faster than per-key calls.
+// Every bulk path that adds keys (BuildFromSeq, Rebuild, BulkLoad, UnionKeys,
+// Reseeded, log replay) goes through AddBatch; Union and the other bit-wise
+// operations work on whole words and have no probes to batch.
+//
+// Grouping each batch's probes by cache line was measured and declined. For
+// 1024-key AddBatch calls at 1% FPR, in ns per key (hash order / slices.Sort /
+// radix by line): 66 / 430 / 122 at 100k keys, 121 / 540 / 198 at 10M keys and
+// 187 / 506 / 224 at 100M keys. Probes of 32 keys rarely share a line, so the
+// grouping costs more than the locality it buys and probes apply in hash order.
+
+// AddStrings adds every string in keys. Strings are converted without copying
+// and the operation performs no allocations, except with the purego build tag,
+// which copies each string.
+//
+// With load shedding enabled the limit is checked once per batch.
+func (bf *CacheOptimizedBloomFilter) AddStrings(keys []string) {
+	bf.addKeys(len(keys), func(i int) []byte { return stringBytes(keys[i]) })
+}
+
+// AddBatch adds every key in keys and is equivalent to calling Add for each of
+// them. The operation performs no allocations.
+//
+// With load shedding enabled the limit is checked once per batch.
+func (bf *CacheOptimizedBloomFilter) AddBatch(keys [][]byte) {
+	bf.addKeys(len(keys), func(i int) []byte { return keys[i] })
+}
+
+// addKeys adds the n keys returned by key in batches of batchKeys
+func (bf *CacheOptimizedBloomFilter) addKeys(n int, key func(i int) []byte) {
+	// Recording logs each add with its result, which needs per-key adds
+	if bf.hashCount > batchMaxHashCount || bf.recorder.Load() != nil {
+		for i := 0; i < n; i++ {
+			bf.Add(key(i))
+		}
+		return
+	}
+
+	k := int(bf.hashCount)
+	var buf [batchKeys * batchMaxHashCount]uint64
+	ghost := bf.ghost.Load()
+	reservoir := bf.reservoir.Load()
+	counting := bf.countAdds.Load()
+
+	for start := 0; start < n; start += batchKeys {
+		size := min(batchKeys, n-start)
+		if bf.overloaded() {
+			bf.dropped.Add(uint64(size))
+			continue
+		}
+
+		positions := buf[:size*k]
+		for i := 0; i < size; i++ {
+			data := key(start + i)
+			h1, h2 := bf.hashKey(data)
+			bf.hashPositions(h1, h2, positions[i*k:(i+1)*k])
+			if ghost != nil {
+				ghost.observeAdd(data, h1, h2)
+			}
+			if reservoir != nil {
+				reservoir.observe(data)
+			}
+		}
+
+		bf.applyBatch(positions, size, k, counting)
+	}
+}
+
+// AddUint64Batch adds every key in keys and is equivalent to calling AddUint64
+// for each of them. The hashes of a batch are computed together: with AVX2, 8
+// keys per iteration for HashV1 and 4 for HashV2, otherwise 4 interleaved scalar
+// keys (NEON has no 64-bit lane multiply, so arm64 uses the scalar kernels).
+// The operation performs no allocations.
+//
+// With load shedding enabled the limit is checked once per batch.
+func (bf *CacheOptimizedBloomFilter) AddUint64Batch(keys []uint64) {
+	if bf.hashCount > batchMaxHashCount || bf.recorder.Load() != nil || bf.hasher != nil {
+		for _, n := range keys {
+			bf.AddUint64(n)
+		}
+		return
+	}
+
+	k := int(bf.hashCount)
+	var buf [batchKeys * batchMaxHashCount]uint64
+	var h1s, h2s [batchKeys]uint64
+	ghost := bf.ghost.Load()
+	reservoir := bf.reservoir.Load()
+	counting := bf.countAdds.Load()
+	vectorized := bf.simdEnabled()
+
+	for start := 0; start < len(keys); start += batchKeys {
+		chunk := keys[start:min(start+batchKeys, len(keys))]
+		if bf.overloaded() {
+			bf.dropped.Add(uint64(len(chunk)))
+			continue
+		}
+
+		bf.hashUint64s(chunk, h1s[:], h2s[:], vectorized)
+
+		positions := buf[:len(chunk)*k]
+		for i := range chunk {
+			bf.hashPositions(h1s[i], h2s[i], positions[i*k:(i+1)*k])
+			if ghost != nil || reservoir != nil {
+				data := (*[8]byte)(unsafe.Pointer(&chunk[i]))[:]
+				if ghost != nil {
+					ghost.observeAdd(data, h1s[i], h2s[i])
+				}
+				if reservoir != nil {
+					reservoir.observe(data)
+				}
+			}
+		}
+
+		bf.applyBatch(positions, len(chunk), k, counting)
+	}
+}
+
+// applyBatch sets the probes of n keys, k per key, and publishes the bit and
+// add counts once for the whole batch
+func (bf *CacheOptimizedBloomFilter) applyBatch(positions []uint64, n, k int, counting bool) {
+	if c := bf.ops.Load(); c != nil {
+		c.adds.Add(uint64(n))
+	}
+	if !counting {
+		bf.recordFlips(bf.setBits(positions))
+		return
+	}
+
+	// Per-key flips classify each add
+	var flipped, newItems, duplicates uint64
+	for i := 0; i < n; i++ {
+		f := bf.setBits(positions[i*k : (i+1)*k])
+		flipped += f
+		if f == 0 {
+			duplicates++
+		} else {
+			newItems++
+		}
+	}
+	bf.recordFlips(flipped)
+	bf.newItems.Add(newItems)
+	bf.duplicates.Add(duplicates)
+}
+
+// ContainsStrings checks every string in keys and returns one result per key.
+// The only allocation is the returned slice, plus a copy of each string with
+// the purego build tag.
+func (bf *CacheOptimizedBloomFilter) ContainsStrings(keys []string) []bool {
+	results := make([]bool, len(keys))
+	bf.containsKeys(results, func(i int) []byte { return stringBytes(keys[i]) })
+	return results
+}
+
+// ContainsBatch checks every key in keys and returns one result per key,
+// equivalent to calling Contains for each of them. The only allocation is the
+// returned slice.
+func (bf *CacheOptimizedBloomFilter) ContainsBatch(keys [][]byte) []bool {
+	results := make([]bool, len(keys))
+	bf.containsKeys(results, func(i int) []byte { return keys[i] })
+	return results
+}
+
+// containsKeys answers one lookup per result for the keys returned by key
+func (bf *CacheOptimizedBloomFilter) containsKeys(results []bool, key func(i int) []byte) {
+	// Recording logs each lookup with its result, which needs per-key lookups
+	if bf.hashCount > batchMaxHashCount || bf.recorder.Load() != nil {
+		for i := range results {
+			results[i] = bf.Contains(key(i))
+		}
+		return
+	}
+
+	var buf, masks [batchKeys * batchMaxHashCount]uint64
+	var hashes [batchKeys][2]uint64
+	ghost := bf.ghost.Load()
+
+	for start := 0; start < len(results); start += batchKeys {
+		size := min(batchKeys, len(results)-start)
+		k := bf.batchProbes(size)
+
+		positions := buf[:size*k]
+		for i := 0; i < size; i++ {
+			h1, h2 := bf.hashKey(key(start + i))
+			bf.hashPositions(h1, h2, positions[i*k:(i+1)*k])
+			hashes[i] = [2]uint64{h1, h2}
+		}
+
+		bf.probeBatch(positions, masks[:len(positions)], k, results[start:start+size])
+		bf.countLookups(results[start : start+size])
+		if ghost != nil {
+			for i := 0; i < size; i++ {
+				ghost.observeQuery(key(start+i), hashes[i][0], hashes[i][1], results[start+i])
+			}
+		}
+	}
+}
+
+// ContainsUint64Batch checks every key in keys and returns one result per key,
+// equivalent to calling ContainsUint64 for each of them. Hashes are computed
+// as in AddUint64Batch and probes are answered as in ContainsBatch. The only
+// allocation is the returned slice.
+func (bf *CacheOptimizedBloomFilter) ContainsUint64Batch(keys []uint64) []bool {
+	results := make([]bool, len(keys))
+	if bf.hashCount > batchMaxHashCount || bf.recorder.Load() != nil || bf.hasher != nil {
+		for i, n := range keys {
+			results[i] = bf.ContainsUint64(n)
+		}
+		return results
+	}
+
+	var buf, masks [batchKeys * batchMaxHashCount]uint64
+	var h1s, h2s [batchKeys]uint64
+	ghost := bf.ghost.Load()
+	vectorized := bf.simdEnabled()
+
+	for start := 0; start < len(keys); start += batchKeys {
+		chunk := keys[start:min(start+batchKeys, len(keys))]
+		k := bf.batchProbes(len(chunk))
+
+		bf.hashUint64s(chunk, h1s[:], h2s[:], vectorized)
+
+		positions := buf[:len(chunk)*k]
+		for i := range chunk {
+			bf.hashPositions(h1s[i], h2s[i], positions[i*k:(i+1)*k])
+		}
+
+		bf.probeBatch(positions, masks[:len(positions)], k, results[start:start+len(chunk)])
+		bf.countLookups(results[start : start+len(chunk)])
+		if ghost != nil {
+			for i := range chunk {
+				data := (*[8]byte)(unsafe.Pointer(&chunk[i]))[:]
+				ghost.observeQuery(data, h1s[i], h2s[i], results[start+i])
+			}
+		}
+	}
+	return results
+}
+
+// batchProbes returns the number of probes per key for a batch of n lookups,
+// counting the lookups adaptive probing reduces
+func (bf *CacheOptimizedBloomFilter) batchProbes(n int) int {
+	k := bf.containsProbes()
+	if k < bf.hashCount {
+		bf.adaptive.Load().reduced.Add(uint64(n))
+	}
+	return int(k)
+}
+
+// probeBatch answers len(results) lookups whose probes, k per key, are in
+// positions. The probes are converted in place to word indexes and bit masks
+// and answered by the probe kernel, which with AVX2 gathers the words 8 probes
+// at a time instead of one atomic load per probe. To keep the early exit of
+// the per-key path, the first probe of every key is answered in one pass and
+// only the keys it does not rule out have their remaining probes answered in a
+// second pass.
+func (bf *CacheOptimizedBloomFilter) probeBatch(positions, masks []uint64, k int, results []bool) {
+	probe := simd.FallbackProbeWords
+	if bf.simdEnabled() {
+		probe = simd.ProbeWords
+	}
+	words := bf.wordSlice()
+
+	var firstIdx, firstMasks [batchKeys]uint64
+	for i := range results {
+		p := positions[i*k]
+		firstIdx[i] = p / 64
+		firstMasks[i] = 1 << (p % 64)
+	}
+	probe(words, firstIdx[:len(results)], firstMasks[:len(results)])
+
+	// Compact the remaining probes of the surviving keys to the front of the
+	// buffers; a key's probes never move forward, so the copy is safe in place
+	var survivors [batchKeys]int
+	n, rest := 0, k-1
+	for i := range results {
+		results[i] = firstMasks[i] == 0
+		if !results[i] || rest == 0 {
+			continue
+		}
+		for j, p := range positions[i*k+1 : (i+1)*k] {
+			positions[n*rest+j] = p / 64
+			masks[n*rest+j] = 1 << (p % 64)
+		}
+		survivors[n] = i
+		n++
+	}
+	if n == 0 {
+		return
+	}
+	probe(words, positions[:n*rest], masks[:n*rest])
+
+	for s, i := range survivors[:n] {
+		var missing uint64
+		for _, m := range masks[s*rest : (s+1)*rest] {
+			missing |= m
+		}
+		results[i] = missing == 0
+	}
+}
+
+// hashUint64s stores the base hashes of 8-byte keys in h1s and h2s, with the
+// SIMD kernel of the hash version when vectorized
+func (bf *CacheOptimizedBloomFilter) hashUint64s(keys, h1s, h2s []uint64, vectorized bool) {
+	switch {
+	case bf.hashVersion == HashV2 && vectorized:
+		simd.Hash128Uint64Batch(keys, bf.seed, h1s, h2s)
+	case bf.hashVersion == HashV2:
+		hash.Seeded128Uint64Batch(keys, bf.seed, h1s, h2s)
+	case vectorized:
+		simd.HashUint64Batch(keys, bf.seed, h1s, h2s)
+	default:
+		hash.SeededUint64Batch(keys, bf.seed, h1s, h2s)
+	}
+}
@@ -0,0 +1,227 @@
+package bloomfilter
+
+import (
+	"fmt"
+	"testing"
+)
+
+// TestStringBatchOperations tests that batch results match per-key operations
+func TestStringBatchOperations(t *testing.T) {
+	for _, fpr := range []float64{0.01, 1e-7} { // 1e-7 exceeds the batch hash count limit
+		t.Run(fmt.Sprintf("FPR_%g", fpr), func(t *testing.T) {
+			bf := NewCacheOptimizedBloomFilter(1000, fpr)
+
+			keys := make([]string, 100) // not a multiple of the batch size
+			for i := range keys {
+				keys[i] = fmt.Sprintf("key_%d", i)
+			}
+			bf.AddStrings(keys)
+
+			queries := append(append([]string{}, keys...), "absent_1", "absent_2", "")
+			results := bf.ContainsStrings(queries)
+			if len(results) != len(queries) {
+				t.Fatalf("Expected %d results, got %d", len(queries), len(results))
+			}
+			for i, q := range queries {
+				if results[i] != bf.ContainsString(q) {
+					t.Errorf("ContainsStrings[%d] = %t disagrees with ContainsString(%q)", i, results[i], q)
+				}
+				if i < len(keys) && !results[i] {
+					t.Errorf("Added key %q not found", q)
+				}
+			}
+		})
+	}
+}
+
+// TestByteBatchOperations tests that byte slice batches match per-key operations
+func TestByteBatchOperations(t *testing.T) {
+	keys := make([][]byte, 100)
+	for i := range keys {
+		keys[i] = []byte(fmt.Sprintf("key_%d", i))
+	}
+
+	batch := NewCacheOptimizedBloomFilter(1000, 0.01)
+	perKey := batch.Clone()
+	batch.AddBatch(keys)
+	for _, k := range keys {
+		perKey.Add(k)
+	}
+	if !batch.Equal(perKey) {
+		t.Fatal("AddBatch set different bits than Add")
+	}
+
+	queries := append(append([][]byte{}, keys...), []byte("absent"), nil)
+	results := batch.ContainsBatch(queries)
+	for i, q := range queries {
+		if results[i] != batch.Contains(q) {
+			t.Errorf("ContainsBatch[%d] = %t disagrees with Contains(%q)", i, results[i], q)
+		}
+		if i < len(keys) && !results[i] {
+			t.Errorf("Added key %q not found", q)
+		}
+	}
+}
+
+// TestAddStringsZeroAllocations verifies the batch add path does not allocate,
+// beyond one copy per string key in purego builds
+func TestAddStringsZeroAllocations(t *testing.T) {
+	bf := NewCacheOptimizedBloomFilter(10_000, 0.01)
+	keys := make([]string, 256)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("key_%d", i)
+	}
+
+	var copies float64
+	if stringsCopy() {
+		copies = float64(len(keys))
+	}
+	if allocs := testing.AllocsPerRun(100, func() { bf.AddStrings(keys) }); allocs > copies {
+		t.Errorf("Expected at most %.0f allocations, got %f", copies, allocs)
+	}
+	if allocs := testing.AllocsPerRun(100, func() { bf.ContainsStrings(keys) }); allocs > copies+1 {
+		t.Errorf("Expected at most %.0f allocations (the result slice and copies), got %f", copies+1, allocs)
+	}
+
+	byteKeys := make([][]byte, len(keys))
+	for i, k := range keys {
+		byteKeys[i] = []byte(k)
+	}
+	if allocs := testing.AllocsPerRun(100, func() { bf.AddBatch(byteKeys) }); allocs != 0 {
+		t.Errorf("AddBatch: expected 0 allocations, got %f", allocs)
+	}
+	if allocs := testing.AllocsPerRun(100, func() { bf.ContainsBatch(byteKeys) }); allocs != 1 {
+		t.Errorf("ContainsBatch: expected 1 allocation (the result slice), got %f", allocs)
+	}
+}
+
+// TestAddUint64Batch verifies the batch uint64 path sets exactly the bits of per-key adds
+func TestAddUint64Batch(t *testing.T) {
+	keys := make([]uint64, 1003) // not a multiple of the batch or kernel width
+	for i := range keys {
+		keys[i] = uint64(i) * 0x9e3779b97f4a7c15
+	}
+
+	cases := []struct {
+		name string
+		opts []Option
+	}{
+		{"Default", nil},
+		{"Seeded", []Option{WithSeed(0xdeadbeef)}},
+		{"SIMDDisabled", []Option{WithSIMDDisabled(), WithSeed(7)}},
+		{"ManyHashes", []Option{WithHashCount(20)}}, // exceeds the batch hash count limit
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			opts := append([]Option{WithExpectedElements(1000), WithSeed(1)}, tc.opts...)
+			batch, err := NewWithOptions(opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			single, _ := NewWithOptions(opts...)
+			batch.EnableAddCounting()
+			single.EnableAddCounting()
+
+			batch.AddUint64Batch(keys)
+			batch.AddUint64Batch(keys[:10])
+			for _, n := range keys {
+				single.AddUint64(n)
+			}
+			for _, n := range keys[:10] {
+				single.AddUint64(n)
+			}
+
+			for i := 0; i < batch.WordCount(); i++ {
+				if batch.LoadWord(i) != single.LoadWord(i) {
+					t.Fatalf("Word %d differs: batch %x, per-key %x", i, batch.LoadWord(i), single.LoadWord(i))
+				}
+			}
+			bs, ss := batch.GetCacheStats(), single.GetCacheStats()
+			if bs.BitsSet != ss.BitsSet || bs.NewItems != ss.NewItems || bs.ProbableDuplicates != ss.ProbableDuplicates {
+				t.Errorf("Stats differ: batch %+v, per-key %+v", bs, ss)
+			}
+		})
+	}
+}
+
+// TestAddUint64BatchZeroAllocations verifies the batch uint64 path does not allocate
+func TestAddUint64BatchZeroAllocations(t *testing.T) {
+	bf := NewCacheOptimizedBloomFilter(10_000, 0.01)
+	keys := make([]uint64, 256)
+	for i := range keys {
+		keys[i] = uint64(i)
+	}
+	if allocs := testing.AllocsPerRun(100, func() { bf.AddUint64Batch(keys) }); allocs != 0 {
+		t.Errorf("Expected 0 allocations, got %f", allocs)
+	}
+	if allocs := testing.AllocsPerRun(100, func() { bf.ContainsUint64Batch(keys) }); allocs != 1 {
+		t.Errorf("Expected 1 allocation (the result slice), got %f", allocs)
+	}
+}
+
+// TestContainsUint64Batch verifies batch uint64 lookups match per-key lookups
+func TestContainsUint64Batch(t *testing.T) {
+	keys := make([]uint64, 2003)
+	for i := range keys {
+		keys[i] = uint64(i) * 0x9e3779b97f4a7c15
+	}
+
+	cases := []struct {
+		name string
+		opts []Option
+	}{
+		{"Default", nil},
+		{"SIMDDisabled", []Option{WithSIMDDisabled(), WithSeed(7)}},
+		{"ManyHashes", []Option{WithHashCount(20)}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			bf, err := NewWithOptions(append([]Option{WithExpectedElements(1000)}, tc.opts...)...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			bf.AddUint64Batch(keys[:1000])
+
+			results := bf.ContainsUint64Batch(keys)
+			if len(results) != len(keys) {
+				t.Fatalf("Expected %d results, got %d", len(keys), len(results))
+			}
+			for i, n := range keys {
+				if results[i] != bf.ContainsUint64(n) {
+					t.Fatalf("ContainsUint64Batch[%d] disagrees with ContainsUint64", i)
+				}
+				if i < 1000 && !results[i] {
+					t.Fatalf("Added key %d not found", i)
+				}
+			}
+		})
+	}
+}
+
+// TestBatchContainsAdaptiveProbing verifies batch lookups use the reduced probe count
+func TestBatchContainsAdaptiveProbing(t *testing.T) {
+	bf := NewCacheOptimizedBloomFilter(1000, 0.01)
+	keys := make([]uint64, 40000)
+	for i := range keys {
+		keys[i] = uint64(i)
+	}
+	bf.AddUint64Batch(keys) // 40x overloaded
+	bf.EnableAdaptiveProbing(0.1)
+	if bf.AdaptiveProbing().Probes >= bf.HashCount() {
+		t.Fatal("Expected reduced probes at this load")
+	}
+
+	queries := make([]string, 1000)
+	for i := range queries {
+		queries[i] = fmt.Sprintf("absent_%d", i)
+	}
+	results := bf.ContainsStrings(queries)
+	if got := bf.AdaptiveProbing().ReducedLookups; got != 1000 {
+		t.Errorf("Expected 1000 reduced lookups, got %d", got)
+	}
+	for i, q := range queries {
+		if results[i] != bf.ContainsString(q) {
+			t.Fatalf("ContainsStrings[%d] disagrees with ContainsString(%q)", i, q)
+		}
+	}
+}
@@ -0,0 +1,36 @@
+package bloomfilter
+
+import "context"
+
+// ctxBatchKeys is how many keys the context-aware batch operations process
+// between checks of their context
+const ctxBatchKeys = 128 * batchKeys
+
+// AddBatchCtx is AddBatch for long bulk loads that must stop when ctx is done.
+// It checks ctx every few thousand keys and returns the number of keys added,
+// a prefix of keys, with ctx's error if it stopped early. Resuming with
+// keys[n:] finishes the load.
+func (bf *CacheOptimizedBloomFilter) AddBatchCtx(ctx context.Context, keys [][]byte) (int, error) {
+	for start := 0; start < len(keys); start += ctxBatchKeys {
+		if err := ctx.Err(); err != nil {
+			return start, err
+		}
+		bf.AddBatch(keys[start:min(start+ctxBatchKeys, len(keys))])
+	}
+	return len(keys), nil
+}
+
+// ContainsBatchCtx is ContainsBatch that stops when ctx is done. The results
+// answer a prefix of keys; they are complete when the error is nil and
+// otherwise cover the keys checked before ctx was done.
+func (bf *CacheOptimizedBloomFilter) ContainsBatchCtx(ctx context.Context, keys [][]byte) ([]bool, error) {
+	results := make([]bool, len(keys))
+	for start := 0; start < len(keys); start += ctxBatchKeys {
+		if err := ctx.Err(); err != nil {
+			return results[:start], err
+		}
+		end := min(start+ctxBatchKeys, len(keys))
+		bf.containsKeys(results[start:end], func(i int) []byte { return keys[start+i] })
+	}
+	return results, nil
+}
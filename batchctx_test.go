@@ -0,0 +1,78 @@
+package bloomfilter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+// cancelAfter is a context that reports itself canceled after n checks
+type cancelAfter struct {
+	context.Context
+	n int
+}
+
+func (c *cancelAfter) Err() error {
+	if c.n == 0 {
+		return context.Canceled
+	}
+	c.n--
+	return nil
+}
+
+func ctxKeys(n int) [][]byte {
+	keys := make([][]byte, n)
+	for i := range keys {
+		keys[i] = []byte(fmt.Sprintf("key-%d", i))
+	}
+	return keys
+}
+
+// TestAddBatchCtx tests complete, interrupted and resumed bulk loads
+func TestAddBatchCtx(t *testing.T) {
+	keys := ctxKeys(3*ctxBatchKeys + 7)
+
+	full := NewCacheOptimizedBloomFilter(uint64(len(keys)), 0.01)
+	if n, err := full.AddBatchCtx(context.Background(), keys); n != len(keys) || err != nil {
+		t.Fatalf("AddBatchCtx = %d, %v", n, err)
+	}
+
+	bf, _ := NewWithOptions(WithExpectedElements(uint64(len(keys))), WithSeed(full.Seed()))
+	n, err := bf.AddBatchCtx(&cancelAfter{context.Background(), 2}, keys)
+	if n != 2*ctxBatchKeys || !errors.Is(err, context.Canceled) {
+		t.Fatalf("Canceled AddBatchCtx = %d, %v", n, err)
+	}
+	if !bf.Contains(keys[n-1]) {
+		t.Error("Key reported added is missing")
+	}
+	if _, err := bf.AddBatchCtx(context.Background(), keys[n:]); err != nil {
+		t.Fatal(err)
+	}
+	if !bf.Equal(full) {
+		t.Error("Resumed load differs from a full load")
+	}
+}
+
+// TestContainsBatchCtx tests that the results match ContainsBatch
+func TestContainsBatchCtx(t *testing.T) {
+	keys := ctxKeys(2*ctxBatchKeys + 5)
+	bf := NewCacheOptimizedBloomFilter(uint64(len(keys)), 0.01)
+	bf.AddBatch(keys[:len(keys)/2])
+
+	want := bf.ContainsBatch(keys)
+	got, err := bf.ContainsBatchCtx(context.Background(), keys)
+	if err != nil || len(got) != len(want) {
+		t.Fatalf("ContainsBatchCtx = %d results, %v", len(got), err)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("Result %d = %t, want %t", i, got[i], want[i])
+		}
+	}
+
+	got, err = bf.ContainsBatchCtx(&cancelAfter{context.Background(), 1}, keys)
+	if len(got) != ctxBatchKeys || !errors.Is(err, context.Canceled) {
+		t.Errorf("Canceled ContainsBatchCtx = %d results, %v", len(got), err)
+	}
+}
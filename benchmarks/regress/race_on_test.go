@@ -0,0 +1,6 @@
+//go:build race
+
+package regress
+
+// raceEnabled reports whether the race detector is active; timings are meaningless under it
+const raceEnabled = true
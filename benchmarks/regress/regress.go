@@ -0,0 +1,307 @@
+// Package regress implements a benchmark regression harness for the bloom filter.
+//
+// It runs a fixed, canonical suite of operations for every SIMD backend available
+// on the host and for several sizes, records the results as JSON, and compares
+// them against a committed baseline. Timings use the minimum of several rounds to
+// reduce the impact of scheduler noise on shared machines.
+//
+// Usage: go test -run Regress ./benchmarks/regress
+package regress
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"runtime"
+	"sort"
+	"time"
+	"unsafe"
+
+	bloomfilter "github.com/shaia/BloomFilter"
+	"github.com/shaia/BloomFilter/internal/simd"
+)
+
+// DefaultThreshold is the allowed slowdown (as a fraction) before a case is
+// reported as a regression. 0.5 means a case may be up to 50% slower than baseline.
+const DefaultThreshold = 0.5
+
+// Config controls how the suite is executed
+type Config struct {
+	// Rounds is the number of times each case is measured; the fastest round wins
+	Rounds int
+	// MinTime is the minimum wall time of a single round
+	MinTime time.Duration
+	// KernelSizes are the buffer sizes (bytes) used for SIMD kernel cases
+	KernelSizes []int
+	// FilterSizes are the expected element counts used for filter cases
+	FilterSizes []uint64
+}
+
+// DefaultConfig returns the canonical suite configuration used for committed baselines
+func DefaultConfig() Config {
+	return Config{
+		Rounds:      7,
+		MinTime:     20 * time.Millisecond,
+		KernelSizes: []int{4 << 10, 256 << 10, 4 << 20},
+		FilterSizes: []uint64{10_000, 1_000_000},
+	}
+}
+
+// Result is the measurement of a single case
+type Result struct {
+	Name      string  `json:"name"`
+	Op        string  `json:"op"`
+	Backend   string  `json:"backend"`
+	Size      uint64  `json:"size"`
+	NsPerOp   float64 `json:"ns_per_op"`
+	Threshold float64 `json:"threshold,omitempty"`
+}
+
+// Report is a complete suite run, as stored on disk
+type Report struct {
+	GOOS      string   `json:"goos"`
+	GOARCH    string   `json:"goarch"`
+	GoVersion string   `json:"go_version"`
+	Results   []Result `json:"results"`
+}
+
+// Regression describes a case that is slower than its baseline by more than the threshold
+type Regression struct {
+	Name      string
+	Baseline  float64
+	Current   float64
+	Ratio     float64
+	Threshold float64
+}
+
+func (r Regression) String() string {
+	return fmt.Sprintf("%s: %.1f ns/op -> %.1f ns/op (%.0f%% slower, threshold %.0f%%)",
+		r.Name, r.Baseline, r.Current, (r.Ratio-1)*100, r.Threshold*100)
+}
+
+// Backend is a named SIMD implementation
+type Backend struct {
+	Name string
+	Ops  simd.Operations
+}
+
+// Backends returns every SIMD backend usable on the current CPU, fallback first
+func Backends() []Backend {
+	backends := []Backend{{Name: "fallback", Ops: &simd.FallbackOperations{}}}
+	if simd.HasAVX2() {
+		backends = append(backends, Backend{Name: "avx2", Ops: &simd.AVX2Operations{}})
+	}
+	if simd.HasAVX512() {
+		backends = append(backends, Backend{Name: "avx512", Ops: &simd.AVX512Operations{}})
+	}
+	if simd.HasNEON() {
+		backends = append(backends, Backend{Name: "neon", Ops: &simd.NEONOperations{}})
+	}
+	return backends
+}
+
+// Run executes the suite described by cfg and returns the report
+func Run(cfg Config) Report {
+	report := Report{
+		GOOS:      runtime.GOOS,
+		GOARCH:    runtime.GOARCH,
+		GoVersion: runtime.Version(),
+	}
+
+	for _, backend := range Backends() {
+		for _, size := range cfg.KernelSizes {
+			report.Results = append(report.Results, runKernelCases(cfg, backend, size)...)
+		}
+	}
+
+	for _, size := range cfg.FilterSizes {
+		report.Results = append(report.Results, runFilterCases(cfg, size)...)
+	}
+
+	return report
+}
+
+// runKernelCases measures the raw SIMD kernels of a backend on buffers of the given size
+func runKernelCases(cfg Config, backend Backend, size int) []Result {
+	dst := make([]uint64, size/8)
+	src := make([]uint64, size/8)
+	for i := range src {
+		src[i] = uint64(i) * 0x9e3779b97f4a7c15
+	}
+	dstPtr := unsafe.Pointer(&dst[0])
+	srcPtr := unsafe.Pointer(&src[0])
+	ops := backend.Ops
+
+	cases := []struct {
+		op string
+		fn func()
+	}{
+		{"PopCount", func() { _ = ops.PopCount(srcPtr, size) }},
+		{"VectorOr", func() { ops.VectorOr(dstPtr, srcPtr, size) }},
+		{"VectorAnd", func() { ops.VectorAnd(dstPtr, srcPtr, size) }},
+		{"VectorXor", func() { ops.VectorXor(dstPtr, srcPtr, size) }},
+		{"VectorAndNot", func() { ops.VectorAndNot(dstPtr, srcPtr, size) }},
+		{"VectorClear", func() { ops.VectorClear(dstPtr, size) }},
+		{"VectorEqual", func() { _ = ops.VectorEqual(srcPtr, srcPtr, size) }}, // equal buffers, full scan
+		{"VectorCopy", func() { ops.VectorCopy(dstPtr, srcPtr, size) }},
+		{"PopCountAnd", func() { _ = ops.PopCountAnd(dstPtr, srcPtr, size) }},
+		{"PopCountOr", func() { _ = ops.PopCountOr(dstPtr, srcPtr, size) }},
+	}
+
+	results := make([]Result, 0, len(cases))
+	for _, c := range cases {
+		results = append(results, Result{
+			Name:    fmt.Sprintf("%s/%s/%d", c.op, backend.Name, size),
+			Op:      c.op,
+			Backend: backend.Name,
+			Size:    uint64(size),
+			NsPerOp: measure(cfg, c.fn),
+		})
+	}
+	return results
+}
+
+// runFilterCases measures the public filter API using the default SIMD backend
+func runFilterCases(cfg Config, size uint64) []Result {
+	bf := bloomfilter.NewCacheOptimizedBloomFilter(size, 0.01)
+	keys := make([][]byte, 1024)
+	for i := range keys {
+		keys[i] = []byte(fmt.Sprintf("regress_key_%d", i))
+	}
+	other, _ := bloomfilter.NewWithOptions(bloomfilter.WithExpectedElements(size), bloomfilter.WithSeed(bf.Seed()))
+	uints := make([]uint64, 64)
+	for i := range uints {
+		uints[i] = uint64(i) * 0x9e3779b97f4a7c15
+	}
+
+	var n int
+	cases := []struct {
+		op string
+		fn func()
+	}{
+		{"Add", func() { bf.Add(keys[n&1023]); n++ }},
+		{"Contains", func() { _ = bf.Contains(keys[n&1023]); n++ }},
+		{"AddUint64", func() { bf.AddUint64(uint64(n)); n++ }},
+		{"AddUint64Batch", func() { bf.AddUint64Batch(uints) }},
+		{"Union", func() { _ = bf.Union(other) }},
+		{"PopCount", func() { _ = bf.PopCount() }},
+	}
+
+	backend := backendName()
+	results := make([]Result, 0, len(cases))
+	for _, c := range cases {
+		results = append(results, Result{
+			Name:    fmt.Sprintf("Filter%s/%s/%d", c.op, backend, size),
+			Op:      "Filter" + c.op,
+			Backend: backend,
+			Size:    size,
+			NsPerOp: measure(cfg, c.fn),
+		})
+	}
+	return results
+}
+
+// backendName reports the backend selected by simd.Get()
+func backendName() string {
+	switch simd.Get().(type) {
+	case *simd.AVX512Operations:
+		return "avx512"
+	case *simd.AVX2Operations:
+		return "avx2"
+	case *simd.NEONOperations:
+		return "neon"
+	default:
+		return "fallback"
+	}
+}
+
+// measure returns the fastest ns/op of fn across cfg.Rounds rounds
+func measure(cfg Config, fn func()) float64 {
+	// Calibrate the iteration count so that one round lasts at least MinTime
+	iters := 1
+	for {
+		start := time.Now()
+		for i := 0; i < iters; i++ {
+			fn()
+		}
+		if elapsed := time.Since(start); elapsed >= cfg.MinTime || iters >= 1<<30 {
+			break
+		}
+		iters *= 2
+	}
+
+	best := 0.0
+	for r := 0; r < cfg.Rounds; r++ {
+		start := time.Now()
+		for i := 0; i < iters; i++ {
+			fn()
+		}
+		nsPerOp := float64(time.Since(start).Nanoseconds()) / float64(iters)
+		if r == 0 || nsPerOp < best {
+			best = nsPerOp
+		}
+	}
+	// Round to 0.1ns to keep baselines readable
+	return math.Round(best*10) / 10
+}
+
+// Compare returns the cases in current that are slower than in baseline by more
+// than the applicable threshold. A per-case threshold in the baseline wins over the
+// threshold argument. Cases missing from either side (for example a backend
+// unavailable on this CPU) are ignored.
+func Compare(baseline, current Report, threshold float64) []Regression {
+	byName := make(map[string]Result, len(current.Results))
+	for _, r := range current.Results {
+		byName[r.Name] = r
+	}
+
+	var regressions []Regression
+	for _, base := range baseline.Results {
+		cur, ok := byName[base.Name]
+		if !ok || base.NsPerOp <= 0 {
+			continue
+		}
+		limit := threshold
+		if base.Threshold > 0 {
+			limit = base.Threshold
+		}
+		ratio := cur.NsPerOp / base.NsPerOp
+		if ratio > 1+limit {
+			regressions = append(regressions, Regression{
+				Name:      base.Name,
+				Baseline:  base.NsPerOp,
+				Current:   cur.NsPerOp,
+				Ratio:     ratio,
+				Threshold: limit,
+			})
+		}
+	}
+
+	sort.Slice(regressions, func(i, j int) bool {
+		return regressions[i].Ratio > regressions[j].Ratio
+	})
+	return regressions
+}
+
+// Load reads a report from a JSON file
+func Load(path string) (Report, error) {
+	var report Report
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return report, err
+	}
+	if err := json.Unmarshal(data, &report); err != nil {
+		return report, fmt.Errorf("regress: parse %s: %w", path, err)
+	}
+	return report, nil
+}
+
+// Save writes a report to a JSON file
+func Save(path string, report Report) error {
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
@@ -0,0 +1,104 @@
+package regress
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+var (
+	update    = flag.Bool("regress.update", false, "overwrite the committed baseline with the current results")
+	threshold = flag.Float64("regress.threshold", DefaultThreshold, "allowed slowdown fraction before a case fails")
+	outPath   = flag.String("regress.out", "", "where to write the current results (default testdata/latest_<goos>_<goarch>.json)")
+)
+
+func platform() string {
+	return fmt.Sprintf("%s_%s", runtime.GOOS, runtime.GOARCH)
+}
+
+// selected reports whether the -run pattern names the suite. Timings are only
+// meaningful on a quiet machine, so the suite does not run as part of
+// "go test ./..." or "go test -run . ./..." and must be requested by name.
+func selected() bool {
+	f := flag.Lookup("test.run")
+	return f != nil && strings.Contains(f.Value.String(), "Regress")
+}
+
+// TestRegress runs the canonical suite and compares it against the committed baseline.
+//
+// Usage:
+//
+//	go test -run Regress ./benchmarks/regress
+//	go test -run Regress ./benchmarks/regress -args -regress.update
+func TestRegress(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping benchmark regression suite in short mode")
+	}
+	if !selected() {
+		t.Skip("benchmark regression suite runs only with -run Regress")
+	}
+	if raceEnabled {
+		t.Skip("skipping benchmark regression suite under the race detector")
+	}
+
+	current := Run(DefaultConfig())
+
+	out := *outPath
+	if out == "" {
+		out = filepath.Join("testdata", "latest_"+platform()+".json")
+	}
+	if err := Save(out, current); err != nil {
+		t.Fatalf("Failed to write results: %v", err)
+	}
+	t.Logf("Wrote %d results to %s", len(current.Results), out)
+
+	baselinePath := filepath.Join("testdata", "baseline_"+platform()+".json")
+	if *update {
+		if err := Save(baselinePath, current); err != nil {
+			t.Fatalf("Failed to write baseline: %v", err)
+		}
+		t.Logf("Updated baseline %s", baselinePath)
+		return
+	}
+
+	baseline, err := Load(baselinePath)
+	if errors.Is(err, fs.ErrNotExist) {
+		t.Skipf("No baseline for %s; create one with -args -regress.update", platform())
+	}
+	if err != nil {
+		t.Fatalf("Failed to load baseline: %v", err)
+	}
+
+	for _, r := range Compare(baseline, current, *threshold) {
+		t.Errorf("Regression: %s", r)
+	}
+}
+
+// TestCompare verifies threshold handling without running the suite
+func TestCompare(t *testing.T) {
+	baseline := Report{
+		Results: []Result{
+			{Name: "a", NsPerOp: 100},
+			{Name: "b", NsPerOp: 100},
+			{Name: "c", NsPerOp: 100, Threshold: 1.0},
+			{Name: "missing", NsPerOp: 100},
+		},
+	}
+	current := Report{
+		Results: []Result{
+			{Name: "a", NsPerOp: 115},
+			{Name: "b", NsPerOp: 150},
+			{Name: "c", NsPerOp: 150},
+		},
+	}
+
+	regressions := Compare(baseline, current, 0.2)
+	if len(regressions) != 1 || regressions[0].Name != "b" {
+		t.Fatalf("Expected only case b to regress, got %v", regressions)
+	}
+}
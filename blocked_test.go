@@ -0,0 +1,120 @@
+package bloomfilter
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"sync"
+	"testing"
+)
+
+// TestRegisterBlockedFilter tests membership and the key type equivalences
+func TestRegisterBlockedFilter(t *testing.T) {
+	f := NewRegisterBlockedFilter(10000, 0.01)
+	for i := 0; i < 10000; i++ {
+		f.AddString(fmt.Sprint("key_", i))
+		f.AddUint64(uint64(i))
+	}
+	for i := 0; i < 10000; i++ {
+		if !f.ContainsString(fmt.Sprint("key_", i)) || !f.Contains([]byte(fmt.Sprint("key_", i))) {
+			t.Fatalf("False negative for key_%d", i)
+		}
+		var b [8]byte
+		binary.NativeEndian.PutUint64(b[:], uint64(i))
+		if !f.ContainsUint64(uint64(i)) || !f.Contains(b[:]) {
+			t.Fatalf("False negative for %d", i)
+		}
+	}
+
+	if f.HashCount() < 1 || f.HashCount() > MaxRegisterBlockedHashCount || f.BitCount()%BitsPerCacheLine != 0 {
+		t.Errorf("Unexpected geometry: k=%d bits=%d", f.HashCount(), f.BitCount())
+	}
+	if f.PopCount() == 0 {
+		t.Error("PopCount is 0 after adds")
+	}
+	f.Clear()
+	if f.PopCount() != 0 || f.ContainsString("key_0") {
+		t.Error("Clear left bits set")
+	}
+}
+
+// TestRegisterBlockedFPR tests that the measured rate matches the sizing model
+// and EstimatedFPP
+func TestRegisterBlockedFPR(t *testing.T) {
+	const n = 200_000
+	for _, target := range []float64{0.05, 0.01, 0.001} {
+		t.Run(fmt.Sprint(target), func(t *testing.T) {
+			f := NewRegisterBlockedFilter(n, target)
+			for i := uint64(0); i < n; i++ {
+				f.AddUint64(i)
+			}
+			var fp int
+			const probes = 1_000_000
+			for i := uint64(0); i < probes; i++ {
+				if f.ContainsUint64(1<<40 + i) {
+					fp++
+				}
+			}
+			measured := float64(fp) / probes
+			if measured > target*1.25 {
+				t.Errorf("Measured FPR %g above target %g", measured, target)
+			}
+			if est := f.EstimatedFPP(); math.Abs(est-measured) > target*0.25 {
+				t.Errorf("EstimatedFPP %g, measured %g", est, measured)
+			}
+		})
+	}
+}
+
+// TestRegisterBlockedWithWords tests explicit geometry, seeds and validation
+func TestRegisterBlockedWithWords(t *testing.T) {
+	a := NewRegisterBlockedFilterWithWords(100, 4, 0)
+	b := NewRegisterBlockedFilterWithWords(100, 4, 7)
+	if a.BitCount() != 104*64 || b.Seed() != 7 {
+		t.Errorf("Geometry: bits=%d seed=%d", a.BitCount(), b.Seed())
+	}
+	a.AddString("x")
+	b.AddString("x")
+	if !a.ContainsString("x") || !b.ContainsString("x") {
+		t.Error("Seeded filter lost the key")
+	}
+
+	for name, fn := range map[string]func(){
+		"ZeroWords": func() { NewRegisterBlockedFilterWithWords(0, 4, 0) },
+		"ZeroK":     func() { NewRegisterBlockedFilterWithWords(8, 0, 0) },
+		"LargeK":    func() { NewRegisterBlockedFilterWithWords(8, 9, 0) },
+		"BadRate":   func() { NewRegisterBlockedFilter(100, 1) },
+		"TinyRate":  func() { NewRegisterBlockedFilter(math.MaxUint64/2, 1e-300) },
+	} {
+		t.Run(name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Error("Expected a panic")
+				}
+			}()
+			fn()
+		})
+	}
+}
+
+// TestRegisterBlockedConcurrent tests concurrent adds and lookups
+func TestRegisterBlockedConcurrent(t *testing.T) {
+	f := NewRegisterBlockedFilter(100_000, 0.01)
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 5000; i++ {
+				f.AddUint64(uint64(g*5000 + i))
+				f.ContainsUint64(uint64(i))
+			}
+		}(g)
+	}
+	wg.Wait()
+	for i := 0; i < 40000; i++ {
+		if !f.ContainsUint64(uint64(i)) {
+			t.Fatalf("Lost concurrent add %d", i)
+		}
+	}
+}
@@ -3,10 +3,10 @@ package bloomfilter
 import (
 	"fmt"
 	"math"
+	"sync"
 	"sync/atomic"
 	"unsafe"
 
-	"github.com/shaia/BloomFilter/internal/hash"
 	"github.com/shaia/BloomFilter/internal/simd"
 )
 
@@ -17,22 +17,102 @@ type CacheOptimizedBloomFilter struct {
 	bitCount       uint64
 	hashCount      uint32
 	cacheLineCount uint64
+	// Hash seed, random unless fixed with WithSeed (see Seed)
+	seed uint64
+	// How keys map to bit positions (see ProbeScheme)
+	probe ProbeScheme
+	// Built-in key hash (see HashVersion)
+	hashVersion HashVersion
+	// Custom key hash, nil for the built-in one (see WithHasher)
+	hasher HashFunc
+	// Keys the filter was sized for, 0 when built from a geometry (see AchievedFPR)
+	expectedElements uint64
+	// Bits alias a caller's buffer that must not be written (see NewFromBuffer)
+	readOnly bool
 
 	// SIMD operations instance (initialized once for performance)
 	simdOps simd.Operations
+
+	// Incremental set-bit count, maintained only once a feature that needs an
+	// O(1) load factor has enabled it (see enableBitTracking)
+	trackBits atomic.Bool
+	bitsSet   atomic.Uint64
+
+	// Load shedding (see SetMaxLoadFactor), maxLoadBits is 0 when disabled
+	maxLoadBits atomic.Uint64
+	dropped     atomic.Uint64
+
+	// Online FPR measurement (see EnableFPRSampling), nil when disabled
+	ghost atomic.Pointer[ghostSampler]
+
+	// Uniform sample of added keys (see EnableKeyReservoir), nil when disabled
+	reservoir atomic.Pointer[keyReservoir]
+
+	// Reduced probing on saturated filters (see EnableAdaptiveProbing), nil when disabled
+	adaptive atomic.Pointer[adaptiveProbing]
+
+	// Load factor callback (see OnSaturation), nil when not set
+	saturation atomic.Pointer[saturationWatch]
+
+	// Operation log (see StartRecording), nil when not recording
+	recorder atomic.Pointer[recorder]
+
+	// Operation counts (see EnableOperationCounters), nil when not counting
+	ops atomic.Pointer[opCounters]
+
+	// Cache lines changed since the last delta (see EnableChangeTracking), nil
+	// when not tracking
+	changes atomic.Pointer[changeTracker]
+	// Change version of the latest delta applied (see ApplyDelta), 0 before the first
+	deltaApplied atomic.Uint64
+
+	// Write epoch, odd while ClearAndReset or Snapshot runs; clearMu serializes
+	// them and parks adds that arrive meanwhile (see quiesce)
+	epoch   atomic.Uint64
+	clearMu sync.Mutex
+
+	// New vs. duplicate add counters (see EnableAddCounting)
+	countAdds  atomic.Bool
+	newItems   atomic.Uint64
+	duplicates atomic.Uint64
+
+	// Owning pool for filters from FilterPool.Get, released while in the pool
+	pool     *FilterPool
+	released atomic.Bool
 }
 
 // CacheStats provides detailed statistics about the bloom filter
 type CacheStats struct {
-	BitCount       uint64
-	HashCount      uint32
-	BitsSet        uint64
-	LoadFactor     float64
-	EstimatedFPP   float64
-	CacheLineCount uint64
-	CacheLineSize  int
-	MemoryUsage    uint64
-	Alignment      uintptr
+	BitCount     uint64
+	HashCount    uint32
+	BitsSet      uint64
+	LoadFactor   float64
+	EstimatedFPP float64
+	// Distinct items added, estimated from the bits set (see EstimatedCount)
+	EstimatedCount uint64
+	// Keys the filter was sized for and the rate it has at that many keys (see
+	// AchievedFPR), both zero when unknown
+	ExpectedElements uint64
+	AchievedFPR      float64
+	// The bit statistics above and Work are zero: skipped by Stats (see SkipBitStats)
+	BitStatsSkipped bool
+	CacheLineCount  uint64
+	CacheLineSize   int
+	MemoryUsage     uint64
+	Alignment       uintptr
+	// Adds dropped by load shedding (see SetMaxLoadFactor)
+	DroppedAdds uint64
+	// Adds that set a new bit vs. found all bits set (see EnableAddCounting)
+	NewItems           uint64
+	ProbableDuplicates uint64
+	// Adds, lookups, positives and CAS retries (see EnableOperationCounters)
+	Operations OperationCounters
+	// Expected probes and cache lines touched per operation at the current load
+	Work WorkEstimate
+	// Bits Contains checks: HashCount, or fewer under adaptive probing
+	ContainsProbes uint32
+	// Online false positive rate measurement (see EnableFPRSampling)
+	MeasuredFPR FPRMeasurement
 	// SIMD capability information
 	HasAVX2     bool
 	HasAVX512   bool
@@ -43,31 +123,75 @@ type CacheStats struct {
 // NewCacheOptimizedBloomFilter creates a cache line optimized bloom filter.
 // Uses SIMD-accelerated operations and lock-free atomic operations for thread-safety.
 // Achieves zero allocations for typical use cases (hashCount ≤ 16, which covers 99% of scenarios).
+// The hash functions get a random seed (see Seed); use NewWithOptions and
+// WithSeed for reproducible bit positions.
 //
 // Panics if:
 //   - expectedElements is 0
 //   - falsePositiveRate is <= 0, >= 1.0, or NaN
 func NewCacheOptimizedBloomFilter(expectedElements uint64, falsePositiveRate float64) *CacheOptimizedBloomFilter {
-	// Validate inputs
+	validateSizing(expectedElements, falsePositiveRate)
+	bf := newFilter(optimalGeometry(expectedElements, falsePositiveRate))
+	bf.expectedElements = expectedElements
+	bf.seed = RandomSeed()
+	return bf
+}
+
+// NewWithError is NewCacheOptimizedBloomFilter returning an error instead of
+// panicking, for filters sized from configuration or user input.
+//
+// Returns an error if expectedElements is 0, falsePositiveRate is not in (0, 1),
+// or the two result in zero bits or in a filter too large to allocate.
+func NewWithError(expectedElements uint64, falsePositiveRate float64) (*CacheOptimizedBloomFilter, error) {
+	if err := checkSizing(expectedElements, falsePositiveRate); err != nil {
+		return nil, err
+	}
+	bf := newFilter(optimalGeometry(expectedElements, falsePositiveRate))
+	bf.expectedElements = expectedElements
+	bf.seed = RandomSeed()
+	return bf, nil
+}
+
+// validateSizing panics if the sizing parameters are invalid
+func validateSizing(expectedElements uint64, falsePositiveRate float64) {
+	if err := checkSizing(expectedElements, falsePositiveRate); err != nil {
+		panic(err.Error())
+	}
+}
+
+// checkSizing reports why the sizing parameters are invalid, or nil
+func checkSizing(expectedElements uint64, falsePositiveRate float64) error {
 	if expectedElements == 0 {
-		panic("bloomfilter: expectedElements must be greater than 0")
+		return fmt.Errorf("bloomfilter: expectedElements must be greater than 0")
 	}
 	if falsePositiveRate <= 0 || falsePositiveRate >= 1.0 {
-		panic(fmt.Sprintf("bloomfilter: falsePositiveRate must be in range (0, 1), got %f", falsePositiveRate))
+		return fmt.Errorf("bloomfilter: falsePositiveRate must be in range (0, 1), got %f", falsePositiveRate)
 	}
 	if math.IsNaN(falsePositiveRate) {
-		panic("bloomfilter: falsePositiveRate cannot be NaN")
+		return fmt.Errorf("bloomfilter: falsePositiveRate cannot be NaN")
 	}
 
-	// Calculate optimal parameters
-	ln2 := math.Ln2
-	bitCount := uint64(-float64(expectedElements) * math.Log(falsePositiveRate) / (ln2 * ln2))
-	hashCount := uint32(float64(bitCount) * ln2 / float64(expectedElements))
-
-	// Validate calculated parameters
-	if bitCount == 0 {
-		panic(fmt.Sprintf("bloomfilter: falsePositiveRate too high (%f) for %d elements, results in zero bits", falsePositiveRate, expectedElements))
+	bits := optimalBits(expectedElements, falsePositiveRate)
+	if bits < 1 {
+		return fmt.Errorf("bloomfilter: falsePositiveRate too high (%f) for %d elements, results in zero bits", falsePositiveRate, expectedElements)
 	}
+	if bits > float64(math.MaxInt/CacheLineSize)*BitsPerCacheLine {
+		return fmt.Errorf("bloomfilter: %d elements at falsePositiveRate %g need %.3g bits, too many to allocate", expectedElements, falsePositiveRate, bits)
+	}
+	return nil
+}
+
+// optimalBits returns the optimal bit count, m = -n*ln(p)/ln(2)^2
+func optimalBits(expectedElements uint64, falsePositiveRate float64) float64 {
+	return -float64(expectedElements) * math.Log(falsePositiveRate) / (math.Ln2 * math.Ln2)
+}
+
+// optimalGeometry returns the cache line and hash function counts for a filter
+// holding expectedElements at falsePositiveRate. Inputs must already be validated.
+func optimalGeometry(expectedElements uint64, falsePositiveRate float64) (uint64, uint32) {
+	// Calculate optimal parameters
+	bitCount := uint64(optimalBits(expectedElements, falsePositiveRate))
+	hashCount := uint32(float64(bitCount) * math.Ln2 / float64(expectedElements))
 
 	if hashCount < 1 {
 		hashCount = 1
@@ -78,39 +202,63 @@ func NewCacheOptimizedBloomFilter(expectedElements uint64, falsePositiveRate flo
 	if cacheLineCount == 0 {
 		cacheLineCount = 1 // Ensure at least one cache line
 	}
-	bitCount = cacheLineCount * BitsPerCacheLine
 
-	// Allocate cache line aligned memory
-	cacheLines := make([]CacheLine, cacheLineCount)
+	return cacheLineCount, hashCount
+}
 
-	// Verify alignment
-	if uintptr(unsafe.Pointer(&cacheLines[0]))%CacheLineSize != 0 {
-		// Force alignment by creating a larger slice and finding aligned offset
-		oversized := make([]byte, int(cacheLineCount)*CacheLineSize+CacheLineSize)
-		alignedPtr := (uintptr(unsafe.Pointer(&oversized[0])) + CacheLineSize - 1) &^ (CacheLineSize - 1)
-		cacheLines = *(*[]CacheLine)(unsafe.Pointer(&struct {
-			ptr uintptr
-			len int
-			cap int
-		}{alignedPtr, int(cacheLineCount), int(cacheLineCount)}))
-	}
+// newFilter creates an empty filter with the given geometry.
+// cacheLineCount must be greater than 0 and hashCount at least 1.
+func newFilter(cacheLineCount uint64, hashCount uint32) *CacheOptimizedBloomFilter {
+	return newFilterOver(allocCacheLines(cacheLineCount), hashCount)
+}
 
-	bf := &CacheOptimizedBloomFilter{
+// newFilterOver creates a filter backed by existing, aligned cache lines
+func newFilterOver(cacheLines []CacheLine, hashCount uint32) *CacheOptimizedBloomFilter {
+	cacheLineCount := uint64(len(cacheLines))
+	return &CacheOptimizedBloomFilter{
 		cacheLines:     cacheLines,
-		bitCount:       bitCount,
+		bitCount:       cacheLineCount * BitsPerCacheLine,
 		hashCount:      hashCount,
 		cacheLineCount: cacheLineCount,
+		probe:          DefaultProbeScheme,
+		hashVersion:    DefaultHashVersion,
 		simdOps:        simd.Get(), // Initialize SIMD operations once
 	}
+}
 
-	return bf
+// allocCacheLines allocates cacheLineCount cache lines aligned to CacheLineSize
+func allocCacheLines(cacheLineCount uint64) []CacheLine {
+	cacheLines := make([]CacheLine, cacheLineCount)
+
+	// Verify alignment
+	if uintptr(unsafe.Pointer(&cacheLines[0]))%CacheLineSize != 0 {
+		// Force alignment by creating a larger slice and finding aligned offset.
+		// The aligned slice points into oversized, which keeps it reachable.
+		oversized := make([]byte, int(cacheLineCount)*CacheLineSize+CacheLineSize)
+		offset := CacheLineSize - int(uintptr(unsafe.Pointer(&oversized[0]))%CacheLineSize)
+		cacheLines = unsafe.Slice((*CacheLine)(unsafe.Pointer(&oversized[offset])), int(cacheLineCount))
+	}
+
+	return cacheLines
 }
 
-// Add adds an element with cache line optimization
+// Add adds an element with cache line optimization.
+// If load shedding is enabled and the filter is past its maximum load factor,
+// the element is dropped and counted instead (see SetMaxLoadFactor).
 func (bf *CacheOptimizedBloomFilter) Add(data []byte) {
-	h1 := hash.Optimized1(data)
-	h2 := hash.Optimized2(data)
+	if bf.overloaded() {
+		bf.dropped.Add(1)
+		return
+	}
+
+	h1, h2 := bf.hashKey(data)
+	bf.addHashed(data, h1, h2, false)
+}
 
+// addHashed adds an element whose hashes have already been computed and
+// returns the number of bits it set. With test, the add also answers a lookup
+// (TestAndAdd), which is scored by the FPR sampler before the key is recorded.
+func (bf *CacheOptimizedBloomFilter) addHashed(data []byte, h1, h2 uint64, test bool) uint64 {
 	// Stack buffer for typical filters
 	var stackBuf [16]uint64
 	var positions []uint64
@@ -121,19 +269,50 @@ func (bf *CacheOptimizedBloomFilter) Add(data []byte) {
 	}
 
 	// Generate positions
-	for i := uint32(0); i < bf.hashCount; i++ {
-		positions[i] = (h1 + uint64(i)*h2) % bf.bitCount
-	}
+	bf.hashPositions(h1, h2, positions)
 
 	// Set bits atomically
-	bf.setBitsAtomic(positions)
+	flipped := bf.setBits(positions)
+	bf.recordFlips(flipped)
+	bf.countAdd(flipped)
+	if c := bf.ops.Load(); c != nil {
+		c.adds.Add(1)
+		if test {
+			c.countLookup(flipped == 0)
+		}
+	}
+	if r := bf.recorder.Load(); r != nil {
+		r.record(RecordAdd, flipped > 0, h1, h2)
+	}
+
+	if g := bf.ghost.Load(); g != nil {
+		if test {
+			g.observeQuery(data, h1, h2, flipped == 0)
+		}
+		g.observeAdd(data, h1, h2)
+	}
+	if r := bf.reservoir.Load(); r != nil {
+		r.observe(data)
+	}
+	return flipped
 }
 
 // Contains checks membership with cache line optimization
 func (bf *CacheOptimizedBloomFilter) Contains(data []byte) bool {
-	h1 := hash.Optimized1(data)
-	h2 := hash.Optimized2(data)
+	h1, h2 := bf.hashKey(data)
+	return bf.containsHashed(data, h1, h2)
+}
 
+// hashKey returns the two base hashes of data under the filter's seed
+func (bf *CacheOptimizedBloomFilter) hashKey(data []byte) (uint64, uint64) {
+	if bf.hasher != nil {
+		return customHash(bf.hasher, data)
+	}
+	return bf.hashVersion.hash(data, bf.seed)
+}
+
+// containsHashed checks an element whose hashes have already been computed
+func (bf *CacheOptimizedBloomFilter) containsHashed(data []byte, h1, h2 uint64) bool {
 	var stackBuf [16]uint64
 	var positions []uint64
 	if bf.hashCount <= 16 {
@@ -142,29 +321,33 @@ func (bf *CacheOptimizedBloomFilter) Contains(data []byte) bool {
 		positions = make([]uint64, bf.hashCount)
 	}
 
-	for i := uint32(0); i < bf.hashCount; i++ {
-		positions[i] = (h1 + uint64(i)*h2) % bf.bitCount
+	if k := bf.containsProbes(); k < bf.hashCount {
+		positions = positions[:k]
+		bf.adaptive.Load().reduced.Add(1)
 	}
+	bf.hashPositions(h1, h2, positions)
 
-	return bf.checkBitsAtomic(positions)
+	present := bf.checkBitsAtomic(positions)
+	if c := bf.ops.Load(); c != nil {
+		c.countLookup(present)
+	}
+	if g := bf.ghost.Load(); g != nil {
+		g.observeQuery(data, h1, h2, present)
+	}
+	if r := bf.recorder.Load(); r != nil {
+		r.record(RecordContains, present, h1, h2)
+	}
+	return present
 }
 
 // AddString adds a string element to the bloom filter
 func (bf *CacheOptimizedBloomFilter) AddString(s string) {
-	data := *(*[]byte)(unsafe.Pointer(&struct {
-		string
-		int
-	}{s, len(s)}))
-	bf.Add(data)
+	bf.Add(stringBytes(s))
 }
 
 // ContainsString checks if a string element exists in the bloom filter
 func (bf *CacheOptimizedBloomFilter) ContainsString(s string) bool {
-	data := *(*[]byte)(unsafe.Pointer(&struct {
-		string
-		int
-	}{s, len(s)}))
-	return bf.Contains(data)
+	return bf.Contains(stringBytes(s))
 }
 
 // AddUint64 adds a uint64 element to the bloom filter
@@ -181,6 +364,7 @@ func (bf *CacheOptimizedBloomFilter) ContainsUint64(n uint64) bool {
 
 // Clear resets the bloom filter using vectorized operations with automatic fallback
 func (bf *CacheOptimizedBloomFilter) Clear() {
+	bf.checkWritable()
 	if bf.cacheLineCount == 0 {
 		return
 	}
@@ -190,12 +374,25 @@ func (bf *CacheOptimizedBloomFilter) Clear() {
 
 	// Use the pre-initialized SIMD operations for vectorized clear operation
 	bf.simdOps.VectorClear(unsafe.Pointer(&bf.cacheLines[0]), totalBytes)
+	bf.markAllChanged()
+	bf.bitsSet.Store(0)
+	bf.checkSaturation(0)
+	if r := bf.recorder.Load(); r != nil {
+		r.record(RecordClear, false, 0, 0)
+	}
+	if g := bf.ghost.Load(); g != nil {
+		g.reset()
+	}
+	if r := bf.reservoir.Load(); r != nil {
+		r.reset()
+	}
 }
 
 // Union performs vectorized union operation with automatic fallback to optimized scalar
 func (bf *CacheOptimizedBloomFilter) Union(other *CacheOptimizedBloomFilter) error {
-	if bf.cacheLineCount != other.cacheLineCount {
-		return fmt.Errorf("bloom filters must have same size for union")
+	bf.checkWritable()
+	if err := bf.checkCombinable(other, "union"); err != nil {
+		return err
 	}
 
 	if bf.cacheLineCount == 0 {
@@ -211,14 +408,21 @@ func (bf *CacheOptimizedBloomFilter) Union(other *CacheOptimizedBloomFilter) err
 		unsafe.Pointer(&other.cacheLines[0]),
 		totalBytes,
 	)
+	bf.markAllChanged()
+	bf.resyncBitsSet()
+	if g := bf.ghost.Load(); g != nil {
+		g.invalidate()
+	}
+	bf.recordExternal()
 
 	return nil
 }
 
 // Intersection performs vectorized intersection operation with automatic fallback to optimized scalar
 func (bf *CacheOptimizedBloomFilter) Intersection(other *CacheOptimizedBloomFilter) error {
-	if bf.cacheLineCount != other.cacheLineCount {
-		return fmt.Errorf("bloom filters must have same size for intersection")
+	bf.checkWritable()
+	if err := bf.checkCombinable(other, "intersection"); err != nil {
+		return err
 	}
 
 	if bf.cacheLineCount == 0 {
@@ -234,6 +438,12 @@ func (bf *CacheOptimizedBloomFilter) Intersection(other *CacheOptimizedBloomFilt
 		unsafe.Pointer(&other.cacheLines[0]),
 		totalBytes,
 	)
+	bf.markAllChanged()
+	bf.resyncBitsSet()
+	if g := bf.ghost.Load(); g != nil {
+		g.invalidate()
+	}
+	bf.recordExternal()
 
 	return nil
 }
@@ -253,34 +463,84 @@ func (bf *CacheOptimizedBloomFilter) PopCount() uint64 {
 	return uint64(count)
 }
 
-// EstimatedFPP calculates the estimated false positive probability
+// EstimatedFPP calculates the estimated false positive probability.
+// It is O(1) with fill tracking (see EnableFillTracking) and a PopCount otherwise.
 func (bf *CacheOptimizedBloomFilter) EstimatedFPP() float64 {
-	bitsSet := float64(bf.PopCount())
-	ratio := bitsSet / float64(bf.bitCount)
-	return math.Pow(ratio, float64(bf.hashCount))
+	return bf.fppAt(bf.BitsSet(), bf.hashCount)
+}
+
+// EstimatedCount estimates the distinct items added as -m/k * ln(1 - X/m) for X
+// bits set. A full filter saturates at the estimate for one unset bit.
+// It is O(1) with fill tracking (see EnableFillTracking) and a PopCount otherwise.
+func (bf *CacheOptimizedBloomFilter) EstimatedCount() uint64 {
+	return bf.estimateCount(bf.BitsSet())
+}
+
+func (bf *CacheOptimizedBloomFilter) estimateCount(bitsSet uint64) uint64 {
+	return uint64(math.Round(bf.itemsForBits(bitsSet)))
+}
+
+// itemsForBits is the expected number of items that set bitsSet bits
+func (bf *CacheOptimizedBloomFilter) itemsForBits(bitsSet uint64) float64 {
+	m := float64(bf.bitCount)
+	if m == 0 {
+		return 0
+	}
+	bitsSet = min(bitsSet, bf.bitCount-1)
+	return -m / float64(bf.hashCount) * math.Log1p(-float64(bitsSet)/m)
 }
 
-// GetCacheStats returns detailed statistics about the bloom filter
+// GetCacheStats returns detailed statistics about the bloom filter. The bit
+// statistics come from the tracked count with fill tracking (see
+// EnableFillTracking) and from a PopCount otherwise.
 func (bf *CacheOptimizedBloomFilter) GetCacheStats() CacheStats {
-	bitsSet := bf.PopCount()
-	alignment := uintptr(unsafe.Pointer(&bf.cacheLines[0])) % CacheLineSize
+	return bf.statsFor(bf.BitsSet(), true)
+}
 
-	return CacheStats{
-		BitCount:       bf.bitCount,
-		HashCount:      bf.hashCount,
-		BitsSet:        bitsSet,
-		LoadFactor:     float64(bitsSet) / float64(bf.bitCount),
-		EstimatedFPP:   bf.EstimatedFPP(),
-		CacheLineCount: bf.cacheLineCount,
-		CacheLineSize:  CacheLineSize,
-		MemoryUsage:    bf.cacheLineCount * CacheLineSize,
-		Alignment:      alignment,
+// statsFor assembles the statistics with bitsSet bits set. Without counted the
+// bit statistics are left zero and BitStatsSkipped is set.
+func (bf *CacheOptimizedBloomFilter) statsFor(bitsSet uint64, counted bool) CacheStats {
+	alignment := uintptr(unsafe.Pointer(&bf.cacheLines[0])) % CacheLineSize
+	stats := CacheStats{
+		BitCount:           bf.bitCount,
+		HashCount:          bf.hashCount,
+		BitStatsSkipped:    !counted,
+		ExpectedElements:   bf.expectedElements,
+		AchievedFPR:        bf.AchievedFPR(),
+		CacheLineCount:     bf.cacheLineCount,
+		CacheLineSize:      CacheLineSize,
+		MemoryUsage:        bf.cacheLineCount * CacheLineSize,
+		Alignment:          alignment,
+		DroppedAdds:        bf.dropped.Load(),
+		NewItems:           bf.newItems.Load(),
+		ProbableDuplicates: bf.duplicates.Load(),
+		Operations:         bf.OperationCounters(),
+		ContainsProbes:     bf.containsProbes(),
+		MeasuredFPR:        bf.FPRMeasurement(),
 		// SIMD capability information
 		HasAVX2:     simd.HasAVX2(),
 		HasAVX512:   simd.HasAVX512(),
 		HasNEON:     simd.HasNEON(),
-		SIMDEnabled: simd.HasAny(),
+		SIMDEnabled: bf.simdEnabled(),
+	}
+	if counted {
+		stats.BitsSet = bitsSet
+		stats.LoadFactor = float64(bitsSet) / float64(bf.bitCount)
+		stats.EstimatedFPP = bf.fppAt(bitsSet, bf.hashCount)
+		stats.EstimatedCount = bf.estimateCount(bitsSet)
+		stats.Work = EstimateWork(bf.cacheLineCount, bf.hashCount, stats.LoadFactor)
 	}
+	return stats
+}
+
+// BitCount returns the number of bits in the filter (a multiple of BitsPerCacheLine)
+func (bf *CacheOptimizedBloomFilter) BitCount() uint64 {
+	return bf.bitCount
+}
+
+// HashCount returns the number of hash functions (bit probes per element)
+func (bf *CacheOptimizedBloomFilter) HashCount() uint32 {
+	return bf.hashCount
 }
 
 // HasAVX2 returns true if AVX2 SIMD instructions are available
@@ -316,10 +576,12 @@ const (
 	AVX512VectorSize = 64 // 512-bit vectors = 64 bytes = 8 uint64
 	NEONVectorSize   = 16 // 128-bit vectors = 16 bytes = 2 uint64
 
-	// Threshold for choosing between array and map mode
-	// Arrays: ≤10K cache lines = ~5MB bloom filter = efficient for small/medium filters
-	// Maps: >10K cache lines = scalable for large filters (up to billions of elements)
-	// Memory overhead: Array mode ~240KB fixed, Map mode grows dynamically
+	// Threshold formerly used to choose between array and map storage modes.
+	//
+	// Deprecated: storage modes were removed. Every filter, including the
+	// generations built by AutoScalingFilter, uses a single aligned cache line
+	// array, so there is no mode to select or migrate between, and no pooled
+	// per-operation arrays sized by it.
 	ArrayModeThreshold = 10000
 )
 
@@ -328,6 +590,73 @@ type CacheLine struct {
 	words [WordsPerCacheLine]uint64
 }
 
+// hashPositions fills positions with the bit positions derived from h1 and h2
+// using the filter's probe scheme. len(positions) must equal hashCount.
+func (bf *CacheOptimizedBloomFilter) hashPositions(h1, h2 uint64, positions []uint64) {
+	hashPositions(h1, h2, bf.bitCount, bf.probe, positions)
+}
+
+// hashPositions fills positions with the bit positions of scheme in a bit array
+// of bitCount bits. Every filter type uses it so that all layouts agree.
+func hashPositions(h1, h2, bitCount uint64, scheme ProbeScheme, positions []uint64) {
+	if bitCount&(bitCount-1) == 0 {
+		hashPositionsMasked(h1, h2, bitCount-1, scheme, positions)
+		return
+	}
+	if scheme == DoubleHashing {
+		for i := range positions {
+			positions[i] = (h1 + uint64(i)*h2) % bitCount
+		}
+		return
+	}
+	// Enhanced double hashing: x_i = h1 + i*h2 + (i³-i)/6, computed incrementally
+	x, y := h1, h2
+	for i := range positions {
+		positions[i] = x % bitCount
+		x += y
+		y += uint64(i) + 1
+	}
+}
+
+// hashPositionsMasked is hashPositions for a power-of-two bit count, where
+// reducing with mask = bitCount-1 gives the same positions as the modulo
+// without a division per probe (see WithPowerOfTwoSize)
+func hashPositionsMasked(h1, h2, mask uint64, scheme ProbeScheme, positions []uint64) {
+	if scheme == DoubleHashing {
+		for i := range positions {
+			positions[i] = (h1 + uint64(i)*h2) & mask
+		}
+		return
+	}
+	x, y := h1, h2
+	for i := range positions {
+		positions[i] = x & mask
+		x += y
+		y += uint64(i) + 1
+	}
+}
+
+// setBits sets the bits of one or more keys so that none straddles a
+// ClearAndReset: it waits out a running quiesce, and sets the bits again if
+// one started while it was setting them. Bits set twice count twice in the
+// returned flips, which only overestimates a tracked count.
+func (bf *CacheOptimizedBloomFilter) setBits(positions []uint64) uint64 {
+	var flipped uint64
+	for {
+		epoch := bf.epoch.Load()
+		if epoch&1 != 0 {
+			// Block until the quiesce holding the lock is done
+			bf.clearMu.Lock()
+			bf.clearMu.Unlock()
+			continue
+		}
+		flipped += bf.setBitsAtomic(positions)
+		if bf.epoch.Load() == epoch {
+			return flipped
+		}
+	}
+}
+
 // setBitsAtomic sets multiple bits atomically using lock-free CAS operations.
 //
 // CORRECTNESS GUARANTEE: This function MUST successfully set all bits to maintain
@@ -349,7 +678,13 @@ type CacheLine struct {
 // PERFORMANCE: Benchmarks show this approach achieves 14M+ writes/sec with 50
 // concurrent goroutines without any backoff mechanism, indicating that contention
 // is naturally low due to the large bit array size.
-func (bf *CacheOptimizedBloomFilter) setBitsAtomic(positions []uint64) {
+//
+// Returns the number of bits that were newly set by this call. Failed CAS
+// attempts are counted when operation counting is on.
+func (bf *CacheOptimizedBloomFilter) setBitsAtomic(positions []uint64) uint64 {
+	bf.checkWritable()
+	changes := bf.changes.Load()
+	var flipped, retries uint64
 	for _, bitPos := range positions {
 		cacheLineIdx := bitPos / BitsPerCacheLine
 		wordIdx := (bitPos % BitsPerCacheLine) / 64
@@ -373,13 +708,24 @@ func (bf *CacheOptimizedBloomFilter) setBitsAtomic(positions []uint64) {
 
 			// Attempt to set the bit
 			if atomic.CompareAndSwapUint64(wordPtr, old, new) {
+				flipped++
+				if changes != nil {
+					changes.mark(cacheLineIdx)
+				}
 				break
 			}
 
 			// CAS failed, retry (another thread modified the word)
 			// No backoff needed - natural hash distribution provides low contention
+			retries++
+		}
+	}
+	if retries > 0 {
+		if c := bf.ops.Load(); c != nil {
+			c.casRetries.Add(retries)
 		}
 	}
+	return flipped
 }
 
 func (bf *CacheOptimizedBloomFilter) checkBitsAtomic(positions []uint64) bool {
@@ -47,7 +47,7 @@ func TestSIMDFunctions(t *testing.T) {
 	t.Logf("PopCount result: %d bits set", count1)
 
 	// Test Union (uses SIMD automatically)
-	bf2 := NewCacheOptimizedBloomFilter(1000, 0.01)
+	bf2, _ := NewWithOptions(WithExpectedElements(1000), WithSeed(bf.Seed()))
 	for i := 50; i < 150; i++ {
 		bf2.AddString(fmt.Sprintf("test_%d", i))
 	}
@@ -58,7 +58,7 @@ func TestSIMDFunctions(t *testing.T) {
 	}
 
 	// Test Intersection (uses SIMD automatically)
-	bf3 := NewCacheOptimizedBloomFilter(1000, 0.01)
+	bf3, _ := NewWithOptions(WithExpectedElements(1000), WithSeed(bf.Seed()))
 	for i := 0; i < 100; i++ {
 		bf3.AddString(fmt.Sprintf("test_%d", i))
 	}
@@ -2,6 +2,9 @@ package bloomfilter
 
 import (
 	"fmt"
+	"math"
+	"math/rand/v2"
+	"runtime"
 	"testing"
 )
 
@@ -167,7 +170,7 @@ func TestPopCount(t *testing.T) {
 // TestUnionOperation tests union of two bloom filters
 func TestUnionOperation(t *testing.T) {
 	bf1 := NewCacheOptimizedBloomFilter(1000, 0.01)
-	bf2 := NewCacheOptimizedBloomFilter(1000, 0.01)
+	bf2 := bf1.Clone()
 
 	// Add different data to each filter
 	set1 := []string{"apple", "banana", "cherry"}
@@ -199,7 +202,7 @@ func TestUnionOperation(t *testing.T) {
 // TestIntersectionOperation tests intersection of two bloom filters
 func TestIntersectionOperation(t *testing.T) {
 	bf1 := NewCacheOptimizedBloomFilter(1000, 0.01)
-	bf2 := NewCacheOptimizedBloomFilter(1000, 0.01)
+	bf2 := bf1.Clone()
 
 	// Add overlapping data to both filters
 	set1 := []string{"apple", "banana", "cherry", "shared1", "shared2"}
@@ -312,7 +315,7 @@ func TestSIMDFunctionality(t *testing.T) {
 	}
 
 	// Test SIMD operations with another filter
-	bf2 := NewCacheOptimizedBloomFilter(1000, 0.01)
+	bf2, _ := NewWithOptions(WithExpectedElements(1000), WithSeed(bf.Seed()))
 	bf2.AddString("simd6")
 	bf2.AddString("simd7")
 
@@ -368,3 +371,64 @@ func TestFalsePositiveRate(t *testing.T) {
 	t.Logf("False positive rate test: actual=%.4f%%, target=%.4f%%, elements=%d, tests=%d",
 		actualFPP*100, targetFPP*100, numElements, numTests)
 }
+
+// TestEstimatedCount tests the item count estimate from the fill ratio
+func TestEstimatedCount(t *testing.T) {
+	bf := NewCacheOptimizedBloomFilter(100000, 0.01)
+	if n := bf.EstimatedCount(); n != 0 {
+		t.Errorf("Empty filter: estimated %d items", n)
+	}
+
+	// Random keys: the estimate assumes uniformly spread probes
+	rng := rand.New(rand.NewPCG(1, 2))
+	var keys []uint64
+	for _, n := range []int{1000, 50000, 100000, 200000} {
+		for len(keys) < n {
+			keys = append(keys, rng.Uint64())
+			bf.AddUint64(keys[len(keys)-1])
+		}
+		// Duplicates set no new bits
+		for _, k := range keys[:1000] {
+			bf.AddUint64(k)
+		}
+		est := bf.EstimatedCount()
+		if relErr := math.Abs(float64(est)-float64(n)) / float64(n); relErr > 0.02 {
+			t.Errorf("%d items: estimated %d (%.2f%% off)", n, est, relErr*100)
+		}
+		if s := bf.GetCacheStats(); s.EstimatedCount != est {
+			t.Errorf("CacheStats.EstimatedCount %d, EstimatedCount() %d", s.EstimatedCount, est)
+		}
+	}
+
+	// A saturated filter reports a finite estimate
+	full := NewCacheOptimizedBloomFilter(10, 0.5)
+	for i := uint64(0); i < 100000; i++ {
+		full.AddUint64(i)
+	}
+	if full.PopCount() != full.BitCount() {
+		t.Fatal("Expected every bit set")
+	}
+	if n := full.EstimatedCount(); n == 0 || n == math.MaxUint64 {
+		t.Errorf("Saturated filter: estimated %d items", n)
+	}
+}
+
+// TestSmallFilterFootprint tests that a small filter allocates little beyond its
+// bits: there are no fixed-size per-filter or pooled operation arrays
+func TestSmallFilterFootprint(t *testing.T) {
+	const filters = 100
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+	keep := make([]*CacheOptimizedBloomFilter, filters)
+	for i := range keep {
+		keep[i] = NewCacheOptimizedBloomFilter(100, 0.01)
+		keep[i].AddString("key")
+	}
+	runtime.ReadMemStats(&after)
+
+	perFilter := (after.TotalAlloc - before.TotalAlloc) / filters
+	if bits := keep[0].GetCacheStats().MemoryUsage; perFilter > bits+1024 {
+		t.Errorf("Filter with %d bytes of bits allocated %d bytes", bits, perFilter)
+	}
+}
@@ -311,3 +311,42 @@ func TestInputValidation_PanicRecovery(t *testing.T) {
 		t.Fatal("Failed to create valid filter after panic tests")
 	}
 }
+
+// TestNewWithError verifies that NewWithError returns the panic messages of
+// NewCacheOptimizedBloomFilter as errors
+func TestNewWithError(t *testing.T) {
+	testCases := []struct {
+		elements uint64
+		fpr      float64
+		desc     string
+		contains string
+	}{
+		{0, 0.01, "zero elements", "expectedElements must be greater than 0"},
+		{1000, 0.0, "zero FPR", "must be in range (0, 1)"},
+		{1000, 1.0, "FPR = 1.0", "must be in range (0, 1)"},
+		{1000, math.NaN(), "NaN FPR", "cannot be NaN"},
+		{1, 0.9, "zero bits", "zero bits"},
+		{math.MaxUint64, 1e-9, "too large", "too many to allocate"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			bf, err := NewWithError(tc.elements, tc.fpr)
+			if err == nil || bf != nil {
+				t.Fatalf("Expected an error for %s, got filter %v", tc.desc, bf)
+			}
+			if !strings.Contains(err.Error(), tc.contains) {
+				t.Errorf("Expected %q in error, got: %v", tc.contains, err)
+			}
+		})
+	}
+
+	bf, err := NewWithError(1000, 0.01)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := NewCacheOptimizedBloomFilter(1000, 0.01)
+	if bf.BitCount() != want.BitCount() || bf.HashCount() != want.HashCount() {
+		t.Error("NewWithError sized the filter differently from NewCacheOptimizedBloomFilter")
+	}
+}
@@ -0,0 +1,23 @@
+package bloomfilter
+
+// ErrSeedMismatch is returned by Union and Intersection for filters with
+// different hash seeds, and narrows ErrIncompatibleHash. The same key sets
+// different bits in such filters, so combining their bits would produce
+// garbage; use UnionKeys to merge them.
+var ErrSeedMismatch error = &narrowError{"bloomfilter: filters have different hash seeds", ErrIncompatibleHash}
+
+// UnionKeys merges another filter into bf by re-inserting that filter's keys,
+// and returns the number of keys added. It is the bridge for filters that
+// cannot be merged bit-wise because their hash seeds (or geometries) differ:
+// keys is a KeySource for the other side, such as its MemoryKeyLog.
+//
+//	if err := a.Union(b); errors.Is(err, bloomfilter.ErrSeedMismatch) {
+//		a.UnionKeys(bKeys)
+//	}
+//
+// The result is exact: bf then contains every key of the other filter under its
+// own seed, with the false positive rate of a filter holding both key sets.
+// Keys are added in batches as in BuildFromSeq.
+func (bf *CacheOptimizedBloomFilter) UnionKeys(keys KeySource) uint64 {
+	return bf.addSeq(keys.Keys())
+}
@@ -0,0 +1,36 @@
+package bloomfilter
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// TestUnionKeysAcrossSeeds tests the re-insertion bridge for filters with different seeds
+func TestUnionKeysAcrossSeeds(t *testing.T) {
+	a := NewCacheOptimizedBloomFilter(2000, 0.01)
+	bKeys := NewMemoryKeyLog()
+	for i := 0; i < 1000; i++ {
+		a.AddString(fmt.Sprintf("a_%d", i))
+		bKeys.Record([]byte(fmt.Sprintf("b_%d", i)))
+	}
+	b := a.Reseeded(99, bKeys)
+
+	err := a.Union(b)
+	if !errors.Is(err, ErrSeedMismatch) || !strings.Contains(err.Error(), "UnionKeys") {
+		t.Fatalf("Union across seeds should fail pointing to UnionKeys, got %v", err)
+	}
+	if err := a.Intersection(b); !errors.Is(err, ErrSeedMismatch) {
+		t.Errorf("Intersection across seeds should fail with ErrSeedMismatch, got %v", err)
+	}
+
+	if n := a.UnionKeys(bKeys); n != 1000 {
+		t.Errorf("UnionKeys added %d keys, expected 1000", n)
+	}
+	for i := 0; i < 1000; i++ {
+		if !a.ContainsString(fmt.Sprintf("a_%d", i)) || !a.ContainsString(fmt.Sprintf("b_%d", i)) {
+			t.Fatalf("Key %d missing after UnionKeys", i)
+		}
+	}
+}
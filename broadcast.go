@@ -0,0 +1,184 @@
+package bloomfilter
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+)
+
+// BroadcasterConfig configures a Broadcaster
+type BroadcasterConfig struct {
+	// Replicas receive every add asynchronously. Use AsFilterE for in-memory filters.
+	Replicas []FilterE
+	// QueueSize bounds the adds buffered per replica. Defaults to 1024.
+	QueueSize int
+	// OnError, if set, is called from a replica's goroutine when an add fails
+	OnError func(replica int, err error)
+}
+
+// ReplicaStats reports the replication state of one replica
+type ReplicaStats struct {
+	Queued  int    // Adds waiting in the queue
+	Applied uint64 // Adds applied successfully
+	Dropped uint64 // Adds dropped because the queue was full
+	Errors  uint64 // Adds that failed
+}
+
+// Broadcaster applies every Add to a primary filter synchronously and to N
+// replica filters asynchronously, for serving tiers that keep read-mostly copies
+// (per availability zone, per NUMA node). Each replica has a bounded queue and
+// its own goroutine; when a replica falls behind, adds to it are dropped and
+// counted rather than slowing the primary down. Lookups are served by the primary.
+//
+// A replica that dropped or failed adds is missing keys and must be resynced
+// (for example with Union from the primary) before it can be trusted.
+type Broadcaster struct {
+	primary  Filter
+	replicas []*replica
+	onError  func(int, error)
+	closed   atomic.Bool
+	wg       sync.WaitGroup
+}
+
+type replica struct {
+	target  FilterE
+	queue   chan replicaOp
+	applied atomic.Uint64
+	dropped atomic.Uint64
+	errors  atomic.Uint64
+}
+
+// replicaOp is a key to add, or a flush or stop marker when data is nil
+type replicaOp struct {
+	data []byte
+	done chan struct{}
+	stop bool
+}
+
+// NewBroadcaster starts one goroutine per replica; call Close to stop them.
+// Panics if primary or a replica is nil, or QueueSize is negative.
+func NewBroadcaster(primary Filter, cfg BroadcasterConfig) *Broadcaster {
+	if primary == nil {
+		panic("bloomfilter: Broadcaster requires a primary filter")
+	}
+	if cfg.QueueSize < 0 {
+		panic(fmt.Sprintf("bloomfilter: QueueSize must not be negative, got %d", cfg.QueueSize))
+	}
+	if cfg.QueueSize == 0 {
+		cfg.QueueSize = 1024
+	}
+
+	b := &Broadcaster{primary: primary, onError: cfg.OnError}
+	for i, target := range cfg.Replicas {
+		if target == nil {
+			panic(fmt.Sprintf("bloomfilter: replica %d is nil", i))
+		}
+		r := &replica{target: target, queue: make(chan replicaOp, cfg.QueueSize)}
+		b.replicas = append(b.replicas, r)
+		b.wg.Add(1)
+		go b.run(i, r)
+	}
+	return b
+}
+
+// run applies queued adds to one replica until stopped
+func (b *Broadcaster) run(i int, r *replica) {
+	defer b.wg.Done()
+	for op := range r.queue {
+		switch {
+		case op.stop:
+			return
+		case op.done != nil:
+			close(op.done)
+		default:
+			if err := r.target.AddE(op.data); err != nil {
+				r.errors.Add(1)
+				if b.onError != nil {
+					b.onError(i, err)
+				}
+			} else {
+				r.applied.Add(1)
+			}
+		}
+	}
+}
+
+// Add adds data to the primary and enqueues it for every replica. The data is
+// copied once and shared by the replicas. After Close only the primary is updated.
+func (b *Broadcaster) Add(data []byte) {
+	b.primary.Add(data)
+	if len(b.replicas) == 0 || b.closed.Load() {
+		return
+	}
+
+	op := replicaOp{data: append([]byte(nil), data...)}
+	for _, r := range b.replicas {
+		select {
+		case r.queue <- op:
+		default:
+			r.dropped.Add(1)
+		}
+	}
+}
+
+// Contains checks membership in the primary
+func (b *Broadcaster) Contains(data []byte) bool {
+	return b.primary.Contains(data)
+}
+
+// Flush blocks until every add enqueued before the call has been applied or
+// has failed. It must not be called after Close.
+func (b *Broadcaster) Flush() {
+	dones := make([]chan struct{}, len(b.replicas))
+	for i, r := range b.replicas {
+		dones[i] = make(chan struct{})
+		r.queue <- replicaOp{done: dones[i]}
+	}
+	for _, done := range dones {
+		<-done
+	}
+}
+
+// Close applies the queued adds and stops the replica goroutines. Adds that race
+// with Close may not reach the replicas. Calling Close more than once is a no-op.
+func (b *Broadcaster) Close() error {
+	if b.closed.Swap(true) {
+		return nil
+	}
+	for _, r := range b.replicas {
+		r.queue <- replicaOp{stop: true}
+	}
+	b.wg.Wait()
+	return nil
+}
+
+// Stats returns the replication state of every replica, in configuration order
+func (b *Broadcaster) Stats() []ReplicaStats {
+	stats := make([]ReplicaStats, len(b.replicas))
+	for i, r := range b.replicas {
+		stats[i] = ReplicaStats{
+			Queued:  len(r.queue),
+			Applied: r.applied.Load(),
+			Dropped: r.dropped.Load(),
+			Errors:  r.errors.Load(),
+		}
+	}
+	return stats
+}
+
+// Primary returns the primary filter
+func (b *Broadcaster) Primary() Filter {
+	return b.primary
+}
+
+// AsFilterE adapts an infallible Filter, such as an in-memory filter, to FilterE
+func AsFilterE(f Filter) FilterE {
+	return infallible{f}
+}
+
+type infallible struct{ f Filter }
+
+func (i infallible) AddE(data []byte) error              { i.f.Add(data); return nil }
+func (i infallible) ContainsE(data []byte) (bool, error) { return i.f.Contains(data), nil }
+
+var _ Filter = (*Broadcaster)(nil)
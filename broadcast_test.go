@@ -0,0 +1,117 @@
+package bloomfilter
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+)
+
+// TestBroadcasterReplicates tests that adds reach local and storage-backed replicas
+func TestBroadcasterReplicates(t *testing.T) {
+	primary := NewCacheOptimizedBloomFilter(1000, 0.01)
+	local := NewCacheOptimizedBloomFilter(1000, 0.01)
+	storage, err := OpenFileStorage(filepath.Join(t.TempDir(), "replica.bits"), primary.BitCount())
+	if err != nil {
+		t.Fatal(err)
+	}
+	remote, _ := NewStorageFilter(storage, primary.HashCount())
+	defer remote.Close()
+
+	b := NewBroadcaster(primary, BroadcasterConfig{
+		Replicas:  []FilterE{AsFilterE(local), remote},
+		QueueSize: 2000,
+	})
+	for i := 0; i < 1000; i++ {
+		b.Add([]byte(fmt.Sprintf("key_%d", i)))
+	}
+	b.Flush()
+
+	for i := 0; i < 1000; i++ {
+		key := []byte(fmt.Sprintf("key_%d", i))
+		if !b.Contains(key) || !local.Contains(key) {
+			t.Fatalf("%s missing from the primary or local replica", key)
+		}
+		if ok, err := remote.ContainsE(key); err != nil || !ok {
+			t.Fatalf("%s missing from the storage replica: %v", key, err)
+		}
+	}
+	for i, s := range b.Stats() {
+		if s.Applied != 1000 || s.Dropped != 0 || s.Errors != 0 || s.Queued != 0 {
+			t.Errorf("Replica %d stats: %+v", i, s)
+		}
+	}
+	if err := b.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// After Close only the primary is updated
+	b.Add([]byte("late"))
+	if !primary.Contains([]byte("late")) {
+		t.Error("Add after Close did not reach the primary")
+	}
+}
+
+// blockingReplica blocks every add until released
+type blockingReplica struct {
+	release chan struct{}
+	err     error
+}
+
+func (r *blockingReplica) AddE([]byte) error {
+	<-r.release
+	return r.err
+}
+
+func (r *blockingReplica) ContainsE([]byte) (bool, error) { return false, r.err }
+
+// TestBroadcasterDropsWhenFull tests that a slow replica drops adds instead of
+// blocking the primary, and that replica errors are counted
+func TestBroadcasterDropsWhenFull(t *testing.T) {
+	slow := &blockingReplica{release: make(chan struct{}), err: errors.New("unavailable")}
+	var reported atomic.Int64
+	b := NewBroadcaster(NewCacheOptimizedBloomFilter(1000, 0.01), BroadcasterConfig{
+		Replicas:  []FilterE{slow},
+		QueueSize: 4,
+		OnError:   func(int, error) { reported.Add(1) },
+	})
+
+	for i := 0; i < 100; i++ {
+		b.Add([]byte{byte(i)})
+	}
+	close(slow.release)
+	b.Flush()
+
+	s := b.Stats()[0]
+	if s.Dropped == 0 || s.Dropped+s.Errors != 100 {
+		t.Errorf("Expected every add to be dropped or failed, got %+v", s)
+	}
+	if s.Applied != 0 || uint64(reported.Load()) != s.Errors {
+		t.Errorf("Stats %+v, OnError called %d times", s, reported.Load())
+	}
+	b.Close()
+}
+
+// TestBroadcasterValidation tests constructor panics
+func TestBroadcasterValidation(t *testing.T) {
+	for name, fn := range map[string]func(){
+		"nil primary": func() { NewBroadcaster(nil, BroadcasterConfig{}) },
+		"nil replica": func() {
+			NewBroadcaster(NewCacheOptimizedBloomFilter(10, 0.01), BroadcasterConfig{Replicas: []FilterE{nil}})
+		},
+		"negative queue": func() { NewBroadcaster(NewCacheOptimizedBloomFilter(10, 0.01), BroadcasterConfig{QueueSize: -1}) },
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%s: expected panic", name)
+				}
+			}()
+			fn()
+		}()
+	}
+}
@@ -0,0 +1,120 @@
+package bloomfilter
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"iter"
+)
+
+// BuildFromReader creates a filter sized for n keys at falsePositiveRate, with
+// any further options of NewWithOptions applied, and adds every key read from r.
+// Keys are separated by delim; with '\n' a trailing '\r' is dropped, so files
+// with either line ending load the same keys. Empty keys are skipped. Keys are
+// read through one buffer and added in batches of AddBatch, without allocating
+// per key.
+//
+// Returns an error if the options are invalid or reading r fails.
+func BuildFromReader(r io.Reader, delim byte, n uint64, falsePositiveRate float64, opts ...Option) (*CacheOptimizedBloomFilter, error) {
+	bf, err := newBuildFilter(n, falsePositiveRate, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	br := bufio.NewReaderSize(r, 64<<10)
+	var b keyBatcher
+	var partial []byte // start of a key longer than the read buffer
+	for {
+		line, err := br.ReadSlice(delim)
+		if errors.Is(err, bufio.ErrBufferFull) {
+			partial = append(partial, line...)
+			continue
+		}
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+		key := line
+		if len(partial) > 0 {
+			// The batcher copies key before partial is reused
+			key = append(partial, line...)
+			partial = partial[:0]
+		}
+		if len(key) > 0 && key[len(key)-1] == delim {
+			key = key[:len(key)-1]
+			if delim == '\n' && len(key) > 0 && key[len(key)-1] == '\r' {
+				key = key[:len(key)-1]
+			}
+		}
+		if len(key) > 0 {
+			b.add(bf, key)
+		}
+		if err == io.EOF {
+			break
+		}
+	}
+	b.flush(bf)
+	return bf, nil
+}
+
+// BuildFromSeq creates a filter sized for n keys at falsePositiveRate, with any
+// further options of NewWithOptions applied, and adds every key of keys. Keys
+// are copied, so the sequence may reuse its slices, and added in batches of
+// AddBatch.
+//
+// Returns an error if the options are invalid.
+func BuildFromSeq(keys iter.Seq[[]byte], n uint64, falsePositiveRate float64, opts ...Option) (*CacheOptimizedBloomFilter, error) {
+	bf, err := newBuildFilter(n, falsePositiveRate, opts)
+	if err != nil {
+		return nil, err
+	}
+	bf.addSeq(keys)
+	return bf, nil
+}
+
+// addSeq adds every key of keys in batches of AddBatch and returns how many
+func (bf *CacheOptimizedBloomFilter) addSeq(keys iter.Seq[[]byte]) uint64 {
+	var b keyBatcher
+	var n uint64
+	for key := range keys {
+		b.add(bf, key)
+		n++
+	}
+	b.flush(bf)
+	return n
+}
+
+// newBuildFilter creates the filter of the Build constructors
+func newBuildFilter(n uint64, falsePositiveRate float64, opts []Option) (*CacheOptimizedBloomFilter, error) {
+	sizing := []Option{WithExpectedElements(n), WithFalsePositiveRate(falsePositiveRate)}
+	return NewWithOptions(append(sizing, opts...)...)
+}
+
+// keyBatcher copies keys into a reused arena and adds them batchKeys at a time
+type keyBatcher struct {
+	arena []byte
+	ends  [batchKeys]int
+	keys  [batchKeys][]byte
+	n     int
+}
+
+func (b *keyBatcher) add(bf *CacheOptimizedBloomFilter, key []byte) {
+	b.arena = append(b.arena, key...)
+	b.ends[b.n] = len(b.arena)
+	b.n++
+	if b.n == batchKeys {
+		b.flush(bf)
+	}
+}
+
+// flush adds the batched keys. Slices are taken only now, since the arena may
+// have moved while it grew.
+func (b *keyBatcher) flush(bf *CacheOptimizedBloomFilter) {
+	start := 0
+	for i, end := range b.ends[:b.n] {
+		b.keys[i] = b.arena[start:end]
+		start = end
+	}
+	bf.AddBatch(b.keys[:b.n])
+	b.arena = b.arena[:0]
+	b.n = 0
+}
@@ -0,0 +1,101 @@
+package bloomfilter
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+// TestBuildFromReader tests delimiters, line endings, empty and long keys
+func TestBuildFromReader(t *testing.T) {
+	var input strings.Builder
+	var keys []string
+	for i := 0; i < 1000; i++ {
+		keys = append(keys, fmt.Sprintf("key-%d", i))
+	}
+	long := strings.Repeat("x", 200<<10) // longer than the read buffer
+	keys = append(keys, long)
+	for i, k := range keys {
+		input.WriteString(k)
+		if i%2 == 0 {
+			input.WriteString("\r\n")
+		} else {
+			input.WriteString("\n\n") // an empty key between
+		}
+	}
+	input.WriteString("last") // no trailing delimiter
+	keys = append(keys, "last")
+
+	bf, err := BuildFromReader(strings.NewReader(input.String()), '\n', uint64(len(keys)), 0.01, WithSeed(5))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, _ := NewWithOptions(WithExpectedElements(uint64(len(keys))), WithSeed(5))
+	want.AddStrings(keys)
+	if !bf.Equal(want) {
+		t.Error("Built filter differs from adding the keys")
+	}
+
+	// Other delimiters keep '\r'
+	bf, _ = BuildFromReader(strings.NewReader("a\r,b"), ',', 10, 0.01)
+	if !bf.ContainsString("a\r") || bf.ContainsString("a") {
+		t.Error("'\\r' dropped with a delimiter other than newline")
+	}
+}
+
+// TestBuildFromReaderErrors tests sizing and read errors
+func TestBuildFromReaderErrors(t *testing.T) {
+	if _, err := BuildFromReader(strings.NewReader("a"), '\n', 0, 0.01); err == nil {
+		t.Error("Expected an error for zero keys")
+	}
+	errRead := errors.New("read failed")
+	r := iotest.TimeoutReader(bytes.NewReader([]byte("a\nb\n")))
+	if _, err := BuildFromReader(r, '\n', 10, 0.01); err == nil {
+		t.Error("Expected the read error")
+	}
+	if _, err := BuildFromReader(iotest.ErrReader(errRead), '\n', 10, 0.01); !errors.Is(err, errRead) {
+		t.Errorf("got %v, want the read error", err)
+	}
+}
+
+// TestBuildFromSeq tests that reused slices from the sequence are copied
+func TestBuildFromSeq(t *testing.T) {
+	keys := make([]string, 100)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("key-%d", i)
+	}
+	seq := func(yield func([]byte) bool) {
+		buf := make([]byte, 0, 16)
+		for _, k := range keys {
+			buf = append(buf[:0], k...)
+			if !yield(buf) {
+				return
+			}
+		}
+	}
+	bf, err := BuildFromSeq(seq, 100, 0.01)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(bf.ContainsStrings(keys), slices.Repeat([]bool{true}, len(keys))) {
+		t.Error("Built filter is missing keys")
+	}
+	if _, err := BuildFromSeq(seq, 100, 1.5); err == nil {
+		t.Error("Expected an error for an invalid rate")
+	}
+}
+
+// TestBuildFromReaderAllocations tests that reading does not allocate per key
+func TestBuildFromReaderAllocations(t *testing.T) {
+	input := strings.Repeat("some-key-value\n", 10000)
+	allocs := testing.AllocsPerRun(5, func() {
+		BuildFromReader(strings.NewReader(input), '\n', 10000, 0.01)
+	})
+	if allocs > 50 {
+		t.Errorf("Building from 10000 keys allocated %.0f times", allocs)
+	}
+}
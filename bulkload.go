@@ -0,0 +1,88 @@
+package bloomfilter
+
+import (
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+// BulkLoadOption configures BulkLoad
+type BulkLoadOption func(*bulkLoadOptions)
+
+type bulkLoadOptions struct {
+	sharded bool
+}
+
+// ShardedBulkLoad makes each BulkLoad worker fill a private filter of the same
+// geometry, merged into the target with SIMD ORs once the channel is drained.
+// Workers then never contend on the target's words, at the cost of one extra
+// filter of memory per worker. Keys loaded this way bypass the features that
+// observe individual adds (FPR sampling, the key reservoir, recording, add and
+// operation counting), and load shedding is not applied.
+func ShardedBulkLoad() BulkLoadOption {
+	return func(o *bulkLoadOptions) {
+		o.sharded = true
+	}
+}
+
+// BulkLoad adds every key received from keys using workers goroutines, which
+// hash and add keys in batches of AddBatch, and returns the number of keys
+// read once keys is closed. workers <= 0 uses GOMAXPROCS. Keys are held until
+// their batch is added, so a sender must not modify a key after sending it.
+//
+// Adds go through the filter's atomic bit setters, so the filter may be
+// queried while loading; with ShardedBulkLoad the keys become visible only
+// when BulkLoad returns.
+func (bf *CacheOptimizedBloomFilter) BulkLoad(keys <-chan []byte, workers int, opts ...BulkLoadOption) uint64 {
+	bf.checkWritable()
+	var o bulkLoadOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+
+	targets := make([]*CacheOptimizedBloomFilter, workers)
+	for i := range targets {
+		targets[i] = bf
+		if o.sharded {
+			targets[i] = newFilter(bf.cacheLineCount, bf.hashCount)
+			targets[i].seed = bf.seed
+			targets[i].probe = bf.probe
+			targets[i].hashVersion = bf.hashVersion
+			targets[i].hasher = bf.hasher
+			targets[i].simdOps = bf.simdOps
+		}
+	}
+
+	var loaded atomic.Uint64
+	var wg sync.WaitGroup
+	for _, target := range targets {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var n uint64
+			batch := make([][]byte, 0, batchKeys)
+			for key := range keys {
+				batch = append(batch, key)
+				if len(batch) == batchKeys {
+					target.AddBatch(batch)
+					n += batchKeys
+					batch = batch[:0]
+				}
+			}
+			target.AddBatch(batch)
+			loaded.Add(n + uint64(len(batch)))
+		}()
+	}
+	wg.Wait()
+
+	if o.sharded {
+		for _, shard := range targets {
+			// Same geometry, seed, probe scheme and hash version by construction
+			_ = bf.Union(shard)
+		}
+	}
+	return loaded.Load()
+}
@@ -0,0 +1,58 @@
+package bloomfilter
+
+import (
+	"fmt"
+	"testing"
+)
+
+// sendKeys returns a channel carrying n keys, closed after the last
+func sendKeys(n int) <-chan []byte {
+	ch := make(chan []byte, 256)
+	go func() {
+		for i := 0; i < n; i++ {
+			ch <- []byte(fmt.Sprintf("key-%d", i))
+		}
+		close(ch)
+	}()
+	return ch
+}
+
+// TestBulkLoad tests that both loading modes set the same bits as sequential adds
+func TestBulkLoad(t *testing.T) {
+	const n = 50_000
+	want, _ := NewWithOptions(WithExpectedElements(n), WithSeed(3))
+	for i := 0; i < n; i++ {
+		want.AddString(fmt.Sprintf("key-%d", i))
+	}
+
+	for _, tc := range []struct {
+		name    string
+		workers int
+		opts    []BulkLoadOption
+	}{
+		{"Shared", 4, nil},
+		{"DefaultWorkers", 0, nil},
+		{"Sharded", 4, []BulkLoadOption{ShardedBulkLoad()}},
+		{"OneWorker", 1, []BulkLoadOption{ShardedBulkLoad()}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			bf, _ := NewWithOptions(WithExpectedElements(n), WithSeed(3))
+			if loaded := bf.BulkLoad(sendKeys(n), tc.workers, tc.opts...); loaded != n {
+				t.Errorf("BulkLoad = %d, want %d", loaded, n)
+			}
+			if !bf.Equal(want) {
+				t.Error("Bulk loaded filter differs from sequential adds")
+			}
+		})
+	}
+}
+
+// TestBulkLoadTracked tests that sharded loading keeps the tracked bit count exact
+func TestBulkLoadTracked(t *testing.T) {
+	bf := NewCacheOptimizedBloomFilter(10_000, 0.01)
+	bf.EnableFillTracking()
+	bf.BulkLoad(sendKeys(5000), 3, ShardedBulkLoad())
+	if got, want := bf.BitsSet(), bf.PopCount(); got != want {
+		t.Errorf("Tracked %d bits, PopCount %d", got, want)
+	}
+}
@@ -0,0 +1,87 @@
+package bloomfilter
+
+import (
+	"testing"
+	"time"
+)
+
+// TestManualClock tests deterministic time and ticker delivery
+func TestManualClock(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := NewManualClock(start)
+	tk := c.NewTicker(time.Second)
+
+	select {
+	case <-tk.C():
+		t.Fatal("Ticker fired before time advanced")
+	default:
+	}
+
+	c.Advance(999 * time.Millisecond)
+	select {
+	case <-tk.C():
+		t.Fatal("Ticker fired early")
+	default:
+	}
+
+	c.Advance(time.Millisecond)
+	if got := <-tk.C(); !got.Equal(start.Add(time.Second)) {
+		t.Errorf("Tick at %v, expected %v", got, start.Add(time.Second))
+	}
+
+	// Undelivered ticks are dropped, like time.Ticker
+	c.Advance(5 * time.Second)
+	if got := <-tk.C(); !got.Equal(start.Add(2 * time.Second)) {
+		t.Errorf("Tick at %v, expected the first pending tick", got)
+	}
+	select {
+	case <-tk.C():
+		t.Error("Expected dropped ticks")
+	default:
+	}
+	if !c.Now().Equal(start.Add(6 * time.Second)) {
+		t.Errorf("Now = %v", c.Now())
+	}
+
+	tk.Stop()
+	c.Advance(time.Hour)
+	select {
+	case <-tk.C():
+		t.Error("Stopped ticker fired")
+	default:
+	}
+}
+
+// TestSystemClock tests the default clock
+func TestSystemClock(t *testing.T) {
+	c := clockOrSystem(nil)
+	if d := time.Since(c.Now()); d < 0 || d > time.Minute {
+		t.Errorf("SystemClock.Now is off by %v", d)
+	}
+	tk := c.NewTicker(time.Millisecond)
+	defer tk.Stop()
+	<-tk.C()
+}
+
+// TestAutoScalingClock tests that rebuild durations come from the configured clock
+func TestAutoScalingClock(t *testing.T) {
+	clock := NewManualClock(time.Unix(0, 0))
+	var event RebuildEvent
+	a, err := NewAutoScalingFilter(AutoScalingConfig{
+		ExpectedElements:  100,
+		FalsePositiveRate: 0.01,
+		Keys:              NewMemoryKeyLog(),
+		Clock:             clock,
+		OnRebuild:         func(e RebuildEvent) { event = e },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := uint64(0); a.Stats().Rebuilds == 0; i++ {
+		a.Add([]byte{byte(i), byte(i >> 8)})
+		a.Wait()
+	}
+	if event.Duration != 0 || event.NewCapacity != 200 {
+		t.Errorf("Unexpected rebuild event with a stopped clock: %+v", event)
+	}
+}
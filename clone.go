@@ -0,0 +1,55 @@
+package bloomfilter
+
+import "unsafe"
+
+// Clone returns an independent copy of the filter with the same bits, geometry,
+// seed, probe scheme, hash version, hasher and settings: SIMD choice, load
+// shedding limit, add counting and adaptive probing. Statistics counters of the
+// copy start at zero. Features that observe the original's stream (FPR
+// sampling, the key reservoir, recording, operation counts, change tracking)
+// and saturation callbacks are not copied, and a clone never belongs to a
+// FilterPool.
+//
+// The bits are copied with the SIMD copy kernel. The copy is not atomic: with
+// adds running concurrently it holds every add that completed before Clone was
+// called and possibly some of the concurrent ones.
+func (bf *CacheOptimizedBloomFilter) Clone() *CacheOptimizedBloomFilter {
+	c := newFilter(bf.cacheLineCount, bf.hashCount)
+	c.seed = bf.seed
+	c.probe = bf.probe
+	c.hashVersion = bf.hashVersion
+	c.hasher = bf.hasher
+	c.expectedElements = bf.expectedElements
+	c.simdOps = bf.simdOps
+	if bf.cacheLineCount > 0 {
+		bf.simdOps.VectorCopy(
+			unsafe.Pointer(&c.cacheLines[0]),
+			unsafe.Pointer(&bf.cacheLines[0]),
+			int(bf.cacheLineCount*CacheLineSize),
+		)
+	}
+
+	if bf.trackBits.Load() {
+		c.enableBitTracking()
+	}
+	c.maxLoadBits.Store(bf.maxLoadBits.Load())
+	c.countAdds.Store(bf.countAdds.Load())
+	if a := bf.adaptive.Load(); a != nil {
+		// The thresholds are immutable and depend only on the geometry
+		c.adaptive.Store(&adaptiveProbing{maxIncrease: a.maxIncrease, minBits: a.minBits})
+	}
+	return c
+}
+
+// Snapshot returns a Clone taken with adds paused, for analytics that need
+// PopCount, EstimatedFPP and Contains to agree on one state of a filter that
+// is being written. Adds arriving during the copy wait for it, for as long as
+// one SIMD copy of the bits takes. The
+// snapshot holds every add that returned before Snapshot was called; adds
+// still setting bits at that moment may be in it in part. Union and the
+// word-level writes are not paused.
+func (bf *CacheOptimizedBloomFilter) Snapshot() *CacheOptimizedBloomFilter {
+	var c *CacheOptimizedBloomFilter
+	bf.quiesce(func() { c = bf.Clone() })
+	return c
+}
@@ -0,0 +1,126 @@
+package bloomfilter
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestClone tests that a clone is equal to, but independent of, the original
+func TestClone(t *testing.T) {
+	for _, simdDisabled := range []bool{false, true} {
+		t.Run(fmt.Sprintf("SIMDDisabled_%t", simdDisabled), func(t *testing.T) {
+			opts := []Option{WithExpectedElements(10000), WithSeed(99)}
+			if simdDisabled {
+				opts = append(opts, WithSIMDDisabled())
+			}
+			bf, _ := NewWithOptions(opts...)
+			for i := 0; i < 5000; i++ {
+				bf.AddUint64(uint64(i))
+			}
+
+			c := bf.Clone()
+			if !c.Equal(bf) || c.Seed() != bf.Seed() || c.simdEnabled() != bf.simdEnabled() {
+				t.Fatal("Clone differs from the original")
+			}
+
+			// A destructive operation on the original leaves the snapshot intact
+			other, _ := NewWithOptions(opts...)
+			if err := bf.Intersection(other); err != nil {
+				t.Fatal(err)
+			}
+			for i := 0; i < 5000; i++ {
+				if !c.ContainsUint64(uint64(i)) {
+					t.Fatalf("Clone lost key %d after Intersection on the original", i)
+				}
+			}
+			c.AddString("only in clone")
+			if bf.ContainsString("only in clone") {
+				t.Error("Add to the clone reached the original")
+			}
+		})
+	}
+}
+
+// TestCloneSettings tests which settings a clone keeps
+func TestCloneSettings(t *testing.T) {
+	bf := NewCacheOptimizedBloomFilter(1000, 0.01)
+	bf.SetMaxLoadFactor(0.5)
+	bf.EnableAddCounting()
+	bf.EnableAdaptiveProbing(0.1)
+	bf.EnableFPRSampling(1, 100)
+	for i := 0; i < 500; i++ {
+		bf.AddUint64(uint64(i))
+	}
+
+	c := bf.Clone()
+	cs, bs := c.GetCacheStats(), bf.GetCacheStats()
+	if cs.BitsSet != bs.BitsSet {
+		t.Errorf("BitsSet: clone %d, original %d", cs.BitsSet, bs.BitsSet)
+	}
+	if cs.NewItems != 0 || cs.ProbableDuplicates != 0 {
+		t.Errorf("Clone counters must start at zero: %+v", cs)
+	}
+	if c.maxLoadBits.Load() != bf.maxLoadBits.Load() || !c.countAdds.Load() {
+		t.Error("Clone lost load shedding or add counting")
+	}
+	if a := c.AdaptiveProbing(); !a.Enabled || a.MaxFPRIncrease != 0.1 {
+		t.Errorf("Clone lost adaptive probing: %+v", a)
+	}
+	if c.ghost.Load() != nil {
+		t.Error("FPR sampling must not be copied")
+	}
+
+	c.AddUint64(1_000_000)
+	if c.NewItems() != 1 || bf.NewItems() != 500 {
+		t.Errorf("Counters are not independent: clone %d, original %d", c.NewItems(), bf.NewItems())
+	}
+}
+
+// TestSnapshot tests that a snapshot taken during adds is a stable copy holding
+// every earlier add
+func TestSnapshot(t *testing.T) {
+	bf := NewCacheOptimizedBloomFilter(100_000, 0.01)
+	bf.EnableFillTracking()
+	for i := uint64(0); i < 1000; i++ {
+		bf.AddUint64(i)
+	}
+
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	for w := 0; w < 4; w++ {
+		wg.Add(1)
+		go func(w uint64) {
+			defer wg.Done()
+			for i := uint64(0); ; i++ {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				bf.AddUint64(1<<40 | w<<32 | i)
+			}
+		}(uint64(w))
+	}
+	time.Sleep(time.Millisecond)
+	snap := bf.Snapshot()
+	close(stop)
+	wg.Wait()
+
+	for i := uint64(0); i < 1000; i++ {
+		if !snap.ContainsUint64(i) {
+			t.Fatalf("Snapshot is missing key %d", i)
+		}
+	}
+	bits := snap.PopCount()
+	if snap.BitsSet() != bits {
+		t.Errorf("Snapshot tracked %d bits, holds %d", snap.BitsSet(), bits)
+	}
+	if bf.PopCount() < bits {
+		t.Errorf("Snapshot has %d bits, more than the filter's %d", bits, bf.PopCount())
+	}
+	if bf.epoch.Load()&1 != 0 {
+		t.Error("Epoch left odd")
+	}
+}
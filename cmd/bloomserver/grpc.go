@@ -0,0 +1,287 @@
+package main
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// grpcService is the full name of the service in bloomfilter.proto
+const grpcService = "bloomfilter.v1.BloomFilter"
+
+// gRPC status codes
+const (
+	codeOK                = 0
+	codeInvalidArgument   = 3
+	codeNotFound          = 5
+	codeAlreadyExists     = 6
+	codeResourceExhausted = 8
+	codeUnimplemented     = 12
+	codeInternal          = 13
+	codeUnavailable       = 14
+)
+
+// grpcError is an error with its gRPC status code
+type grpcError struct {
+	code int
+	msg  string
+}
+
+func (e *grpcError) Error() string { return e.msg }
+
+// grpcMethod decodes a request message, runs it and encodes the response
+type grpcMethod func(s *server, ctx context.Context, msg []byte) ([]byte, error)
+
+var grpcMethods = map[string]grpcMethod{
+	"Create":    (*server).grpcCreate,
+	"Drop":      (*server).grpcDrop,
+	"Add":       (*server).grpcAdd,
+	"Check":     (*server).grpcCheck,
+	"BulkAdd":   (*server).grpcBulkAdd,
+	"BulkCheck": (*server).grpcBulkCheck,
+	"Stats":     (*server).grpcStats,
+}
+
+// serveGRPC serves the unary methods of the gRPC service. gRPC runs over
+// HTTP/2, which net/http serves over TLS; the handler refuses HTTP/1 requests.
+// Messages are not compressed, and the server does not advertise any
+// grpc-accept-encoding, so clients send them uncompressed as well.
+func (s *server) serveGRPC(w http.ResponseWriter, r *http.Request) {
+	if r.ProtoMajor != 2 || !strings.HasPrefix(r.Header.Get("Content-Type"), "application/grpc") {
+		writeError(w, http.StatusUnsupportedMediaType, errors.New("gRPC needs HTTP/2 and an application/grpc content type"))
+		return
+	}
+	w.Header().Set("Content-Type", "application/grpc")
+	w.WriteHeader(http.StatusOK)
+
+	method, ok := grpcMethods[r.PathValue("method")]
+	if !ok {
+		writeGRPCStatus(w, &grpcError{codeUnimplemented, fmt.Sprintf("unknown method %s", r.PathValue("method"))})
+		return
+	}
+	msg, err := readGRPCMessage(r.Body)
+	if err != nil {
+		writeGRPCStatus(w, err)
+		return
+	}
+	resp, err := method(s, r.Context(), msg)
+	if err != nil {
+		writeGRPCStatus(w, err)
+		return
+	}
+	var prefix [5]byte
+	binary.BigEndian.PutUint32(prefix[1:], uint32(len(resp)))
+	w.Write(prefix[:])
+	w.Write(resp)
+	writeGRPCStatus(w, nil)
+}
+
+// readGRPCMessage reads the single length-prefixed message of a unary call
+func readGRPCMessage(r io.Reader) ([]byte, error) {
+	var prefix [5]byte
+	if _, err := io.ReadFull(r, prefix[:]); err != nil {
+		return nil, &grpcError{codeInvalidArgument, "missing request message"}
+	}
+	if prefix[0] != 0 {
+		return nil, &grpcError{codeUnimplemented, "compressed messages are not supported"}
+	}
+	size := binary.BigEndian.Uint32(prefix[1:])
+	if size > maxBodyBytes {
+		return nil, &grpcError{codeResourceExhausted, fmt.Sprintf("message of %d bytes exceeds %d", size, maxBodyBytes)}
+	}
+	msg := make([]byte, size)
+	if _, err := io.ReadFull(r, msg); err != nil {
+		return nil, &grpcError{codeInvalidArgument, "truncated request message"}
+	}
+	return msg, nil
+}
+
+// writeGRPCStatus sends the status of err, nil for OK, in the trailers
+func writeGRPCStatus(w http.ResponseWriter, err error) {
+	code, msg := codeOK, ""
+	if err != nil {
+		code, msg = grpcCode(err), err.Error()
+	}
+	w.Header().Set(http.TrailerPrefix+"Grpc-Status", strconv.Itoa(code))
+	if msg != "" {
+		w.Header().Set(http.TrailerPrefix+"Grpc-Message", percentEncode(msg))
+	}
+}
+
+// grpcCode maps an error to its gRPC status code
+func grpcCode(err error) int {
+	var ge *grpcError
+	if errors.As(err, &ge) {
+		return ge.code
+	}
+	if errors.Is(err, errMalformed) {
+		return codeInvalidArgument
+	}
+	var se *statusError
+	if errors.As(err, &se) {
+		switch se.status {
+		case http.StatusBadRequest:
+			return codeInvalidArgument
+		case http.StatusNotFound:
+			return codeNotFound
+		case http.StatusConflict:
+			return codeAlreadyExists
+		case http.StatusRequestEntityTooLarge:
+			return codeResourceExhausted
+		case http.StatusServiceUnavailable:
+			return codeUnavailable
+		}
+	}
+	return codeInternal
+}
+
+// percentEncode encodes a grpc-message: bytes outside printable ASCII, and %,
+// as %XX
+func percentEncode(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if c := s[i]; c < 0x20 || c > 0x7e || c == '%' {
+			fmt.Fprintf(&b, "%%%02X", c)
+		} else {
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+// nameAndKeys decodes the name = 1 and bytes key(s) = 2 fields shared by the
+// filter requests
+func nameAndKeys(msg []byte) (name string, keys [][]byte, err error) {
+	err = readFields(msg, func(f protoField) error {
+		switch {
+		case f.num == 1 && f.wire == wireBytes:
+			name = string(f.bytes)
+		case f.num == 2 && f.wire == wireBytes:
+			keys = append(keys, f.bytes)
+		}
+		return nil
+	})
+	return name, keys, err
+}
+
+// grpcEntry decodes a filter request and looks up its filter
+func (s *server) grpcEntry(msg []byte) (*entry, [][]byte, error) {
+	name, keys, err := nameAndKeys(msg)
+	if err != nil {
+		return nil, nil, err
+	}
+	e, err := s.lookup(name)
+	return e, keys, err
+}
+
+func (s *server) grpcCreate(_ context.Context, msg []byte) ([]byte, error) {
+	var name string
+	var req createRequest
+	err := readFields(msg, func(f protoField) error {
+		switch {
+		case f.num == 1 && f.wire == wireBytes:
+			name = string(f.bytes)
+		case f.num == 2 && f.wire == wireVarint:
+			req.ExpectedElements = f.value
+		case f.num == 3 && f.wire == wireFixed64:
+			req.FalsePositiveRate = math.Float64frombits(f.value)
+		case f.num == 4 && f.wire == wireVarint:
+			req.MaxMemory = f.value
+		case f.num == 5 && f.wire == wireVarint:
+			seed := f.value
+			req.Seed = &seed
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	params, err := s.createFilter(name, req)
+	if err != nil {
+		return nil, err
+	}
+	var b []byte
+	b = appendVarint(b, 1, params.BitCount)
+	b = appendVarint(b, 2, uint64(params.HashCount))
+	b = appendVarint(b, 3, params.MemoryUsage)
+	b = appendVarint(b, 4, params.Seed)
+	b = appendVarint(b, 5, params.ExpectedElements)
+	return b, nil
+}
+
+func (s *server) grpcDrop(_ context.Context, msg []byte) ([]byte, error) {
+	name, _, err := nameAndKeys(msg)
+	if err != nil {
+		return nil, err
+	}
+	return nil, s.dropFilter(name)
+}
+
+func (s *server) grpcAdd(_ context.Context, msg []byte) ([]byte, error) {
+	e, keys, err := s.grpcEntry(msg)
+	if err != nil {
+		return nil, err
+	}
+	if len(keys) != 1 {
+		return nil, &grpcError{codeInvalidArgument, "Add takes one key"}
+	}
+	e.bf.Add(keys[0])
+	e.dirty.Store(true)
+	return nil, nil
+}
+
+func (s *server) grpcCheck(_ context.Context, msg []byte) ([]byte, error) {
+	e, keys, err := s.grpcEntry(msg)
+	if err != nil {
+		return nil, err
+	}
+	if len(keys) != 1 {
+		return nil, &grpcError{codeInvalidArgument, "Check takes one key"}
+	}
+	return appendBool(nil, 1, e.bf.Contains(keys[0])), nil
+}
+
+func (s *server) grpcBulkAdd(_ context.Context, msg []byte) ([]byte, error) {
+	e, keys, err := s.grpcEntry(msg)
+	if err != nil {
+		return nil, err
+	}
+	e.bf.AddBatch(keys)
+	e.dirty.Store(true)
+	return appendVarint(nil, 1, uint64(len(keys))), nil
+}
+
+func (s *server) grpcBulkCheck(_ context.Context, msg []byte) ([]byte, error) {
+	e, keys, err := s.grpcEntry(msg)
+	if err != nil {
+		return nil, err
+	}
+	return appendPackedBools(nil, 1, e.bf.ContainsBatch(keys)), nil
+}
+
+func (s *server) grpcStats(ctx context.Context, msg []byte) ([]byte, error) {
+	e, _, err := s.grpcEntry(msg)
+	if err != nil {
+		return nil, err
+	}
+	stats, err := e.bf.Stats(ctx)
+	if err != nil {
+		return nil, &grpcError{codeUnavailable, err.Error()}
+	}
+	var b []byte
+	b = appendVarint(b, 1, stats.BitCount)
+	b = appendVarint(b, 2, uint64(stats.HashCount))
+	b = appendVarint(b, 3, stats.BitsSet)
+	b = appendDouble(b, 4, stats.LoadFactor)
+	b = appendDouble(b, 5, stats.EstimatedFPP)
+	b = appendVarint(b, 6, stats.EstimatedCount)
+	b = appendVarint(b, 7, stats.ExpectedElements)
+	b = appendVarint(b, 8, stats.MemoryUsage)
+	return b, nil
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+)
+
+// grpcCall makes a unary gRPC call over client and returns the response
+// message and the grpc-status
+func grpcCall(t *testing.T, client *http.Client, url, method string, msg []byte) ([]byte, int) {
+	t.Helper()
+	frame := binary.BigEndian.AppendUint32([]byte{0}, uint32(len(msg)))
+	req, _ := http.NewRequest("POST", url+"/"+grpcService+"/"+method, bytes.NewReader(append(frame, msg...)))
+	req.Header.Set("Content-Type", "application/grpc")
+	req.Header.Set("TE", "trailers")
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("%s: %v", method, err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	code, err := strconv.Atoi(resp.Trailer.Get("Grpc-Status"))
+	if err != nil {
+		t.Fatalf("%s: grpc-status %q", method, resp.Trailer.Get("Grpc-Status"))
+	}
+	if code != codeOK {
+		return nil, code
+	}
+	if len(body) < 5 || int(binary.BigEndian.Uint32(body[1:])) != len(body)-5 {
+		t.Fatalf("%s: malformed response %x", method, body)
+	}
+	return body[5:], code
+}
+
+// fields decodes a response message into its integer and bytes fields
+func fields(t *testing.T, msg []byte) map[uint64]protoField {
+	t.Helper()
+	out := map[uint64]protoField{}
+	if err := readFields(msg, func(f protoField) error { out[f.num] = f; return nil }); err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
+
+// TestGRPC tests the filter lifecycle through the gRPC service over HTTP/2
+func TestGRPC(t *testing.T) {
+	s, err := newServer("", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := httptest.NewUnstartedServer(s.handler())
+	ts.EnableHTTP2 = true
+	ts.StartTLS()
+	defer ts.Close()
+	client := ts.Client()
+
+	name := appendBytes(nil, 1, []byte("users"))
+	create := appendVarint(append([]byte(nil), name...), 2, 1000)
+	create = appendDouble(create, 3, 0.01)
+	resp, code := grpcCall(t, client, ts.URL, "Create", create)
+	if code != codeOK || fields(t, resp)[2].value == 0 {
+		t.Fatalf("Create: status %d, %x", code, resp)
+	}
+	if _, code := grpcCall(t, client, ts.URL, "Create", create); code != codeAlreadyExists {
+		t.Errorf("Duplicate create: status %d", code)
+	}
+	if _, code := grpcCall(t, client, ts.URL, "Create", appendBytes(nil, 1, []byte("bad"))); code != codeInvalidArgument {
+		t.Errorf("Invalid config: status %d", code)
+	}
+
+	key := func(k string) []byte { return appendBytes(append([]byte(nil), name...), 2, []byte(k)) }
+	if _, code := grpcCall(t, client, ts.URL, "Add", key("alice")); code != codeOK {
+		t.Errorf("Add: status %d", code)
+	}
+	bulk := appendBytes(appendBytes(append([]byte(nil), name...), 2, []byte("bob")), 2, []byte("carol"))
+	if resp, code := grpcCall(t, client, ts.URL, "BulkAdd", bulk); code != codeOK || fields(t, resp)[1].value != 2 {
+		t.Errorf("BulkAdd: status %d, %x", code, resp)
+	}
+	if resp, _ := grpcCall(t, client, ts.URL, "Check", key("alice")); fields(t, resp)[1].value != 1 {
+		t.Error("Check: alice not present")
+	}
+	// A key added over HTTP as a string is the same key
+	do(t, s.handler(), "PUT", "/filters/users/keys/dave", "", nil)
+	check := appendBytes(append([]byte(nil), bulk...), 2, []byte("dave"))
+	check = appendBytes(check, 2, []byte("mallory"))
+	resp, _ = grpcCall(t, client, ts.URL, "BulkCheck", check)
+	if present := fields(t, resp)[1].bytes; !bytes.Equal(present, []byte{1, 1, 1, 0}) {
+		t.Errorf("BulkCheck: %v", present)
+	}
+	if resp, code := grpcCall(t, client, ts.URL, "Stats", name); code != codeOK || fields(t, resp)[3].value == 0 || fields(t, resp)[7].value != 1000 {
+		t.Errorf("Stats: status %d, %x", code, resp)
+	}
+
+	if _, code := grpcCall(t, client, ts.URL, "Drop", name); code != codeOK {
+		t.Errorf("Drop: status %d", code)
+	}
+	if _, code := grpcCall(t, client, ts.URL, "Check", key("alice")); code != codeNotFound {
+		t.Errorf("Check after drop: status %d", code)
+	}
+	if _, code := grpcCall(t, client, ts.URL, "Rename", name); code != codeUnimplemented {
+		t.Errorf("Unknown method: status %d", code)
+	}
+
+	// HTTP/1 requests are refused
+	if code := do(t, s.handler(), "POST", "/"+grpcService+"/Check", "", nil); code != http.StatusUnsupportedMediaType {
+		t.Errorf("HTTP/1 gRPC request: status %d", code)
+	}
+}
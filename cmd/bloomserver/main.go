@@ -0,0 +1,92 @@
+// Command bloomserver serves named bloom filters over HTTP and gRPC, for
+// services that want a shared filter without embedding the package.
+//
+// Every filter lives in its own namespace under /filters/{name}:
+//
+//	PUT    /filters/{name}            create: {"expected_elements": 1000000, "false_positive_rate": 0.01}
+//	DELETE /filters/{name}            drop the filter and its file
+//	GET    /filters                   list the filter names
+//	PUT    /filters/{name}/keys/{key} add one key
+//	GET    /filters/{name}/keys/{key} check one key: {"present": true}
+//	POST   /filters/{name}/add        add many keys: {"keys": ["a", "b"]}
+//	POST   /filters/{name}/check      check many keys: {"keys": [...]} → {"present": [...]}
+//	GET    /filters/{name}/stats      parameters and load (CacheStats)
+//
+// With -dir, each filter is saved to {dir}/{name}.bloom in the native format
+// every -save-interval when it changed, and on SIGINT or SIGTERM; filters found
+// there are loaded at startup. Adds acknowledged after the last save are lost
+// if the process dies.
+//
+// With -max-memory, creates that would take the filters' total memory past
+// the limit are refused with 413 before the filter is allocated.
+//
+// The gRPC service bloomfilter.v1.BloomFilter in bloomfilter.proto offers the
+// same operations as unary calls. gRPC needs HTTP/2, which is served over TLS:
+// start the server with -tls-cert and -tls-key to accept gRPC clients. The
+// codec is built in, so the command needs no module beyond the standard
+// library; messages must be uncompressed.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+func main() {
+	addr := flag.String("addr", ":8080", "listen address")
+	dir := flag.String("dir", "", "directory to persist filters in; empty keeps them in memory only")
+	saveInterval := flag.Duration("save-interval", time.Minute, "how often changed filters are saved")
+	maxMemory := flag.Uint64("max-memory", 0, "limit on the total bytes of the filters; 0 for none")
+	tlsCert := flag.String("tls-cert", "", "TLS certificate file; with -tls-key serves HTTPS and gRPC")
+	tlsKey := flag.String("tls-key", "", "TLS key file")
+	flag.Parse()
+
+	s, err := newServer(*dir, *maxMemory)
+	if err != nil {
+		log.Fatal(err)
+	}
+	httpServer := &http.Server{Addr: *addr, Handler: s.handler(), ReadHeaderTimeout: 10 * time.Second}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		var err error
+		if *tlsCert != "" || *tlsKey != "" {
+			err = httpServer.ListenAndServeTLS(*tlsCert, *tlsKey)
+		} else {
+			err = httpServer.ListenAndServe()
+		}
+		if !errors.Is(err, http.ErrServerClosed) {
+			log.Fatal(err)
+		}
+	}()
+	log.Printf("bloomserver listening on %s", *addr)
+
+	ticker := time.NewTicker(*saveInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := s.saveAll(); err != nil {
+				log.Print(err)
+			}
+		case <-ctx.Done():
+			shutdown, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			if err := httpServer.Shutdown(shutdown); err != nil {
+				log.Print(err)
+			}
+			if err := s.saveAll(); err != nil {
+				log.Fatal(err)
+			}
+			return
+		}
+	}
+}
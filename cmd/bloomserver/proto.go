@@ -0,0 +1,121 @@
+package main
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+)
+
+// Protocol buffer wire encoding for the messages of bloomfilter.proto. The
+// messages are flat, so a field reader and a few appenders are all the codec
+// the gRPC service needs.
+
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+	wireFixed32 = 5
+)
+
+var errMalformed = errors.New("malformed protobuf message")
+
+// protoField is a decoded field: its number, wire type, and value as an
+// integer (varint and fixed types) or bytes (length-delimited)
+type protoField struct {
+	num   uint64
+	wire  uint64
+	value uint64
+	bytes []byte
+}
+
+// readFields calls fn for each field of msg
+func readFields(msg []byte, fn func(f protoField) error) error {
+	for len(msg) > 0 {
+		tag, n := binary.Uvarint(msg)
+		if n <= 0 {
+			return errMalformed
+		}
+		msg = msg[n:]
+		f := protoField{num: tag >> 3, wire: tag & 7}
+		switch f.wire {
+		case wireVarint:
+			f.value, n = binary.Uvarint(msg)
+			if n <= 0 {
+				return errMalformed
+			}
+			msg = msg[n:]
+		case wireFixed64:
+			if len(msg) < 8 {
+				return errMalformed
+			}
+			f.value, msg = binary.LittleEndian.Uint64(msg), msg[8:]
+		case wireFixed32:
+			if len(msg) < 4 {
+				return errMalformed
+			}
+			f.value, msg = uint64(binary.LittleEndian.Uint32(msg)), msg[4:]
+		case wireBytes:
+			size, n := binary.Uvarint(msg)
+			if n <= 0 || size > uint64(len(msg)-n) {
+				return errMalformed
+			}
+			f.bytes, msg = msg[n:n+int(size)], msg[n+int(size):]
+		default:
+			return errMalformed
+		}
+		if f.num == 0 {
+			return errMalformed
+		}
+		if err := fn(f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func appendTag(b []byte, num, wire uint64) []byte {
+	return binary.AppendUvarint(b, num<<3|wire)
+}
+
+// appendVarint appends a varint field, omitted when zero as in proto3
+func appendVarint(b []byte, num, v uint64) []byte {
+	if v == 0 {
+		return b
+	}
+	return binary.AppendUvarint(appendTag(b, num, wireVarint), v)
+}
+
+func appendBool(b []byte, num uint64, v bool) []byte {
+	if !v {
+		return b
+	}
+	return appendVarint(b, num, 1)
+}
+
+func appendDouble(b []byte, num uint64, v float64) []byte {
+	if v == 0 {
+		return b
+	}
+	return binary.LittleEndian.AppendUint64(appendTag(b, num, wireFixed64), math.Float64bits(v))
+}
+
+func appendBytes(b []byte, num uint64, v []byte) []byte {
+	b = binary.AppendUvarint(appendTag(b, num, wireBytes), uint64(len(v)))
+	return append(b, v...)
+}
+
+// appendPackedBools appends a packed repeated bool field
+func appendPackedBools(b []byte, num uint64, v []bool) []byte {
+	if len(v) == 0 {
+		return b
+	}
+	b = binary.AppendUvarint(appendTag(b, num, wireBytes), uint64(len(v)))
+	for _, x := range v {
+		if x {
+			b = append(b, 1)
+		} else {
+			b = append(b, 0)
+		}
+	}
+	return b
+}
@@ -0,0 +1,380 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	bloomfilter "github.com/shaia/BloomFilter"
+)
+
+// fileExt is the extension of persisted filters
+const fileExt = ".bloom"
+
+// maxBodyBytes caps request bodies, 64 MiB
+const maxBodyBytes = 64 << 20
+
+// validName matches filter names, which double as file names
+var validName = regexp.MustCompile(`^[A-Za-z0-9_-][A-Za-z0-9_.-]{0,127}$`)
+
+// server holds the named filters
+type server struct {
+	dir string
+	// Cap on the total memory of the filters, 0 for none
+	maxMemory uint64
+
+	mu      sync.RWMutex
+	filters map[string]*entry
+	memory  uint64 // Total size of the filters and of those being created
+
+	// saveMu serializes saves with the file removal of drops, so a save cannot
+	// bring back the file of a filter dropped while it ran
+	saveMu sync.Mutex
+}
+
+// entry is a served filter
+type entry struct {
+	bf   *bloomfilter.CacheOptimizedBloomFilter
+	size uint64
+	// Changed since it was last saved
+	dirty atomic.Bool
+}
+
+// statusError is an API error with its HTTP status
+type statusError struct {
+	status int
+	err    error
+}
+
+func (e *statusError) Error() string { return e.err.Error() }
+
+func failf(status int, format string, args ...any) error {
+	return &statusError{status, fmt.Errorf(format, args...)}
+}
+
+// newServer creates a server, loading the filters persisted in dir. A positive
+// maxMemory caps the total memory of the filters; loaded filters count against
+// it but are never refused.
+func newServer(dir string, maxMemory uint64) (*server, error) {
+	s := &server{dir: dir, maxMemory: maxMemory, filters: make(map[string]*entry)}
+	if dir == "" {
+		return s, nil
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	paths, err := filepath.Glob(filepath.Join(dir, "*"+fileExt))
+	if err != nil {
+		return nil, err
+	}
+	for _, path := range paths {
+		name := strings.TrimSuffix(filepath.Base(path), fileExt)
+		if !validName.MatchString(name) {
+			continue
+		}
+		bf, err := loadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("loading %s: %w", path, err)
+		}
+		size := bf.Params().MemoryUsage
+		s.filters[name] = &entry{bf: bf, size: size}
+		s.memory += size
+	}
+	return s, nil
+}
+
+func loadFile(path string) (*bloomfilter.CacheOptimizedBloomFilter, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	bf := &bloomfilter.CacheOptimizedBloomFilter{}
+	if _, err := bf.ReadFrom(f); err != nil {
+		return nil, err
+	}
+	return bf, nil
+}
+
+// saveFile writes bf to a temporary file next to path and renames it over path,
+// so a crash leaves the previous save intact
+func saveFile(path string, bf *bloomfilter.CacheOptimizedBloomFilter) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // no-op after a successful rename
+
+	if _, err := bf.WriteTo(tmp); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// saveAll saves the filters changed since their last save, returning the
+// errors of those that failed. Files are written without holding s.mu, so
+// requests are served during a save.
+func (s *server) saveAll() error {
+	if s.dir == "" {
+		return nil
+	}
+	s.saveMu.Lock()
+	defer s.saveMu.Unlock()
+	s.mu.RLock()
+	filters := maps.Clone(s.filters)
+	s.mu.RUnlock()
+
+	var errs []error
+	for name, e := range filters {
+		// Clearing the flag first leaves adds racing with the save for the next one
+		if e.dirty.Swap(false) {
+			if err := saveFile(s.path(name), e.bf); err != nil {
+				e.dirty.Store(true)
+				errs = append(errs, fmt.Errorf("saving %s: %w", name, err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (s *server) path(name string) string {
+	return filepath.Join(s.dir, name+fileExt)
+}
+
+// handler routes the API
+func (s *server) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /filters", s.list)
+	mux.HandleFunc("PUT /filters/{name}", s.create)
+	mux.HandleFunc("DELETE /filters/{name}", s.drop)
+	mux.HandleFunc("PUT /filters/{name}/keys/{key}", s.withFilter(s.add))
+	mux.HandleFunc("GET /filters/{name}/keys/{key}", s.withFilter(s.check))
+	mux.HandleFunc("POST /filters/{name}/add", s.withFilter(s.bulkAdd))
+	mux.HandleFunc("POST /filters/{name}/check", s.withFilter(s.bulkCheck))
+	mux.HandleFunc("GET /filters/{name}/stats", s.withFilter(s.stats))
+	mux.HandleFunc("POST /"+grpcService+"/{method}", s.serveGRPC)
+	return mux
+}
+
+// createRequest is the body of a create
+type createRequest struct {
+	ExpectedElements  uint64  `json:"expected_elements"`
+	FalsePositiveRate float64 `json:"false_positive_rate"`
+	// Optional: cap on the bit array, and the hash seed
+	MaxMemory uint64  `json:"max_memory,omitempty"`
+	Seed      *uint64 `json:"seed,omitempty"`
+}
+
+// keysRequest is the body of the bulk operations
+type keysRequest struct {
+	Keys []string `json:"keys"`
+}
+
+func (s *server) list(w http.ResponseWriter, r *http.Request) {
+	s.mu.RLock()
+	names := make([]string, 0, len(s.filters))
+	for name := range s.filters {
+		names = append(names, name)
+	}
+	s.mu.RUnlock()
+	slices.Sort(names)
+	writeJSON(w, http.StatusOK, map[string][]string{"filters": names})
+}
+
+func (s *server) create(w http.ResponseWriter, r *http.Request) {
+	var req createRequest
+	if !readJSON(w, r, &req) {
+		return
+	}
+	params, err := s.createFilter(r.PathValue("name"), req)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, params)
+}
+
+// createFilter creates the filter name for req. The size is checked against
+// maxMemory and reserved before the filter is allocated, so a request for a
+// huge filter is refused without allocating it.
+func (s *server) createFilter(name string, req createRequest) (bloomfilter.Params, error) {
+	if !validName.MatchString(name) {
+		return bloomfilter.Params{}, failf(http.StatusBadRequest, "invalid filter name %q", name)
+	}
+	opts := []bloomfilter.Option{bloomfilter.WithExpectedElements(req.ExpectedElements)}
+	if req.FalsePositiveRate != 0 {
+		opts = append(opts, bloomfilter.WithFalsePositiveRate(req.FalsePositiveRate))
+	}
+	if req.MaxMemory != 0 {
+		opts = append(opts, bloomfilter.WithMaxMemory(req.MaxMemory))
+	}
+	if req.Seed != nil {
+		opts = append(opts, bloomfilter.WithSeed(*req.Seed))
+	}
+	size, err := bloomfilter.MemoryFor(opts...)
+	if err != nil {
+		return bloomfilter.Params{}, &statusError{http.StatusBadRequest, err}
+	}
+
+	s.mu.Lock()
+	if _, ok := s.filters[name]; ok {
+		s.mu.Unlock()
+		return bloomfilter.Params{}, failf(http.StatusConflict, "filter %q exists", name)
+	}
+	if s.maxMemory > 0 && s.memory+size > s.maxMemory {
+		used := s.memory
+		s.mu.Unlock()
+		return bloomfilter.Params{}, failf(http.StatusRequestEntityTooLarge,
+			"filter needs %d bytes, %d of the %d-byte limit in use", size, used, s.maxMemory)
+	}
+	s.memory += size
+	s.mu.Unlock()
+
+	bf, err := bloomfilter.NewWithOptions(opts...)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err != nil {
+		s.memory -= size
+		return bloomfilter.Params{}, &statusError{http.StatusBadRequest, err}
+	}
+	if _, ok := s.filters[name]; ok {
+		s.memory -= size
+		return bloomfilter.Params{}, failf(http.StatusConflict, "filter %q exists", name)
+	}
+	e := &entry{bf: bf, size: size}
+	e.dirty.Store(true)
+	s.filters[name] = e
+	return bf.Params(), nil
+}
+
+func (s *server) drop(w http.ResponseWriter, r *http.Request) {
+	if err := s.dropFilter(r.PathValue("name")); err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// dropFilter removes the filter name and its file
+func (s *server) dropFilter(name string) error {
+	s.saveMu.Lock()
+	defer s.saveMu.Unlock()
+	if _, err := s.lookup(name); err != nil {
+		return err
+	}
+	if s.dir != "" && validName.MatchString(name) {
+		if err := os.Remove(s.path(name)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if e, ok := s.filters[name]; ok {
+		s.memory -= e.size
+		delete(s.filters, name)
+	}
+	return nil
+}
+
+// lookup returns the filter name
+func (s *server) lookup(name string) (*entry, error) {
+	s.mu.RLock()
+	e, ok := s.filters[name]
+	s.mu.RUnlock()
+	if !ok {
+		return nil, failf(http.StatusNotFound, "no filter %q", name)
+	}
+	return e, nil
+}
+
+// withFilter resolves the filter named in the path for h
+func (s *server) withFilter(h func(http.ResponseWriter, *http.Request, *entry)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		e, err := s.lookup(r.PathValue("name"))
+		if err != nil {
+			writeError(w, http.StatusNotFound, err)
+			return
+		}
+		h(w, r, e)
+	}
+}
+
+func (s *server) add(w http.ResponseWriter, r *http.Request, e *entry) {
+	e.bf.AddString(r.PathValue("key"))
+	e.dirty.Store(true)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *server) check(w http.ResponseWriter, r *http.Request, e *entry) {
+	writeJSON(w, http.StatusOK, map[string]bool{"present": e.bf.ContainsString(r.PathValue("key"))})
+}
+
+func (s *server) bulkAdd(w http.ResponseWriter, r *http.Request, e *entry) {
+	var req keysRequest
+	if !readJSON(w, r, &req) {
+		return
+	}
+	e.bf.AddStrings(req.Keys)
+	e.dirty.Store(true)
+	writeJSON(w, http.StatusOK, map[string]int{"added": len(req.Keys)})
+}
+
+func (s *server) bulkCheck(w http.ResponseWriter, r *http.Request, e *entry) {
+	var req keysRequest
+	if !readJSON(w, r, &req) {
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string][]bool{"present": e.bf.ContainsStrings(req.Keys)})
+}
+
+func (s *server) stats(w http.ResponseWriter, r *http.Request, e *entry) {
+	stats, err := e.bf.Stats(r.Context())
+	if err != nil {
+		writeError(w, http.StatusServiceUnavailable, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, stats)
+}
+
+// readJSON decodes the request body into v, answering 400 if it cannot
+func readJSON(w http.ResponseWriter, r *http.Request, v any) bool {
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodyBytes)).Decode(v); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return false
+	}
+	return true
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeError answers with err, using the status of a statusError and status
+// otherwise
+func writeError(w http.ResponseWriter, status int, err error) {
+	var se *statusError
+	if errors.As(err, &se) {
+		status = se.status
+	}
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// do sends a request to h and decodes the JSON response into out, if given
+func do(t *testing.T, h http.Handler, method, path, body string, out any) int {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(method, path, strings.NewReader(body)))
+	if out != nil {
+		if err := json.Unmarshal(rec.Body.Bytes(), out); err != nil {
+			t.Fatalf("%s %s: %v in %q", method, path, err, rec.Body)
+		}
+	}
+	return rec.Code
+}
+
+// TestServer tests the filter lifecycle through the HTTP API
+func TestServer(t *testing.T) {
+	s, err := newServer("", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := s.handler()
+
+	if code := do(t, h, "PUT", "/filters/users", `{"expected_elements": 1000, "false_positive_rate": 0.01}`, nil); code != http.StatusCreated {
+		t.Fatalf("Create: status %d", code)
+	}
+	if code := do(t, h, "PUT", "/filters/users", `{"expected_elements": 1000}`, nil); code != http.StatusConflict {
+		t.Errorf("Duplicate create: status %d", code)
+	}
+	if code := do(t, h, "PUT", "/filters/bad", `{"expected_elements": 0}`, nil); code != http.StatusBadRequest {
+		t.Errorf("Invalid config: status %d", code)
+	}
+	if code := do(t, h, "PUT", "/filters/.hidden", `{"expected_elements": 10}`, nil); code != http.StatusBadRequest {
+		t.Errorf("Invalid name: status %d", code)
+	}
+
+	if code := do(t, h, "PUT", "/filters/users/keys/alice", "", nil); code != http.StatusNoContent {
+		t.Errorf("Add: status %d", code)
+	}
+	if code := do(t, h, "POST", "/filters/users/add", `{"keys": ["bob", "carol"]}`, nil); code != http.StatusOK {
+		t.Errorf("Bulk add: status %d", code)
+	}
+	var one struct{ Present bool }
+	if do(t, h, "GET", "/filters/users/keys/alice", "", &one); !one.Present {
+		t.Error("Check: alice not present")
+	}
+	var many struct{ Present []bool }
+	do(t, h, "POST", "/filters/users/check", `{"keys": ["bob", "carol", "mallory"]}`, &many)
+	if len(many.Present) != 3 || !many.Present[0] || !many.Present[1] || many.Present[2] {
+		t.Errorf("Bulk check: %v", many.Present)
+	}
+
+	var stats struct{ BitsSet, ExpectedElements uint64 }
+	if code := do(t, h, "GET", "/filters/users/stats", "", &stats); code != http.StatusOK || stats.BitsSet == 0 || stats.ExpectedElements != 1000 {
+		t.Errorf("Stats: status %d, %+v", code, stats)
+	}
+	var list struct{ Filters []string }
+	if do(t, h, "GET", "/filters", "", &list); len(list.Filters) != 1 || list.Filters[0] != "users" {
+		t.Errorf("List: %v", list.Filters)
+	}
+
+	if code := do(t, h, "DELETE", "/filters/users", "", nil); code != http.StatusNoContent {
+		t.Errorf("Drop: status %d", code)
+	}
+	if code := do(t, h, "GET", "/filters/users/keys/alice", "", nil); code != http.StatusNotFound {
+		t.Errorf("Check after drop: status %d", code)
+	}
+}
+
+// TestServerPersistence tests that saved filters are loaded by a new server
+func TestServerPersistence(t *testing.T) {
+	dir := t.TempDir()
+	s, err := newServer(dir, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := s.handler()
+	do(t, h, "PUT", "/filters/a", `{"expected_elements": 1000}`, nil)
+	do(t, h, "PUT", "/filters/b", `{"expected_elements": 1000}`, nil)
+	do(t, h, "PUT", "/filters/a/keys/alice", "", nil)
+	if err := s.saveAll(); err != nil {
+		t.Fatal(err)
+	}
+	do(t, h, "DELETE", "/filters/b", "", nil)
+
+	restarted, err := newServer(dir, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h = restarted.handler()
+	var list struct{ Filters []string }
+	if do(t, h, "GET", "/filters", "", &list); len(list.Filters) != 1 || list.Filters[0] != "a" {
+		t.Errorf("Loaded filters %v, want [a]", list.Filters)
+	}
+	var one struct{ Present bool }
+	if do(t, h, "GET", "/filters/a/keys/alice", "", &one); !one.Present {
+		t.Error("Saved key lost")
+	}
+}
+
+// TestServerMaxMemory tests that creates past the memory limit are refused
+// before allocating, and that drops free their memory
+func TestServerMaxMemory(t *testing.T) {
+	s, err := newServer("", 1<<20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := s.handler()
+
+	if code := do(t, h, "PUT", "/filters/huge", `{"expected_elements": 1000000000000000}`, nil); code != http.StatusRequestEntityTooLarge {
+		t.Errorf("Huge filter: status %d", code)
+	}
+	// 600,000 keys at 1% take about 700 KiB, so only one fits
+	body := `{"expected_elements": 600000}`
+	if code := do(t, h, "PUT", "/filters/a", body, nil); code != http.StatusCreated {
+		t.Fatalf("Create: status %d", code)
+	}
+	var resp struct{ Error string }
+	if code := do(t, h, "PUT", "/filters/b", body, &resp); code != http.StatusRequestEntityTooLarge || !strings.Contains(resp.Error, "limit") {
+		t.Errorf("Create past the limit: status %d, %q", code, resp.Error)
+	}
+	do(t, h, "DELETE", "/filters/a", "", nil)
+	if code := do(t, h, "PUT", "/filters/b", body, nil); code != http.StatusCreated {
+		t.Errorf("Create after drop: status %d", code)
+	}
+}
@@ -0,0 +1,165 @@
+package bloomfilter
+
+import (
+	"errors"
+	"fmt"
+	"unsafe"
+)
+
+// ErrIncompatible is returned, wrapped in an IncompatibleError, for filters
+// whose bits cannot be combined or compared: different bit counts, hash counts,
+// seeds, probe schemes, hash versions or hashers.
+var ErrIncompatible = errors.New("bloomfilter: incompatible filters")
+
+// ErrSizeMismatch narrows ErrIncompatible to different bit counts, and is also
+// returned for storage whose size does not match the filter's
+var ErrSizeMismatch error = &narrowError{"bloomfilter: sizes do not match", ErrIncompatible}
+
+// ErrIncompatibleHash narrows ErrIncompatible to every difference in how keys
+// are hashed to bits: hash counts, seeds, probe schemes, hash versions and
+// hashers
+var ErrIncompatibleHash error = &narrowError{"bloomfilter: filters hash keys differently", ErrIncompatible}
+
+// narrowError is a sentinel error that also matches a more general one, so
+// errors.Is follows the hierarchy ErrIncompatible > ErrIncompatibleHash >
+// ErrSeedMismatch without each error listing its ancestors
+type narrowError struct {
+	msg    string
+	parent error
+}
+
+func (e *narrowError) Error() string { return e.msg }
+func (e *narrowError) Unwrap() error { return e.parent }
+
+// IncompatibleError is the error of Compatible and of the operations that
+// combine two filters bit by bit (Union, Intersection, Difference,
+// SymmetricDifference, ApplyDelta) when the filters differ in a parameter
+// that maps keys to bits. It wraps ErrSizeMismatch for bit counts,
+// ErrSeedMismatch for seeds and ErrIncompatibleHash otherwise, all of which
+// match ErrIncompatible:
+//
+//	var ie *bloomfilter.IncompatibleError
+//	if errors.As(err, &ie) && ie.Param == bloomfilter.ParamHashCount { ... }
+type IncompatibleError struct {
+	// Operation that failed, such as "union"; empty for Compatible
+	Op string
+	// Parameter that differs, one of the Param constants
+	Param string
+	// The parameter's value in the receiver and in the other filter. A probe
+	// scheme is its ProbeScheme value, a hasher 1 if custom and 0 if built-in.
+	Value, OtherValue uint64
+}
+
+// Parameters reported by IncompatibleError, in the order they are checked
+const (
+	ParamBitCount    = "bit count"
+	ParamHashCount   = "hash count"
+	ParamSeed        = "seed"
+	ParamProbeScheme = "probe scheme"
+	ParamHashVersion = "hash version"
+	ParamHasher      = "hasher"
+)
+
+func (e *IncompatibleError) Error() string {
+	var values string
+	switch e.Param {
+	case ParamSeed:
+		values = fmt.Sprintf("seeds %#x and %#x", e.Value, e.OtherValue)
+	case ParamProbeScheme:
+		values = fmt.Sprintf("probe schemes %s and %s", ProbeScheme(e.Value), ProbeScheme(e.OtherValue))
+	case ParamHashVersion:
+		values = fmt.Sprintf("hash versions %s and %s", HashVersion(e.Value), HashVersion(e.OtherValue))
+	case ParamHasher:
+		values = fmt.Sprintf("hashers %s and %s", hasherName(e.Value), hasherName(e.OtherValue))
+	default:
+		values = fmt.Sprintf("%ss %d and %d", e.Param, e.Value, e.OtherValue)
+	}
+	msg := ErrIncompatible.Error()
+	if e.Param == ParamSeed {
+		msg = ErrSeedMismatch.Error()
+	}
+	if e.Op != "" {
+		msg += " for " + e.Op
+	}
+	msg += ": " + values
+	if e.Param == ParamSeed && e.Op == "union" {
+		msg += "; re-insert the other filter's keys with UnionKeys instead"
+	}
+	return msg
+}
+
+// Unwrap returns the most specific sentinel error for Param
+func (e *IncompatibleError) Unwrap() error {
+	switch e.Param {
+	case ParamSeed:
+		return ErrSeedMismatch
+	case ParamBitCount:
+		return ErrSizeMismatch
+	}
+	return ErrIncompatibleHash
+}
+
+// Compatible returns nil if other has the same bit count, hash count, seed
+// (unless both use a custom hasher), probe scheme, hash version and kind of
+// hasher (see WithHasher), so the two filters map every key to the same bits
+// and can be merged or compared with Equal. Otherwise it returns an
+// *IncompatibleError.
+func (bf *CacheOptimizedBloomFilter) Compatible(other *CacheOptimizedBloomFilter) error {
+	return bf.checkCombinable(other, "")
+}
+
+// checkCombinable returns an *IncompatibleError for op if other cannot be
+// combined bit by bit with the filter
+func (bf *CacheOptimizedBloomFilter) checkCombinable(other *CacheOptimizedBloomFilter, op string) error {
+	mismatch := func(param string, value, otherValue uint64) error {
+		return &IncompatibleError{Op: op, Param: param, Value: value, OtherValue: otherValue}
+	}
+	switch {
+	case bf.bitCount != other.bitCount:
+		return mismatch(ParamBitCount, bf.bitCount, other.bitCount)
+	case bf.hashCount != other.hashCount:
+		return mismatch(ParamHashCount, uint64(bf.hashCount), uint64(other.hashCount))
+	case bf.seed != other.seed && (bf.hasher == nil || other.hasher == nil):
+		// Custom hashers ignore the seed
+		return mismatch(ParamSeed, bf.seed, other.seed)
+	case bf.probe != other.probe:
+		return mismatch(ParamProbeScheme, uint64(bf.probe), uint64(other.probe))
+	case bf.hashVersion != other.hashVersion:
+		return mismatch(ParamHashVersion, uint64(bf.hashVersion), uint64(other.hashVersion))
+	case bf.HasCustomHasher() != other.HasCustomHasher():
+		custom := func(f *CacheOptimizedBloomFilter) uint64 {
+			if f.HasCustomHasher() {
+				return 1
+			}
+			return 0
+		}
+		return mismatch(ParamHasher, custom(bf), custom(other))
+	}
+	return nil
+}
+
+// hasherName names a hasher value of an IncompatibleError
+func hasherName(custom uint64) string {
+	if custom != 0 {
+		return "custom"
+	}
+	return "built-in"
+}
+
+// Equal reports whether other is Compatible and has exactly the same bits set,
+// comparing the bit arrays with SIMD. Two replicas that saw the same keys in
+// any order are Equal. The comparison is not atomic: with adds running
+// concurrently on either filter the result reflects some mix of before and after.
+func (bf *CacheOptimizedBloomFilter) Equal(other *CacheOptimizedBloomFilter) bool {
+	if bf.Compatible(other) != nil {
+		return false
+	}
+	if bf == other || bf.cacheLineCount == 0 {
+		return true
+	}
+	return bf.simdOps.VectorEqual(
+		unsafe.Pointer(&bf.cacheLines[0]),
+		unsafe.Pointer(&other.cacheLines[0]),
+		int(bf.cacheLineCount*CacheLineSize),
+	)
+}
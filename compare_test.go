@@ -0,0 +1,171 @@
+package bloomfilter
+
+import (
+	"errors"
+	"fmt"
+	"hash/maphash"
+	"testing"
+)
+
+// TestCompatible tests that each geometry difference is reported
+func TestCompatible(t *testing.T) {
+	base, _ := NewWithOptions(WithExpectedElements(1000), WithHashCount(5), WithSeed(1))
+	same, _ := NewWithOptions(WithExpectedElements(1000), WithHashCount(5), WithSeed(1))
+	if err := base.Compatible(same); err != nil {
+		t.Fatalf("Identical geometry reported incompatible: %v", err)
+	}
+
+	size, _ := NewWithOptions(WithExpectedElements(100000), WithHashCount(5), WithSeed(1))
+	hashes, _ := NewWithOptions(WithExpectedElements(1000), WithHashCount(6), WithSeed(1))
+	seed, _ := NewWithOptions(WithExpectedElements(1000), WithHashCount(5), WithSeed(2))
+
+	if err := base.Compatible(size); !errors.Is(err, ErrIncompatible) {
+		t.Errorf("Bit count difference: expected ErrIncompatible, got %v", err)
+	}
+	if err := base.Compatible(hashes); !errors.Is(err, ErrIncompatible) {
+		t.Errorf("Hash count difference: expected ErrIncompatible, got %v", err)
+	}
+	if err := base.Compatible(seed); !errors.Is(err, ErrSeedMismatch) {
+		t.Errorf("Seed difference: expected ErrSeedMismatch, got %v", err)
+	}
+	for _, other := range []*CacheOptimizedBloomFilter{size, hashes, seed} {
+		if base.Equal(other) {
+			t.Error("Incompatible filters reported equal")
+		}
+	}
+}
+
+// TestCombineIncompatible tests that the bitwise combinations refuse filters
+// that differ in any key-to-bit parameter and leave the receiver untouched
+func TestCombineIncompatible(t *testing.T) {
+	newBase := func() *CacheOptimizedBloomFilter {
+		bf, _ := NewWithOptions(WithExpectedElements(1000), WithHashCount(5), WithSeed(1))
+		bf.AddString("kept")
+		return bf
+	}
+	size, _ := NewWithOptions(WithExpectedElements(100000), WithHashCount(5), WithSeed(1))
+	hashes, _ := NewWithOptions(WithExpectedElements(1000), WithHashCount(6), WithSeed(1))
+	seed, _ := NewWithOptions(WithExpectedElements(1000), WithHashCount(5), WithSeed(2))
+	probe, _ := NewWithOptions(WithExpectedElements(1000), WithHashCount(5), WithSeed(1), WithProbeScheme(DoubleHashing))
+	cases := []struct {
+		other    *CacheOptimizedBloomFilter
+		param    string
+		sentinel error
+		detail   error
+	}{
+		{size, ParamBitCount, ErrIncompatible, ErrSizeMismatch},
+		{hashes, ParamHashCount, ErrIncompatible, ErrIncompatibleHash},
+		{seed, ParamSeed, ErrSeedMismatch, ErrIncompatibleHash},
+		{probe, ParamProbeScheme, ErrIncompatible, ErrIncompatibleHash},
+	}
+	ops := map[string]func(bf, other *CacheOptimizedBloomFilter) error{
+		"union":                (*CacheOptimizedBloomFilter).Union,
+		"intersection":         (*CacheOptimizedBloomFilter).Intersection,
+		"difference":           (*CacheOptimizedBloomFilter).Difference,
+		"symmetric difference": (*CacheOptimizedBloomFilter).SymmetricDifference,
+	}
+	for op, combine := range ops {
+		for _, tc := range cases {
+			bf := newBase()
+			tc.other.AddString("other")
+			err := combine(bf, tc.other)
+			var ie *IncompatibleError
+			if !errors.As(err, &ie) || !errors.Is(err, tc.sentinel) || !errors.Is(err, tc.detail) {
+				t.Errorf("%s with different %s: got %v", op, tc.param, err)
+				continue
+			}
+			if ie.Op != op || ie.Param != tc.param || ie.Value == ie.OtherValue {
+				t.Errorf("%s with different %s: details %+v", op, tc.param, *ie)
+			}
+			if !bf.ContainsString("kept") || bf.ContainsString("other") {
+				t.Errorf("%s with different %s modified the filter", op, tc.param)
+			}
+		}
+	}
+
+	err := newBase().Union(hashes)
+	if want := "bloomfilter: incompatible filters for union: hash counts 5 and 6"; err == nil || err.Error() != want {
+		t.Errorf("Error() = %v, want %q", err, want)
+	}
+}
+
+// TestIncompatibleErrorSentinels tests that every parameter difference
+// matches ErrIncompatible, and the hierarchy of the sentinels
+func TestIncompatibleErrorSentinels(t *testing.T) {
+	opts := func(extra ...Option) *CacheOptimizedBloomFilter {
+		bf, _ := NewWithOptions(append([]Option{WithExpectedElements(1000), WithHashCount(5), WithSeed(1)}, extra...)...)
+		return bf
+	}
+	base := opts()
+	others := map[string]*CacheOptimizedBloomFilter{
+		ParamBitCount:    opts(WithExpectedElements(100000)),
+		ParamHashCount:   opts(WithHashCount(6)),
+		ParamSeed:        opts(WithSeed(2)),
+		ParamProbeScheme: opts(WithProbeScheme(DoubleHashing)),
+		ParamHashVersion: opts(WithHashVersion(HashV1)),
+		ParamHasher:      opts(WithHasher(MapHash(maphash.MakeSeed()))),
+	}
+	for param, other := range others {
+		err := base.Compatible(other)
+		var ie *IncompatibleError
+		if !errors.As(err, &ie) || ie.Param != param {
+			t.Errorf("Different %s: got %v", param, err)
+			continue
+		}
+		if !errors.Is(err, ErrIncompatible) {
+			t.Errorf("Different %s: %v does not match ErrIncompatible", param, err)
+		}
+	}
+
+	for _, pair := range [][2]error{
+		{ErrSizeMismatch, ErrIncompatible},
+		{ErrIncompatibleHash, ErrIncompatible},
+		{ErrSeedMismatch, ErrIncompatibleHash},
+		{ErrSeedMismatch, ErrIncompatible},
+	} {
+		if !errors.Is(pair[0], pair[1]) {
+			t.Errorf("%v does not match %v", pair[0], pair[1])
+		}
+	}
+	if errors.Is(ErrSizeMismatch, ErrIncompatibleHash) {
+		t.Error("ErrSizeMismatch matches ErrIncompatibleHash")
+	}
+}
+
+// TestEqual tests that replicas converge regardless of insertion order
+func TestEqual(t *testing.T) {
+	for _, simdDisabled := range []bool{false, true} {
+		t.Run(fmt.Sprintf("SIMDDisabled_%t", simdDisabled), func(t *testing.T) {
+			opts := []Option{WithExpectedElements(10000), WithSeed(1)}
+			if simdDisabled {
+				opts = append(opts, WithSIMDDisabled())
+			}
+			a, _ := NewWithOptions(opts...)
+			b, _ := NewWithOptions(opts...)
+
+			if !a.Equal(b) || !a.Equal(a) {
+				t.Fatal("Empty filters must be equal")
+			}
+			for i := 0; i < 5000; i++ {
+				a.AddUint64(uint64(i))
+				b.AddUint64(uint64(4999 - i))
+			}
+			if !a.Equal(b) || !b.Equal(a) {
+				t.Fatal("Filters with the same keys must be equal")
+			}
+
+			b.AddString("only in b")
+			if a.Equal(b) || b.Equal(a) {
+				t.Fatal("Filters with different keys must not be equal")
+			}
+
+			// The last word is compared as well
+			c, _ := NewWithOptions(opts...)
+			d, _ := NewWithOptions(opts...)
+			d.OrWord(d.WordCount()-1, 1<<63)
+			if c.Equal(d) {
+				t.Fatal("Difference in the last word not detected")
+			}
+		})
+	}
+}
@@ -0,0 +1,161 @@
+// Package conformance is the compatibility kit for the native filter format
+// written by CacheOptimizedBloomFilter.AppendBinary.
+//
+// It ships a machine-readable description of the format (Format), a set of test
+// vectors (Vectors) and a harness (Check) that runs a decoder against them. The
+// vectors are plain files under vectors/ (manifest.json plus one .bin per
+// vector), so readers written in other languages can use them directly: for
+// every valid vector, decode the file, compare the geometry, answer each probe
+// like the manifest says and re-encode to the identical bytes; every invalid
+// vector must be rejected. The format itself is specified in
+// docs/format/NATIVE_FORMAT.md.
+package conformance
+
+import (
+	"bytes"
+	"embed"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path"
+
+	bloomfilter "github.com/shaia/BloomFilter"
+)
+
+//go:embed format.json vectors
+var files embed.FS
+
+// Format returns the machine-readable description of the format (format.json)
+func Format() []byte {
+	data, _ := files.ReadFile("format.json")
+	return data
+}
+
+// HexBytes is a byte string encoded as hex in JSON
+type HexBytes []byte
+
+func (h HexBytes) MarshalText() ([]byte, error) {
+	return []byte(hex.EncodeToString(h)), nil
+}
+
+func (h *HexBytes) UnmarshalText(text []byte) error {
+	b, err := hex.DecodeString(string(text))
+	*h = b
+	return err
+}
+
+// Probe is a membership query and the answer the format requires
+type Probe struct {
+	Key      HexBytes `json:"key"`
+	Added    bool     `json:"added"`    // The key was added when the vector was built
+	Contains bool     `json:"contains"` // Expected answer, including false positives
+}
+
+// Vector is one test case of the manifest
+type Vector struct {
+	Name        string `json:"name"`
+	File        string `json:"file"`
+	Description string `json:"description"`
+	// Valid vectors must decode; invalid ones must be rejected
+	Valid bool `json:"valid"`
+
+	HashCount      uint32  `json:"hash_count,omitempty"`
+	BitCount       uint64  `json:"bit_count,omitempty"`
+	CacheLineCount uint64  `json:"cache_line_count,omitempty"`
+	Seed           uint64  `json:"seed,string,omitempty"` // A string, as it may exceed 2^53
+	Flags          uint16  `json:"flags,omitempty"`       // Header flags, bit 0 for enhanced double hashing, bit 1 for the one-pass hash
+	Probes         []Probe `json:"probes,omitempty"`
+}
+
+// manifest is the layout of vectors/manifest.json
+type manifest struct {
+	FormatVersion int      `json:"format_version"`
+	Vectors       []Vector `json:"vectors"`
+}
+
+// Vectors returns the test vectors shipped with the package
+func Vectors() ([]Vector, error) {
+	data, err := files.ReadFile("vectors/manifest.json")
+	if err != nil {
+		return nil, err
+	}
+	var m manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("conformance: parse manifest: %w", err)
+	}
+	return m.Vectors, nil
+}
+
+// Data returns the encoded bytes of a shipped vector
+func (v Vector) Data() ([]byte, error) {
+	return files.ReadFile(path.Join("vectors", v.File))
+}
+
+// Filter is the view of a decoded filter that Check verifies.
+// *bloomfilter.CacheOptimizedBloomFilter implements it.
+type Filter interface {
+	HashCount() uint32
+	BitCount() uint64
+	Seed() uint64
+	Contains(key []byte) bool
+	AppendBinary(b []byte) ([]byte, error)
+}
+
+// Native decodes with this package's implementation
+func Native(data []byte) (Filter, error) {
+	return bloomfilter.DecodeBinary(data)
+}
+
+// Check runs decode against every shipped vector and returns all failures joined,
+// or nil if decode conforms. Implementations in other languages can be checked by
+// wrapping them in a decode function, for example one that runs a subprocess.
+func Check(decode func([]byte) (Filter, error)) error {
+	vectors, err := Vectors()
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for _, v := range vectors {
+		data, err := v.Data()
+		if err != nil {
+			return err
+		}
+		if err := CheckVector(v, data, decode); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// CheckVector runs decode against a single vector
+func CheckVector(v Vector, data []byte, decode func([]byte) (Filter, error)) error {
+	f, err := decode(data)
+	if !v.Valid {
+		if err == nil {
+			return fmt.Errorf("%s: invalid encoding was accepted (%s)", v.Name, v.Description)
+		}
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", v.Name, err)
+	}
+
+	if f.HashCount() != v.HashCount || f.BitCount() != v.BitCount || f.Seed() != v.Seed {
+		return fmt.Errorf("%s: decoded hash_count=%d bit_count=%d seed=%#x, want %d, %d, %#x",
+			v.Name, f.HashCount(), f.BitCount(), f.Seed(), v.HashCount, v.BitCount, v.Seed)
+	}
+	for _, p := range v.Probes {
+		if got := f.Contains(p.Key); got != p.Contains {
+			return fmt.Errorf("%s: Contains(%x) = %v, want %v", v.Name, []byte(p.Key), got, p.Contains)
+		}
+	}
+	encoded, err := f.AppendBinary(nil)
+	if err != nil {
+		return fmt.Errorf("%s: re-encode: %w", v.Name, err)
+	}
+	if !bytes.Equal(encoded, data) {
+		return fmt.Errorf("%s: re-encoding is not byte-identical", v.Name)
+	}
+	return nil
+}
@@ -0,0 +1,120 @@
+package conformance
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"flag"
+	"hash/crc32"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite the shipped vectors")
+
+// TestNativeConforms tests this package's implementation against the vectors
+func TestNativeConforms(t *testing.T) {
+	if err := Check(Native); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestVectorsUpToDate tests that the shipped vectors match what the current
+// implementation generates. Run with -update after an intentional format change.
+func TestVectorsUpToDate(t *testing.T) {
+	if *update {
+		if err := Generate("vectors"); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+
+	dir := t.TempDir()
+	if err := Generate(dir); err != nil {
+		t.Fatal(err)
+	}
+	entries, _ := os.ReadDir(dir)
+	shipped, _ := files.ReadDir("vectors")
+	if len(entries) != len(shipped) {
+		t.Fatalf("Generated %d files, shipped %d", len(entries), len(shipped))
+	}
+	for _, e := range entries {
+		want, _ := os.ReadFile(filepath.Join(dir, e.Name()))
+		got, err := files.ReadFile("vectors/" + e.Name())
+		if err != nil || !bytes.Equal(got, want) {
+			t.Errorf("%s is stale (run go test -run TestVectorsUpToDate -update)", e.Name())
+		}
+	}
+}
+
+// TestFormatDescription tests that format.json describes the actual encoding by
+// reading every valid vector through it
+func TestFormatDescription(t *testing.T) {
+	var desc struct {
+		Version    int `json:"version"`
+		HeaderSize int `json:"header_size"`
+		Header     []struct {
+			Name   string `json:"name"`
+			Offset int    `json:"offset"`
+			Size   int    `json:"size"`
+			Value  any    `json:"value"`
+		} `json:"header"`
+	}
+	if err := json.Unmarshal(Format(), &desc); err != nil {
+		t.Fatal(err)
+	}
+
+	vectors, err := Vectors()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, v := range vectors {
+		if !v.Valid {
+			continue
+		}
+		data, _ := v.Data()
+		chunks := (int(v.CacheLineCount) + 1023) / 1024
+		if len(data) != desc.HeaderSize+64*int(v.CacheLineCount)+4*chunks+4 {
+			t.Errorf("%s: size %d does not match total_size", v.Name, len(data))
+		}
+
+		want := map[string]uint64{
+			"version":          uint64(desc.Version),
+			"flags":            uint64(v.Flags),
+			"hash_count":       uint64(v.HashCount),
+			"header_crc":       headerCRC(data[:desc.HeaderSize]),
+			"bit_count":        v.BitCount,
+			"cache_line_count": v.CacheLineCount,
+			"seed":             v.Seed,
+		}
+		for _, f := range desc.Header {
+			field := data[f.Offset : f.Offset+f.Size]
+			if f.Name == "magic" {
+				if string(field) != f.Value {
+					t.Errorf("%s: magic %q", v.Name, field)
+				}
+				continue
+			}
+			var got uint64
+			switch f.Size {
+			case 2:
+				got = uint64(binary.LittleEndian.Uint16(field))
+			case 4:
+				got = uint64(binary.LittleEndian.Uint32(field))
+			case 8:
+				got = binary.LittleEndian.Uint64(field)
+			}
+			if got != want[f.Name] {
+				t.Errorf("%s: field %s = %d, want %d", v.Name, f.Name, got, want[f.Name])
+			}
+		}
+	}
+}
+
+// headerCRC returns the CRC-32C of a header with its header_crc field zero
+func headerCRC(header []byte) uint64 {
+	h := bytes.Clone(header)
+	binary.LittleEndian.PutUint32(h[offHeaderCRC:], 0)
+	return uint64(crc32.Checksum(h, castagnoli))
+}
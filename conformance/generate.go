@@ -0,0 +1,249 @@
+package conformance
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"hash/crc32"
+	"os"
+	"path/filepath"
+	"regexp"
+
+	bloomfilter "github.com/shaia/BloomFilter"
+)
+
+// Header offsets used to derive the invalid vectors (see format.json)
+const (
+	offVersion        = 4
+	offFlags          = 6
+	offHashCount      = 8
+	offHeaderCRC      = 12
+	offBitCount       = 16
+	offCacheLineCount = 24
+	offSeed           = 32
+	headerSize        = 40
+	chunkBytes        = 1024 * bloomfilter.CacheLineSize
+)
+
+// castagnoli is the CRC-32C table of the format's checksums
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+// probeObject matches an indented probe
+var probeObject = regexp.MustCompile(`\{\s*"key": ("[0-9a-f]*"),\s*"added": (true|false),\s*"contains": (true|false)\s*\}`)
+
+// validSpec describes how a valid vector is built
+type validSpec struct {
+	name, description string
+	expected          uint64
+	fpr               float64
+	seed              uint64
+	enhanced          bool // EnhancedDoubleHashing instead of DoubleHashing
+	hashV2            bool // HashV2 instead of HashV1
+	keys              [][]byte
+	probeStride       int // Probe every probeStride-th added key
+}
+
+// invalidSpec derives an invalid vector from the "small" vector. Unless raw, the
+// checksums are recomputed (see reseal) so that the structural check is what
+// must fail.
+type invalidSpec struct {
+	name, description string
+	raw               bool
+	mutate            func([]byte) []byte
+}
+
+// Generate writes the test vectors produced by the current implementation to dir
+// as manifest.json and one .bin per vector. The output is deterministic; the
+// package tests fail if it differs from the shipped vectors.
+func Generate(dir string) error {
+	valid := []validSpec{
+		{name: "empty", description: "no keys added", expected: 100, fpr: 0.01},
+		{name: "small", description: "100 string keys", expected: 100, fpr: 0.01, keys: numbered("key-", 100), probeStride: 1},
+		{name: "seeded", description: "500 keys with a seed above 2^53", expected: 1000, fpr: 0.01,
+			seed: 0x9e3779b97f4a7c15, keys: numbered("seeded-", 500), probeStride: 2},
+		{name: "many_hashes", description: "1000 keys at FPR 1e-6, more than 16 hash functions", expected: 1000, fpr: 1e-6,
+			keys: numbered("k", 1000), probeStride: 4},
+		{name: "overfilled", description: "300 keys in a filter sized for 100, so absent probes include false positives",
+			expected: 100, fpr: 0.01, keys: numbered("over-", 300), probeStride: 3},
+		{name: "key_lengths", description: "binary keys of length 0 to 64, covering every hash tail length", expected: 200, fpr: 0.001,
+			keys: lengths(65), probeStride: 1},
+		{name: "enhanced", description: "500 keys with enhanced double hashing (flag bit 0)", expected: 1000, fpr: 0.01,
+			enhanced: true, keys: numbered("enhanced-", 500), probeStride: 2},
+		{name: "hash_v2", description: "binary keys of length 0 to 79 with enhanced double hashing and the one-pass hash (flag bits 0 and 1), covering every tail length",
+			expected: 200, fpr: 0.001, enhanced: true, hashV2: true, keys: lengths(80), probeStride: 1},
+		{name: "multi_chunk", description: "2000 keys in 1123 cache lines, two chunk CRCs, the second over 99 lines",
+			expected: 60000, fpr: 0.01, keys: numbered("chunk-", 2000), probeStride: 20},
+	}
+	invalid := []invalidSpec{
+		{name: "bad_magic", description: "magic is not BLMF", mutate: func(d []byte) []byte { d[0] = 'X'; return d }},
+		{name: "unsupported_version", description: "version 3", mutate: func(d []byte) []byte {
+			binary.LittleEndian.PutUint16(d[offVersion:], 3)
+			return d
+		}},
+		{name: "unknown_flags", description: "flag bit 2, which is undefined", mutate: func(d []byte) []byte {
+			binary.LittleEndian.PutUint16(d[offFlags:], 4)
+			return d
+		}},
+		{name: "zero_hash_count", description: "hash_count is 0", mutate: func(d []byte) []byte {
+			binary.LittleEndian.PutUint32(d[offHashCount:], 0)
+			return d
+		}},
+		{name: "bit_count_mismatch", description: "bit_count is not cache_line_count*512", mutate: func(d []byte) []byte {
+			binary.LittleEndian.PutUint64(d[offBitCount:], binary.LittleEndian.Uint64(d[offBitCount:])-1)
+			return d
+		}},
+		{name: "truncated", description: "last cache line missing", mutate: func(d []byte) []byte {
+			end := wordsEnd(d)
+			return append(d[:end-64:end-64], d[end:]...)
+		}},
+		{name: "trailing_bytes", description: "8 bytes between the words and the chunk table", mutate: func(d []byte) []byte {
+			end := wordsEnd(d)
+			return append(append(d[:end:end], make([]byte, 8)...), d[end:]...)
+		}},
+		{name: "short", description: "shorter than a header", raw: true, mutate: func(d []byte) []byte { return d[:headerSize-1] }},
+		{name: "bad_header_checksum", description: "seed changed without updating the header CRC", raw: true, mutate: func(d []byte) []byte {
+			d[offSeed] ^= 1
+			return d
+		}},
+		{name: "bad_checksum", description: "one bit of the words flipped without updating its chunk CRC", raw: true, mutate: func(d []byte) []byte {
+			d[headerSize] ^= 1
+			return d
+		}},
+		{name: "bad_chunk_table", description: "chunk CRC changed without updating the footer", raw: true, mutate: func(d []byte) []byte {
+			d[len(d)-8] ^= 1
+			return d
+		}},
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	m := manifest{FormatVersion: bloomfilter.FormatVersion}
+	var small []byte
+	for _, s := range valid {
+		v, data, err := buildValid(s)
+		if err != nil {
+			return err
+		}
+		if s.name == "small" {
+			small = data
+		}
+		if err := os.WriteFile(filepath.Join(dir, v.File), data, 0o644); err != nil {
+			return err
+		}
+		m.Vectors = append(m.Vectors, v)
+	}
+	for _, s := range invalid {
+		data := s.mutate(append([]byte(nil), small...))
+		if !s.raw {
+			reseal(data)
+		}
+		v := Vector{Name: s.name, File: s.name + ".bin", Description: s.description}
+		if err := os.WriteFile(filepath.Join(dir, v.File), data, 0o644); err != nil {
+			return err
+		}
+		m.Vectors = append(m.Vectors, v)
+	}
+
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	// One probe per line keeps the manifest readable and its diffs small
+	data = probeObject.ReplaceAll(data, []byte(`{"key": $1, "added": $2, "contains": $3}`))
+	return os.WriteFile(filepath.Join(dir, "manifest.json"), append(data, '\n'), 0o644)
+}
+
+// wordsEnd returns the offset of the chunk table of a valid encoding
+func wordsEnd(d []byte) int {
+	return headerSize + int(binary.LittleEndian.Uint64(d[offCacheLineCount:]))*bloomfilter.CacheLineSize
+}
+
+// reseal recomputes every checksum of a mutated encoding: the header CRC, and
+// the chunk CRCs over whatever lies between the header and a chunk table of
+// the length the header implies, followed by the footer
+func reseal(d []byte) {
+	binary.LittleEndian.PutUint32(d[offHeaderCRC:], 0)
+	binary.LittleEndian.PutUint32(d[offHeaderCRC:], crc32.Checksum(d[:headerSize], castagnoli))
+
+	lines := int(binary.LittleEndian.Uint64(d[offCacheLineCount:]))
+	chunks := (lines*bloomfilter.CacheLineSize + chunkBytes - 1) / chunkBytes
+	table := d[len(d)-4*chunks-4:]
+	words := d[headerSize : len(d)-len(table)]
+	for i := 0; i < chunks; i++ {
+		chunk := words[min(i*chunkBytes, len(words)):min((i+1)*chunkBytes, len(words))]
+		binary.LittleEndian.PutUint32(table[4*i:], crc32.Checksum(chunk, castagnoli))
+	}
+	binary.LittleEndian.PutUint32(table[4*chunks:], crc32.Checksum(table[:4*chunks], castagnoli))
+}
+
+// buildValid builds a valid vector and its encoding
+func buildValid(s validSpec) (Vector, []byte, error) {
+	scheme := bloomfilter.DoubleHashing
+	if s.enhanced {
+		scheme = bloomfilter.EnhancedDoubleHashing
+	}
+	version := bloomfilter.HashV1
+	if s.hashV2 {
+		version = bloomfilter.HashV2
+	}
+	bf, err := bloomfilter.NewWithOptions(bloomfilter.WithExpectedElements(s.expected),
+		bloomfilter.WithFalsePositiveRate(s.fpr), bloomfilter.WithSeed(s.seed),
+		bloomfilter.WithProbeScheme(scheme), bloomfilter.WithHashVersion(version))
+	if err != nil {
+		return Vector{}, nil, err
+	}
+	for _, k := range s.keys {
+		bf.Add(k)
+	}
+	data, err := bf.AppendBinary(nil)
+	if err != nil {
+		return Vector{}, nil, err
+	}
+
+	v := Vector{
+		Name:           s.name,
+		File:           s.name + ".bin",
+		Description:    s.description,
+		Valid:          true,
+		HashCount:      bf.HashCount(),
+		BitCount:       bf.BitCount(),
+		CacheLineCount: bf.BitCount() / bloomfilter.BitsPerCacheLine,
+		Seed:           s.seed,
+	}
+	if s.enhanced {
+		v.Flags |= 1
+	}
+	if s.hashV2 {
+		v.Flags |= 2
+	}
+	for i := 0; s.probeStride > 0 && i < len(s.keys); i += s.probeStride {
+		v.Probes = append(v.Probes, Probe{Key: s.keys[i], Added: true, Contains: true})
+	}
+	// Absent keys, with whatever answer the filter gives (false positives included)
+	for _, k := range numbered("absent-"+s.name+"-", 64) {
+		v.Probes = append(v.Probes, Probe{Key: k, Contains: bf.Contains(k)})
+	}
+	return v, data, nil
+}
+
+// numbered returns n keys prefix0, prefix1, ...
+func numbered(prefix string, n int) [][]byte {
+	keys := make([][]byte, n)
+	for i := range keys {
+		keys[i] = []byte(fmt.Sprintf("%s%d", prefix, i))
+	}
+	return keys
+}
+
+// lengths returns n binary keys, the i-th of length i
+func lengths(n int) [][]byte {
+	keys := make([][]byte, n)
+	for i := range keys {
+		keys[i] = make([]byte, i)
+		for j := range keys[i] {
+			keys[i][j] = byte(i*31 + j*7)
+		}
+	}
+	return keys
+}
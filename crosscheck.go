@@ -0,0 +1,116 @@
+package bloomfilter
+
+import "sync/atomic"
+
+// Divergence describes a key for which the two filters of a CrossCheckFilter disagreed.
+// Key aliases the caller's buffer and must be copied if retained.
+type Divergence struct {
+	Key       []byte
+	Primary   bool
+	Secondary bool
+}
+
+// CrossCheckStats reports how often the two filters of a CrossCheckFilter disagreed
+type CrossCheckStats struct {
+	Checks        uint64 // Total Contains calls
+	Divergences   uint64 // Calls where the answers differed
+	PrimaryOnly   uint64 // Primary said present, secondary said absent
+	SecondaryOnly uint64 // Secondary said present, primary said absent
+}
+
+// DivergenceRate returns Divergences / Checks, or 0 before the first check
+func (s CrossCheckStats) DivergenceRate() float64 {
+	if s.Checks == 0 {
+		return 0
+	}
+	return float64(s.Divergences) / float64(s.Checks)
+}
+
+// CrossCheckFilter maintains two filters built with different hashers or strategies,
+// adds every key to both and compares their answers on Contains. It is meant to
+// validate a hash migration in production before cutover: the primary keeps serving
+// answers while the secondary is shadowed.
+//
+// Because both filters see every Add, a key added through the wrapper is present in
+// both and can never diverge. Divergences on other keys are expected at roughly the
+// sum of the two false positive rates; a rate well above that, or any divergence on
+// a key known to be added, points at a bug in the new configuration.
+type CrossCheckFilter struct {
+	primary      Filter
+	secondary    Filter
+	onDivergence func(Divergence)
+
+	checks        atomic.Uint64
+	primaryOnly   atomic.Uint64
+	secondaryOnly atomic.Uint64
+}
+
+// NewCrossCheckFilter creates a cross-checking wrapper around two filters.
+// onDivergence, if non-nil, is called synchronously for every divergence and is
+// typically used for logging, e.g.:
+//
+//	cc := NewCrossCheckFilter(oldFilter, newFilter, func(d Divergence) {
+//	    log.Printf("bloom divergence key=%x old=%t new=%t", d.Key, d.Primary, d.Secondary)
+//	})
+//
+// Panics if either filter is nil.
+func NewCrossCheckFilter(primary, secondary Filter, onDivergence func(Divergence)) *CrossCheckFilter {
+	if primary == nil || secondary == nil {
+		panic("bloomfilter: cross-check filters must not be nil")
+	}
+	return &CrossCheckFilter{
+		primary:      primary,
+		secondary:    secondary,
+		onDivergence: onDivergence,
+	}
+}
+
+// Add adds an element to both filters
+func (c *CrossCheckFilter) Add(data []byte) {
+	c.primary.Add(data)
+	c.secondary.Add(data)
+}
+
+// Contains queries both filters and returns the primary's answer, recording a
+// divergence if the secondary disagrees
+func (c *CrossCheckFilter) Contains(data []byte) bool {
+	p := c.primary.Contains(data)
+	s := c.secondary.Contains(data)
+	c.checks.Add(1)
+
+	if p != s {
+		if p {
+			c.primaryOnly.Add(1)
+		} else {
+			c.secondaryOnly.Add(1)
+		}
+		if c.onDivergence != nil {
+			c.onDivergence(Divergence{Key: data, Primary: p, Secondary: s})
+		}
+	}
+	return p
+}
+
+// Primary returns the filter whose answers are served
+func (c *CrossCheckFilter) Primary() Filter {
+	return c.primary
+}
+
+// Secondary returns the shadowed filter, which becomes the primary after cutover
+func (c *CrossCheckFilter) Secondary() Filter {
+	return c.secondary
+}
+
+// Stats returns the divergence counters
+func (c *CrossCheckFilter) Stats() CrossCheckStats {
+	primaryOnly := c.primaryOnly.Load()
+	secondaryOnly := c.secondaryOnly.Load()
+	return CrossCheckStats{
+		Checks:        c.checks.Load(),
+		Divergences:   primaryOnly + secondaryOnly,
+		PrimaryOnly:   primaryOnly,
+		SecondaryOnly: secondaryOnly,
+	}
+}
+
+var _ Filter = (*CrossCheckFilter)(nil)
@@ -0,0 +1,52 @@
+package bloomfilter
+
+import (
+	"fmt"
+	"sync/atomic"
+	"testing"
+)
+
+// TestCrossCheckFilter tests that divergences are detected, counted and reported
+func TestCrossCheckFilter(t *testing.T) {
+	primary := NewCacheOptimizedBloomFilter(1000, 0.001)
+	secondary := NewCacheOptimizedBloomFilter(1000, 0.3) // deliberately weak
+
+	var reported atomic.Uint64
+	cc := NewCrossCheckFilter(primary, secondary, func(d Divergence) {
+		if d.Primary == d.Secondary {
+			t.Errorf("Divergence reported for agreeing answers: %+v", d)
+		}
+		reported.Add(1)
+	})
+
+	for i := 0; i < 1000; i++ {
+		cc.Add([]byte(fmt.Sprintf("key_%d", i)))
+	}
+
+	// Added keys must never diverge
+	for i := 0; i < 1000; i++ {
+		if !cc.Contains([]byte(fmt.Sprintf("key_%d", i))) {
+			t.Fatalf("Added key_%d not found", i)
+		}
+	}
+	if s := cc.Stats(); s.Divergences != 0 {
+		t.Fatalf("Expected no divergences on added keys, got %+v", s)
+	}
+
+	// Absent keys diverge at roughly the weaker filter's FPR
+	for i := 0; i < 1000; i++ {
+		cc.Contains([]byte(fmt.Sprintf("absent_%d", i)))
+	}
+
+	stats := cc.Stats()
+	if stats.Checks != 2000 {
+		t.Errorf("Expected 2000 checks, got %d", stats.Checks)
+	}
+	if stats.SecondaryOnly == 0 {
+		t.Error("Expected the weak secondary filter to produce divergences")
+	}
+	if stats.Divergences != reported.Load() {
+		t.Errorf("Callback saw %d divergences, stats report %d", reported.Load(), stats.Divergences)
+	}
+	t.Logf("Cross-check stats: %+v (rate %.4f)", stats, stats.DivergenceRate())
+}
@@ -0,0 +1,65 @@
+package bloomfilter
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"unsafe"
+)
+
+// String summarizes the filter on one line for logs, as CacheStats.String plus
+// the probe scheme, hash version and read-only mode. It counts the set bits with a PopCount
+// unless fill tracking is on (see EnableFillTracking).
+func (bf *CacheOptimizedBloomFilter) String() string {
+	if bf.cacheLineCount == 0 {
+		// Zero value, as passed to UnmarshalBinary
+		return "bloomfilter: uninitialized"
+	}
+	s := "bloomfilter: " + bf.GetCacheStats().String() + " probe=" + bf.probe.String() + " hash=" + bf.hashVersion.String()
+	if bf.readOnly {
+		s += " read-only"
+	}
+	return s
+}
+
+// debugRegions is the number of regions of the DebugDump heatmap, 64 per row
+const debugRegions = 256
+
+// fillRamp shades a region's load factor from empty to full
+const fillRamp = " .:-=+*#%@"
+
+// DebugDump writes the one-line summary, the detailed statistics (see
+// CacheStats.Report) and a heatmap of the load factor of up to 256 equal
+// regions of the bit array, for eyeballing skew. It reads every bit, like
+// PopCount.
+func (bf *CacheOptimizedBloomFilter) DebugDump(w io.Writer) error {
+	if bf.cacheLineCount == 0 {
+		_, err := io.WriteString(w, bf.String()+"\n")
+		return err
+	}
+	var b strings.Builder
+	b.WriteString(bf.String())
+	b.WriteString("\n")
+	b.WriteString(bf.GetCacheStats().Report())
+
+	regions := min(bf.cacheLineCount, debugRegions)
+	fmt.Fprintf(&b, "fill: %d regions of ~%d cache lines, %q from empty to full\n",
+		regions, bf.cacheLineCount/regions, fillRamp)
+	minLoad, maxLoad := 1.0, 0.0
+	for r := uint64(0); r < regions; r++ {
+		first, end := r*bf.cacheLineCount/regions, (r+1)*bf.cacheLineCount/regions
+		set := bf.simdOps.PopCount(unsafe.Pointer(&bf.cacheLines[first]), int((end-first)*CacheLineSize))
+		load := float64(set) / float64((end-first)*BitsPerCacheLine)
+		minLoad, maxLoad = min(minLoad, load), max(maxLoad, load)
+		if r%64 == 0 {
+			fmt.Fprintf(&b, "  %10d |", first)
+		}
+		b.WriteByte(fillRamp[min(int(load*float64(len(fillRamp))), len(fillRamp)-1)])
+		if r%64 == 63 || r == regions-1 {
+			b.WriteString("|\n")
+		}
+	}
+	fmt.Fprintf(&b, "fill range: %.4f-%.4f\n", minLoad, maxLoad)
+	_, err := io.WriteString(w, b.String())
+	return err
+}
@@ -0,0 +1,58 @@
+package bloomfilter
+
+import (
+	"io"
+	"strings"
+	"testing"
+)
+
+// TestFilterString tests the one-line summary of a filter
+func TestFilterString(t *testing.T) {
+	bf := NewCacheOptimizedBloomFilter(1000, 0.01)
+	bf.AddString("a")
+	s := bf.String()
+	for _, want := range []string{"bloomfilter: bits=", "load=", "probe=enhanced", "hash=v2"} {
+		if !strings.Contains(s, want) {
+			t.Errorf("String() missing %q: %s", want, s)
+		}
+	}
+	data, _ := bf.MarshalBinary()
+	view, _ := NewFromBuffer(data)
+	if !strings.HasSuffix(view.String(), " read-only") {
+		t.Errorf("String() of a view: %s", view)
+	}
+	if s := (&CacheOptimizedBloomFilter{}).String(); s != "bloomfilter: uninitialized" {
+		t.Errorf("String() of the zero value: %s", s)
+	}
+}
+
+// TestDebugDump tests that the heatmap shows where the bits are
+func TestDebugDump(t *testing.T) {
+	bf := NewCacheOptimizedBloomFilter(100000, 0.01)
+	// Fill the first half of the bit array only
+	for i := 0; i < bf.WordCount()/2; i++ {
+		bf.OrWord(i, ^uint64(0))
+	}
+	var b strings.Builder
+	if err := bf.DebugDump(&b); err != nil {
+		t.Fatal(err)
+	}
+	out := b.String()
+	var rows []string
+	for _, line := range strings.Split(out, "\n") {
+		if i := strings.IndexByte(line, '|'); i >= 0 {
+			rows = append(rows, strings.Trim(line[i:], "|"))
+		}
+	}
+	heatmap := strings.Join(rows, "")
+	if len(heatmap) != debugRegions || strings.Count(heatmap, "@") != debugRegions/2 || strings.Count(heatmap, " ") < debugRegions/2-1 {
+		t.Errorf("Heatmap of a half-full filter:\n%s", out)
+	}
+	if !strings.Contains(out, "fill range: 0.0000-1.0000") || !strings.Contains(out, "adds:") {
+		t.Errorf("DebugDump() missing the range or the report:\n%s", out)
+	}
+
+	if err := (&CacheOptimizedBloomFilter{}).DebugDump(io.Discard); err != nil {
+		t.Error(err)
+	}
+}
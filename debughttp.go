@@ -0,0 +1,16 @@
+//go:build !bloomfilter_minimal
+
+package bloomfilter
+
+import "net/http"
+
+// DebugHandler serves the DebugDump of bf as plain text, for mounting on a
+// service's debug mux:
+//
+//	http.Handle("/debug/bloomfilter/users", bloomfilter.DebugHandler(users))
+func DebugHandler(bf *CacheOptimizedBloomFilter) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		bf.DebugDump(w)
+	})
+}
@@ -0,0 +1,23 @@
+//go:build !bloomfilter_minimal
+
+package bloomfilter
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// TestDebugHandler tests that the handler serves the dump
+func TestDebugHandler(t *testing.T) {
+	bf := NewCacheOptimizedBloomFilter(1000, 0.01)
+	bf.AddString("a")
+	var want strings.Builder
+	bf.DebugDump(&want)
+
+	rec := httptest.NewRecorder()
+	DebugHandler(bf).ServeHTTP(rec, httptest.NewRequest("GET", "/debug/bloomfilter", nil))
+	if rec.Body.String() != want.String() || !strings.HasPrefix(rec.Header().Get("Content-Type"), "text/plain") {
+		t.Errorf("DebugHandler served %q", rec.Body)
+	}
+}
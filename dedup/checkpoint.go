@@ -0,0 +1,155 @@
+package dedup
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"sort"
+
+	bloomfilter "github.com/shaia/BloomFilter"
+)
+
+// Checkpoint layout, all integers little-endian:
+//
+//	magic "BFDD" | version u32 | hashCount u32 | seed u64 (versions 5-8) |
+//	wordCount u64 | words [wordCount]u64 |
+//	partitionCount u32 | { topicLen u16 | topic | partition i32 | offset i64 }... |
+//	crc32 (IEEE) of everything before it
+//
+// The version records the filter's probe scheme and hash version, and whether
+// the seed is stored, see checkpointSchemes. Filters using seed 0,
+// DoubleHashing and HashV1 are written as version 1, the layout's original
+// version.
+const checkpointMagic = "BFDD"
+
+// checkpointSchemes is the probe scheme and hash version of each checkpoint
+// version
+var checkpointSchemes = map[uint32]struct {
+	probe   bloomfilter.ProbeScheme
+	version bloomfilter.HashVersion
+	seeded  bool
+}{
+	1: {bloomfilter.DoubleHashing, bloomfilter.HashV1, false},
+	2: {bloomfilter.EnhancedDoubleHashing, bloomfilter.HashV1, false},
+	3: {bloomfilter.EnhancedDoubleHashing, bloomfilter.HashV2, false},
+	4: {bloomfilter.DoubleHashing, bloomfilter.HashV2, false},
+	5: {bloomfilter.DoubleHashing, bloomfilter.HashV1, true},
+	6: {bloomfilter.EnhancedDoubleHashing, bloomfilter.HashV1, true},
+	7: {bloomfilter.EnhancedDoubleHashing, bloomfilter.HashV2, true},
+	8: {bloomfilter.DoubleHashing, bloomfilter.HashV2, true},
+}
+
+var errCorrupt = errors.New("dedup: corrupt checkpoint")
+
+// encodeCheckpoint serializes the filter and offsets
+func encodeCheckpoint(bf *bloomfilter.CacheOptimizedBloomFilter, offsets Offsets) []byte {
+	words := bf.Words()
+	buf := make([]byte, 0, 32+8*len(words)+32*len(offsets))
+	buf = append(buf, checkpointMagic...)
+	seeded := bf.Seed() != 0
+	var version uint32
+	for v, s := range checkpointSchemes {
+		if s.probe == bf.ProbeScheme() && s.version == bf.HashVersion() && s.seeded == seeded {
+			version = v
+		}
+	}
+	buf = binary.LittleEndian.AppendUint32(buf, version)
+	buf = binary.LittleEndian.AppendUint32(buf, bf.HashCount())
+	if seeded {
+		buf = binary.LittleEndian.AppendUint64(buf, bf.Seed())
+	}
+	buf = binary.LittleEndian.AppendUint64(buf, uint64(len(words)))
+	for _, w := range words {
+		buf = binary.LittleEndian.AppendUint64(buf, w)
+	}
+
+	// Sorted so identical state always encodes identically
+	partitions := make([]Partition, 0, len(offsets))
+	for p := range offsets {
+		partitions = append(partitions, p)
+	}
+	sort.Slice(partitions, func(i, j int) bool {
+		if partitions[i].Topic != partitions[j].Topic {
+			return partitions[i].Topic < partitions[j].Topic
+		}
+		return partitions[i].Partition < partitions[j].Partition
+	})
+
+	buf = binary.LittleEndian.AppendUint32(buf, uint32(len(partitions)))
+	for _, p := range partitions {
+		buf = binary.LittleEndian.AppendUint16(buf, uint16(len(p.Topic)))
+		buf = append(buf, p.Topic...)
+		buf = binary.LittleEndian.AppendUint32(buf, uint32(p.Partition))
+		buf = binary.LittleEndian.AppendUint64(buf, uint64(offsets[p]))
+	}
+	return binary.LittleEndian.AppendUint32(buf, crc32.ChecksumIEEE(buf))
+}
+
+// decodeCheckpoint parses a checkpoint written by encodeCheckpoint
+func decodeCheckpoint(data []byte) (*bloomfilter.CacheOptimizedBloomFilter, Offsets, error) {
+	if len(data) < 24 || string(data[:4]) != checkpointMagic {
+		return nil, nil, errCorrupt
+	}
+	body, sum := data[:len(data)-4], binary.LittleEndian.Uint32(data[len(data)-4:])
+	if crc32.ChecksumIEEE(body) != sum {
+		return nil, nil, fmt.Errorf("%w: checksum mismatch", errCorrupt)
+	}
+	v := binary.LittleEndian.Uint32(body[4:])
+	scheme, ok := checkpointSchemes[v]
+	if !ok {
+		return nil, nil, fmt.Errorf("dedup: unsupported checkpoint version %d", v)
+	}
+
+	hashCount := binary.LittleEndian.Uint32(body[8:])
+	rest := body[12:]
+	var seed uint64
+	if scheme.seeded {
+		if len(rest) < 16 {
+			return nil, nil, errCorrupt
+		}
+		seed, rest = binary.LittleEndian.Uint64(rest), rest[8:]
+	}
+	wordCount := binary.LittleEndian.Uint64(rest)
+	rest = rest[8:]
+	if wordCount > uint64(len(rest))/8 {
+		return nil, nil, errCorrupt
+	}
+	words := make([]uint64, wordCount)
+	for i := range words {
+		words[i] = binary.LittleEndian.Uint64(rest[8*i:])
+	}
+	rest = rest[8*wordCount:]
+	bf, err := bloomfilter.NewFromWordsWithSeed(words, hashCount, seed)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", errCorrupt, err)
+	}
+	bf.SetProbeScheme(scheme.probe)
+	bf.SetHashVersion(scheme.version)
+
+	if len(rest) < 4 {
+		return nil, nil, errCorrupt
+	}
+	count := binary.LittleEndian.Uint32(rest)
+	rest = rest[4:]
+	offsets := make(Offsets, count)
+	for i := uint32(0); i < count; i++ {
+		if len(rest) < 2 {
+			return nil, nil, errCorrupt
+		}
+		topicLen := int(binary.LittleEndian.Uint16(rest))
+		if len(rest) < 2+topicLen+12 {
+			return nil, nil, errCorrupt
+		}
+		p := Partition{
+			Topic:     string(rest[2 : 2+topicLen]),
+			Partition: int32(binary.LittleEndian.Uint32(rest[2+topicLen:])),
+		}
+		offsets[p] = int64(binary.LittleEndian.Uint64(rest[6+topicLen:]))
+		rest = rest[14+topicLen:]
+	}
+	if len(rest) != 0 {
+		return nil, nil, errCorrupt
+	}
+	return bf, offsets, nil
+}
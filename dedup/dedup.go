@@ -0,0 +1,120 @@
+// Package dedup implements exactly-once deduplication for partitioned, offset
+// based streams such as Kafka topics.
+//
+// A Deduplicator pairs a bloom filter of seen message keys with the next offset
+// to consume for every topic partition, and checkpoints both in a single atomic
+// write. After a restart the consumer seeks every partition to Offsets(): the
+// restored filter contains exactly the keys of the messages before those offsets,
+// so no duplicate is re-admitted and no membership is lost. Messages redelivered
+// below the checkpointed offset are reported as duplicates without a filter lookup.
+//
+// For this to hold, consumer offsets must be taken from the Deduplicator rather
+// than committed to the broker independently.
+package dedup
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"sync"
+
+	bloomfilter "github.com/shaia/BloomFilter"
+)
+
+// Partition identifies a topic partition
+type Partition struct {
+	Topic     string
+	Partition int32
+}
+
+// Offsets maps each partition to the next offset to consume
+type Offsets map[Partition]int64
+
+// Config configures a Deduplicator
+type Config struct {
+	// ExpectedElements and FalsePositiveRate size the filter when no checkpoint exists
+	ExpectedElements  uint64
+	FalsePositiveRate float64
+	// Store persists checkpoints. Required.
+	Store Store
+}
+
+// Deduplicator filters duplicate messages and checkpoints its state together
+// with consumer offsets. It is safe for concurrent use.
+type Deduplicator struct {
+	mu      sync.Mutex
+	filter  *bloomfilter.CacheOptimizedBloomFilter
+	offsets Offsets
+	store   Store
+}
+
+// New creates a Deduplicator, restoring the latest checkpoint from cfg.Store if
+// there is one.
+//
+// Returns an error if the configuration is invalid or the checkpoint cannot be
+// loaded.
+func New(cfg Config) (*Deduplicator, error) {
+	if cfg.Store == nil {
+		return nil, errors.New("dedup: Config.Store is required")
+	}
+
+	d := &Deduplicator{store: cfg.Store, offsets: make(Offsets)}
+	data, err := cfg.Store.Load()
+	if err != nil {
+		return nil, fmt.Errorf("dedup: load checkpoint: %w", err)
+	}
+	if data != nil {
+		d.filter, d.offsets, err = decodeCheckpoint(data)
+		if err != nil {
+			return nil, err
+		}
+		return d, nil
+	}
+
+	d.filter, err = bloomfilter.NewWithError(cfg.ExpectedElements, cfg.FalsePositiveRate)
+	if err != nil {
+		return nil, fmt.Errorf("dedup: invalid filter size: %w", err)
+	}
+	return d, nil
+}
+
+// Process records the message at offset of partition p and reports whether it is
+// a duplicate. A message is a duplicate if its offset is below the partition's
+// next offset (a redelivery) or if its key was already seen (false positives
+// included). Otherwise the key is added and the partition advances past offset.
+func (d *Deduplicator) Process(p Partition, offset int64, key []byte) (duplicate bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if next, ok := d.offsets[p]; ok && offset < next {
+		return true
+	}
+	d.offsets[p] = offset + 1
+
+	return d.filter.TestAndAdd(key)
+}
+
+// Offsets returns a copy of the next offset to consume for every partition seen
+func (d *Deduplicator) Offsets() Offsets {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return maps.Clone(d.offsets)
+}
+
+// Checkpoint atomically persists the filter and offsets. Processing is blocked
+// while the snapshot is taken, not while it is written.
+func (d *Deduplicator) Checkpoint() error {
+	d.mu.Lock()
+	data := encodeCheckpoint(d.filter, d.offsets)
+	d.mu.Unlock()
+
+	if err := d.store.Save(data); err != nil {
+		return fmt.Errorf("dedup: save checkpoint: %w", err)
+	}
+	return nil
+}
+
+// Filter returns the underlying filter. Adding to it directly bypasses offset tracking.
+func (d *Deduplicator) Filter() *bloomfilter.CacheOptimizedBloomFilter {
+	return d.filter
+}
@@ -0,0 +1,139 @@
+package dedup
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	bloomfilter "github.com/shaia/BloomFilter"
+)
+
+// TestRestartExactlyOnce simulates a crash after a checkpoint and a replay of
+// the uncheckpointed tail from the restored offsets
+func TestRestartExactlyOnce(t *testing.T) {
+	store := FileStore{Path: filepath.Join(t.TempDir(), "dedup.ckpt")}
+	cfg := Config{ExpectedElements: 10000, FalsePositiveRate: 0.001, Store: store}
+	p0 := Partition{Topic: "events", Partition: 0}
+	p1 := Partition{Topic: "events", Partition: 1}
+
+	// message i on partition i%2 at offset i/2; keys repeat every 300 messages
+	key := func(i int) []byte { return []byte(fmt.Sprintf("msg_%d", i%300)) }
+	partition := func(i int) Partition {
+		if i%2 == 0 {
+			return p0
+		}
+		return p1
+	}
+
+	d, err := New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	admitted := make(map[string]int)
+	for i := 0; i < 400; i++ {
+		if !d.Process(partition(i), int64(i/2), key(i)) {
+			admitted[string(key(i))]++
+		}
+	}
+	if err := d.Checkpoint(); err != nil {
+		t.Fatal(err)
+	}
+	// Processed but never checkpointed: lost in the crash
+	for i := 400; i < 500; i++ {
+		d.Process(partition(i), int64(i/2), key(i))
+	}
+
+	restored, err := New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	offsets := restored.Offsets()
+	if offsets[p0] != 200 || offsets[p1] != 200 {
+		t.Fatalf("Restored offsets %v, expected 200 for both partitions", offsets)
+	}
+
+	// The broker redelivers from an older committed position; everything below
+	// the restored offsets is a duplicate, the rest is processed normally
+	for i := 300; i < 600; i++ {
+		if !restored.Process(partition(i), int64(i/2), key(i)) {
+			admitted[string(key(i))]++
+		}
+	}
+	for k, n := range admitted {
+		if n != 1 {
+			t.Errorf("Key %s admitted %d times", k, n)
+		}
+	}
+	if len(admitted) != 300 {
+		t.Errorf("Admitted %d distinct keys, expected 300", len(admitted))
+	}
+}
+
+// TestCheckpointEncoding tests round trips and corruption detection
+func TestCheckpointEncoding(t *testing.T) {
+	store := &MemoryStore{}
+	d, err := New(Config{ExpectedElements: 1000, FalsePositiveRate: 0.01, Store: store})
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.Process(Partition{"a", 3}, 41, []byte("x"))
+	d.Process(Partition{"b", 0}, 7, []byte("y"))
+	if err := d.Checkpoint(); err != nil {
+		t.Fatal(err)
+	}
+
+	data, _ := store.Load()
+	bf, offsets, err := decodeCheckpoint(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bf.ContainsString("x") || !bf.ContainsString("y") || bf.HashCount() != d.Filter().HashCount() {
+		t.Error("Filter not restored")
+	}
+	if offsets[Partition{"a", 3}] != 42 || offsets[Partition{"b", 0}] != 8 || len(offsets) != 2 {
+		t.Errorf("Unexpected offsets %v", offsets)
+	}
+
+	for _, i := range []int{0, 10, len(data) / 2, len(data) - 1} {
+		bad := append([]byte(nil), data...)
+		bad[i] ^= 0x40
+		if _, _, err := decodeCheckpoint(bad); err == nil {
+			t.Errorf("Corruption at byte %d not detected", i)
+		}
+	}
+	if _, _, err := decodeCheckpoint(data[:len(data)-5]); !errors.Is(err, errCorrupt) {
+		t.Errorf("Truncation not detected: %v", err)
+	}
+
+	// Each probe scheme, hash version and seeding has its own version, and
+	// filters using the original ones keep the version 1 layout
+	for want, s := range checkpointSchemes {
+		seed := uint64(0)
+		if s.seeded {
+			seed = 0x5eed
+		}
+		bf, _ := bloomfilter.NewWithOptions(bloomfilter.WithExpectedElements(100), bloomfilter.WithSeed(seed),
+			bloomfilter.WithProbeScheme(s.probe), bloomfilter.WithHashVersion(s.version))
+		bf.AddString("x")
+		data = encodeCheckpoint(bf, Offsets{})
+		if v := binary.LittleEndian.Uint32(data[4:]); v != want {
+			t.Errorf("%s %s seed %#x checkpoint has version %d, want %d", s.probe, s.version, seed, v, want)
+		}
+		restored, _, err := decodeCheckpoint(data)
+		if err != nil || restored.ProbeScheme() != s.probe || restored.HashVersion() != s.version || restored.Seed() != seed || !restored.ContainsString("x") {
+			t.Errorf("Version %d checkpoint not restored with %s %s seed %#x: %v", want, s.probe, s.version, seed, err)
+		}
+	}
+
+	if _, err := New(Config{Store: store}); err != nil {
+		t.Errorf("Restoring needs no sizing: %v", err)
+	}
+	if _, err := New(Config{ExpectedElements: 1000, FalsePositiveRate: 0.01}); err == nil {
+		t.Error("Expected error without a Store")
+	}
+	if _, err := New(Config{Store: &MemoryStore{}}); err == nil {
+		t.Error("Expected error for a new filter without sizing")
+	}
+}
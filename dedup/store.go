@@ -0,0 +1,85 @@
+package dedup
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// Store persists checkpoints. Save must replace the previous checkpoint
+// atomically: a crash leaves either the old or the new checkpoint, never a mix.
+type Store interface {
+	Save(data []byte) error
+	// Load returns the latest checkpoint, or nil if none has been saved
+	Load() ([]byte, error)
+}
+
+// FileStore stores checkpoints in a file, replaced atomically through a
+// temporary file and rename
+type FileStore struct {
+	Path string
+}
+
+// Save writes data to a temporary file next to Path, syncs it and renames it over Path
+func (s FileStore) Save(data []byte) error {
+	dir := filepath.Dir(s.Path)
+	tmp, err := os.CreateTemp(dir, filepath.Base(s.Path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // no-op after a successful rename
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), s.Path); err != nil {
+		return err
+	}
+
+	// Persist the rename itself; not supported on every platform
+	if d, err := os.Open(dir); err == nil {
+		d.Sync()
+		d.Close()
+	}
+	return nil
+}
+
+// Load reads the checkpoint file, returning nil if it does not exist
+func (s FileStore) Load() ([]byte, error) {
+	data, err := os.ReadFile(s.Path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	return data, err
+}
+
+// MemoryStore keeps the latest checkpoint in memory, for tests
+type MemoryStore struct {
+	mu   sync.Mutex
+	data []byte
+}
+
+// Save replaces the stored checkpoint with a copy of data
+func (s *MemoryStore) Save(data []byte) error {
+	s.mu.Lock()
+	s.data = append([]byte(nil), data...)
+	s.mu.Unlock()
+	return nil
+}
+
+// Load returns the stored checkpoint, or nil
+func (s *MemoryStore) Load() ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.data, nil
+}
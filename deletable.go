@@ -0,0 +1,220 @@
+package bloomfilter
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+)
+
+// DeletableConfig configures a DeletableFilter
+type DeletableConfig struct {
+	// ExpectedElements and FalsePositiveRate size the filter of added keys
+	ExpectedElements  uint64
+	FalsePositiveRate float64
+	// ExpectedDeletes sizes the tombstone filter of deleted keys. Defaults to
+	// ExpectedElements.
+	ExpectedDeletes uint64
+	// TombstoneFalsePositiveRate is the rate at which a key that was never
+	// deleted is mistaken for a deleted one, which without Verify makes Contains
+	// return a false negative. Defaults to FalsePositiveRate/10.
+	TombstoneFalsePositiveRate float64
+	// Verify, if set, is consulted when the tombstone filter reports an added key
+	// as deleted, making Contains free of false negatives. It should report
+	// whether the key is still present in the source of truth.
+	Verify Verifier
+}
+
+// DeletableStats reports the state of a DeletableFilter
+type DeletableStats struct {
+	Deletes  uint64 // Delete calls since the last compaction
+	Restored int    // Keys added after a matching tombstone, held exactly
+	// TombstoneLoadFactor and TombstoneFPP describe the tombstone filter;
+	// TombstoneFPP is the false negative rate of Contains without Verify
+	TombstoneLoadFactor float64
+	TombstoneFPP        float64
+	Verifications       uint64
+	VerifyErrors        uint64
+	Compactions         uint64
+}
+
+// DeletableFilter supports deletion without the memory of a counting filter by
+// pairing the filter of added keys with a tombstone filter of deleted keys:
+// Contains reports present && !removed. Deleted keys that are added again are
+// kept in a small exact set, since bits cannot be taken out of the tombstones.
+//
+// Tombstones only turn positives into negatives, so the false positive rate is
+// at most that of the filter of added keys. The cost is on the other side: a
+// key that was never deleted but collides in the tombstone filter is reported
+// absent, at a rate of TombstoneFPP. Setting Verify removes these false
+// negatives by checking tombstone hits against the source of truth.
+//
+// Deleted keys keep their bits in the filter of added keys and the tombstone
+// filter only grows, so both rates degrade with churn. Compact rebuilds both
+// from the live keys; NeedsCompaction reports when the tombstones are past
+// their design capacity.
+//
+// Add, Delete and Contains are safe for concurrent use. Compact blocks Add and
+// Delete for its duration; Contains keeps answering from the old filters.
+type DeletableFilter struct {
+	cfg DeletableConfig
+
+	mu    sync.RWMutex // held for writing by Compact, for reading by Add and Delete
+	state atomic.Pointer[deletableState]
+
+	verifications atomic.Uint64
+	verifyErrors  atomic.Uint64
+	compactions   atomic.Uint64
+}
+
+// deletableState is the set of filters swapped by Compact
+type deletableState struct {
+	live       *CacheOptimizedBloomFilter
+	tombstones *CacheOptimizedBloomFilter
+	deletes    atomic.Uint64
+
+	mu       sync.RWMutex
+	restored map[string]struct{}
+}
+
+// NewDeletableFilter creates a DeletableFilter.
+// Returns an error if either filter's sizing is invalid.
+func NewDeletableFilter(cfg DeletableConfig) (*DeletableFilter, error) {
+	if cfg.ExpectedDeletes == 0 {
+		cfg.ExpectedDeletes = cfg.ExpectedElements
+	}
+	if cfg.TombstoneFalsePositiveRate == 0 {
+		cfg.TombstoneFalsePositiveRate = cfg.FalsePositiveRate / 10
+	}
+	if err := checkSizing(cfg.ExpectedElements, cfg.FalsePositiveRate); err != nil {
+		return nil, err
+	}
+	if err := checkSizing(cfg.ExpectedDeletes, cfg.TombstoneFalsePositiveRate); err != nil {
+		return nil, fmt.Errorf("%w (tombstone filter)", err)
+	}
+
+	d := &DeletableFilter{cfg: cfg}
+	d.state.Store(d.newState())
+	return d, nil
+}
+
+func (d *DeletableFilter) newState() *deletableState {
+	return &deletableState{
+		live:       NewCacheOptimizedBloomFilter(d.cfg.ExpectedElements, d.cfg.FalsePositiveRate),
+		tombstones: NewCacheOptimizedBloomFilter(d.cfg.ExpectedDeletes, d.cfg.TombstoneFalsePositiveRate),
+		restored:   make(map[string]struct{}),
+	}
+}
+
+// Add adds data. Adding a deleted key makes it present again; so does adding
+// a key that only collides with the tombstones, which protects it from that
+// false negative.
+func (d *DeletableFilter) Add(data []byte) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	s := d.state.Load()
+	s.live.Add(data)
+	if s.tombstones.Contains(data) {
+		s.mu.Lock()
+		s.restored[string(data)] = struct{}{}
+		s.mu.Unlock()
+	}
+}
+
+// Delete removes data, so Contains reports it absent until it is added again.
+// Deleting a key that was never added only costs tombstone capacity.
+func (d *DeletableFilter) Delete(data []byte) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	s := d.state.Load()
+	s.tombstones.Add(data)
+	s.deletes.Add(1)
+	s.mu.Lock()
+	delete(s.restored, string(data))
+	s.mu.Unlock()
+}
+
+// Contains checks whether data may be present and has not been deleted
+func (d *DeletableFilter) Contains(data []byte) bool {
+	s := d.state.Load()
+	if !s.live.Contains(data) {
+		return false
+	}
+	if !s.tombstones.Contains(data) {
+		return true
+	}
+
+	s.mu.RLock()
+	_, restored := s.restored[string(data)]
+	s.mu.RUnlock()
+	if restored || d.cfg.Verify == nil {
+		return restored
+	}
+
+	// A tombstone hit on a live key is a false negative; the source of truth decides
+	d.verifications.Add(1)
+	present, err := d.cfg.Verify(data)
+	if err != nil {
+		// Fail open: a spurious positive is cheaper than a lost key
+		d.verifyErrors.Add(1)
+		return true
+	}
+	return present
+}
+
+// AddString adds a string element
+func (d *DeletableFilter) AddString(data string) {
+	d.Add(stringBytes(data))
+}
+
+// DeleteString deletes a string element
+func (d *DeletableFilter) DeleteString(data string) {
+	d.Delete(stringBytes(data))
+}
+
+// ContainsString checks a string element
+func (d *DeletableFilter) ContainsString(data string) bool {
+	return d.Contains(stringBytes(data))
+}
+
+// NeedsCompaction reports whether more keys have been deleted than the
+// tombstone filter was sized for, so its false negative rate is above target
+func (d *DeletableFilter) NeedsCompaction() bool {
+	return d.state.Load().deletes.Load() > d.cfg.ExpectedDeletes
+}
+
+// Compact rebuilds the filter from keys, the keys currently present, with
+// empty tombstones and an empty restored set. Adds and deletes wait until it
+// finishes. Returns an error if keys is nil.
+func (d *DeletableFilter) Compact(keys KeySource) error {
+	if keys == nil {
+		return fmt.Errorf("bloomfilter: Compact needs the live keys")
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	s := d.newState()
+	s.live.addSeq(keys.Keys())
+	d.state.Store(s)
+	d.compactions.Add(1)
+	return nil
+}
+
+// Stats returns the filter's statistics
+func (d *DeletableFilter) Stats() DeletableStats {
+	s := d.state.Load()
+	s.mu.RLock()
+	restored := len(s.restored)
+	s.mu.RUnlock()
+	ts := s.tombstones.GetCacheStats()
+	return DeletableStats{
+		Deletes:             s.deletes.Load(),
+		Restored:            restored,
+		TombstoneLoadFactor: ts.LoadFactor,
+		TombstoneFPP:        ts.EstimatedFPP,
+		Verifications:       d.verifications.Load(),
+		VerifyErrors:        d.verifyErrors.Load(),
+		Compactions:         d.compactions.Load(),
+	}
+}
+
+var _ Filter = (*DeletableFilter)(nil)
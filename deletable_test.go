@@ -0,0 +1,147 @@
+package bloomfilter
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+// TestDeletableFilter tests add, delete and re-add
+func TestDeletableFilter(t *testing.T) {
+	d, err := NewDeletableFilter(DeletableConfig{ExpectedElements: 1000, FalsePositiveRate: 0.01})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 100; i++ {
+		d.AddString(fmt.Sprintf("key_%d", i))
+	}
+	for i := 0; i < 50; i++ {
+		d.DeleteString(fmt.Sprintf("key_%d", i))
+	}
+	for i := 0; i < 100; i++ {
+		key := fmt.Sprintf("key_%d", i)
+		if got, want := d.ContainsString(key), i >= 50; got != want {
+			t.Fatalf("ContainsString(%q) = %t, want %t", key, got, want)
+		}
+	}
+
+	d.AddString("key_0")
+	if !d.ContainsString("key_0") {
+		t.Fatal("Re-added key not found")
+	}
+	d.DeleteString("key_0")
+	if d.ContainsString("key_0") {
+		t.Fatal("Key deleted again still found")
+	}
+
+	s := d.Stats()
+	if s.Deletes != 51 || s.Restored != 0 || s.TombstoneLoadFactor == 0 {
+		t.Errorf("Unexpected stats %+v", s)
+	}
+}
+
+// TestDeletableFilterVerify tests that Verify removes tombstone false negatives
+func TestDeletableFilterVerify(t *testing.T) {
+	present := make(map[string]bool)
+	var verifyErr error
+	verify := func(key []byte) (bool, error) { return present[string(key)], verifyErr }
+
+	// A tombstone filter far past its capacity collides with many live keys
+	cfg := DeletableConfig{ExpectedElements: 1000, FalsePositiveRate: 0.01, ExpectedDeletes: 10}
+	plain, _ := NewDeletableFilter(cfg)
+	cfg.Verify = verify
+	verified, _ := NewDeletableFilter(cfg)
+
+	for i := 0; i < 1000; i++ {
+		key := fmt.Sprintf("live_%d", i)
+		present[key] = true
+		plain.AddString(key)
+		verified.AddString(key)
+	}
+	for i := 0; i < 500; i++ {
+		plain.DeleteString(fmt.Sprintf("gone_%d", i))
+		verified.DeleteString(fmt.Sprintf("gone_%d", i))
+	}
+	if !plain.NeedsCompaction() {
+		t.Error("Expected NeedsCompaction past ExpectedDeletes")
+	}
+
+	falseNegatives := 0
+	for key := range present {
+		if !plain.ContainsString(key) {
+			falseNegatives++
+		}
+		if !verified.ContainsString(key) {
+			t.Fatalf("Verified filter lost live key %q", key)
+		}
+	}
+	if falseNegatives == 0 {
+		t.Fatal("Expected tombstone false negatives without Verify")
+	}
+	if verified.Stats().Verifications == 0 {
+		t.Error("Expected verifications")
+	}
+
+	// Verify errors fail open
+	verifyErr = errors.New("store down")
+	for key := range present {
+		if !verified.ContainsString(key) {
+			t.Fatalf("Verify error lost live key %q", key)
+		}
+	}
+	if verified.Stats().VerifyErrors == 0 {
+		t.Error("Expected verify errors to be counted")
+	}
+}
+
+// TestDeletableFilterCompact tests that compaction rebuilds from the live keys
+func TestDeletableFilterCompact(t *testing.T) {
+	d, _ := NewDeletableFilter(DeletableConfig{ExpectedElements: 1000, FalsePositiveRate: 0.01, ExpectedDeletes: 10})
+	live := NewMemoryKeyLog()
+	for i := 0; i < 100; i++ {
+		key := []byte(fmt.Sprintf("key_%d", i))
+		d.Add(key)
+		if i%2 == 0 {
+			d.Delete(key)
+		} else if err := live.Record(key); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !d.NeedsCompaction() {
+		t.Fatal("Expected NeedsCompaction after 50 deletes")
+	}
+
+	if err := d.Compact(live); err != nil {
+		t.Fatal(err)
+	}
+	if d.NeedsCompaction() {
+		t.Error("NeedsCompaction after Compact")
+	}
+	s := d.Stats()
+	if s.Deletes != 0 || s.TombstoneLoadFactor != 0 || s.Compactions != 1 {
+		t.Errorf("Unexpected stats after Compact %+v", s)
+	}
+	for i := 0; i < 100; i++ {
+		key := fmt.Sprintf("key_%d", i)
+		if i%2 == 1 && !d.ContainsString(key) {
+			t.Fatalf("Live key %q lost by Compact", key)
+		}
+	}
+	if err := d.Compact(nil); err == nil {
+		t.Error("Expected an error for nil keys")
+	}
+}
+
+// TestDeletableFilterValidation tests invalid sizing
+func TestDeletableFilterValidation(t *testing.T) {
+	for _, cfg := range []DeletableConfig{
+		{FalsePositiveRate: 0.01},
+		{ExpectedElements: 100, FalsePositiveRate: 1.5},
+		{ExpectedElements: 100, FalsePositiveRate: 0.01, TombstoneFalsePositiveRate: 2},
+	} {
+		if _, err := NewDeletableFilter(cfg); err == nil {
+			t.Errorf("Expected an error for %+v", cfg)
+		}
+	}
+}
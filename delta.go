@@ -0,0 +1,297 @@
+package bloomfilter
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"math/bits"
+	"sync"
+	"sync/atomic"
+)
+
+// Delta format, all integers little-endian:
+//
+//	magic "BLMD" | version u16 | flags u16 | hashCount u32 | reserved u32 |
+//	bitCount u64 | seed u64 | baseVersion u64 | changeVersion u64 | lines u64 |
+//	lines × (index u64 | words [8]u64) | crc32c of everything before it
+//
+// Each entry is the whole content of a cache line that changed after
+// baseVersion, in increasing index order; changeVersion is baseVersion+1.
+// Flag bits 0 and 1 mark EnhancedDoubleHashing and HashV2, as in the native
+// format; bit 2 marks a custom hasher (see WithHasher).
+const (
+	deltaMagic         = "BLMD"
+	deltaHeaderSize    = 56
+	deltaEntrySize     = 8 + CacheLineSize
+	deltaFormatVersion = 1
+
+	flagCustomHasher = 1 << 2
+)
+
+// ErrDeltaUnavailable is returned by ExportDelta for a base version other than
+// the filter's current change version: only the changes since the latest
+// export are kept, so a replica that missed a delta must resync from a full
+// encoding
+var ErrDeltaUnavailable = errors.New("bloomfilter: delta base version not available")
+
+// ErrDeltaGap is returned by ApplyDelta for a delta whose base version is past
+// the latest one applied: the deltas in between were missed, so the replica
+// must resync from a full encoding
+var ErrDeltaGap = errors.New("bloomfilter: delta does not follow the last applied delta")
+
+// changeTracker records which cache lines changed since the last delta
+type changeTracker struct {
+	// Serializes exports
+	mu      sync.Mutex
+	version atomic.Uint64
+	// One bit per cache line, set atomically by writers
+	dirty []uint64
+}
+
+// mark records that a cache line changed
+func (t *changeTracker) mark(line uint64) {
+	word, bit := &t.dirty[line/64], uint64(1)<<(line%64)
+	// Most writes hit lines already marked; skip the read-modify-write
+	if atomic.LoadUint64(word)&bit == 0 {
+		atomic.OrUint64(word, bit)
+	}
+}
+
+// markAll records that every cache line may have changed
+func (t *changeTracker) markAll(cacheLineCount uint64) {
+	for i := range t.dirty {
+		mask := ^uint64(0)
+		if rest := cacheLineCount - uint64(i)*64; rest < 64 {
+			mask = 1<<rest - 1
+		}
+		atomic.StoreUint64(&t.dirty[i], mask)
+	}
+}
+
+// EnableChangeTracking starts recording which cache lines change, so that
+// ExportDelta can send a replica only those lines instead of the whole filter.
+// The record costs one bit per cache line (0.2% of the filter), and each add
+// that sets a new bit pays for an atomic load of the line's bit, plus an
+// atomic OR the first time the line changes after an export. Calling it again
+// keeps the record.
+//
+// Tracking starts at change version 0, the base of the first delta. A replica
+// is seeded with a full encoding (MarshalBinary, WriteTo) taken after tracking
+// started; adds racing with that copy are in the next delta either way.
+func (bf *CacheOptimizedBloomFilter) EnableChangeTracking() {
+	if bf.changes.Load() != nil {
+		return
+	}
+	bf.changes.CompareAndSwap(nil, &changeTracker{dirty: make([]uint64, (bf.cacheLineCount+63)/64)})
+}
+
+// ChangeVersion returns the number of deltas exported since change tracking
+// started, the base version the next ExportDelta takes; 0 when tracking is off
+func (bf *CacheOptimizedBloomFilter) ChangeVersion() uint64 {
+	if t := bf.changes.Load(); t != nil {
+		return t.version.Load()
+	}
+	return 0
+}
+
+// ExportDelta encodes the cache lines that changed since change version since,
+// which must be the current ChangeVersion, and advances the change version by
+// one. Each changed line is sent whole, 72 bytes, so a delta is proportional
+// to the lines written rather than to the filter: a minute of adds to a 2 GiB
+// filter typically dirties a few MiB of lines. Bulk operations (Clear, Union,
+// Intersection, the differences) mark every line.
+//
+// Adds running during the export are in this delta or in the next one. Any
+// other base version returns ErrDeltaUnavailable, and the error is returned if
+// change tracking is off (see EnableChangeTracking).
+func (bf *CacheOptimizedBloomFilter) ExportDelta(since uint64) ([]byte, error) {
+	t := bf.changes.Load()
+	if t == nil {
+		return nil, fmt.Errorf("bloomfilter: ExportDelta requires EnableChangeTracking")
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if v := t.version.Load(); since != v {
+		return nil, fmt.Errorf("%w: version %d, the filter is at %d", ErrDeltaUnavailable, since, v)
+	}
+
+	b := make([]byte, 0, deltaHeaderSize+formatTrailer)
+	b = append(b, deltaMagic...)
+	b = binary.LittleEndian.AppendUint16(b, deltaFormatVersion)
+	var flags uint16
+	if bf.probe == EnhancedDoubleHashing {
+		flags |= flagEnhancedProbing
+	}
+	if bf.hashVersion == HashV2 {
+		flags |= flagHashV2
+	}
+	if bf.hasher != nil {
+		flags |= flagCustomHasher
+	}
+	b = binary.LittleEndian.AppendUint16(b, flags)
+	b = binary.LittleEndian.AppendUint32(b, bf.hashCount)
+	b = binary.LittleEndian.AppendUint32(b, 0) // reserved
+	b = binary.LittleEndian.AppendUint64(b, bf.bitCount)
+	b = binary.LittleEndian.AppendUint64(b, bf.seed)
+	b = binary.LittleEndian.AppendUint64(b, since)
+	b = binary.LittleEndian.AppendUint64(b, since+1)
+	b = binary.LittleEndian.AppendUint64(b, 0) // lines, filled in below
+
+	var lines uint64
+	for i := range t.dirty {
+		// Clearing the bits before reading the lines leaves a racing add's
+		// line marked for the next delta
+		for dirty := atomic.SwapUint64(&t.dirty[i], 0); dirty != 0; dirty &= dirty - 1 {
+			idx := uint64(i)*64 + uint64(bits.TrailingZeros64(dirty))
+			b = binary.LittleEndian.AppendUint64(b, idx)
+			line := &bf.cacheLines[idx]
+			for j := range line.words {
+				b = binary.LittleEndian.AppendUint64(b, atomic.LoadUint64(&line.words[j]))
+			}
+			lines++
+		}
+	}
+	binary.LittleEndian.PutUint64(b[deltaHeaderSize-8:], lines)
+	t.version.Store(since + 1)
+	return binary.LittleEndian.AppendUint32(b, crc32.Checksum(b, castagnoli)), nil
+}
+
+// markAllChanged marks every cache line for the next delta after a bulk
+// operation
+func (bf *CacheOptimizedBloomFilter) markAllChanged() {
+	if t := bf.changes.Load(); t != nil {
+		t.markAll(bf.cacheLineCount)
+	}
+}
+
+// ApplyDelta ORs the cache lines of a delta written by ExportDelta into the
+// filter, so a replica seeded with a full encoding of the primary follows its
+// adds. The delta is verified whole before any bit is set: malformed or
+// truncated input returns an error wrapping ErrInvalidEncoding, a failed
+// checksum a *CorruptDataError, and a primary with other parameters an
+// *IncompatibleError.
+//
+// Applying is idempotent: a delta applied twice, or an older one applied late,
+// sets no bit the primary did not. A delta whose base version is past the
+// latest applied one (AppliedDeltaVersion) returns ErrDeltaGap, since the
+// deltas in between are missing; the first delta applied may have any base
+// version at or before the primary's ChangeVersion when the seed was taken.
+//
+// Only set bits travel: the lines are ORed, so a Clear (or Intersection, or a
+// difference) on the primary clears nothing on the replica. Reseed replicas
+// from a full encoding after such operations.
+func (bf *CacheOptimizedBloomFilter) ApplyDelta(r io.Reader) error {
+	bf.checkWritable()
+	header := make([]byte, deltaHeaderSize)
+	if err := readDelta(r, header, 0); err != nil {
+		return err
+	}
+	if string(header[:4]) != deltaMagic {
+		return fmt.Errorf("%w: missing delta magic", ErrInvalidEncoding)
+	}
+	if version := binary.LittleEndian.Uint16(header[4:]); version != deltaFormatVersion {
+		return fmt.Errorf("%w: unsupported delta version %d", ErrInvalidEncoding, version)
+	}
+	flags := binary.LittleEndian.Uint16(header[6:])
+	if unknown := flags &^ (flagEnhancedProbing | flagHashV2 | flagCustomHasher); unknown != 0 {
+		return fmt.Errorf("%w: unknown delta flags %#x", ErrInvalidEncoding, unknown)
+	}
+	primary := CacheOptimizedBloomFilter{
+		hashCount: binary.LittleEndian.Uint32(header[8:]),
+		bitCount:  binary.LittleEndian.Uint64(header[16:]),
+		seed:      binary.LittleEndian.Uint64(header[24:]),
+	}
+	if flags&flagEnhancedProbing != 0 {
+		primary.probe = EnhancedDoubleHashing
+	}
+	if flags&flagHashV2 != 0 {
+		primary.hashVersion = HashV2
+	}
+	if flags&flagCustomHasher != 0 {
+		// Stands in for the primary's hasher, which the delta does not carry
+		primary.hasher = func([]byte) (uint64, uint64) { return 0, 0 }
+	}
+	if err := bf.checkCombinable(&primary, "apply delta"); err != nil {
+		return err
+	}
+	base, next := binary.LittleEndian.Uint64(header[32:]), binary.LittleEndian.Uint64(header[40:])
+	if next != base+1 {
+		return fmt.Errorf("%w: delta from version %d to %d", ErrInvalidEncoding, base, next)
+	}
+	lines := binary.LittleEndian.Uint64(header[48:])
+	if lines > bf.cacheLineCount {
+		return fmt.Errorf("%w: %d lines in a delta for %d cache lines", ErrInvalidEncoding, lines, bf.cacheLineCount)
+	}
+	if applied := bf.deltaApplied.Load(); applied != 0 && base > applied {
+		return fmt.Errorf("%w: delta from version %d, last applied %d", ErrDeltaGap, base, applied)
+	}
+
+	body := make([]byte, lines*deltaEntrySize+formatTrailer)
+	if err := readDelta(r, body, deltaHeaderSize); err != nil {
+		return err
+	}
+	entries := body[:len(body)-formatTrailer]
+	sum := crc32.Update(crc32.Checksum(header, castagnoli), castagnoli, entries)
+	if binary.LittleEndian.Uint32(body[len(entries):]) != sum {
+		return &CorruptDataError{Region: "delta", Offset: 0, Length: int64(deltaHeaderSize + len(body))}
+	}
+	for e, prev := entries, int64(-1); len(e) > 0; e = e[deltaEntrySize:] {
+		idx := binary.LittleEndian.Uint64(e)
+		if idx >= bf.cacheLineCount || int64(idx) <= prev {
+			return fmt.Errorf("%w: delta line %d out of order or range", ErrInvalidEncoding, idx)
+		}
+		prev = int64(idx)
+	}
+
+	for e := entries; len(e) > 0; e = e[deltaEntrySize:] {
+		bf.orLine(binary.LittleEndian.Uint64(e), e[8:deltaEntrySize])
+	}
+	for {
+		applied := bf.deltaApplied.Load()
+		if next <= applied || bf.deltaApplied.CompareAndSwap(applied, next) {
+			return nil
+		}
+	}
+}
+
+// AppliedDeltaVersion returns the change version of the latest delta applied
+// by ApplyDelta, the base version of the next one; 0 before the first
+func (bf *CacheOptimizedBloomFilter) AppliedDeltaVersion() uint64 {
+	return bf.deltaApplied.Load()
+}
+
+// readDelta fills p from r, reporting a short read as truncation at the
+// offset of the byte that is missing
+func readDelta(r io.Reader, p []byte, offset int) error {
+	n, err := io.ReadFull(r, p)
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return fmt.Errorf("%w: %w at byte %d", ErrCorruptData, io.ErrUnexpectedEOF, offset+n)
+	}
+	return err
+}
+
+// orLine atomically ORs the little-endian words of data into a cache line,
+// with the bookkeeping of OrWord for the bits it sets
+func (bf *CacheOptimizedBloomFilter) orLine(idx uint64, data []byte) {
+	line := &bf.cacheLines[idx]
+	var flipped int
+	for j := range line.words {
+		mask := binary.LittleEndian.Uint64(data[8*j:])
+		if mask != 0 {
+			flipped += bits.OnesCount64(mask &^ atomic.OrUint64(&line.words[j], mask))
+		}
+	}
+	if flipped == 0 {
+		return
+	}
+	if t := bf.changes.Load(); t != nil {
+		t.mark(idx)
+	}
+	bf.recordFlips(uint64(flipped))
+	if g := bf.ghost.Load(); g != nil {
+		g.invalidate()
+	}
+	bf.recordExternal()
+}
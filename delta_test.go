@@ -0,0 +1,277 @@
+package bloomfilter
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"hash/maphash"
+	"sync"
+	"testing"
+)
+
+// applyDeltaLines copies the lines of a delta into dst and returns the indexes,
+// checking the header against src
+func applyDeltaLines(t *testing.T, dst, src *CacheOptimizedBloomFilter, delta []byte, since uint64) []uint64 {
+	t.Helper()
+	body := delta[:len(delta)-formatTrailer]
+	if crc32.Checksum(body, castagnoli) != binary.LittleEndian.Uint32(delta[len(body):]) {
+		t.Fatal("Delta checksum mismatch")
+	}
+	if string(delta[:4]) != deltaMagic || binary.LittleEndian.Uint32(delta[8:]) != src.hashCount ||
+		binary.LittleEndian.Uint64(delta[16:]) != src.bitCount || binary.LittleEndian.Uint64(delta[24:]) != src.seed {
+		t.Fatal("Delta header does not describe the filter")
+	}
+	if base, next := binary.LittleEndian.Uint64(delta[32:]), binary.LittleEndian.Uint64(delta[40:]); base != since || next != since+1 {
+		t.Fatalf("Delta versions %d to %d, want %d to %d", base, next, since, since+1)
+	}
+	n := binary.LittleEndian.Uint64(delta[48:])
+	if uint64(len(body)) != deltaHeaderSize+n*deltaEntrySize {
+		t.Fatalf("Delta of %d bytes holds %d lines", len(delta), n)
+	}
+	var lines []uint64
+	for e := body[deltaHeaderSize:]; len(e) > 0; e = e[deltaEntrySize:] {
+		idx := binary.LittleEndian.Uint64(e)
+		for j := range dst.cacheLines[idx].words {
+			dst.cacheLines[idx].words[j] = binary.LittleEndian.Uint64(e[8+8*j:])
+		}
+		lines = append(lines, idx)
+	}
+	return lines
+}
+
+// TestExportDelta tests that deltas hold exactly the changed lines
+func TestExportDelta(t *testing.T) {
+	bf := NewCacheOptimizedBloomFilter(10000, 0.01)
+	if _, err := bf.ExportDelta(0); err == nil {
+		t.Fatal("ExportDelta without change tracking succeeded")
+	}
+	replica := bf.Clone()
+	bf.EnableChangeTracking()
+
+	for i := uint64(0); i < 100; i++ {
+		bf.AddUint64(i)
+	}
+	delta, err := bf.ExportDelta(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := applyDeltaLines(t, replica, bf, delta, 0)
+	var nonEmpty int
+	for i := range bf.cacheLines {
+		if bf.cacheLines[i].words != [WordsPerCacheLine]uint64{} {
+			nonEmpty++
+		}
+	}
+	if len(lines) != nonEmpty || !replica.Equal(bf) {
+		t.Errorf("First delta has %d lines, filter has %d non-empty", len(lines), nonEmpty)
+	}
+	if bf.ChangeVersion() != 1 {
+		t.Errorf("ChangeVersion() = %d, want 1", bf.ChangeVersion())
+	}
+	if _, err := bf.ExportDelta(0); !errors.Is(err, ErrDeltaUnavailable) {
+		t.Errorf("Stale base: expected ErrDeltaUnavailable, got %v", err)
+	}
+
+	// Re-adding keys changes nothing; a new key changes at most k lines
+	for i := uint64(0); i < 100; i++ {
+		bf.AddUint64(i)
+	}
+	delta, _ = bf.ExportDelta(1)
+	if lines := applyDeltaLines(t, replica, bf, delta, 1); len(lines) != 0 {
+		t.Errorf("Duplicate adds dirtied %d lines", len(lines))
+	}
+	bf.AddString("new key")
+	delta, _ = bf.ExportDelta(2)
+	if lines := applyDeltaLines(t, replica, bf, delta, 2); len(lines) == 0 || len(lines) > int(bf.hashCount) {
+		t.Errorf("One add dirtied %d lines", len(lines))
+	}
+	if !replica.Equal(bf) {
+		t.Error("Replica diverged")
+	}
+
+	// Bulk operations mark every line
+	bf.Clear()
+	delta, _ = bf.ExportDelta(3)
+	if lines := applyDeltaLines(t, replica, bf, delta, 3); len(lines) != len(bf.cacheLines) {
+		t.Errorf("Clear dirtied %d of %d lines", len(lines), len(bf.cacheLines))
+	}
+	if !replica.Equal(bf) {
+		t.Error("Replica diverged after Clear")
+	}
+}
+
+// TestExportDeltaConcurrent tests that deltas taken during adds lose none of them
+func TestExportDeltaConcurrent(t *testing.T) {
+	bf := NewCacheOptimizedBloomFilter(100000, 0.01)
+	replica := bf.Clone()
+	bf.EnableChangeTracking()
+
+	var wg sync.WaitGroup
+	for w := uint64(0); w < 4; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := uint64(0); i < 20000; i++ {
+				bf.AddUint64(w<<32 | i)
+				if i%1000 == 0 {
+					bf.OrWord(int(i)%bf.WordCount(), 1<<w)
+				}
+			}
+		}()
+	}
+	version := uint64(0)
+	export := func() {
+		delta, err := bf.ExportDelta(version)
+		if err != nil {
+			t.Fatal(err)
+		}
+		applyDeltaLines(t, replica, bf, delta, version)
+		version++
+	}
+	for i := 0; i < 20; i++ {
+		export()
+	}
+	wg.Wait()
+	export()
+	if !replica.Equal(bf) {
+		t.Error("Replica misses adds that raced with the exports")
+	}
+}
+
+// TestApplyDelta tests that replicas follow the primary through deltas
+func TestApplyDelta(t *testing.T) {
+	primary := NewCacheOptimizedBloomFilter(10000, 0.01)
+	primary.EnableChangeTracking()
+	primary.AddString("before seeding")
+	seed, _ := primary.MarshalBinary()
+	replica := &CacheOptimizedBloomFilter{}
+	if err := replica.UnmarshalBinary(seed); err != nil {
+		t.Fatal(err)
+	}
+
+	var deltas [][]byte
+	for v := uint64(0); v < 3; v++ {
+		for i := uint64(0); i < 50; i++ {
+			primary.AddUint64(v*1000 + i)
+		}
+		delta, err := primary.ExportDelta(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		deltas = append(deltas, delta)
+	}
+	if err := replica.ApplyDelta(bytes.NewReader(deltas[1])); err != nil {
+		t.Fatal(err)
+	}
+	if err := replica.ApplyDelta(bytes.NewReader(deltas[0])); err != nil {
+		t.Errorf("Late delta: %v", err)
+	}
+	for _, d := range deltas[1:] {
+		if err := replica.ApplyDelta(bytes.NewReader(d)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !replica.Equal(primary) || replica.AppliedDeltaVersion() != 3 {
+		t.Errorf("Replica at version %d is not equal to the primary", replica.AppliedDeltaVersion())
+	}
+
+	// A missed delta leaves a gap
+	primary.AddString("missed")
+	primary.ExportDelta(3)
+	primary.AddString("after the gap")
+	delta, _ := primary.ExportDelta(4)
+	if err := replica.ApplyDelta(bytes.NewReader(delta)); !errors.Is(err, ErrDeltaGap) {
+		t.Errorf("Gap: expected ErrDeltaGap, got %v", err)
+	}
+
+	// A primary Clear clears nothing on the replica
+	primary.Clear()
+	delta, _ = primary.ExportDelta(5)
+	fresh := primary.Clone()
+	fresh.AddString("before seeding")
+	if err := fresh.ApplyDelta(bytes.NewReader(delta)); err != nil || !fresh.ContainsString("before seeding") {
+		t.Errorf("Clear propagated through a delta: %v", err)
+	}
+}
+
+// TestApplyDeltaCustomHasher tests deltas between filters with custom hashers
+func TestApplyDeltaCustomHasher(t *testing.T) {
+	h := MapHash(maphash.MakeSeed())
+	opts := []Option{WithExpectedElements(10000), WithFalsePositiveRate(0.01), WithHasher(h)}
+	primary, _ := NewWithOptions(opts...)
+	// Custom hashers ignore the seed, so the replica's may differ
+	replica, _ := NewWithOptions(opts...)
+	primary.EnableChangeTracking()
+	for i := uint64(0); i < 100; i++ {
+		primary.AddUint64(i)
+	}
+	delta, err := primary.ExportDelta(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := replica.ApplyDelta(bytes.NewReader(delta)); err != nil {
+		t.Fatal(err)
+	}
+	if !replica.Equal(primary) || !replica.ContainsUint64(42) {
+		t.Error("Replica is not equal to the primary")
+	}
+
+	builtin, _ := NewWithOptions(opts[0], opts[1], WithSeed(primary.Seed()))
+	var ie *IncompatibleError
+	if err := builtin.ApplyDelta(bytes.NewReader(delta)); !errors.As(err, &ie) || ie.Param != ParamHasher {
+		t.Errorf("Built-in hasher: expected an IncompatibleError, got %v", err)
+	}
+	primary.AddUint64(1000)
+	delta, _ = primary.ExportDelta(1)
+	if err := replica.ApplyDelta(bytes.NewReader(delta)); err != nil || !replica.ContainsUint64(1000) {
+		t.Errorf("Second delta: %v", err)
+	}
+}
+
+// TestApplyDeltaInvalid tests that bad deltas leave the replica unchanged
+func TestApplyDeltaInvalid(t *testing.T) {
+	primary := NewCacheOptimizedBloomFilter(10000, 0.01)
+	blank := primary.Clone()
+	primary.EnableChangeTracking()
+	primary.AddString("key")
+	delta, _ := primary.ExportDelta(0)
+	mutate := func(fn func([]byte)) []byte {
+		d := bytes.Clone(delta)
+		fn(d)
+		return d
+	}
+
+	tests := []struct {
+		name   string
+		delta  []byte
+		target error
+	}{
+		{"magic", mutate(func(b []byte) { b[0] = 'X' }), ErrInvalidEncoding},
+		{"version", mutate(func(b []byte) { b[4] = 2 }), ErrInvalidEncoding},
+		{"lines", mutate(func(b []byte) { binary.LittleEndian.PutUint64(b[48:], 1<<60) }), ErrInvalidEncoding},
+		{"checksum", mutate(func(b []byte) { b[len(b)-10] ^= 1 }), ErrCorruptData},
+		{"truncated", delta[:len(delta)-1], ErrCorruptData},
+		{"empty", nil, ErrCorruptData},
+		{"hash count", mutate(func(b []byte) { b[8]++ }), ErrIncompatibleHash},
+		{"seed", mutate(func(b []byte) { b[24]++ }), ErrSeedMismatch},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			replica := blank.Clone()
+			err := replica.ApplyDelta(bytes.NewReader(tt.delta))
+			if !errors.Is(err, tt.target) {
+				t.Errorf("Expected %v, got %v", tt.target, err)
+			}
+			if replica.PopCount() != 0 || replica.AppliedDeltaVersion() != 0 {
+				t.Error("Invalid delta changed the replica")
+			}
+		})
+	}
+
+	other := NewCacheOptimizedBloomFilter(20000, 0.01)
+	var ie *IncompatibleError
+	if err := other.ApplyDelta(bytes.NewReader(delta)); !errors.As(err, &ie) || ie.Param != ParamBitCount {
+		t.Errorf("Bit count: expected an IncompatibleError, got %v", err)
+	}
+}
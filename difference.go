@@ -0,0 +1,87 @@
+package bloomfilter
+
+import "unsafe"
+
+// Set difference
+//
+// Difference and SymmetricDifference combine the bit arrays like Union and
+// Intersection do, with the AND NOT and XOR kernels. Unlike those two, the
+// result is not the filter of the corresponding key set: a key is represented by
+// several bits, and a key that belongs in the result loses any of its bits that
+// the other filter also has set, for a key of its own or a false positive. Keys
+// that must be excluded are always excluded, but keys that should remain may be
+// dropped, so the result can have false negatives: a key survives with
+// probability about (1-f)^k, where f is the fraction of the other filter's bits
+// set and k the hash count, so 93% survive at k=7 and f=1%. For exact answers,
+// check each key with a.Contains(k) && !b.Contains(k).
+//
+// All four functions return an *IncompatibleError if the filters are not
+// Compatible.
+
+// Difference removes from the filter every bit set in other, keeping the keys
+// that were added to the filter but not to other. Every key of other is removed;
+// a key only in the filter is kept unless one of its bits is set in other, so
+// the result can have false negatives.
+func (bf *CacheOptimizedBloomFilter) Difference(other *CacheOptimizedBloomFilter) error {
+	return bf.combineWith(other, "difference", bf.simdOps.VectorAndNot)
+}
+
+// SymmetricDifference keeps the bits set in exactly one of the two filters. Keys
+// added to both are removed; a key added to only one is kept unless one of its
+// bits is set in the other, so the result can have false negatives. Applying
+// the same SymmetricDifference twice restores the filter.
+func (bf *CacheOptimizedBloomFilter) SymmetricDifference(other *CacheOptimizedBloomFilter) error {
+	return bf.combineWith(other, "symmetric difference", bf.simdOps.VectorXor)
+}
+
+// DifferenceOf returns a new filter holding a.Difference(b), leaving a and b
+// unchanged. The result is a Clone of a, with the same settings.
+func DifferenceOf(a, b *CacheOptimizedBloomFilter) (*CacheOptimizedBloomFilter, error) {
+	if err := a.checkCombinable(b, "difference"); err != nil {
+		return nil, err
+	}
+	c := a.Clone()
+	if err := c.Difference(b); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// SymmetricDifferenceOf returns a new filter holding a.SymmetricDifference(b),
+// leaving a and b unchanged. The result is a Clone of a, with the same settings.
+func SymmetricDifferenceOf(a, b *CacheOptimizedBloomFilter) (*CacheOptimizedBloomFilter, error) {
+	if err := a.checkCombinable(b, "symmetric difference"); err != nil {
+		return nil, err
+	}
+	c := a.Clone()
+	if err := c.SymmetricDifference(b); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// combineWith applies kernel to the filter's bits and other's, then updates the
+// state derived from the bits
+func (bf *CacheOptimizedBloomFilter) combineWith(other *CacheOptimizedBloomFilter, op string,
+	kernel func(dst, src unsafe.Pointer, length int)) error {
+	bf.checkWritable()
+	if err := bf.checkCombinable(other, op); err != nil {
+		return err
+	}
+	if bf.cacheLineCount == 0 {
+		return nil
+	}
+
+	kernel(
+		unsafe.Pointer(&bf.cacheLines[0]),
+		unsafe.Pointer(&other.cacheLines[0]),
+		int(bf.cacheLineCount*CacheLineSize),
+	)
+	bf.markAllChanged()
+	bf.resyncBitsSet()
+	if g := bf.ghost.Load(); g != nil {
+		g.invalidate()
+	}
+	bf.recordExternal()
+	return nil
+}
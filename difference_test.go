@@ -0,0 +1,170 @@
+package bloomfilter
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"testing"
+)
+
+// newDifferenceFilters returns two filters sharing keys [1000, 2000), with
+// [0, 1000) only in a and [2000, 3000) only in b
+func newDifferenceFilters(simdDisabled bool) (a, b *CacheOptimizedBloomFilter) {
+	opts := []Option{WithExpectedElements(100000), WithSeed(7)}
+	if simdDisabled {
+		opts = append(opts, WithSIMDDisabled())
+	}
+	a, _ = NewWithOptions(opts...)
+	b, _ = NewWithOptions(opts...)
+	for i := 0; i < 2000; i++ {
+		a.AddUint64(uint64(i))
+		b.AddUint64(uint64(i + 1000))
+	}
+	return a, b
+}
+
+// minKept returns 90% of the number of n keys expected to keep all their bits
+// when the bits set in other are removed
+func minKept(other *CacheOptimizedBloomFilter, n int) int {
+	fill := float64(other.PopCount()) / float64(other.BitCount())
+	return int(0.9 * float64(n) * math.Pow(1-fill, float64(other.HashCount())))
+}
+
+// countContained returns how many keys in [lo, hi) the filter contains
+func countContained(bf *CacheOptimizedBloomFilter, lo, hi int) int {
+	n := 0
+	for i := lo; i < hi; i++ {
+		if bf.ContainsUint64(uint64(i)) {
+			n++
+		}
+	}
+	return n
+}
+
+// TestDifference tests that Difference drops every shared key and keeps nearly
+// all keys only in the receiver
+func TestDifference(t *testing.T) {
+	for _, simdDisabled := range []bool{false, true} {
+		t.Run(fmt.Sprintf("SIMDDisabled_%t", simdDisabled), func(t *testing.T) {
+			a, b := newDifferenceFilters(simdDisabled)
+			want := minKept(b, 1000)
+			if err := a.Difference(b); err != nil {
+				t.Fatal(err)
+			}
+			if n := countContained(a, 1000, 2000); n != 0 {
+				t.Errorf("%d shared keys survived Difference", n)
+			}
+			if n := countContained(a, 0, 1000); n < want {
+				t.Errorf("Difference kept %d of 1000 keys only in the receiver", n)
+			}
+			if n := countContained(a, 2000, 3000); n != 0 {
+				t.Errorf("Difference reports %d keys only in the other filter", n)
+			}
+		})
+	}
+}
+
+// TestSymmetricDifference tests that SymmetricDifference keeps the keys in
+// exactly one filter and is its own inverse
+func TestSymmetricDifference(t *testing.T) {
+	for _, simdDisabled := range []bool{false, true} {
+		t.Run(fmt.Sprintf("SIMDDisabled_%t", simdDisabled), func(t *testing.T) {
+			a, b := newDifferenceFilters(simdDisabled)
+			orig := a.Clone()
+			want := minKept(b, 1000) // Both filters hold 2000 keys
+			if err := a.SymmetricDifference(b); err != nil {
+				t.Fatal(err)
+			}
+			if n := countContained(a, 1000, 2000); n != 0 {
+				t.Errorf("%d shared keys survived SymmetricDifference", n)
+			}
+			for _, r := range [][2]int{{0, 1000}, {2000, 3000}} {
+				if n := countContained(a, r[0], r[1]); n < want {
+					t.Errorf("SymmetricDifference kept %d of 1000 keys in [%d, %d)", n, r[0], r[1])
+				}
+			}
+
+			if err := a.SymmetricDifference(b); err != nil {
+				t.Fatal(err)
+			}
+			if !a.Equal(orig) {
+				t.Error("Applying SymmetricDifference twice did not restore the filter")
+			}
+		})
+	}
+}
+
+// TestDifferenceOf tests the copying variants leave their inputs unchanged
+func TestDifferenceOf(t *testing.T) {
+	a, b := newDifferenceFilters(false)
+	aBefore, bBefore := a.Clone(), b.Clone()
+
+	diff, err := DifferenceOf(a, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := a.Clone()
+	want.Difference(b)
+	if !diff.Equal(want) {
+		t.Error("DifferenceOf differs from Difference")
+	}
+
+	sym, err := SymmetricDifferenceOf(a, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = a.Clone()
+	want.SymmetricDifference(b)
+	if !sym.Equal(want) {
+		t.Error("SymmetricDifferenceOf differs from SymmetricDifference")
+	}
+
+	if !a.Equal(aBefore) || !b.Equal(bBefore) {
+		t.Error("Copying variants modified their inputs")
+	}
+
+	// A filter minus itself is empty
+	self, _ := DifferenceOf(a, a)
+	if self.PopCount() != 0 {
+		t.Errorf("DifferenceOf(a, a) has %d bits set", self.PopCount())
+	}
+}
+
+// TestDifferenceErrors tests size and seed mismatches and read-only receivers
+func TestDifferenceErrors(t *testing.T) {
+	a := NewCacheOptimizedBloomFilter(1000, 0.01)
+	small := NewCacheOptimizedBloomFilter(10, 0.01)
+	seeded, _ := NewWithOptions(WithExpectedElements(1000), WithFalsePositiveRate(0.01), WithSeed(1))
+
+	if err := a.Difference(small); err == nil {
+		t.Error("Difference accepted a filter of another size")
+	}
+	if err := a.SymmetricDifference(seeded); !errors.Is(err, ErrSeedMismatch) {
+		t.Errorf("SymmetricDifference with another seed: got %v, want ErrSeedMismatch", err)
+	}
+	if _, err := DifferenceOf(a, seeded); !errors.Is(err, ErrSeedMismatch) {
+		t.Errorf("DifferenceOf with another seed: got %v, want ErrSeedMismatch", err)
+	}
+	if _, err := SymmetricDifferenceOf(a, small); err == nil {
+		t.Error("SymmetricDifferenceOf accepted a filter of another size")
+	}
+
+	// Read-only views panic on in-place operations but work with the copying ones
+	data, _ := a.MarshalBinary()
+	view, err := NewFromBuffer(alignedCopy(data, 0))
+	if errors.Is(err, ErrNotViewable) {
+		t.Skip(err)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := DifferenceOf(view, a); err != nil {
+		t.Error(err)
+	}
+	defer func() {
+		if recover() == nil {
+			t.Error("Difference on a read-only view did not panic")
+		}
+	}()
+	view.Difference(a)
+}
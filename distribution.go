@@ -0,0 +1,209 @@
+package bloomfilter
+
+import (
+	"cmp"
+	"math"
+	"slices"
+)
+
+// distributionExtremes is the number of hottest and coldest regions reported
+const distributionExtremes = 8
+
+// DistributionReport is the result of AnalyzeDistribution
+type DistributionReport struct {
+	SkewReport
+	// Histogram is LineHistogram: entry i is the number of cache lines with
+	// exactly i bits set
+	Histogram []uint64
+	// ChiSquared is Pearson's statistic of the histogram against the binomial
+	// distribution expected at the filter's load, after pooling the tails so
+	// every bin expects at least 5 lines. DegreesOfFreedom is the bin count
+	// less 2 (the total and the estimated load), and PValue the probability of
+	// a statistic at least this large under uniform hashing: values below
+	// about 0.001 mean the set bits are not spread the way independent uniform
+	// probes spread them. All three are 0 (PValue 1) when the test cannot be
+	// run: an empty or full filter, or too few lines for three bins.
+	ChiSquared       float64
+	DegreesOfFreedom int
+	PValue           float64
+	// Hottest and Coldest are the fullest and emptiest of up to 256 equal
+	// regions of the bit array, at most 8 of each, fullest (or emptiest) first
+	Hottest []Region
+	Coldest []Region
+}
+
+// Region is a run of cache lines and the fraction of its bits that are set
+type Region struct {
+	FirstLine  uint64
+	Lines      uint64
+	LoadFactor float64
+}
+
+// AnalyzeDistribution measures how uniformly set bits are spread over the bit
+// array: the per-line histogram and skew, a chi-squared test of the histogram
+// against uniform hashing, and the hottest and coldest regions. Keys that
+// interact badly with the hash show up as a tiny PValue or as regions far from
+// the mean load. It reads every bit once.
+func (bf *CacheOptimizedBloomFilter) AnalyzeDistribution() DistributionReport {
+	report := DistributionReport{
+		SkewReport: SkewReport{Lines: bf.cacheLineCount},
+		Histogram:  make([]uint64, BitsPerCacheLine+1),
+		PValue:     1,
+	}
+	if bf.cacheLineCount == 0 {
+		return report
+	}
+
+	regionCount := min(bf.cacheLineCount, debugRegions)
+	regions := make([]Region, regionCount)
+	for r := range regions {
+		first, end := uint64(r)*bf.cacheLineCount/regionCount, uint64(r+1)*bf.cacheLineCount/regionCount
+		var set int
+		for i := first; i < end; i++ {
+			n := bf.lineBits(int(i))
+			report.Histogram[n]++
+			set += n
+		}
+		regions[r] = Region{FirstLine: first, Lines: end - first,
+			LoadFactor: float64(set) / float64((end-first)*BitsPerCacheLine)}
+	}
+	report.SkewReport = skewOf(report.Histogram, bf.cacheLineCount)
+	report.ChiSquared, report.DegreesOfFreedom, report.PValue = uniformityTest(report.Histogram, bf.cacheLineCount)
+
+	slices.SortStableFunc(regions, func(a, b Region) int {
+		return cmp.Compare(b.LoadFactor, a.LoadFactor)
+	})
+	extremes := min(len(regions), distributionExtremes)
+	report.Hottest = slices.Clone(regions[:extremes])
+	report.Coldest = slices.Clone(regions[len(regions)-extremes:])
+	slices.Reverse(report.Coldest)
+	return report
+}
+
+// skewOf computes a SkewReport from a line histogram
+func skewOf(hist []uint64, lineCount uint64) SkewReport {
+	report := SkewReport{Lines: lineCount}
+	var sum, sumSq float64
+	for n, count := range hist {
+		if count > 0 {
+			report.MaxBits = n
+		}
+		sum += float64(n) * float64(count)
+		sumSq += float64(n) * float64(n) * float64(count)
+	}
+
+	lines := float64(lineCount)
+	report.MeanBits = sum / lines
+	p := report.MeanBits / BitsPerCacheLine
+	expected := BitsPerCacheLine * p * (1 - p)
+	if lineCount > 1 && expected > 0 {
+		variance := (sumSq - sum*sum/lines) / (lines - 1)
+		report.Dispersion = variance / expected
+	}
+	return report
+}
+
+// uniformityTest runs Pearson's chi-squared test of a line histogram against
+// Binomial(BitsPerCacheLine, p) at the observed load p. Bins are merged from
+// each tail until they expect at least 5 lines.
+func uniformityTest(hist []uint64, lineCount uint64) (chi2 float64, dof int, pValue float64) {
+	lines := float64(lineCount)
+	var sum float64
+	for n, count := range hist {
+		sum += float64(n) * float64(count)
+	}
+	p := sum / lines / BitsPerCacheLine
+	if p <= 0 || p >= 1 {
+		return 0, 0, 1
+	}
+
+	// Expected lines per bin, from log-space binomial probabilities
+	lgN, _ := math.Lgamma(BitsPerCacheLine + 1)
+	logP, logQ := math.Log(p), math.Log1p(-p)
+	expected := make([]float64, len(hist))
+	for k := range expected {
+		lgK, _ := math.Lgamma(float64(k) + 1)
+		lgNK, _ := math.Lgamma(float64(BitsPerCacheLine-k) + 1)
+		expected[k] = lines * math.Exp(lgN-lgK-lgNK+float64(k)*logP+float64(BitsPerCacheLine-k)*logQ)
+	}
+
+	const minExpected = 5
+	var obs, exp []float64
+	var o, e float64
+	for k := range hist {
+		o += float64(hist[k])
+		e += expected[k]
+		if e >= minExpected {
+			obs, exp = append(obs, o), append(exp, e)
+			o, e = 0, 0
+		}
+	}
+	if len(exp) == 0 {
+		return 0, 0, 1
+	}
+	// Fold the upper tail into the last full bin
+	obs[len(obs)-1] += o
+	exp[len(exp)-1] += e
+	if len(exp) < 3 {
+		return 0, 0, 1
+	}
+
+	for i := range exp {
+		d := obs[i] - exp[i]
+		chi2 += d * d / exp[i]
+	}
+	dof = len(exp) - 2
+	return chi2, dof, chiSquaredSurvival(chi2, dof)
+}
+
+// chiSquaredSurvival returns P(X >= x) for X chi-squared with dof degrees of
+// freedom, the regularized upper incomplete gamma function Q(dof/2, x/2)
+func chiSquaredSurvival(x float64, dof int) float64 {
+	a, x := float64(dof)/2, x/2
+	if x <= 0 {
+		return 1
+	}
+	lgA, _ := math.Lgamma(a)
+	prefix := math.Exp(a*math.Log(x) - x - lgA)
+	const (
+		maxIter = 1000
+		eps     = 1e-14
+		tiny    = 1e-300
+	)
+	if x < a+1 {
+		// Series for the lower function P, Q = 1-P
+		term := 1 / a
+		sum := term
+		for n := 1; n < maxIter; n++ {
+			term *= x / (a + float64(n))
+			sum += term
+			if math.Abs(term) < math.Abs(sum)*eps {
+				break
+			}
+		}
+		return max(0, 1-sum*prefix)
+	}
+	// Continued fraction for Q (modified Lentz)
+	b := x + 1 - a
+	c, d := 1/tiny, 1/b
+	h := d
+	for n := 1; n < maxIter; n++ {
+		an := -float64(n) * (float64(n) - a)
+		b += 2
+		d = an*d + b
+		if math.Abs(d) < tiny {
+			d = tiny
+		}
+		c = b + an/c
+		if math.Abs(c) < tiny {
+			c = tiny
+		}
+		d = 1 / d
+		delta := d * c
+		h *= delta
+		if math.Abs(delta-1) < eps {
+			break
+		}
+	}
+	return h * prefix
+}
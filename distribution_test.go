@@ -0,0 +1,81 @@
+package bloomfilter
+
+import (
+	"math"
+	"testing"
+)
+
+// TestAnalyzeDistribution tests the uniformity test and regions for uniform
+// and crowded keys
+func TestAnalyzeDistribution(t *testing.T) {
+	uniform := NewCacheOptimizedBloomFilter(50000, 0.01)
+	for i := 0; i < 50000; i++ {
+		uniform.AddUint64(uint64(i))
+	}
+	report := uniform.AnalyzeDistribution()
+	if report.SkewReport != uniform.Skew() {
+		t.Errorf("Skew mismatch: %+v vs %+v", report.SkewReport, uniform.Skew())
+	}
+	for i, n := range uniform.LineHistogram() {
+		if report.Histogram[i] != n {
+			t.Fatalf("Histogram[%d] = %d, want %d", i, report.Histogram[i], n)
+		}
+	}
+	if report.DegreesOfFreedom < 3 || report.PValue < 0.001 {
+		t.Errorf("Uniform keys should pass the uniformity test: chi2=%.1f dof=%d p=%.4g",
+			report.ChiSquared, report.DegreesOfFreedom, report.PValue)
+	}
+	if len(report.Hottest) != distributionExtremes || len(report.Coldest) != distributionExtremes {
+		t.Fatalf("Want %d hottest and coldest regions, got %d and %d", distributionExtremes, len(report.Hottest), len(report.Coldest))
+	}
+	for i := 1; i < distributionExtremes; i++ {
+		if report.Hottest[i].LoadFactor > report.Hottest[i-1].LoadFactor || report.Coldest[i].LoadFactor < report.Coldest[i-1].LoadFactor {
+			t.Fatalf("Regions out of order: %+v %+v", report.Hottest, report.Coldest)
+		}
+	}
+	if report.Coldest[0].LoadFactor > report.Hottest[0].LoadFactor || report.Hottest[0].Lines == 0 {
+		t.Errorf("Unexpected regions: %+v %+v", report.Hottest[0], report.Coldest[0])
+	}
+
+	crowded := NewCacheOptimizedBloomFilter(3400, 0.01)
+	for _, k := range crowdedKeys(300, crowded.bitCount, crowded.Seed()) {
+		crowded.Add(k)
+	}
+	report = crowded.AnalyzeDistribution()
+	if report.PValue > 1e-6 {
+		t.Errorf("Crowded keys should fail the uniformity test: chi2=%.1f dof=%d p=%.4g",
+			report.ChiSquared, report.DegreesOfFreedom, report.PValue)
+	}
+	if report.Hottest[0].FirstLine >= 4 || report.Hottest[0].LoadFactor < 2*report.MeanBits/BitsPerCacheLine {
+		t.Errorf("The first region should be hottest: %+v", report.Hottest[0])
+	}
+
+	empty := NewCacheOptimizedBloomFilter(1000, 0.01).AnalyzeDistribution()
+	if empty.ChiSquared != 0 || empty.DegreesOfFreedom != 0 || empty.PValue != 1 || empty.Coldest[0].LoadFactor != 0 {
+		t.Errorf("Unexpected report for an empty filter: %+v", empty)
+	}
+	if zero := (&CacheOptimizedBloomFilter{}).AnalyzeDistribution(); zero.Lines != 0 || zero.PValue != 1 || zero.Hottest != nil {
+		t.Errorf("Unexpected report for the zero filter: %+v", zero)
+	}
+}
+
+// TestChiSquaredSurvival tests the chi-squared tail against known values
+func TestChiSquaredSurvival(t *testing.T) {
+	for _, tc := range []struct {
+		x    float64
+		dof  int
+		want float64
+	}{
+		{0, 4, 1},
+		{2, 2, math.Exp(-1)},
+		{3.841, 1, 0.05},
+		{18.307, 10, 0.05},
+		{23.209, 10, 0.01},
+		{124.342, 100, 0.05},
+		{200, 10, 0},
+	} {
+		if got := chiSquaredSurvival(tc.x, tc.dof); math.Abs(got-tc.want) > 1e-3 {
+			t.Errorf("chiSquaredSurvival(%v, %d) = %.5f, want %.5f", tc.x, tc.dof, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package bloomfilter
+
+import (
+	"fmt"
+	"strings"
+	"sync/atomic"
+)
+
+// Probe describes a single bit probe performed for a key
+type Probe struct {
+	Position  uint64 // Absolute bit position in the filter
+	CacheLine uint64 // Index of the cache line holding the bit
+	Word      uint64 // Word index within the cache line (0-7)
+	Bit       uint64 // Bit offset within the word (0-63)
+	Set       bool   // Whether the bit was set when it was probed
+}
+
+// Explanation is a per-probe trace of a membership check, as returned by Explain
+type Explanation struct {
+	Hash1   uint64
+	Hash2   uint64
+	Probes  []Probe
+	Present bool
+	// FirstMiss is the index in Probes of the first unset bit, which is the probe
+	// that makes Contains return false. It is -1 when the key is present.
+	FirstMiss int
+	// Checked is the number of leading probes Contains checks; it is less than
+	// len(Probes) under adaptive probing, and Present only reflects those
+	Checked int
+}
+
+// Explain checks membership like Contains, but returns the full probe trace: the
+// hashes, every bit position with its cache line/word/bit coordinates, and which
+// probe caused a miss. Unlike Contains it does not stop at the first unset bit.
+//
+// This is a debugging aid for interop mismatches ("why does this filter say yes
+// but another reader says no for this key") and is not meant for hot paths.
+func (bf *CacheOptimizedBloomFilter) Explain(data []byte) Explanation {
+	h1, h2 := bf.hashKey(data)
+
+	positions := make([]uint64, bf.hashCount)
+	bf.hashPositions(h1, h2, positions)
+
+	exp := Explanation{
+		Hash1:     h1,
+		Hash2:     h2,
+		Probes:    make([]Probe, len(positions)),
+		Present:   true,
+		FirstMiss: -1,
+		Checked:   int(bf.containsProbes()),
+	}
+
+	for i, bitPos := range positions {
+		cacheLineIdx := bitPos / BitsPerCacheLine
+		wordIdx := (bitPos % BitsPerCacheLine) / 64
+		bitOffset := bitPos % 64
+
+		word := atomic.LoadUint64(&bf.cacheLines[cacheLineIdx].words[wordIdx])
+		set := word&(1<<bitOffset) != 0
+
+		exp.Probes[i] = Probe{
+			Position:  bitPos,
+			CacheLine: cacheLineIdx,
+			Word:      wordIdx,
+			Bit:       bitOffset,
+			Set:       set,
+		}
+		if !set && exp.Present && i < exp.Checked {
+			exp.Present = false
+			exp.FirstMiss = i
+		}
+	}
+
+	return exp
+}
+
+// String renders the explanation as a human-readable, multi-line trace
+func (e Explanation) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "present=%t h1=%#016x h2=%#016x probes=%d\n", e.Present, e.Hash1, e.Hash2, len(e.Probes))
+	for i, p := range e.Probes {
+		marker := ""
+		if i == e.FirstMiss {
+			marker = " <- first miss"
+		} else if i >= e.Checked {
+			marker = " (not checked: adaptive probing)"
+		}
+		fmt.Fprintf(&b, "  [%2d] pos=%d line=%d word=%d bit=%d set=%t%s\n",
+			i, p.Position, p.CacheLine, p.Word, p.Bit, p.Set, marker)
+	}
+	return b.String()
+}
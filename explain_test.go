@@ -0,0 +1,57 @@
+package bloomfilter
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestExplain tests that the probe trace agrees with Contains
+func TestExplain(t *testing.T) {
+	bf := NewCacheOptimizedBloomFilter(1000, 0.01)
+	bf.AddString("present")
+
+	exp := bf.Explain([]byte("present"))
+	if !exp.Present || exp.FirstMiss != -1 {
+		t.Errorf("Expected added key to be present, got present=%t firstMiss=%d", exp.Present, exp.FirstMiss)
+	}
+	if len(exp.Probes) != int(bf.hashCount) {
+		t.Fatalf("Expected %d probes, got %d", bf.hashCount, len(exp.Probes))
+	}
+
+	for i, p := range exp.Probes {
+		if !p.Set {
+			t.Errorf("Probe %d should be set for an added key", i)
+		}
+		if p.Position != p.CacheLine*BitsPerCacheLine+p.Word*64+p.Bit {
+			t.Errorf("Probe %d coordinates do not match position %d: %+v", i, p.Position, p)
+		}
+		if p.Word >= WordsPerCacheLine || p.Bit >= 64 || p.CacheLine >= bf.cacheLineCount {
+			t.Errorf("Probe %d coordinates out of range: %+v", i, p)
+		}
+	}
+
+	// Find a key that is absent and check the reported miss
+	for i := 0; i < 100; i++ {
+		key := []byte(strings.Repeat("x", i+1))
+		if bf.Contains(key) {
+			continue
+		}
+		exp := bf.Explain(key)
+		if exp.Present {
+			t.Fatalf("Explain reported present for a key Contains rejects")
+		}
+		if exp.FirstMiss < 0 || exp.Probes[exp.FirstMiss].Set {
+			t.Fatalf("FirstMiss %d does not point at an unset bit", exp.FirstMiss)
+		}
+		for j := 0; j < exp.FirstMiss; j++ {
+			if !exp.Probes[j].Set {
+				t.Fatalf("Probe %d is unset but FirstMiss is %d", j, exp.FirstMiss)
+			}
+		}
+		if !strings.Contains(exp.String(), "first miss") {
+			t.Errorf("String() should mark the first miss:\n%s", exp)
+		}
+		return
+	}
+	t.Fatal("Could not find an absent key")
+}
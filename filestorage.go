@@ -0,0 +1,106 @@
+package bloomfilter
+
+import (
+	"encoding/binary"
+	"fmt"
+	"os"
+	"sync"
+)
+
+// FileStorage is a Storage backed by a file of little-endian uint64 words in the
+// layout of Words, read and written in place with positioned IO. Bits are
+// updated with read-modify-write under a mutex, so a FileStorage is safe for
+// concurrent use within one process but must not be shared between processes.
+type FileStorage struct {
+	mu       sync.Mutex
+	file     *os.File
+	bitCount uint64
+}
+
+// OpenFileStorage opens or creates the file at path holding bitCount bits.
+// A new file is zero-filled to size; an existing file must have exactly that size.
+func OpenFileStorage(path string, bitCount uint64) (*FileStorage, error) {
+	if bitCount == 0 || bitCount%BitsPerCacheLine != 0 {
+		return nil, fmt.Errorf("bloomfilter: bitCount must be a positive multiple of %d, got %d", BitsPerCacheLine, bitCount)
+	}
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+
+	size := int64(bitCount / 8)
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	switch info.Size() {
+	case size:
+	case 0:
+		if err := file.Truncate(size); err != nil {
+			file.Close()
+			return nil, err
+		}
+	default:
+		file.Close()
+		return nil, fmt.Errorf("%w: %s has %d bytes, expected %d", ErrSizeMismatch, path, info.Size(), size)
+	}
+
+	return &FileStorage{file: file, bitCount: bitCount}, nil
+}
+
+// BitCount returns the number of bits in the file
+func (s *FileStorage) BitCount() uint64 {
+	return s.bitCount
+}
+
+// SetBits sets every bit in positions
+func (s *FileStorage) SetBits(positions []uint64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var buf [8]byte
+	for _, pos := range positions {
+		offset := int64(pos/64) * 8
+		if _, err := s.file.ReadAt(buf[:], offset); err != nil {
+			return err
+		}
+		word := binary.LittleEndian.Uint64(buf[:])
+		mask := uint64(1) << (pos % 64)
+		if word&mask != 0 {
+			continue
+		}
+		binary.LittleEndian.PutUint64(buf[:], word|mask)
+		if _, err := s.file.WriteAt(buf[:], offset); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// TestBits reports whether every bit in positions is set
+func (s *FileStorage) TestBits(positions []uint64) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var buf [8]byte
+	for _, pos := range positions {
+		if _, err := s.file.ReadAt(buf[:], int64(pos/64)*8); err != nil {
+			return false, err
+		}
+		if binary.LittleEndian.Uint64(buf[:])&(uint64(1)<<(pos%64)) == 0 {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// Sync commits the file to stable storage
+func (s *FileStorage) Sync() error {
+	return s.file.Sync()
+}
+
+// Close closes the file. Later operations return an error.
+func (s *FileStorage) Close() error {
+	return s.file.Close()
+}
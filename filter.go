@@ -0,0 +1,10 @@
+package bloomfilter
+
+// Filter is the minimal membership interface shared by the filter types and
+// wrappers in this package, so that wrappers can be composed with each other.
+type Filter interface {
+	Add(data []byte)
+	Contains(data []byte) bool
+}
+
+var _ Filter = (*CacheOptimizedBloomFilter)(nil)
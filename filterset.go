@@ -0,0 +1,118 @@
+package bloomfilter
+
+import "fmt"
+
+// FilterSet answers membership queries across many compatible filters, such as
+// the levels of an LSM tree or the shards of a partitioned key space. Because
+// every filter has the same bit count, hash count, seed, probe scheme and hash
+// version, a key maps to the same bit positions in all of them, so each query
+// hashes the key and computes its positions once and then only tests bits in
+// each filter.
+//
+// The set holds references: adds to its filters are visible to its queries, and
+// queries are safe to run concurrently with them. Queries through the set are
+// not seen by per-filter features that observe lookups (FPR sampling, recording,
+// adaptive probing).
+type FilterSet struct {
+	filters []*CacheOptimizedBloomFilter
+}
+
+// NewFilterSet creates a set over filters, queried in the order given.
+// Returns an error if filters is empty or any filter is not Compatible with the
+// first.
+func NewFilterSet(filters ...*CacheOptimizedBloomFilter) (*FilterSet, error) {
+	if len(filters) == 0 {
+		return nil, fmt.Errorf("bloomfilter: a filter set needs at least one filter")
+	}
+	for i, bf := range filters[1:] {
+		if err := filters[0].Compatible(bf); err != nil {
+			return nil, fmt.Errorf("bloomfilter: filter %d of the set: %w", i+1, err)
+		}
+	}
+	return &FilterSet{filters: append([]*CacheOptimizedBloomFilter(nil), filters...)}, nil
+}
+
+// Len returns the number of filters in the set
+func (s *FilterSet) Len() int {
+	return len(s.filters)
+}
+
+// Filter returns the i-th filter of the set
+func (s *FilterSet) Filter(i int) *CacheOptimizedBloomFilter {
+	return s.filters[i]
+}
+
+// ContainsAny reports whether any filter contains data, stopping at the first
+// that does
+func (s *FilterSet) ContainsAny(data []byte) bool {
+	found := false
+	s.probe(data, func(_ int, present bool) bool {
+		found = present
+		return !present
+	})
+	return found
+}
+
+// ContainsAll reports whether every filter contains data, stopping at the first
+// that does not
+func (s *FilterSet) ContainsAll(data []byte) bool {
+	all := true
+	s.probe(data, func(_ int, present bool) bool {
+		all = present
+		return present
+	})
+	return all
+}
+
+// WhichContain returns the indexes of the filters that contain data, in order
+func (s *FilterSet) WhichContain(data []byte) []int {
+	return s.AppendWhichContain(nil, data)
+}
+
+// AppendWhichContain appends the indexes of the filters that contain data to
+// dst, so callers can reuse one slice across queries without allocating
+func (s *FilterSet) AppendWhichContain(dst []int, data []byte) []int {
+	s.probe(data, func(i int, present bool) bool {
+		if present {
+			dst = append(dst, i)
+		}
+		return true
+	})
+	return dst
+}
+
+// ContainsAnyString is ContainsAny for a string key
+func (s *FilterSet) ContainsAnyString(key string) bool {
+	return s.ContainsAny(stringBytes(key))
+}
+
+// ContainsAllString is ContainsAll for a string key
+func (s *FilterSet) ContainsAllString(key string) bool {
+	return s.ContainsAll(stringBytes(key))
+}
+
+// WhichContainString is WhichContain for a string key
+func (s *FilterSet) WhichContainString(key string) []int {
+	return s.WhichContain(stringBytes(key))
+}
+
+// probe tests data's positions in each filter in order, calling visit with the
+// result until it returns false
+func (s *FilterSet) probe(data []byte, visit func(i int, present bool) bool) {
+	first := s.filters[0]
+	var stackBuf [16]uint64
+	var positions []uint64
+	if first.hashCount <= 16 {
+		positions = stackBuf[:first.hashCount]
+	} else {
+		positions = make([]uint64, first.hashCount)
+	}
+	h1, h2 := first.hashKey(data)
+	first.hashPositions(h1, h2, positions)
+
+	for i, bf := range s.filters {
+		if !visit(i, bf.checkBitsAtomic(positions)) {
+			return
+		}
+	}
+}
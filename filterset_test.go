@@ -0,0 +1,96 @@
+package bloomfilter
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+)
+
+// newLevels returns n compatible filters, filter i holding keys [100i, 100i+100)
+// plus the shared key "everywhere"
+func newLevels(n int) []*CacheOptimizedBloomFilter {
+	levels := make([]*CacheOptimizedBloomFilter, n)
+	for i := range levels {
+		levels[i], _ = NewWithOptions(WithExpectedElements(1000), WithSeed(9))
+		for j := 0; j < 100; j++ {
+			levels[i].AddString(fmt.Sprintf("key-%d", 100*i+j))
+		}
+		levels[i].AddString("everywhere")
+	}
+	return levels
+}
+
+// TestFilterSet tests the three queries against querying each filter directly
+func TestFilterSet(t *testing.T) {
+	levels := newLevels(8)
+	set, err := NewFilterSet(levels...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if set.Len() != len(levels) || set.Filter(3) != levels[3] {
+		t.Error("Len or Filter does not match the filters given")
+	}
+
+	for i := 0; i < 1200; i++ {
+		key := fmt.Sprintf("key-%d", i)
+		var want []int
+		for j, bf := range levels {
+			if bf.ContainsString(key) {
+				want = append(want, j)
+			}
+		}
+		if got := set.WhichContainString(key); !slices.Equal(got, want) {
+			t.Fatalf("WhichContain(%s) = %v, want %v", key, got, want)
+		}
+		if got := set.ContainsAnyString(key); got != (len(want) > 0) {
+			t.Errorf("ContainsAny(%s) = %t with %v containing it", key, got, want)
+		}
+		if got := set.ContainsAllString(key); got != (len(want) == len(levels)) {
+			t.Errorf("ContainsAll(%s) = %t with %v containing it", key, got, want)
+		}
+	}
+
+	if !set.ContainsAllString("everywhere") {
+		t.Error("ContainsAll missed a key in every filter")
+	}
+	if got := set.WhichContainString("key-250"); !slices.Contains(got, 2) {
+		t.Errorf("WhichContain(key-250) = %v, want it to include 2", got)
+	}
+
+	// Adds after construction are visible
+	levels[5].AddString("late")
+	if !slices.Contains(set.WhichContainString("late"), 5) {
+		t.Error("Query missed a key added after the set was created")
+	}
+}
+
+// TestFilterSetErrors tests that incompatible filters are rejected
+func TestFilterSetErrors(t *testing.T) {
+	if _, err := NewFilterSet(); err == nil {
+		t.Error("Expected an error for an empty set")
+	}
+	levels := newLevels(2)
+	other, _ := NewWithOptions(WithExpectedElements(1000), WithSeed(10))
+	if _, err := NewFilterSet(append(levels, other)...); !errors.Is(err, ErrSeedMismatch) {
+		t.Errorf("Different seed: got %v, want ErrSeedMismatch", err)
+	}
+	small, _ := NewWithOptions(WithExpectedElements(10), WithSeed(9))
+	if _, err := NewFilterSet(levels[0], small); !errors.Is(err, ErrIncompatible) {
+		t.Errorf("Different size: got %v, want ErrIncompatible", err)
+	}
+}
+
+// TestFilterSetAllocations tests that queries other than WhichContain do not allocate
+func TestFilterSetAllocations(t *testing.T) {
+	set, _ := NewFilterSet(newLevels(4)...)
+	key := []byte("key-150")
+	buf := make([]int, 0, 4)
+	if allocs := testing.AllocsPerRun(100, func() {
+		set.ContainsAny(key)
+		set.ContainsAll(key)
+		buf = set.AppendWhichContain(buf[:0], key)
+	}); allocs != 0 {
+		t.Errorf("Queries allocated %.0f times", allocs)
+	}
+}
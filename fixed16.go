@@ -0,0 +1,44 @@
+package bloomfilter
+
+import (
+	"unsafe"
+
+	"github.com/shaia/BloomFilter/internal/hash"
+)
+
+// Add16 adds a 16-byte key such as a UUID, an IPv6 address or a 128-bit hash.
+// The key is hashed as two words by an inlined mixer instead of the generic
+// byte loop. Add16(k) is equivalent to Add(k[:]), so either form may be used to
+// add and query the same key.
+func (bf *CacheOptimizedBloomFilter) Add16(key [16]byte) {
+	if bf.overloaded() {
+		bf.dropped.Add(1)
+		return
+	}
+	h1, h2 := bf.hash16(&key)
+	bf.addHashed(key[:], h1, h2, false)
+}
+
+// Contains16 checks membership of a 16-byte key. Contains16(k) is equivalent to Contains(k[:]).
+func (bf *CacheOptimizedBloomFilter) Contains16(key [16]byte) bool {
+	h1, h2 := bf.hash16(&key)
+	return bf.containsHashed(key[:], h1, h2)
+}
+
+// hash16 returns the base hashes of a 16-byte key, hashKey(key[:])
+func (bf *CacheOptimizedBloomFilter) hash16(key *[16]byte) (uint64, uint64) {
+	if bf.hasher != nil {
+		return customHash(bf.hasher, key[:])
+	}
+	lo, hi := words16(key)
+	if bf.hashVersion == HashV2 {
+		return hash.Seeded128Words(lo, hi, bf.seed)
+	}
+	return hash.Seeded16(lo, hi, bf.seed)
+}
+
+// words16 returns the two 8-byte words of key in memory order, matching the
+// unaligned loads of the generic hash functions
+func words16(key *[16]byte) (uint64, uint64) {
+	return *(*uint64)(unsafe.Pointer(&key[0])), *(*uint64)(unsafe.Pointer(&key[8]))
+}
@@ -0,0 +1,45 @@
+package bloomfilter
+
+import (
+	"crypto/sha256"
+	"testing"
+)
+
+// TestAdd16 tests that 16-byte keys interoperate with the slice API
+func TestAdd16(t *testing.T) {
+	bf := NewCacheOptimizedBloomFilter(10000, 0.01)
+	key := func(i int) (k [16]byte) {
+		sum := sha256.Sum256([]byte{byte(i), byte(i >> 8)})
+		copy(k[:], sum[:])
+		return k
+	}
+
+	for i := 0; i < 1000; i++ {
+		k := key(i)
+		if i%2 == 0 {
+			bf.Add16(k)
+		} else {
+			bf.Add(k[:])
+		}
+	}
+	for i := 0; i < 1000; i++ {
+		k := key(i)
+		if !bf.Contains16(k) || !bf.Contains(k[:]) {
+			t.Fatalf("Key %d missing from one of the APIs", i)
+		}
+	}
+
+	if allocs := testing.AllocsPerRun(100, func() {
+		k := key(5)
+		bf.Add16(k)
+		_ = bf.Contains16(k)
+	}); allocs != 0 {
+		t.Errorf("Add16/Contains16 allocated %.0f times", allocs)
+	}
+
+	bf.SetMaxLoadFactor(0.0001)
+	bf.Add16(key(2000))
+	if bf.Dropped() != 1 {
+		t.Errorf("Add16 must honor load shedding, dropped=%d", bf.Dropped())
+	}
+}
@@ -0,0 +1,446 @@
+package bloomfilter
+
+import (
+	"encoding"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"math"
+	"slices"
+	"sync/atomic"
+)
+
+// Native format, all integers little-endian (see docs/format/NATIVE_FORMAT.md):
+//
+//	magic "BLMF" | version u16 | flags u16 | hashCount u32 | headerCRC u32 |
+//	bitCount u64 | cacheLineCount u64 | seed u64 |
+//	words [cacheLineCount*8]u64 |
+//	chunkCRCs [ceil(cacheLineCount/1024)]u32 | footer u32
+//
+// Every checksum is CRC-32C. headerCRC covers the header with the field itself
+// zero, each chunk CRC covers 1024 cache lines (64 KiB) of words, the last
+// chunk possibly fewer, and the footer covers the chunk CRCs. A damaged region
+// is thus located to the chunk, and the header is verified before the filter
+// is allocated.
+//
+// Version 1 encodings, still read, have a reserved zero instead of headerCRC
+// and a single CRC-32 (IEEE) of all preceding bytes instead of the chunk CRCs
+// and footer.
+//
+// Flag bit 0 marks EnhancedDoubleHashing; without it the filter uses
+// DoubleHashing, as every encoding did before the flag existed. Flag bit 1
+// marks HashV2 in the same way.
+const (
+	formatMagic      = "BLMF"
+	formatHeaderSize = 40
+	formatTrailer    = 4
+
+	flagEnhancedProbing = 1 << 0
+	flagHashV2          = 1 << 1
+)
+
+// FormatVersion is the version of the native format written by AppendBinary
+const FormatVersion = 2
+
+// castagnoli is the CRC-32C table of the version 2 checksums
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+// ErrInvalidEncoding is returned when decoding data that is not a valid
+// native-format filter
+var ErrInvalidEncoding = errors.New("bloomfilter: invalid encoding")
+
+// ErrCorruptData is the ErrInvalidEncoding of data damaged after it was
+// written: a checksum mismatch, truncation, or a length that contradicts the
+// header. Unsupported versions and flags, written by a newer library, are
+// ErrInvalidEncoding only.
+var ErrCorruptData = fmt.Errorf("%w: corrupt data", ErrInvalidEncoding)
+
+// CorruptDataError locates a checksum mismatch in a native-format encoding or
+// a delta. It wraps ErrCorruptData.
+type CorruptDataError struct {
+	// Region whose checksum failed: "header", "words", "chunk table",
+	// "encoding" for a version 1 encoding, whose one checksum covers
+	// everything, or "delta"
+	Region string
+	// Byte range of the region from the start of the encoding
+	Offset, Length int64
+}
+
+func (e *CorruptDataError) Error() string {
+	return fmt.Sprintf("%v: %s checksum mismatch at bytes %d-%d", ErrCorruptData, e.Region, e.Offset, e.Offset+e.Length)
+}
+
+// Unwrap returns ErrCorruptData
+func (e *CorruptDataError) Unwrap() error {
+	return ErrCorruptData
+}
+
+// AppendBinary appends the filter in the native format to b. The encoding holds
+// the bits, geometry, seed, probe scheme and hash version; a custom hasher and
+// optional features (FPR sampling, reservoirs, counters, load shedding) are not
+// part of it. Encoding while other goroutines add elements yields a valid
+// filter holding some subset of those elements.
+func (bf *CacheOptimizedBloomFilter) AppendBinary(b []byte) ([]byte, error) {
+	b = bf.appendHeader(b)
+	b = slices.Grow(b, int(formatSize(bf.cacheLineCount)-formatHeaderSize))
+	sums := make([]uint32, 0, formatChunks(bf.cacheLineCount))
+	for first := 0; first < len(bf.cacheLines); first += formatChunkLines {
+		start := len(b)
+		b = bf.appendWords(b, first)
+		sums = append(sums, crc32.Checksum(b[start:], castagnoli))
+	}
+	return appendChunkTable(b, sums), nil
+}
+
+// appendHeader appends the format header
+func (bf *CacheOptimizedBloomFilter) appendHeader(b []byte) []byte {
+	start := len(b)
+	b = append(b, formatMagic...)
+	b = binary.LittleEndian.AppendUint16(b, FormatVersion)
+	var flags uint16
+	if bf.probe == EnhancedDoubleHashing {
+		flags |= flagEnhancedProbing
+	}
+	if bf.hashVersion == HashV2 {
+		flags |= flagHashV2
+	}
+	b = binary.LittleEndian.AppendUint16(b, flags)
+	b = binary.LittleEndian.AppendUint32(b, bf.hashCount)
+	b = binary.LittleEndian.AppendUint32(b, 0) // header CRC, filled in below
+	b = binary.LittleEndian.AppendUint64(b, bf.bitCount)
+	b = binary.LittleEndian.AppendUint64(b, bf.cacheLineCount)
+	b = binary.LittleEndian.AppendUint64(b, bf.seed)
+	binary.LittleEndian.PutUint32(b[start+12:], crc32.Checksum(b[start:], castagnoli))
+	return b
+}
+
+// appendWords appends the words of the chunk starting at cache line first
+func (bf *CacheOptimizedBloomFilter) appendWords(b []byte, first int) []byte {
+	for i := first; i < min(first+formatChunkLines, len(bf.cacheLines)); i++ {
+		line := &bf.cacheLines[i]
+		for j := range line.words {
+			b = binary.LittleEndian.AppendUint64(b, atomic.LoadUint64(&line.words[j]))
+		}
+	}
+	return b
+}
+
+// appendChunkTable appends the chunk CRCs and the footer that covers them
+func appendChunkTable(b []byte, sums []uint32) []byte {
+	start := len(b)
+	for _, sum := range sums {
+		b = binary.LittleEndian.AppendUint32(b, sum)
+	}
+	return binary.LittleEndian.AppendUint32(b, crc32.Checksum(b[start:], castagnoli))
+}
+
+// formatChunkLines is the number of cache lines covered by one chunk CRC (64
+// KiB), which is also how much WriteTo and ReadFrom buffer at a time
+const formatChunkLines = 1024
+
+// readFromAllocLines is the most cache lines (64 MiB) ReadFrom allocates before
+// reading them
+const readFromAllocLines = 1 << 20
+
+// formatChunks returns the number of chunk CRCs of a filter
+func formatChunks(cacheLineCount uint64) uint64 {
+	return (cacheLineCount + formatChunkLines - 1) / formatChunkLines
+}
+
+// formatSize returns the size of the current version's encoding of a filter
+func formatSize(cacheLineCount uint64) uint64 {
+	return formatHeaderSize + cacheLineCount*CacheLineSize + formatChunks(cacheLineCount)*4 + formatTrailer
+}
+
+// WriteTo streams the filter to w in the native format, producing the same bytes
+// as MarshalBinary while buffering at most 64 KiB, so multi-gigabyte filters can
+// be saved without a second copy in memory (implements io.WriterTo). It returns
+// the number of bytes written.
+func (bf *CacheOptimizedBloomFilter) WriteTo(w io.Writer) (int64, error) {
+	var written int64
+	write := func(p []byte) error {
+		n, err := w.Write(p)
+		written += int64(n)
+		return err
+	}
+
+	if err := write(bf.appendHeader(make([]byte, 0, formatHeaderSize))); err != nil {
+		return written, err
+	}
+	buf := make([]byte, 0, min(bf.cacheLineCount, formatChunkLines)*CacheLineSize)
+	sums := make([]uint32, 0, formatChunks(bf.cacheLineCount))
+	for first := 0; first < len(bf.cacheLines); first += formatChunkLines {
+		buf = bf.appendWords(buf[:0], first)
+		sums = append(sums, crc32.Checksum(buf, castagnoli))
+		if err := write(buf); err != nil {
+			return written, err
+		}
+	}
+
+	err := write(appendChunkTable(nil, sums))
+	return written, err
+}
+
+// ReadFrom replaces the filter with one streamed from r in the native format,
+// reading exactly one encoding and buffering at most 64 KiB besides the filter
+// itself (implements io.ReaderFrom). It has the semantics of UnmarshalBinary:
+// the receiver may be a zero CacheOptimizedBloomFilter and is left unchanged on
+// error. Malformed or truncated input returns an error wrapping
+// ErrInvalidEncoding; a failed checksum is a *CorruptDataError with the offset
+// of the damaged region. Filters larger than 64 MiB are allocated as their data
+// arrives, briefly needing up to 1.5 times their size, so a header declaring a
+// huge filter costs no more memory than the data r actually supplies.
+func (bf *CacheOptimizedBloomFilter) ReadFrom(r io.Reader) (int64, error) {
+	var read int64
+	readFull := func(p []byte) error {
+		n, err := io.ReadFull(r, p)
+		read += int64(n)
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return fmt.Errorf("%w: %w at byte %d", ErrCorruptData, io.ErrUnexpectedEOF, read)
+		}
+		return err
+	}
+
+	header := make([]byte, formatHeaderSize)
+	if err := readFull(header); err != nil {
+		return read, err
+	}
+	h, err := parseHeader(header)
+	if err != nil {
+		return read, err
+	}
+	v1sum := crc32.NewIEEE()
+	v1sum.Write(header)
+
+	cacheLines := allocCacheLines(min(h.cacheLineCount, readFromAllocLines))
+	buf := make([]byte, min(h.cacheLineCount, formatChunkLines)*CacheLineSize)
+	sums := make([]uint32, 0, formatChunks(uint64(len(cacheLines))))
+	for first := uint64(0); first < h.cacheLineCount; first += formatChunkLines {
+		end := min(first+formatChunkLines, h.cacheLineCount)
+		if end > uint64(len(cacheLines)) {
+			grown := allocCacheLines(min(2*uint64(len(cacheLines)), h.cacheLineCount))
+			copy(grown, cacheLines)
+			cacheLines = grown
+		}
+		lines := cacheLines[first:end]
+		chunk := buf[:len(lines)*CacheLineSize]
+		if err := readFull(chunk); err != nil {
+			return read, err
+		}
+		if h.version == 1 {
+			v1sum.Write(chunk)
+		} else {
+			sums = append(sums, crc32.Checksum(chunk, castagnoli))
+		}
+		for i := range lines {
+			for j := range lines[i].words {
+				lines[i].words[j] = binary.LittleEndian.Uint64(chunk[(i*WordsPerCacheLine+j)*8:])
+			}
+		}
+	}
+
+	trailer := make([]byte, h.size()-uint64(read))
+	if err := readFull(trailer); err != nil {
+		return read, err
+	}
+	if h.version == 1 {
+		if binary.LittleEndian.Uint32(trailer) != v1sum.Sum32() {
+			return read, &CorruptDataError{Region: "encoding", Offset: 0, Length: read}
+		}
+	} else if err := verifyChunks(trailer, sums, h.cacheLineCount); err != nil {
+		return read, err
+	}
+	decoded := newFilterOver(cacheLines, h.hashCount)
+	decoded.seed = h.seed
+	decoded.probe = h.probe
+	decoded.hashVersion = h.hashVersion
+	bf.replaceWith(decoded)
+	return read, nil
+}
+
+// DecodeBinary creates a filter from data written by AppendBinary, in the
+// current or the previous format version. Errors for malformed data wrap
+// ErrInvalidEncoding; a failed checksum is a *CorruptDataError locating the
+// damage.
+func DecodeBinary(data []byte) (*CacheOptimizedBloomFilter, error) {
+	h, err := parseFormatHeader(data)
+	if err != nil {
+		return nil, err
+	}
+
+	bf := newFilter(h.cacheLineCount, h.hashCount)
+	bf.seed = h.seed
+	bf.probe = h.probe
+	bf.hashVersion = h.hashVersion
+	words := data[formatHeaderSize:]
+	for i := range bf.cacheLines {
+		for j := range bf.cacheLines[i].words {
+			bf.cacheLines[i].words[j] = binary.LittleEndian.Uint64(words[(i*WordsPerCacheLine+j)*8:])
+		}
+	}
+	return bf, nil
+}
+
+// MarshalBinary encodes the filter in the native format (implements
+// encoding.BinaryMarshaler)
+func (bf *CacheOptimizedBloomFilter) MarshalBinary() ([]byte, error) {
+	return bf.AppendBinary(nil)
+}
+
+// UnmarshalBinary replaces the filter with one encoded by MarshalBinary or
+// AppendBinary, restoring bits, geometry, seed, probe scheme and hash version exactly
+// (implements encoding.BinaryUnmarshaler). The receiver may be a zero
+// CacheOptimizedBloomFilter.
+// Optional features are disabled, as in a new filter, because their state
+// described the previous contents. It must not be called concurrently with
+// other methods. On error the filter is left unchanged.
+func (bf *CacheOptimizedBloomFilter) UnmarshalBinary(data []byte) error {
+	decoded, err := DecodeBinary(data)
+	if err != nil {
+		return err
+	}
+	bf.replaceWith(decoded)
+	return nil
+}
+
+// replaceWith makes bf a new filter with the contents and geometry of decoded
+func (bf *CacheOptimizedBloomFilter) replaceWith(decoded *CacheOptimizedBloomFilter) {
+	if bf.readOnly {
+		// Drop the view before resetToNew clears the bits, which belong to the caller
+		bf.cacheLines, bf.cacheLineCount, bf.readOnly = nil, 0, false
+	}
+	bf.resetToNew()
+	bf.cacheLines = decoded.cacheLines
+	bf.bitCount = decoded.bitCount
+	bf.hashCount = decoded.hashCount
+	bf.cacheLineCount = decoded.cacheLineCount
+	bf.seed = decoded.seed
+	bf.probe = decoded.probe
+	bf.hashVersion = decoded.hashVersion
+	if bf.simdOps == nil {
+		// Zero value; a filter created WithSIMDDisabled keeps its choice
+		bf.simdOps = decoded.simdOps
+	}
+	if p := bf.pool; p != nil && (p.cacheLineCount != bf.cacheLineCount || p.hashCount != bf.hashCount) {
+		// The pool only holds filters of its own geometry
+		bf.pool = nil
+	}
+}
+
+// formatHeader is the validated header of a native-format encoding
+type formatHeader struct {
+	version        uint16
+	hashCount      uint32
+	cacheLineCount uint64
+	seed           uint64
+	probe          ProbeScheme
+	hashVersion    HashVersion
+}
+
+// size returns the size of the whole encoding
+func (h formatHeader) size() uint64 {
+	if h.version == 1 {
+		return formatHeaderSize + h.cacheLineCount*CacheLineSize + formatTrailer
+	}
+	return formatSize(h.cacheLineCount)
+}
+
+// parseFormatHeader validates everything but the words themselves, whose
+// checksums it verifies
+func parseFormatHeader(data []byte) (formatHeader, error) {
+	if len(data) < formatHeaderSize+formatTrailer {
+		return formatHeader{}, fmt.Errorf("%w: missing header", ErrCorruptData)
+	}
+	h, err := parseHeader(data[:formatHeaderSize])
+	if err != nil {
+		return h, err
+	}
+	if size := h.size(); uint64(len(data)) != size {
+		return h, fmt.Errorf("%w: %d bytes for %d cache lines, want %d", ErrCorruptData, len(data), h.cacheLineCount, size)
+	}
+
+	wordsEnd := formatHeaderSize + int(h.cacheLineCount)*CacheLineSize
+	if h.version == 1 {
+		if crc32.ChecksumIEEE(data[:wordsEnd]) != binary.LittleEndian.Uint32(data[wordsEnd:]) {
+			return h, &CorruptDataError{Region: "encoding", Offset: 0, Length: int64(wordsEnd)}
+		}
+		return h, nil
+	}
+	sums := make([]uint32, 0, formatChunks(h.cacheLineCount))
+	for start := formatHeaderSize; start < wordsEnd; start += formatChunkLines * CacheLineSize {
+		sums = append(sums, crc32.Checksum(data[start:min(start+formatChunkLines*CacheLineSize, wordsEnd)], castagnoli))
+	}
+	return h, verifyChunks(data[wordsEnd:], sums, h.cacheLineCount)
+}
+
+// verifyChunks checks the chunk table and footer of a version 2 encoding, then
+// compares the chunk CRCs with sums, those of the words read
+func verifyChunks(table []byte, sums []uint32, cacheLineCount uint64) error {
+	wordsEnd := int64(formatHeaderSize + cacheLineCount*CacheLineSize)
+	n := len(table) - formatTrailer
+	if crc32.Checksum(table[:n], castagnoli) != binary.LittleEndian.Uint32(table[n:]) {
+		return &CorruptDataError{Region: "chunk table", Offset: wordsEnd, Length: int64(len(table))}
+	}
+	for i, sum := range sums {
+		if binary.LittleEndian.Uint32(table[4*i:]) != sum {
+			first := int64(i) * formatChunkLines * CacheLineSize
+			return &CorruptDataError{Region: "words", Offset: formatHeaderSize + first,
+				Length: min(formatChunkLines*CacheLineSize, wordsEnd-formatHeaderSize-first)}
+		}
+	}
+	return nil
+}
+
+// parseHeader validates the fixed-size header on its own, as streaming readers
+// must before they know the length of the data
+func parseHeader(header []byte) (formatHeader, error) {
+	var h formatHeader
+	if string(header[:4]) != formatMagic {
+		return h, fmt.Errorf("%w: missing header", ErrInvalidEncoding)
+	}
+	switch h.version = binary.LittleEndian.Uint16(header[4:]); h.version {
+	case 1:
+	case 2:
+		// The header CRC is computed with its own field zero
+		sum := crc32.Update(0, castagnoli, header[:12])
+		sum = crc32.Update(sum, castagnoli, make([]byte, 4))
+		sum = crc32.Update(sum, castagnoli, header[16:formatHeaderSize])
+		if sum != binary.LittleEndian.Uint32(header[12:]) {
+			return h, &CorruptDataError{Region: "header", Offset: 0, Length: formatHeaderSize}
+		}
+	default:
+		return h, fmt.Errorf("%w: unsupported version %d", ErrInvalidEncoding, h.version)
+	}
+	flags := binary.LittleEndian.Uint16(header[6:])
+	if unknown := flags &^ (flagEnhancedProbing | flagHashV2); unknown != 0 {
+		return h, fmt.Errorf("%w: unknown flags %#x", ErrInvalidEncoding, unknown)
+	}
+	if flags&flagEnhancedProbing != 0 {
+		h.probe = EnhancedDoubleHashing
+	}
+	if flags&flagHashV2 != 0 {
+		h.hashVersion = HashV2
+	}
+
+	h.hashCount = binary.LittleEndian.Uint32(header[8:])
+	bitCount := binary.LittleEndian.Uint64(header[16:])
+	h.cacheLineCount = binary.LittleEndian.Uint64(header[24:])
+	h.seed = binary.LittleEndian.Uint64(header[32:])
+	if h.hashCount == 0 {
+		return h, fmt.Errorf("%w: hashCount is 0", ErrInvalidEncoding)
+	}
+	// The upper bound keeps byte and bit counts from overflowing
+	if h.cacheLineCount == 0 || h.cacheLineCount > math.MaxInt/CacheLineSize || bitCount != h.cacheLineCount*BitsPerCacheLine {
+		return h, fmt.Errorf("%w: bitCount %d does not match %d cache lines", ErrInvalidEncoding, bitCount, h.cacheLineCount)
+	}
+	return h, nil
+}
+
+var (
+	_ encoding.BinaryMarshaler   = (*CacheOptimizedBloomFilter)(nil)
+	_ encoding.BinaryUnmarshaler = (*CacheOptimizedBloomFilter)(nil)
+	_ io.WriterTo                = (*CacheOptimizedBloomFilter)(nil)
+	_ io.ReaderFrom              = (*CacheOptimizedBloomFilter)(nil)
+)
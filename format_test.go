@@ -0,0 +1,321 @@
+package bloomfilter
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"slices"
+	"testing"
+)
+
+// TestFormatRoundTrip tests that decoding restores bits, geometry and seed exactly
+func TestFormatRoundTrip(t *testing.T) {
+	keys := make([][]byte, 1000)
+	for i := range keys {
+		keys[i] = []byte(fmt.Sprintf("key_%d", i))
+	}
+	for _, seed := range []uint64{0, 0xdeadbeef} {
+		bf := NewCacheOptimizedBloomFilter(1000, 0.01).Reseeded(seed, sliceKeySource(keys))
+
+		data, err := bf.AppendBinary([]byte("prefix"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.HasPrefix(data, []byte("prefix")) {
+			t.Fatal("AppendBinary did not append")
+		}
+		data = data[len("prefix"):]
+
+		got, err := DecodeBinary(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Seed() != seed || got.HashCount() != bf.HashCount() || got.BitCount() != bf.BitCount() {
+			t.Fatalf("Geometry changed: seed %#x, k=%d, m=%d", got.Seed(), got.HashCount(), got.BitCount())
+		}
+		if !slices.Equal(got.Words(), bf.Words()) {
+			t.Fatal("Bits changed")
+		}
+		for _, k := range keys {
+			if !got.Contains(k) {
+				t.Fatalf("%s missing after decode", k)
+			}
+		}
+
+		again, _ := got.AppendBinary(nil)
+		if !bytes.Equal(again, data) {
+			t.Error("Re-encoding is not byte-identical")
+		}
+	}
+}
+
+// TestFormatRejectsCorruption tests that malformed encodings return ErrInvalidEncoding
+func TestFormatRejectsCorruption(t *testing.T) {
+	bf := NewCacheOptimizedBloomFilter(100, 0.01)
+	bf.AddString("x")
+	valid, _ := bf.AppendBinary(nil)
+
+	// mutate applies a change and, unless raw, fixes up the header checksum so
+	// that the structural checks are what reject it
+	mutate := func(raw bool, fn func([]byte) []byte) []byte {
+		data := fn(bytes.Clone(valid))
+		if !raw {
+			resealHeader(data)
+		}
+		return data
+	}
+
+	cases := map[string][]byte{
+		"empty":     nil,
+		"magic":     mutate(false, func(d []byte) []byte { d[0] = 'X'; return d }),
+		"version":   mutate(false, func(d []byte) []byte { d[4] = 3; return d }),
+		"flags":     mutate(false, func(d []byte) []byte { d[6] |= 4; return d }),
+		"hashCount": mutate(false, func(d []byte) []byte { binary.LittleEndian.PutUint32(d[8:], 0); return d }),
+		"bitCount":  mutate(false, func(d []byte) []byte { d[16]++; return d }),
+		"lines":     mutate(false, func(d []byte) []byte { binary.LittleEndian.PutUint64(d[24:], 1<<60); return d }),
+		"truncated": mutate(false, func(d []byte) []byte { return append(d[:len(d)-68], 0, 0, 0, 0) }),
+		"checksum":  mutate(true, func(d []byte) []byte { d[formatHeaderSize] ^= 1; return d }),
+	}
+	for name, data := range cases {
+		if _, err := DecodeBinary(data); !errors.Is(err, ErrInvalidEncoding) {
+			t.Errorf("%s: expected ErrInvalidEncoding, got %v", name, err)
+		}
+	}
+
+	// Damage after writing is told apart from data of a newer format
+	for name, corrupt := range map[string]bool{"empty": true, "truncated": true, "checksum": true, "version": false, "flags": false} {
+		if _, err := DecodeBinary(cases[name]); errors.Is(err, ErrCorruptData) != corrupt {
+			t.Errorf("%s: got %v, want ErrCorruptData %t", name, err, corrupt)
+		}
+	}
+}
+
+// resealHeader recomputes the header CRC of a version 2 encoding
+func resealHeader(data []byte) {
+	binary.LittleEndian.PutUint32(data[12:], 0)
+	binary.LittleEndian.PutUint32(data[12:], crc32.Checksum(data[:formatHeaderSize], castagnoli))
+}
+
+// TestFormatLocatesCorruption tests that each checksum names its region, for
+// DecodeBinary and ReadFrom alike
+func TestFormatLocatesCorruption(t *testing.T) {
+	// Three chunks, the last one partial
+	bf, _ := NewWithOptions(WithBitCount(2500*BitsPerCacheLine), WithHashCount(7))
+	for i := uint64(0); i < 100000; i++ {
+		bf.AddUint64(i)
+	}
+	valid, _ := bf.MarshalBinary()
+	if got := uint64(len(valid)); got != formatSize(2500) {
+		t.Fatalf("Encoding is %d bytes, want %d", got, formatSize(2500))
+	}
+
+	const chunkBytes = formatChunkLines * CacheLineSize
+	wordsEnd := int64(formatHeaderSize + 2500*CacheLineSize)
+	cases := []struct {
+		name   string
+		flip   int
+		region string
+		offset int64
+		length int64
+	}{
+		{"header", 32, "header", 0, formatHeaderSize},
+		{"first chunk", formatHeaderSize + 100, "words", formatHeaderSize, chunkBytes},
+		{"last chunk", int(wordsEnd) - 1, "words", formatHeaderSize + 2*chunkBytes, 452 * CacheLineSize},
+		{"chunk table", int(wordsEnd) + 5, "chunk table", wordsEnd, 16},
+		{"footer", len(valid) - 1, "chunk table", wordsEnd, 16},
+	}
+	for _, tc := range cases {
+		data := bytes.Clone(valid)
+		data[tc.flip] ^= 0x10
+		_, decodeErr := DecodeBinary(data)
+		_, readErr := new(CacheOptimizedBloomFilter).ReadFrom(bytes.NewReader(data))
+		for name, err := range map[string]error{"DecodeBinary": decodeErr, "ReadFrom": readErr} {
+			var ce *CorruptDataError
+			if !errors.As(err, &ce) || !errors.Is(err, ErrCorruptData) {
+				t.Errorf("%s, %s: got %v", tc.name, name, err)
+				continue
+			}
+			if ce.Region != tc.region || ce.Offset != tc.offset || ce.Length != tc.length {
+				t.Errorf("%s, %s: got %s at %d+%d, want %s at %d+%d",
+					tc.name, name, ce.Region, ce.Offset, ce.Length, tc.region, tc.offset, tc.length)
+			}
+		}
+	}
+}
+
+// TestFormatVersion1 tests that encodings of the previous version, with one
+// CRC-32 (IEEE) over everything, are still read
+func TestFormatVersion1(t *testing.T) {
+	bf := NewCacheOptimizedBloomFilter(1000, 0.01)
+	bf.AddString("kept")
+	v2, _ := bf.MarshalBinary()
+	wordsEnd := formatHeaderSize + len(bf.cacheLines)*CacheLineSize
+	v1 := bytes.Clone(v2[:wordsEnd])
+	binary.LittleEndian.PutUint16(v1[4:], 1)
+	binary.LittleEndian.PutUint32(v1[12:], 0)
+	v1 = binary.LittleEndian.AppendUint32(v1, crc32.ChecksumIEEE(v1))
+
+	decoded, err := DecodeBinary(v1)
+	if err != nil || !decoded.Equal(bf) {
+		t.Fatalf("DecodeBinary of version 1: %v", err)
+	}
+	var streamed CacheOptimizedBloomFilter
+	if n, err := streamed.ReadFrom(bytes.NewReader(v1)); err != nil || n != int64(len(v1)) || !streamed.Equal(bf) {
+		t.Fatalf("ReadFrom of version 1: %d, %v", n, err)
+	}
+	if re, _ := decoded.MarshalBinary(); !bytes.Equal(re, v2) {
+		t.Error("Re-encoding a version 1 filter does not write the current version")
+	}
+
+	v1[formatHeaderSize] ^= 1
+	var ce *CorruptDataError
+	if _, err := DecodeBinary(v1); !errors.As(err, &ce) || ce.Region != "encoding" {
+		t.Errorf("Corrupt version 1: got %v", err)
+	}
+}
+
+// TestMarshalBinary tests the encoding interfaces, including unmarshaling into a
+// zero value and into a filter of a different geometry
+func TestMarshalBinary(t *testing.T) {
+	bf := NewCacheOptimizedBloomFilter(10000, 0.001)
+	for i := 0; i < 5000; i++ {
+		bf.AddUint64(uint64(i))
+	}
+	data, err := bf.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var zero CacheOptimizedBloomFilter
+	other := NewCacheOptimizedBloomFilter(10, 0.1)
+	other.AddString("stale")
+	other.EnableAddCounting()
+	other.SetMaxLoadFactor(0.5)
+	for name, target := range map[string]*CacheOptimizedBloomFilter{"zero": &zero, "other": other} {
+		if err := target.UnmarshalBinary(data); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if target.BitCount() != bf.BitCount() || target.HashCount() != bf.HashCount() {
+			t.Fatalf("%s: geometry not restored", name)
+		}
+		if !slices.Equal(target.Words(), bf.Words()) {
+			t.Fatalf("%s: bits not restored", name)
+		}
+		for i := 0; i < 5000; i++ {
+			if !target.ContainsUint64(uint64(i)) {
+				t.Fatalf("%s: %d missing", name, i)
+			}
+		}
+
+		// Features of the previous contents are disabled; the filter stays usable
+		target.AddString("new")
+		if !target.ContainsString("new") || target.NewItems() != 0 || target.Dropped() != 0 {
+			t.Errorf("%s: previous features still active", name)
+		}
+	}
+
+	// A failed unmarshal leaves the filter unchanged
+	before := other.Words()
+	if err := other.UnmarshalBinary(data[:len(data)-1]); !errors.Is(err, ErrInvalidEncoding) {
+		t.Fatalf("Expected ErrInvalidEncoding, got %v", err)
+	}
+	if !slices.Equal(other.Words(), before) {
+		t.Error("Failed unmarshal modified the filter")
+	}
+}
+
+// failingWriter fails after accepting limit bytes
+type failingWriter struct{ limit int }
+
+func (w *failingWriter) Write(p []byte) (int, error) {
+	if len(p) > w.limit {
+		n := w.limit
+		w.limit = 0
+		return n, errors.New("disk full")
+	}
+	w.limit -= len(p)
+	return len(p), nil
+}
+
+// TestReadFromGrows tests ReadFrom of a filter larger than it allocates up front
+func TestReadFromGrows(t *testing.T) {
+	bf := newFilter(readFromAllocLines+formatChunkLines+1, 3)
+	for i := range bf.cacheLines {
+		bf.cacheLines[i].words[i%WordsPerCacheLine] = uint64(i)
+	}
+	var buf bytes.Buffer
+	bf.WriteTo(&buf)
+	var got CacheOptimizedBloomFilter
+	if _, err := got.ReadFrom(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if !got.Equal(bf) {
+		t.Error("ReadFrom did not restore the filter")
+	}
+}
+
+// TestStreamingFormat tests that WriteTo and ReadFrom stream the MarshalBinary
+// bytes across several chunks
+func TestStreamingFormat(t *testing.T) {
+	// 200k elements at 1% is several 64 KiB chunks, the last one partial
+	bf := NewCacheOptimizedBloomFilter(200000, 0.01)
+	for i := 0; i < 200000; i += 3 {
+		bf.AddUint64(uint64(i))
+	}
+	if bf.BitCount()/BitsPerCacheLine <= 2*formatChunkLines {
+		t.Fatal("Test filter does not span several chunks")
+	}
+	want, _ := bf.MarshalBinary()
+
+	var buf bytes.Buffer
+	n, err := bf.WriteTo(&buf)
+	if err != nil || n != int64(len(want)) || !bytes.Equal(buf.Bytes(), want) {
+		t.Fatalf("WriteTo wrote %d bytes (err %v), differing from MarshalBinary", n, err)
+	}
+
+	// ReadFrom consumes exactly one encoding
+	buf.WriteString("next")
+	var got CacheOptimizedBloomFilter
+	n, err = got.ReadFrom(&buf)
+	if err != nil || n != int64(len(want)) {
+		t.Fatalf("ReadFrom read %d bytes: %v", n, err)
+	}
+	if buf.String() != "next" {
+		t.Errorf("ReadFrom consumed past the encoding, %q left", buf.String())
+	}
+	if !slices.Equal(got.Words(), bf.Words()) || got.HashCount() != bf.HashCount() {
+		t.Fatal("ReadFrom did not restore the filter")
+	}
+
+	// Truncation anywhere, or corruption, is an encoding error and leaves the filter unchanged
+	before := got.Words()
+	for _, size := range []int{0, 10, formatHeaderSize, formatHeaderSize + 70000, len(want) - 1} {
+		if _, err := got.ReadFrom(bytes.NewReader(want[:size])); !errors.Is(err, ErrInvalidEncoding) {
+			t.Errorf("Truncated at %d: expected ErrInvalidEncoding, got %v", size, err)
+		}
+	}
+	corrupt := bytes.Clone(want)
+	corrupt[len(corrupt)/2] ^= 0x80
+	if _, err := got.ReadFrom(bytes.NewReader(corrupt)); !errors.Is(err, ErrInvalidEncoding) {
+		t.Errorf("Corrupted: expected ErrInvalidEncoding, got %v", err)
+	}
+	if !slices.Equal(got.Words(), before) {
+		t.Error("Failed ReadFrom modified the filter")
+	}
+
+	// A header declaring a filter larger than memory fails on the missing data
+	huge := newFilter(1, 3)
+	huge.cacheLineCount, huge.bitCount = 1<<50, 1<<50*BitsPerCacheLine
+	header := huge.appendHeader(nil)
+	if _, err := got.ReadFrom(bytes.NewReader(append(header, make([]byte, 1<<20)...))); !errors.Is(err, ErrInvalidEncoding) {
+		t.Errorf("Header declaring 1<<50 cache lines: expected ErrInvalidEncoding, got %v", err)
+	}
+
+	// Write errors are returned with the byte count so far
+	n, err = bf.WriteTo(&failingWriter{limit: 100000})
+	if err == nil || n != 100000 {
+		t.Errorf("Expected the write error after 100000 bytes, got %d, %v", n, err)
+	}
+}
@@ -0,0 +1,191 @@
+package bloomfilter
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+)
+
+// FPRMeasurement is the online false positive rate measured by the sampling ghost
+// set (see EnableFPRSampling)
+type FPRMeasurement struct {
+	Enabled bool
+	// Valid is false after Union or Intersection, which add or remove members the
+	// ghost set never saw; Clear or ResetFPRMeasurement make it valid again
+	Valid bool
+	// SampleRate is the current sampling divisor: 1 in SampleRate keys is tracked
+	SampleRate uint64
+	// SampledKeys is the number of added keys held in the ghost set
+	SampledKeys int
+	// Negatives is the number of sampled queries for keys that were never added
+	Negatives uint64
+	// FalsePositives is how many of those the filter reported as present
+	FalsePositives uint64
+	// MeasuredFPP is FalsePositives / Negatives, or 0 before the first negative
+	MeasuredFPP float64
+}
+
+// ghostSampler keeps an exact set of the added keys that fall into a hash-defined
+// sampling subspace. Because every added key in the subspace is recorded, any
+// query in the same subspace has exact ground truth, so comparing the filter's
+// answer against the set measures the real false positive rate.
+type ghostSampler struct {
+	maxKeys int
+
+	// rate and valid are read without the lock so that unsampled operations never
+	// take it; they are only written while mu is held
+	rate  atomic.Uint64
+	valid atomic.Bool
+
+	mu   sync.RWMutex
+	keys map[string]uint64 // key -> sample hash
+
+	negatives      atomic.Uint64
+	falsePositives atomic.Uint64
+}
+
+// EnableFPRSampling maintains a small exact "ghost" set of 1 in sampleRate added
+// keys, chosen by hash, and uses it to measure the real false positive rate of
+// Contains online. The measurement is reported by FPRMeasurement and in
+// CacheStats.MeasuredFPR, so monitoring does not have to rely on the theoretical
+// formula alone.
+//
+// Memory is capped at maxKeys sampled keys: when the cap is reached the sample
+// rate doubles and keys outside the smaller subspace are evicted, which keeps the
+// ground truth exact. Only sampled operations pay for the set (a map access under
+// a lock); the others pay one atomic load. Enabling resets any previous sample
+// and only keys added afterwards are tracked, so enable it on an empty filter.
+//
+// Panics if sampleRate or maxKeys is 0.
+func (bf *CacheOptimizedBloomFilter) EnableFPRSampling(sampleRate uint64, maxKeys int) {
+	if sampleRate == 0 {
+		panic("bloomfilter: sampleRate must be greater than 0")
+	}
+	if maxKeys <= 0 {
+		panic(fmt.Sprintf("bloomfilter: maxKeys must be greater than 0, got %d", maxKeys))
+	}
+	g := &ghostSampler{
+		maxKeys: maxKeys,
+		keys:    make(map[string]uint64),
+	}
+	g.rate.Store(sampleRate)
+	g.valid.Store(true)
+	bf.ghost.Store(g)
+}
+
+// DisableFPRSampling stops sampling and releases the ghost set
+func (bf *CacheOptimizedBloomFilter) DisableFPRSampling() {
+	bf.ghost.Store(nil)
+}
+
+// FPRMeasurement returns the current online false positive rate measurement
+func (bf *CacheOptimizedBloomFilter) FPRMeasurement() FPRMeasurement {
+	g := bf.ghost.Load()
+	if g == nil {
+		return FPRMeasurement{}
+	}
+
+	g.mu.RLock()
+	m := FPRMeasurement{
+		Enabled:     true,
+		Valid:       g.valid.Load(),
+		SampleRate:  g.rate.Load(),
+		SampledKeys: len(g.keys),
+	}
+	g.mu.RUnlock()
+
+	m.Negatives = g.negatives.Load()
+	m.FalsePositives = g.falsePositives.Load()
+	if m.Negatives > 0 {
+		m.MeasuredFPP = float64(m.FalsePositives) / float64(m.Negatives)
+	}
+	return m
+}
+
+// ResetFPRMeasurement zeroes the query counters, starting a new measurement
+// window while keeping the sampled keys
+func (bf *CacheOptimizedBloomFilter) ResetFPRMeasurement() {
+	if g := bf.ghost.Load(); g != nil {
+		g.negatives.Store(0)
+		g.falsePositives.Store(0)
+	}
+}
+
+// sampleHash derives the sampling hash from the key hashes. It is mixed so that
+// sampling is independent of the bit positions derived from h1 and h2.
+func sampleHash(h1, h2 uint64) uint64 {
+	x := h1*0x9e3779b97f4a7c15 ^ h2
+	x ^= x >> 31
+	x *= 0xbf58476d1ce4e5b9
+	return x ^ x>>29
+}
+
+// observeAdd records an added key if it falls into the sampling subspace
+func (g *ghostSampler) observeAdd(data []byte, h1, h2 uint64) {
+	sh := sampleHash(h1, h2)
+	if sh%g.rate.Load() != 0 {
+		return
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	// Re-check under the lock: the rate may have grown meanwhile
+	rate := g.rate.Load()
+	if sh%rate != 0 {
+		return
+	}
+	if _, ok := g.keys[string(data)]; ok {
+		return
+	}
+	g.keys[string(data)] = sh
+
+	// Over the cap: halve the subspace until the set fits again
+	for len(g.keys) > g.maxKeys {
+		rate *= 2
+		for k, h := range g.keys {
+			if h%rate != 0 {
+				delete(g.keys, k)
+			}
+		}
+	}
+	g.rate.Store(rate)
+}
+
+// observeQuery scores a Contains answer if the key falls into the sampling subspace
+func (g *ghostSampler) observeQuery(data []byte, h1, h2 uint64, present bool) {
+	sh := sampleHash(h1, h2)
+	if !g.valid.Load() || sh%g.rate.Load() != 0 {
+		return
+	}
+
+	g.mu.RLock()
+	_, added := g.keys[string(data)]
+	sampled := sh%g.rate.Load() == 0
+	g.mu.RUnlock()
+
+	if added || !sampled {
+		return
+	}
+	g.negatives.Add(1)
+	if present {
+		g.falsePositives.Add(1)
+	}
+}
+
+// reset empties the sample after the filter was cleared
+func (g *ghostSampler) reset() {
+	g.mu.Lock()
+	clear(g.keys)
+	g.valid.Store(true)
+	g.mu.Unlock()
+	g.negatives.Store(0)
+	g.falsePositives.Store(0)
+}
+
+// invalidate suspends measurement after a bulk operation changed membership
+func (g *ghostSampler) invalidate() {
+	g.mu.Lock()
+	g.valid.Store(false)
+	g.mu.Unlock()
+}
@@ -0,0 +1,101 @@
+package bloomfilter
+
+import (
+	"fmt"
+	"testing"
+)
+
+// TestFPRSampling tests that the ghost set measures a false positive rate close to the real one
+func TestFPRSampling(t *testing.T) {
+	bf := NewCacheOptimizedBloomFilter(10_000, 0.05)
+	bf.EnableFPRSampling(16, 1_000_000)
+
+	for i := 0; i < 10_000; i++ {
+		bf.AddString(fmt.Sprintf("member_%d", i))
+	}
+
+	// Query members (never counted) and non-members (counted when sampled)
+	realFP := 0
+	for i := 0; i < 10_000; i++ {
+		bf.ContainsString(fmt.Sprintf("member_%d", i))
+	}
+	for i := 0; i < 100_000; i++ {
+		if bf.ContainsString(fmt.Sprintf("absent_%d", i)) {
+			realFP++
+		}
+	}
+	realFPR := float64(realFP) / 100_000
+
+	m := bf.FPRMeasurement()
+	if !m.Enabled || !m.Valid {
+		t.Fatalf("Expected enabled and valid measurement, got %+v", m)
+	}
+	if m.Negatives < 100_000/32 || m.Negatives > 100_000/8 {
+		t.Errorf("Expected ~1/16 of absent queries sampled, got %d", m.Negatives)
+	}
+	if m.MeasuredFPP < realFPR/2 || m.MeasuredFPP > realFPR*2 {
+		t.Errorf("Measured FPR %.4f too far from real FPR %.4f", m.MeasuredFPP, realFPR)
+	}
+	if stats := bf.GetCacheStats(); stats.MeasuredFPR != m {
+		t.Errorf("CacheStats.MeasuredFPR %+v differs from %+v", stats.MeasuredFPR, m)
+	}
+	t.Logf("Measured FPR %.4f (%d/%d), real FPR %.4f", m.MeasuredFPP, m.FalsePositives, m.Negatives, realFPR)
+
+	bf.ResetFPRMeasurement()
+	if m := bf.FPRMeasurement(); m.Negatives != 0 || m.SampledKeys == 0 {
+		t.Errorf("Reset should zero counters but keep keys, got %+v", m)
+	}
+}
+
+// TestFPRSamplingCap tests that the sample stays within its memory cap and stays exact
+func TestFPRSamplingCap(t *testing.T) {
+	bf := NewCacheOptimizedBloomFilter(10_000, 0.01)
+	bf.EnableFPRSampling(1, 100)
+
+	keys := make([]string, 5000)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("member_%d", i)
+	}
+	bf.AddStrings(keys)
+
+	m := bf.FPRMeasurement()
+	if m.SampledKeys > 100 || m.SampledKeys == 0 {
+		t.Errorf("Expected 1..100 sampled keys, got %d", m.SampledKeys)
+	}
+	if m.SampleRate < 32 {
+		t.Errorf("Expected the sample rate to grow past 32, got %d", m.SampleRate)
+	}
+
+	// Members must never count as negatives, even after eviction
+	bf.ContainsStrings(keys)
+	if m := bf.FPRMeasurement(); m.Negatives != 0 {
+		t.Errorf("Added keys were counted as negatives: %+v", m)
+	}
+}
+
+// TestFPRSamplingInvalidation tests bulk operations suspend and Clear restores the measurement
+func TestFPRSamplingInvalidation(t *testing.T) {
+	bf := NewCacheOptimizedBloomFilter(1000, 0.01)
+	bf.EnableFPRSampling(1, 1000)
+	bf.AddString("a")
+
+	other, _ := NewWithOptions(WithExpectedElements(1000), WithSeed(bf.Seed()))
+	other.AddString("b")
+	if err := bf.Union(other); err != nil {
+		t.Fatal(err)
+	}
+	bf.ContainsString("b")
+	if m := bf.FPRMeasurement(); m.Valid || m.Negatives != 0 {
+		t.Errorf("Expected measurement suspended after Union, got %+v", m)
+	}
+
+	bf.Clear()
+	if m := bf.FPRMeasurement(); !m.Valid || m.SampledKeys != 0 {
+		t.Errorf("Expected a fresh valid measurement after Clear, got %+v", m)
+	}
+
+	bf.DisableFPRSampling()
+	if m := bf.FPRMeasurement(); m.Enabled {
+		t.Errorf("Expected sampling disabled, got %+v", m)
+	}
+}
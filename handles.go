@@ -0,0 +1,225 @@
+package bloomfilter
+
+import (
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ReadHandle is a read-only view of a filter with its own counters, so the
+// lookups of one path can be told apart from other users of the same filter.
+// It is safe for concurrent use and its methods add only two atomic increments
+// to the underlying Contains.
+type ReadHandle struct {
+	bf        *CacheOptimizedBloomFilter
+	lookups   atomic.Uint64
+	positives atomic.Uint64
+}
+
+// ReadHandleStats reports a ReadHandle's lookups since it was created
+type ReadHandleStats struct {
+	Lookups   uint64
+	Positives uint64
+}
+
+// ReadHandle returns a new read-only view of the filter with zeroed counters
+func (bf *CacheOptimizedBloomFilter) ReadHandle() *ReadHandle {
+	return &ReadHandle{bf: bf}
+}
+
+// Filter returns the underlying filter
+func (h *ReadHandle) Filter() *CacheOptimizedBloomFilter { return h.bf }
+
+// Contains checks data in the underlying filter
+func (h *ReadHandle) Contains(data []byte) bool {
+	return h.count(h.bf.Contains(data))
+}
+
+// ContainsString checks s in the underlying filter
+func (h *ReadHandle) ContainsString(s string) bool {
+	return h.count(h.bf.ContainsString(s))
+}
+
+// ContainsUint64 checks n in the underlying filter
+func (h *ReadHandle) ContainsUint64(n uint64) bool {
+	return h.count(h.bf.ContainsUint64(n))
+}
+
+// ContainsStrings checks every string in keys, see CacheOptimizedBloomFilter.ContainsStrings
+func (h *ReadHandle) ContainsStrings(keys []string) []bool {
+	results := h.bf.ContainsStrings(keys)
+	var positives uint64
+	for _, r := range results {
+		if r {
+			positives++
+		}
+	}
+	h.lookups.Add(uint64(len(keys)))
+	h.positives.Add(positives)
+	return results
+}
+
+func (h *ReadHandle) count(found bool) bool {
+	h.lookups.Add(1)
+	if found {
+		h.positives.Add(1)
+	}
+	return found
+}
+
+// Stats returns the handle's counters
+func (h *ReadHandle) Stats() ReadHandleStats {
+	return ReadHandleStats{Lookups: h.lookups.Load(), Positives: h.positives.Load()}
+}
+
+// WriteHandleConfig configures a WriteHandle
+type WriteHandleConfig struct {
+	// Rate caps adds per second; a batch counts one per key. 0 means unlimited.
+	Rate float64
+	// Burst is how many adds may run ahead of Rate. Defaults to a tenth of a
+	// second's worth of Rate, at least 1.
+	Burst int
+	// Clock times the rate limit. Defaults to SystemClock.
+	Clock Clock
+}
+
+// WriteHandle is a write-only view of a filter with its own counters and an
+// optional rate limit, so a bulk backfill sharing a filter with a
+// latency-critical read path can be capped. Adds over the limit block the
+// calling goroutine until the limit allows them; readers are never blocked.
+// It is safe for concurrent use.
+type WriteHandle struct {
+	bf      *CacheOptimizedBloomFilter
+	limiter *writeLimiter // nil when unlimited
+
+	adds         atomic.Uint64
+	throttled    atomic.Uint64
+	throttleWait atomic.Int64
+}
+
+// WriteHandleStats reports a WriteHandle's adds since it was created
+type WriteHandleStats struct {
+	Adds uint64
+	// Throttled counts calls that waited for the rate limit
+	Throttled uint64
+	// ThrottleWait is the total time calls waited for the rate limit
+	ThrottleWait time.Duration
+}
+
+// WriteHandle returns a new write-only view of the filter.
+// Panics if cfg.Rate is negative or NaN, or cfg.Burst is negative.
+func (bf *CacheOptimizedBloomFilter) WriteHandle(cfg WriteHandleConfig) *WriteHandle {
+	if cfg.Rate < 0 || math.IsNaN(cfg.Rate) {
+		panic("bloomfilter: WriteHandle rate must not be negative")
+	}
+	if cfg.Burst < 0 {
+		panic("bloomfilter: WriteHandle burst must not be negative")
+	}
+
+	h := &WriteHandle{bf: bf}
+	if cfg.Rate > 0 && !math.IsInf(cfg.Rate, 1) {
+		burst := float64(cfg.Burst)
+		if burst == 0 {
+			burst = math.Max(1, math.Floor(cfg.Rate/10))
+		}
+		clock := clockOrSystem(cfg.Clock)
+		h.limiter = &writeLimiter{
+			clock:  clock,
+			rate:   cfg.Rate,
+			burst:  burst,
+			tokens: burst,
+			last:   clock.Now(),
+		}
+	}
+	return h
+}
+
+// Filter returns the underlying filter
+func (h *WriteHandle) Filter() *CacheOptimizedBloomFilter { return h.bf }
+
+// Add adds data to the underlying filter, waiting for the rate limit
+func (h *WriteHandle) Add(data []byte) {
+	h.wait(1)
+	h.bf.Add(data)
+}
+
+// AddString adds s to the underlying filter, waiting for the rate limit
+func (h *WriteHandle) AddString(s string) {
+	h.wait(1)
+	h.bf.AddString(s)
+}
+
+// AddUint64 adds n to the underlying filter, waiting for the rate limit
+func (h *WriteHandle) AddUint64(n uint64) {
+	h.wait(1)
+	h.bf.AddUint64(n)
+}
+
+// AddStrings adds every string in keys, waiting once for the whole batch
+func (h *WriteHandle) AddStrings(keys []string) {
+	h.wait(len(keys))
+	h.bf.AddStrings(keys)
+}
+
+// AddUint64Batch adds every key in keys, waiting once for the whole batch
+func (h *WriteHandle) AddUint64Batch(keys []uint64) {
+	h.wait(len(keys))
+	h.bf.AddUint64Batch(keys)
+}
+
+// wait blocks until the limit allows n adds and counts them
+func (h *WriteHandle) wait(n int) {
+	h.adds.Add(uint64(n))
+	if h.limiter == nil || n == 0 {
+		return
+	}
+	d := h.limiter.reserve(float64(n))
+	if d <= 0 {
+		return
+	}
+	h.throttled.Add(1)
+	h.throttleWait.Add(int64(d))
+
+	t := h.limiter.clock.NewTicker(d)
+	<-t.C()
+	t.Stop()
+}
+
+// Stats returns the handle's counters
+func (h *WriteHandle) Stats() WriteHandleStats {
+	return WriteHandleStats{
+		Adds:         h.adds.Load(),
+		Throttled:    h.throttled.Load(),
+		ThrottleWait: time.Duration(h.throttleWait.Load()),
+	}
+}
+
+// writeLimiter is a token bucket that lets callers go into debt: a reservation
+// always succeeds and returns how long to wait until the bucket is non-negative,
+// so batches larger than the burst are paced rather than rejected
+type writeLimiter struct {
+	clock Clock
+
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func (l *writeLimiter) reserve(n float64) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.clock.Now()
+	if elapsed := now.Sub(l.last); elapsed > 0 {
+		l.tokens = math.Min(l.burst, l.tokens+elapsed.Seconds()*l.rate)
+	}
+	l.last = now
+	l.tokens -= n
+	if l.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(math.Ceil(-l.tokens / l.rate * float64(time.Second)))
+}
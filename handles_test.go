@@ -0,0 +1,112 @@
+package bloomfilter
+
+import (
+	"testing"
+	"time"
+)
+
+// TestReadHandle tests that read handles count their own lookups
+func TestReadHandle(t *testing.T) {
+	bf := NewCacheOptimizedBloomFilter(1000, 0.01)
+	bf.AddString("present")
+	bf.AddUint64(42)
+
+	a, b := bf.ReadHandle(), bf.ReadHandle()
+	if !a.ContainsString("present") || !a.ContainsUint64(42) || a.Contains([]byte("absent")) {
+		t.Fatal("ReadHandle answers differ from the filter")
+	}
+	results := a.ContainsStrings([]string{"present", "absent", "missing"})
+	if !results[0] || results[1] || results[2] {
+		t.Fatalf("Unexpected ContainsStrings results %v", results)
+	}
+	b.ContainsString("present")
+
+	if s := a.Stats(); s.Lookups != 6 || s.Positives != 3 {
+		t.Errorf("Handle a: expected 6 lookups and 3 positives, got %+v", s)
+	}
+	if s := b.Stats(); s.Lookups != 1 || s.Positives != 1 {
+		t.Errorf("Handle b: expected 1 lookup and 1 positive, got %+v", s)
+	}
+	if a.Filter() != bf {
+		t.Error("Filter must return the underlying filter")
+	}
+}
+
+// TestWriteHandleUnlimited tests that writes without a rate never wait
+func TestWriteHandleUnlimited(t *testing.T) {
+	bf := NewCacheOptimizedBloomFilter(1000, 0.01)
+	w := bf.WriteHandle(WriteHandleConfig{})
+
+	w.Add([]byte("a"))
+	w.AddString("b")
+	w.AddUint64(3)
+	w.AddStrings([]string{"c", "d"})
+	w.AddUint64Batch([]uint64{4, 5, 6})
+
+	if !bf.Contains([]byte("a")) || !bf.ContainsString("d") || !bf.ContainsUint64(6) {
+		t.Error("WriteHandle adds missing from the filter")
+	}
+	if s := w.Stats(); s.Adds != 8 || s.Throttled != 0 || s.ThrottleWait != 0 {
+		t.Errorf("Expected 8 unthrottled adds, got %+v", s)
+	}
+}
+
+// TestWriteHandleRateLimit tests that writes over the rate wait for the clock
+func TestWriteHandleRateLimit(t *testing.T) {
+	clock := NewManualClock(time.Unix(0, 0))
+	bf := NewCacheOptimizedBloomFilter(1000, 0.01)
+	w := bf.WriteHandle(WriteHandleConfig{Rate: 10, Burst: 2, Clock: clock})
+
+	// The burst passes without waiting
+	w.AddUint64(1)
+	w.AddUint64(2)
+	if s := w.Stats(); s.Throttled != 0 {
+		t.Fatalf("Burst adds were throttled: %+v", s)
+	}
+
+	// A batch of 3 over an empty bucket owes 300ms at 10/s
+	done := make(chan struct{})
+	go func() {
+		w.AddUint64Batch([]uint64{3, 4, 5})
+		close(done)
+	}()
+
+	var waited time.Duration
+	for {
+		select {
+		case <-done:
+			if waited < 300*time.Millisecond {
+				t.Errorf("Batch finished after %v of clock time, expected at least 300ms", waited)
+			}
+			s := w.Stats()
+			if s.Adds != 5 || s.Throttled != 1 || s.ThrottleWait != 300*time.Millisecond {
+				t.Errorf("Expected 5 adds with one 300ms wait, got %+v", s)
+			}
+			if !bf.ContainsUint64(5) {
+				t.Error("Throttled batch missing from the filter")
+			}
+			return
+		case <-time.After(time.Millisecond):
+			clock.Advance(10 * time.Millisecond)
+			waited += 10 * time.Millisecond
+			if waited > 10*time.Second {
+				t.Fatal("Throttled batch never finished")
+			}
+		}
+	}
+}
+
+// TestWriteHandleValidation tests that invalid limits panic
+func TestWriteHandleValidation(t *testing.T) {
+	bf := NewCacheOptimizedBloomFilter(1000, 0.01)
+	for _, cfg := range []WriteHandleConfig{{Rate: -1}, {Burst: -1}} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("Expected panic for %+v", cfg)
+				}
+			}()
+			bf.WriteHandle(cfg)
+		}()
+	}
+}
@@ -0,0 +1,88 @@
+package bloomfilter
+
+import (
+	"hash"
+	"hash/maphash"
+	"sync"
+)
+
+// HashFunc computes the two base hashes of a key, from which the probe scheme
+// derives the bit positions. h2 should be independent of h1: keys sharing
+// both share every probe. It must be safe for concurrent use and must not
+// retain data, which is a reused buffer.
+type HashFunc func(data []byte) (h1, h2 uint64)
+
+// hashBuffers hold keys passed to a HashFunc. Handing the caller's slice to an
+// unknown function would make every key escape, so that keys built on the
+// stack (AddUint64, Add16, AddString) allocated even with the built-in hash.
+var hashBuffers = sync.Pool{New: func() any { return new([]byte) }}
+
+// customHash returns h(data), passing a pooled copy of data
+func customHash(h HashFunc, data []byte) (uint64, uint64) {
+	buf := hashBuffers.Get().(*[]byte)
+	*buf = append((*buf)[:0], data...)
+	h1, h2 := h(*buf)
+	hashBuffers.Put(buf)
+	return h1, h2
+}
+
+// WithHasher replaces the built-in hash functions with h, for example
+// Hash64Pair or MapHash, to reuse a vetted hash or to randomize bit positions
+// per process. The seed (see WithSeed) is not passed to h, so it does not
+// keep filters with custom hashers from combining. A nil h keeps the built-in
+// hash.
+//
+// The serialization formats do not record the hasher: restore a filter and
+// call SetHasher with the same function before using it. Filters combine only
+// when both use a custom hasher or neither does, and it is up to the caller to
+// use the same function for both. Batch adds of uint64 keys lose their SIMD
+// hashing, and each key is copied to a pooled buffer before h sees it.
+func WithHasher(h HashFunc) Option {
+	return func(o *options) { o.hasher = h }
+}
+
+// SetHasher changes the function that hashes keys, nil for the built-in hash.
+// Bits already set are not moved, so like SetProbeScheme it is only meaningful
+// on an empty filter or right after restoring bits written with h. It must
+// not be called concurrently with other methods.
+func (bf *CacheOptimizedBloomFilter) SetHasher(h HashFunc) {
+	bf.hasher = h
+}
+
+// HasCustomHasher reports whether keys are hashed by a function given to
+// WithHasher or SetHasher
+func (bf *CacheOptimizedBloomFilter) HasCustomHasher() bool {
+	return bf.hasher != nil
+}
+
+// Hash64Pair returns a HashFunc computing h1 with hashers from newH1 and h2
+// with hashers from newH2, such as fnv.New64a and a crc64 digest. Hashers are
+// pooled and Reset after each key, so neither needs to be safe for concurrent
+// use and adds do not allocate in the steady state.
+func Hash64Pair(newH1, newH2 func() hash.Hash64) HashFunc {
+	type pair struct{ h1, h2 hash.Hash64 }
+	pool := sync.Pool{New: func() any { return &pair{newH1(), newH2()} }}
+	return func(data []byte) (uint64, uint64) {
+		p := pool.Get().(*pair)
+		p.h1.Write(data)
+		p.h2.Write(data)
+		h1, h2 := p.h1.Sum64(), p.h2.Sum64()
+		p.h1.Reset()
+		p.h2.Reset()
+		pool.Put(p)
+		return h1, h2
+	}
+}
+
+// MapHash returns a HashFunc based on hash/maphash with seed. With
+// maphash.MakeSeed() bit positions differ in every process, so they cannot be
+// predicted, but the filter's bits are only meaningful to the process that
+// made the seed. h2 is derived from h1 by the SplitMix64 finalizer, a
+// bijection, so two keys share their probes only when their 64-bit hashes
+// collide.
+func MapHash(seed maphash.Seed) HashFunc {
+	return func(data []byte) (uint64, uint64) {
+		h1 := maphash.Bytes(seed, data)
+		return h1, mix64(h1)
+	}
+}
@@ -0,0 +1,113 @@
+package bloomfilter
+
+import (
+	"errors"
+	"fmt"
+	"hash"
+	"hash/crc64"
+	"hash/fnv"
+	"hash/maphash"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// TestWithHasher tests filters built on stdlib hashers
+func TestWithHasher(t *testing.T) {
+	crc := crc64.MakeTable(crc64.ECMA)
+	pair := Hash64Pair(fnv.New64a, func() hash.Hash64 { return crc64.New(crc) })
+	for name, h := range map[string]HashFunc{"pair": pair, "maphash": MapHash(maphash.MakeSeed())} {
+		bf, err := NewWithOptions(WithExpectedElements(5000), WithFalsePositiveRate(0.01), WithHasher(h))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bf.HasCustomHasher() {
+			t.Fatalf("%s: HasCustomHasher is false", name)
+		}
+		ref := NewReferenceFilter(bf)
+		var keys [][]byte
+		for i := 0; i < 5000; i++ {
+			key := []byte(fmt.Sprintf("key-%d", i))
+			keys = append(keys, key)
+			ref.Add(key)
+		}
+		bf.AddBatch(keys[:2500])
+		for _, key := range keys[2500:] {
+			bf.Add(key)
+		}
+		negatives := func(yield func([]byte) bool) {
+			for i := 0; i < 20000; i++ {
+				if !yield([]byte(fmt.Sprintf("absent-%d", i))) {
+					return
+				}
+			}
+		}
+		if err := DifferentialCheck(bf, ref, negatives); err != nil {
+			t.Errorf("%s: %v", name, err)
+		}
+		if fpp := bf.MeasureFPP(negatives); fpp > 0.03 {
+			t.Errorf("%s: false positive rate %.4f", name, fpp)
+		}
+
+		bf.AddUint64Batch([]uint64{1, 2, 3})
+		bf.Add16([16]byte{9})
+		if !bf.ContainsUint64(2) || slices.Contains(bf.ContainsUint64Batch([]uint64{1, 2, 3}), false) || !bf.Contains([]byte{9, 15: 0}) {
+			t.Errorf("%s: uint64 and 16-byte keys must hash like their bytes", name)
+		}
+		if !bf.ContainsKey(PrecomputeKey(keys[0])) || !bf.Clone().Contains(keys[1]) {
+			t.Errorf("%s: precomputed keys and clones must use the hasher", name)
+		}
+		log := NewMemoryKeyLog()
+		for _, key := range keys {
+			log.Record(key)
+		}
+		reseeded := bf.Reseeded(7, log)
+		if err := DifferentialCheck(reseeded, ref, negatives); err != nil || !reseeded.HasCustomHasher() {
+			t.Errorf("%s: Reseeded must keep the hasher: %v", name, err)
+		}
+		loaded := bf.Clone()
+		loaded.Clear()
+		ch := make(chan []byte, len(keys))
+		for _, key := range keys {
+			ch <- key
+		}
+		close(ch)
+		loaded.BulkLoad(ch, 4, ShardedBulkLoad())
+		if err := DifferentialCheck(loaded, ref, slices.Values(keys)); err != nil {
+			t.Errorf("%s: sharded bulk load: %v", name, err)
+		}
+
+		data, err := bf.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		restored := &CacheOptimizedBloomFilter{}
+		if err := restored.UnmarshalBinary(data); err != nil {
+			t.Fatal(err)
+		}
+		restored.SetHasher(h)
+		if err := DifferentialCheck(restored, ref, slices.Values(keys)); err != nil {
+			t.Errorf("%s: restored: %v", name, err)
+		}
+
+		if allocs := testing.AllocsPerRun(100, func() {
+			bf.AddUint64(7)
+			bf.ContainsString("key-1")
+		}); allocs != 0 && !raceEnabled {
+			t.Errorf("%s: expected 0 allocations, got %f", name, allocs)
+		}
+
+		plain, _ := NewWithOptions(WithExpectedElements(5000), WithFalsePositiveRate(0.01), WithSeed(bf.Seed()))
+		err = plain.Union(bf)
+		var ie *IncompatibleError
+		if !errors.As(err, &ie) || ie.Param != ParamHasher || !errors.Is(err, ErrIncompatibleHash) ||
+			!strings.Contains(err.Error(), "hashers built-in and custom") {
+			t.Errorf("%s: union with the built-in hash: %v", name, err)
+		}
+		// The seed does not reach a custom hasher, so seeds do not keep such filters apart
+		same, _ := NewWithOptions(WithExpectedElements(5000), WithFalsePositiveRate(0.01), WithHasher(h))
+		if err := same.Union(bf); err != nil || same.Seed() == bf.Seed() || !same.Contains(keys[0]) {
+			t.Errorf("%s: union of custom hashers with seeds %#x and %#x: %v", name, same.Seed(), bf.Seed(), err)
+		}
+	}
+}
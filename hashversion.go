@@ -0,0 +1,87 @@
+package bloomfilter
+
+import (
+	"fmt"
+
+	"github.com/shaia/BloomFilter/internal/hash"
+)
+
+// HashVersion selects the built-in function hashing a key to the h1 and h2 the
+// probe scheme starts from. Filters only combine with filters of the same
+// version.
+type HashVersion uint8
+
+const (
+	// HashV1 computes h1 and h2 in two passes over the key, an FNV-1a variant
+	// and a multiply-shift hash, both reading 8 bytes at a time. It is the hash
+	// of every filter written before HashV2 existed.
+	HashV1 HashVersion = iota
+	// HashV2 computes both in one pass with MurmurHash3 x64_128, about half the
+	// work of HashV1 for long keys, with a length-aware finalizer that also
+	// separates keys differing only in trailing zero bytes. It is the default
+	// for new filters.
+	HashV2
+)
+
+// DefaultHashVersion is the hash of filters created without WithHashVersion
+const DefaultHashVersion = HashV2
+
+// String returns the version's name as used by the serialization formats
+func (v HashVersion) String() string {
+	switch v {
+	case HashV1:
+		return "v1"
+	case HashV2:
+		return "v2"
+	}
+	return fmt.Sprintf("HashVersion(%d)", uint8(v))
+}
+
+// ParseHashVersion returns the version named by String
+func ParseHashVersion(name string) (HashVersion, error) {
+	switch name {
+	case "v1":
+		return HashV1, nil
+	case "v2":
+		return HashV2, nil
+	}
+	return 0, fmt.Errorf("bloomfilter: unknown hash version %q", name)
+}
+
+// valid reports whether v is a defined version
+func (v HashVersion) valid() bool {
+	return v <= HashV2
+}
+
+// hash returns the h1 and h2 of data under version v
+func (v HashVersion) hash(data []byte, seed uint64) (uint64, uint64) {
+	if v == HashV2 {
+		return hash.Seeded128(data, seed)
+	}
+	return hash.Seeded1(data, seed), hash.Seeded2(data, seed)
+}
+
+// WithHashVersion sets the built-in hash. The default is HashV2; use HashV1 to
+// build filters that match bits written by earlier versions.
+func WithHashVersion(v HashVersion) Option {
+	return func(o *options) { o.hashVersion = v }
+}
+
+// HashVersion returns the built-in hash the filter uses
+func (bf *CacheOptimizedBloomFilter) HashVersion() HashVersion {
+	return bf.hashVersion
+}
+
+// SetHashVersion changes the built-in hash. Like SetProbeScheme it does not
+// move bits already set, so it is only meaningful on an empty filter or right
+// after restoring bits written with v, such as a filter from NewFromWords or
+// NewFromJavaLongs whose bits were built with HashV1. It must not be called
+// concurrently with other methods.
+// Returns an error if v is not a defined version.
+func (bf *CacheOptimizedBloomFilter) SetHashVersion(v HashVersion) error {
+	if !v.valid() {
+		return fmt.Errorf("bloomfilter: unknown hash version %d", uint8(v))
+	}
+	bf.hashVersion = v
+	return nil
+}
@@ -0,0 +1,295 @@
+package bloomfilter
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/shaia/BloomFilter/internal/hash"
+)
+
+// TestHashVersionOptions tests the default, the option and its validation
+func TestHashVersionOptions(t *testing.T) {
+	if v := NewCacheOptimizedBloomFilter(1000, 0.01).HashVersion(); v != HashV2 {
+		t.Errorf("Default hash version %s, want %s", v, HashV2)
+	}
+	bf, err := NewWithOptions(WithExpectedElements(1000), WithHashVersion(HashV1))
+	if err != nil || bf.HashVersion() != HashV1 {
+		t.Fatalf("WithHashVersion(HashV1): %v, %v", bf, err)
+	}
+	if _, err := NewWithOptions(WithExpectedElements(1000), WithHashVersion(HashVersion(9))); err == nil {
+		t.Error("Undefined hash version accepted by NewWithOptions")
+	}
+	if err := bf.SetHashVersion(HashVersion(9)); err == nil || bf.HashVersion() != HashV1 {
+		t.Errorf("SetHashVersion accepted an undefined version: %v", err)
+	}
+
+	for _, v := range []HashVersion{HashV1, HashV2} {
+		if parsed, err := ParseHashVersion(v.String()); err != nil || parsed != v {
+			t.Errorf("ParseHashVersion(%q) = %s, %v", v.String(), parsed, err)
+		}
+	}
+	if _, err := ParseHashVersion("v3"); err == nil {
+		t.Error(`ParseHashVersion("v3") succeeded`)
+	}
+}
+
+// TestHashVersionPaths tests that every way of adding a key hashes it like Add
+// for both versions, and that the versions set different bits
+func TestHashVersionPaths(t *testing.T) {
+	filters := map[HashVersion]*CacheOptimizedBloomFilter{}
+	for _, v := range []HashVersion{HashV1, HashV2} {
+		t.Run(v.String(), func(t *testing.T) {
+			opts := []Option{WithExpectedElements(2000), WithSeed(0x1234_5678_9abc), WithHashVersion(v)}
+			want, _ := NewWithOptions(opts...)
+			typed, _ := NewWithOptions(opts...)
+			wantUints, _ := NewWithOptions(opts...)
+			batch, _ := NewWithOptions(opts...)
+
+			var uints []uint64
+			for i := range 503 { // not a multiple of the SIMD kernel widths
+				n := uint64(i) * 0x9e3779b97f4a7c15
+				uints = append(uints, n)
+				var b [16]byte
+				binary.LittleEndian.PutUint64(b[:], n)
+				binary.LittleEndian.PutUint64(b[8:], ^n)
+				key := []byte(fmt.Sprintf("key-%d", i))
+
+				want.Add(b[:8])
+				want.Add(b[:])
+				want.Add(key)
+				typed.AddUint64(n)
+				typed.Add16(b)
+				typed.AddKey(PrecomputeKeyWithSeed(key, want.Seed()))
+				wantUints.Add(b[:8])
+			}
+			batch.AddUint64Batch(uints)
+
+			if !typed.Equal(want) {
+				t.Error("AddUint64, Add16 and AddKey set different bits than Add")
+			}
+			if !batch.Equal(wantUints) {
+				t.Error("AddUint64Batch set different bits than Add")
+			}
+			filters[v] = want
+		})
+	}
+	if filters[HashV1].Equal(filters[HashV2]) {
+		t.Error("HashV1 and HashV2 set the same bits")
+	}
+}
+
+// TestHashVersionMatchesHash tests that HashV2 probes from Seeded128 and HashV1
+// from the two legacy hashes
+func TestHashVersionMatchesHash(t *testing.T) {
+	key := []byte("the quick brown fox")
+	for v, h := range map[HashVersion]func() (uint64, uint64){
+		HashV1: func() (uint64, uint64) { return hash.Seeded1(key, 5), hash.Seeded2(key, 5) },
+		HashV2: func() (uint64, uint64) { return hash.Seeded128(key, 5) },
+	} {
+		bf, _ := NewWithOptions(WithExpectedElements(100), WithSeed(5), WithHashVersion(v))
+		h1, h2 := bf.hashKey(key)
+		if w1, w2 := h(); h1 != w1 || h2 != w2 {
+			t.Errorf("%s: hashKey = %#x, %#x, want %#x, %#x", v, h1, h2, w1, w2)
+		}
+	}
+}
+
+// TestHashVersionRoundTrip tests that every encoding and copy keeps the version
+func TestHashVersionRoundTrip(t *testing.T) {
+	for _, v := range []HashVersion{HashV1, HashV2} {
+		t.Run(v.String(), func(t *testing.T) {
+			bf, _ := NewWithOptions(WithExpectedElements(1000), WithSeed(3), WithHashVersion(v))
+			var keys [][]byte
+			for i := range 500 {
+				keys = append(keys, []byte(fmt.Sprintf("key-%d", i)))
+			}
+			bf.AddBatch(keys)
+			log := NewMemoryKeyLog()
+			for _, k := range keys {
+				log.Record(k)
+			}
+
+			data, _ := bf.MarshalBinary()
+			decoded, err := DecodeBinary(data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			view, err := NewFromBuffer(slices.Clone(data))
+			if err != nil {
+				t.Fatal(err)
+			}
+			jsonData, _ := json.Marshal(bf)
+			fromJSON := &CacheOptimizedBloomFilter{}
+			if err := json.Unmarshal(jsonData, fromJSON); err != nil {
+				t.Fatal(err)
+			}
+			grown, err := bf.Grow(2, slices.Values(keys))
+			if err != nil {
+				t.Fatal(err)
+			}
+			sub, _ := bf.SubFilter(SubFilterView{LineCount: bf.cacheLineCount})
+
+			copies := map[string]*CacheOptimizedBloomFilter{
+				"DecodeBinary": decoded,
+				"View":         view,
+				"JSON":         fromJSON,
+				"Clone":        bf.Clone(),
+				"SubFilter":    sub,
+				"Reseeded":     bf.Reseeded(9, log),
+				"Grow":         grown,
+			}
+			for name, c := range copies {
+				if c.HashVersion() != v {
+					t.Errorf("%s: hash version %s, want %s", name, c.HashVersion(), v)
+				}
+				for _, k := range keys {
+					if !c.Contains(k) {
+						t.Fatalf("%s: false negative for %q", name, k)
+					}
+				}
+			}
+			for _, name := range []string{"DecodeBinary", "View", "JSON", "Clone"} {
+				if !copies[name].Equal(bf) {
+					t.Errorf("%s: bits differ", name)
+				}
+			}
+		})
+	}
+}
+
+// TestHashVersionLegacyEncodings tests that HashV1 filters keep the encodings
+// written before HashV2 existed, which decode as HashV1
+func TestHashVersionLegacyEncodings(t *testing.T) {
+	legacy, _ := NewWithOptions(WithExpectedElements(100), WithProbeScheme(DoubleHashing), WithHashVersion(HashV1))
+	legacy.AddString("x")
+	data, _ := legacy.MarshalBinary()
+	if flags := binary.LittleEndian.Uint16(data[6:]); flags&flagHashV2 != 0 {
+		t.Errorf("HashV1 filter written with flags %#x", flags)
+	}
+	var j jsonFilter
+	jsonData, _ := json.Marshal(legacy)
+	json.Unmarshal(jsonData, &j)
+	if j.Version != 1 || j.Hash != "" {
+		t.Errorf("HashV1 filter written as JSON version %d with hash %q", j.Version, j.Hash)
+	}
+	restored := &CacheOptimizedBloomFilter{}
+	if err := json.Unmarshal(jsonData, restored); err != nil || restored.HashVersion() != HashV1 || !restored.Equal(legacy) {
+		t.Errorf("JSON version 1 not restored as HashV1: %v", err)
+	}
+
+	current := NewCacheOptimizedBloomFilter(100, 0.01)
+	current.AddString("x")
+	jsonData, _ = json.Marshal(current)
+	json.Unmarshal(jsonData, &j)
+	if j.Version != jsonFormatVersion || j.Hash != "v2" {
+		t.Errorf("HashV2 filter written as JSON version %d with hash %q", j.Version, j.Hash)
+	}
+
+	// Bare words record no version; SetHashVersion restores HashV1 bits
+	fromWords, _ := NewFromWordsWithSeed(legacy.Words(), legacy.HashCount(), legacy.Seed())
+	if fromWords.HashVersion() != DefaultHashVersion {
+		t.Errorf("NewFromWords uses %s, want %s", fromWords.HashVersion(), DefaultHashVersion)
+	}
+	fromWords.SetProbeScheme(legacy.ProbeScheme())
+	fromWords.SetHashVersion(HashV1)
+	if !fromWords.Equal(legacy) || !fromWords.ContainsString("x") {
+		t.Error("HashV1 words not restored by SetHashVersion")
+	}
+}
+
+// TestHashVersionDelta tests that deltas carry the version and are rejected
+// by a filter of the other version
+func TestHashVersionDelta(t *testing.T) {
+	for _, v := range []HashVersion{HashV1, HashV2} {
+		primary, _ := NewWithOptions(WithExpectedElements(1000), WithHashVersion(v))
+		replica := primary.Clone()
+		primary.EnableChangeTracking()
+		primary.AddString("delta")
+		delta, err := primary.ExportDelta(0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := replica.ApplyDelta(bytes.NewReader(delta)); err != nil || !replica.ContainsString("delta") {
+			t.Errorf("%s: delta not applied: %v", v, err)
+		}
+
+		other := primary.Clone()
+		other.Clear()
+		other.SetHashVersion(1 - v)
+		var incompatible *IncompatibleError
+		if err := other.ApplyDelta(bytes.NewReader(delta)); !errors.As(err, &incompatible) || incompatible.Param != ParamHashVersion {
+			t.Errorf("%s: delta applied to a filter of the other version: %v", v, err)
+		}
+	}
+}
+
+// TestHashVersionIncompatible tests that filters of different versions do not combine
+func TestHashVersionIncompatible(t *testing.T) {
+	a, _ := NewWithOptions(WithExpectedElements(1000), WithSeed(1), WithHashVersion(HashV1))
+	b, _ := NewWithOptions(WithExpectedElements(1000), WithSeed(1), WithHashVersion(HashV2))
+	err := a.Union(b)
+	var incompatible *IncompatibleError
+	if !errors.As(err, &incompatible) || incompatible.Param != ParamHashVersion || !errors.Is(err, ErrIncompatibleHash) {
+		t.Fatalf("Union across versions: %v", err)
+	}
+	if msg := err.Error(); !strings.Contains(msg, "hash versions v1 and v2") {
+		t.Errorf("Error %q does not name the versions", msg)
+	}
+	if a.Compatible(b) == nil {
+		t.Error("Compatible accepted filters of different versions")
+	}
+}
+
+// TestHashVersionStorageAndReference tests that StorageFilter and
+// ReferenceFilter hash like the filter for both versions
+func TestHashVersionStorageAndReference(t *testing.T) {
+	for _, v := range []HashVersion{HashV1, HashV2} {
+		bf, _ := NewWithOptions(WithExpectedElements(1000), WithSeed(11), WithHashVersion(v))
+		storage, err := OpenFileStorage(filepath.Join(t.TempDir(), "filter.bits"), bf.BitCount())
+		if err != nil {
+			t.Fatal(err)
+		}
+		sf, _ := NewStorageFilterWithSeed(storage, bf.HashCount(), bf.Seed())
+		defer sf.Close()
+		if sf.HashVersion() != DefaultHashVersion {
+			t.Errorf("StorageFilter uses %s, want %s", sf.HashVersion(), DefaultHashVersion)
+		}
+		if err := sf.SetHashVersion(v); err != nil {
+			t.Fatal(err)
+		}
+		ref := NewReferenceFilter(bf)
+		for i := range 300 {
+			key := []byte(fmt.Sprintf("key-%d", i))
+			bf.Add(key)
+			ref.Add(key)
+			if err := sf.AddE(key); err != nil {
+				t.Fatal(err)
+			}
+		}
+		keys := func(yield func([]byte) bool) {
+			for i := range 2000 {
+				if !yield([]byte(fmt.Sprintf("key-%d", i))) {
+					return
+				}
+			}
+		}
+		if err := DifferentialCheck(bf, ref, keys); err != nil {
+			t.Errorf("%s: %v", v, err)
+		}
+		for key := range keys {
+			if got, err := sf.ContainsE(key); err != nil || got != bf.Contains(key) {
+				t.Fatalf("%s: StorageFilter answers %v, %v for %q", v, got, err, key)
+			}
+		}
+	}
+	if err := (&StorageFilter{}).SetHashVersion(HashVersion(9)); err == nil {
+		t.Error("StorageFilter accepted an undefined version")
+	}
+}
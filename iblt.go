@@ -0,0 +1,356 @@
+package bloomfilter
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"math"
+
+	"github.com/shaia/BloomFilter/internal/hash"
+)
+
+// Invertible Bloom lookup table
+//
+// Every key is XORed into hashCount cells, one in each of hashCount equal
+// partitions of the table, found by double hashing as in the filter.
+// A cell holds the signed number of keys in it, the XOR of their lengths, the
+// XOR of a check hash of each key and the XOR of the keys themselves, zero
+// padded to KeySize. Subtracting one party's table from the other's cancels
+// every key the two sets share, and what remains can be peeled: a cell with a
+// count of ±1 whose check hash matches its key holds exactly one key, which is
+// then removed from its other cells, often making them pure in turn. Decoding
+// succeeds with high probability while the difference is below about 75% of
+// the cell count (the peeling threshold for 3 hashes is 1/1.222).
+//
+// Cells are stored as consecutive words in cache-line aligned memory:
+//
+//	count i64 | length xor u64 | check hash xor u64 | key xor [KeySize/8 rounded up]u64
+
+const (
+	// ibltHashCount is the number of cells each key is added to
+	ibltHashCount = 3
+	// ibltOverhead is the number of cells per expected difference; the peeling
+	// threshold is 1.222 and the rest absorbs small-size variance
+	ibltOverhead = 1.5
+	// ibltMinCells keeps small tables decodable
+	ibltMinCells = 24
+	// ibltCheckSeed derives the check hash from the table's seed
+	ibltCheckSeed = 0x6a09e667f3bcc909
+	// ibltCellHeader is the number of words before a cell's key
+	ibltCellHeader = 3
+)
+
+// ErrIBLTUndecodable is returned by Decode when the difference is too large
+// for the table to peel completely
+var ErrIBLTUndecodable = errors.New("bloomfilter: IBLT difference too large to decode")
+
+// IBLT is an invertible Bloom lookup table for computing the difference of two
+// key sets. Each party encodes its keys into a table of the same geometry,
+// one side sends its table (MarshalBinary), and the other subtracts it from its
+// own and decodes the keys only it has and the keys only the sender has. The
+// table size depends on the expected difference, not the size of the sets.
+//
+// An IBLT is not safe for concurrent use.
+type IBLT struct {
+	words     []uint64 // cells, cellWords each
+	cells     uint64
+	keySize   int
+	keyWords  int
+	cellWords int
+	seed      uint64
+}
+
+// NewIBLT creates a table that decodes set differences of up to about
+// expectedDifference keys of at most keySize bytes.
+// Panics if expectedDifference or keySize is 0.
+func NewIBLT(expectedDifference uint64, keySize int) *IBLT {
+	if expectedDifference == 0 {
+		panic("bloomfilter: expectedDifference must be greater than 0")
+	}
+	cells := uint64(math.Ceil(float64(expectedDifference) * ibltOverhead))
+	return NewIBLTWithCells(max(cells, ibltMinCells), keySize, 0)
+}
+
+// NewIBLTWithCells creates a table with an explicit cell count, rounded up to a
+// multiple of the hash count, and hash seed. Both parties must use the same
+// geometry and seed.
+// Panics if cells or keySize is 0.
+func NewIBLTWithCells(cells uint64, keySize int, seed uint64) *IBLT {
+	if cells == 0 {
+		panic("bloomfilter: cells must be greater than 0")
+	}
+	if keySize <= 0 {
+		panic("bloomfilter: keySize must be greater than 0")
+	}
+
+	cells = (cells + ibltHashCount - 1) / ibltHashCount * ibltHashCount
+	keyWords := (keySize + 7) / 8
+	cellWords := ibltCellHeader + keyWords
+	totalWords := cells * uint64(cellWords)
+	lines := allocCacheLines((totalWords + WordsPerCacheLine - 1) / WordsPerCacheLine)
+	return &IBLT{
+		words:     wordsOf(lines)[:totalWords],
+		cells:     cells,
+		keySize:   keySize,
+		keyWords:  keyWords,
+		cellWords: cellWords,
+		seed:      seed,
+	}
+}
+
+// Cells returns the number of cells
+func (t *IBLT) Cells() uint64 { return t.cells }
+
+// KeySize returns the maximum key length in bytes
+func (t *IBLT) KeySize() int { return t.keySize }
+
+// Seed returns the hash seed
+func (t *IBLT) Seed() uint64 { return t.seed }
+
+// Insert adds key to the table.
+// Returns an error if key is longer than KeySize.
+func (t *IBLT) Insert(key []byte) error {
+	return t.update(key, 1)
+}
+
+// Delete removes a key that was inserted, or records a key the other party
+// has when it was not.
+// Returns an error if key is longer than KeySize.
+func (t *IBLT) Delete(key []byte) error {
+	return t.update(key, -1)
+}
+
+// Encode inserts every key of keys, stopping at the first key longer than KeySize
+func (t *IBLT) Encode(keys KeySource) error {
+	for key := range keys.Keys() {
+		if err := t.Insert(key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (t *IBLT) update(key []byte, delta int64) error {
+	if len(key) > t.keySize {
+		return fmt.Errorf("bloomfilter: IBLT key of %d bytes exceeds KeySize %d", len(key), t.keySize)
+	}
+	check := hash.Seeded1(key, t.seed^ibltCheckSeed)
+	var idx [ibltHashCount]uint64
+	t.cellIndexes(key, &idx)
+	for _, c := range idx {
+		t.xorCell(c, delta, uint64(len(key)), check, key)
+	}
+	return nil
+}
+
+// cellIndexes returns one cell per partition for key. Unlike filter probes,
+// the double-hashed values are mixed before the reduction: reduced directly,
+// a key's cells would depend only on h1 and h2 modulo the partition size, and
+// two keys sharing all their cells, which can never be peeled, would be
+// likely in any table holding more keys than the square root of a partition.
+func (t *IBLT) cellIndexes(key []byte, idx *[ibltHashCount]uint64) {
+	h1, h2 := hash.Seeded1(key, t.seed), hash.Seeded2(key, t.seed)
+	part := t.cells / ibltHashCount
+	for i := range idx {
+		idx[i] = uint64(i)*part + mix64(h1+uint64(i)*h2)%part
+	}
+}
+
+// mix64 is the SplitMix64 finalizer
+func mix64(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	return x ^ x>>31
+}
+
+// xorCell adds delta to cell c's count and XORs the key fields into it
+func (t *IBLT) xorCell(c uint64, delta int64, length, check uint64, key []byte) {
+	cell := t.words[c*uint64(t.cellWords) : (c+1)*uint64(t.cellWords)]
+	cell[0] = uint64(int64(cell[0]) + delta)
+	cell[1] ^= length
+	cell[2] ^= check
+	for w := 0; w*8 < len(key); w++ {
+		cell[ibltCellHeader+w] ^= keyWord(key, w)
+	}
+}
+
+// keyWord returns word w of key, zero padded, little-endian
+func keyWord(key []byte, w int) uint64 {
+	if len(key) >= (w+1)*8 {
+		return binary.LittleEndian.Uint64(key[w*8:])
+	}
+	var buf [8]byte
+	copy(buf[:], key[w*8:])
+	return binary.LittleEndian.Uint64(buf[:])
+}
+
+// Subtract subtracts other cell by cell, leaving a table of the difference of
+// the two key sets.
+// Returns an error if the geometries or seeds differ.
+func (t *IBLT) Subtract(other *IBLT) error {
+	if t.cells != other.cells || t.keySize != other.keySize {
+		return fmt.Errorf("%w: IBLTs of %d cells with %d-byte keys and %d cells with %d-byte keys",
+			ErrIncompatible, t.cells, t.keySize, other.cells, other.keySize)
+	}
+	if t.seed != other.seed {
+		return fmt.Errorf("%w for IBLT subtraction (seeds %#x and %#x)", ErrSeedMismatch, t.seed, other.seed)
+	}
+	for c := uint64(0); c < t.cells; c++ {
+		base := c * uint64(t.cellWords)
+		t.words[base] = uint64(int64(t.words[base]) - int64(other.words[base]))
+		for w := base + 1; w < base+uint64(t.cellWords); w++ {
+			t.words[w] ^= other.words[w]
+		}
+	}
+	return nil
+}
+
+// Decode lists the keys of the table without modifying it. After Subtract,
+// onlyHere holds the keys only the receiver had and onlyThere the keys only
+// the subtracted table had. If the table cannot be peeled completely, Decode
+// returns the keys it recovered and an error wrapping ErrIBLTUndecodable.
+func (t *IBLT) Decode() (onlyHere, onlyThere [][]byte, err error) {
+	work := &IBLT{
+		words:     append([]uint64(nil), t.words...),
+		cells:     t.cells,
+		keySize:   t.keySize,
+		keyWords:  t.keyWords,
+		cellWords: t.cellWords,
+		seed:      t.seed,
+	}
+
+	pending := make([]uint64, 0, t.cells)
+	for c := uint64(0); c < t.cells; c++ {
+		pending = append(pending, c)
+	}
+	var idx [ibltHashCount]uint64
+	for len(pending) > 0 {
+		c := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+
+		key, sign, ok := work.pureKey(c)
+		if !ok {
+			continue
+		}
+		if sign > 0 {
+			onlyHere = append(onlyHere, key)
+		} else {
+			onlyThere = append(onlyThere, key)
+		}
+		// Removing the key may leave its other cells pure
+		check := hash.Seeded1(key, t.seed^ibltCheckSeed)
+		work.cellIndexes(key, &idx)
+		for _, other := range idx {
+			work.xorCell(other, -sign, uint64(len(key)), check, key)
+			pending = append(pending, other)
+		}
+	}
+
+	for _, w := range work.words {
+		if w != 0 {
+			return onlyHere, onlyThere, fmt.Errorf("%w: %d keys recovered", ErrIBLTUndecodable, len(onlyHere)+len(onlyThere))
+		}
+	}
+	return onlyHere, onlyThere, nil
+}
+
+// pureKey returns the key of cell c and its count if the cell holds exactly one key
+func (t *IBLT) pureKey(c uint64) ([]byte, int64, bool) {
+	cell := t.words[c*uint64(t.cellWords) : (c+1)*uint64(t.cellWords)]
+	count := int64(cell[0])
+	if count != 1 && count != -1 {
+		return nil, 0, false
+	}
+	length := cell[1]
+	if length > uint64(t.keySize) {
+		return nil, 0, false
+	}
+
+	key := make([]byte, t.keyWords*8)
+	for w := 0; w < t.keyWords; w++ {
+		binary.LittleEndian.PutUint64(key[w*8:], cell[ibltCellHeader+w])
+	}
+	for _, b := range key[length:] {
+		if b != 0 {
+			return nil, 0, false
+		}
+	}
+	key = key[:length]
+	if hash.Seeded1(key, t.seed^ibltCheckSeed) != cell[2] {
+		return nil, 0, false
+	}
+	return key, count, true
+}
+
+// IBLT encoding, all integers little-endian:
+//
+//	magic "BLMI" | version u16 | flags u16 | hashCount u32 | keySize u32 |
+//	cells u64 | seed u64 | cell words [cells*cellWords]u64 | crc32 (IEEE)
+const (
+	ibltMagic         = "BLMI"
+	ibltHeaderSize    = 32
+	ibltFormatVersion = 1
+)
+
+// MarshalBinary encodes the table for sending to the other party
+func (t *IBLT) MarshalBinary() ([]byte, error) {
+	b := make([]byte, 0, ibltHeaderSize+len(t.words)*8+formatTrailer)
+	b = append(b, ibltMagic...)
+	b = binary.LittleEndian.AppendUint16(b, ibltFormatVersion)
+	b = binary.LittleEndian.AppendUint16(b, 0) // flags
+	b = binary.LittleEndian.AppendUint32(b, ibltHashCount)
+	b = binary.LittleEndian.AppendUint32(b, uint32(t.keySize))
+	b = binary.LittleEndian.AppendUint64(b, t.cells)
+	b = binary.LittleEndian.AppendUint64(b, t.seed)
+	for _, w := range t.words {
+		b = binary.LittleEndian.AppendUint64(b, w)
+	}
+	return binary.LittleEndian.AppendUint32(b, crc32.ChecksumIEEE(b)), nil
+}
+
+// UnmarshalBinary replaces the table with one encoded by MarshalBinary. The
+// receiver may be a zero IBLT and is left unchanged on error. Malformed input
+// returns an error wrapping ErrInvalidEncoding.
+func (t *IBLT) UnmarshalBinary(data []byte) error {
+	if len(data) < ibltHeaderSize+formatTrailer {
+		return fmt.Errorf("%w: missing IBLT header", ErrCorruptData)
+	}
+	if string(data[:4]) != ibltMagic {
+		return fmt.Errorf("%w: bad IBLT magic %q", ErrInvalidEncoding, data[:4])
+	}
+	if v := binary.LittleEndian.Uint16(data[4:]); v != ibltFormatVersion {
+		return fmt.Errorf("%w: unsupported IBLT version %d", ErrInvalidEncoding, v)
+	}
+	if f := binary.LittleEndian.Uint16(data[6:]); f != 0 {
+		return fmt.Errorf("%w: unknown IBLT flags %#x", ErrInvalidEncoding, f)
+	}
+	if k := binary.LittleEndian.Uint32(data[8:]); k != ibltHashCount {
+		return fmt.Errorf("%w: unsupported IBLT hash count %d", ErrInvalidEncoding, k)
+	}
+	keySize := uint64(binary.LittleEndian.Uint32(data[12:]))
+	cells := binary.LittleEndian.Uint64(data[16:])
+	seed := binary.LittleEndian.Uint64(data[24:])
+	if keySize == 0 || cells == 0 || cells%ibltHashCount != 0 {
+		return fmt.Errorf("%w: invalid IBLT geometry (%d cells, %d-byte keys)", ErrInvalidEncoding, cells, keySize)
+	}
+
+	cellWords := ibltCellHeader + (keySize+7)/8
+	body := uint64(len(data) - ibltHeaderSize - formatTrailer)
+	if cells > body/8/cellWords || body != cells*cellWords*8 {
+		return fmt.Errorf("%w: %d bytes of cells for %d cells", ErrCorruptData, body, cells)
+	}
+	sum := binary.LittleEndian.Uint32(data[len(data)-formatTrailer:])
+	if crc32.ChecksumIEEE(data[:len(data)-formatTrailer]) != sum {
+		return fmt.Errorf("%w: IBLT checksum mismatch", ErrCorruptData)
+	}
+
+	decoded := NewIBLTWithCells(cells, int(keySize), seed)
+	for i := range decoded.words {
+		decoded.words[i] = binary.LittleEndian.Uint64(data[ibltHeaderSize+i*8:])
+	}
+	*t = *decoded
+	return nil
+}
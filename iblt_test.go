@@ -0,0 +1,167 @@
+package bloomfilter
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+)
+
+// ibltKeys returns the keys of a test set as strings for comparison
+func ibltKeys(keys [][]byte) []string {
+	s := make([]string, len(keys))
+	for i, k := range keys {
+		s[i] = string(k)
+	}
+	slices.Sort(s)
+	return s
+}
+
+// TestIBLTSetDifference tests the full protocol between two parties
+func TestIBLTSetDifference(t *testing.T) {
+	for _, diff := range []int{0, 1, 10, 200} {
+		t.Run(fmt.Sprintf("Diff_%d", diff), func(t *testing.T) {
+			alice, bob := NewMemoryKeyLog(), NewMemoryKeyLog()
+			var wantAlice, wantBob []string
+			for i := 0; i < 10000; i++ {
+				key := []byte(fmt.Sprintf("shared_%d", i))
+				alice.Record(key)
+				bob.Record(key)
+			}
+			for i := 0; i < diff; i++ {
+				a := fmt.Sprintf("alice_%d", i)
+				b := fmt.Sprintf("bob-%d-longer", i)
+				alice.Record([]byte(a))
+				bob.Record([]byte(b))
+				wantAlice = append(wantAlice, a)
+				wantBob = append(wantBob, b)
+			}
+			slices.Sort(wantAlice)
+			slices.Sort(wantBob)
+
+			ta := NewIBLT(uint64(max(2*diff, 1)), 32)
+			tb := NewIBLT(uint64(max(2*diff, 1)), 32)
+			if err := ta.Encode(alice); err != nil {
+				t.Fatal(err)
+			}
+			if err := tb.Encode(bob); err != nil {
+				t.Fatal(err)
+			}
+
+			// Bob sends his table over the network
+			data, err := tb.MarshalBinary()
+			if err != nil {
+				t.Fatal(err)
+			}
+			var received IBLT
+			if err := received.UnmarshalBinary(data); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := ta.Subtract(&received); err != nil {
+				t.Fatal(err)
+			}
+			onlyAlice, onlyBob, err := ta.Decode()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(ibltKeys(onlyAlice), wantAlice) || !slices.Equal(ibltKeys(onlyBob), wantBob) {
+				t.Fatalf("Decoded %d/%d keys, want %d/%d", len(onlyAlice), len(onlyBob), len(wantAlice), len(wantBob))
+			}
+		})
+	}
+}
+
+// TestIBLTKeyLengths tests keys that differ only in trailing zero bytes
+func TestIBLTKeyLengths(t *testing.T) {
+	tbl := NewIBLT(10, 16)
+	keys := [][]byte{{}, {0}, {0, 0}, []byte("a"), []byte("a\x00"), []byte("0123456789abcdef")}
+	for _, k := range keys {
+		if err := tbl.Insert(k); err != nil {
+			t.Fatal(err)
+		}
+	}
+	here, there, err := tbl.Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(there) != 0 || !slices.Equal(ibltKeys(here), ibltKeys(keys)) {
+		t.Fatalf("Decoded %q, want %q", here, keys)
+	}
+
+	if err := tbl.Insert(make([]byte, 17)); err == nil {
+		t.Error("Expected an error for a key longer than KeySize")
+	}
+	if err := tbl.Delete([]byte("a")); err != nil {
+		t.Fatal(err)
+	}
+	if here, _, _ := tbl.Decode(); len(here) != len(keys)-1 {
+		t.Errorf("Expected %d keys after Delete, got %d", len(keys)-1, len(here))
+	}
+}
+
+// TestIBLTUndecodable tests that an overloaded table reports partial results
+func TestIBLTUndecodable(t *testing.T) {
+	tbl := NewIBLT(10, 8)
+	for i := 0; i < 1000; i++ {
+		tbl.Insert([]byte(fmt.Sprint(i)))
+	}
+	here, _, err := tbl.Decode()
+	if !errors.Is(err, ErrIBLTUndecodable) {
+		t.Fatalf("Expected ErrIBLTUndecodable, got %v", err)
+	}
+	if len(here) >= 1000 {
+		t.Errorf("Recovered %d keys from an overloaded table", len(here))
+	}
+
+	// Decode leaves the table unchanged
+	again, _, _ := tbl.Decode()
+	if len(again) != len(here) {
+		t.Error("Decode modified the table")
+	}
+}
+
+// TestIBLTCompatibility tests that mismatched tables are rejected
+func TestIBLTCompatibility(t *testing.T) {
+	base := NewIBLTWithCells(30, 8, 1)
+	if err := base.Subtract(NewIBLTWithCells(60, 8, 1)); !errors.Is(err, ErrIncompatible) {
+		t.Errorf("Cell count mismatch: expected ErrIncompatible, got %v", err)
+	}
+	if err := base.Subtract(NewIBLTWithCells(30, 16, 1)); !errors.Is(err, ErrIncompatible) {
+		t.Errorf("Key size mismatch: expected ErrIncompatible, got %v", err)
+	}
+	if err := base.Subtract(NewIBLTWithCells(30, 8, 2)); !errors.Is(err, ErrSeedMismatch) {
+		t.Errorf("Seed mismatch: expected ErrSeedMismatch, got %v", err)
+	}
+	if NewIBLTWithCells(31, 8, 0).Cells() != 33 {
+		t.Error("Cell count must round up to a multiple of the hash count")
+	}
+}
+
+// TestIBLTRejectsCorruption tests UnmarshalBinary validation
+func TestIBLTRejectsCorruption(t *testing.T) {
+	tbl := NewIBLT(10, 8)
+	tbl.Insert([]byte("key"))
+	data, _ := tbl.MarshalBinary()
+
+	corrupt := func(f func([]byte) []byte) []byte {
+		return f(append([]byte(nil), data...))
+	}
+	cases := map[string][]byte{
+		"short":     data[:10],
+		"magic":     corrupt(func(b []byte) []byte { b[0] = 'X'; return b }),
+		"version":   corrupt(func(b []byte) []byte { b[4] = 9; return b }),
+		"truncated": data[:len(data)-9],
+		"checksum":  corrupt(func(b []byte) []byte { b[40] ^= 1; return b }),
+		"cells":     corrupt(func(b []byte) []byte { b[16] = 0; b[17] = 0xff; return b }),
+	}
+	for name, b := range cases {
+		orig := *tbl
+		if err := tbl.UnmarshalBinary(b); !errors.Is(err, ErrInvalidEncoding) {
+			t.Errorf("%s: expected ErrInvalidEncoding, got %v", name, err)
+		}
+		if tbl.cells != orig.cells {
+			t.Errorf("%s: receiver modified on error", name)
+		}
+	}
+}
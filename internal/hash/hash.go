@@ -1,16 +1,25 @@
 package hash
 
-import "unsafe"
+import (
+	"math/bits"
+	"unsafe"
+)
 
 // Optimized1 implements FNV-1a hash with optimized chunking for cache efficiency.
 // Processes data in 32-byte chunks (AVX2-friendly) for better performance.
 func Optimized1(data []byte) uint64 {
+	return Seeded1(data, 0)
+}
+
+// Seeded1 is Optimized1 with the seed mixed into the initial state.
+// Seeded1(data, 0) == Optimized1(data).
+func Seeded1(data []byte, seed uint64) uint64 {
 	const (
 		fnvOffsetBasis = 14695981039346656037
 		fnvPrime       = 1099511628211
 	)
 
-	hash := uint64(fnvOffsetBasis)
+	hash := uint64(fnvOffsetBasis) ^ seed
 	i := 0
 
 	// Process 32-byte chunks when possible (AVX2 friendly)
@@ -54,13 +63,19 @@ func Optimized1(data []byte) uint64 {
 // Optimized2 implements a variant hash function with different constants.
 // Using two independent hash functions provides better distribution.
 func Optimized2(data []byte) uint64 {
+	return Seeded2(data, 0)
+}
+
+// Seeded2 is Optimized2 with the seed mixed into the initial state.
+// Seeded2(data, 0) == Optimized2(data).
+func Seeded2(data []byte, seed uint64) uint64 {
 	const (
-		seed = 0x9e3779b97f4a7c15
-		mult = 0xc6a4a7935bd1e995
-		r    = 47
+		initial = 0x9e3779b97f4a7c15
+		mult    = 0xc6a4a7935bd1e995
+		r       = 47
 	)
 
-	hash := uint64(seed)
+	hash := uint64(initial) ^ seed
 	i := 0
 
 	// Process 32-byte chunks when possible (AVX2 friendly)
@@ -106,3 +121,172 @@ func Optimized2(data []byte) uint64 {
 
 	return hash
 }
+
+// Optimized16 returns Optimized1 and Optimized2 of a 16-byte key given as its
+// two 8-byte words in memory order. It is small enough to inline, so fixed-width
+// keys skip the generic loops entirely.
+func Optimized16(lo, hi uint64) (uint64, uint64) {
+	return Seeded16(lo, hi, 0)
+}
+
+// Seeded16 returns Seeded1 and Seeded2 of a 16-byte key given as its two words
+func Seeded16(lo, hi, seed uint64) (uint64, uint64) {
+	const (
+		fnvOffsetBasis = 14695981039346656037
+		fnvPrime       = 1099511628211
+		initial        = 0x9e3779b97f4a7c15
+		mult           = 0xc6a4a7935bd1e995
+		r              = 47
+	)
+
+	h1 := (uint64(fnvOffsetBasis) ^ seed ^ lo) * fnvPrime
+	h1 = (h1 ^ hi) * fnvPrime
+
+	h2 := (uint64(initial) ^ seed ^ lo) * mult
+	h2 ^= h2 >> r
+	h2 = (h2 ^ hi) * mult
+	h2 ^= h2 >> r
+
+	return h1, h2
+}
+
+// SeededUint64 returns Seeded1 and Seeded2 of an 8-byte key given as its word
+// in memory order
+func SeededUint64(w, seed uint64) (uint64, uint64) {
+	const (
+		fnvOffsetBasis = 14695981039346656037
+		fnvPrime       = 1099511628211
+		initial        = 0x9e3779b97f4a7c15
+		mult           = 0xc6a4a7935bd1e995
+		r              = 47
+	)
+
+	h1 := (uint64(fnvOffsetBasis) ^ seed ^ w) * fnvPrime
+	h2 := (uint64(initial) ^ seed ^ w) * mult
+	h2 ^= h2 >> r
+	return h1, h2
+}
+
+// SeededUint64Batch stores SeededUint64 of every key in h1 and h2, which must be
+// at least len(keys) long. Keys are processed four at a time so the independent
+// multiplies of different keys overlap in the pipeline.
+func SeededUint64Batch(keys []uint64, seed uint64, h1, h2 []uint64) {
+	h1 = h1[:len(keys)]
+	h2 = h2[:len(keys)]
+	i := 0
+	for ; i+4 <= len(keys); i += 4 {
+		h1[i], h2[i] = SeededUint64(keys[i], seed)
+		h1[i+1], h2[i+1] = SeededUint64(keys[i+1], seed)
+		h1[i+2], h2[i+2] = SeededUint64(keys[i+2], seed)
+		h1[i+3], h2[i+3] = SeededUint64(keys[i+3], seed)
+	}
+	for ; i < len(keys); i++ {
+		h1[i], h2[i] = SeededUint64(keys[i], seed)
+	}
+}
+
+// Seeded128 returns both hashes of data from a single pass, MurmurHash3
+// x64_128 with the 64-bit seed as the initial state of both halves. Words are
+// loaded in memory order like Seeded1, so on little-endian machines, and for
+// seeds below 2^32, it matches the reference MurmurHash3_x64_128.
+func Seeded128(data []byte, seed uint64) (uint64, uint64) {
+	h1, h2 := seed, seed
+	i := 0
+	for ; i+16 <= len(data); i += 16 {
+		k1 := *(*uint64)(unsafe.Pointer(&data[i]))
+		k2 := *(*uint64)(unsafe.Pointer(&data[i+8]))
+		h1, h2 = murmurBlock(h1, h2, k1, k2)
+	}
+
+	var k1, k2 uint64
+	tail := data[i:]
+	if len(tail) >= 8 {
+		k1 = *(*uint64)(unsafe.Pointer(&tail[0]))
+		for j := len(tail) - 1; j >= 8; j-- {
+			k2 = k2<<8 | uint64(tail[j])
+		}
+	} else {
+		for j := len(tail) - 1; j >= 0; j-- {
+			k1 = k1<<8 | uint64(tail[j])
+		}
+	}
+	h1, h2 = murmurTail(h1, h2, k1, k2)
+	return murmurFinish(h1, h2, uint64(len(data)))
+}
+
+// Seeded128Uint64 returns Seeded128 of an 8-byte key given as its word in
+// memory order
+func Seeded128Uint64(w, seed uint64) (uint64, uint64) {
+	h1, h2 := murmurTail(seed, seed, w, 0)
+	return murmurFinish(h1, h2, 8)
+}
+
+// Seeded128Uint64Batch stores Seeded128Uint64 of every key in h1 and h2, which
+// must be at least len(keys) long, four keys at a time like SeededUint64Batch
+func Seeded128Uint64Batch(keys []uint64, seed uint64, h1, h2 []uint64) {
+	h1 = h1[:len(keys)]
+	h2 = h2[:len(keys)]
+	i := 0
+	for ; i+4 <= len(keys); i += 4 {
+		h1[i], h2[i] = Seeded128Uint64(keys[i], seed)
+		h1[i+1], h2[i+1] = Seeded128Uint64(keys[i+1], seed)
+		h1[i+2], h2[i+2] = Seeded128Uint64(keys[i+2], seed)
+		h1[i+3], h2[i+3] = Seeded128Uint64(keys[i+3], seed)
+	}
+	for ; i < len(keys); i++ {
+		h1[i], h2[i] = Seeded128Uint64(keys[i], seed)
+	}
+}
+
+// Seeded128Words returns Seeded128 of a 16-byte key given as its two words
+func Seeded128Words(lo, hi, seed uint64) (uint64, uint64) {
+	h1, h2 := murmurBlock(seed, seed, lo, hi)
+	return murmurFinish(h1, h2, 16)
+}
+
+const (
+	murmurC1 = 0x87c37b91114253d5
+	murmurC2 = 0x4cf5ad432745937f
+)
+
+// murmurBlock mixes one 16-byte block into the state
+func murmurBlock(h1, h2, k1, k2 uint64) (uint64, uint64) {
+	h1 ^= bits.RotateLeft64(k1*murmurC1, 31) * murmurC2
+	h1 = bits.RotateLeft64(h1, 27) + h2
+	h1 = h1*5 + 0x52dce729
+
+	h2 ^= bits.RotateLeft64(k2*murmurC2, 33) * murmurC1
+	h2 = bits.RotateLeft64(h2, 31) + h1
+	h2 = h2*5 + 0x38495ab5
+	return h1, h2
+}
+
+// murmurTail mixes the zero-padded final partial block into the state
+func murmurTail(h1, h2, k1, k2 uint64) (uint64, uint64) {
+	h2 ^= bits.RotateLeft64(k2*murmurC2, 33) * murmurC1
+	h1 ^= bits.RotateLeft64(k1*murmurC1, 31) * murmurC2
+	return h1, h2
+}
+
+// murmurFinish folds in the length and avalanches both halves
+func murmurFinish(h1, h2, length uint64) (uint64, uint64) {
+	h1 ^= length
+	h2 ^= length
+	h1 += h2
+	h2 += h1
+	h1 = fmix64(h1)
+	h2 = fmix64(h2)
+	h1 += h2
+	h2 += h1
+	return h1, h2
+}
+
+// fmix64 is the MurmurHash3 finalizer
+func fmix64(k uint64) uint64 {
+	k ^= k >> 33
+	k *= 0xff51afd7ed558ccd
+	k ^= k >> 33
+	k *= 0xc4ceb9fe1a85ec53
+	k ^= k >> 33
+	return k
+}
@@ -2,6 +2,7 @@ package hash
 
 import (
 	"testing"
+	"unsafe"
 )
 
 // TestOptimized1BasicFunctionality tests basic hash function properties
@@ -476,3 +477,132 @@ func TestHashNonZeroForKnownInputs(t *testing.T) {
 		}
 	}
 }
+
+// TestOptimized16MatchesGeneric verifies the fixed-width path equals the generic hashes
+func TestOptimized16MatchesGeneric(t *testing.T) {
+	var key [16]byte
+	for n := 0; n < 1000; n++ {
+		for i := range key {
+			key[i] = byte(n*31 + i*7 + n>>3)
+		}
+		lo := *(*uint64)(unsafe.Pointer(&key[0]))
+		hi := *(*uint64)(unsafe.Pointer(&key[8]))
+
+		h1, h2 := Optimized16(lo, hi)
+		if h1 != Optimized1(key[:]) || h2 != Optimized2(key[:]) {
+			t.Fatalf("Key %x: Optimized16 = (%x, %x), generic = (%x, %x)",
+				key, h1, h2, Optimized1(key[:]), Optimized2(key[:]))
+		}
+	}
+}
+
+// TestSeededHashes verifies seed 0 matches the unseeded hashes and other seeds differ
+func TestSeededHashes(t *testing.T) {
+	inputs := [][]byte{{}, []byte("a"), []byte("hello world"), make([]byte, 40), []byte("0123456789abcdef")}
+	for _, in := range inputs {
+		if Seeded1(in, 0) != Optimized1(in) || Seeded2(in, 0) != Optimized2(in) {
+			t.Errorf("Seed 0 must match the unseeded hashes for %q", in)
+		}
+		if Seeded1(in, 42) == Optimized1(in) || Seeded2(in, 42) == Optimized2(in) {
+			t.Errorf("Seed 42 produced the unseeded hash for %q", in)
+		}
+	}
+
+	var key [16]byte
+	copy(key[:], "0123456789abcdef")
+	lo := *(*uint64)(unsafe.Pointer(&key[0]))
+	hi := *(*uint64)(unsafe.Pointer(&key[8]))
+	for _, seed := range []uint64{0, 1, 0xdeadbeef} {
+		h1, h2 := Seeded16(lo, hi, seed)
+		if h1 != Seeded1(key[:], seed) || h2 != Seeded2(key[:], seed) {
+			t.Errorf("Seeded16 differs from the generic path for seed %x", seed)
+		}
+	}
+}
+
+// TestSeededUint64MatchesGeneric verifies the uint64 kernels equal the generic hashes
+func TestSeededUint64MatchesGeneric(t *testing.T) {
+	keys := make([]uint64, 1003)
+	for i := range keys {
+		keys[i] = uint64(i)*0x9e3779b97f4a7c15 ^ uint64(i)>>3
+	}
+	for _, seed := range []uint64{0, 1, 0xdeadbeef} {
+		h1s := make([]uint64, len(keys))
+		h2s := make([]uint64, len(keys))
+		SeededUint64Batch(keys, seed, h1s, h2s)
+		for i, w := range keys {
+			data := (*[8]byte)(unsafe.Pointer(&w))[:]
+			h1, h2 := SeededUint64(w, seed)
+			if h1 != Seeded1(data, seed) || h2 != Seeded2(data, seed) {
+				t.Fatalf("SeededUint64(%x, %x) differs from the generic path", w, seed)
+			}
+			if h1s[i] != h1 || h2s[i] != h2 {
+				t.Fatalf("SeededUint64Batch differs at key %d for seed %x", i, seed)
+			}
+		}
+	}
+}
+
+// TestSeeded128Uint64Batch verifies the batch kernel equals Seeded128 of the
+// key's bytes
+func TestSeeded128Uint64Batch(t *testing.T) {
+	keys := make([]uint64, 1003)
+	for i := range keys {
+		keys[i] = uint64(i)*0x9e3779b97f4a7c15 ^ uint64(i)>>3
+	}
+	for _, seed := range []uint64{0, 1, 0xdeadbeef} {
+		h1s := make([]uint64, len(keys))
+		h2s := make([]uint64, len(keys))
+		Seeded128Uint64Batch(keys, seed, h1s, h2s)
+		for i, w := range keys {
+			h1, h2 := Seeded128((*[8]byte)(unsafe.Pointer(&w))[:], seed)
+			if h1s[i] != h1 || h2s[i] != h2 {
+				t.Fatalf("Seeded128Uint64Batch differs at key %d for seed %x", i, seed)
+			}
+		}
+	}
+}
+
+// TestSeeded128 checks the single-pass hash against MurmurHash3_x64_128
+// reference values and its fixed-width forms against the generic path
+func TestSeeded128(t *testing.T) {
+	for _, tc := range []struct {
+		data   string
+		seed   uint64
+		h1, h2 uint64
+	}{
+		{"", 0, 0, 0},
+		{"hello", 0, 0xcbd8a7b341bd9b02, 0x5b1e906a48ae1d19},
+		{"The quick brown fox jumps over the lazy dog", 0, 0xe34bbc7bbc071b6c, 0x7a433ca9c49a9347},
+	} {
+		h1, h2 := Seeded128([]byte(tc.data), tc.seed)
+		if h1 != tc.h1 || h2 != tc.h2 {
+			t.Errorf("Seeded128(%q, %d) = %#x, %#x, want %#x, %#x", tc.data, tc.seed, h1, h2, tc.h1, tc.h2)
+		}
+	}
+
+	for _, seed := range []uint64{0, 1, 0xdeadbeefcafe} {
+		for i := uint64(0); i < 1000; i++ {
+			w, v := i*0x9e3779b97f4a7c15, i^0xabcdef
+			key := [2]uint64{w, v}
+			data := (*[16]byte)(unsafe.Pointer(&key))[:]
+			h1, h2 := Seeded128Uint64(w, seed)
+			if g1, g2 := Seeded128(data[:8], seed); h1 != g1 || h2 != g2 {
+				t.Fatalf("Seeded128Uint64(%x, %x) differs from the generic path", w, seed)
+			}
+			h1, h2 = Seeded128Words(w, v, seed)
+			if g1, g2 := Seeded128(data, seed); h1 != g1 || h2 != g2 {
+				t.Fatalf("Seeded128Words(%x, %x, %x) differs from the generic path", w, v, seed)
+			}
+		}
+	}
+
+	seen := make(map[[2]uint64]int)
+	for n := 0; n <= 64; n++ {
+		h1, h2 := Seeded128(make([]byte, n), 0)
+		if prev, ok := seen[[2]uint64{h1, h2}]; ok {
+			t.Errorf("%d and %d zero bytes hash alike", prev, n)
+		}
+		seen[[2]uint64{h1, h2}] = n
+	}
+}
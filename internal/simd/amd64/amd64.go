@@ -1,3 +1,5 @@
+//go:build amd64 && !purego
+
 package amd64
 
 import "unsafe"
@@ -17,12 +19,106 @@ func VectorAnd(dst, src unsafe.Pointer, length int) {
 	avx2VectorAnd(dst, src, length)
 }
 
+// VectorXor performs SIMD XOR operation using AVX2
+func VectorXor(dst, src unsafe.Pointer, length int) {
+	avx2VectorXor(dst, src, length)
+}
+
+// VectorAndNot clears the bits of dst that are set in src using AVX2
+func VectorAndNot(dst, src unsafe.Pointer, length int) {
+	avx2VectorAndNot(dst, src, length)
+}
+
 // VectorClear performs SIMD clear operation using AVX2
 func VectorClear(data unsafe.Pointer, length int) {
 	avx2VectorClear(data, length)
 }
 
+// VectorCopy copies length bytes from src to dst using AVX2
+func VectorCopy(dst, src unsafe.Pointer, length int) {
+	avx2VectorCopy(dst, src, length)
+}
+
+// VectorEqual compares two buffers using AVX2
+func VectorEqual(a, b unsafe.Pointer, length int) bool {
+	return avx2VectorEqual(a, b, length)
+}
+
+// PopCountAnd counts the bits set in both buffers using AVX2
+func PopCountAnd(a, b unsafe.Pointer, length int) int {
+	return avx2PopCountAnd(a, b, length)
+}
+
+// PopCountOr counts the bits set in either buffer using AVX2
+func PopCountOr(a, b unsafe.Pointer, length int) int {
+	return avx2PopCountOr(a, b, length)
+}
+
+// HashUint64 stores the seeded hashes of n 8-byte keys in h1 and h2 using AVX2.
+// n must be a multiple of 8.
+func HashUint64(keys, h1, h2 unsafe.Pointer, n int, seed uint64) {
+	avx2HashUint64(keys, h1, h2, n, seed)
+}
+
+// Hash128Uint64 stores the one-pass 128-bit hashes of n 8-byte keys in h1 and
+// h2 using AVX2. n must be a multiple of 4.
+func Hash128Uint64(keys, h1, h2 unsafe.Pointer, n int, seed uint64) {
+	avx2Hash128Uint64(keys, h1, h2, n, seed)
+}
+
+// ProbeWords replaces each of n masks with its bits that are clear in
+// words[idx[i]], gathering the words with AVX2. n must be a multiple of 8.
+func ProbeWords(words, idx, masks unsafe.Pointer, n int) {
+	avx2ProbeWords(words, idx, masks, n)
+}
+
 // HasAVX2 returns true if AVX2 is supported
 func HasAVX2() bool {
 	return hasAVX2Support()
 }
+
+// AVX512PopCount performs SIMD population count using AVX-512 VPOPCNTQ
+func AVX512PopCount(data unsafe.Pointer, length int) int {
+	return avx512PopCount(data, length)
+}
+
+// AVX512VectorOr performs SIMD OR operation using AVX-512
+func AVX512VectorOr(dst, src unsafe.Pointer, length int) {
+	avx512VectorOr(dst, src, length)
+}
+
+// AVX512VectorAnd performs SIMD AND operation using AVX-512
+func AVX512VectorAnd(dst, src unsafe.Pointer, length int) {
+	avx512VectorAnd(dst, src, length)
+}
+
+// AVX512VectorXor performs SIMD XOR operation using AVX-512
+func AVX512VectorXor(dst, src unsafe.Pointer, length int) {
+	avx512VectorXor(dst, src, length)
+}
+
+// AVX512VectorAndNot clears the bits of dst that are set in src using AVX-512
+func AVX512VectorAndNot(dst, src unsafe.Pointer, length int) {
+	avx512VectorAndNot(dst, src, length)
+}
+
+// AVX512VectorClear performs SIMD clear operation using AVX-512
+func AVX512VectorClear(data unsafe.Pointer, length int) {
+	avx512VectorClear(data, length)
+}
+
+// AVX512PopCountAnd counts the bits set in both buffers using AVX-512 VPOPCNTQ
+func AVX512PopCountAnd(a, b unsafe.Pointer, length int) int {
+	return avx512PopCountAnd(a, b, length)
+}
+
+// AVX512PopCountOr counts the bits set in either buffer using AVX-512 VPOPCNTQ
+func AVX512PopCountOr(a, b unsafe.Pointer, length int) int {
+	return avx512PopCountOr(a, b, length)
+}
+
+// HasAVX512 returns true if AVX-512 Foundation and VPOPCNTDQ are supported and
+// enabled by the OS
+func HasAVX512() bool {
+	return hasAVX512Support()
+}
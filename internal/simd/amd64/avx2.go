@@ -21,3 +21,30 @@ func avx2VectorClear(data unsafe.Pointer, length int)
 
 //go:noescape
 func hasAVX2Support() bool
+
+//go:noescape
+func avx2HashUint64(keys, h1, h2 unsafe.Pointer, n int, seed uint64)
+
+//go:noescape
+func avx2Hash128Uint64(keys, h1, h2 unsafe.Pointer, n int, seed uint64)
+
+//go:noescape
+func avx2VectorEqual(a, b unsafe.Pointer, length int) bool
+
+//go:noescape
+func avx2ProbeWords(words, idx, masks unsafe.Pointer, n int)
+
+//go:noescape
+func avx2VectorCopy(dst, src unsafe.Pointer, length int)
+
+//go:noescape
+func avx2PopCountAnd(a, b unsafe.Pointer, length int) int
+
+//go:noescape
+func avx2VectorXor(dst, src unsafe.Pointer, length int)
+
+//go:noescape
+func avx2VectorAndNot(dst, src unsafe.Pointer, length int)
+
+//go:noescape
+func avx2PopCountOr(a, b unsafe.Pointer, length int) int
@@ -231,3 +231,532 @@ scalar_clear_loop:
 clear_done:
     VZEROUPPER
     RET
+
+// MULQ4 sets dst to the low 64 bits of a*b in each lane using VPMULUDQ, which
+// multiplies only the low 32 bits of each lane: a*b = lo*lo + (hi*lo + lo*hi)<<32.
+// blo holds b and bhi holds b>>32; t1 and t2 are clobbered.
+#define MULQ4(a, blo, bhi, dst, t1, t2) \
+    VPMULUDQ blo, a, dst; \
+    VPSRLQ $32, a, t1; \
+    VPMULUDQ blo, t1, t1; \
+    VPMULUDQ bhi, a, t2; \
+    VPADDQ t1, t2, t1; \
+    VPSLLQ $32, t1, t1; \
+    VPADDQ t1, dst, dst
+
+// avx2HashUint64 computes the seeded FNV-1a and multiply-xorshift hashes of
+// 8-byte keys, 8 keys per iteration in two YMM registers. n must be a multiple of 8.
+// func avx2HashUint64(keys, h1, h2 unsafe.Pointer, n int, seed uint64)
+TEXT ·avx2HashUint64(SB), NOSPLIT, $0-40
+    MOVQ keys+0(FP), SI
+    MOVQ h1+8(FP), DI
+    MOVQ h2+16(FP), R8
+    MOVQ n+24(FP), CX
+    MOVQ seed+32(FP), R9
+    XORQ DX, DX
+
+    // Y10 = fnvOffsetBasis ^ seed, Y11 = initial ^ seed
+    MOVQ $0xcbf29ce484222325, AX
+    XORQ R9, AX
+    VMOVQ AX, X10
+    VPBROADCASTQ X10, Y10
+    MOVQ $0x9e3779b97f4a7c15, AX
+    XORQ R9, AX
+    VMOVQ AX, X11
+    VPBROADCASTQ X11, Y11
+
+    // Y12/Y13 = fnvPrime and its high half, Y14/Y15 = mult and its high half
+    MOVQ $0x100000001b3, AX
+    VMOVQ AX, X12
+    VPBROADCASTQ X12, Y12
+    SHRQ $32, AX
+    VMOVQ AX, X13
+    VPBROADCASTQ X13, Y13
+    MOVQ $0xc6a4a7935bd1e995, AX
+    VMOVQ AX, X14
+    VPBROADCASTQ X14, Y14
+    SHRQ $32, AX
+    VMOVQ AX, X15
+    VPBROADCASTQ X15, Y15
+
+    CMPQ CX, $0
+    JLE hash_done
+
+hash_loop:
+    VMOVDQU (SI)(DX*8), Y0
+    VMOVDQU 32(SI)(DX*8), Y5
+
+    // h1 = (fnvOffsetBasis ^ seed ^ w) * fnvPrime
+    VPXOR Y10, Y0, Y1
+    VPXOR Y10, Y5, Y6
+    MULQ4(Y1, Y12, Y13, Y2, Y3, Y4)
+    MULQ4(Y6, Y12, Y13, Y7, Y8, Y9)
+    VMOVDQU Y2, (DI)(DX*8)
+    VMOVDQU Y7, 32(DI)(DX*8)
+
+    // h2 = (initial ^ seed ^ w) * mult; h2 ^= h2 >> 47
+    VPXOR Y11, Y0, Y1
+    VPXOR Y11, Y5, Y6
+    MULQ4(Y1, Y14, Y15, Y2, Y3, Y4)
+    MULQ4(Y6, Y14, Y15, Y7, Y8, Y9)
+    VPSRLQ $47, Y2, Y3
+    VPSRLQ $47, Y7, Y8
+    VPXOR Y3, Y2, Y2
+    VPXOR Y8, Y7, Y7
+    VMOVDQU Y2, (R8)(DX*8)
+    VMOVDQU Y7, 32(R8)(DX*8)
+
+    ADDQ $8, DX
+    CMPQ DX, CX
+    JL hash_loop
+
+hash_done:
+    VZEROUPPER
+    RET
+
+// avx2Hash128Uint64 computes the one-pass MurmurHash3 x64_128 of 8-byte keys,
+// 4 keys per iteration. With the second key word zero, h2 enters the finalizer
+// as seed^8, so h1 = ((seed^8) ^ k1) + (seed^8) and h2 = h1 + (seed^8). n must
+// be a multiple of 4.
+// func avx2Hash128Uint64(keys, h1, h2 unsafe.Pointer, n int, seed uint64)
+TEXT ·avx2Hash128Uint64(SB), NOSPLIT, $0-40
+    MOVQ keys+0(FP), SI
+    MOVQ h1+8(FP), DI
+    MOVQ h2+16(FP), R8
+    MOVQ n+24(FP), CX
+    MOVQ seed+32(FP), R9
+    XORQ DX, DX
+
+    // Y7/Y8 = c1 and its high half, Y9/Y10 = c2, Y11/Y12 and Y13/Y14 = the
+    // finalizer multipliers, Y15 = seed ^ 8
+    MOVQ $0x87c37b91114253d5, AX
+    VMOVQ AX, X7
+    VPBROADCASTQ X7, Y7
+    SHRQ $32, AX
+    VMOVQ AX, X8
+    VPBROADCASTQ X8, Y8
+    MOVQ $0x4cf5ad432745937f, AX
+    VMOVQ AX, X9
+    VPBROADCASTQ X9, Y9
+    SHRQ $32, AX
+    VMOVQ AX, X10
+    VPBROADCASTQ X10, Y10
+    MOVQ $0xff51afd7ed558ccd, AX
+    VMOVQ AX, X11
+    VPBROADCASTQ X11, Y11
+    SHRQ $32, AX
+    VMOVQ AX, X12
+    VPBROADCASTQ X12, Y12
+    MOVQ $0xc4ceb9fe1a85ec53, AX
+    VMOVQ AX, X13
+    VPBROADCASTQ X13, Y13
+    SHRQ $32, AX
+    VMOVQ AX, X14
+    VPBROADCASTQ X14, Y14
+    MOVQ R9, AX
+    XORQ $8, AX
+    VMOVQ AX, X15
+    VPBROADCASTQ X15, Y15
+
+    CMPQ CX, $0
+    JLE hash128_done
+
+hash128_loop:
+    VMOVDQU (SI)(DX*8), Y0
+
+    // k1 = rotl(w*c1, 31) * c2
+    MULQ4(Y0, Y7, Y8, Y1, Y2, Y3)
+    VPSLLQ $31, Y1, Y2
+    VPSRLQ $33, Y1, Y1
+    VPOR Y2, Y1, Y1
+    MULQ4(Y1, Y9, Y10, Y0, Y2, Y3)
+
+    // h1 = ((seed^8) ^ k1) + (seed^8); h2 = h1 + (seed^8)
+    VPXOR Y15, Y0, Y0
+    VPADDQ Y15, Y0, Y0
+    VPADDQ Y15, Y0, Y1
+
+    // fmix64 of both halves
+    VPSRLQ $33, Y0, Y2
+    VPXOR Y2, Y0, Y0
+    VPSRLQ $33, Y1, Y2
+    VPXOR Y2, Y1, Y1
+    MULQ4(Y0, Y11, Y12, Y2, Y3, Y4)
+    MULQ4(Y1, Y11, Y12, Y5, Y3, Y4)
+    VPSRLQ $33, Y2, Y3
+    VPXOR Y3, Y2, Y2
+    VPSRLQ $33, Y5, Y3
+    VPXOR Y3, Y5, Y5
+    MULQ4(Y2, Y13, Y14, Y0, Y3, Y4)
+    MULQ4(Y5, Y13, Y14, Y1, Y3, Y4)
+    VPSRLQ $33, Y0, Y3
+    VPXOR Y3, Y0, Y0
+    VPSRLQ $33, Y1, Y3
+    VPXOR Y3, Y1, Y1
+
+    // h1 += h2; h2 += h1
+    VPADDQ Y1, Y0, Y0
+    VPADDQ Y0, Y1, Y1
+    VMOVDQU Y0, (DI)(DX*8)
+    VMOVDQU Y1, (R8)(DX*8)
+
+    ADDQ $4, DX
+    CMPQ DX, CX
+    JL hash128_loop
+
+hash128_done:
+    VZEROUPPER
+    RET
+
+// avx2VectorEqual reports whether two buffers hold the same bytes using AVX2,
+// stopping at the first differing 32-byte chunk
+// func avx2VectorEqual(a, b unsafe.Pointer, length int) bool
+TEXT ·avx2VectorEqual(SB), NOSPLIT, $0-25
+    MOVQ a+0(FP), SI         // Load a pointer
+    MOVQ b+8(FP), DI         // Load b pointer
+    MOVQ length+16(FP), CX   // Load length in bytes
+    XORQ DX, DX              // Initialize loop counter
+
+    // Calculate number of 32-byte chunks
+    MOVQ CX, R8
+    SHRQ $5, R8
+    SHLQ $5, R8              // Aligned length
+
+avx2_eq_loop:
+    CMPQ DX, R8
+    JGE scalar_eq_loop
+
+    // XOR the chunks; VPTEST sets ZF only if every bit of the XOR is zero
+    VMOVDQU (SI)(DX*1), Y0
+    VPXOR (DI)(DX*1), Y0, Y0
+    VPTEST Y0, Y0
+    JNZ not_equal
+
+    ADDQ $32, DX
+    JMP avx2_eq_loop
+
+scalar_eq_loop:
+    CMPQ DX, CX
+    JGE equal
+
+    MOVBQZX (SI)(DX*1), AX   // Load a byte
+    MOVBQZX (DI)(DX*1), R9   // Load b byte
+    CMPQ AX, R9
+    JNE not_equal
+
+    INCQ DX
+    JMP scalar_eq_loop
+
+equal:
+    VZEROUPPER
+    MOVB $1, ret+24(FP)
+    RET
+
+not_equal:
+    VZEROUPPER
+    MOVB $0, ret+24(FP)
+    RET
+
+// avx2ProbeWords gathers words[idx[i]] for 8 probes per iteration with
+// VPGATHERQQ and replaces masks[i] with the mask bits that are clear in the
+// gathered word, so a probe hit leaves zero. n must be a multiple of 8.
+// func avx2ProbeWords(words, idx, masks unsafe.Pointer, n int)
+TEXT ·avx2ProbeWords(SB), NOSPLIT, $0-32
+    MOVQ words+0(FP), SI
+    MOVQ idx+8(FP), DI
+    MOVQ masks+16(FP), R8
+    MOVQ n+24(FP), CX
+    XORQ DX, DX
+
+    CMPQ CX, $0
+    JLE probe_done
+
+probe_loop:
+    VMOVDQU (DI)(DX*8), Y2
+    VMOVDQU 32(DI)(DX*8), Y6
+
+    // The gather clears its mask operand as lanes complete, so reset it every time
+    VPCMPEQQ Y1, Y1, Y1
+    VPCMPEQQ Y5, Y5, Y5
+    VPXOR Y3, Y3, Y3
+    VPXOR Y7, Y7, Y7
+    VPGATHERQQ Y1, (SI)(Y2*8), Y3
+    VPGATHERQQ Y5, (SI)(Y6*8), Y7
+
+    // masks = masks &^ word
+    VMOVDQU (R8)(DX*8), Y4
+    VMOVDQU 32(R8)(DX*8), Y8
+    VPANDN Y4, Y3, Y4
+    VPANDN Y8, Y7, Y8
+    VMOVDQU Y4, (R8)(DX*8)
+    VMOVDQU Y8, 32(R8)(DX*8)
+
+    ADDQ $8, DX
+    CMPQ DX, CX
+    JL probe_loop
+
+probe_done:
+    VZEROUPPER
+    RET
+
+// avx2VectorCopy copies length bytes from src to dst using AVX2
+// func avx2VectorCopy(dst, src unsafe.Pointer, length int)
+TEXT ·avx2VectorCopy(SB), NOSPLIT, $0-24
+    MOVQ dst+0(FP), DI       // Load dst pointer
+    MOVQ src+8(FP), SI       // Load src pointer
+    MOVQ length+16(FP), CX   // Load length in bytes
+    XORQ DX, DX              // Initialize loop counter
+
+    // Calculate number of 64-byte chunks (one cache line per iteration)
+    MOVQ CX, R8
+    SHRQ $6, R8
+    SHLQ $6, R8              // Aligned length
+
+avx2_copy_loop:
+    CMPQ DX, R8
+    JGE scalar_copy_loop
+
+    VMOVDQU (SI)(DX*1), Y0
+    VMOVDQU 32(SI)(DX*1), Y1
+    VMOVDQU Y0, (DI)(DX*1)
+    VMOVDQU Y1, 32(DI)(DX*1)
+
+    ADDQ $64, DX
+    JMP avx2_copy_loop
+
+scalar_copy_loop:
+    CMPQ DX, CX
+    JGE copy_done
+
+    MOVB (SI)(DX*1), AX      // Load src byte
+    MOVB AX, (DI)(DX*1)      // Store to dst
+
+    INCQ DX
+    JMP scalar_copy_loop
+
+copy_done:
+    VZEROUPPER
+    RET
+
+// avx2PopCountAnd counts the bits set in both a and b without storing a & b
+// func avx2PopCountAnd(a, b unsafe.Pointer, length int) int
+TEXT ·avx2PopCountAnd(SB), NOSPLIT, $0-32
+    MOVQ a+0(FP), SI         // Load a pointer
+    MOVQ b+8(FP), DI         // Load b pointer
+    MOVQ length+16(FP), CX   // Load length in bytes
+    XORQ AX, AX              // Initialize count accumulator
+    XORQ DX, DX              // Initialize loop counter
+
+    // Calculate number of 32-byte chunks
+    MOVQ CX, R8
+    SHRQ $5, R8
+    SHLQ $5, R8              // Aligned length
+
+avx2_and_count_loop:
+    CMPQ DX, R8
+    JGE scalar_and_count_loop
+
+    // Load 32 bytes from each buffer and AND them
+    VMOVDQU (SI)(DX*1), Y0
+    VPAND (DI)(DX*1), Y0, Y0
+
+    // Count each 64-bit lane with POPCNT
+    VMOVQ X0, R9
+    POPCNTQ R9, R9
+    ADDQ R9, AX
+
+    VPEXTRQ $1, X0, R9
+    POPCNTQ R9, R9
+    ADDQ R9, AX
+
+    VEXTRACTI128 $1, Y0, X1
+    VMOVQ X1, R9
+    POPCNTQ R9, R9
+    ADDQ R9, AX
+
+    VPEXTRQ $1, X1, R9
+    POPCNTQ R9, R9
+    ADDQ R9, AX
+
+    ADDQ $32, DX
+    JMP avx2_and_count_loop
+
+scalar_and_count_loop:
+    CMPQ DX, CX
+    JGE and_count_done
+
+    MOVBQZX (SI)(DX*1), R9   // Load a byte
+    MOVBQZX (DI)(DX*1), R10  // Load b byte
+    ANDQ R10, R9
+    POPCNTQ R9, R9
+    ADDQ R9, AX
+
+    INCQ DX
+    JMP scalar_and_count_loop
+
+and_count_done:
+    VZEROUPPER
+    MOVQ AX, ret+24(FP)      // Store result
+    RET
+
+// avx2VectorXor performs SIMD XOR operation using AVX2
+// func avx2VectorXor(dst, src unsafe.Pointer, length int)
+TEXT ·avx2VectorXor(SB), NOSPLIT, $0-24
+    MOVQ dst+0(FP), DI       // Load dst pointer
+    MOVQ src+8(FP), SI       // Load src pointer
+    MOVQ length+16(FP), CX   // Load length in bytes
+    XORQ DX, DX              // Initialize loop counter
+
+    // Check if we have at least 32 bytes
+    CMPQ CX, $32
+    JL scalar_xor_loop
+
+    // Calculate number of 32-byte chunks
+    MOVQ CX, R8
+    SHRQ $5, R8
+    SHLQ $5, R8              // Aligned length
+
+avx2_xor_loop:
+    CMPQ DX, R8
+    JGE scalar_xor_loop
+
+    // Load 32 bytes from src and dst
+    VMOVDQU (SI)(DX*1), Y0   // Load src
+    VMOVDQU (DI)(DX*1), Y1   // Load dst
+
+    // Perform XOR operation
+    VPXOR Y0, Y1, Y1         // dst = dst ^ src
+
+    // Store result back to dst
+    VMOVDQU Y1, (DI)(DX*1)
+
+    ADDQ $32, DX
+    JMP avx2_xor_loop
+
+scalar_xor_loop:
+    CMPQ DX, CX
+    JGE xor_done
+
+    MOVBQZX (DI)(DX*1), AX   // Load dst byte
+    MOVBQZX (SI)(DX*1), R9   // Load src byte
+    XORQ R9, AX              // dst = dst ^ src
+    MOVB AX, (DI)(DX*1)      // Store result
+
+    INCQ DX
+    JMP scalar_xor_loop
+
+xor_done:
+    VZEROUPPER
+    RET
+
+// avx2VectorAndNot performs SIMD AND NOT operation using AVX2
+// func avx2VectorAndNot(dst, src unsafe.Pointer, length int)
+TEXT ·avx2VectorAndNot(SB), NOSPLIT, $0-24
+    MOVQ dst+0(FP), DI       // Load dst pointer
+    MOVQ src+8(FP), SI       // Load src pointer
+    MOVQ length+16(FP), CX   // Load length in bytes
+    XORQ DX, DX              // Initialize loop counter
+
+    // Check if we have at least 32 bytes
+    CMPQ CX, $32
+    JL scalar_andnot_loop
+
+    // Calculate number of 32-byte chunks
+    MOVQ CX, R8
+    SHRQ $5, R8
+    SHLQ $5, R8              // Aligned length
+
+avx2_andnot_loop:
+    CMPQ DX, R8
+    JGE scalar_andnot_loop
+
+    // Load 32 bytes from src and dst
+    VMOVDQU (SI)(DX*1), Y0   // Load src
+    VMOVDQU (DI)(DX*1), Y1   // Load dst
+
+    // Perform AND NOT operation
+    VPANDN Y1, Y0, Y1        // dst = dst &^ src (Y1 = ^Y0 & Y1)
+
+    // Store result back to dst
+    VMOVDQU Y1, (DI)(DX*1)
+
+    ADDQ $32, DX
+    JMP avx2_andnot_loop
+
+scalar_andnot_loop:
+    CMPQ DX, CX
+    JGE andnot_done
+
+    MOVBQZX (DI)(DX*1), AX   // Load dst byte
+    MOVBQZX (SI)(DX*1), R9   // Load src byte
+    NOTQ R9
+    ANDQ R9, AX              // dst = dst &^ src
+    MOVB AX, (DI)(DX*1)      // Store result
+
+    INCQ DX
+    JMP scalar_andnot_loop
+
+andnot_done:
+    VZEROUPPER
+    RET
+
+// avx2PopCountOr counts the bits set in either a or b without storing a | b
+// func avx2PopCountOr(a, b unsafe.Pointer, length int) int
+TEXT ·avx2PopCountOr(SB), NOSPLIT, $0-32
+    MOVQ a+0(FP), SI         // Load a pointer
+    MOVQ b+8(FP), DI         // Load b pointer
+    MOVQ length+16(FP), CX   // Load length in bytes
+    XORQ AX, AX              // Initialize count accumulator
+    XORQ DX, DX              // Initialize loop counter
+
+    // Calculate number of 32-byte chunks
+    MOVQ CX, R8
+    SHRQ $5, R8
+    SHLQ $5, R8              // Aligned length
+
+avx2_or_count_loop:
+    CMPQ DX, R8
+    JGE scalar_or_count_loop
+
+    // Load 32 bytes from each buffer and OR them
+    VMOVDQU (SI)(DX*1), Y0
+    VPOR (DI)(DX*1), Y0, Y0
+
+    // Count each 64-bit lane with POPCNT
+    VMOVQ X0, R9
+    POPCNTQ R9, R9
+    ADDQ R9, AX
+
+    VPEXTRQ $1, X0, R9
+    POPCNTQ R9, R9
+    ADDQ R9, AX
+
+    VEXTRACTI128 $1, Y0, X1
+    VMOVQ X1, R9
+    POPCNTQ R9, R9
+    ADDQ R9, AX
+
+    VPEXTRQ $1, X1, R9
+    POPCNTQ R9, R9
+    ADDQ R9, AX
+
+    ADDQ $32, DX
+    JMP avx2_or_count_loop
+
+scalar_or_count_loop:
+    CMPQ DX, CX
+    JGE or_count_done
+
+    MOVBQZX (SI)(DX*1), R9   // Load a byte
+    MOVBQZX (DI)(DX*1), R10  // Load b byte
+    ORQ R10, R9
+    POPCNTQ R9, R9
+    ADDQ R9, AX
+
+    INCQ DX
+    JMP scalar_or_count_loop
+
+or_count_done:
+    VZEROUPPER
+    MOVQ AX, ret+24(FP)      // Store result
+    RET
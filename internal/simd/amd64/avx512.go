@@ -0,0 +1,35 @@
+//go:build amd64 && !purego
+
+package amd64
+
+import "unsafe"
+
+// AVX-512 SIMD intrinsics for AMD64/x86-64
+// These functions use 512-bit ZMM registers and VPOPCNTQ and are implemented in assembly
+
+//go:noescape
+func hasAVX512Support() bool
+
+//go:noescape
+func avx512PopCount(data unsafe.Pointer, length int) int
+
+//go:noescape
+func avx512VectorOr(dst, src unsafe.Pointer, length int)
+
+//go:noescape
+func avx512VectorAnd(dst, src unsafe.Pointer, length int)
+
+//go:noescape
+func avx512VectorClear(data unsafe.Pointer, length int)
+
+//go:noescape
+func avx512PopCountAnd(a, b unsafe.Pointer, length int) int
+
+//go:noescape
+func avx512VectorXor(dst, src unsafe.Pointer, length int)
+
+//go:noescape
+func avx512VectorAndNot(dst, src unsafe.Pointer, length int)
+
+//go:noescape
+func avx512PopCountOr(a, b unsafe.Pointer, length int) int
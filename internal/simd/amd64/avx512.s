@@ -0,0 +1,421 @@
+//go:build amd64 && !purego
+
+#include "textflag.h"
+
+// hasAVX512Support checks that the CPU supports AVX-512 Foundation and
+// VPOPCNTDQ and that the OS saves the opmask and ZMM registers on context switch
+// Checks CPUID.1:ECX.OSXSAVE[bit 27], XCR0 bits 1, 2 and 5-7 (SSE, AVX,
+// opmask, ZMM_Hi256, Hi16_ZMM), CPUID.7.0:EBX.AVX512F[bit 16] and
+// CPUID.7.0:ECX.AVX512_VPOPCNTDQ[bit 14]
+// func hasAVX512Support() bool
+TEXT ·hasAVX512Support(SB), NOSPLIT, $0-1
+    // CPUID must support leaf 7
+    MOVL $0, AX
+    CPUID
+    CMPL AX, $7
+    JL no_avx512
+
+    // XGETBV is only available when the OS has enabled XSAVE
+    MOVL $1, AX
+    MOVL $0, CX
+    CPUID
+    BTL $27, CX
+    JCC no_avx512
+
+    // The OS must save XMM, YMM, opmask and both halves of the ZMM state
+    MOVL $0, CX
+    XGETBV
+    ANDL $0xe6, AX
+    CMPL AX, $0xe6
+    JNE no_avx512
+
+    MOVL $7, AX
+    MOVL $0, CX
+    CPUID
+    BTL $16, BX        // AVX512F
+    JCC no_avx512
+    BTL $14, CX        // AVX512_VPOPCNTDQ
+    JCC no_avx512
+
+    MOVB $1, ret+0(FP)
+    RET
+
+no_avx512:
+    MOVB $0, ret+0(FP)
+    RET
+
+// avx512PopCount counts the bits set using VPOPCNTQ, 128 bytes per iteration
+// into two accumulators so consecutive counts do not wait on each other
+// func avx512PopCount(data unsafe.Pointer, length int) int
+TEXT ·avx512PopCount(SB), NOSPLIT, $0-24
+    MOVQ data+0(FP), SI      // Load data pointer
+    MOVQ length+8(FP), CX    // Load length in bytes
+    XORQ AX, AX              // Initialize count accumulator
+    XORQ DX, DX              // Initialize loop counter
+    VPXORQ Z2, Z2, Z2        // Per-lane counts
+    VPXORQ Z3, Z3, Z3
+
+    // Calculate number of 128-byte chunks
+    MOVQ CX, R8
+    SHRQ $7, R8
+    SHLQ $7, R8              // Aligned length
+
+avx512_loop:
+    CMPQ DX, R8
+    JGE avx512_line
+
+    VPOPCNTQ (SI)(DX*1), Z0
+    VPOPCNTQ 64(SI)(DX*1), Z1
+    VPADDQ Z0, Z2, Z2
+    VPADDQ Z1, Z3, Z3
+
+    ADDQ $128, DX
+    JMP avx512_loop
+
+avx512_line:
+    // One remaining 64-byte chunk
+    MOVQ CX, R8
+    SUBQ DX, R8
+    CMPQ R8, $64
+    JL reduce
+
+    VPOPCNTQ (SI)(DX*1), Z0
+    VPADDQ Z0, Z2, Z2
+    ADDQ $64, DX
+
+reduce:
+    // Sum the 8 lanes of both accumulators
+    VPADDQ Z3, Z2, Z2
+    VEXTRACTI64X4 $1, Z2, Y3
+    VPADDQ Y3, Y2, Y2
+    VEXTRACTI128 $1, Y2, X3
+    VPADDQ X3, X2, X2
+    VPSHUFD $0x4e, X2, X3
+    VPADDQ X3, X2, X2
+    VMOVQ X2, AX
+
+scalar_loop:
+    CMPQ DX, CX
+    JGE done
+
+    MOVBQZX (SI)(DX*1), R9   // Load one byte
+    POPCNTQ R9, R9
+    ADDQ R9, AX
+
+    INCQ DX
+    JMP scalar_loop
+
+done:
+    VZEROUPPER               // Clear upper AVX state
+    MOVQ AX, ret+16(FP)      // Store result
+    RET
+
+// avx512VectorOr performs SIMD OR operation using AVX-512
+// func avx512VectorOr(dst, src unsafe.Pointer, length int)
+TEXT ·avx512VectorOr(SB), NOSPLIT, $0-24
+    MOVQ dst+0(FP), DI       // Load dst pointer
+    MOVQ src+8(FP), SI       // Load src pointer
+    MOVQ length+16(FP), CX   // Load length in bytes
+    XORQ DX, DX              // Initialize loop counter
+
+    // Calculate number of 64-byte chunks
+    MOVQ CX, R8
+    SHRQ $6, R8
+    SHLQ $6, R8              // Aligned length
+
+avx512_or_loop:
+    CMPQ DX, R8
+    JGE scalar_or_loop
+
+    // dst = dst | src, one cache line per iteration
+    VMOVDQU64 (DI)(DX*1), Z0
+    VPORQ (SI)(DX*1), Z0, Z0
+    VMOVDQU64 Z0, (DI)(DX*1)
+
+    ADDQ $64, DX
+    JMP avx512_or_loop
+
+scalar_or_loop:
+    CMPQ DX, CX
+    JGE or_done
+
+    MOVBQZX (DI)(DX*1), AX   // Load dst byte
+    MOVBQZX (SI)(DX*1), R9   // Load src byte
+    ORQ R9, AX               // dst = dst | src
+    MOVB AX, (DI)(DX*1)      // Store result
+
+    INCQ DX
+    JMP scalar_or_loop
+
+or_done:
+    VZEROUPPER
+    RET
+
+// avx512VectorAnd performs SIMD AND operation using AVX-512
+// func avx512VectorAnd(dst, src unsafe.Pointer, length int)
+TEXT ·avx512VectorAnd(SB), NOSPLIT, $0-24
+    MOVQ dst+0(FP), DI       // Load dst pointer
+    MOVQ src+8(FP), SI       // Load src pointer
+    MOVQ length+16(FP), CX   // Load length in bytes
+    XORQ DX, DX              // Initialize loop counter
+
+    // Calculate number of 64-byte chunks
+    MOVQ CX, R8
+    SHRQ $6, R8
+    SHLQ $6, R8              // Aligned length
+
+avx512_and_loop:
+    CMPQ DX, R8
+    JGE scalar_and_loop
+
+    // dst = dst & src, one cache line per iteration
+    VMOVDQU64 (DI)(DX*1), Z0
+    VPANDQ (SI)(DX*1), Z0, Z0
+    VMOVDQU64 Z0, (DI)(DX*1)
+
+    ADDQ $64, DX
+    JMP avx512_and_loop
+
+scalar_and_loop:
+    CMPQ DX, CX
+    JGE and_done
+
+    MOVBQZX (DI)(DX*1), AX   // Load dst byte
+    MOVBQZX (SI)(DX*1), R9   // Load src byte
+    ANDQ R9, AX              // dst = dst & src
+    MOVB AX, (DI)(DX*1)      // Store result
+
+    INCQ DX
+    JMP scalar_and_loop
+
+and_done:
+    VZEROUPPER
+    RET
+
+// avx512VectorClear performs SIMD clear operation using AVX-512
+// func avx512VectorClear(data unsafe.Pointer, length int)
+TEXT ·avx512VectorClear(SB), NOSPLIT, $0-16
+    MOVQ data+0(FP), DI      // Load data pointer
+    MOVQ length+8(FP), CX    // Load length in bytes
+    XORQ DX, DX              // Initialize loop counter
+
+    // Zero out ZMM register for clearing
+    VPXORQ Z0, Z0, Z0
+
+    // Calculate number of 64-byte chunks
+    MOVQ CX, R8
+    SHRQ $6, R8
+    SHLQ $6, R8              // Aligned length
+
+avx512_clear_loop:
+    CMPQ DX, R8
+    JGE scalar_clear_loop
+
+    // Store 64 zeros
+    VMOVDQU64 Z0, (DI)(DX*1)
+
+    ADDQ $64, DX
+    JMP avx512_clear_loop
+
+scalar_clear_loop:
+    CMPQ DX, CX
+    JGE clear_done
+
+    MOVB $0, (DI)(DX*1)      // Store zero byte
+    INCQ DX
+    JMP scalar_clear_loop
+
+clear_done:
+    VZEROUPPER
+    RET
+
+// avx512PopCountAnd counts the bits set in both a and b without storing a & b
+// func avx512PopCountAnd(a, b unsafe.Pointer, length int) int
+TEXT ·avx512PopCountAnd(SB), NOSPLIT, $0-32
+    MOVQ a+0(FP), SI         // Load a pointer
+    MOVQ b+8(FP), DI         // Load b pointer
+    MOVQ length+16(FP), CX   // Load length in bytes
+    XORQ DX, DX              // Initialize loop counter
+    VPXORQ Z2, Z2, Z2        // Per-lane counts
+
+    // Calculate number of 64-byte chunks
+    MOVQ CX, R8
+    SHRQ $6, R8
+    SHLQ $6, R8              // Aligned length
+
+avx512_and_count_loop:
+    CMPQ DX, R8
+    JGE and_count_reduce
+
+    VMOVDQU64 (SI)(DX*1), Z0
+    VPANDQ (DI)(DX*1), Z0, Z0
+    VPOPCNTQ Z0, Z0
+    VPADDQ Z0, Z2, Z2
+
+    ADDQ $64, DX
+    JMP avx512_and_count_loop
+
+and_count_reduce:
+    // Sum the 8 lanes
+    VEXTRACTI64X4 $1, Z2, Y3
+    VPADDQ Y3, Y2, Y2
+    VEXTRACTI128 $1, Y2, X3
+    VPADDQ X3, X2, X2
+    VPSHUFD $0x4e, X2, X3
+    VPADDQ X3, X2, X2
+    VMOVQ X2, AX
+
+scalar_and_count_loop:
+    CMPQ DX, CX
+    JGE and_count_done
+
+    MOVBQZX (SI)(DX*1), R9   // Load a byte
+    MOVBQZX (DI)(DX*1), R10  // Load b byte
+    ANDQ R10, R9
+    POPCNTQ R9, R9
+    ADDQ R9, AX
+
+    INCQ DX
+    JMP scalar_and_count_loop
+
+and_count_done:
+    VZEROUPPER
+    MOVQ AX, ret+24(FP)      // Store result
+    RET
+
+// avx512VectorXor performs SIMD XOR operation using AVX-512
+// func avx512VectorXor(dst, src unsafe.Pointer, length int)
+TEXT ·avx512VectorXor(SB), NOSPLIT, $0-24
+    MOVQ dst+0(FP), DI       // Load dst pointer
+    MOVQ src+8(FP), SI       // Load src pointer
+    MOVQ length+16(FP), CX   // Load length in bytes
+    XORQ DX, DX              // Initialize loop counter
+
+    // Calculate number of 64-byte chunks
+    MOVQ CX, R8
+    SHRQ $6, R8
+    SHLQ $6, R8              // Aligned length
+
+avx512_xor_loop:
+    CMPQ DX, R8
+    JGE scalar_xor_loop
+
+    // dst = dst ^ src, one cache line per iteration
+    VMOVDQU64 (DI)(DX*1), Z0
+    VPXORQ (SI)(DX*1), Z0, Z0
+    VMOVDQU64 Z0, (DI)(DX*1)
+
+    ADDQ $64, DX
+    JMP avx512_xor_loop
+
+scalar_xor_loop:
+    CMPQ DX, CX
+    JGE xor_done
+
+    MOVBQZX (DI)(DX*1), AX   // Load dst byte
+    MOVBQZX (SI)(DX*1), R9   // Load src byte
+    XORQ R9, AX              // dst = dst ^ src
+    MOVB AX, (DI)(DX*1)      // Store result
+
+    INCQ DX
+    JMP scalar_xor_loop
+
+xor_done:
+    VZEROUPPER
+    RET
+
+// avx512VectorAndNot performs SIMD AND NOT operation using AVX-512
+// func avx512VectorAndNot(dst, src unsafe.Pointer, length int)
+TEXT ·avx512VectorAndNot(SB), NOSPLIT, $0-24
+    MOVQ dst+0(FP), DI       // Load dst pointer
+    MOVQ src+8(FP), SI       // Load src pointer
+    MOVQ length+16(FP), CX   // Load length in bytes
+    XORQ DX, DX              // Initialize loop counter
+
+    // Calculate number of 64-byte chunks
+    MOVQ CX, R8
+    SHRQ $6, R8
+    SHLQ $6, R8              // Aligned length
+
+avx512_andnot_loop:
+    CMPQ DX, R8
+    JGE scalar_andnot_loop
+
+    // dst = dst &^ src, one cache line per iteration
+    VMOVDQU64 (SI)(DX*1), Z1
+    VPANDNQ (DI)(DX*1), Z1, Z0  // Z0 = ^src & dst
+    VMOVDQU64 Z0, (DI)(DX*1)
+
+    ADDQ $64, DX
+    JMP avx512_andnot_loop
+
+scalar_andnot_loop:
+    CMPQ DX, CX
+    JGE andnot_done
+
+    MOVBQZX (DI)(DX*1), AX   // Load dst byte
+    MOVBQZX (SI)(DX*1), R9   // Load src byte
+    NOTQ R9
+    ANDQ R9, AX              // dst = dst &^ src
+    MOVB AX, (DI)(DX*1)      // Store result
+
+    INCQ DX
+    JMP scalar_andnot_loop
+
+andnot_done:
+    VZEROUPPER
+    RET
+
+// avx512PopCountOr counts the bits set in either a or b without storing a | b
+// func avx512PopCountOr(a, b unsafe.Pointer, length int) int
+TEXT ·avx512PopCountOr(SB), NOSPLIT, $0-32
+    MOVQ a+0(FP), SI         // Load a pointer
+    MOVQ b+8(FP), DI         // Load b pointer
+    MOVQ length+16(FP), CX   // Load length in bytes
+    XORQ DX, DX              // Initialize loop counter
+    VPXORQ Z2, Z2, Z2        // Per-lane counts
+
+    // Calculate number of 64-byte chunks
+    MOVQ CX, R8
+    SHRQ $6, R8
+    SHLQ $6, R8              // Aligned length
+
+avx512_or_count_loop:
+    CMPQ DX, R8
+    JGE or_count_reduce
+
+    VMOVDQU64 (SI)(DX*1), Z0
+    VPORQ (DI)(DX*1), Z0, Z0
+    VPOPCNTQ Z0, Z0
+    VPADDQ Z0, Z2, Z2
+
+    ADDQ $64, DX
+    JMP avx512_or_count_loop
+
+or_count_reduce:
+    // Sum the 8 lanes
+    VEXTRACTI64X4 $1, Z2, Y3
+    VPADDQ Y3, Y2, Y2
+    VEXTRACTI128 $1, Y2, X3
+    VPADDQ X3, X2, X2
+    VPSHUFD $0x4e, X2, X3
+    VPADDQ X3, X2, X2
+    VMOVQ X2, AX
+
+scalar_or_count_loop:
+    CMPQ DX, CX
+    JGE or_count_done
+
+    MOVBQZX (SI)(DX*1), R9   // Load a byte
+    MOVBQZX (DI)(DX*1), R10  // Load b byte
+    ORQ R10, R9
+    POPCNTQ R9, R9
+    ADDQ R9, AX
+
+    INCQ DX
+    JMP scalar_or_count_loop
+
+or_count_done:
+    VZEROUPPER
+    MOVQ AX, ret+24(FP)      // Store result
+    RET
@@ -1,3 +1,5 @@
+//go:build arm64 && !purego
+
 package arm64
 
 import "unsafe"
@@ -17,7 +19,37 @@ func VectorAnd(dst, src unsafe.Pointer, length int) {
 	neonVectorAnd(dst, src, length)
 }
 
+// VectorXor performs SIMD XOR operation using NEON
+func VectorXor(dst, src unsafe.Pointer, length int) {
+	neonVectorXor(dst, src, length)
+}
+
+// VectorAndNot clears the bits of dst that are set in src using NEON
+func VectorAndNot(dst, src unsafe.Pointer, length int) {
+	neonVectorAndNot(dst, src, length)
+}
+
 // VectorClear performs SIMD clear operation using NEON
 func VectorClear(data unsafe.Pointer, length int) {
 	neonVectorClear(data, length)
 }
+
+// VectorCopy copies length bytes from src to dst using NEON
+func VectorCopy(dst, src unsafe.Pointer, length int) {
+	neonVectorCopy(dst, src, length)
+}
+
+// VectorEqual compares two buffers using NEON
+func VectorEqual(a, b unsafe.Pointer, length int) bool {
+	return neonVectorEqual(a, b, length)
+}
+
+// PopCountAnd counts the bits set in both buffers using NEON
+func PopCountAnd(a, b unsafe.Pointer, length int) int {
+	return neonPopCountAnd(a, b, length)
+}
+
+// PopCountOr counts the bits set in either buffer using NEON
+func PopCountOr(a, b unsafe.Pointer, length int) int {
+	return neonPopCountOr(a, b, length)
+}
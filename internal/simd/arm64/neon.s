@@ -2,13 +2,38 @@
 
 #include "textflag.h"
 
-// neonPopCount performs SIMD population count using ARM NEON
+// neonPopCount performs SIMD population count using ARM NEON: CNT counts the
+// bits of each byte and UADDLV sums them, one 64-byte chunk per iteration
 // func neonPopCount(data unsafe.Pointer, length int) int
 TEXT ·neonPopCount(SB), NOSPLIT, $0-24
     MOVD data+0(FP), R0      // Load data pointer
     MOVD length+8(FP), R1    // Load length in bytes
-    MOVD $0, R2              // Initialize count accumulator
     MOVD $0, R3              // Initialize loop counter
+    VEOR V5.B16, V5.B16, V5.B16  // Vector count accumulator
+    BIC $63, R1, R7          // Length covered by 64-byte chunks
+
+neon_loop:
+    CMP R7, R3
+    BGE neon_done
+
+    VLD1.P 64(R0), [V0.B16, V1.B16, V2.B16, V3.B16]
+    VCNT V0.B16, V0.B16
+    VCNT V1.B16, V1.B16
+    VCNT V2.B16, V2.B16
+    VCNT V3.B16, V3.B16
+
+    // At most 8 bits per byte, so the sum of four fits in a byte lane
+    VADD V1.B16, V0.B16, V0.B16
+    VADD V3.B16, V2.B16, V2.B16
+    VADD V2.B16, V0.B16, V0.B16
+    VUADDLV V0.B16, V4       // Horizontal sum into a halfword
+    VADD V4, V5              // Accumulate in the low 64-bit element
+
+    ADD $64, R3
+    B neon_loop
+
+neon_done:
+    VMOV V5.D[0], R2         // Initialize count accumulator from the vector count
 
     // Check if length is less than 8 bytes 
     CMP $8, R1
@@ -98,6 +123,23 @@ TEXT ·neonVectorOr(SB), NOSPLIT, $0-24
     MOVD src+8(FP), R1       // Load src pointer  
     MOVD length+16(FP), R2   // Load length in bytes
     MOVD $0, R3              // Initialize loop counter
+    BIC $63, R2, R7          // Length covered by 64-byte chunks
+
+neon_or_loop:
+    CMP R7, R3
+    BGE uint64_or_loop
+
+    // dst = dst | src, one 64-byte chunk per iteration
+    VLD1 (R0), [V0.B16, V1.B16, V2.B16, V3.B16]
+    VLD1.P 64(R1), [V4.B16, V5.B16, V6.B16, V7.B16]
+    VORR V4.B16, V0.B16, V0.B16
+    VORR V5.B16, V1.B16, V1.B16
+    VORR V6.B16, V2.B16, V2.B16
+    VORR V7.B16, V3.B16, V3.B16
+    VST1.P [V0.B16, V1.B16, V2.B16, V3.B16], 64(R0)
+
+    ADD $64, R3
+    B neon_or_loop
 
 uint64_or_loop:
     CMP R3, R2
@@ -146,6 +188,23 @@ TEXT ·neonVectorAnd(SB), NOSPLIT, $0-24
     MOVD src+8(FP), R1       // Load src pointer
     MOVD length+16(FP), R2   // Load length in bytes
     MOVD $0, R3              // Initialize loop counter
+    BIC $63, R2, R7          // Length covered by 64-byte chunks
+
+neon_and_loop:
+    CMP R7, R3
+    BGE uint64_and_loop
+
+    // dst = dst & src, one 64-byte chunk per iteration
+    VLD1 (R0), [V0.B16, V1.B16, V2.B16, V3.B16]
+    VLD1.P 64(R1), [V4.B16, V5.B16, V6.B16, V7.B16]
+    VAND V4.B16, V0.B16, V0.B16
+    VAND V5.B16, V1.B16, V1.B16
+    VAND V6.B16, V2.B16, V2.B16
+    VAND V7.B16, V3.B16, V3.B16
+    VST1.P [V0.B16, V1.B16, V2.B16, V3.B16], 64(R0)
+
+    ADD $64, R3
+    B neon_and_loop
 
 uint64_and_loop:
     CMP R3, R2
@@ -193,6 +252,20 @@ TEXT ·neonVectorClear(SB), NOSPLIT, $0-16
     MOVD data+0(FP), R0      // Load data pointer
     MOVD length+8(FP), R1    // Load length in bytes
     MOVD $0, R2              // Initialize loop counter
+    BIC $63, R1, R7          // Length covered by 64-byte chunks
+
+neon_clear_loop:
+    CMP R7, R2
+    BGE uint64_clear_loop
+
+    // Store 64 zeros as four register pairs
+    STP.P (ZR, ZR), 16(R0)
+    STP.P (ZR, ZR), 16(R0)
+    STP.P (ZR, ZR), 16(R0)
+    STP.P (ZR, ZR), 16(R0)
+
+    ADD $64, R2
+    B neon_clear_loop
 
 uint64_clear_loop:
     CMP R2, R1
@@ -220,3 +293,418 @@ clear_scalar:
 
 clear_done:
     RET
+
+// neonVectorEqual reports whether two buffers hold the same bytes,
+// stopping at the first differing word
+// func neonVectorEqual(a, b unsafe.Pointer, length int) bool
+TEXT ·neonVectorEqual(SB), NOSPLIT, $0-25
+    MOVD a+0(FP), R0         // Load a pointer
+    MOVD b+8(FP), R1         // Load b pointer
+    MOVD length+16(FP), R2   // Load length in bytes
+    MOVD $0, R3              // Initialize loop counter
+
+uint64_eq_loop:
+    CMP R3, R2
+    BEQ eq_true
+
+    SUB R3, R2, R4           // Calculate remaining bytes
+    CMP $8, R4               // Check if we have at least 8 bytes
+    BLT eq_scalar
+
+    MOVD (R0), R5            // Load a
+    MOVD (R1), R6            // Load b
+    CMP R5, R6
+    BNE eq_false
+
+    ADD $8, R0               // Advance a pointer
+    ADD $8, R1               // Advance b pointer
+    ADD $8, R3               // Advance counter
+    B uint64_eq_loop
+
+eq_scalar:
+    CMP R3, R2
+    BEQ eq_true
+
+    MOVBU (R0), R4           // Load a byte
+    MOVBU (R1), R5           // Load b byte
+    CMP R4, R5
+    BNE eq_false
+
+    ADD $1, R0               // Advance a pointer
+    ADD $1, R1               // Advance b pointer
+    ADD $1, R3               // Advance counter
+    B eq_scalar
+
+eq_true:
+    MOVD $1, R4
+    MOVB R4, ret+24(FP)
+    RET
+
+eq_false:
+    MOVB ZR, ret+24(FP)
+    RET
+
+// neonVectorCopy copies length bytes from src to dst
+// func neonVectorCopy(dst, src unsafe.Pointer, length int)
+TEXT ·neonVectorCopy(SB), NOSPLIT, $0-24
+    MOVD dst+0(FP), R0       // Load dst pointer
+    MOVD src+8(FP), R1       // Load src pointer
+    MOVD length+16(FP), R2   // Load length in bytes
+    MOVD $0, R3              // Initialize loop counter
+
+uint64_copy_loop:
+    CMP R3, R2
+    BEQ copy_done
+
+    SUB R3, R2, R4           // Calculate remaining bytes
+    CMP $8, R4               // Check if we have at least 8 bytes
+    BLT copy_scalar
+
+    MOVD (R1), R5            // Load src
+    MOVD R5, (R0)            // Store to dst
+
+    ADD $8, R0               // Advance dst pointer
+    ADD $8, R1               // Advance src pointer
+    ADD $8, R3               // Advance counter
+    B uint64_copy_loop
+
+copy_scalar:
+    CMP R3, R2
+    BEQ copy_done
+
+    MOVBU (R1), R4           // Load src byte
+    MOVB R4, (R0)            // Store to dst
+
+    ADD $1, R0               // Advance dst pointer
+    ADD $1, R1               // Advance src pointer
+    ADD $1, R3               // Advance counter
+    B copy_scalar
+
+copy_done:
+    RET
+
+// neonPopCountAnd counts the bits set in both a and b without storing a & b
+// func neonPopCountAnd(a, b unsafe.Pointer, length int) int
+TEXT ·neonPopCountAnd(SB), NOSPLIT, $0-32
+    MOVD a+0(FP), R0         // Load a pointer
+    MOVD b+8(FP), R1         // Load b pointer
+    MOVD length+16(FP), R2   // Load length in bytes
+    MOVD $0, R3              // Initialize loop counter
+    VEOR V9.B16, V9.B16, V9.B16  // Vector count accumulator
+    BIC $63, R2, R7          // Length covered by 64-byte chunks
+
+neon_and_count_loop:
+    CMP R7, R3
+    BGE neon_and_count_done
+
+    VLD1.P 64(R0), [V0.B16, V1.B16, V2.B16, V3.B16]
+    VLD1.P 64(R1), [V4.B16, V5.B16, V6.B16, V7.B16]
+    VAND V4.B16, V0.B16, V0.B16
+    VAND V5.B16, V1.B16, V1.B16
+    VAND V6.B16, V2.B16, V2.B16
+    VAND V7.B16, V3.B16, V3.B16
+    VCNT V0.B16, V0.B16
+    VCNT V1.B16, V1.B16
+    VCNT V2.B16, V2.B16
+    VCNT V3.B16, V3.B16
+    VADD V1.B16, V0.B16, V0.B16
+    VADD V3.B16, V2.B16, V2.B16
+    VADD V2.B16, V0.B16, V0.B16
+    VUADDLV V0.B16, V8
+    VADD V8, V9
+
+    ADD $64, R3
+    B neon_and_count_loop
+
+neon_and_count_done:
+    VMOV V9.D[0], R10        // Initialize count accumulator from the vector count
+
+uint64_and_count_loop:
+    CMP R3, R2
+    BEQ and_count_done
+
+    SUB R3, R2, R4           // Calculate remaining bytes
+    CMP $8, R4               // Check if we have at least 8 bytes
+    BLT and_count_scalar
+
+    MOVD (R0), R4            // Load a
+    MOVD (R1), R5            // Load b
+    AND R5, R4               // a & b
+
+    // Same bit manipulation popcount as neonPopCount
+    MOVD $0x5555555555555555, R5
+    LSR $1, R4, R6
+    AND R5, R6
+    SUB R6, R4
+    MOVD $0x3333333333333333, R5
+    LSR $2, R4, R6
+    AND R5, R6
+    AND R5, R4
+    ADD R6, R4
+    LSR $4, R4, R6
+    ADD R6, R4
+    MOVD $0x0f0f0f0f0f0f0f0f, R5
+    AND R5, R4
+    MOVD $0x0101010101010101, R5
+    MUL R5, R4
+    LSR $56, R4
+
+    ADD R4, R10              // Add to accumulator
+    ADD $8, R0               // Advance a pointer
+    ADD $8, R1               // Advance b pointer
+    ADD $8, R3               // Advance counter
+    B uint64_and_count_loop
+
+and_count_scalar:
+    CMP R3, R2
+    BEQ and_count_done
+
+    MOVBU (R0), R4           // Load a byte
+    MOVBU (R1), R5           // Load b byte
+    AND R5, R4
+
+    // Count bits one at a time
+and_count_bits:
+    CBZ R4, and_count_next
+    AND $1, R4, R6
+    ADD R6, R10
+    LSR $1, R4
+    B and_count_bits
+
+and_count_next:
+    ADD $1, R0               // Advance a pointer
+    ADD $1, R1               // Advance b pointer
+    ADD $1, R3               // Advance counter
+    B and_count_scalar
+
+and_count_done:
+    MOVD R10, ret+24(FP)     // Store result
+    RET
+
+// neonVectorXor performs SIMD XOR operation using ARM NEON  
+// func neonVectorXor(dst, src unsafe.Pointer, length int)
+TEXT ·neonVectorXor(SB), NOSPLIT, $0-24
+    MOVD dst+0(FP), R0       // Load dst pointer
+    MOVD src+8(FP), R1       // Load src pointer  
+    MOVD length+16(FP), R2   // Load length in bytes
+    MOVD $0, R3              // Initialize loop counter
+    BIC $63, R2, R7          // Length covered by 64-byte chunks
+
+neon_xor_loop:
+    CMP R7, R3
+    BGE uint64_xor_loop
+
+    // dst = dst ^ src, one 64-byte chunk per iteration
+    VLD1 (R0), [V0.B16, V1.B16, V2.B16, V3.B16]
+    VLD1.P 64(R1), [V4.B16, V5.B16, V6.B16, V7.B16]
+    VEOR V4.B16, V0.B16, V0.B16
+    VEOR V5.B16, V1.B16, V1.B16
+    VEOR V6.B16, V2.B16, V2.B16
+    VEOR V7.B16, V3.B16, V3.B16
+    VST1.P [V0.B16, V1.B16, V2.B16, V3.B16], 64(R0)
+
+    ADD $64, R3
+    B neon_xor_loop
+
+uint64_xor_loop:
+    CMP R3, R2
+    BEQ xor_done
+    
+    SUB R3, R2, R4           // Calculate remaining bytes
+    CMP $8, R4               // Check if we have at least 8 bytes
+    BLT xor_scalar
+    
+    // Load 8 bytes from both src and dst
+    MOVD (R0), R5            // Load dst
+    MOVD (R1), R6            // Load src
+    
+    // Perform XOR operation
+    EOR R6, R5, R5           // dst = dst ^ src
+    
+    // Store result back to dst
+    MOVD R5, (R0)
+    
+    ADD $8, R0               // Advance dst pointer
+    ADD $8, R1               // Advance src pointer
+    ADD $8, R3               // Advance counter
+    B uint64_xor_loop
+
+xor_scalar:
+    CMP R3, R2
+    BEQ xor_done
+    
+    MOVBU (R0), R4           // Load dst byte
+    MOVBU (R1), R5           // Load src byte
+    EOR R5, R4, R4           // dst = dst ^ src
+    MOVB R4, (R0)            // Store result
+    
+    ADD $1, R0               // Advance dst pointer
+    ADD $1, R1               // Advance src pointer  
+    ADD $1, R3               // Advance counter
+    B xor_scalar
+
+xor_done:
+    RET
+
+// neonVectorAndNot performs SIMD AND NOT operation using ARM NEON  
+// func neonVectorAndNot(dst, src unsafe.Pointer, length int)
+TEXT ·neonVectorAndNot(SB), NOSPLIT, $0-24
+    MOVD dst+0(FP), R0       // Load dst pointer
+    MOVD src+8(FP), R1       // Load src pointer  
+    MOVD length+16(FP), R2   // Load length in bytes
+    MOVD $0, R3              // Initialize loop counter
+    BIC $63, R2, R7          // Length covered by 64-byte chunks
+
+neon_andnot_loop:
+    CMP R7, R3
+    BGE uint64_andnot_loop
+
+    // dst = dst &^ src, one 64-byte chunk per iteration
+    VLD1 (R0), [V0.B16, V1.B16, V2.B16, V3.B16]
+    VLD1.P 64(R1), [V4.B16, V5.B16, V6.B16, V7.B16]
+    VBIC V4.B16, V0.B16, V0.B16
+    VBIC V5.B16, V1.B16, V1.B16
+    VBIC V6.B16, V2.B16, V2.B16
+    VBIC V7.B16, V3.B16, V3.B16
+    VST1.P [V0.B16, V1.B16, V2.B16, V3.B16], 64(R0)
+
+    ADD $64, R3
+    B neon_andnot_loop
+
+uint64_andnot_loop:
+    CMP R3, R2
+    BEQ andnot_done
+    
+    SUB R3, R2, R4           // Calculate remaining bytes
+    CMP $8, R4               // Check if we have at least 8 bytes
+    BLT andnot_scalar
+    
+    // Load 8 bytes from both src and dst
+    MOVD (R0), R5            // Load dst
+    MOVD (R1), R6            // Load src
+    
+    // Perform AND NOT operation
+    BIC R6, R5, R5           // dst = dst &^ src
+    
+    // Store result back to dst
+    MOVD R5, (R0)
+    
+    ADD $8, R0               // Advance dst pointer
+    ADD $8, R1               // Advance src pointer
+    ADD $8, R3               // Advance counter
+    B uint64_andnot_loop
+
+andnot_scalar:
+    CMP R3, R2
+    BEQ andnot_done
+    
+    MOVBU (R0), R4           // Load dst byte
+    MOVBU (R1), R5           // Load src byte
+    BIC R5, R4, R4           // dst = dst &^ src
+    MOVB R4, (R0)            // Store result
+    
+    ADD $1, R0               // Advance dst pointer
+    ADD $1, R1               // Advance src pointer  
+    ADD $1, R3               // Advance counter
+    B andnot_scalar
+
+andnot_done:
+    RET
+
+// neonPopCountOr counts the bits set in either a or b without storing a | b
+// func neonPopCountOr(a, b unsafe.Pointer, length int) int
+TEXT ·neonPopCountOr(SB), NOSPLIT, $0-32
+    MOVD a+0(FP), R0         // Load a pointer
+    MOVD b+8(FP), R1         // Load b pointer
+    MOVD length+16(FP), R2   // Load length in bytes
+    MOVD $0, R3              // Initialize loop counter
+    VEOR V9.B16, V9.B16, V9.B16  // Vector count accumulator
+    BIC $63, R2, R7          // Length covered by 64-byte chunks
+
+neon_or_count_loop:
+    CMP R7, R3
+    BGE neon_or_count_done
+
+    VLD1.P 64(R0), [V0.B16, V1.B16, V2.B16, V3.B16]
+    VLD1.P 64(R1), [V4.B16, V5.B16, V6.B16, V7.B16]
+    VORR V4.B16, V0.B16, V0.B16
+    VORR V5.B16, V1.B16, V1.B16
+    VORR V6.B16, V2.B16, V2.B16
+    VORR V7.B16, V3.B16, V3.B16
+    VCNT V0.B16, V0.B16
+    VCNT V1.B16, V1.B16
+    VCNT V2.B16, V2.B16
+    VCNT V3.B16, V3.B16
+    VADD V1.B16, V0.B16, V0.B16
+    VADD V3.B16, V2.B16, V2.B16
+    VADD V2.B16, V0.B16, V0.B16
+    VUADDLV V0.B16, V8
+    VADD V8, V9
+
+    ADD $64, R3
+    B neon_or_count_loop
+
+neon_or_count_done:
+    VMOV V9.D[0], R10        // Initialize count accumulator from the vector count
+
+uint64_or_count_loop:
+    CMP R3, R2
+    BEQ or_count_done
+
+    SUB R3, R2, R4           // Calculate remaining bytes
+    CMP $8, R4               // Check if we have at least 8 bytes
+    BLT or_count_scalar
+
+    MOVD (R0), R4            // Load a
+    MOVD (R1), R5            // Load b
+    ORR R5, R4               // a | b
+
+    // Same bit manipulation popcount as neonPopCount
+    MOVD $0x5555555555555555, R5
+    LSR $1, R4, R6
+    AND R5, R6
+    SUB R6, R4
+    MOVD $0x3333333333333333, R5
+    LSR $2, R4, R6
+    AND R5, R6
+    AND R5, R4
+    ADD R6, R4
+    LSR $4, R4, R6
+    ADD R6, R4
+    MOVD $0x0f0f0f0f0f0f0f0f, R5
+    AND R5, R4
+    MOVD $0x0101010101010101, R5
+    MUL R5, R4
+    LSR $56, R4
+
+    ADD R4, R10              // Add to accumulator
+    ADD $8, R0               // Advance a pointer
+    ADD $8, R1               // Advance b pointer
+    ADD $8, R3               // Advance counter
+    B uint64_or_count_loop
+
+or_count_scalar:
+    CMP R3, R2
+    BEQ or_count_done
+
+    MOVBU (R0), R4           // Load a byte
+    MOVBU (R1), R5           // Load b byte
+    ORR R5, R4
+
+    // Count bits one at a time
+or_count_bits:
+    CBZ R4, or_count_next
+    AND $1, R4, R6
+    ADD R6, R10
+    LSR $1, R4
+    B or_count_bits
+
+or_count_next:
+    ADD $1, R0               // Advance a pointer
+    ADD $1, R1               // Advance b pointer
+    ADD $1, R3               // Advance counter
+    B or_count_scalar
+
+or_count_done:
+    MOVD R10, ret+24(FP)     // Store result
+    RET
@@ -20,3 +20,21 @@ func neonVectorAnd(dst, src unsafe.Pointer, length int)
 
 //go:noescape
 func neonVectorClear(data unsafe.Pointer, length int)
+
+//go:noescape
+func neonVectorEqual(a, b unsafe.Pointer, length int) bool
+
+//go:noescape
+func neonVectorCopy(dst, src unsafe.Pointer, length int)
+
+//go:noescape
+func neonPopCountAnd(a, b unsafe.Pointer, length int) int
+
+//go:noescape
+func neonVectorXor(dst, src unsafe.Pointer, length int)
+
+//go:noescape
+func neonVectorAndNot(dst, src unsafe.Pointer, length int)
+
+//go:noescape
+func neonPopCountOr(a, b unsafe.Pointer, length int) int
@@ -1,3 +1,5 @@
+//go:build amd64 && !purego
+
 package simd
 
 import (
@@ -21,6 +23,30 @@ func (a *AVX2Operations) VectorAnd(dst, src unsafe.Pointer, length int) {
 	amd64.VectorAnd(dst, src, length)
 }
 
+func (a *AVX2Operations) VectorXor(dst, src unsafe.Pointer, length int) {
+	amd64.VectorXor(dst, src, length)
+}
+
+func (a *AVX2Operations) VectorAndNot(dst, src unsafe.Pointer, length int) {
+	amd64.VectorAndNot(dst, src, length)
+}
+
 func (a *AVX2Operations) VectorClear(data unsafe.Pointer, length int) {
 	amd64.VectorClear(data, length)
 }
+
+func (a *AVX2Operations) VectorEqual(x, y unsafe.Pointer, length int) bool {
+	return amd64.VectorEqual(x, y, length)
+}
+
+func (a *AVX2Operations) VectorCopy(dst, src unsafe.Pointer, length int) {
+	amd64.VectorCopy(dst, src, length)
+}
+
+func (a *AVX2Operations) PopCountAnd(x, y unsafe.Pointer, length int) int {
+	return amd64.PopCountAnd(x, y, length)
+}
+
+func (a *AVX2Operations) PopCountOr(x, y unsafe.Pointer, length int) int {
+	return amd64.PopCountOr(x, y, length)
+}
@@ -1,27 +1,54 @@
+//go:build amd64 && !purego
+
 package simd
 
-import "unsafe"
+import (
+	"unsafe"
+
+	"github.com/shaia/BloomFilter/internal/simd/amd64"
+)
 
-// AVX512Operations implements SIMD operations using Intel AVX512
-// This is a placeholder for future implementation - falls back to optimized scalar for now
+// AVX512Operations implements SIMD operations using Intel/AMD AVX-512 with
+// VPOPCNTDQ. Compare and copy have no AVX-512 kernel and use the AVX2 ones,
+// which every AVX-512 CPU supports.
 type AVX512Operations struct{}
 
 func (a *AVX512Operations) PopCount(data unsafe.Pointer, length int) int {
-	// TODO: Implement true AVX512 popcount - using fallback for now
-	return (&FallbackOperations{}).PopCount(data, length)
+	return amd64.AVX512PopCount(data, length)
 }
 
 func (a *AVX512Operations) VectorOr(dst, src unsafe.Pointer, length int) {
-	// TODO: Implement true AVX512 vector OR - using fallback for now
-	(&FallbackOperations{}).VectorOr(dst, src, length)
+	amd64.AVX512VectorOr(dst, src, length)
 }
 
 func (a *AVX512Operations) VectorAnd(dst, src unsafe.Pointer, length int) {
-	// TODO: Implement true AVX512 vector AND - using fallback for now
-	(&FallbackOperations{}).VectorAnd(dst, src, length)
+	amd64.AVX512VectorAnd(dst, src, length)
+}
+
+func (a *AVX512Operations) VectorXor(dst, src unsafe.Pointer, length int) {
+	amd64.AVX512VectorXor(dst, src, length)
+}
+
+func (a *AVX512Operations) VectorAndNot(dst, src unsafe.Pointer, length int) {
+	amd64.AVX512VectorAndNot(dst, src, length)
 }
 
 func (a *AVX512Operations) VectorClear(data unsafe.Pointer, length int) {
-	// TODO: Implement true AVX512 vector clear - using fallback for now
-	(&FallbackOperations{}).VectorClear(data, length)
+	amd64.AVX512VectorClear(data, length)
+}
+
+func (a *AVX512Operations) VectorEqual(x, y unsafe.Pointer, length int) bool {
+	return amd64.VectorEqual(x, y, length)
+}
+
+func (a *AVX512Operations) VectorCopy(dst, src unsafe.Pointer, length int) {
+	amd64.VectorCopy(dst, src, length)
+}
+
+func (a *AVX512Operations) PopCountAnd(x, y unsafe.Pointer, length int) int {
+	return amd64.AVX512PopCountAnd(x, y, length)
+}
+
+func (a *AVX512Operations) PopCountOr(x, y unsafe.Pointer, length int) int {
+	return amd64.AVX512PopCountOr(x, y, length)
 }
@@ -0,0 +1,12 @@
+//go:build !amd64 || purego
+
+package simd
+
+// AVX2Operations stands in for the AVX2 kernels in builds without them
+// (non-amd64 or purego) and runs the scalar fallback. HasAVX2 reports false,
+// so Get never returns it.
+type AVX2Operations struct{ FallbackOperations }
+
+// AVX512Operations stands in for the AVX-512 kernels in builds without them
+// and runs the scalar fallback. HasAVX512 reports false, so Get never returns it.
+type AVX512Operations struct{ FallbackOperations }
@@ -7,7 +7,7 @@ type FallbackOperations struct{}
 
 func (f *FallbackOperations) PopCount(data unsafe.Pointer, length int) int {
 	// Use optimized scalar popcount
-	ptr := (*[1 << 30]uint64)(data)[:length/8]
+	ptr := unsafe.Slice((*uint64)(data), length/8)
 	count := 0
 	for i := 0; i < len(ptr); i++ {
 		count += popcount64(ptr[i])
@@ -16,7 +16,7 @@ func (f *FallbackOperations) PopCount(data unsafe.Pointer, length int) int {
 	// Handle remaining bytes
 	remaining := length % 8
 	if remaining > 0 {
-		lastBytes := (*[8]byte)(unsafe.Pointer(uintptr(data) + uintptr(length-remaining)))
+		lastBytes := unsafe.Slice((*byte)(unsafe.Add(data, length-remaining)), remaining)
 		var lastWord uint64
 		for i := 0; i < remaining; i++ {
 			lastWord |= uint64(lastBytes[i]) << (i * 8)
@@ -29,8 +29,8 @@ func (f *FallbackOperations) PopCount(data unsafe.Pointer, length int) int {
 
 func (f *FallbackOperations) VectorOr(dst, src unsafe.Pointer, length int) {
 	// Process 8 bytes at a time
-	dstPtr := (*[1 << 30]uint64)(dst)[:length/8]
-	srcPtr := (*[1 << 30]uint64)(src)[:length/8]
+	dstPtr := unsafe.Slice((*uint64)(dst), length/8)
+	srcPtr := unsafe.Slice((*uint64)(src), length/8)
 
 	for i := 0; i < len(dstPtr); i++ {
 		dstPtr[i] |= srcPtr[i]
@@ -39,8 +39,8 @@ func (f *FallbackOperations) VectorOr(dst, src unsafe.Pointer, length int) {
 	// Handle remaining bytes
 	remaining := length % 8
 	if remaining > 0 {
-		dstBytes := (*[8]byte)(unsafe.Pointer(uintptr(dst) + uintptr(length-remaining)))
-		srcBytes := (*[8]byte)(unsafe.Pointer(uintptr(src) + uintptr(length-remaining)))
+		dstBytes := unsafe.Slice((*byte)(unsafe.Add(dst, length-remaining)), remaining)
+		srcBytes := unsafe.Slice((*byte)(unsafe.Add(src, length-remaining)), remaining)
 		for i := 0; i < remaining; i++ {
 			dstBytes[i] |= srcBytes[i]
 		}
@@ -49,8 +49,8 @@ func (f *FallbackOperations) VectorOr(dst, src unsafe.Pointer, length int) {
 
 func (f *FallbackOperations) VectorAnd(dst, src unsafe.Pointer, length int) {
 	// Process 8 bytes at a time
-	dstPtr := (*[1 << 30]uint64)(dst)[:length/8]
-	srcPtr := (*[1 << 30]uint64)(src)[:length/8]
+	dstPtr := unsafe.Slice((*uint64)(dst), length/8)
+	srcPtr := unsafe.Slice((*uint64)(src), length/8)
 
 	for i := 0; i < len(dstPtr); i++ {
 		dstPtr[i] &= srcPtr[i]
@@ -59,17 +59,57 @@ func (f *FallbackOperations) VectorAnd(dst, src unsafe.Pointer, length int) {
 	// Handle remaining bytes
 	remaining := length % 8
 	if remaining > 0 {
-		dstBytes := (*[8]byte)(unsafe.Pointer(uintptr(dst) + uintptr(length-remaining)))
-		srcBytes := (*[8]byte)(unsafe.Pointer(uintptr(src) + uintptr(length-remaining)))
+		dstBytes := unsafe.Slice((*byte)(unsafe.Add(dst, length-remaining)), remaining)
+		srcBytes := unsafe.Slice((*byte)(unsafe.Add(src, length-remaining)), remaining)
 		for i := 0; i < remaining; i++ {
 			dstBytes[i] &= srcBytes[i]
 		}
 	}
 }
 
+func (f *FallbackOperations) VectorXor(dst, src unsafe.Pointer, length int) {
+	// Process 8 bytes at a time
+	dstPtr := unsafe.Slice((*uint64)(dst), length/8)
+	srcPtr := unsafe.Slice((*uint64)(src), length/8)
+
+	for i := 0; i < len(dstPtr); i++ {
+		dstPtr[i] ^= srcPtr[i]
+	}
+
+	// Handle remaining bytes
+	remaining := length % 8
+	if remaining > 0 {
+		dstBytes := unsafe.Slice((*byte)(unsafe.Add(dst, length-remaining)), remaining)
+		srcBytes := unsafe.Slice((*byte)(unsafe.Add(src, length-remaining)), remaining)
+		for i := 0; i < remaining; i++ {
+			dstBytes[i] ^= srcBytes[i]
+		}
+	}
+}
+
+func (f *FallbackOperations) VectorAndNot(dst, src unsafe.Pointer, length int) {
+	// Process 8 bytes at a time
+	dstPtr := unsafe.Slice((*uint64)(dst), length/8)
+	srcPtr := unsafe.Slice((*uint64)(src), length/8)
+
+	for i := 0; i < len(dstPtr); i++ {
+		dstPtr[i] &^= srcPtr[i]
+	}
+
+	// Handle remaining bytes
+	remaining := length % 8
+	if remaining > 0 {
+		dstBytes := unsafe.Slice((*byte)(unsafe.Add(dst, length-remaining)), remaining)
+		srcBytes := unsafe.Slice((*byte)(unsafe.Add(src, length-remaining)), remaining)
+		for i := 0; i < remaining; i++ {
+			dstBytes[i] &^= srcBytes[i]
+		}
+	}
+}
+
 func (f *FallbackOperations) VectorClear(data unsafe.Pointer, length int) {
 	// Process 8 bytes at a time
-	ptr := (*[1 << 30]uint64)(data)[:length/8]
+	ptr := unsafe.Slice((*uint64)(data), length/8)
 
 	for i := 0; i < len(ptr); i++ {
 		ptr[i] = 0
@@ -78,13 +118,84 @@ func (f *FallbackOperations) VectorClear(data unsafe.Pointer, length int) {
 	// Handle remaining bytes
 	remaining := length % 8
 	if remaining > 0 {
-		bytes := (*[8]byte)(unsafe.Pointer(uintptr(data) + uintptr(length-remaining)))
+		bytes := unsafe.Slice((*byte)(unsafe.Add(data, length-remaining)), remaining)
 		for i := 0; i < remaining; i++ {
 			bytes[i] = 0
 		}
 	}
 }
 
+func (f *FallbackOperations) VectorEqual(a, b unsafe.Pointer, length int) bool {
+	// Compare 8 bytes at a time
+	aPtr := unsafe.Slice((*uint64)(a), length/8)
+	bPtr := unsafe.Slice((*uint64)(b), length/8)
+
+	for i := 0; i < len(aPtr); i++ {
+		if aPtr[i] != bPtr[i] {
+			return false
+		}
+	}
+
+	// Handle remaining bytes
+	remaining := length % 8
+	if remaining > 0 {
+		aBytes := unsafe.Slice((*byte)(unsafe.Add(a, length-remaining)), remaining)
+		bBytes := unsafe.Slice((*byte)(unsafe.Add(b, length-remaining)), remaining)
+		for i := 0; i < remaining; i++ {
+			if aBytes[i] != bBytes[i] {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func (f *FallbackOperations) VectorCopy(dst, src unsafe.Pointer, length int) {
+	copy(unsafe.Slice((*byte)(dst), length), unsafe.Slice((*byte)(src), length))
+}
+
+func (f *FallbackOperations) PopCountAnd(a, b unsafe.Pointer, length int) int {
+	// Count 8 bytes at a time
+	aPtr := unsafe.Slice((*uint64)(a), length/8)
+	bPtr := unsafe.Slice((*uint64)(b), length/8)
+	count := 0
+	for i := 0; i < len(aPtr); i++ {
+		count += popcount64(aPtr[i] & bPtr[i])
+	}
+
+	// Handle remaining bytes
+	remaining := length % 8
+	if remaining > 0 {
+		aBytes := unsafe.Slice((*byte)(unsafe.Add(a, length-remaining)), remaining)
+		bBytes := unsafe.Slice((*byte)(unsafe.Add(b, length-remaining)), remaining)
+		for i := 0; i < remaining; i++ {
+			count += popcount64(uint64(aBytes[i] & bBytes[i]))
+		}
+	}
+	return count
+}
+
+func (f *FallbackOperations) PopCountOr(a, b unsafe.Pointer, length int) int {
+	// Count 8 bytes at a time
+	aPtr := unsafe.Slice((*uint64)(a), length/8)
+	bPtr := unsafe.Slice((*uint64)(b), length/8)
+	count := 0
+	for i := 0; i < len(aPtr); i++ {
+		count += popcount64(aPtr[i] | bPtr[i])
+	}
+
+	// Handle remaining bytes
+	remaining := length % 8
+	if remaining > 0 {
+		aBytes := unsafe.Slice((*byte)(unsafe.Add(a, length-remaining)), remaining)
+		bBytes := unsafe.Slice((*byte)(unsafe.Add(b, length-remaining)), remaining)
+		for i := 0; i < remaining; i++ {
+			count += popcount64(uint64(aBytes[i] | bBytes[i]))
+		}
+	}
+	return count
+}
+
 // popcount64 implements efficient popcount for uint64
 func popcount64(x uint64) int {
 	// Use the same algorithm as bits.OnesCount64 but inline for performance
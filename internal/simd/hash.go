@@ -0,0 +1,29 @@
+package simd
+
+import "github.com/shaia/BloomFilter/internal/hash"
+
+// HashUint64Batch stores hash.SeededUint64 of every key in h1 and h2, which must
+// be at least len(keys) long.
+//
+// On AVX2 keys are hashed 8 per iteration; AVX2 has no 64-bit lane multiply, so
+// each product is assembled from three 32-bit VPMULUDQ multiplies. NEON lacks the
+// 64-bit lane multiply as well, and there the emulation costs more than the
+// scalar MUL it replaces, so arm64 and other platforms use the interleaved
+// scalar kernel.
+func HashUint64Batch(keys []uint64, seed uint64, h1, h2 []uint64) {
+	h1 = h1[:len(keys)]
+	h2 = h2[:len(keys)]
+	n := hashUint64Kernel(keys, seed, h1, h2)
+	hash.SeededUint64Batch(keys[n:], seed, h1[n:], h2[n:])
+}
+
+// Hash128Uint64Batch stores hash.Seeded128Uint64 of every key in h1 and h2,
+// which must be at least len(keys) long. On AVX2 keys are hashed 4 per
+// iteration with the same emulated 64-bit multiply; elsewhere the interleaved
+// scalar kernel is used.
+func Hash128Uint64Batch(keys []uint64, seed uint64, h1, h2 []uint64) {
+	h1 = h1[:len(keys)]
+	h2 = h2[:len(keys)]
+	n := hash128Uint64Kernel(keys, seed, h1, h2)
+	hash.Seeded128Uint64Batch(keys[n:], seed, h1[n:], h2[n:])
+}
@@ -1,3 +1,5 @@
+//go:build arm64 && !purego
+
 package simd
 
 import (
@@ -21,6 +23,30 @@ func (n *NEONOperations) VectorAnd(dst, src unsafe.Pointer, length int) {
 	arm64.VectorAnd(dst, src, length)
 }
 
+func (n *NEONOperations) VectorXor(dst, src unsafe.Pointer, length int) {
+	arm64.VectorXor(dst, src, length)
+}
+
+func (n *NEONOperations) VectorAndNot(dst, src unsafe.Pointer, length int) {
+	arm64.VectorAndNot(dst, src, length)
+}
+
 func (n *NEONOperations) VectorClear(data unsafe.Pointer, length int) {
 	arm64.VectorClear(data, length)
 }
+
+func (n *NEONOperations) VectorEqual(a, b unsafe.Pointer, length int) bool {
+	return arm64.VectorEqual(a, b, length)
+}
+
+func (n *NEONOperations) VectorCopy(dst, src unsafe.Pointer, length int) {
+	arm64.VectorCopy(dst, src, length)
+}
+
+func (n *NEONOperations) PopCountAnd(a, b unsafe.Pointer, length int) int {
+	return arm64.PopCountAnd(a, b, length)
+}
+
+func (n *NEONOperations) PopCountOr(a, b unsafe.Pointer, length int) int {
+	return arm64.PopCountOr(a, b, length)
+}
@@ -0,0 +1,8 @@
+//go:build !arm64 || purego
+
+package simd
+
+// NEONOperations stands in for the NEON kernels in builds without them
+// (non-arm64 or purego) and runs the scalar fallback. HasNEON reports false,
+// so Get never returns it.
+type NEONOperations struct{ FallbackOperations }
@@ -0,0 +1,24 @@
+package simd
+
+import "sync/atomic"
+
+// ProbeWords answers a batch of bit probes: masks[i] is replaced with the bits
+// of masks[i] that are clear in words[idx[i]], so a probe whose bits are all set
+// leaves zero. idx and masks must have the same length and every index must be
+// below len(words); the AVX2 gather does not bounds-check.
+//
+// On AVX2 the words are fetched 8 probes per iteration with VPGATHERQQ. Probes
+// may come in any order; the gather does not need them sorted.
+func ProbeWords(words []uint64, idx, masks []uint64) {
+	masks = masks[:len(idx)]
+	n := probeWordsKernel(words, idx, masks)
+	FallbackProbeWords(words, idx[n:], masks[n:])
+}
+
+// FallbackProbeWords is ProbeWords using one atomic load per probe
+func FallbackProbeWords(words []uint64, idx, masks []uint64) {
+	masks = masks[:len(idx)]
+	for i, w := range idx {
+		masks[i] &^= atomic.LoadUint64(&words[w])
+	}
+}
@@ -1,11 +1,6 @@
 package simd
 
-import (
-	"runtime"
-	"unsafe"
-
-	"github.com/shaia/BloomFilter/internal/simd/amd64"
-)
+import "unsafe"
 
 // Operations defines the interface for SIMD operations
 // This allows us to support different SIMD instruction sets (NEON, AVX2, AVX512)
@@ -13,7 +8,14 @@ type Operations interface {
 	PopCount(data unsafe.Pointer, length int) int
 	VectorOr(dst, src unsafe.Pointer, length int)
 	VectorAnd(dst, src unsafe.Pointer, length int)
+	VectorXor(dst, src unsafe.Pointer, length int)
+	// VectorAndNot sets dst to dst &^ src, the bits of dst not set in src
+	VectorAndNot(dst, src unsafe.Pointer, length int)
 	VectorClear(data unsafe.Pointer, length int)
+	VectorEqual(a, b unsafe.Pointer, length int) bool
+	VectorCopy(dst, src unsafe.Pointer, length int)
+	PopCountAnd(a, b unsafe.Pointer, length int) int
+	PopCountOr(a, b unsafe.Pointer, length int) int
 }
 
 // Get returns the best available SIMD implementation
@@ -57,25 +59,7 @@ var (
 )
 
 func init() {
+	// detectCapabilities is per platform: simd_amd64.go, simd_arm64.go, and
+	// simd_generic.go for other architectures and purego builds
 	detectCapabilities()
 }
-
-// detectCapabilities detects available SIMD instruction sets using runtime CPU feature detection
-func detectCapabilities() {
-	switch runtime.GOARCH {
-	case "amd64":
-		// Use CPUID-based detection for AVX2 (implemented in assembly)
-		hasAVX2 = amd64.HasAVX2()
-		// AVX512 detection would go here (not yet implemented)
-		hasAVX512 = false
-	case "arm64":
-		// ARM64 has NEON by default as part of the ARMv8 specification
-		// All ARM64 CPUs are required to support NEON
-		hasNEON = true
-	default:
-		// No SIMD support on other architectures, will use optimized scalar fallback
-		hasAVX2 = false
-		hasAVX512 = false
-		hasNEON = false
-	}
-}
@@ -0,0 +1,56 @@
+//go:build !purego
+
+package simd
+
+import (
+	"unsafe"
+
+	"github.com/shaia/BloomFilter/internal/simd/amd64"
+)
+
+// detectCapabilities uses CPUID-based detection implemented in assembly
+func detectCapabilities() {
+	hasAVX2 = amd64.HasAVX2()
+	// AVX-512 needs Foundation and VPOPCNTDQ in CPUID plus OS support for
+	// the ZMM state in XCR0; the kernels also use AVX2 for compare and copy
+	hasAVX512 = hasAVX2 && amd64.HasAVX512()
+}
+
+// hashUint64Kernel hashes the longest prefix of keys whose length is a multiple
+// of 8 with AVX2 and returns its length
+func hashUint64Kernel(keys []uint64, seed uint64, h1, h2 []uint64) int {
+	if !hasAVX2 {
+		return 0
+	}
+	n := len(keys) &^ 7
+	if n > 0 {
+		amd64.HashUint64(unsafe.Pointer(&keys[0]), unsafe.Pointer(&h1[0]), unsafe.Pointer(&h2[0]), n, seed)
+	}
+	return n
+}
+
+// hash128Uint64Kernel hashes the longest prefix of keys whose length is a
+// multiple of 4 with AVX2 and returns its length
+func hash128Uint64Kernel(keys []uint64, seed uint64, h1, h2 []uint64) int {
+	if !hasAVX2 {
+		return 0
+	}
+	n := len(keys) &^ 3
+	if n > 0 {
+		amd64.Hash128Uint64(unsafe.Pointer(&keys[0]), unsafe.Pointer(&h1[0]), unsafe.Pointer(&h2[0]), n, seed)
+	}
+	return n
+}
+
+// probeWordsKernel answers the longest prefix of probes whose length is a
+// multiple of 8 with the AVX2 gather and returns its length
+func probeWordsKernel(words, idx, masks []uint64) int {
+	if !hasAVX2 {
+		return 0
+	}
+	n := len(idx) &^ 7
+	if n > 0 {
+		amd64.ProbeWords(unsafe.Pointer(&words[0]), unsafe.Pointer(&idx[0]), unsafe.Pointer(&masks[0]), n)
+	}
+	return n
+}
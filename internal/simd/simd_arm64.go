@@ -0,0 +1,25 @@
+//go:build !purego
+
+package simd
+
+func detectCapabilities() {
+	// ARM64 has NEON by default as part of the ARMv8 specification
+	// All ARM64 CPUs are required to support NEON
+	hasNEON = true
+}
+
+// hashUint64Kernel hashes no keys: NEON has no 64-bit lane multiply (see
+// HashUint64Batch)
+func hashUint64Kernel(keys []uint64, seed uint64, h1, h2 []uint64) int {
+	return 0
+}
+
+// hash128Uint64Kernel hashes no keys, for the same reason
+func hash128Uint64Kernel(keys []uint64, seed uint64, h1, h2 []uint64) int {
+	return 0
+}
+
+// probeWordsKernel answers no probes: NEON has no gather
+func probeWordsKernel(words, idx, masks []uint64) int {
+	return 0
+}
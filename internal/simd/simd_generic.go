@@ -0,0 +1,21 @@
+//go:build purego || (!amd64 && !arm64)
+
+package simd
+
+// Builds with the purego tag, and architectures without SIMD kernels, link no
+// assembly: every capability is false, Get returns FallbackOperations and the
+// batch kernels leave all work to the scalar code.
+
+func detectCapabilities() {}
+
+func hashUint64Kernel(keys []uint64, seed uint64, h1, h2 []uint64) int {
+	return 0
+}
+
+func hash128Uint64Kernel(keys []uint64, seed uint64, h1, h2 []uint64) int {
+	return 0
+}
+
+func probeWordsKernel(words, idx, masks []uint64) int {
+	return 0
+}
@@ -0,0 +1,82 @@
+package bloomfilter
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"sync/atomic"
+)
+
+// JVM interop
+//
+// java.util.BitSet.toLongArray() and Guava's LockFreeBitArray store bit i in
+// long i/64 at (1L << (i % 64)), which is the same in-word bit order as Words().
+// JVM consumers typically serialize those arrays with DataOutputStream.writeLong,
+// i.e. each long big-endian (most significant byte first). The functions below
+// produce and consume exactly that byte layout, so a JVM reader can rebuild the
+// bit array with BitSet.valueOf(longs) without bit-reversing anything.
+//
+// Only the bit layout is shared: a JVM reader must use this package's hash
+// positions to query the bits.
+
+// AppendJavaLongs appends the bit array to dst as big-endian 64-bit words
+func (bf *CacheOptimizedBloomFilter) AppendJavaLongs(dst []byte) []byte {
+	for i := range bf.cacheLines {
+		line := &bf.cacheLines[i]
+		for j := range line.words {
+			dst = binary.BigEndian.AppendUint64(dst, atomic.LoadUint64(&line.words[j]))
+		}
+	}
+	return dst
+}
+
+// WriteJavaLongs writes the bit array to w as big-endian 64-bit words, one cache
+// line at a time, without materializing the whole array. It returns the number of
+// bytes written.
+func (bf *CacheOptimizedBloomFilter) WriteJavaLongs(w io.Writer) (int64, error) {
+	var buf [CacheLineSize]byte
+	var written int64
+	for i := range bf.cacheLines {
+		line := &bf.cacheLines[i]
+		for j := range line.words {
+			binary.BigEndian.PutUint64(buf[j*8:], atomic.LoadUint64(&line.words[j]))
+		}
+		n, err := w.Write(buf[:])
+		written += int64(n)
+		if err != nil {
+			return written, err
+		}
+	}
+	return written, nil
+}
+
+// NewFromJavaLongs creates a filter from big-endian 64-bit words as written by
+// WriteJavaLongs (or by DataOutputStream.writeLong over BitSet.toLongArray()).
+// Like NewFromWords, it uses seed 0 (see NewFromJavaLongsWithSeed), and
+// DefaultProbeScheme and DefaultHashVersion unless changed with SetProbeScheme
+// and SetHashVersion.
+//
+// Returns an error if data is not a whole number of cache lines or hashCount is 0.
+func NewFromJavaLongs(data []byte, hashCount uint32) (*CacheOptimizedBloomFilter, error) {
+	return NewFromJavaLongsWithSeed(data, hashCount, 0)
+}
+
+// NewFromJavaLongsWithSeed is NewFromJavaLongs for a filter built with a hash seed
+func NewFromJavaLongsWithSeed(data []byte, hashCount uint32, seed uint64) (*CacheOptimizedBloomFilter, error) {
+	if len(data) == 0 || len(data)%CacheLineSize != 0 {
+		return nil, fmt.Errorf("bloomfilter: data length must be a positive multiple of %d bytes, got %d", CacheLineSize, len(data))
+	}
+	if hashCount == 0 {
+		return nil, fmt.Errorf("bloomfilter: hashCount must be greater than 0")
+	}
+
+	bf := newFilter(uint64(len(data)/CacheLineSize), hashCount)
+	for i := range bf.cacheLines {
+		line := &bf.cacheLines[i]
+		for j := range line.words {
+			line.words[j] = binary.BigEndian.Uint64(data[i*CacheLineSize+j*8:])
+		}
+	}
+	bf.seed = seed
+	return bf, nil
+}
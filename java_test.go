@@ -0,0 +1,61 @@
+package bloomfilter
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"testing"
+)
+
+// TestJavaLongsLayout tests the big-endian word layout expected by JVM consumers
+func TestJavaLongsLayout(t *testing.T) {
+	bf := NewCacheOptimizedBloomFilter(100, 0.01)
+	// Set bit 0 and bit 65 directly: long[0] = 1L, long[1] = 1L << 1
+	bf.setBitsAtomic([]uint64{0, 65})
+
+	data := bf.AppendJavaLongs(nil)
+	if uint64(len(data)) != bf.BitCount()/8 {
+		t.Fatalf("Expected %d bytes, got %d", bf.BitCount()/8, len(data))
+	}
+	if got := binary.BigEndian.Uint64(data[0:]); got != 1 {
+		t.Errorf("long[0] = %#x, expected 0x1", got)
+	}
+	if got := binary.BigEndian.Uint64(data[8:]); got != 2 {
+		t.Errorf("long[1] = %#x, expected 0x2", got)
+	}
+	// Big-endian: the least significant byte of long[0] is the 8th byte
+	if data[7] != 1 || data[0] != 0 {
+		t.Errorf("Expected big-endian byte order, got % x", data[:8])
+	}
+}
+
+// TestJavaLongsRoundTrip tests that streaming and appending agree and round-trip
+func TestJavaLongsRoundTrip(t *testing.T) {
+	bf, _ := NewWithOptions(WithExpectedElements(1000), WithSeed(0)) // the seed NewFromJavaLongs assumes
+	for i := 0; i < 500; i++ {
+		bf.AddString(fmt.Sprintf("key_%d", i))
+	}
+
+	var buf bytes.Buffer
+	n, err := bf.WriteJavaLongs(&buf)
+	if err != nil {
+		t.Fatalf("WriteJavaLongs failed: %v", err)
+	}
+	if n != int64(buf.Len()) || !bytes.Equal(buf.Bytes(), bf.AppendJavaLongs(nil)) {
+		t.Fatal("WriteJavaLongs and AppendJavaLongs disagree")
+	}
+
+	restored, err := NewFromJavaLongs(buf.Bytes(), bf.HashCount())
+	if err != nil {
+		t.Fatalf("NewFromJavaLongs failed: %v", err)
+	}
+	for i := 0; i < 500; i++ {
+		if !restored.ContainsString(fmt.Sprintf("key_%d", i)) {
+			t.Fatalf("Restored filter is missing key_%d", i)
+		}
+	}
+
+	if _, err := NewFromJavaLongs(make([]byte, 63), 3); err == nil {
+		t.Error("Expected error for partial cache line")
+	}
+}
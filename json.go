@@ -0,0 +1,204 @@
+package bloomfilter
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"hash/crc32"
+	"math"
+	"sync/atomic"
+)
+
+// JSON format, for embedding filters in config payloads and API responses:
+//
+//	{"version":3,"bit_count":1024,"hash_count":7,"seed":"42","probe":"enhanced",
+//	 "hash":"v2","encoding":"zrle","bits":"<base64>","crc32":1234}
+//
+// bits is the bit array as little-endian words (the byte layout of the native
+// format), base64 encoded (standard alphabet, padded). With encoding "raw" it
+// is the bytes themselves; with "zrle" it is a sequence of (zero run, literal
+// length, literal bytes) triples with uvarint lengths, which shrinks sparse
+// filters. MarshalJSON picks whichever is shorter. seed is a decimal string,
+// as it may exceed 2^53, and is omitted when 0. crc32 (IEEE) covers the
+// decoded bytes. probe names the ProbeScheme; filters using DoubleHashing are
+// written as version 1, which has no probe field, so readers that predate the
+// field still accept them and reject the encodings they would misread. hash
+// names the HashVersion in the same way: only HashV2 filters are written as
+// version 3, and versions 1 and 2 decode as HashV1.
+const (
+	jsonFormatVersion = 3
+	jsonProbeVersion  = 2
+	jsonEncodingRaw   = "raw"
+	jsonEncodingZRLE  = "zrle"
+	// zrleMinRun is the shortest zero run worth ending a literal for
+	zrleMinRun = 4
+)
+
+type jsonFilter struct {
+	Version   int    `json:"version"`
+	BitCount  uint64 `json:"bit_count"`
+	HashCount uint32 `json:"hash_count"`
+	Seed      uint64 `json:"seed,string,omitempty"`
+	Probe     string `json:"probe,omitempty"`
+	Hash      string `json:"hash,omitempty"`
+	Encoding  string `json:"encoding"`
+	Bits      string `json:"bits"`
+	CRC32     uint32 `json:"crc32"`
+}
+
+// MarshalJSON encodes the filter's bits, geometry, seed, probe scheme and hash
+// version as JSON (implements
+// json.Marshaler). Like AppendBinary, optional features are not included.
+func (bf *CacheOptimizedBloomFilter) MarshalJSON() ([]byte, error) {
+	raw := make([]byte, 0, bf.cacheLineCount*CacheLineSize)
+	for i := range bf.cacheLines {
+		line := &bf.cacheLines[i]
+		for j := range line.words {
+			raw = binary.LittleEndian.AppendUint64(raw, atomic.LoadUint64(&line.words[j]))
+		}
+	}
+
+	bits, enc := raw, jsonEncodingRaw
+	if z := appendZRLE(nil, raw); len(z) < len(raw) {
+		bits, enc = z, jsonEncodingZRLE
+	}
+	j := jsonFilter{
+		Version:   1,
+		BitCount:  bf.bitCount,
+		HashCount: bf.hashCount,
+		Seed:      bf.seed,
+		Encoding:  enc,
+		Bits:      base64.StdEncoding.EncodeToString(bits),
+		CRC32:     crc32.ChecksumIEEE(raw),
+	}
+	if bf.probe != DoubleHashing {
+		j.Version, j.Probe = jsonProbeVersion, bf.probe.String()
+	}
+	if bf.hashVersion != HashV1 {
+		j.Version, j.Probe, j.Hash = jsonFormatVersion, bf.probe.String(), bf.hashVersion.String()
+	}
+	return json.Marshal(j)
+}
+
+// UnmarshalJSON replaces the filter with one encoded by MarshalJSON (implements
+// json.Unmarshaler), with the semantics of UnmarshalBinary. Malformed input
+// returns an error wrapping ErrInvalidEncoding.
+func (bf *CacheOptimizedBloomFilter) UnmarshalJSON(data []byte) error {
+	var j jsonFilter
+	if err := json.Unmarshal(data, &j); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidEncoding, err)
+	}
+	probe, version := DoubleHashing, HashV1
+	switch j.Version {
+	case 1:
+	case jsonProbeVersion, jsonFormatVersion:
+		var err error
+		if probe, err = ParseProbeScheme(j.Probe); err != nil {
+			return fmt.Errorf("%w: %w", ErrInvalidEncoding, err)
+		}
+		if j.Version == jsonFormatVersion {
+			if version, err = ParseHashVersion(j.Hash); err != nil {
+				return fmt.Errorf("%w: %w", ErrInvalidEncoding, err)
+			}
+		}
+	default:
+		return fmt.Errorf("%w: unsupported JSON version %d", ErrInvalidEncoding, j.Version)
+	}
+	if j.HashCount == 0 {
+		return fmt.Errorf("%w: hash_count is 0", ErrInvalidEncoding)
+	}
+	cacheLineCount := j.BitCount / BitsPerCacheLine
+	if cacheLineCount == 0 || cacheLineCount > math.MaxInt/CacheLineSize || j.BitCount%BitsPerCacheLine != 0 {
+		return fmt.Errorf("%w: bit_count %d is not a positive multiple of %d", ErrInvalidEncoding, j.BitCount, BitsPerCacheLine)
+	}
+
+	bits, err := base64.StdEncoding.DecodeString(j.Bits)
+	if err != nil {
+		return fmt.Errorf("%w: bits: %w", ErrInvalidEncoding, err)
+	}
+	size := int(cacheLineCount * CacheLineSize)
+	switch j.Encoding {
+	case jsonEncodingRaw:
+	case jsonEncodingZRLE:
+		if bits, err = decodeZRLE(bits, size); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("%w: unknown bits encoding %q", ErrInvalidEncoding, j.Encoding)
+	}
+	if len(bits) != size {
+		return fmt.Errorf("%w: %d bytes of bits for bit_count %d", ErrCorruptData, len(bits), j.BitCount)
+	}
+	if crc32.ChecksumIEEE(bits) != j.CRC32 {
+		return fmt.Errorf("%w: checksum mismatch", ErrCorruptData)
+	}
+
+	decoded := newFilter(cacheLineCount, j.HashCount)
+	decoded.seed = j.Seed
+	decoded.probe = probe
+	decoded.hashVersion = version
+	for i := range decoded.cacheLines {
+		for w := range decoded.cacheLines[i].words {
+			decoded.cacheLines[i].words[w] = binary.LittleEndian.Uint64(bits[(i*WordsPerCacheLine+w)*8:])
+		}
+	}
+	bf.replaceWith(decoded)
+	return nil
+}
+
+// appendZRLE appends the zero run-length encoding of src to dst
+func appendZRLE(dst, src []byte) []byte {
+	for i := 0; i < len(src); {
+		zeros := i
+		for zeros < len(src) && src[zeros] == 0 {
+			zeros++
+		}
+		// The literal runs until the next zero run long enough to encode
+		end := zeros
+		for end < len(src) {
+			run := end
+			for run < len(src) && run-end < zrleMinRun && src[run] == 0 {
+				run++
+			}
+			if run-end == zrleMinRun || run == len(src) {
+				break
+			}
+			end = run + 1
+		}
+		dst = binary.AppendUvarint(dst, uint64(zeros-i))
+		dst = binary.AppendUvarint(dst, uint64(end-zeros))
+		dst = append(dst, src[zeros:end]...)
+		i = end
+	}
+	return dst
+}
+
+// decodeZRLE decodes a zero run-length encoding of at most size bytes
+func decodeZRLE(src []byte, size int) ([]byte, error) {
+	out := make([]byte, 0, size)
+	for len(src) > 0 {
+		zeros, n := binary.Uvarint(src)
+		if n <= 0 {
+			return nil, fmt.Errorf("%w: truncated zero run", ErrCorruptData)
+		}
+		src = src[n:]
+		literal, n := binary.Uvarint(src)
+		if n <= 0 {
+			return nil, fmt.Errorf("%w: truncated literal length", ErrCorruptData)
+		}
+		src = src[n:]
+		if zeros > uint64(size-len(out)) || literal > uint64(size-len(out))-zeros || literal > uint64(len(src)) {
+			return nil, fmt.Errorf("%w: run exceeds the bit array", ErrCorruptData)
+		}
+		out = out[:len(out)+int(zeros)] // capacity beyond the length is already zero
+		out = append(out, src[:literal]...)
+		src = src[literal:]
+	}
+	return out, nil
+}
+
+var (
+	_ json.Marshaler   = (*CacheOptimizedBloomFilter)(nil)
+	_ json.Unmarshaler = (*CacheOptimizedBloomFilter)(nil)
+)
@@ -0,0 +1,164 @@
+package bloomfilter
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// TestJSONRoundTrip tests that filters of every density survive JSON and pick
+// the shorter bits encoding
+func TestJSONRoundTrip(t *testing.T) {
+	for _, tt := range []struct {
+		keys     int
+		encoding string
+	}{
+		{0, jsonEncodingZRLE},
+		{10, jsonEncodingZRLE},
+		{1000, jsonEncodingRaw},
+		{100000, jsonEncodingRaw}, // saturated
+	} {
+		t.Run(fmt.Sprintf("Keys_%d", tt.keys), func(t *testing.T) {
+			bf, _ := NewWithOptions(WithExpectedElements(1000), WithSeed(1<<60))
+			for i := 0; i < tt.keys; i++ {
+				bf.AddUint64(uint64(i))
+			}
+
+			data, err := json.Marshal(bf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var j jsonFilter
+			if err := json.Unmarshal(data, &j); err != nil {
+				t.Fatal(err)
+			}
+			if j.Encoding != tt.encoding {
+				t.Errorf("Encoding %q, want %q", j.Encoding, tt.encoding)
+			}
+			if !strings.Contains(string(data), `"seed":"1152921504606846976"`) {
+				t.Errorf("Seed must be a decimal string: %s", data[:min(len(data), 120)])
+			}
+
+			var restored CacheOptimizedBloomFilter
+			if err := json.Unmarshal(data, &restored); err != nil {
+				t.Fatal(err)
+			}
+			if !restored.Equal(bf) {
+				t.Error("Restored filter differs")
+			}
+		})
+	}
+}
+
+// TestJSONEmbedded tests a filter inside a larger payload
+func TestJSONEmbedded(t *testing.T) {
+	type config struct {
+		Name    string                     `json:"name"`
+		Blocked *CacheOptimizedBloomFilter `json:"blocked"`
+	}
+	in := config{Name: "edge", Blocked: NewCacheOptimizedBloomFilter(10000, 0.01)}
+	in.Blocked.AddString("10.0.0.1")
+
+	data, err := json.Marshal(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A nearly empty filter compresses to a fraction of its 12 KB bit array
+	if len(data) > 250 {
+		t.Errorf("Sparse filter encoded in %d bytes", len(data))
+	}
+	var out config
+	if err := json.Unmarshal(data, &out); err != nil {
+		t.Fatal(err)
+	}
+	if out.Name != "edge" || !out.Blocked.ContainsString("10.0.0.1") || !out.Blocked.Equal(in.Blocked) {
+		t.Error("Embedded filter not restored")
+	}
+}
+
+// TestJSONRejectsCorruption tests that malformed payloads wrap ErrInvalidEncoding
+// and leave the receiver unchanged
+func TestJSONRejectsCorruption(t *testing.T) {
+	bf := NewCacheOptimizedBloomFilter(1000, 0.01)
+	bf.AddString("key")
+	data, _ := json.Marshal(bf)
+	var valid jsonFilter
+	json.Unmarshal(data, &valid)
+
+	corrupt := func(edit func(j *jsonFilter)) []byte {
+		j := valid
+		edit(&j)
+		out, _ := json.Marshal(j)
+		return out
+	}
+	cases := map[string][]byte{
+		"NotJSON":     []byte(`{"version":`),
+		"Version":     corrupt(func(j *jsonFilter) { j.Version = jsonFormatVersion + 1 }),
+		"Probe":       corrupt(func(j *jsonFilter) { j.Probe = "triple" }),
+		"Hash":        corrupt(func(j *jsonFilter) { j.Hash = "v9" }),
+		"HashCount":   corrupt(func(j *jsonFilter) { j.HashCount = 0 }),
+		"BitCount":    corrupt(func(j *jsonFilter) { j.BitCount = 100 }),
+		"WrongSize":   corrupt(func(j *jsonFilter) { j.BitCount *= 2 }),
+		"Encoding":    corrupt(func(j *jsonFilter) { j.Encoding = "gzip" }),
+		"Base64":      corrupt(func(j *jsonFilter) { j.Bits = "!!" }),
+		"Checksum":    corrupt(func(j *jsonFilter) { j.CRC32++ }),
+		"RunOverflow": corrupt(func(j *jsonFilter) { j.Bits = base64.StdEncoding.EncodeToString([]byte{0xff, 0xff, 0x7f, 0}) }),
+		"Truncated":   corrupt(func(j *jsonFilter) { j.Bits = base64.StdEncoding.EncodeToString([]byte{0x80}) }),
+	}
+	for name, data := range cases {
+		t.Run(name, func(t *testing.T) {
+			target := NewCacheOptimizedBloomFilter(100, 0.01)
+			target.AddString("kept")
+			if err := target.UnmarshalJSON(data); !errors.Is(err, ErrInvalidEncoding) {
+				t.Errorf("Expected ErrInvalidEncoding, got %v", err)
+			}
+			if !target.ContainsString("kept") {
+				t.Error("Failed decode modified the receiver")
+			}
+		})
+	}
+}
+
+// TestZRLE tests the run-length coder on edge-case inputs
+func TestZRLE(t *testing.T) {
+	for _, src := range [][]byte{
+		{},
+		{0, 0, 0},
+		{1, 2, 3},
+		{0, 0, 0, 0, 0, 1, 0, 0, 1, 0, 0, 0, 0, 2, 0},
+		{1, 0, 0, 0, 0},
+		bytes.Repeat([]byte{0, 0, 0, 0, 0, 0, 0, 7}, 100),
+	} {
+		got, err := decodeZRLE(appendZRLE(nil, src), len(src))
+		if err != nil || !bytes.Equal(got, src) {
+			t.Errorf("Round trip of %v: got %v, %v", src, got, err)
+		}
+	}
+}
+
+// TestGobRoundTrip tests that gob encodes filters through MarshalBinary
+func TestGobRoundTrip(t *testing.T) {
+	type payload struct {
+		Filter *CacheOptimizedBloomFilter
+		Note   string
+	}
+	bf, _ := NewWithOptions(WithExpectedElements(1000), WithSeed(9))
+	bf.AddString("gob")
+
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(payload{Filter: bf, Note: "n"}); err != nil {
+		t.Fatal(err)
+	}
+	var out payload
+	if err := gob.NewDecoder(&buf).Decode(&out); err != nil {
+		t.Fatal(err)
+	}
+	if out.Note != "n" || !out.Filter.Equal(bf) || !out.Filter.ContainsString("gob") {
+		t.Error("Gob round trip lost the filter")
+	}
+}
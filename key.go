@@ -0,0 +1,68 @@
+package bloomfilter
+
+// Key is a key hashed once for use with many filters, for example to check a
+// key against every shard of a partitioned filter without hashing the same
+// bytes again for each. It keeps a reference to the data, which must not be
+// modified while the Key is in use.
+//
+// The hashes are computed with DefaultHashVersion and are only valid for
+// filters of that version and of the seed the Key was computed with. Other
+// filters, and filters with a custom hasher (see WithHasher), hash the data
+// again, so a Key works with any filter but only saves the hashing when the
+// seed and version match.
+type Key struct {
+	data    []byte
+	h1, h2  uint64
+	seed    uint64
+	version HashVersion
+}
+
+// PrecomputeKey hashes data for filters built WithSeed(0)
+func PrecomputeKey(data []byte) Key {
+	return PrecomputeKeyWithSeed(data, 0)
+}
+
+// PrecomputeKeyWithSeed is PrecomputeKey for filters built with a hash seed
+func PrecomputeKeyWithSeed(data []byte, seed uint64) Key {
+	h1, h2 := DefaultHashVersion.hash(data, seed)
+	return Key{data: data, h1: h1, h2: h2, seed: seed, version: DefaultHashVersion}
+}
+
+// PrecomputeStringKey hashes s for filters built WithSeed(0)
+func PrecomputeStringKey(s string) Key {
+	return PrecomputeKey(stringBytes(s))
+}
+
+// Bytes returns the key's data
+func (k Key) Bytes() []byte {
+	return k.data
+}
+
+// Seed returns the seed the key was hashed with
+func (k Key) Seed() uint64 {
+	return k.seed
+}
+
+// AddKey adds a precomputed key and is equivalent to Add(k.Bytes())
+func (bf *CacheOptimizedBloomFilter) AddKey(k Key) {
+	if bf.overloaded() {
+		bf.dropped.Add(1)
+		return
+	}
+	h1, h2 := bf.keyHashes(k)
+	bf.addHashed(k.data, h1, h2, false)
+}
+
+// ContainsKey checks a precomputed key and is equivalent to Contains(k.Bytes())
+func (bf *CacheOptimizedBloomFilter) ContainsKey(k Key) bool {
+	h1, h2 := bf.keyHashes(k)
+	return bf.containsHashed(k.data, h1, h2)
+}
+
+// keyHashes returns the base hashes of k under the filter's seed
+func (bf *CacheOptimizedBloomFilter) keyHashes(k Key) (uint64, uint64) {
+	if k.seed == bf.seed && k.version == bf.hashVersion && bf.hasher == nil {
+		return k.h1, k.h2
+	}
+	return bf.hashKey(k.data)
+}
@@ -0,0 +1,75 @@
+package bloomfilter
+
+import (
+	"fmt"
+	"testing"
+)
+
+// TestPrecomputedKey tests that AddKey and ContainsKey match Add and Contains
+// across filters of different seeds, sizes and probe schemes
+func TestPrecomputedKey(t *testing.T) {
+	shards := make([]*CacheOptimizedBloomFilter, 8)
+	plain := make([]*CacheOptimizedBloomFilter, len(shards))
+	for i := range shards {
+		opts := []Option{WithExpectedElements(uint64(500 * (i + 1))), WithSeed(uint64(i % 2))}
+		if i%4 == 3 {
+			opts = append(opts, WithProbeScheme(DoubleHashing))
+		}
+		shards[i], _ = NewWithOptions(opts...)
+		plain[i], _ = NewWithOptions(opts...)
+	}
+
+	for i := 0; i < 1000; i++ {
+		key := []byte(fmt.Sprintf("key-%d", i))
+		k := PrecomputeKey(key)
+		shards[i%len(shards)].AddKey(k)
+		plain[i%len(shards)].Add(key)
+	}
+	for i := range shards {
+		if !shards[i].Equal(plain[i]) {
+			t.Errorf("Shard %d: AddKey set different bits than Add", i)
+		}
+	}
+
+	for i := 0; i < 2000; i++ {
+		key := []byte(fmt.Sprintf("key-%d", i))
+		k := PrecomputeKey(key)
+		for j, bf := range shards {
+			if got, want := bf.ContainsKey(k), bf.Contains(key); got != want {
+				t.Fatalf("Shard %d: ContainsKey(%s) = %t, Contains = %t", j, key, got, want)
+			}
+		}
+	}
+}
+
+// TestPrecomputedKeySeeded tests keys hashed for a seed, and their reuse with
+// filters of another seed
+func TestPrecomputedKeySeeded(t *testing.T) {
+	seeded, _ := NewWithOptions(WithExpectedElements(1000), WithSeed(42))
+	unseeded := NewCacheOptimizedBloomFilter(1000, 0.01)
+
+	k := PrecomputeKeyWithSeed([]byte("hello"), 42)
+	if k.Seed() != 42 || string(k.Bytes()) != "hello" {
+		t.Errorf("Key reports seed %d and data %q", k.Seed(), k.Bytes())
+	}
+	seeded.AddKey(k)
+	unseeded.AddKey(k)
+	if !seeded.ContainsString("hello") || !unseeded.ContainsString("hello") {
+		t.Error("AddKey did not add the key under the filter's own seed")
+	}
+	if !seeded.ContainsKey(PrecomputeStringKey("hello")) {
+		t.Error("ContainsKey missed a key hashed for another seed")
+	}
+}
+
+// TestPrecomputedKeyAllocations tests that keyed operations do not allocate
+func TestPrecomputedKeyAllocations(t *testing.T) {
+	bf := NewCacheOptimizedBloomFilter(10000, 0.01)
+	k := PrecomputeStringKey("key")
+	if allocs := testing.AllocsPerRun(100, func() {
+		bf.AddKey(k)
+		bf.ContainsKey(k)
+	}); allocs != 0 {
+		t.Errorf("AddKey and ContainsKey allocated %.0f times", allocs)
+	}
+}
@@ -0,0 +1,381 @@
+package bloomfilter
+
+import (
+	"bufio"
+	"cmp"
+	"compress/flate"
+	"compress/gzip"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"io/fs"
+	"iter"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// FileKeyLogConfig configures a FileKeyLog
+type FileKeyLogConfig struct {
+	// Dir holds the log's segments, keys-N.log, or keys-N.log.gz once compressed
+	Dir string
+	// SegmentSize is the size in bytes past which Record starts a new segment.
+	// Defaults to 64 MiB.
+	SegmentSize int64
+	// Compress gzips each segment in the background once a newer one is started
+	Compress bool
+	// Sync syncs every record to disk before Record returns
+	Sync bool
+}
+
+// defaultSegmentSize is the default FileKeyLogConfig.SegmentSize, 64 MiB
+const defaultSegmentSize = 64 << 20
+
+// FileKeyLog is an on-disk KeyRecorder: an append-only log of every key added
+// to a filter, so the filter can be rebuilt exactly with other parameters
+// (Rebuild, Grow, BuildFromSeq) or replayed after its own file was damaged.
+// Keys are appended to the newest of a series of segment files, each record
+// with its own checksum; older segments can be compressed, which typically
+// halves text keys.
+//
+// Record, Keys and Rotate are safe for concurrent use. Keys sees the records
+// written before it was called.
+type FileKeyLog struct {
+	cfg FileKeyLogConfig
+
+	mu     sync.Mutex
+	active *os.File
+	seq    uint64 // Sequence number of the active segment
+	size   int64  // Bytes written to the active segment
+	buf    []byte
+	closed bool
+
+	compressing sync.WaitGroup
+	errMu       sync.Mutex
+	compressErr error
+}
+
+// OpenFileKeyLog opens the key log in cfg.Dir, creating the directory if
+// needed, and starts a new segment after the existing ones.
+// Returns an error if SegmentSize is negative or the directory or the new
+// segment cannot be created.
+func OpenFileKeyLog(cfg FileKeyLogConfig) (*FileKeyLog, error) {
+	if cfg.Dir == "" {
+		return nil, fmt.Errorf("bloomfilter: file key log requires a Dir")
+	}
+	if cfg.SegmentSize < 0 {
+		return nil, fmt.Errorf("bloomfilter: SegmentSize must not be negative, got %d", cfg.SegmentSize)
+	}
+	if cfg.SegmentSize == 0 {
+		cfg.SegmentSize = defaultSegmentSize
+	}
+	if err := os.MkdirAll(cfg.Dir, 0o755); err != nil {
+		return nil, err
+	}
+	segments, err := keyLogSegments(cfg.Dir)
+	if err != nil {
+		return nil, err
+	}
+
+	l := &FileKeyLog{cfg: cfg}
+	for _, s := range segments {
+		l.seq = s.seq
+		if cfg.Compress && !s.compressed {
+			l.compress(s.seq)
+		}
+	}
+	l.seq++
+	if l.active, err = l.create(l.seq); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// Record appends data to the log, starting a new segment first if the active
+// one is full
+func (l *FileKeyLog) Record(data []byte) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.closed {
+		return fmt.Errorf("bloomfilter: key log is closed")
+	}
+	if l.size >= l.cfg.SegmentSize {
+		if err := l.rotateLocked(); err != nil {
+			return err
+		}
+	}
+	l.buf = appendKeyRecord(l.buf[:0], data)
+	n, err := l.active.Write(l.buf)
+	l.size += int64(n)
+	if err != nil {
+		return err
+	}
+	if l.cfg.Sync {
+		return l.active.Sync()
+	}
+	return nil
+}
+
+// Rotate starts a new segment, as Record does when the active one reaches
+// SegmentSize, for callers that rotate on a schedule
+func (l *FileKeyLog) Rotate() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.closed {
+		return fmt.Errorf("bloomfilter: key log is closed")
+	}
+	return l.rotateLocked()
+}
+
+func (l *FileKeyLog) rotateLocked() error {
+	next, err := l.create(l.seq + 1)
+	if err != nil {
+		return err
+	}
+	if err := l.active.Close(); err != nil {
+		next.Close()
+		return err
+	}
+	if l.cfg.Compress {
+		l.compress(l.seq)
+	}
+	l.active, l.seq, l.size = next, l.seq+1, 0
+	return nil
+}
+
+func (l *FileKeyLog) create(seq uint64) (*os.File, error) {
+	return os.OpenFile(keyLogPath(l.cfg.Dir, seq, false), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+}
+
+// compress gzips segment seq in the background, replacing the plain file
+func (l *FileKeyLog) compress(seq uint64) {
+	l.compressing.Add(1)
+	go func() {
+		defer l.compressing.Done()
+		if err := compressKeyLogSegment(l.cfg.Dir, seq); err != nil {
+			l.errMu.Lock()
+			l.compressErr = err
+			l.errMu.Unlock()
+		}
+	}()
+}
+
+// compressKeyLogSegment writes keys-seq.log.gz atomically, then removes
+// keys-seq.log; after a crash in between, readers prefer the compressed file
+func compressKeyLogSegment(dir string, seq uint64) error {
+	plain := keyLogPath(dir, seq, false)
+	src, err := os.Open(plain)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	if err := writeFileAtomic(keyLogPath(dir, seq, true), func(w io.Writer) error {
+		zw := gzip.NewWriter(w)
+		if _, err := io.Copy(zw, src); err != nil {
+			return err
+		}
+		return zw.Close()
+	}); err != nil {
+		return err
+	}
+	return os.Remove(plain)
+}
+
+// Keys iterates over the keys recorded before the call, oldest first. The rest
+// of a damaged segment is skipped; Replay reports the damage instead.
+func (l *FileKeyLog) Keys() iter.Seq[[]byte] {
+	return func(yield func([]byte) bool) {
+		l.replay(func(key []byte) bool { return yield(key) }, true)
+	}
+}
+
+// Replay calls fn with every key recorded before the call, oldest first. It
+// returns an error wrapping ErrCorruptData for a damaged or truncated record,
+// such as one torn by a crash during a write, after passing the keys before it.
+func (l *FileKeyLog) Replay(fn func(key []byte)) error {
+	return l.replay(func(key []byte) bool { fn(key); return true }, false)
+}
+
+func (l *FileKeyLog) replay(fn func(key []byte) bool, skipDamage bool) error {
+	l.mu.Lock()
+	activeSeq, activeSize := l.seq, l.size
+	l.mu.Unlock()
+	segments, err := keyLogSegments(l.cfg.Dir)
+	if err != nil {
+		return err
+	}
+	stopped := errors.New("stopped")
+	for _, s := range segments {
+		if s.seq > activeSeq {
+			break
+		}
+		limit := int64(-1)
+		if s.seq == activeSeq {
+			limit = activeSize
+		}
+		err := readKeyLogSegment(l.cfg.Dir, s, limit, func(key []byte) error {
+			if !fn(key) {
+				return stopped
+			}
+			return nil
+		})
+		switch {
+		case err == stopped:
+			return nil
+		case errors.Is(err, ErrCorruptData) && skipDamage:
+		case err != nil:
+			return fmt.Errorf("segment %d: %w", s.seq, err)
+		}
+	}
+	return nil
+}
+
+// Close closes the active segment and waits for background compression. It
+// returns the first compression error, if any.
+func (l *FileKeyLog) Close() error {
+	l.mu.Lock()
+	var err error
+	if !l.closed {
+		l.closed = true
+		err = l.active.Close()
+	}
+	l.mu.Unlock()
+	l.compressing.Wait()
+	l.errMu.Lock()
+	defer l.errMu.Unlock()
+	if err == nil {
+		err = l.compressErr
+	}
+	return err
+}
+
+// keyLogSegment is a segment file found in a key log directory
+type keyLogSegment struct {
+	seq        uint64
+	compressed bool
+}
+
+// keyLogPath returns the path of segment seq
+func keyLogPath(dir string, seq uint64, compressed bool) string {
+	name := "keys-" + strconv.FormatUint(seq, 10) + ".log"
+	if compressed {
+		name += ".gz"
+	}
+	return filepath.Join(dir, name)
+}
+
+// keyLogSegments lists the segments in dir in order, a compressed file winning
+// over a plain one of the same number
+func keyLogSegments(dir string) ([]keyLogSegment, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	found := make(map[uint64]bool)
+	for _, e := range entries {
+		name, compressed := strings.CutSuffix(e.Name(), ".gz")
+		digits, ok := strings.CutPrefix(name, "keys-")
+		if !ok || !strings.HasSuffix(digits, ".log") {
+			continue
+		}
+		if seq, err := strconv.ParseUint(strings.TrimSuffix(digits, ".log"), 10, 64); err == nil {
+			found[seq] = found[seq] || compressed
+		}
+	}
+	segments := make([]keyLogSegment, 0, len(found))
+	for seq, compressed := range found {
+		segments = append(segments, keyLogSegment{seq, compressed})
+	}
+	slices.SortFunc(segments, func(a, b keyLogSegment) int { return cmp.Compare(a.seq, b.seq) })
+	return segments, nil
+}
+
+// readKeyLogSegment calls fn with the keys of a segment, reading at most limit
+// bytes of a plain segment if limit is not negative. A plain segment that was
+// compressed meanwhile is read from its compressed file.
+func readKeyLogSegment(dir string, s keyLogSegment, limit int64, fn func(key []byte) error) error {
+	f, err := os.Open(keyLogPath(dir, s.seq, s.compressed))
+	if errors.Is(err, fs.ErrNotExist) && !s.compressed {
+		s.compressed = true
+		f, err = os.Open(keyLogPath(dir, s.seq, true))
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var r io.Reader = bufio.NewReader(f)
+	if s.compressed {
+		zr, err := gzip.NewReader(r)
+		if err != nil {
+			return fmt.Errorf("%w: %w", ErrCorruptData, err)
+		}
+		r = zr
+	} else if limit >= 0 {
+		r = io.LimitReader(r, limit)
+	}
+	err = readKeyRecords(r, fn)
+	var flateErr flate.CorruptInputError
+	if errors.Is(err, gzip.ErrChecksum) || errors.Is(err, gzip.ErrHeader) || errors.As(err, &flateErr) {
+		return fmt.Errorf("%w: %w", ErrCorruptData, err)
+	}
+	return err
+}
+
+// Records of the key logs (FileKeyLog and the write-ahead log of
+// PersistentBloomFilter): length u32 | crc32c of the key u32 | key,
+// little-endian
+const keyRecordHeader = 8
+
+// appendKeyRecord appends the record of key to b
+func appendKeyRecord(b, key []byte) []byte {
+	b = binary.LittleEndian.AppendUint32(b, uint32(len(key)))
+	b = binary.LittleEndian.AppendUint32(b, crc32.Checksum(key, castagnoli))
+	return append(b, key...)
+}
+
+// readKeyRecords calls fn with each key read from r until EOF or until fn
+// returns an error, which it returns. A truncated or damaged record returns an
+// error wrapping ErrCorruptData. The key passed to fn is only valid during the
+// call.
+func readKeyRecords(r io.Reader, fn func(key []byte) error) error {
+	var header [keyRecordHeader]byte
+	var key []byte
+	for {
+		if _, err := io.ReadFull(r, header[:]); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			if err == io.ErrUnexpectedEOF {
+				return fmt.Errorf("%w: truncated key record", ErrCorruptData)
+			}
+			return err
+		}
+		size := binary.LittleEndian.Uint32(header[:])
+		// Read in chunks of at most 1 MiB, so a damaged length fails at the end
+		// of the data instead of forcing a huge allocation
+		key = key[:0]
+		for len(key) < int(size) {
+			chunk := min(int(size)-len(key), 1<<20)
+			key = slices.Grow(key, chunk)
+			if _, err := io.ReadFull(r, key[len(key):len(key)+chunk]); err != nil {
+				if err == io.EOF || err == io.ErrUnexpectedEOF {
+					return fmt.Errorf("%w: truncated key record", ErrCorruptData)
+				}
+				return err
+			}
+			key = key[:len(key)+chunk]
+		}
+		if crc32.Checksum(key, castagnoli) != binary.LittleEndian.Uint32(header[4:]) {
+			return fmt.Errorf("%w: key record checksum mismatch", ErrCorruptData)
+		}
+		if err := fn(key); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,165 @@
+package bloomfilter
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+// TestFileKeyLog tests recording across segments, compression and reopening
+func TestFileKeyLog(t *testing.T) {
+	dir := t.TempDir()
+	l, err := OpenFileKeyLog(FileKeyLogConfig{Dir: dir, SegmentSize: 1000, Compress: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	bf := NewCacheOptimizedBloomFilter(1000, 0.01)
+	for i := 0; i < 500; i++ {
+		key := []byte(fmt.Sprintf("key-%d", i))
+		if err := l.Record(key); err != nil {
+			t.Fatal(err)
+		}
+		bf.Add(key)
+	}
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Record([]byte("late")); err == nil {
+		t.Error("Record after Close succeeded")
+	}
+	compressed, _ := filepath.Glob(filepath.Join(dir, "keys-*.log.gz"))
+	if len(compressed) < 5 {
+		t.Errorf("%d compressed segments", len(compressed))
+	}
+
+	l, err = OpenFileKeyLog(FileKeyLogConfig{Dir: dir, SegmentSize: 1000, Compress: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	l.Record([]byte("after reopening"))
+	var keys []string
+	for key := range l.Keys() {
+		keys = append(keys, string(key))
+	}
+	if len(keys) != 501 || keys[0] != "key-0" || keys[499] != "key-499" || keys[500] != "after reopening" {
+		t.Fatalf("Read %d keys back, first %q", len(keys), keys[0])
+	}
+
+	// An exact rebuild at another size
+	rebuilt, err := bf.Rebuild(5000, 0.001, l.Keys())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 500; i++ {
+		if !rebuilt.ContainsString(fmt.Sprintf("key-%d", i)) {
+			t.Fatalf("Rebuilt filter lost key-%d", i)
+		}
+	}
+	for range l.Keys() {
+		break // Stopping early is allowed
+	}
+}
+
+// TestFileKeyLogDamage tests that Replay reports a torn record and Keys skips it
+func TestFileKeyLogDamage(t *testing.T) {
+	dir := t.TempDir()
+	l, err := OpenFileKeyLog(FileKeyLogConfig{Dir: dir, Sync: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.Record([]byte("a"))
+	l.Record([]byte("b"))
+	l.Close()
+	f, _ := os.OpenFile(keyLogPath(dir, 1, false), os.O_WRONLY|os.O_APPEND, 0)
+	f.Write(appendKeyRecord(nil, []byte("torn"))[:9])
+	f.Close()
+
+	l, err = OpenFileKeyLog(FileKeyLogConfig{Dir: dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	l.Record([]byte("c"))
+
+	var replayed []string
+	err = l.Replay(func(key []byte) { replayed = append(replayed, string(key)) })
+	if !errors.Is(err, ErrCorruptData) || len(replayed) != 2 {
+		t.Errorf("Replay: %v after %v", err, replayed)
+	}
+	var keys []string
+	for key := range l.Keys() {
+		keys = append(keys, string(key))
+	}
+	if fmt.Sprint(keys) != "[a b c]" {
+		t.Errorf("Keys() = %v, want the records around the damage", keys)
+	}
+
+	if _, err := OpenFileKeyLog(FileKeyLogConfig{}); err == nil {
+		t.Error("Open without a Dir succeeded")
+	}
+}
+
+// TestFileKeyLogConcurrent tests recording while iterating and rotating
+func TestFileKeyLogConcurrent(t *testing.T) {
+	l, err := OpenFileKeyLog(FileKeyLogConfig{Dir: t.TempDir(), SegmentSize: 4096, Compress: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	var wg sync.WaitGroup
+	for w := 0; w < 4; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 1000; i++ {
+				if err := l.Record([]byte{byte(w), byte(i), byte(i >> 8)}); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}()
+	}
+	for i := 0; i < 10; i++ {
+		l.Rotate()
+		for range l.Keys() {
+		}
+	}
+	wg.Wait()
+	n := 0
+	for range l.Keys() {
+		n++
+	}
+	if n != 4000 {
+		t.Errorf("Read %d keys, want 4000", n)
+	}
+}
+
+// TestKeyRecords tests the log record encoding
+func TestKeyRecords(t *testing.T) {
+	var b []byte
+	keys := [][]byte{[]byte("a"), {}, bytes.Repeat([]byte("x"), 3<<20)}
+	for _, k := range keys {
+		b = appendKeyRecord(b, k)
+	}
+	var got [][]byte
+	if err := readKeyRecords(bytes.NewReader(b), func(k []byte) error { got = append(got, bytes.Clone(k)); return nil }); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != len(keys) || !bytes.Equal(got[2], keys[2]) {
+		t.Errorf("Read %d keys", len(got))
+	}
+
+	damaged := bytes.Clone(b)
+	damaged[9]++
+	if err := readKeyRecords(bytes.NewReader(damaged), func([]byte) error { return nil }); !errors.Is(err, ErrCorruptData) {
+		t.Errorf("Damaged record: expected ErrCorruptData, got %v", err)
+	}
+	if err := readKeyRecords(bytes.NewReader(b[:len(b)-1]), func([]byte) error { return nil }); !errors.Is(err, ErrCorruptData) {
+		t.Errorf("Truncated record: expected ErrCorruptData, got %v", err)
+	}
+}
@@ -0,0 +1,69 @@
+package bloomfilter
+
+import (
+	"iter"
+	"sync"
+)
+
+// KeySource replays the keys added to a filter so the filter can be rebuilt with
+// different parameters. Keys may be yielded more than once; re-adding a key is
+// harmless. Yielded slices must not be modified or retained.
+type KeySource interface {
+	Keys() iter.Seq[[]byte]
+}
+
+// KeyRecorder is a KeySource that records keys as they are added, such as an
+// in-memory or on-disk key log
+type KeyRecorder interface {
+	KeySource
+	Record(data []byte) error
+}
+
+// MemoryKeyLog is an in-memory KeyRecorder that stores keys back to back in a
+// single growing buffer. It is safe for concurrent use, and Keys iterates over a
+// snapshot without blocking concurrent Record calls.
+type MemoryKeyLog struct {
+	mu   sync.RWMutex
+	data []byte
+	ends []int
+}
+
+// NewMemoryKeyLog creates an empty in-memory key log
+func NewMemoryKeyLog() *MemoryKeyLog {
+	return &MemoryKeyLog{}
+}
+
+// Record appends a copy of data to the log. It never fails.
+func (l *MemoryKeyLog) Record(data []byte) error {
+	l.mu.Lock()
+	l.data = append(l.data, data...)
+	l.ends = append(l.ends, len(l.data))
+	l.mu.Unlock()
+	return nil
+}
+
+// Keys iterates over the keys recorded before the call
+func (l *MemoryKeyLog) Keys() iter.Seq[[]byte] {
+	return func(yield func([]byte) bool) {
+		// Recorded bytes are never modified, so the snapshot stays valid even if
+		// later appends reallocate the buffers
+		l.mu.RLock()
+		data, ends := l.data, l.ends
+		l.mu.RUnlock()
+
+		start := 0
+		for _, end := range ends {
+			if !yield(data[start:end:end]) {
+				return
+			}
+			start = end
+		}
+	}
+}
+
+// Len returns the number of recorded keys
+func (l *MemoryKeyLog) Len() int {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return len(l.ends)
+}
@@ -0,0 +1,43 @@
+package bloomfilter
+
+import (
+	"bytes"
+	"testing"
+)
+
+// TestMemoryKeyLog tests recording and snapshot iteration
+func TestMemoryKeyLog(t *testing.T) {
+	log := NewMemoryKeyLog()
+	keys := [][]byte{[]byte("a"), {}, []byte("hello"), {0, 1, 2}}
+	for _, k := range keys {
+		if err := log.Record(k); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if log.Len() != len(keys) {
+		t.Fatalf("Expected %d keys, got %d", len(keys), log.Len())
+	}
+
+	i := 0
+	for k := range log.Keys() {
+		if !bytes.Equal(k, keys[i]) {
+			t.Errorf("Key %d = %q, expected %q", i, k, keys[i])
+		}
+		// Records during iteration are not part of the snapshot
+		log.Record([]byte("late"))
+		i++
+	}
+	if i != len(keys) {
+		t.Errorf("Iterated %d keys, expected %d", i, len(keys))
+	}
+
+	// Early termination
+	n := 0
+	for range log.Keys() {
+		n++
+		break
+	}
+	if n != 1 {
+		t.Errorf("Expected early break after 1 key, got %d", n)
+	}
+}
@@ -0,0 +1,81 @@
+package bloomfilter
+
+import (
+	"net/netip"
+	"time"
+)
+
+// Integer keys are widened to uint64 and hashed like AddUint64, as in
+// NewTypedInteger, so AddInt64(7), AddUint32(7) and AddUint64(7) add the same
+// key. None of the helpers allocate.
+
+// AddInt64 adds an int64 key, hashed like AddUint64(uint64(n))
+func (bf *CacheOptimizedBloomFilter) AddInt64(n int64) {
+	bf.AddUint64(uint64(n))
+}
+
+// ContainsInt64 checks an int64 key added by AddInt64
+func (bf *CacheOptimizedBloomFilter) ContainsInt64(n int64) bool {
+	return bf.ContainsUint64(uint64(n))
+}
+
+// AddUint32 adds a uint32 key, hashed like AddUint64(uint64(n))
+func (bf *CacheOptimizedBloomFilter) AddUint32(n uint32) {
+	bf.AddUint64(uint64(n))
+}
+
+// ContainsUint32 checks a uint32 key added by AddUint32
+func (bf *CacheOptimizedBloomFilter) ContainsUint32(n uint32) bool {
+	return bf.ContainsUint64(uint64(n))
+}
+
+// AddTime adds an instant as its Unix time in nanoseconds, so equal instants
+// are the same key whatever their location or monotonic reading. Times outside
+// the years 1678 to 2262 are not representable and collide (see
+// time.Time.UnixNano).
+func (bf *CacheOptimizedBloomFilter) AddTime(t time.Time) {
+	bf.AddInt64(t.UnixNano())
+}
+
+// ContainsTime checks an instant added by AddTime
+func (bf *CacheOptimizedBloomFilter) ContainsTime(t time.Time) bool {
+	return bf.ContainsInt64(t.UnixNano())
+}
+
+// AddIP adds an IP address as its 4-byte form for IPv4 and its 16-byte form
+// otherwise, so 10.0.0.1 and ::ffff:10.0.0.1 are different keys; Unmap the
+// address first to treat them as one. The IPv6 zone is not part of the key,
+// and the zero Addr is the empty key.
+func (bf *CacheOptimizedBloomFilter) AddIP(addr netip.Addr) {
+	switch {
+	case addr.Is4():
+		v4 := addr.As4()
+		bf.Add(v4[:])
+	case addr.IsValid():
+		bf.Add16(addr.As16())
+	default:
+		bf.Add(nil)
+	}
+}
+
+// ContainsIP checks an IP address added by AddIP
+func (bf *CacheOptimizedBloomFilter) ContainsIP(addr netip.Addr) bool {
+	switch {
+	case addr.Is4():
+		v4 := addr.As4()
+		return bf.Contains(v4[:])
+	case addr.IsValid():
+		return bf.Contains16(addr.As16())
+	}
+	return bf.Contains(nil)
+}
+
+// AddUUID adds a UUID, hashed like Add16(id)
+func (bf *CacheOptimizedBloomFilter) AddUUID(id [16]byte) {
+	bf.Add16(id)
+}
+
+// ContainsUUID checks a UUID added by AddUUID
+func (bf *CacheOptimizedBloomFilter) ContainsUUID(id [16]byte) bool {
+	return bf.Contains16(id)
+}
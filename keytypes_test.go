@@ -0,0 +1,67 @@
+package bloomfilter
+
+import (
+	"net/netip"
+	"testing"
+	"time"
+)
+
+// TestTypedKeyHelpers tests the integer, time, IP and UUID helpers
+func TestTypedKeyHelpers(t *testing.T) {
+	bf := NewCacheOptimizedBloomFilter(1000, 0.001)
+
+	bf.AddInt64(-42)
+	bf.AddUint32(7)
+	if !bf.ContainsInt64(-42) || !bf.ContainsUint64(uint64(7)) || !bf.ContainsUint32(7) || !bf.ContainsInt64(7) {
+		t.Error("Integer keys must hash like AddUint64")
+	}
+	if bf.ContainsInt64(42) || bf.ContainsUint32(8) {
+		t.Error("Unexpected integer key")
+	}
+
+	now := time.Date(2026, 10, 17, 12, 0, 0, 123, time.UTC)
+	bf.AddTime(now)
+	if !bf.ContainsTime(now.In(time.FixedZone("X", 3600))) || !bf.ContainsInt64(now.UnixNano()) {
+		t.Error("Equal instants must be the same key")
+	}
+	if bf.ContainsTime(now.Add(time.Nanosecond)) {
+		t.Error("Unexpected time key")
+	}
+
+	v4 := netip.MustParseAddr("10.0.0.1")
+	v6 := netip.MustParseAddr("2001:db8::1")
+	bf.AddIP(v4)
+	bf.AddIP(v6)
+	if !bf.ContainsIP(v4) || !bf.ContainsIP(v6) || !bf.Contains([]byte{10, 0, 0, 1}) {
+		t.Error("IP keys not found")
+	}
+	if bf.ContainsIP(netip.MustParseAddr("::ffff:10.0.0.1")) || bf.ContainsIP(netip.MustParseAddr("10.0.0.2")) {
+		t.Error("Unexpected IP key")
+	}
+	if !bf.ContainsIP(netip.MustParseAddr("2001:db8::1%eth0")) {
+		t.Error("The zone must not be part of the key")
+	}
+	bf.AddIP(netip.Addr{})
+	if !bf.Contains(nil) {
+		t.Error("The zero Addr must be the empty key")
+	}
+
+	id := [16]byte{0x12, 0x34, 15: 0xff}
+	bf.AddUUID(id)
+	if !bf.ContainsUUID(id) || !bf.Contains(id[:]) || bf.ContainsUUID([16]byte{}) {
+		t.Error("Unexpected UUID membership")
+	}
+
+	if allocs := testing.AllocsPerRun(100, func() {
+		bf.AddInt64(1)
+		bf.ContainsUint32(2)
+		bf.AddTime(now)
+		bf.ContainsTime(now)
+		bf.AddIP(v6)
+		bf.ContainsIP(v4)
+		bf.AddUUID(id)
+		bf.ContainsUUID(id)
+	}); allocs != 0 {
+		t.Errorf("Expected 0 allocations, got %f", allocs)
+	}
+}
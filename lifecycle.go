@@ -0,0 +1,142 @@
+package bloomfilter
+
+import (
+	"io"
+	"sync"
+)
+
+// Lifecycle
+//
+// Three operations end a filter's current use, and they are deliberately distinct:
+//
+//   - Reset clears the contents and per-use counters but keeps the allocation
+//     and the configured features (load shedding, sampling), ready for reuse
+//     by the same owner. Clear only clears the bits.
+//   - Release returns a filter obtained from a FilterPool to its pool. The
+//     filter must not be used afterwards; the next Get may hand it out again.
+//   - Close frees OS resources such as files. Only types that hold them
+//     (StorageFilter, FileStorage) have Close; in-memory filters need none.
+//
+// All three are safe to call concurrently with each other and with lookups.
+// Adds that race with Reset or Release may or may not survive in the cleared
+// filter, and may survive only in part. ClearAndReset is the Reset to use while
+// adds are running, such as when rotating a live filter.
+
+// Reset clears the filter and its per-use counters (dropped adds, FPR
+// measurement, sampled keys, add counts, reduced lookups) while keeping its
+// allocation and settings
+func (bf *CacheOptimizedBloomFilter) Reset() {
+	bf.Clear()
+	bf.dropped.Store(0)
+	bf.ResetFPRMeasurement()
+	bf.ResetAddCounts()
+	if a := bf.adaptive.Load(); a != nil {
+		a.reduced.Store(0)
+	}
+}
+
+// ClearAndReset is Reset made safe against concurrent adds, so a live service
+// can rotate a filter without locking around it. The clear is fenced by an
+// epoch: an add that arrives while it runs waits for it, and an add that was
+// setting bits when it started sets them again afterwards. Every add is thus
+// either entirely cleared or entirely present in the new contents, never half
+// of each, and every add that returns after ClearAndReset returns is present.
+//
+// Concurrent calls run one at a time. Lookups are not fenced and may see the
+// filter partly cleared; Union and the word-level writes are not fenced either.
+func (bf *CacheOptimizedBloomFilter) ClearAndReset() {
+	bf.checkWritable()
+	bf.quiesce(bf.Reset)
+}
+
+// quiesce runs fn with adds held off: adds arriving meanwhile wait, and adds
+// caught setting bits set them again once fn returns (see setBits)
+func (bf *CacheOptimizedBloomFilter) quiesce(fn func()) {
+	bf.clearMu.Lock()
+	defer bf.clearMu.Unlock()
+	bf.epoch.Add(1)
+	defer bf.epoch.Add(1)
+	fn()
+}
+
+// FilterPool recycles filters of one geometry, so that short-lived filters (per
+// request, per session) reuse their cache line allocation instead of producing
+// garbage. Filters handed out by the pool start empty with every optional feature
+// disabled, exactly like newly constructed ones. The filters of a pool share one
+// random seed, so they can be combined with each other. Like sync.Pool, which it is
+// built on, idle filters may be freed by the garbage collector at any time.
+type FilterPool struct {
+	cacheLineCount uint64
+	hashCount      uint32
+	seed           uint64
+	pool           sync.Pool
+}
+
+// NewFilterPool creates a pool of filters sized for expectedElements at
+// falsePositiveRate.
+//
+// Panics on the same invalid inputs as NewCacheOptimizedBloomFilter.
+func NewFilterPool(expectedElements uint64, falsePositiveRate float64) *FilterPool {
+	validateSizing(expectedElements, falsePositiveRate)
+	p := &FilterPool{seed: RandomSeed()}
+	p.cacheLineCount, p.hashCount = optimalGeometry(expectedElements, falsePositiveRate)
+	return p
+}
+
+// Get returns an empty filter from the pool, allocating one if the pool is empty
+func (p *FilterPool) Get() *CacheOptimizedBloomFilter {
+	if bf, ok := p.pool.Get().(*CacheOptimizedBloomFilter); ok {
+		bf.released.Store(false)
+		return bf
+	}
+	bf := newFilter(p.cacheLineCount, p.hashCount)
+	bf.seed = p.seed
+	bf.pool = p
+	return bf
+}
+
+// Release returns a filter obtained from a FilterPool to its pool, after
+// resetting it to the state of a new filter. Releasing a filter that did not
+// come from a pool, or releasing it twice, does nothing. The filter must not be
+// used after Release.
+func (bf *CacheOptimizedBloomFilter) Release() {
+	if bf.pool == nil || !bf.released.CompareAndSwap(false, true) {
+		return
+	}
+	bf.resetToNew()
+	bf.seed = bf.pool.seed
+	bf.pool.pool.Put(bf)
+}
+
+// resetToNew clears the filter, disables every optional feature and restores
+// the default hashing and probing
+func (bf *CacheOptimizedBloomFilter) resetToNew() {
+	bf.StopRecording()
+	bf.maxLoadBits.Store(0)
+	bf.ghost.Store(nil)
+	bf.reservoir.Store(nil)
+	bf.adaptive.Store(nil)
+	bf.saturation.Store(nil)
+	bf.ops.Store(nil)
+	bf.changes.Store(nil)
+	bf.deltaApplied.Store(0)
+	bf.countAdds.Store(false)
+	bf.ResetAddCounts()
+	bf.Clear()
+	bf.trackBits.Store(false)
+	bf.bitsSet.Store(0)
+	bf.dropped.Store(0)
+	bf.hasher = nil
+	bf.hashVersion = DefaultHashVersion
+	bf.probe = DefaultProbeScheme
+}
+
+// Close closes the storage if it holds OS resources (implements io.Closer)
+func (f *StorageFilter) Close() error {
+	if c, ok := f.storage.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+var _ io.Closer = (*StorageFilter)(nil)
@@ -0,0 +1,192 @@
+package bloomfilter
+
+import (
+	"errors"
+	"hash/fnv"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestReset tests that Reset clears contents and counters but keeps settings
+func TestReset(t *testing.T) {
+	bf := NewCacheOptimizedBloomFilter(1000, 0.01)
+	bf.SetMaxLoadFactor(0.01)
+	bf.EnableKeyReservoir(10)
+	for i := uint64(0); i < 1000; i++ {
+		bf.AddUint64(i)
+	}
+	if bf.Dropped() == 0 {
+		t.Fatal("Expected dropped adds before Reset")
+	}
+
+	bf.Reset()
+	if bf.PopCount() != 0 || bf.Dropped() != 0 || len(bf.SampleKeys()) != 0 {
+		t.Errorf("Reset left state behind: bits=%d dropped=%d", bf.PopCount(), bf.Dropped())
+	}
+	if bf.MaxLoadFactor() == 0 || bf.SampleKeys() == nil {
+		t.Error("Reset must keep settings")
+	}
+}
+
+// TestClearAndResetParksAdds tests that an add arriving during a clear waits
+// for it and lands in the cleared filter
+func TestClearAndResetParksAdds(t *testing.T) {
+	bf := NewCacheOptimizedBloomFilter(1000, 0.01)
+	bf.AddString("old")
+
+	// Hold the filter mid-clear, as ClearAndReset does
+	bf.clearMu.Lock()
+	bf.epoch.Add(1)
+	done := make(chan struct{})
+	go func() {
+		bf.AddString("new")
+		close(done)
+	}()
+	select {
+	case <-done:
+		t.Fatal("Add completed during a clear")
+	case <-time.After(20 * time.Millisecond):
+	}
+	bf.Reset()
+	bf.epoch.Add(1)
+	bf.clearMu.Unlock()
+	<-done
+
+	if bf.ContainsString("old") || !bf.ContainsString("new") {
+		t.Errorf("After the clear: old=%t new=%t, want false true", bf.ContainsString("old"), bf.ContainsString("new"))
+	}
+	if bf.epoch.Load()&1 != 0 {
+		t.Error("Epoch left odd")
+	}
+}
+
+// TestClearAndResetConcurrent rotates a filter under concurrent adds
+func TestClearAndResetConcurrent(t *testing.T) {
+	bf := NewCacheOptimizedBloomFilter(100_000, 0.01)
+	bf.EnableFillTracking()
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	for w := 0; w < 4; w++ {
+		wg.Add(1)
+		go func(w uint64) {
+			defer wg.Done()
+			for i := uint64(0); ; i++ {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				bf.AddUint64(w<<32 | i)
+			}
+		}(uint64(w))
+	}
+	for i := 0; i < 50; i++ {
+		bf.ClearAndReset()
+	}
+	close(stop)
+	wg.Wait()
+
+	bf.ClearAndReset()
+	if bf.PopCount() != 0 || bf.BitsSet() != 0 {
+		t.Fatalf("ClearAndReset left %d bits", bf.PopCount())
+	}
+	for i := uint64(0); i < 1000; i++ {
+		bf.AddUint64(1<<40 | i)
+	}
+	for i := uint64(0); i < 1000; i++ {
+		if !bf.ContainsUint64(1<<40 | i) {
+			t.Fatalf("Key %d added after ClearAndReset is missing", i)
+		}
+	}
+	if bf.epoch.Load()&1 != 0 {
+		t.Error("Epoch left odd")
+	}
+}
+
+// TestFilterPool tests Get/Release recycling
+func TestFilterPool(t *testing.T) {
+	pool := NewFilterPool(1000, 0.01)
+	bf := pool.Get()
+	bf.AddString("a")
+	bf.SetMaxLoadFactor(0.5)
+	bf.EnableFPRSampling(1, 10)
+	bf.Release()
+	bf.Release() // double release is a no-op
+
+	for i := 0; i < 4; i++ {
+		got := pool.Get()
+		if got.PopCount() != 0 || got.MaxLoadFactor() != 0 || got.FPRMeasurement().Enabled {
+			t.Fatal("Pooled filter was not reset to a new state")
+		}
+		if got.cacheLineCount != bf.cacheLineCount || got.hashCount != bf.hashCount {
+			t.Fatal("Pooled filter has the wrong geometry")
+		}
+		defer got.Release()
+	}
+
+	// Not pooled: Release does nothing
+	plain := NewCacheOptimizedBloomFilter(1000, 0.01)
+	plain.AddString("a")
+	plain.Release()
+	if !plain.ContainsString("a") {
+		t.Error("Release must not touch filters that are not pooled")
+	}
+
+	// Concurrent use
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				f := pool.Get()
+				f.AddUint64(uint64(i))
+				if !f.ContainsUint64(uint64(i)) {
+					t.Error("Pooled filter lost an add")
+				}
+				f.Release()
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// TestFilterPoolResetsHashing tests that Release undoes SetHasher,
+// SetHashVersion and SetProbeScheme
+func TestFilterPoolResetsHashing(t *testing.T) {
+	pool := NewFilterPool(1000, 0.01)
+	fresh := pool.Get()
+	bf := pool.Get()
+	bf.SetHasher(Hash64Pair(fnv.New64a, fnv.New64))
+	bf.SetHashVersion(HashV1)
+	bf.SetProbeScheme(DoubleHashing)
+	bf.Release()
+
+	// sync.Pool may drop the released filter, so check it as well as Get
+	for _, got := range []*CacheOptimizedBloomFilter{bf, pool.Get()} {
+		if got.HasCustomHasher() || got.HashVersion() != DefaultHashVersion || got.ProbeScheme() != DefaultProbeScheme {
+			t.Errorf("Pooled filter kept custom=%v, %s, probe %s", got.HasCustomHasher(), got.HashVersion(), got.ProbeScheme())
+		}
+		if err := got.Compatible(fresh); err != nil {
+			t.Errorf("Pooled filter not combinable with the pool's other filters: %v", err)
+		}
+	}
+}
+
+// TestStorageFilterClose tests that Close frees the backend's file
+func TestStorageFilterClose(t *testing.T) {
+	storage, err := OpenFileStorage(filepath.Join(t.TempDir(), "bits"), BitsPerCacheLine)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, _ := NewStorageFilter(storage, 3)
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.AddE([]byte("x")); !errors.Is(err, os.ErrClosed) {
+		t.Errorf("Expected ErrClosed after Close, got %v", err)
+	}
+}
@@ -0,0 +1,67 @@
+package bloomfilter
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+// ErrOverloaded is returned by TryAdd when load shedding is enabled and the
+// filter is past its maximum load factor
+var ErrOverloaded = errors.New("bloomfilter: filter is past its maximum load factor")
+
+// SetMaxLoadFactor enables load shedding: once the fraction of set bits reaches
+// maxLoad, Add becomes a no-op that only increments the dropped counter and TryAdd
+// returns ErrOverloaded. This preserves the false positive rate for the elements
+// already in the filter instead of letting an overload saturate it; the price is
+// that dropped elements are not remembered and will be reported as absent.
+//
+// A maxLoad of 0 disables shedding (the default). While shedding is enabled the
+// filter tracks its set-bit count incrementally (see enableBitTracking). Under
+// concurrent writers the limit may be overshot by a few in-flight adds.
+//
+// Panics if maxLoad is outside [0, 1) or NaN.
+func (bf *CacheOptimizedBloomFilter) SetMaxLoadFactor(maxLoad float64) {
+	if maxLoad < 0 || maxLoad >= 1.0 || math.IsNaN(maxLoad) {
+		panic(fmt.Sprintf("bloomfilter: maxLoad must be in range [0, 1), got %f", maxLoad))
+	}
+
+	if maxLoad == 0 {
+		bf.maxLoadBits.Store(0)
+		return
+	}
+
+	limit := uint64(maxLoad * float64(bf.bitCount))
+	if limit == 0 {
+		limit = 1
+	}
+	bf.enableBitTracking()
+	bf.maxLoadBits.Store(limit)
+}
+
+// MaxLoadFactor returns the configured load shedding threshold, or 0 if disabled
+func (bf *CacheOptimizedBloomFilter) MaxLoadFactor() float64 {
+	return float64(bf.maxLoadBits.Load()) / float64(bf.bitCount)
+}
+
+// TryAdd adds an element like Add, but returns ErrOverloaded instead of silently
+// dropping it when the filter is past its maximum load factor
+func (bf *CacheOptimizedBloomFilter) TryAdd(data []byte) error {
+	if bf.overloaded() {
+		bf.dropped.Add(1)
+		return ErrOverloaded
+	}
+	bf.Add(data)
+	return nil
+}
+
+// Dropped returns the number of adds dropped by load shedding
+func (bf *CacheOptimizedBloomFilter) Dropped() uint64 {
+	return bf.dropped.Load()
+}
+
+// overloaded reports whether load shedding is enabled and the limit is reached
+func (bf *CacheOptimizedBloomFilter) overloaded() bool {
+	limit := bf.maxLoadBits.Load()
+	return limit != 0 && bf.bitsSet.Load() >= limit
+}
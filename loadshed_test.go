@@ -0,0 +1,86 @@
+package bloomfilter
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+// TestLoadShedding tests that adds are dropped once the maximum load factor is reached
+func TestLoadShedding(t *testing.T) {
+	bf := NewCacheOptimizedBloomFilter(1000, 0.01)
+	bf.SetMaxLoadFactor(0.2)
+
+	if got := bf.MaxLoadFactor(); got < 0.19 || got > 0.2 {
+		t.Errorf("Expected max load factor ~0.2, got %f", got)
+	}
+
+	var accepted []string
+	var overloaded int
+	for i := 0; i < 5000; i++ {
+		key := fmt.Sprintf("key_%d", i)
+		err := bf.TryAdd([]byte(key))
+		switch {
+		case err == nil:
+			accepted = append(accepted, key)
+		case errors.Is(err, ErrOverloaded):
+			overloaded++
+		default:
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+
+	if overloaded == 0 {
+		t.Fatal("Expected some adds to be shed")
+	}
+	if bf.Dropped() != uint64(overloaded) {
+		t.Errorf("Dropped() = %d, expected %d", bf.Dropped(), overloaded)
+	}
+
+	// Load must stay close to the limit, and accepted keys must still be present
+	stats := bf.GetCacheStats()
+	if stats.LoadFactor > 0.25 {
+		t.Errorf("Load factor %f exceeds the shedding limit by too much", stats.LoadFactor)
+	}
+	if stats.DroppedAdds != bf.Dropped() {
+		t.Errorf("CacheStats.DroppedAdds = %d, expected %d", stats.DroppedAdds, bf.Dropped())
+	}
+	for _, key := range accepted {
+		if !bf.ContainsString(key) {
+			t.Fatalf("Accepted key %s not found", key)
+		}
+	}
+
+	// Plain Add is a counted no-op while overloaded
+	before := bf.PopCount()
+	bf.AddString("one_more")
+	if bf.PopCount() != before || bf.Dropped() != uint64(overloaded)+1 {
+		t.Error("Add should be a counted no-op while overloaded")
+	}
+
+	// Clear resets the tracked load; disabling accepts everything again
+	bf.Clear()
+	if err := bf.TryAdd([]byte("after_clear")); err != nil {
+		t.Errorf("Expected add to succeed after Clear, got %v", err)
+	}
+	bf.SetMaxLoadFactor(0)
+	for i := 0; i < 5000; i++ {
+		if err := bf.TryAdd([]byte(fmt.Sprintf("key_%d", i))); err != nil {
+			t.Fatalf("Expected no shedding when disabled, got %v", err)
+		}
+	}
+}
+
+// TestLoadSheddingValidation verifies panics on invalid thresholds
+func TestLoadSheddingValidation(t *testing.T) {
+	for _, v := range []float64{-0.1, 1.0, 1.5} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("Expected panic for maxLoad=%f", v)
+				}
+			}()
+			NewCacheOptimizedBloomFilter(1000, 0.01).SetMaxLoadFactor(v)
+		}()
+	}
+}
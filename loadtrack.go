@@ -0,0 +1,78 @@
+package bloomfilter
+
+// Incremental load tracking
+//
+// Features that need the load factor on every operation (load shedding, automatic
+// rebuilds) cannot afford an O(m) PopCount each time. Once one of them enables
+// tracking, Add accumulates the number of bits it actually flipped, and bulk
+// operations recount. Tracking stays enabled for the life of the filter and is
+// off by default so that plain filters do not pay for a shared atomic counter.
+// Callers enable it directly with EnableFillTracking to make the statistics O(1).
+
+// enableBitTracking starts maintaining bitsSet. The flag is raised before the
+// initial recount so no flip is lost; concurrent adds may be counted twice,
+// which can only overestimate the load.
+func (bf *CacheOptimizedBloomFilter) enableBitTracking() {
+	if bf.trackBits.Swap(true) {
+		return
+	}
+	bf.bitsSet.Store(bf.PopCount())
+}
+
+// EnableFillTracking makes BitsSet, EstimatedFPP, EstimatedCount and
+// GetCacheStats O(1) instead of a PopCount over the whole bit array, for large
+// filters whose statistics are read per request. It costs one PopCount now and
+// an atomic add on the shared counter for each add that sets a new bit.
+//
+// The tracked count can only run high, by the adds racing with this call, and
+// bulk operations (Union, Intersection, Difference, Clear) recount it.
+// RecountBits corrects it exactly.
+func (bf *CacheOptimizedBloomFilter) EnableFillTracking() {
+	bf.enableBitTracking()
+}
+
+// FillTracking reports whether the set-bit count is tracked incrementally,
+// enabled by EnableFillTracking or by a feature that needs it
+func (bf *CacheOptimizedBloomFilter) FillTracking() bool {
+	return bf.trackBits.Load()
+}
+
+// BitsSet returns the number of set bits: the tracked count in O(1) when fill
+// tracking is on, and a PopCount otherwise
+func (bf *CacheOptimizedBloomFilter) BitsSet() uint64 {
+	if bf.trackBits.Load() {
+		return min(bf.bitsSet.Load(), bf.bitCount)
+	}
+	return bf.PopCount()
+}
+
+// RecountBits counts the set bits exactly with a PopCount and, when fill
+// tracking is on, resets the tracked count to the result
+func (bf *CacheOptimizedBloomFilter) RecountBits() uint64 {
+	bitsSet := bf.PopCount()
+	if bf.trackBits.Load() {
+		bf.bitsSet.Store(bitsSet)
+		bf.checkSaturation(bitsSet)
+	}
+	return bitsSet
+}
+
+// recordFlips adds newly set bits to the tracked count
+func (bf *CacheOptimizedBloomFilter) recordFlips(flipped uint64) {
+	if flipped > 0 && bf.trackBits.Load() {
+		bf.checkSaturation(bf.bitsSet.Add(flipped))
+	}
+}
+
+// resyncBitsSet recounts the set bits after a bulk operation, if they are being tracked
+func (bf *CacheOptimizedBloomFilter) resyncBitsSet() {
+	if bf.trackBits.Load() {
+		bf.RecountBits()
+	}
+}
+
+// trackedLoadFactor returns the load factor in O(1) when tracking is enabled,
+// falling back to a PopCount otherwise
+func (bf *CacheOptimizedBloomFilter) trackedLoadFactor() float64 {
+	return float64(bf.BitsSet()) / float64(bf.bitCount)
+}
@@ -0,0 +1,73 @@
+package bloomfilter
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+)
+
+// TestFillTracking tests that the tracked statistics match a full recount
+func TestFillTracking(t *testing.T) {
+	bf := NewCacheOptimizedBloomFilter(10000, 0.01)
+	for i := 0; i < 1000; i++ {
+		bf.AddString(fmt.Sprintf("before-%d", i))
+	}
+	if bf.FillTracking() {
+		t.Fatal("Fill tracking on by default")
+	}
+	untracked := bf.GetCacheStats()
+
+	bf.EnableFillTracking()
+	if !bf.FillTracking() {
+		t.Fatal("EnableFillTracking did not enable tracking")
+	}
+	if got := bf.GetCacheStats(); got.BitsSet != untracked.BitsSet || got.EstimatedFPP != untracked.EstimatedFPP {
+		t.Errorf("Tracked stats %d bits FPP %g, want %d bits FPP %g",
+			got.BitsSet, got.EstimatedFPP, untracked.BitsSet, untracked.EstimatedFPP)
+	}
+
+	var wg sync.WaitGroup
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 2000; i++ {
+				bf.AddString(fmt.Sprintf("after-%d-%d", g, i))
+			}
+		}(g)
+	}
+	wg.Wait()
+	bf.AddStrings([]string{"batch-1", "batch-2", "batch-3"})
+
+	if got, want := bf.BitsSet(), bf.PopCount(); got != want {
+		t.Errorf("Tracked %d bits, PopCount %d", got, want)
+	}
+	if got, want := bf.EstimatedCount(), bf.estimateCount(bf.PopCount()); got != want {
+		t.Errorf("EstimatedCount %d, want %d", got, want)
+	}
+
+	// Recount corrects a drifted count
+	bf.bitsSet.Add(100)
+	if got, want := bf.RecountBits(), bf.PopCount(); got != want || bf.BitsSet() != want {
+		t.Errorf("RecountBits %d, BitsSet %d, want %d", got, bf.BitsSet(), want)
+	}
+
+	bf.Clear()
+	if bf.BitsSet() != 0 || bf.EstimatedFPP() != 0 {
+		t.Error("Tracked count not reset by Clear")
+	}
+}
+
+// TestFillTrackingAllocations tests that tracked statistics do not allocate
+func TestFillTrackingAllocations(t *testing.T) {
+	bf := NewCacheOptimizedBloomFilter(10000, 0.01)
+	bf.EnableFillTracking()
+	key := []byte("key")
+	if allocs := testing.AllocsPerRun(100, func() {
+		bf.Add(key)
+		bf.EstimatedFPP()
+		bf.EstimatedCount()
+	}); allocs != 0 {
+		t.Errorf("Tracked statistics allocated %.0f times", allocs)
+	}
+}
@@ -0,0 +1,403 @@
+package bloomfilter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ErrFilterExists is returned by Manager.Create for a name already in use
+var ErrFilterExists = errors.New("bloomfilter: filter name already in use")
+
+// ErrMemoryLimit is returned by Manager.Create for a filter larger than the
+// Manager's MaxMemory
+var ErrMemoryLimit = errors.New("bloomfilter: filter exceeds the memory limit")
+
+// EvictReason is the cause of a Manager eviction
+type EvictReason int
+
+const (
+	// EvictExpired is the removal of a filter past its TTL
+	EvictExpired EvictReason = iota
+	// EvictIdle is the removal of a filter not looked up for MaxIdle
+	EvictIdle
+	// EvictMemory is the removal of the least recently used filter to fit a new
+	// one within MaxMemory
+	EvictMemory
+)
+
+func (r EvictReason) String() string {
+	switch r {
+	case EvictIdle:
+		return "idle"
+	case EvictMemory:
+		return "memory"
+	}
+	return "expired"
+}
+
+// ManagerConfig configures a Manager
+type ManagerConfig struct {
+	// SweepEvery, if positive, drops expired filters and rotates due ones in a
+	// background goroutine at this interval until Close, so idle filters free
+	// their memory. Expiry and rotation are also applied whenever a filter is
+	// looked up.
+	SweepEvery time.Duration
+	// MaxMemory, if positive, caps the total MemoryUsage of the filters: Create
+	// evicts the least recently used filters until the new one fits
+	MaxMemory uint64
+	// MaxIdle, if positive, evicts filters not looked up with Get for this long
+	MaxIdle time.Duration
+	// OnEvict, if set, is called with each filter the Manager removes on its own
+	// (expiry, idleness, memory), after the removal and outside the Manager's
+	// locks, so it may call the Manager; it is not called for Drop. Callers
+	// still holding the filter can keep using it.
+	OnEvict func(name string, bf *CacheOptimizedBloomFilter, reason EvictReason)
+	// Clock drives TTLs, rotation and sweeps. Defaults to SystemClock.
+	Clock Clock
+}
+
+// ManagedConfig configures one filter of a Manager
+type ManagedConfig struct {
+	// Options create the filter, as with NewWithOptions
+	Options []Option
+	// TTL, if positive, drops the filter this long after it was created
+	TTL time.Duration
+	// RotateEvery, if positive, empties the filter with ClearAndReset at this
+	// interval, so a key is forgotten at the first rotation after it was added
+	// (use an AgingBloomFilter to keep keys for a minimum time). The filter
+	// stays the same object with the same settings.
+	RotateEvery time.Duration
+}
+
+// Manager owns named filters, such as one per tenant, with their configuration,
+// expiry and rotation, and bounds their total memory. All methods are safe for
+// concurrent use.
+type Manager struct {
+	cfg ManagerConfig
+
+	mu      sync.RWMutex
+	filters map[string]*managed
+	memory  uint64 // Total MemoryUsage of filters
+
+	stop      chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// managed is a filter of a Manager
+type managed struct {
+	bf       *CacheOptimizedBloomFilter
+	cfg      ManagedConfig
+	expires  time.Time // Zero without a TTL
+	rotateAt time.Time // Zero without rotation
+	size     uint64
+	// Time of the latest Get, UnixNano
+	lastAccess atomic.Int64
+
+	mu sync.Mutex // serializes rotations
+}
+
+// NewManager creates a Manager, starting background sweeps if cfg.SweepEvery
+// is positive.
+// Returns an error if SweepEvery or MaxIdle is negative.
+func NewManager(cfg ManagerConfig) (*Manager, error) {
+	if cfg.SweepEvery < 0 || cfg.MaxIdle < 0 {
+		return nil, fmt.Errorf("bloomfilter: SweepEvery and MaxIdle must not be negative, got %v and %v", cfg.SweepEvery, cfg.MaxIdle)
+	}
+	cfg.Clock = clockOrSystem(cfg.Clock)
+	m := &Manager{cfg: cfg, filters: make(map[string]*managed)}
+	if cfg.SweepEvery > 0 {
+		m.stop = make(chan struct{})
+		m.done = make(chan struct{})
+		go m.sweepLoop(cfg.Clock.NewTicker(cfg.SweepEvery))
+	}
+	return m, nil
+}
+
+func (m *Manager) sweepLoop(t Ticker) {
+	defer close(m.done)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C():
+			m.Sweep()
+		case <-m.stop:
+			return
+		}
+	}
+}
+
+// Create creates a filter named name from cfg and returns it, evicting the
+// least recently used filters if it does not fit within MaxMemory. The size is
+// checked and room made before the filter is allocated. It returns an error
+// wrapping ErrFilterExists if the name is in use, ErrMemoryLimit if the filter
+// alone is larger than MaxMemory, and the error of NewWithOptions if
+// cfg.Options are invalid, or if TTL or RotateEvery is negative.
+func (m *Manager) Create(name string, cfg ManagedConfig) (*CacheOptimizedBloomFilter, error) {
+	if cfg.TTL < 0 || cfg.RotateEvery < 0 {
+		return nil, fmt.Errorf("bloomfilter: TTL and RotateEvery must not be negative, got %v and %v", cfg.TTL, cfg.RotateEvery)
+	}
+	o := newOptions(cfg.Options)
+	cacheLineCount, hashCount, err := o.geometry()
+	if err != nil {
+		return nil, err
+	}
+	e := &managed{cfg: cfg, size: cacheLineCount * CacheLineSize}
+	if limit := m.cfg.MaxMemory; limit > 0 && e.size > limit {
+		return nil, fmt.Errorf("%w: %d bytes, limit %d", ErrMemoryLimit, e.size, limit)
+	}
+	now := m.cfg.Clock.Now()
+	e.lastAccess.Store(now.UnixNano())
+	if cfg.TTL > 0 {
+		e.expires = now.Add(cfg.TTL)
+	}
+	if cfg.RotateEvery > 0 {
+		e.rotateAt = now.Add(cfg.RotateEvery)
+	}
+
+	var evicted []eviction
+	defer func() { m.notify(evicted) }()
+	m.mu.Lock()
+	if old, ok := m.filters[name]; ok {
+		reason, stale := m.stale(old, now)
+		if !stale {
+			m.mu.Unlock()
+			return nil, fmt.Errorf("%w: %q", ErrFilterExists, name)
+		}
+		m.removeLocked(name)
+		evicted = append(evicted, eviction{name, old.bf, reason})
+	}
+	if limit := m.cfg.MaxMemory; limit > 0 {
+		for m.memory+e.size > limit && len(m.filters) > 0 {
+			lru := m.leastRecentlyUsedLocked()
+			evicted = append(evicted, eviction{lru, m.filters[lru].bf, EvictMemory})
+			m.removeLocked(lru)
+		}
+		if m.memory+e.size > limit {
+			// The rest is reserved by creates still allocating
+			m.mu.Unlock()
+			return nil, fmt.Errorf("%w: %d bytes, %d in use, limit %d", ErrMemoryLimit, e.size, m.memory, limit)
+		}
+	}
+	// Reserve the memory, so concurrent creates cannot overrun MaxMemory while
+	// the filter is allocated outside the lock
+	m.memory += e.size
+	m.mu.Unlock()
+
+	e.bf = o.build(cacheLineCount, hashCount)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.filters[name]; ok {
+		m.memory -= e.size
+		return nil, fmt.Errorf("%w: %q", ErrFilterExists, name)
+	}
+	m.filters[name] = e
+	return e.bf, nil
+}
+
+// eviction is a filter removed by the Manager, for OnEvict
+type eviction struct {
+	name   string
+	bf     *CacheOptimizedBloomFilter
+	reason EvictReason
+}
+
+// notify calls OnEvict for evictions made under the lock
+func (m *Manager) notify(evicted []eviction) {
+	if m.cfg.OnEvict == nil {
+		return
+	}
+	for _, ev := range evicted {
+		m.cfg.OnEvict(ev.name, ev.bf, ev.reason)
+	}
+}
+
+// stale reports whether e is to be evicted at now, and why
+func (m *Manager) stale(e *managed, now time.Time) (EvictReason, bool) {
+	if !e.expires.IsZero() && !now.Before(e.expires) {
+		return EvictExpired, true
+	}
+	if m.cfg.MaxIdle > 0 && now.Sub(time.Unix(0, e.lastAccess.Load())) >= m.cfg.MaxIdle {
+		return EvictIdle, true
+	}
+	return 0, false
+}
+
+// removeLocked removes the filter named name, which must exist
+func (m *Manager) removeLocked(name string) {
+	m.memory -= m.filters[name].size
+	delete(m.filters, name)
+}
+
+// leastRecentlyUsedLocked returns the name of the filter looked up least
+// recently; there must be one
+func (m *Manager) leastRecentlyUsedLocked() string {
+	var lru string
+	oldest := int64(math.MaxInt64)
+	for name, e := range m.filters {
+		if t := e.lastAccess.Load(); t < oldest || (t == oldest && name < lru) {
+			lru, oldest = name, t
+		}
+	}
+	return lru
+}
+
+// Get returns the filter named name, or false if there is none or it was
+// evicted, and counts as its latest use. A filter due for rotation is emptied
+// first.
+func (m *Manager) Get(name string) (*CacheOptimizedBloomFilter, bool) {
+	m.mu.RLock()
+	e, ok := m.filters[name]
+	m.mu.RUnlock()
+	if !ok {
+		return nil, false
+	}
+	now := m.cfg.Clock.Now()
+	if reason, stale := m.stale(e, now); stale {
+		m.evict(name, e, reason)
+		return nil, false
+	}
+	e.lastAccess.Store(now.UnixNano())
+	e.rotate(now)
+	return e.bf, true
+}
+
+// Drop removes the filter named name, reporting whether there was one. Callers
+// still holding the filter can keep using it. A filter that was due for
+// eviction is evicted instead, and Drop returns false.
+func (m *Manager) Drop(name string) bool {
+	m.mu.Lock()
+	e, ok := m.filters[name]
+	if !ok {
+		m.mu.Unlock()
+		return false
+	}
+	m.removeLocked(name)
+	m.mu.Unlock()
+	if reason, stale := m.stale(e, m.cfg.Clock.Now()); stale {
+		m.notify([]eviction{{name, e.bf, reason}})
+		return false
+	}
+	return true
+}
+
+// evict removes e for reason if it is still the filter named name
+func (m *Manager) evict(name string, e *managed, reason EvictReason) {
+	m.mu.Lock()
+	current := m.filters[name] == e
+	if current {
+		m.removeLocked(name)
+	}
+	m.mu.Unlock()
+	if current {
+		m.notify([]eviction{{name, e.bf, reason}})
+	}
+}
+
+// List returns the names of the filters, sorted
+func (m *Manager) List() []string {
+	now := m.cfg.Clock.Now()
+	m.mu.RLock()
+	names := make([]string, 0, len(m.filters))
+	for name, e := range m.filters {
+		if _, stale := m.stale(e, now); !stale {
+			names = append(names, name)
+		}
+	}
+	m.mu.RUnlock()
+	slices.Sort(names)
+	return names
+}
+
+// MemoryUsage returns the total MemoryUsage of the filters, the amount
+// MaxMemory bounds
+func (m *Manager) MemoryUsage() uint64 {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.memory
+}
+
+// Stats returns the statistics of every filter by name, as returned by each
+// filter's Stats with opts; with many large filters pass SkipBitStats or
+// enable fill tracking to avoid a PopCount of each. It does not count as a use
+// of the filters. It stops with ctx's error once ctx is done.
+func (m *Manager) Stats(ctx context.Context, opts ...StatsOption) (map[string]CacheStats, error) {
+	now := m.cfg.Clock.Now()
+	m.mu.RLock()
+	filters := make(map[string]*CacheOptimizedBloomFilter, len(m.filters))
+	for name, e := range m.filters {
+		if _, stale := m.stale(e, now); !stale {
+			filters[name] = e.bf
+		}
+	}
+	m.mu.RUnlock()
+
+	stats := make(map[string]CacheStats, len(filters))
+	for name, bf := range filters {
+		s, err := bf.Stats(ctx, opts...)
+		if err != nil {
+			return nil, err
+		}
+		stats[name] = s
+	}
+	return stats, nil
+}
+
+// Sweep evicts the expired and idle filters and rotates the ones due, as
+// background sweeps do (see ManagerConfig.SweepEvery)
+func (m *Manager) Sweep() {
+	now := m.cfg.Clock.Now()
+	var evicted []eviction
+	var live []*managed
+	m.mu.Lock()
+	for name, e := range m.filters {
+		if reason, stale := m.stale(e, now); stale {
+			m.removeLocked(name)
+			evicted = append(evicted, eviction{name, e.bf, reason})
+		} else {
+			live = append(live, e)
+		}
+	}
+	m.mu.Unlock()
+	m.notify(evicted)
+	for _, e := range live {
+		e.rotate(now)
+	}
+}
+
+// Close stops background sweeps (implements io.Closer). The Manager keeps
+// working. Calling Close more than once, or on a Manager without background
+// sweeps, is a no-op.
+func (m *Manager) Close() error {
+	if m.stop == nil {
+		return nil
+	}
+	m.closeOnce.Do(func() {
+		close(m.stop)
+		<-m.done
+	})
+	return nil
+}
+
+// rotate empties the filter if its rotation is due, then schedules the next
+// one on the rotation grid
+func (e *managed) rotate(now time.Time) {
+	if e.cfg.RotateEvery <= 0 {
+		return
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if now.Before(e.rotateAt) {
+		return
+	}
+	e.bf.ClearAndReset()
+	missed := now.Sub(e.rotateAt) / e.cfg.RotateEvery
+	e.rotateAt = e.rotateAt.Add((missed + 1) * e.cfg.RotateEvery)
+}
@@ -0,0 +1,260 @@
+package bloomfilter
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestManager tests creating, listing and dropping named filters
+func TestManager(t *testing.T) {
+	m, err := NewManager(ManagerConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+
+	small, err := m.Create("small", ManagedConfig{Options: []Option{WithExpectedElements(1000)}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Create("large", ManagedConfig{Options: []Option{WithExpectedElements(100000), WithFalsePositiveRate(0.001)}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Create("small", ManagedConfig{Options: []Option{WithExpectedElements(10)}}); !errors.Is(err, ErrFilterExists) {
+		t.Errorf("Duplicate name: expected ErrFilterExists, got %v", err)
+	}
+	if _, err := m.Create("invalid", ManagedConfig{}); err == nil {
+		t.Error("Create without a size succeeded")
+	}
+	if _, err := m.Create("negative", ManagedConfig{Options: []Option{WithExpectedElements(10)}, TTL: -time.Second}); err == nil {
+		t.Error("Create with a negative TTL succeeded")
+	}
+
+	small.AddString("key")
+	if bf, ok := m.Get("small"); !ok || bf != small || !bf.ContainsString("key") {
+		t.Error("Get returned another filter")
+	}
+	if names := m.List(); !slices.Equal(names, []string{"large", "small"}) {
+		t.Errorf("List() = %v", names)
+	}
+	stats, err := m.Stats(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stats) != 2 || stats["small"].BitsSet == 0 || stats["large"].BitCount <= stats["small"].BitCount {
+		t.Errorf("Stats() = %v", stats)
+	}
+
+	if !m.Drop("small") || m.Drop("small") {
+		t.Error("Drop did not report the filter once")
+	}
+	if _, ok := m.Get("small"); ok {
+		t.Error("Dropped filter still found")
+	}
+}
+
+// TestManagerExpiry tests TTLs and rotation on lookup and in sweeps
+func TestManagerExpiry(t *testing.T) {
+	clock := NewManualClock(time.Unix(1000, 0))
+	m, _ := NewManager(ManagerConfig{Clock: clock})
+	opts := []Option{WithExpectedElements(1000)}
+	m.Create("session", ManagedConfig{Options: opts, TTL: time.Hour})
+	rotating, _ := m.Create("window", ManagedConfig{Options: opts, RotateEvery: 10 * time.Minute})
+
+	rotating.AddString("early")
+	clock.Advance(9 * time.Minute)
+	if bf, _ := m.Get("window"); !bf.ContainsString("early") {
+		t.Error("Rotated before the interval")
+	}
+	clock.Advance(time.Minute)
+	if bf, _ := m.Get("window"); bf != rotating || bf.ContainsString("early") {
+		t.Error("Due rotation did not empty the filter in place")
+	}
+
+	// Missed rotations are skipped, keeping the schedule
+	rotating.AddString("late")
+	clock.Advance(35 * time.Minute)
+	m.Sweep()
+	if rotating.ContainsString("late") {
+		t.Error("Sweep did not rotate")
+	}
+	rotating.AddString("kept")
+	clock.Advance(4 * time.Minute)
+	if bf, _ := m.Get("window"); !bf.ContainsString("kept") {
+		t.Error("Rotated off the schedule")
+	}
+
+	clock.Advance(16 * time.Minute)
+	if _, ok := m.Get("session"); ok {
+		t.Error("Expired filter still found")
+	}
+	if names := m.List(); !slices.Equal(names, []string{"window"}) {
+		t.Errorf("List() = %v", names)
+	}
+	if _, err := m.Create("session", ManagedConfig{Options: opts}); err != nil {
+		t.Errorf("Name of an expired filter not reusable: %v", err)
+	}
+}
+
+// TestManagerBackgroundSweep tests sweeps driven by the clock
+func TestManagerBackgroundSweep(t *testing.T) {
+	clock := NewManualClock(time.Unix(1000, 0))
+	m, err := NewManager(ManagerConfig{SweepEvery: time.Minute, Clock: clock})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+	m.Create("tenant", ManagedConfig{Options: []Option{WithExpectedElements(1000)}, TTL: 90 * time.Second})
+
+	clock.Advance(time.Minute)
+	clock.Advance(time.Minute)
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		m.mu.RLock()
+		n := len(m.filters)
+		m.mu.RUnlock()
+		if n == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Expired filter was not swept")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	m.Close()
+	m.Close()
+	if _, err := NewManager(ManagerConfig{SweepEvery: -time.Second}); err == nil {
+		t.Error("Negative SweepEvery accepted")
+	}
+}
+
+// TestManagerConcurrent tests lookups and adds racing with creates, drops and
+// rotations
+func TestManagerConcurrent(t *testing.T) {
+	clock := NewManualClock(time.Unix(1000, 0))
+	m, _ := NewManager(ManagerConfig{Clock: clock})
+	names := []string{"a", "b", "c", "d"}
+	opts := []Option{WithExpectedElements(1000)}
+	for _, name := range names {
+		m.Create(name, ManagedConfig{Options: opts, RotateEvery: time.Second})
+	}
+
+	var wg sync.WaitGroup
+	for w := 0; w < 4; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 2000; i++ {
+				name := names[(w+i)%len(names)]
+				if bf, ok := m.Get(name); ok {
+					bf.AddUint64(uint64(i))
+				}
+				switch i % 100 {
+				case 0:
+					m.Drop(name)
+					m.Create(name, ManagedConfig{Options: opts, RotateEvery: time.Second})
+				case 50:
+					clock.Advance(time.Second)
+					m.Sweep()
+				}
+				m.List()
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// TestManagerMemoryLimit tests least recently used eviction under MaxMemory
+func TestManagerMemoryLimit(t *testing.T) {
+	clock := NewManualClock(time.Unix(1000, 0))
+	type evicted struct {
+		name   string
+		reason EvictReason
+	}
+	var got []evicted
+	size := uint64(16 * CacheLineSize)
+	m, _ := NewManager(ManagerConfig{
+		MaxMemory: 3 * size,
+		Clock:     clock,
+		OnEvict: func(name string, bf *CacheOptimizedBloomFilter, reason EvictReason) {
+			got = append(got, evicted{name, reason})
+		},
+	})
+	cfg := ManagedConfig{Options: []Option{WithBitCount(16 * BitsPerCacheLine), WithHashCount(4)}}
+	for _, name := range []string{"a", "b", "c"} {
+		if _, err := m.Create(name, cfg); err != nil {
+			t.Fatal(err)
+		}
+		clock.Advance(time.Second)
+	}
+	if m.MemoryUsage() != 3*size {
+		t.Errorf("MemoryUsage() = %d, want %d", m.MemoryUsage(), 3*size)
+	}
+
+	m.Get("a")
+	m.Stats(context.Background()) // Not a use
+	m.Create("d", cfg)
+	if want := []evicted{{"b", EvictMemory}}; !slices.Equal(got, want) {
+		t.Errorf("Evicted %v, want %v", got, want)
+	}
+	if names := m.List(); !slices.Equal(names, []string{"a", "c", "d"}) || m.MemoryUsage() != 3*size {
+		t.Errorf("After eviction: %v using %d bytes", names, m.MemoryUsage())
+	}
+
+	large := ManagedConfig{Options: []Option{WithBitCount(64 * BitsPerCacheLine), WithHashCount(4)}}
+	if _, err := m.Create("large", large); !errors.Is(err, ErrMemoryLimit) {
+		t.Errorf("Oversized filter: expected ErrMemoryLimit, got %v", err)
+	}
+	// Rejected before allocating the petabytes it asks for
+	huge := ManagedConfig{Options: []Option{WithExpectedElements(1 << 50)}}
+	if _, err := m.Create("huge", huge); !errors.Is(err, ErrMemoryLimit) {
+		t.Errorf("Huge filter: expected ErrMemoryLimit, got %v", err)
+	}
+	if len(got) != 1 || m.MemoryUsage() != 3*size {
+		t.Errorf("Rejected creates evicted %v or left %d bytes in use", got, m.MemoryUsage())
+	}
+	if EvictMemory.String() != "memory" || EvictIdle.String() != "idle" || EvictExpired.String() != "expired" {
+		t.Error("EvictReason names")
+	}
+}
+
+// TestManagerIdle tests eviction of filters not looked up for MaxIdle
+func TestManagerIdle(t *testing.T) {
+	clock := NewManualClock(time.Unix(1000, 0))
+	var got []string
+	m, _ := NewManager(ManagerConfig{
+		MaxIdle: time.Hour,
+		Clock:   clock,
+		OnEvict: func(name string, bf *CacheOptimizedBloomFilter, reason EvictReason) {
+			if reason != EvictIdle {
+				t.Errorf("Evicted %s for %v", name, reason)
+			}
+			got = append(got, name)
+		},
+	})
+	opts := []Option{WithExpectedElements(1000)}
+	m.Create("busy", ManagedConfig{Options: opts})
+	m.Create("idle", ManagedConfig{Options: opts})
+	m.Create("dropped", ManagedConfig{Options: opts})
+	m.Drop("dropped")
+
+	for i := 0; i < 4; i++ {
+		clock.Advance(20 * time.Minute)
+		m.Get("busy")
+	}
+	m.Sweep()
+	if !slices.Equal(got, []string{"idle"}) || m.MemoryUsage() == 0 {
+		t.Errorf("Evicted %v", got)
+	}
+	if _, ok := m.Get("busy"); !ok {
+		t.Error("Busy filter evicted")
+	}
+	if _, err := NewManager(ManagerConfig{MaxIdle: -time.Second}); err == nil {
+		t.Error("Negative MaxIdle accepted")
+	}
+}
@@ -0,0 +1,55 @@
+package bloomfilter
+
+import (
+	"encoding/binary"
+	"iter"
+	"math/rand/v2"
+)
+
+// MeasureFPP returns the fraction of negatives the filter reports as present,
+// the empirical false positive rate for keys known never to have been added.
+// Feeding keys shaped like production keys catches patterns that interact
+// badly with the hash, which EstimatedFPP cannot see. Each key is probed with
+// every hash function, without adaptive probe reduction, and the probes are
+// not counted by OperationCounters, FPR sampling or a recorder.
+//
+// Returns 0 if negatives is empty.
+func (bf *CacheOptimizedBloomFilter) MeasureFPP(negatives iter.Seq[[]byte]) float64 {
+	positions := make([]uint64, bf.hashCount)
+	var queries, positives uint64
+	for key := range negatives {
+		if bf.probeAll(key, positions) {
+			positives++
+		}
+		queries++
+	}
+	if queries == 0 {
+		return 0
+	}
+	return float64(positives) / float64(queries)
+}
+
+// MeasureFPPSampled is MeasureFPP for n random 16-byte keys drawn from rng, or
+// from the global source if rng is nil. Random keys are absent from any filter
+// whose keys are not themselves random 16-byte strings, and they measure the
+// filter's bit-level false positive rate, which should track EstimatedFPP
+// within sampling error; a persistent gap points at a hash regression. The
+// standard error is about sqrt(fpp/n), so n should be many times 1/fpp.
+//
+// Returns 0 if n is not positive.
+func (bf *CacheOptimizedBloomFilter) MeasureFPPSampled(n int, rng *rand.Rand) float64 {
+	next := rand.Uint64
+	if rng != nil {
+		next = rng.Uint64
+	}
+	return bf.MeasureFPP(func(yield func([]byte) bool) {
+		var key [16]byte
+		for range n {
+			binary.LittleEndian.PutUint64(key[:8], next())
+			binary.LittleEndian.PutUint64(key[8:], next())
+			if !yield(key[:]) {
+				return
+			}
+		}
+	})
+}
@@ -0,0 +1,59 @@
+package bloomfilter
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"testing"
+)
+
+// TestMeasureFPP tests empirical false positive rates against the estimate
+func TestMeasureFPP(t *testing.T) {
+	bf := NewCacheOptimizedBloomFilter(10000, 0.01)
+	if got := bf.MeasureFPPSampled(1000, nil); got != 0 {
+		t.Errorf("Empty filter measured %v", got)
+	}
+	for i := 0; i < 10000; i++ {
+		bf.AddString(fmt.Sprintf("key-%d", i))
+	}
+	bf.EnableOperationCounters()
+
+	negatives := func(yield func([]byte) bool) {
+		for i := 0; i < 200000; i++ {
+			if !yield([]byte(fmt.Sprintf("absent-%d", i))) {
+				return
+			}
+		}
+	}
+	estimate := bf.EstimatedFPP()
+	if got := bf.MeasureFPP(negatives); got < estimate/2 || got > estimate*2 {
+		t.Errorf("MeasureFPP = %.4f, estimate %.4f", got, estimate)
+	}
+	rng := rand.New(rand.NewPCG(1, 2))
+	sampled := bf.MeasureFPPSampled(200000, rng)
+	if sampled < estimate/2 || sampled > estimate*2 {
+		t.Errorf("MeasureFPPSampled = %.4f, estimate %.4f", sampled, estimate)
+	}
+	if again := bf.MeasureFPPSampled(200000, rand.New(rand.NewPCG(1, 2))); again != sampled {
+		t.Errorf("Same seed measured %.4f and %.4f", sampled, again)
+	}
+	if c := bf.OperationCounters(); c.Lookups != 0 {
+		t.Errorf("Measurement counted %d lookups", c.Lookups)
+	}
+
+	members := func(yield func([]byte) bool) {
+		for i := 0; i < 1000; i++ {
+			if !yield([]byte(fmt.Sprintf("key-%d", i))) {
+				return
+			}
+		}
+	}
+	if got := bf.MeasureFPP(members); got != 1 {
+		t.Errorf("Members measured %v, want 1", got)
+	}
+	if got := bf.MeasureFPP(func(func([]byte) bool) {}); got != 0 {
+		t.Errorf("No negatives measured %v", got)
+	}
+	if got := bf.MeasureFPPSampled(0, rng); got != 0 {
+		t.Errorf("n=0 measured %v", got)
+	}
+}
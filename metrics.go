@@ -0,0 +1,142 @@
+package bloomfilter
+
+import (
+	"bufio"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// MetricKind tells counters, which only grow, from gauges
+type MetricKind uint8
+
+const (
+	CounterMetric MetricKind = iota
+	GaugeMetric
+)
+
+// String returns the Prometheus type name of the kind
+func (k MetricKind) String() string {
+	if k == GaugeMetric {
+		return "gauge"
+	}
+	return "counter"
+}
+
+// Metric is one exported value of a filter
+type Metric struct {
+	Name  string
+	Help  string
+	Kind  MetricKind
+	Value float64
+}
+
+// MetricsCollector exports a filter's operation counts and load as metrics, so
+// services can alert on saturation without their own instrumentation. It
+// follows the Describe/Collect split of Prometheus collectors without
+// depending on the client library: a prometheus.Collector wraps Collect in a
+// few lines, WritePrometheus serves the text exposition format directly, and
+// Map plugs into expvar.Func.
+//
+// Creating a collector turns on operation counting (see
+// EnableOperationCounters) and fill tracking (see EnableFillTracking) for the
+// filter, so each collection is O(1).
+type MetricsCollector struct {
+	name string
+	bf   *CacheOptimizedBloomFilter
+	ops  *opCounters
+}
+
+// NewMetricsCollector creates a collector for bf, labeled filter=name
+func NewMetricsCollector(name string, bf *CacheOptimizedBloomFilter) *MetricsCollector {
+	bf.enableBitTracking()
+	return &MetricsCollector{name: name, bf: bf, ops: bf.enableOpCounters()}
+}
+
+// Name returns the filter label of the collector's metrics
+func (c *MetricsCollector) Name() string {
+	return c.name
+}
+
+// metricDescs are the exported metrics in Collect order
+var metricDescs = []Metric{
+	{Name: "bloomfilter_adds_total", Help: "Adds applied to the filter.", Kind: CounterMetric},
+	{Name: "bloomfilter_dropped_adds_total", Help: "Adds dropped by load shedding.", Kind: CounterMetric},
+	{Name: "bloomfilter_lookups_total", Help: "Membership lookups.", Kind: CounterMetric},
+	{Name: "bloomfilter_lookup_positives_total", Help: "Lookups that reported the key present.", Kind: CounterMetric},
+	{Name: "bloomfilter_lookup_negatives_total", Help: "Lookups that reported the key absent.", Kind: CounterMetric},
+	{Name: "bloomfilter_cas_retries_total", Help: "Failed compare-and-swap attempts while setting bits.", Kind: CounterMetric},
+	{Name: "bloomfilter_bits", Help: "Size of the bit array.", Kind: GaugeMetric},
+	{Name: "bloomfilter_bits_set", Help: "Bits set.", Kind: GaugeMetric},
+	{Name: "bloomfilter_load_factor", Help: "Fraction of bits set.", Kind: GaugeMetric},
+	{Name: "bloomfilter_estimated_fpp", Help: "Estimated false positive probability.", Kind: GaugeMetric},
+	{Name: "bloomfilter_estimated_count", Help: "Distinct items added, estimated from the bits set.", Kind: GaugeMetric},
+}
+
+// Describe calls fn with every metric the collector exports, values zero
+func (c *MetricsCollector) Describe(fn func(Metric)) {
+	for _, m := range metricDescs {
+		fn(m)
+	}
+}
+
+// Collect calls fn with the current value of every metric, in Describe order
+func (c *MetricsCollector) Collect(fn func(Metric)) {
+	bf := c.bf
+	lookups, positives := c.ops.lookups.Load(), c.ops.positives.Load()
+	bitsSet := bf.BitsSet()
+	values := [...]float64{
+		float64(c.ops.adds.Load()),
+		float64(bf.dropped.Load()),
+		float64(lookups),
+		float64(positives),
+		float64(lookups - min(positives, lookups)),
+		float64(c.ops.casRetries.Load()),
+		float64(bf.bitCount),
+		float64(bitsSet),
+		float64(bitsSet) / float64(bf.bitCount),
+		bf.fppAt(bitsSet, bf.hashCount),
+		float64(bf.estimateCount(bitsSet)),
+	}
+	for i, m := range metricDescs {
+		m.Value = values[i]
+		fn(m)
+	}
+}
+
+// Map returns the current values by metric name, for expvar:
+//
+//	expvar.Publish("users_filter", expvar.Func(func() any { return c.Map() }))
+func (c *MetricsCollector) Map() map[string]float64 {
+	values := make(map[string]float64, len(metricDescs))
+	c.Collect(func(m Metric) { values[m.Name] = m.Value })
+	return values
+}
+
+// labelEscaper escapes a Prometheus label value
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// WritePrometheus writes the metrics of collectors in the Prometheus text
+// exposition format, each labeled with its collector's name. An HTTP handler
+// serving it is a scrape target:
+//
+//	http.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
+//		bloomfilter.WritePrometheus(w, users, sessions)
+//	})
+func WritePrometheus(w io.Writer, collectors ...*MetricsCollector) error {
+	values := make([][]float64, len(collectors))
+	for i, c := range collectors {
+		c.Collect(func(m Metric) { values[i] = append(values[i], m.Value) })
+	}
+
+	bw := bufio.NewWriter(w)
+	for j, m := range metricDescs {
+		bw.WriteString("# HELP " + m.Name + " " + m.Help + "\n")
+		bw.WriteString("# TYPE " + m.Name + " " + m.Kind.String() + "\n")
+		for i, c := range collectors {
+			bw.WriteString(m.Name + `{filter="` + labelEscaper.Replace(c.name) + `"} `)
+			bw.WriteString(strconv.FormatFloat(values[i][j], 'g', -1, 64) + "\n")
+		}
+	}
+	return bw.Flush()
+}
@@ -0,0 +1,124 @@
+package bloomfilter
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// TestMetricsCollector tests the operation counts across the add and lookup paths
+func TestMetricsCollector(t *testing.T) {
+	bf := NewCacheOptimizedBloomFilter(10000, 0.01)
+	bf.AddString("before") // not counted
+	c := NewMetricsCollector("users", bf)
+
+	bf.AddString("a")
+	bf.AddUint64(1)
+	bf.AddStrings([]string{"b", "c", "d"})
+	bf.AddUint64Batch([]uint64{2, 3})
+	bf.TestAndAddString("a") // an add and a positive lookup
+	bf.ContainsString("a")
+	bf.ContainsString("missing")
+	bf.ContainsStrings([]string{"b", "x", "y"})
+	bf.ContainsUint64Batch([]uint64{1, 1000})
+
+	got := c.Map()
+	want := map[string]float64{
+		"bloomfilter_adds_total":             8,
+		"bloomfilter_lookups_total":          8,
+		"bloomfilter_lookup_positives_total": 4,
+		"bloomfilter_lookup_negatives_total": 4,
+		"bloomfilter_bits":                   float64(bf.BitCount()),
+		"bloomfilter_bits_set":               float64(bf.PopCount()),
+		"bloomfilter_estimated_fpp":          bf.EstimatedFPP(),
+	}
+	for name, v := range want {
+		if got[name] != v {
+			t.Errorf("%s = %g, want %g", name, got[name], v)
+		}
+	}
+
+	var described int
+	c.Describe(func(m Metric) {
+		described++
+		if _, ok := got[m.Name]; !ok || m.Help == "" {
+			t.Errorf("Described metric %q not collected or without help", m.Name)
+		}
+	})
+	if described != len(got) {
+		t.Errorf("Described %d metrics, collected %d", described, len(got))
+	}
+
+	// A second collector shares the counts
+	if NewMetricsCollector("again", bf).Map()["bloomfilter_adds_total"] != 8 {
+		t.Error("Second collector reset the counts")
+	}
+}
+
+// TestMetricsCASRetries tests that concurrent adds to the same words keep every
+// add counted; retries depend on scheduling and are only checked for consistency
+func TestMetricsCASRetries(t *testing.T) {
+	bf := NewCacheOptimizedBloomFilter(10, 0.5)
+	c := NewMetricsCollector("hot", bf)
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 1000; i++ {
+				bf.AddString(fmt.Sprintf("%d-%d", g, i))
+			}
+		}(g)
+	}
+	wg.Wait()
+	m := c.Map()
+	if m["bloomfilter_adds_total"] != 8000 {
+		t.Errorf("adds_total = %g, want 8000", m["bloomfilter_adds_total"])
+	}
+	t.Logf("CAS retries: %g", m["bloomfilter_cas_retries_total"])
+}
+
+// TestWritePrometheus tests the text exposition format
+func TestWritePrometheus(t *testing.T) {
+	a := NewCacheOptimizedBloomFilter(1000, 0.01)
+	b := NewCacheOptimizedBloomFilter(1000, 0.01)
+	ca, cb := NewMetricsCollector("users", a), NewMetricsCollector(`odd "name"`, b)
+	a.AddString("x")
+	a.ContainsString("x")
+
+	var buf bytes.Buffer
+	if err := WritePrometheus(&buf, ca, cb); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	for _, want := range []string{
+		"# HELP bloomfilter_adds_total Adds applied to the filter.\n",
+		"# TYPE bloomfilter_adds_total counter\n",
+		"# TYPE bloomfilter_load_factor gauge\n",
+		"bloomfilter_adds_total{filter=\"users\"} 1\n",
+		"bloomfilter_lookup_positives_total{filter=\"users\"} 1\n",
+		`bloomfilter_adds_total{filter="odd \"name\""} 0` + "\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Output missing %q:\n%s", want, out)
+		}
+	}
+	if n := strings.Count(out, "# TYPE bloomfilter_adds_total"); n != 1 {
+		t.Errorf("TYPE line written %d times, want once", n)
+	}
+}
+
+// TestOpCountersAllocations tests that counting adds no allocations
+func TestOpCountersAllocations(t *testing.T) {
+	bf := NewCacheOptimizedBloomFilter(1000, 0.01)
+	NewMetricsCollector("f", bf)
+	key := []byte("key")
+	if allocs := testing.AllocsPerRun(100, func() {
+		bf.Add(key)
+		bf.Contains(key)
+	}); allocs != 0 {
+		t.Errorf("Counted operations allocated %.0f times", allocs)
+	}
+}
@@ -0,0 +1,90 @@
+package bloomfilter
+
+import (
+	"bufio"
+	"go/build"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// TestCoreModuleHasNoRequirements guards the dependency policy: integrations with
+// third-party dependencies live in nested modules (see arrow/), so importing the
+// core package never adds anything to a user's module graph.
+func TestCoreModuleHasNoRequirements(t *testing.T) {
+	f, err := os.Open("go.mod")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); strings.HasPrefix(line, "require") {
+			t.Errorf("Core go.mod must not require modules, found %q; move the integration to a nested module", line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestMinimalBuildImports tests that the bloomfilter_minimal build tag excludes
+// optional subsystems that pull in large parts of the standard library
+func TestMinimalBuildImports(t *testing.T) {
+	ctx := build.Default
+	ctx.BuildTags = append(ctx.BuildTags, "bloomfilter_minimal")
+	pkg, err := ctx.ImportDir(".", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	excluded := []string{"database/sql", "net", "net/http"}
+	for _, imp := range pkg.Imports {
+		for _, ex := range excluded {
+			if imp == ex {
+				t.Errorf("Minimal build imports %s", imp)
+			}
+		}
+	}
+}
+
+// TestPuregoBuildHasNoAssembly tests that the purego build tag leaves no
+// assembly in the SIMD package and does not import the per-architecture kernels
+func TestPuregoBuildHasNoAssembly(t *testing.T) {
+	for _, arch := range []string{"amd64", "arm64"} {
+		ctx := build.Default
+		ctx.GOARCH = arch
+		ctx.BuildTags = append(ctx.BuildTags, "purego")
+
+		pkg, err := ctx.ImportDir("internal/simd", 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(pkg.SFiles) > 0 {
+			t.Errorf("%s purego build has assembly files %v", arch, pkg.SFiles)
+		}
+		for _, imp := range pkg.Imports {
+			if strings.HasPrefix(imp, "github.com/shaia/BloomFilter/internal/simd/") {
+				t.Errorf("%s purego build imports %s", arch, imp)
+			}
+		}
+
+		root, err := ctx.ImportDir(".", 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !slices.Contains(root.GoFiles, "stringbytes_purego.go") || slices.Contains(root.GoFiles, "stringbytes.go") {
+			t.Errorf("%s purego build converts strings without copying", arch)
+		}
+
+		for _, dir := range []string{"internal/simd/amd64", "internal/simd/arm64"} {
+			if _, err := ctx.ImportDir(dir, 0); err == nil {
+				t.Errorf("%s purego build compiles %s", arch, dir)
+			} else if _, ok := err.(*build.NoGoError); !ok {
+				t.Errorf("%s: %v", dir, err)
+			}
+		}
+	}
+}
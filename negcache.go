@@ -0,0 +1,164 @@
+package bloomfilter
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Verifier checks a key against the authoritative store behind a NegativeCache
+type Verifier func(key []byte) (bool, error)
+
+// NegativeCacheConfig configures a NegativeCache
+type NegativeCacheConfig struct {
+	// Filter holds the keys known to the store. Required.
+	Filter Filter
+	// Verify is consulted when the filter reports a key as possibly present. Required.
+	Verify Verifier
+	// TTL is how long a confirmed-absent key is remembered. Defaults to 1 minute.
+	TTL time.Duration
+	// MaxEntries bounds the confirmed-absent keys held. Defaults to 10000.
+	MaxEntries int
+	// Clock expires entries. Defaults to SystemClock.
+	Clock Clock
+}
+
+// NegativeCacheStats reports where NegativeCache lookups were answered
+type NegativeCacheStats struct {
+	Lookups         uint64 // Total Lookup calls
+	CacheHits       uint64 // Answered "absent" by the TTL map
+	FilterNegatives uint64 // Answered "absent" by the filter
+	Verifications   uint64 // Passed on to Verify
+	FalsePositives  uint64 // Verify reported absent, so the key was cached
+	Entries         int    // Confirmed-absent keys currently held
+}
+
+// NegativeCache answers "is this key in the store" with a Bloom filter in front of
+// a verifier, and remembers the keys the verifier confirmed absent. Those are the
+// filter's false positives; when they are hot, every lookup would otherwise pay
+// for a filter probe and a store round trip. A cached key skips both until its
+// TTL expires.
+//
+// Keys added to the store must be added through Add (or removed with Invalidate),
+// which drops them from the cache. A lookup that races with Add for the same key
+// may still cache it as absent for up to TTL, as with any negative cache.
+type NegativeCache struct {
+	cfg NegativeCacheConfig
+
+	mu     sync.Mutex
+	absent map[string]time.Time // Key to expiry
+
+	lookups         atomic.Uint64
+	cacheHits       atomic.Uint64
+	filterNegatives atomic.Uint64
+	verifications   atomic.Uint64
+	falsePositives  atomic.Uint64
+}
+
+// NewNegativeCache creates a negative cache.
+// Panics if Filter or Verify is nil, or TTL or MaxEntries is negative.
+func NewNegativeCache(cfg NegativeCacheConfig) *NegativeCache {
+	if cfg.Filter == nil || cfg.Verify == nil {
+		panic("bloomfilter: NegativeCache requires a Filter and a Verify function")
+	}
+	if cfg.TTL < 0 || cfg.MaxEntries < 0 {
+		panic(fmt.Sprintf("bloomfilter: TTL and MaxEntries must not be negative, got %v and %d", cfg.TTL, cfg.MaxEntries))
+	}
+	if cfg.TTL == 0 {
+		cfg.TTL = time.Minute
+	}
+	if cfg.MaxEntries == 0 {
+		cfg.MaxEntries = 10000
+	}
+	cfg.Clock = clockOrSystem(cfg.Clock)
+	return &NegativeCache{cfg: cfg, absent: make(map[string]time.Time)}
+}
+
+// Lookup reports whether key is in the store. Absent keys are answered from the
+// cache or the filter where possible; otherwise Verify decides, and a negative
+// answer is cached. Errors from Verify are returned and nothing is cached.
+func (c *NegativeCache) Lookup(key []byte) (bool, error) {
+	c.lookups.Add(1)
+
+	now := c.cfg.Clock.Now()
+	c.mu.Lock()
+	expiry, ok := c.absent[string(key)]
+	if ok && now.Before(expiry) {
+		c.mu.Unlock()
+		c.cacheHits.Add(1)
+		return false, nil
+	}
+	if ok {
+		delete(c.absent, string(key))
+	}
+	c.mu.Unlock()
+
+	if !c.cfg.Filter.Contains(key) {
+		c.filterNegatives.Add(1)
+		return false, nil
+	}
+
+	c.verifications.Add(1)
+	present, err := c.cfg.Verify(key)
+	if err != nil || present {
+		return present, err
+	}
+
+	c.falsePositives.Add(1)
+	c.remember(key, c.cfg.Clock.Now().Add(c.cfg.TTL))
+	return false, nil
+}
+
+// remember caches key as absent until expiry, evicting to stay within MaxEntries
+func (c *NegativeCache) remember(key []byte, expiry time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.absent[string(key)]; !ok && len(c.absent) >= c.cfg.MaxEntries {
+		now := c.cfg.Clock.Now()
+		for k, e := range c.absent {
+			if !now.Before(e) {
+				delete(c.absent, k)
+			}
+		}
+		// Still full of live entries: evict an arbitrary one
+		for k := range c.absent {
+			if len(c.absent) < c.cfg.MaxEntries {
+				break
+			}
+			delete(c.absent, k)
+		}
+	}
+	c.absent[string(key)] = expiry
+}
+
+// Add adds key to the filter and drops it from the cache. Call it after the key
+// has been written to the store.
+func (c *NegativeCache) Add(key []byte) {
+	c.cfg.Filter.Add(key)
+	c.Invalidate(key)
+}
+
+// Invalidate drops key from the cache, so the next lookup consults the filter and
+// the verifier again
+func (c *NegativeCache) Invalidate(key []byte) {
+	c.mu.Lock()
+	delete(c.absent, string(key))
+	c.mu.Unlock()
+}
+
+// Stats returns the lookup counters and the current cache size
+func (c *NegativeCache) Stats() NegativeCacheStats {
+	c.mu.Lock()
+	entries := len(c.absent)
+	c.mu.Unlock()
+	return NegativeCacheStats{
+		Lookups:         c.lookups.Load(),
+		CacheHits:       c.cacheHits.Load(),
+		FilterNegatives: c.filterNegatives.Load(),
+		Verifications:   c.verifications.Load(),
+		FalsePositives:  c.falsePositives.Load(),
+		Entries:         entries,
+	}
+}
@@ -0,0 +1,109 @@
+package bloomfilter
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// maybeFilter reports every key as possibly present, so every lookup reaches the verifier
+type maybeFilter struct{}
+
+func (maybeFilter) Add([]byte)           {}
+func (maybeFilter) Contains([]byte) bool { return true }
+
+// TestNegativeCacheSkipsVerifier tests that confirmed-absent keys skip the
+// verifier until their TTL expires
+func TestNegativeCacheSkipsVerifier(t *testing.T) {
+	clock := NewManualClock(time.Unix(0, 0))
+	store := map[string]bool{"present": true}
+	calls := 0
+	c := NewNegativeCache(NegativeCacheConfig{
+		Filter: maybeFilter{},
+		Verify: func(key []byte) (bool, error) {
+			calls++
+			return store[string(key)], nil
+		},
+		TTL:   time.Second,
+		Clock: clock,
+	})
+
+	for i := 0; i < 3; i++ {
+		if ok, _ := c.Lookup([]byte("hot-absent")); ok {
+			t.Fatal("Absent key reported present")
+		}
+		if ok, _ := c.Lookup([]byte("present")); !ok {
+			t.Fatal("Present key reported absent")
+		}
+	}
+	if calls != 4 {
+		t.Errorf("Expected 1 verification of the absent key and 3 of the present one, got %d calls", calls)
+	}
+
+	clock.Advance(time.Second)
+	c.Lookup([]byte("hot-absent"))
+	if calls != 5 {
+		t.Error("Expired entry did not reach the verifier")
+	}
+
+	// Adding the key to the store invalidates the cached absence
+	store["hot-absent"] = true
+	c.Add([]byte("hot-absent"))
+	if ok, _ := c.Lookup([]byte("hot-absent")); !ok {
+		t.Error("Added key still cached as absent")
+	}
+
+	s := c.Stats()
+	if s.Lookups != 8 || s.CacheHits != 2 || s.Verifications != 6 || s.FalsePositives != 2 || s.Entries != 0 {
+		t.Errorf("Unexpected stats %+v", s)
+	}
+}
+
+// TestNegativeCacheFilterAndErrors tests that filter negatives skip the verifier
+// and that verifier errors are returned without caching
+func TestNegativeCacheFilterAndErrors(t *testing.T) {
+	bf := NewCacheOptimizedBloomFilter(1000, 0.01)
+	bf.AddString("flaky")
+	failing := errors.New("store unavailable")
+	c := NewNegativeCache(NegativeCacheConfig{
+		Filter: bf,
+		Verify: func([]byte) (bool, error) { return false, failing },
+	})
+
+	if ok, err := c.Lookup([]byte("never-added")); ok || err != nil {
+		t.Errorf("Filter negative: %v, %v", ok, err)
+	}
+	if _, err := c.Lookup([]byte("flaky")); !errors.Is(err, failing) {
+		t.Errorf("Expected verifier error, got %v", err)
+	}
+	s := c.Stats()
+	if s.FilterNegatives != 1 || s.Verifications != 1 || s.Entries != 0 {
+		t.Errorf("Unexpected stats %+v", s)
+	}
+}
+
+// TestNegativeCacheBounded tests that the cache never exceeds MaxEntries
+func TestNegativeCacheBounded(t *testing.T) {
+	clock := NewManualClock(time.Unix(0, 0))
+	c := NewNegativeCache(NegativeCacheConfig{
+		Filter:     maybeFilter{},
+		Verify:     func([]byte) (bool, error) { return false, nil },
+		MaxEntries: 10,
+		TTL:        time.Minute,
+		Clock:      clock,
+	})
+	for i := 0; i < 100; i++ {
+		c.Lookup([]byte(fmt.Sprintf("key_%d", i)))
+		if n := c.Stats().Entries; n > 10 {
+			t.Fatalf("Cache grew to %d entries", n)
+		}
+	}
+
+	// Expired entries are evicted first
+	clock.Advance(time.Minute)
+	c.Lookup([]byte("fresh"))
+	if n := c.Stats().Entries; n != 1 {
+		t.Errorf("Expected expired entries to be swept, %d left", n)
+	}
+}
@@ -0,0 +1,5 @@
+//go:build !race
+
+package bloomfilter
+
+const raceEnabled = false
@@ -0,0 +1,179 @@
+package bloomfilter
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+)
+
+// ObjectStoreConfig configures SaveToObjectStore and LoadFromObjectStore
+type ObjectStoreConfig struct {
+	// PartSize is the size of the parts written and of the ranges read, rounded
+	// down to whole 64 KiB checksum chunks of the native format. Defaults to
+	// 8 MiB, above the 5 MiB minimum part size of S3 multipart uploads.
+	PartSize int
+	// Retries is how many more times a part is tried after its put or get
+	// fails, or after a range read fails its checksum. Back off in put or get.
+	Retries int
+}
+
+// defaultPartSize is the default ObjectStoreConfig.PartSize, 8 MiB
+const defaultPartSize = 8 << 20
+
+// ObjectPart is one part of a filter saved by SaveToObjectStore
+type ObjectPart struct {
+	// Number counts the parts from 1 in object order, as multipart uploads do
+	Number int
+	// Offset of the part in the object
+	Offset int64
+	// Data is only valid during the call to put, and is the same on each retry
+	Data []byte
+}
+
+// chunksPerPart returns how many checksum chunks a part holds
+func (c ObjectStoreConfig) chunksPerPart() (int, error) {
+	if c.PartSize < 0 || c.Retries < 0 {
+		return 0, fmt.Errorf("bloomfilter: PartSize and Retries must not be negative, got %d and %d", c.PartSize, c.Retries)
+	}
+	if c.PartSize == 0 {
+		c.PartSize = defaultPartSize
+	}
+	return max(1, c.PartSize/(formatChunkLines*CacheLineSize)), nil
+}
+
+// SaveToObjectStore writes the filter in the native format as a series of
+// parts, calling put for each in order, so a filter of any size can be stored
+// as an S3 or GCS multipart upload while buffering only one part. Each part
+// holds whole checksum chunks, so its boundaries fall on cache lines and
+// depend only on the geometry and PartSize: the first part also holds the
+// header, and the last one the chunk table.
+//
+// A put that fails is retried with the same data, up to cfg.Retries times.
+// SaveToObjectStore returns the size of the object, or the last error of put
+// or ctx's error, in which case the caller aborts the upload. Adds running
+// during the save are in the object or not, as with WriteTo.
+func (bf *CacheOptimizedBloomFilter) SaveToObjectStore(ctx context.Context, cfg ObjectStoreConfig, put func(ctx context.Context, part ObjectPart) error) (int64, error) {
+	perPart, err := cfg.chunksPerPart()
+	if err != nil {
+		return 0, err
+	}
+	chunks := int(formatChunks(bf.cacheLineCount))
+	part := make([]byte, 0, formatHeaderSize+min(perPart*formatChunkLines, len(bf.cacheLines))*CacheLineSize)
+	sums := make([]uint32, 0, chunks)
+	var offset int64
+	for number, chunk := 1, 0; chunk < chunks; number++ {
+		part = part[:0]
+		if number == 1 {
+			part = bf.appendHeader(part)
+		}
+		for end := min(chunk+perPart, chunks); chunk < end; chunk++ {
+			start := len(part)
+			part = bf.appendWords(part, chunk*formatChunkLines)
+			sums = append(sums, crc32.Checksum(part[start:], castagnoli))
+		}
+		if chunk == chunks {
+			part = appendChunkTable(part, sums)
+		}
+		p := ObjectPart{Number: number, Offset: offset, Data: part}
+		if err := withRetries(ctx, cfg.Retries, func() error { return put(ctx, p) }); err != nil {
+			return offset, err
+		}
+		offset += int64(len(part))
+	}
+	return offset, nil
+}
+
+// LoadFromObjectStore reads a filter saved in the native format (version 2)
+// through get, which returns length bytes of the object from offset, as an
+// HTTP range request does. The header and chunk table are read first, then
+// the words in ranges of PartSize, each verified against its chunk checksums
+// and fetched again on a mismatch or error, up to cfg.Retries times, so one
+// bad transfer does not fail a multi-gigabyte load.
+//
+// Errors for malformed data wrap ErrInvalidEncoding; a range that still fails
+// its checksum is a *CorruptDataError. Otherwise it returns the last error of
+// get, or ctx's error.
+func LoadFromObjectStore(ctx context.Context, cfg ObjectStoreConfig, get func(ctx context.Context, offset, length int64) ([]byte, error)) (*CacheOptimizedBloomFilter, error) {
+	perPart, err := cfg.chunksPerPart()
+	if err != nil {
+		return nil, err
+	}
+	read := func(offset, length int64, check func([]byte) error) error {
+		return withRetries(ctx, cfg.Retries, func() error {
+			data, err := get(ctx, offset, length)
+			if err != nil {
+				return err
+			}
+			if int64(len(data)) != length {
+				return fmt.Errorf("%w: %d bytes at offset %d, want %d", ErrCorruptData, len(data), offset, length)
+			}
+			return check(data)
+		})
+	}
+
+	var h formatHeader
+	if err := read(0, formatHeaderSize, func(data []byte) (err error) {
+		h, err = parseHeader(data)
+		return err
+	}); err != nil {
+		return nil, err
+	}
+	if h.version != FormatVersion {
+		return nil, fmt.Errorf("%w: version %d has no chunk checksums; use ReadFrom", ErrInvalidEncoding, h.version)
+	}
+	wordsEnd := int64(formatHeaderSize + h.cacheLineCount*CacheLineSize)
+	var table []byte
+	if err := read(wordsEnd, int64(h.size())-wordsEnd, func(data []byte) error {
+		table = data
+		return verifyChunks(data, nil, h.cacheLineCount)
+	}); err != nil {
+		return nil, err
+	}
+
+	bf := newFilter(h.cacheLineCount, h.hashCount)
+	bf.seed = h.seed
+	bf.probe = h.probe
+	bf.hashVersion = h.hashVersion
+	chunkBytes := int64(formatChunkLines * CacheLineSize)
+	chunks := int(formatChunks(h.cacheLineCount))
+	for first := 0; first < chunks; first += perPart {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		offset := formatHeaderSize + int64(first)*chunkBytes
+		length := min(int64(perPart)*chunkBytes, wordsEnd-offset)
+		if err := read(offset, length, func(data []byte) error {
+			for i := 0; int64(i)*chunkBytes < length; i++ {
+				chunk := data[int64(i)*chunkBytes : min(int64(i+1)*chunkBytes, length)]
+				if crc32.Checksum(chunk, castagnoli) != binary.LittleEndian.Uint32(table[4*(first+i):]) {
+					return &CorruptDataError{Region: "words", Offset: offset + int64(i)*chunkBytes, Length: int64(len(chunk))}
+				}
+			}
+			lines := bf.cacheLines[first*formatChunkLines:]
+			for i := range int(length / CacheLineSize) {
+				for j := range lines[i].words {
+					lines[i].words[j] = binary.LittleEndian.Uint64(data[(i*WordsPerCacheLine+j)*8:])
+				}
+			}
+			return nil
+		}); err != nil {
+			return nil, err
+		}
+	}
+	return bf, nil
+}
+
+// withRetries calls fn until it succeeds, it has been retried retries times,
+// or ctx is done
+func withRetries(ctx context.Context, retries int, fn func() error) error {
+	for attempt := 0; ; attempt++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		err := fn()
+		if err == nil || attempt >= retries {
+			return err
+		}
+	}
+}
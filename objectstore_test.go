@@ -0,0 +1,120 @@
+package bloomfilter
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+)
+
+// TestObjectStore tests saving in cache-line aligned parts and loading by ranges
+func TestObjectStore(t *testing.T) {
+	bf := NewCacheOptimizedBloomFilter(300000, 0.01) // 6 checksum chunks
+	for i := uint64(0); i < 300000; i++ {
+		bf.AddUint64(i)
+	}
+	cfg := ObjectStoreConfig{PartSize: 2 * formatChunkLines * CacheLineSize}
+
+	var object []byte
+	var parts []ObjectPart
+	size, err := bf.SaveToObjectStore(context.Background(), cfg, func(_ context.Context, p ObjectPart) error {
+		if p.Offset != int64(len(object)) {
+			t.Errorf("Part %d at offset %d, want %d", p.Number, p.Offset, len(object))
+		}
+		parts = append(parts, ObjectPart{Number: p.Number, Offset: p.Offset, Data: bytes.Clone(p.Data)})
+		object = append(object, p.Data...)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, _ := bf.MarshalBinary()
+	if !bytes.Equal(object, want) || size != int64(len(want)) {
+		t.Fatal("Parts do not concatenate to the native encoding")
+	}
+	if len(parts) != 3 || parts[2].Number != 3 {
+		t.Fatalf("%d parts", len(parts))
+	}
+	if (len(parts[0].Data)-formatHeaderSize)%CacheLineSize != 0 || len(parts[1].Data) != cfg.PartSize {
+		t.Errorf("Part sizes %d and %d are not aligned", len(parts[0].Data), len(parts[1].Data))
+	}
+
+	var gets int
+	loaded, err := LoadFromObjectStore(context.Background(), cfg, func(_ context.Context, offset, length int64) ([]byte, error) {
+		gets++
+		if length > int64(cfg.PartSize) && offset != 0 {
+			t.Errorf("Range of %d bytes exceeds PartSize", length)
+		}
+		return object[offset : offset+length], nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !loaded.Equal(bf) || gets != 5 {
+		t.Errorf("Loaded filter differs, %d gets", gets)
+	}
+
+	// Defaults: one part for a small filter
+	small := NewCacheOptimizedBloomFilter(100, 0.01)
+	var n int
+	small.SaveToObjectStore(context.Background(), ObjectStoreConfig{}, func(context.Context, ObjectPart) error { n++; return nil })
+	if n != 1 {
+		t.Errorf("Small filter saved in %d parts", n)
+	}
+	if _, err := small.SaveToObjectStore(context.Background(), ObjectStoreConfig{PartSize: -1}, nil); err == nil {
+		t.Error("Negative PartSize accepted")
+	}
+}
+
+// TestObjectStoreRetries tests that failed puts and damaged ranges are retried
+func TestObjectStoreRetries(t *testing.T) {
+	bf := NewCacheOptimizedBloomFilter(300000, 0.01)
+	bf.AddString("key")
+	object, _ := bf.MarshalBinary()
+	ctx := context.Background()
+	transient := errors.New("transient")
+
+	failures := map[int]int{2: 1}
+	put := func(_ context.Context, p ObjectPart) error {
+		if failures[p.Number] > 0 {
+			failures[p.Number]--
+			return transient
+		}
+		return nil
+	}
+	cfg := ObjectStoreConfig{PartSize: 2 * formatChunkLines * CacheLineSize}
+	if _, err := bf.SaveToObjectStore(ctx, cfg, put); !errors.Is(err, transient) {
+		t.Errorf("Without retries: expected the put error, got %v", err)
+	}
+	failures[2] = 1
+	cfg.Retries = 1
+	if _, err := bf.SaveToObjectStore(ctx, cfg, put); err != nil {
+		t.Errorf("With a retry: %v", err)
+	}
+
+	// The first read of the second words range is damaged in transit
+	damaged := 0
+	get := func(_ context.Context, offset, length int64) ([]byte, error) {
+		data := bytes.Clone(object[offset : offset+length])
+		if offset == formatHeaderSize+int64(cfg.PartSize) && damaged == 0 {
+			damaged++
+			data[100] ^= 1
+		}
+		return data, nil
+	}
+	if loaded, err := LoadFromObjectStore(ctx, cfg, get); err != nil || !loaded.Equal(bf) {
+		t.Errorf("With a retry: %v", err)
+	}
+	damaged = 0
+	cfg.Retries = 0
+	var cde *CorruptDataError
+	if _, err := LoadFromObjectStore(ctx, cfg, get); !errors.As(err, &cde) || cde.Offset != formatHeaderSize+int64(cfg.PartSize) {
+		t.Errorf("Without retries: expected a CorruptDataError at the second range, got %v", err)
+	}
+
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, err := LoadFromObjectStore(canceled, cfg, get); !errors.Is(err, context.Canceled) {
+		t.Errorf("Canceled: got %v", err)
+	}
+}
@@ -0,0 +1,101 @@
+package bloomfilter
+
+import "sync/atomic"
+
+// OperationCounters is a snapshot of a filter's operation counts (see
+// EnableOperationCounters). Batch operations count one per key.
+type OperationCounters struct {
+	Enabled bool
+	// Adds applied to the bits, including TestAndAdd; adds dropped by load
+	// shedding are counted in DroppedAdds instead
+	Adds uint64
+	// Lookups by Contains and its variants and by TestAndAdd, and how many of
+	// them reported the key present
+	Lookups   uint64
+	Positives uint64
+	// CASRetries is the number of failed compare-and-swap attempts while
+	// setting bits, a measure of write contention
+	CASRetries uint64
+}
+
+// WithOperationCounters enables operation counting from construction (see
+// EnableOperationCounters)
+func WithOperationCounters() Option {
+	return func(o *options) { o.opCounters = true }
+}
+
+// EnableOperationCounters starts counting adds, lookups, positive lookups and
+// CAS retries, for capacity planning. Counting is off by default: every add
+// and lookup then pays only for loading a nil pointer; when on, each pays for
+// one or two atomic adds on counters shared by all goroutines. Calling it again
+// keeps the counts. Lookups through a FilterSet are not counted.
+func (bf *CacheOptimizedBloomFilter) EnableOperationCounters() {
+	bf.enableOpCounters()
+}
+
+// OperationCounters returns the operation counts, zero if counting is off
+func (bf *CacheOptimizedBloomFilter) OperationCounters() OperationCounters {
+	c := bf.ops.Load()
+	if c == nil {
+		return OperationCounters{}
+	}
+	return OperationCounters{
+		Enabled:    true,
+		Adds:       c.adds.Load(),
+		Lookups:    c.lookups.Load(),
+		Positives:  c.positives.Load(),
+		CASRetries: c.casRetries.Load(),
+	}
+}
+
+// ResetOperationCounters zeroes the operation counts, keeping counting on
+func (bf *CacheOptimizedBloomFilter) ResetOperationCounters() {
+	if c := bf.ops.Load(); c != nil {
+		c.adds.Store(0)
+		c.lookups.Store(0)
+		c.positives.Store(0)
+		c.casRetries.Store(0)
+	}
+}
+
+// opCounters holds the operation counts behind OperationCounters and the
+// metrics of a MetricsCollector
+type opCounters struct {
+	adds       atomic.Uint64
+	lookups    atomic.Uint64
+	positives  atomic.Uint64
+	casRetries atomic.Uint64
+}
+
+// enableOpCounters starts counting operations, keeping the counts if already on
+func (bf *CacheOptimizedBloomFilter) enableOpCounters() *opCounters {
+	if c := bf.ops.Load(); c != nil {
+		return c
+	}
+	bf.ops.CompareAndSwap(nil, &opCounters{})
+	return bf.ops.Load()
+}
+
+// countLookup counts one lookup and its answer
+func (c *opCounters) countLookup(present bool) {
+	c.lookups.Add(1)
+	if present {
+		c.positives.Add(1)
+	}
+}
+
+// countLookups counts a batch of lookups with their answers
+func (bf *CacheOptimizedBloomFilter) countLookups(results []bool) {
+	c := bf.ops.Load()
+	if c == nil {
+		return
+	}
+	var positives uint64
+	for _, present := range results {
+		if present {
+			positives++
+		}
+	}
+	c.lookups.Add(uint64(len(results)))
+	c.positives.Add(positives)
+}
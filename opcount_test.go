@@ -0,0 +1,66 @@
+package bloomfilter
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+// TestOperationCounters tests the counts, their reset and their place in the stats
+func TestOperationCounters(t *testing.T) {
+	bf := NewCacheOptimizedBloomFilter(1000, 0.01)
+	bf.AddString("uncounted")
+	if got := bf.OperationCounters(); got != (OperationCounters{}) {
+		t.Fatalf("Counters before enabling: %+v", got)
+	}
+
+	bf.EnableOperationCounters()
+	bf.AddString("a")
+	bf.AddStrings([]string{"b", "c"})
+	bf.ContainsString("a")
+	bf.ContainsString("zz")
+	bf.TestAndAddString("b")
+	want := OperationCounters{Enabled: true, Adds: 4, Lookups: 3, Positives: 2}
+	if got := bf.OperationCounters(); got != want {
+		t.Errorf("OperationCounters() = %+v, want %+v", got, want)
+	}
+	if got := bf.GetCacheStats().Operations; got != want {
+		t.Errorf("CacheStats.Operations = %+v, want %+v", got, want)
+	}
+	if s := bf.GetCacheStats().Report(); !strings.Contains(s, "ops: adds=4 lookups=3 positives=2") {
+		t.Errorf("Report() missing the counts:\n%s", s)
+	}
+
+	bf.EnableOperationCounters()
+	if bf.OperationCounters().Adds != 4 {
+		t.Error("Enabling again reset the counts")
+	}
+	bf.ResetOperationCounters()
+	if got := bf.OperationCounters(); got != (OperationCounters{Enabled: true}) {
+		t.Errorf("Counters after reset: %+v", got)
+	}
+
+	// Dropped adds are not applied
+	bf.SetMaxLoadFactor(0.0001)
+	bf.AddString("dropped")
+	if got := bf.OperationCounters().Adds; got != 0 || bf.Dropped() != 1 {
+		t.Errorf("Dropped add: Adds %d, Dropped %d", got, bf.Dropped())
+	}
+}
+
+// TestWithOperationCounters tests enabling the counters at construction
+func TestWithOperationCounters(t *testing.T) {
+	bf, err := NewWithOptions(WithExpectedElements(1000), WithOperationCounters())
+	if err != nil {
+		t.Fatal(err)
+	}
+	bf.AddUint64(1)
+	bf.ContainsUint64Batch([]uint64{1, 1000})
+	stats, _ := bf.Stats(context.Background(), SkipBitStats())
+	if got := stats.Operations; !got.Enabled || got.Adds != 1 || got.Lookups != 2 || got.Positives != 1 {
+		t.Errorf("Operations = %+v", got)
+	}
+	if bf.Clone().OperationCounters().Enabled {
+		t.Error("Clone copied the operation counters")
+	}
+}
@@ -0,0 +1,227 @@
+package bloomfilter
+
+import (
+	"fmt"
+	"math"
+	"math/bits"
+
+	"github.com/shaia/BloomFilter/internal/simd"
+)
+
+// Option configures a filter created by NewWithOptions
+type Option func(*options)
+
+type options struct {
+	expectedElements  uint64
+	falsePositiveRate float64
+	bitCount          uint64
+	hashCount         uint32
+	seed              uint64
+	fixedSeed         bool
+	probe             ProbeScheme
+	powerOfTwo        bool
+	disableSIMD       bool
+	opCounters        bool
+	maxMemory         uint64
+	hashVersion       HashVersion
+	hasher            HashFunc
+}
+
+// WithExpectedElements sizes the filter for n elements
+func WithExpectedElements(n uint64) Option {
+	return func(o *options) { o.expectedElements = n }
+}
+
+// WithFalsePositiveRate sets the target false positive rate used with
+// WithExpectedElements. Defaults to 0.01.
+func WithFalsePositiveRate(p float64) Option {
+	return func(o *options) { o.falsePositiveRate = p }
+}
+
+// WithBitCount pins the exact bit count m, which must be a positive multiple of
+// BitsPerCacheLine. It overrides the size derived from WithExpectedElements.
+func WithBitCount(m uint64) Option {
+	return func(o *options) { o.bitCount = m }
+}
+
+// WithHashCount pins the exact number of hash functions k. Without it, k is
+// derived from the bit count and WithExpectedElements.
+func WithHashCount(k uint32) Option {
+	return func(o *options) { o.hashCount = k }
+}
+
+// WithSeed fixes the seed of the hash functions (see Seed) instead of drawing
+// a random one, for reproducible bit positions or filters built independently
+// that must combine. Seed 0 gives the unseeded hash functions of earlier
+// releases.
+func WithSeed(seed uint64) Option {
+	return func(o *options) { o.seed, o.fixedSeed = seed, true }
+}
+
+// WithRandomSeed undoes an earlier WithSeed, restoring the default random
+// seed (see RandomSeed)
+func WithRandomSeed() Option {
+	return func(o *options) { o.seed, o.fixedSeed = 0, false }
+}
+
+// WithPowerOfTwoSize rounds the bit count up to a power of two, so probes are
+// reduced to bit positions with a mask instead of a division, which is
+// measurably faster at high hash counts. The filter uses up to twice the memory
+// and has a lower false positive rate than requested. A size pinned with
+// WithBitCount is rounded too. Without WithHashCount the hash count is still
+// the one derived for the requested size, since the extra bits already meet
+// the target rate.
+func WithPowerOfTwoSize() Option {
+	return func(o *options) { o.powerOfTwo = true }
+}
+
+// WithMaxMemory caps the bit array at maxBytes. A size derived from
+// WithExpectedElements that does not fit is reduced to the cache lines that
+// do (the largest power of two of them with WithPowerOfTwoSize), and the hash
+// count is chosen for the reduced size unless pinned with WithHashCount. The
+// filter then misses its target rate; AchievedFPR and CacheStats report the
+// rate it has at the expected number of keys.
+//
+// NewWithOptions returns an error if maxBytes holds no cache line or a size
+// pinned with WithBitCount exceeds it.
+func WithMaxMemory(maxBytes uint64) Option {
+	return func(o *options) { o.maxMemory = maxBytes }
+}
+
+// WithSIMDDisabled makes bulk operations (Union, Intersection, Difference,
+// PopCount, Clear) use the portable scalar implementation, for debugging or for
+// comparing against the SIMD kernels
+func WithSIMDDisabled() Option {
+	return func(o *options) { o.disableSIMD = true }
+}
+
+// NewWithOptions creates a filter from options. It is the constructor for
+// matching filters built by other systems, which needs exact m and k rather
+// than the ones derived from (n, fpr):
+//
+//	bf, err := NewWithOptions(WithBitCount(1<<20), WithHashCount(7), WithSeed(42))
+//
+// The size comes from WithBitCount, or from WithExpectedElements and
+// WithFalsePositiveRate. The hash count comes from WithHashCount, or is the
+// optimal one for the size and WithExpectedElements.
+//
+// Returns an error if the options do not determine a size and hash count, or
+// if any of them is invalid.
+func NewWithOptions(opts ...Option) (*CacheOptimizedBloomFilter, error) {
+	o := newOptions(opts)
+	cacheLineCount, hashCount, err := o.geometry()
+	if err != nil {
+		return nil, err
+	}
+	return o.build(cacheLineCount, hashCount), nil
+}
+
+// MemoryFor returns the MemoryUsage of the filter NewWithOptions(opts...)
+// would create, without allocating it, or the error NewWithOptions would
+// return. It lets a caller enforce a memory budget before allocating.
+func MemoryFor(opts ...Option) (uint64, error) {
+	o := newOptions(opts)
+	cacheLineCount, _, err := o.geometry()
+	if err != nil {
+		return 0, err
+	}
+	return cacheLineCount * CacheLineSize, nil
+}
+
+// newOptions applies opts to the defaults
+func newOptions(opts []Option) options {
+	o := options{falsePositiveRate: 0.01, probe: DefaultProbeScheme, hashVersion: DefaultHashVersion}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// geometry returns the cache line and hash counts of the options, or why they
+// are invalid
+func (o *options) geometry() (cacheLineCount uint64, hashCount uint32, err error) {
+	hashCount = o.hashCount
+	switch {
+	case o.bitCount > 0:
+		if o.bitCount%BitsPerCacheLine != 0 || o.bitCount/BitsPerCacheLine > math.MaxInt/CacheLineSize {
+			return 0, 0, fmt.Errorf("bloomfilter: bitCount must be a multiple of %d that can be allocated, got %d", BitsPerCacheLine, o.bitCount)
+		}
+		cacheLineCount = o.bitCount / BitsPerCacheLine
+		if hashCount == 0 && o.expectedElements > 0 {
+			hashCount = max(1, uint32(float64(o.bitCount)*math.Ln2/float64(o.expectedElements)))
+		}
+	case o.expectedElements > 0:
+		if err := checkSizing(o.expectedElements, o.falsePositiveRate); err != nil {
+			return 0, 0, err
+		}
+		var k uint32
+		cacheLineCount, k = optimalGeometry(o.expectedElements, o.falsePositiveRate)
+		if hashCount == 0 {
+			hashCount = k
+		}
+	default:
+		return 0, 0, fmt.Errorf("bloomfilter: NewWithOptions requires WithBitCount or WithExpectedElements")
+	}
+	if hashCount == 0 {
+		return 0, 0, fmt.Errorf("bloomfilter: WithBitCount without WithExpectedElements requires WithHashCount")
+	}
+	if !o.probe.valid() {
+		return 0, 0, fmt.Errorf("bloomfilter: unknown probe scheme %d", uint8(o.probe))
+	}
+	if !o.hashVersion.valid() {
+		return 0, 0, fmt.Errorf("bloomfilter: unknown hash version %d", uint8(o.hashVersion))
+	}
+	if o.powerOfTwo {
+		rounded := uint64(1) << bits.Len64(cacheLineCount-1)
+		if rounded > math.MaxInt/CacheLineSize {
+			return 0, 0, fmt.Errorf("bloomfilter: %d cache lines cannot be rounded up to an allocatable power of two", cacheLineCount)
+		}
+		cacheLineCount = rounded
+	}
+	if o.maxMemory > 0 {
+		maxLines := o.maxMemory / CacheLineSize
+		if maxLines == 0 {
+			return 0, 0, fmt.Errorf("bloomfilter: WithMaxMemory(%d) does not hold a %d-byte cache line", o.maxMemory, CacheLineSize)
+		}
+		if cacheLineCount > maxLines {
+			if o.bitCount > 0 {
+				return 0, 0, fmt.Errorf("bloomfilter: bitCount %d exceeds WithMaxMemory(%d)", o.bitCount, o.maxMemory)
+			}
+			cacheLineCount = maxLines
+			if o.powerOfTwo {
+				cacheLineCount = uint64(1) << (bits.Len64(maxLines) - 1)
+			}
+			if o.hashCount == 0 {
+				hashCount = max(1, uint32(float64(cacheLineCount*BitsPerCacheLine)*math.Ln2/float64(o.expectedElements)))
+			}
+		}
+	}
+
+	return cacheLineCount, hashCount, nil
+}
+
+// build creates the filter of the options with the given geometry
+func (o *options) build(cacheLineCount uint64, hashCount uint32) *CacheOptimizedBloomFilter {
+	bf := newFilter(cacheLineCount, hashCount)
+	bf.expectedElements = o.expectedElements
+	bf.seed = o.seed
+	if !o.fixedSeed {
+		bf.seed = RandomSeed()
+	}
+	bf.probe = o.probe
+	bf.hashVersion = o.hashVersion
+	bf.hasher = o.hasher
+	if o.disableSIMD {
+		bf.simdOps = &simd.FallbackOperations{}
+	}
+	if o.opCounters {
+		bf.enableOpCounters()
+	}
+	return bf
+}
+
+// simdEnabled reports whether the filter's bulk operations use SIMD kernels
+func (bf *CacheOptimizedBloomFilter) simdEnabled() bool {
+	_, fallback := bf.simdOps.(*simd.FallbackOperations)
+	return simd.HasAny() && !fallback
+}
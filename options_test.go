@@ -0,0 +1,228 @@
+package bloomfilter
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+)
+
+// TestNewWithOptionsMatchesConstructor tests that (n, fpr) options size the filter
+// exactly like NewCacheOptimizedBloomFilter
+func TestNewWithOptionsMatchesConstructor(t *testing.T) {
+	bf, err := NewWithOptions(WithExpectedElements(10000), WithFalsePositiveRate(0.001))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := NewCacheOptimizedBloomFilter(10000, 0.001)
+	if bf.BitCount() != want.BitCount() || bf.HashCount() != want.HashCount() {
+		t.Errorf("Got m=%d k=%d, want m=%d k=%d", bf.BitCount(), bf.HashCount(), want.BitCount(), want.HashCount())
+	}
+
+	// The default FPR is 0.01
+	bf, _ = NewWithOptions(WithExpectedElements(10000))
+	if bf.BitCount() != NewCacheOptimizedBloomFilter(10000, 0.01).BitCount() {
+		t.Error("Default false positive rate is not 0.01")
+	}
+}
+
+// TestNewWithOptionsRandomSeed tests that the constructors draw a random seed
+// per filter unless given WithSeed, and that random seeds move every key's bits
+func TestNewWithOptionsRandomSeed(t *testing.T) {
+	a, _ := NewWithOptions(WithExpectedElements(1000))
+	b := NewCacheOptimizedBloomFilter(1000, 0.01)
+	c, _ := NewWithError(1000, 0.01)
+	if a.Seed() == 0 || a.Seed() == b.Seed() || b.Seed() == c.Seed() {
+		t.Fatalf("Expected distinct nonzero seeds, got %#x, %#x and %#x", a.Seed(), b.Seed(), c.Seed())
+	}
+	if !errors.Is(a.Compatible(b), ErrSeedMismatch) {
+		t.Error("Filters with different random seeds must not be compatible")
+	}
+	if reset, _ := NewWithOptions(WithExpectedElements(1000), WithSeed(0), WithRandomSeed()); reset.Seed() == 0 {
+		t.Error("WithRandomSeed did not undo WithSeed")
+	}
+
+	a.AddString("key")
+	b.AddString("key")
+	unseeded, _ := NewWithOptions(WithExpectedElements(1000), WithSeed(0))
+	unseeded.AddString("key")
+	if slices.Equal(a.Words(), b.Words()) || slices.Equal(a.Words(), unseeded.Words()) {
+		t.Error("A random seed must change the key's bit positions")
+	}
+
+	// Fixing the seed reproduces the filter
+	fixed, _ := NewWithOptions(WithExpectedElements(1000), WithSeed(a.Seed()))
+	fixed.AddString("key")
+	if !fixed.Equal(a) {
+		t.Error("WithSeed(Seed()) must reproduce the filter")
+	}
+}
+
+// TestNewWithOptionsPinned tests that pinned m, k and seed reproduce a filter
+// built elsewhere, bit for bit
+func TestNewWithOptionsPinned(t *testing.T) {
+	other := NewCacheOptimizedBloomFilter(5000, 0.01).Reseeded(42, NewMemoryKeyLog())
+	for i := 0; i < 5000; i++ {
+		other.AddString(fmt.Sprintf("key_%d", i))
+	}
+
+	bf, err := NewWithOptions(WithBitCount(other.BitCount()), WithHashCount(other.HashCount()), WithSeed(42))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 5000; i++ {
+		bf.AddString(fmt.Sprintf("key_%d", i))
+	}
+	if err := bf.Union(other); err != nil {
+		t.Fatal(err)
+	}
+	if bf.PopCount() != other.PopCount() {
+		t.Error("Pinned filter set different bits for the same keys")
+	}
+
+	// k derived from a pinned m and n
+	bf, _ = NewWithOptions(WithBitCount(64*BitsPerCacheLine), WithExpectedElements(3000))
+	if bf.HashCount() != 7 {
+		t.Errorf("Expected k=7 for m/n=%.1f, got %d", float64(64*BitsPerCacheLine)/3000, bf.HashCount())
+	}
+}
+
+// TestNewWithOptionsSIMDDisabled tests that the scalar path gives the same results
+func TestNewWithOptionsSIMDDisabled(t *testing.T) {
+	scalar, _ := NewWithOptions(WithExpectedElements(10000), WithSIMDDisabled())
+	vector, _ := NewWithOptions(WithExpectedElements(10000), WithSeed(scalar.Seed()))
+	other, _ := NewWithOptions(WithExpectedElements(10000), WithSeed(scalar.Seed()))
+	for i := 0; i < 5000; i++ {
+		scalar.AddUint64(uint64(i))
+		vector.AddUint64(uint64(i))
+		other.AddUint64(uint64(i * 7))
+	}
+	scalar.Union(other)
+	vector.Union(other)
+	if scalar.PopCount() != vector.PopCount() {
+		t.Error("Scalar and SIMD unions differ")
+	}
+	if scalar.GetCacheStats().SIMDEnabled {
+		t.Error("SIMDEnabled reported for a filter created WithSIMDDisabled")
+	}
+
+	// Unmarshaling keeps the choice
+	data, _ := vector.MarshalBinary()
+	scalar.UnmarshalBinary(data)
+	if scalar.GetCacheStats().SIMDEnabled {
+		t.Error("UnmarshalBinary re-enabled SIMD")
+	}
+}
+
+// TestNewWithOptionsPowerOfTwo tests that WithPowerOfTwoSize rounds the size up
+// and keeps the hash count of the requested size
+func TestNewWithOptionsPowerOfTwo(t *testing.T) {
+	exact, _ := NewWithOptions(WithExpectedElements(10000))
+	bf, err := NewWithOptions(WithExpectedElements(10000), WithPowerOfTwoSize())
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := bf.BitCount()
+	if m&(m-1) != 0 || m < exact.BitCount() || m >= 2*exact.BitCount() {
+		t.Errorf("Rounded %d bits to %d, want the next power of two", exact.BitCount(), m)
+	}
+	if bf.HashCount() != exact.HashCount() {
+		t.Errorf("Hash count %d, want %d of the requested size", bf.HashCount(), exact.HashCount())
+	}
+
+	pinned, _ := NewWithOptions(WithBitCount(3*BitsPerCacheLine), WithHashCount(5), WithPowerOfTwoSize())
+	if pinned.BitCount() != 4*BitsPerCacheLine {
+		t.Errorf("Rounded a pinned %d bits to %d, want %d", 3*BitsPerCacheLine, pinned.BitCount(), 4*BitsPerCacheLine)
+	}
+	already, _ := NewWithOptions(WithBitCount(8*BitsPerCacheLine), WithHashCount(5), WithPowerOfTwoSize())
+	if already.BitCount() != 8*BitsPerCacheLine {
+		t.Errorf("Rounded a power of two %d to %d", 8*BitsPerCacheLine, already.BitCount())
+	}
+
+	// Masked indexing probes the same bits as a filter of that size built without the option
+	same, _ := NewWithOptions(WithBitCount(m), WithHashCount(bf.HashCount()), WithSeed(bf.Seed()))
+	for i := 0; i < 5000; i++ {
+		bf.AddUint64(uint64(i))
+		same.AddUint64(uint64(i))
+	}
+	if !bf.Equal(same) {
+		t.Error("Power-of-two filter set different bits than an equal-sized filter")
+	}
+}
+
+// TestNewWithOptionsMaxMemory tests clamping to a memory budget
+func TestNewWithOptionsMaxMemory(t *testing.T) {
+	const budget = 1 << 20
+	bf, err := NewWithOptions(WithExpectedElements(10_000_000), WithMaxMemory(budget))
+	if err != nil {
+		t.Fatal(err)
+	}
+	stats := bf.GetCacheStats()
+	if stats.MemoryUsage != budget {
+		t.Errorf("Memory %d, want the %d budget", stats.MemoryUsage, budget)
+	}
+	// k is optimal for the reduced m: m/n*ln2 = 0.58, rounded down to the minimum of 1
+	if bf.HashCount() != 1 {
+		t.Errorf("Hash count %d, want 1", bf.HashCount())
+	}
+	if stats.ExpectedElements != 10_000_000 || stats.AchievedFPR < 0.5 {
+		t.Errorf("Expected %d, achieved FPR %g; want the degraded rate", stats.ExpectedElements, stats.AchievedFPR)
+	}
+
+	// A size within the budget is kept
+	fits, _ := NewWithOptions(WithExpectedElements(1000), WithMaxMemory(budget))
+	if want := NewCacheOptimizedBloomFilter(1000, 0.01); fits.BitCount() != want.BitCount() || fits.HashCount() != want.HashCount() {
+		t.Error("Budget changed a filter that fits")
+	}
+
+	pow2, _ := NewWithOptions(WithExpectedElements(10_000_000), WithMaxMemory(3*budget), WithPowerOfTwoSize())
+	if pow2.GetCacheStats().MemoryUsage != 2*budget {
+		t.Errorf("Power-of-two memory %d, want %d", pow2.GetCacheStats().MemoryUsage, 2*budget)
+	}
+
+	for name, opts := range map[string][]Option{
+		"budget below a line": {WithExpectedElements(1000), WithMaxMemory(CacheLineSize - 1)},
+		"pinned size over":    {WithBitCount(2 * BitsPerCacheLine), WithHashCount(3), WithMaxMemory(CacheLineSize)},
+	} {
+		if _, err := NewWithOptions(opts...); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+// TestMemoryFor tests that MemoryFor predicts NewWithOptions without allocating
+func TestMemoryFor(t *testing.T) {
+	for _, opts := range [][]Option{
+		{WithExpectedElements(1000)},
+		{WithExpectedElements(10_000_000), WithMaxMemory(1 << 20)},
+		{WithExpectedElements(100_000), WithPowerOfTwoSize()},
+		{WithBitCount(64 * BitsPerCacheLine), WithHashCount(3)},
+	} {
+		bf, _ := NewWithOptions(opts...)
+		if size, err := MemoryFor(opts...); err != nil || size != bf.GetCacheStats().MemoryUsage {
+			t.Errorf("MemoryFor = %d, %v, want %d", size, err, bf.GetCacheStats().MemoryUsage)
+		}
+	}
+	if size, err := MemoryFor(WithExpectedElements(1<<50), WithFalsePositiveRate(1e-9)); err != nil || size < 1<<50 {
+		t.Errorf("MemoryFor(2^50 keys) = %d, %v", size, err)
+	}
+	if _, err := MemoryFor(WithExpectedElements(100), WithFalsePositiveRate(1.5)); err == nil {
+		t.Error("MemoryFor accepted an invalid rate")
+	}
+}
+
+// TestNewWithOptionsErrors tests invalid option combinations
+func TestNewWithOptionsErrors(t *testing.T) {
+	cases := map[string][]Option{
+		"no size":          nil,
+		"partial line":     {WithBitCount(1000), WithHashCount(3)},
+		"m without k or n": {WithBitCount(BitsPerCacheLine)},
+		"invalid fpr":      {WithExpectedElements(100), WithFalsePositiveRate(1.5)},
+		"zero bits":        {WithExpectedElements(1), WithFalsePositiveRate(0.9)},
+	}
+	for name, opts := range cases {
+		if bf, err := NewWithOptions(opts...); err == nil {
+			t.Errorf("%s: expected an error, got m=%d k=%d", name, bf.BitCount(), bf.HashCount())
+		}
+	}
+}
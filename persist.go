@@ -0,0 +1,446 @@
+package bloomfilter
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// PersistentConfig configures a PersistentBloomFilter
+type PersistentConfig struct {
+	// Path of the checkpoint file, in the native format. Write-ahead log
+	// segments are kept next to it as Path.wal.N.
+	Path string
+	// Options create the filter when Path does not exist yet, as with
+	// NewWithOptions; a restored filter keeps the parameters it was saved with
+	Options []Option
+	// CheckpointEvery, if positive, checkpoints in a background goroutine at
+	// this interval when keys were added since the last checkpoint
+	CheckpointEvery time.Duration
+	// CheckpointAfter, if positive, checkpoints in the background once this many
+	// keys were added since the last checkpoint
+	CheckpointAfter uint64
+	// WriteAhead logs every key before adding it, so keys added after the last
+	// checkpoint survive a crash of the process. The log is replayed when the
+	// filter is opened and truncated by each checkpoint.
+	WriteAhead bool
+	// SyncWriteAhead also syncs the log to disk on every add, so keys survive a
+	// crash of the machine too, at the cost of an fsync per add
+	SyncWriteAhead bool
+	// Clock drives background checkpoints. Defaults to SystemClock.
+	Clock Clock
+}
+
+// PersistentStats reports the state of a PersistentBloomFilter
+type PersistentStats struct {
+	Checkpoints    uint64
+	LastCheckpoint time.Time // Zero before the first checkpoint
+	// Keys added since the last checkpoint
+	PendingAdds uint64
+	// Keys replayed from the write-ahead log when the filter was opened
+	Recovered uint64
+	// Failed log writes and background checkpoints (see Err)
+	Errors uint64
+}
+
+// PersistentBloomFilter is a filter that survives restarts: it is checkpointed
+// to a file, replaced atomically through a temporary file and rename, and
+// restored from it by OpenPersistent. Without WriteAhead, keys added after the
+// last checkpoint are lost in a crash; with it, every key is appended to a log
+// before it is added and the log is replayed on open.
+//
+// Add and Contains are safe for concurrent use with each other and with
+// checkpoints. Close stops background checkpoints and takes a final one.
+type PersistentBloomFilter struct {
+	cfg PersistentConfig
+	bf  *CacheOptimizedBloomFilter
+
+	// Adds hold gate shared while they log and add a key; a checkpoint holds it
+	// exclusively to switch log segments, so every key in the segments it
+	// deletes is in the filter it saves
+	gate   sync.RWMutex
+	walMu  sync.Mutex
+	wal    *os.File
+	walSeq uint64
+	walBuf []byte
+
+	cpMu           sync.Mutex // serializes checkpoints
+	pending        atomic.Uint64
+	checkpoints    atomic.Uint64
+	lastCheckpoint atomic.Int64 // UnixNano, 0 before the first
+	recovered      uint64
+	errors         atomic.Uint64
+	errMu          sync.Mutex
+	lastErr        error
+
+	trigger   chan struct{}
+	stop      chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
+	closeErr  error
+}
+
+// OpenPersistent restores the filter checkpointed at cfg.Path, replays the
+// keys of its write-ahead log, and starts background checkpoints if configured.
+// A filter is created from cfg.Options if there is no checkpoint yet.
+// Returns an error if Path is empty, the checkpoint cannot be read, the log
+// cannot be opened, or the options are invalid.
+func OpenPersistent(cfg PersistentConfig) (*PersistentBloomFilter, error) {
+	if cfg.Path == "" {
+		return nil, fmt.Errorf("bloomfilter: persistent filter requires a Path")
+	}
+	if cfg.CheckpointEvery < 0 {
+		return nil, fmt.Errorf("bloomfilter: CheckpointEvery must not be negative, got %v", cfg.CheckpointEvery)
+	}
+	cfg.Clock = clockOrSystem(cfg.Clock)
+
+	bf, err := loadCheckpoint(cfg.Path)
+	if errors.Is(err, fs.ErrNotExist) {
+		bf, err = NewWithOptions(cfg.Options...)
+	}
+	if err != nil {
+		return nil, err
+	}
+	p := &PersistentBloomFilter{cfg: cfg, bf: bf}
+
+	segments, err := walSegments(cfg.Path)
+	if err != nil {
+		return nil, err
+	}
+	for _, seq := range segments {
+		n, err := replayWAL(walPath(cfg.Path, seq), bf)
+		if err != nil {
+			return nil, err
+		}
+		p.recovered += n
+		p.walSeq = seq
+	}
+	p.pending.Store(p.recovered)
+	p.walSeq++
+	if cfg.WriteAhead {
+		if p.wal, err = createWAL(cfg.Path, p.walSeq); err != nil {
+			return nil, err
+		}
+	}
+
+	if cfg.CheckpointEvery > 0 || cfg.CheckpointAfter > 0 {
+		p.trigger = make(chan struct{}, 1)
+		p.stop = make(chan struct{})
+		p.done = make(chan struct{})
+		var ticks <-chan time.Time
+		var ticker Ticker
+		if cfg.CheckpointEvery > 0 {
+			ticker = cfg.Clock.NewTicker(cfg.CheckpointEvery)
+			ticks = ticker.C()
+		}
+		go p.checkpointLoop(ticker, ticks)
+	}
+	return p, nil
+}
+
+func (p *PersistentBloomFilter) checkpointLoop(ticker Ticker, ticks <-chan time.Time) {
+	defer close(p.done)
+	if ticker != nil {
+		defer ticker.Stop()
+	}
+	for {
+		select {
+		case <-ticks:
+		case <-p.trigger:
+		case <-p.stop:
+			return
+		}
+		if p.pending.Load() > 0 {
+			if err := p.Checkpoint(); err != nil {
+				p.fail(err)
+			}
+		}
+	}
+}
+
+// Add logs the key if WriteAhead is set and adds it to the filter. A failure
+// to log the key is counted and reported by Err; the key is still added, but
+// is lost if the process crashes before the next checkpoint.
+func (p *PersistentBloomFilter) Add(data []byte) {
+	if p.cfg.WriteAhead {
+		p.gate.RLock()
+		if err := p.logKey(data); err != nil {
+			p.fail(err)
+		}
+		p.bf.Add(data)
+		p.gate.RUnlock()
+	} else {
+		p.bf.Add(data)
+	}
+	if n := p.pending.Add(1); p.cfg.CheckpointAfter > 0 && n >= p.cfg.CheckpointAfter && p.trigger != nil {
+		select {
+		case p.trigger <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// Contains checks whether data is in the filter
+func (p *PersistentBloomFilter) Contains(data []byte) bool {
+	return p.bf.Contains(data)
+}
+
+// AddString adds a string element
+func (p *PersistentBloomFilter) AddString(s string) {
+	p.Add(stringBytes(s))
+}
+
+// ContainsString checks a string element
+func (p *PersistentBloomFilter) ContainsString(s string) bool {
+	return p.bf.ContainsString(s)
+}
+
+// Filter returns the underlying filter for lookups and statistics. Keys added
+// to it directly bypass the write-ahead log and the checkpoint counters.
+func (p *PersistentBloomFilter) Filter() *CacheOptimizedBloomFilter {
+	return p.bf
+}
+
+// logKey appends one record to the current log segment
+func (p *PersistentBloomFilter) logKey(data []byte) error {
+	p.walMu.Lock()
+	defer p.walMu.Unlock()
+	p.walBuf = appendKeyRecord(p.walBuf[:0], data)
+	if _, err := p.wal.Write(p.walBuf); err != nil {
+		return err
+	}
+	if p.cfg.SyncWriteAhead {
+		return p.wal.Sync()
+	}
+	return nil
+}
+
+// Checkpoint saves the filter to Path and deletes the log segments it
+// supersedes. It is safe to call concurrently with adds, which are briefly
+// held while the log switches segments.
+func (p *PersistentBloomFilter) Checkpoint() error {
+	p.cpMu.Lock()
+	defer p.cpMu.Unlock()
+	pending := p.pending.Load()
+
+	p.gate.Lock()
+	seq := p.walSeq
+	var err error
+	if p.wal != nil {
+		err = p.rotateWAL()
+	} else {
+		p.walSeq++
+	}
+	p.gate.Unlock()
+	if err != nil {
+		return err
+	}
+
+	if err := writeFileAtomic(p.cfg.Path, func(w io.Writer) error {
+		_, err := p.bf.WriteTo(w)
+		return err
+	}); err != nil {
+		return err
+	}
+	p.pending.Add(-pending)
+	p.checkpoints.Add(1)
+	p.lastCheckpoint.Store(p.cfg.Clock.Now().UnixNano())
+
+	segments, err := walSegments(p.cfg.Path)
+	if err != nil {
+		return err
+	}
+	for _, s := range segments {
+		if s <= seq {
+			if err := os.Remove(walPath(p.cfg.Path, s)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// rotateWAL closes the current log segment and opens the next
+func (p *PersistentBloomFilter) rotateWAL() error {
+	next, err := createWAL(p.cfg.Path, p.walSeq+1)
+	if err != nil {
+		return err
+	}
+	p.walMu.Lock()
+	old := p.wal
+	p.wal = next
+	p.walSeq++
+	p.walMu.Unlock()
+	if err := old.Sync(); err != nil {
+		old.Close()
+		return err
+	}
+	return old.Close()
+}
+
+// Close stops background checkpoints, takes a final checkpoint and closes the
+// log. The filter must not be used afterwards; calling Close again returns the
+// first call's result.
+func (p *PersistentBloomFilter) Close() error {
+	p.closeOnce.Do(func() {
+		if p.stop != nil {
+			close(p.stop)
+			<-p.done
+		}
+		p.closeErr = p.Checkpoint()
+		if p.wal != nil {
+			if err := p.wal.Close(); p.closeErr == nil {
+				p.closeErr = err
+			}
+		}
+	})
+	return p.closeErr
+}
+
+// Err returns the latest failure to log a key or to take a background
+// checkpoint, or nil
+func (p *PersistentBloomFilter) Err() error {
+	p.errMu.Lock()
+	defer p.errMu.Unlock()
+	return p.lastErr
+}
+
+func (p *PersistentBloomFilter) fail(err error) {
+	p.errors.Add(1)
+	p.errMu.Lock()
+	p.lastErr = err
+	p.errMu.Unlock()
+}
+
+// Stats returns the checkpoint and recovery counters
+func (p *PersistentBloomFilter) Stats() PersistentStats {
+	s := PersistentStats{
+		Checkpoints: p.checkpoints.Load(),
+		PendingAdds: p.pending.Load(),
+		Recovered:   p.recovered,
+		Errors:      p.errors.Load(),
+	}
+	if t := p.lastCheckpoint.Load(); t != 0 {
+		s.LastCheckpoint = time.Unix(0, t)
+	}
+	return s
+}
+
+// loadCheckpoint reads the filter saved at path
+func loadCheckpoint(path string) (*CacheOptimizedBloomFilter, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	bf := &CacheOptimizedBloomFilter{}
+	if _, err := bf.ReadFrom(bufio.NewReader(f)); err != nil {
+		return nil, fmt.Errorf("bloomfilter: reading checkpoint %s: %w", path, err)
+	}
+	return bf, nil
+}
+
+// writeFileAtomic writes a temporary file next to path with write, syncs it
+// and renames it over path, so a crash leaves either the old or the new file
+func writeFileAtomic(path string, write func(io.Writer) error) error {
+	dir := filepath.Dir(path)
+	tmp, err := os.CreateTemp(dir, filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // no-op after a successful rename
+
+	w := bufio.NewWriter(tmp)
+	if err := write(w); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := w.Flush(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return err
+	}
+
+	// Persist the rename itself; not supported on every platform
+	if d, err := os.Open(dir); err == nil {
+		d.Sync()
+		d.Close()
+	}
+	return nil
+}
+
+// walPath returns the path of log segment seq
+func walPath(path string, seq uint64) string {
+	return path + ".wal." + strconv.FormatUint(seq, 10)
+}
+
+// walSegments returns the sequence numbers of the log segments of path, in
+// order. Segments are matched by name rather than with filepath.Glob, which
+// would interpret metacharacters in path.
+func walSegments(path string) ([]uint64, error) {
+	entries, err := os.ReadDir(filepath.Dir(path))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	prefix := filepath.Base(path) + ".wal."
+	var seqs []uint64
+	for _, e := range entries {
+		digits, ok := strings.CutPrefix(e.Name(), prefix)
+		if !ok {
+			continue
+		}
+		if seq, err := strconv.ParseUint(digits, 10, 64); err == nil {
+			seqs = append(seqs, seq)
+		}
+	}
+	slices.Sort(seqs)
+	return seqs, nil
+}
+
+func createWAL(path string, seq uint64) (*os.File, error) {
+	return os.OpenFile(walPath(path, seq), os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+}
+
+// replayWAL adds the keys of a log segment to bf and returns how many. A torn
+// or damaged record ends the segment, as left by a crash during a write.
+func replayWAL(path string, bf *CacheOptimizedBloomFilter) (uint64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	var n uint64
+	var b keyBatcher
+	err = readKeyRecords(bufio.NewReader(f), func(key []byte) error {
+		b.add(bf, key)
+		n++
+		return nil
+	})
+	b.flush(bf)
+	if errors.Is(err, ErrCorruptData) {
+		err = nil
+	}
+	return n, err
+}
@@ -0,0 +1,189 @@
+package bloomfilter
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// waitFor polls cond until it holds, failing the test after 5 seconds
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("Timed out waiting for %s", what)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// TestPersistentRestore tests that a closed filter is restored by OpenPersistent
+func TestPersistentRestore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "filter.bloom")
+	p, err := OpenPersistent(PersistentConfig{Path: path, Options: []Option{WithExpectedElements(1000)}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.AddString("alice")
+	p.Add([]byte("bob"))
+	if err := p.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Close(); err != nil {
+		t.Errorf("Second Close: %v", err)
+	}
+
+	// The checkpoint's parameters win over the options
+	p, err = OpenPersistent(PersistentConfig{Path: path, Options: []Option{WithExpectedElements(10)}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+	if !p.ContainsString("alice") || !p.Contains([]byte("bob")) || p.ContainsString("carol") {
+		t.Error("Restored filter lost keys")
+	}
+	if want := NewCacheOptimizedBloomFilter(1000, 0.01).BitCount(); p.Filter().BitCount() != want {
+		t.Errorf("Restored filter has %d bits, want %d", p.Filter().BitCount(), want)
+	}
+	if s := p.Stats(); s.Recovered != 0 || s.PendingAdds != 0 {
+		t.Errorf("Stats() = %+v", s)
+	}
+
+	if _, err := OpenPersistent(PersistentConfig{Options: []Option{WithExpectedElements(10)}}); err == nil {
+		t.Error("Open without a Path succeeded")
+	}
+	if _, err := OpenPersistent(PersistentConfig{Path: filepath.Join(t.TempDir(), "f")}); err == nil {
+		t.Error("Open without a checkpoint or options succeeded")
+	}
+	os.WriteFile(path+".bad", []byte("not a filter"), 0o644)
+	if _, err := OpenPersistent(PersistentConfig{Path: path + ".bad"}); !errors.Is(err, ErrInvalidEncoding) {
+		t.Errorf("Damaged checkpoint: expected ErrInvalidEncoding, got %v", err)
+	}
+}
+
+// TestPersistentWriteAhead tests that logged keys survive a crash
+func TestPersistentWriteAhead(t *testing.T) {
+	// Glob metacharacters in the path must not hide the log segments
+	path := filepath.Join(t.TempDir(), "filter[1].bloom")
+	cfg := PersistentConfig{Path: path, Options: []Option{WithExpectedElements(1000)}, WriteAhead: true, SyncWriteAhead: true}
+	p, err := OpenPersistent(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.AddString("checkpointed")
+	if err := p.Checkpoint(); err != nil {
+		t.Fatal(err)
+	}
+	p.AddString("logged")
+	p.AddString("also logged")
+	p.wal.Close() // Crash: no final checkpoint
+
+	// A write torn by the crash ends the log
+	segments, _ := walSegments(path)
+	if len(segments) != 1 {
+		t.Fatalf("Log segments %v after a checkpoint, want one", segments)
+	}
+	f, _ := os.OpenFile(walPath(path, segments[0]), os.O_WRONLY|os.O_APPEND, 0)
+	f.Write(appendKeyRecord(nil, []byte("torn"))[:10])
+	f.Close()
+
+	p, err = OpenPersistent(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"checkpointed", "logged", "also logged"} {
+		if !p.ContainsString(key) {
+			t.Errorf("Lost %q", key)
+		}
+	}
+	if s := p.Stats(); s.Recovered != 2 || s.PendingAdds != 2 {
+		t.Errorf("Stats() = %+v, want 2 recovered and pending", s)
+	}
+	if err := p.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if segments, _ := walSegments(path); len(segments) != 1 {
+		t.Errorf("Log segments %v after Close, want the empty current one", segments)
+	}
+}
+
+// TestPersistentBackground tests checkpoints by add count and by interval
+func TestPersistentBackground(t *testing.T) {
+	clock := NewManualClock(time.Unix(1000, 0))
+	path := filepath.Join(t.TempDir(), "filter.bloom")
+	p, err := OpenPersistent(PersistentConfig{
+		Path: path, Options: []Option{WithExpectedElements(1000)}, WriteAhead: true,
+		CheckpointAfter: 100, CheckpointEvery: time.Minute, Clock: clock,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+
+	for i := uint64(0); i < 100; i++ {
+		p.Add([]byte{byte(i)})
+	}
+	waitFor(t, "a checkpoint after 100 adds", func() bool { return p.Stats().Checkpoints == 1 })
+	if _, err := os.Stat(path); err != nil {
+		t.Fatal(err)
+	}
+
+	p.AddString("one more")
+	clock.Advance(time.Minute)
+	waitFor(t, "a checkpoint after an interval", func() bool { return p.Stats().Checkpoints == 2 })
+	if s := p.Stats(); s.PendingAdds != 0 || !s.LastCheckpoint.Equal(clock.Now()) || p.Err() != nil {
+		t.Errorf("Stats() = %+v, Err() = %v", s, p.Err())
+	}
+
+	// Idle intervals do not checkpoint
+	clock.Advance(time.Minute)
+	time.Sleep(10 * time.Millisecond)
+	if n := p.Stats().Checkpoints; n != 2 {
+		t.Errorf("Idle interval checkpointed: %d", n)
+	}
+}
+
+// TestPersistentConcurrent tests that no key added during checkpoints is lost
+// in a crash
+func TestPersistentConcurrent(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "filter.bloom")
+	cfg := PersistentConfig{Path: path, Options: []Option{WithExpectedElements(100000)}, WriteAhead: true}
+	p, err := OpenPersistent(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var wg sync.WaitGroup
+	for w := 0; w < 4; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 2000; i++ {
+				p.Add([]byte{byte(w), byte(i), byte(i >> 8)})
+			}
+		}()
+	}
+	for i := 0; i < 5; i++ {
+		if err := p.Checkpoint(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	wg.Wait()
+	p.wal.Close()
+
+	p, err = OpenPersistent(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+	for w := 0; w < 4; w++ {
+		for i := 0; i < 2000; i++ {
+			if !p.Contains([]byte{byte(w), byte(i), byte(i >> 8)}) {
+				t.Fatalf("Lost key %d of writer %d", i, w)
+			}
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package bloomfilter
+
+import "math"
+
+// EstimateParameters returns the bit and hash function counts the constructors
+// use for expectedElements at falsePositiveRate, with the bit count rounded up
+// to whole cache lines. The memory is bits/8 bytes.
+//
+// Panics on the inputs NewCacheOptimizedBloomFilter panics on.
+func EstimateParameters(expectedElements uint64, falsePositiveRate float64) (bits uint64, hashes uint32) {
+	validateSizing(expectedElements, falsePositiveRate)
+	cacheLineCount, hashCount := optimalGeometry(expectedElements, falsePositiveRate)
+	return cacheLineCount * BitsPerCacheLine, hashCount
+}
+
+// AchievedFPR returns the false positive rate the filter has once it holds the
+// number of keys it was sized for, (1-e^(-kn/m))^k with its actual m and k.
+// It is close to the requested rate, a little above it when rounding the hash
+// count down costs more than rounding the bits up to cache lines gains, and far
+// above it when WithMaxMemory reduced the filter.
+// Returns 0 for filters built from a geometry (WithBitCount without
+// WithExpectedElements, decoding, NewFromWords), which do not know their n.
+func (bf *CacheOptimizedBloomFilter) AchievedFPR() float64 {
+	if bf.expectedElements == 0 {
+		return 0
+	}
+	k := float64(bf.hashCount)
+	return math.Pow(-math.Expm1(-k*float64(bf.expectedElements)/float64(bf.bitCount)), k)
+}
+
+// OptimalFPRForMemory returns the lowest false positive rate for
+// expectedElements that fits in maxBytes: passed to the constructors with
+// expectedElements, it yields a filter of at most maxBytes. The rate is
+// exp(-m/n·ln²2) for the whole cache lines m that fit. Returns 1 if maxBytes
+// holds no cache line, and the smallest positive rate if the budget is so large
+// that the rate underflows.
+//
+// Panics if expectedElements is 0.
+func OptimalFPRForMemory(expectedElements uint64, maxBytes uint64) float64 {
+	if expectedElements == 0 {
+		panic("bloomfilter: expectedElements must be greater than 0")
+	}
+	lines := min(maxBytes/CacheLineSize, math.MaxInt/CacheLineSize)
+	if lines == 0 {
+		return 1
+	}
+	bits := float64(lines * BitsPerCacheLine)
+	p := math.Exp(-bits / float64(expectedElements) * math.Ln2 * math.Ln2)
+	// One step up keeps the bits derived from p back within the budget despite
+	// rounding in the round trip
+	return max(math.Nextafter(p, 1), math.SmallestNonzeroFloat64)
+}
@@ -0,0 +1,72 @@
+package bloomfilter
+
+import (
+	"math"
+	"testing"
+)
+
+// TestEstimateParameters tests that the estimate matches a constructed filter
+func TestEstimateParameters(t *testing.T) {
+	for _, tc := range []struct {
+		n   uint64
+		fpr float64
+	}{{1, 0.5}, {1000, 0.01}, {1_000_000, 0.001}, {50_000_000, 1e-6}} {
+		bits, hashes := EstimateParameters(tc.n, tc.fpr)
+		bf := NewCacheOptimizedBloomFilter(tc.n, tc.fpr)
+		if bits != bf.BitCount() || hashes != bf.HashCount() {
+			t.Errorf("EstimateParameters(%d, %g) = %d, %d, filter has %d, %d",
+				tc.n, tc.fpr, bits, hashes, bf.BitCount(), bf.HashCount())
+		}
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("EstimateParameters(0, 0.01) did not panic")
+		}
+	}()
+	EstimateParameters(0, 0.01)
+}
+
+// TestOptimalFPRForMemory tests that the rate fits the budget and uses most of it
+func TestOptimalFPRForMemory(t *testing.T) {
+	for _, tc := range []struct {
+		n        uint64
+		maxBytes uint64
+	}{{1000, 64}, {1000, 1200}, {1000, 16 << 10}, {1_000_000, 1 << 20}, {10_000_000, 64 << 20}, {7, 100}} {
+		p := OptimalFPRForMemory(tc.n, tc.maxBytes)
+		if !(p > 0 && p < 1) {
+			t.Errorf("OptimalFPRForMemory(%d, %d) = %g", tc.n, tc.maxBytes, p)
+			continue
+		}
+		bits, _ := EstimateParameters(tc.n, p)
+		if bytes := bits / 8; bytes > tc.maxBytes || bytes+CacheLineSize <= tc.maxBytes {
+			t.Errorf("OptimalFPRForMemory(%d, %d) = %g gives %d bytes", tc.n, tc.maxBytes, p, bytes)
+		}
+	}
+
+	if p := OptimalFPRForMemory(1000, 63); p != 1 {
+		t.Errorf("Budget below one cache line: %g, want 1", p)
+	}
+	if p := OptimalFPRForMemory(1000, 1<<20); p != math.SmallestNonzeroFloat64 {
+		t.Errorf("Huge budget: %g, want the smallest rate", p)
+	}
+	// 64MB for 10M keys is about 54 bits per key
+	if p := OptimalFPRForMemory(10_000_000, 64<<20); p > 1e-10 || p < 1e-13 {
+		t.Errorf("64MB for 10M keys: %g", p)
+	}
+}
+
+// TestAchievedFPR tests the rate at the expected number of keys
+func TestAchievedFPR(t *testing.T) {
+	bf := NewCacheOptimizedBloomFilter(100_000, 0.01)
+	if p := bf.AchievedFPR(); p > 0.011 || p < 0.009 {
+		t.Errorf("AchievedFPR() = %g, want about 0.01", p)
+	}
+	if p := bf.Clone().AchievedFPR(); p != bf.AchievedFPR() {
+		t.Errorf("Clone AchievedFPR() = %g", p)
+	}
+	pinned, _ := NewWithOptions(WithBitCount(BitsPerCacheLine), WithHashCount(3))
+	if pinned.AchievedFPR() != 0 {
+		t.Error("AchievedFPR of a filter without expected elements is not 0")
+	}
+}
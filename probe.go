@@ -0,0 +1,75 @@
+package bloomfilter
+
+import "fmt"
+
+// ProbeScheme selects how the hashCount bit positions of a key are derived from
+// its two hashes h1 and h2. Filters only combine with filters of the same scheme.
+type ProbeScheme uint8
+
+const (
+	// DoubleHashing probes (h1 + i*h2) mod m, the scheme of filters written
+	// before EnhancedDoubleHashing existed. When h2 shares a factor with the bit
+	// count m the probes fall on a fraction of the bits, and when h2 is a
+	// multiple of m every probe hits the same bit.
+	DoubleHashing ProbeScheme = iota
+	// EnhancedDoubleHashing probes (h1 + i*h2 + (i³-i)/6) mod m (Dillinger and
+	// Manolios), whose cubic term spreads the probes even when h2 and m share
+	// factors. It is the default for new filters.
+	EnhancedDoubleHashing
+)
+
+// DefaultProbeScheme is the scheme of filters created without WithProbeScheme
+const DefaultProbeScheme = EnhancedDoubleHashing
+
+// String returns the scheme's name as used by the serialization formats
+func (s ProbeScheme) String() string {
+	switch s {
+	case DoubleHashing:
+		return "double"
+	case EnhancedDoubleHashing:
+		return "enhanced"
+	}
+	return fmt.Sprintf("ProbeScheme(%d)", uint8(s))
+}
+
+// ParseProbeScheme returns the scheme named by String
+func ParseProbeScheme(name string) (ProbeScheme, error) {
+	switch name {
+	case "double":
+		return DoubleHashing, nil
+	case "enhanced":
+		return EnhancedDoubleHashing, nil
+	}
+	return 0, fmt.Errorf("bloomfilter: unknown probe scheme %q", name)
+}
+
+// valid reports whether s is one of the defined schemes
+func (s ProbeScheme) valid() bool {
+	return s <= EnhancedDoubleHashing
+}
+
+// WithProbeScheme sets how keys map to bit positions. The default is
+// EnhancedDoubleHashing; use DoubleHashing to build filters that match bits
+// written by earlier versions or by other systems using plain double hashing.
+func WithProbeScheme(s ProbeScheme) Option {
+	return func(o *options) { o.probe = s }
+}
+
+// ProbeScheme returns the scheme mapping keys to bit positions
+func (bf *CacheOptimizedBloomFilter) ProbeScheme() ProbeScheme {
+	return bf.probe
+}
+
+// SetProbeScheme changes the scheme mapping keys to bit positions. Bits already
+// set are not moved, so it is only meaningful on an empty filter or right after
+// restoring bits written with s, such as a filter from NewFromWords or
+// NewFromJavaLongs whose bits were built with DoubleHashing. It must not be
+// called concurrently with other methods.
+// Returns an error if s is not a defined scheme.
+func (bf *CacheOptimizedBloomFilter) SetProbeScheme(s ProbeScheme) error {
+	if !s.valid() {
+		return fmt.Errorf("bloomfilter: unknown probe scheme %d", uint8(s))
+	}
+	bf.probe = s
+	return nil
+}
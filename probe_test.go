@@ -0,0 +1,170 @@
+package bloomfilter
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+// TestProbePositions tests both schemes against their closed forms, with
+// power-of-two bit counts (masked) and others (modulo)
+func TestProbePositions(t *testing.T) {
+	h1, h2 := uint64(0x9e3779b97f4a7c15), uint64(0xc6a4a7935bd1e995)
+	positions := make([]uint64, 24)
+
+	for _, bitCount := range []uint64{1 << 20, 3 * BitsPerCacheLine} {
+		hashPositions(h1, h2, bitCount, DoubleHashing, positions)
+		for i, p := range positions {
+			if want := (h1 + uint64(i)*h2) % bitCount; p != want {
+				t.Errorf("m=%d: DoubleHashing probe %d = %d, want %d", bitCount, i, p, want)
+			}
+		}
+
+		hashPositions(h1, h2, bitCount, EnhancedDoubleHashing, positions)
+		for i, p := range positions {
+			n := uint64(i)
+			if want := (h1 + n*h2 + (n*n*n-n)/6) % bitCount; p != want {
+				t.Errorf("m=%d: EnhancedDoubleHashing probe %d = %d, want %d", bitCount, i, p, want)
+			}
+		}
+	}
+}
+
+// TestEnhancedProbingSpreads tests the degenerate case of double hashing: when
+// h2 is a multiple of the bit count, every probe lands on the same bit
+func TestEnhancedProbingSpreads(t *testing.T) {
+	const bitCount = 64 * BitsPerCacheLine
+	positions := make([]uint64, 7)
+	distinct := func() int {
+		seen := map[uint64]bool{}
+		for _, p := range positions {
+			seen[p] = true
+		}
+		return len(seen)
+	}
+
+	hashPositions(12345, 3*bitCount, bitCount, DoubleHashing, positions)
+	if n := distinct(); n != 1 {
+		t.Errorf("DoubleHashing probed %d distinct bits, expected the degenerate 1", n)
+	}
+	// The first two probes differ by h2 alone; the cubic term separates the rest
+	hashPositions(12345, 3*bitCount, bitCount, EnhancedDoubleHashing, positions)
+	if n := distinct(); n != len(positions)-1 {
+		t.Errorf("EnhancedDoubleHashing probed %d distinct bits, want %d", n, len(positions)-1)
+	}
+}
+
+// TestProbeSchemeDefault tests the default, WithProbeScheme and SetProbeScheme
+func TestProbeSchemeDefault(t *testing.T) {
+	if s := NewCacheOptimizedBloomFilter(1000, 0.01).ProbeScheme(); s != EnhancedDoubleHashing {
+		t.Errorf("New filters use %s, want %s", s, EnhancedDoubleHashing)
+	}
+	legacy, err := NewWithOptions(WithExpectedElements(1000), WithProbeScheme(DoubleHashing))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if legacy.ProbeScheme() != DoubleHashing {
+		t.Errorf("WithProbeScheme(DoubleHashing) gave %s", legacy.ProbeScheme())
+	}
+	if _, err := NewWithOptions(WithExpectedElements(1000), WithProbeScheme(7)); err == nil {
+		t.Error("NewWithOptions accepted an unknown probe scheme")
+	}
+	if err := legacy.SetProbeScheme(7); err == nil {
+		t.Error("SetProbeScheme accepted an unknown probe scheme")
+	}
+
+	for _, s := range []ProbeScheme{DoubleHashing, EnhancedDoubleHashing} {
+		if parsed, err := ParseProbeScheme(s.String()); err != nil || parsed != s {
+			t.Errorf("ParseProbeScheme(%q) = %v, %v", s.String(), parsed, err)
+		}
+	}
+	if _, err := ParseProbeScheme("triple"); err == nil {
+		t.Error("ParseProbeScheme accepted an unknown name")
+	}
+
+	// Bits restored from a bare layout are probed with the scheme they were built with
+	for i := 0; i < 100; i++ {
+		legacy.AddUint64(uint64(i))
+	}
+	restored, _ := NewFromWordsWithSeed(legacy.Words(), legacy.HashCount(), legacy.Seed())
+	if err := restored.SetProbeScheme(DoubleHashing); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 100; i++ {
+		if !restored.ContainsUint64(uint64(i)) {
+			t.Fatalf("Key %d missing after restoring DoubleHashing bits", i)
+		}
+	}
+}
+
+// TestProbeSchemeRoundTrip tests that every encoding and copy keeps the scheme
+func TestProbeSchemeRoundTrip(t *testing.T) {
+	for _, s := range []ProbeScheme{DoubleHashing, EnhancedDoubleHashing} {
+		t.Run(s.String(), func(t *testing.T) {
+			bf, _ := NewWithOptions(WithExpectedElements(1000), WithSeed(3), WithProbeScheme(s), WithHashVersion(HashV1))
+			for i := 0; i < 500; i++ {
+				bf.AddString(fmt.Sprintf("key-%d", i))
+			}
+
+			data, _ := bf.MarshalBinary()
+			decoded, err := DecodeBinary(data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			jsonData, _ := json.Marshal(bf)
+			fromJSON := &CacheOptimizedBloomFilter{}
+			if err := json.Unmarshal(jsonData, fromJSON); err != nil {
+				t.Fatal(err)
+			}
+			var j jsonFilter
+			json.Unmarshal(jsonData, &j)
+			if wantVersion := map[ProbeScheme]int{DoubleHashing: 1, EnhancedDoubleHashing: 2}[s]; j.Version != wantVersion {
+				t.Errorf("JSON version %d, want %d", j.Version, wantVersion)
+			}
+			sub, _ := bf.SubFilter(SubFilterView{LineCount: bf.cacheLineCount})
+
+			copies := map[string]*CacheOptimizedBloomFilter{
+				"DecodeBinary": decoded,
+				"JSON":         fromJSON,
+				"Clone":        bf.Clone(),
+				"SubFilter":    sub,
+			}
+			if view, err := NewFromBuffer(alignedCopy(data, 0)); err == nil {
+				copies["NewFromBuffer"] = view
+			}
+			for name, c := range copies {
+				if c.ProbeScheme() != s || !c.Equal(bf) {
+					t.Errorf("%s: scheme %s, equal %t", name, c.ProbeScheme(), c.Equal(bf))
+				}
+				for i := 0; i < 500; i++ {
+					if !c.ContainsString(fmt.Sprintf("key-%d", i)) {
+						t.Fatalf("%s: key-%d missing", name, i)
+					}
+				}
+			}
+		})
+	}
+}
+
+// TestProbeSchemeMismatch tests that filters of different schemes do not combine
+func TestProbeSchemeMismatch(t *testing.T) {
+	a, _ := NewWithOptions(WithExpectedElements(1000), WithProbeScheme(EnhancedDoubleHashing))
+	b, _ := NewWithOptions(WithExpectedElements(1000), WithProbeScheme(DoubleHashing))
+
+	if err := a.Compatible(b); !errors.Is(err, ErrIncompatible) {
+		t.Errorf("Compatible: got %v, want ErrIncompatible", err)
+	}
+	if err := a.Union(b); !errors.Is(err, ErrIncompatible) {
+		t.Errorf("Union: got %v, want ErrIncompatible", err)
+	}
+	if err := a.Intersection(b); !errors.Is(err, ErrIncompatible) {
+		t.Errorf("Intersection: got %v, want ErrIncompatible", err)
+	}
+	if err := a.Difference(b); !errors.Is(err, ErrIncompatible) {
+		t.Errorf("Difference: got %v, want ErrIncompatible", err)
+	}
+	if a.Equal(b) {
+		t.Error("Empty filters of different schemes are Equal")
+	}
+}
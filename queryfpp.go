@@ -0,0 +1,34 @@
+package bloomfilter
+
+import "math"
+
+// ContainsWithFPP checks membership like Contains and also returns the
+// estimated probability that a positive answer is false, so callers can decide
+// whether to trust a positive or confirm it with a read of the backing store.
+// A negative answer is always correct; the estimate is returned either way.
+//
+// The estimate is load^k from the filter's current load factor, where k is the
+// number of bits the lookup checked (fewer than HashCount under adaptive
+// probing). The load factor comes from the incrementally tracked set-bit count
+// (see enableBitTracking), so the estimate costs no PopCount: the first call
+// enables tracking with one PopCount, after which adds keep the count current.
+func (bf *CacheOptimizedBloomFilter) ContainsWithFPP(data []byte) (bool, float64) {
+	if !bf.trackBits.Load() {
+		bf.enableBitTracking()
+	}
+	k := bf.containsProbes()
+	present := bf.Contains(data)
+	return present, bf.fppAt(bf.bitsSet.Load(), k)
+}
+
+// ContainsStringWithFPP is ContainsWithFPP for a string key
+func (bf *CacheOptimizedBloomFilter) ContainsStringWithFPP(s string) (bool, float64) {
+	return bf.ContainsWithFPP(stringBytes(s))
+}
+
+// fppAt is the false positive probability of a lookup checking k bits with
+// bitsSet of the filter's bits set
+func (bf *CacheOptimizedBloomFilter) fppAt(bitsSet uint64, k uint32) float64 {
+	load := min(1, float64(bitsSet)/float64(bf.bitCount))
+	return math.Pow(load, float64(k))
+}
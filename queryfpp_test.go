@@ -0,0 +1,58 @@
+package bloomfilter
+
+import (
+	"fmt"
+	"math"
+	"testing"
+)
+
+// TestContainsWithFPP tests that the estimate follows the load as keys are added
+func TestContainsWithFPP(t *testing.T) {
+	bf := NewCacheOptimizedBloomFilter(10000, 0.01)
+	if present, fpp := bf.ContainsStringWithFPP("key-0"); present || fpp != 0 {
+		t.Errorf("Empty filter: got (%t, %g), want (false, 0)", present, fpp)
+	}
+
+	prev := 0.0
+	for n := 1000; n <= 10000; n += 3000 {
+		for i := n - 1000; i < n; i++ {
+			bf.AddString(fmt.Sprintf("key-%d", i))
+		}
+		present, fpp := bf.ContainsStringWithFPP("key-0")
+		if !present {
+			t.Fatal("Added key reported absent")
+		}
+		if want := bf.EstimatedFPP(); math.Abs(fpp-want) > 1e-12 {
+			t.Errorf("%d keys: FPP %g, want EstimatedFPP %g", n, fpp, want)
+		}
+		if fpp <= prev {
+			t.Errorf("%d keys: FPP %g did not grow from %g", n, fpp, prev)
+		}
+		prev = fpp
+	}
+
+	// Bulk operations refresh the tracked load
+	bf.Clear()
+	if _, fpp := bf.ContainsStringWithFPP("key-0"); fpp != 0 {
+		t.Errorf("FPP %g after Clear, want 0", fpp)
+	}
+}
+
+// TestContainsWithFPPAdaptive tests that reduced probing raises the estimate
+func TestContainsWithFPPAdaptive(t *testing.T) {
+	bf := NewCacheOptimizedBloomFilter(1000, 0.01)
+	for i := 0; i < 3000; i++ {
+		bf.AddUint64(uint64(i))
+	}
+	_, full := bf.ContainsWithFPP([]byte("probe"))
+	bf.EnableAdaptiveProbing(0.5)
+	k := bf.containsProbes()
+	if k >= bf.HashCount() {
+		t.Skipf("Adaptive probing kept all %d probes", k)
+	}
+	_, reduced := bf.ContainsWithFPP([]byte("probe"))
+	load := float64(bf.PopCount()) / float64(bf.BitCount())
+	if want := math.Pow(load, float64(k)); math.Abs(reduced-want) > 1e-12 || reduced <= full {
+		t.Errorf("FPP with %d probes %g, want %g (above %g with all probes)", k, reduced, want, full)
+	}
+}
@@ -0,0 +1,544 @@
+package bloomfilter
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"sync"
+
+	"github.com/shaia/BloomFilter/internal/hash"
+)
+
+// Quotient filter
+//
+// Every key is reduced to a fingerprint of quotientBits+remainderBits bits. The
+// quotient selects a canonical slot and only the remainder is stored, in a
+// table of 2^quotientBits slots with three metadata bits each: occupied (some
+// key has this slot as its canonical slot), continuation (the slot continues
+// the run of remainders of the previous slot) and shifted (the remainder is not
+// in its canonical slot). Runs of equal quotients are kept contiguous and
+// sorted, and runs pushed out of their canonical slots by linear probing form
+// clusters, so the full fingerprint of every entry can be recovered by walking
+// the table. That is what makes resizing and merging possible without the
+// original keys: moving one bit from the remainder to the quotient doubles the
+// table, and filters with the same fingerprint width merge by reinserting each
+// other's fingerprints.
+//
+// Slots are packed as remainder<<3 | shifted<<2 | continuation<<1 | occupied.
+
+const (
+	qfOccupied     = 1
+	qfContinuation = 2
+	qfShifted      = 4
+	qfMetaBits     = 3
+
+	// qfMaxLoad is the fraction of slots in use at which Add doubles the table
+	qfMaxLoad = 0.75
+	// qfMaxQuotientBits bounds the table at 2^40 slots
+	qfMaxQuotientBits = 40
+)
+
+// QuotientFilter is an approximate membership filter that supports deletion,
+// grows without the original keys and merges with other quotient filters. It
+// holds a multiset of fingerprints: adding a key twice stores it twice, and
+// Delete removes one copy, so deleting only keys that were added keeps Contains
+// free of false negatives.
+//
+// Add doubles the table once it is 75% full. Each doubling moves one bit of
+// every fingerprint from the remainder to the quotient, which doubles the false
+// positive rate; once the remainder is down to one bit the filter stops
+// growing, and adds to a full filter are dropped and counted (see TryAdd).
+//
+// A QuotientFilter is safe for concurrent use.
+type QuotientFilter struct {
+	mu sync.RWMutex
+
+	quotientBits  uint
+	remainderBits uint
+	seed          uint64
+	slotWidth     uint
+	slotMask      uint64
+	indexMask     uint64
+	table         []uint64 // packed slots
+	count         uint64
+	dropped       uint64
+}
+
+// NewQuotientFilter creates a quotient filter holding expectedElements at
+// falsePositiveRate before it first grows.
+//
+// Panics on the same invalid inputs as NewCacheOptimizedBloomFilter, or if the
+// two need a fingerprint wider than 64 bits or more than 2^40 slots.
+func NewQuotientFilter(expectedElements uint64, falsePositiveRate float64) *QuotientFilter {
+	validateSizing(expectedElements, falsePositiveRate)
+	q := uint(max(1, math.Ceil(math.Log2(float64(expectedElements)/qfMaxLoad))))
+	// A lookup collides with each stored fingerprint with probability 2^-(q+r),
+	// so at full load the rate is about qfMaxLoad * 2^-r
+	r := uint(max(1, math.Ceil(math.Log2(qfMaxLoad/falsePositiveRate))))
+	if q > qfMaxQuotientBits || q+r > 64 {
+		panic(fmt.Sprintf("bloomfilter: %d elements at falsePositiveRate %g need a %d-bit fingerprint, more than a quotient filter supports",
+			expectedElements, falsePositiveRate, q+r))
+	}
+	return NewQuotientFilterWithBits(q, r, 0)
+}
+
+// NewQuotientFilterWithBits creates a quotient filter with 2^quotientBits
+// slots, remainderBits stored per entry, and a hash seed. Filters that will be
+// merged must have the same quotientBits+remainderBits and seed.
+// Panics if quotientBits is not in [1, 40], remainderBits is 0, or the two add
+// up to more than 64.
+func NewQuotientFilterWithBits(quotientBits, remainderBits uint, seed uint64) *QuotientFilter {
+	if quotientBits == 0 || quotientBits > qfMaxQuotientBits || remainderBits == 0 || quotientBits+remainderBits > 64 {
+		panic(fmt.Sprintf("bloomfilter: invalid quotient filter geometry: %d quotient bits, %d remainder bits",
+			quotientBits, remainderBits))
+	}
+	f := &QuotientFilter{seed: seed}
+	f.setGeometry(quotientBits, remainderBits)
+	return f
+}
+
+// setGeometry allocates an empty table
+func (f *QuotientFilter) setGeometry(quotientBits, remainderBits uint) {
+	f.quotientBits = quotientBits
+	f.remainderBits = remainderBits
+	f.slotWidth = remainderBits + qfMetaBits
+	f.slotMask = math.MaxUint64 >> (64 - f.slotWidth)
+	f.indexMask = 1<<quotientBits - 1
+	// One extra word lets slots straddle the last word boundary without a check
+	f.table = make([]uint64, (uint64(1)<<quotientBits*uint64(f.slotWidth)+63)/64+1)
+	f.count = 0
+}
+
+// get returns the packed slot i
+func (f *QuotientFilter) get(i uint64) uint64 {
+	bit := i * uint64(f.slotWidth)
+	word, off := bit/64, bit%64
+	v := f.table[word] >> off
+	if off+uint64(f.slotWidth) > 64 {
+		v |= f.table[word+1] << (64 - off)
+	}
+	return v & f.slotMask
+}
+
+// set stores the packed slot i
+func (f *QuotientFilter) set(i, v uint64) {
+	bit := i * uint64(f.slotWidth)
+	word, off := bit/64, bit%64
+	f.table[word] = f.table[word]&^(f.slotMask<<off) | v<<off
+	if off+uint64(f.slotWidth) > 64 {
+		spill := 64 - off
+		f.table[word+1] = f.table[word+1]&^(f.slotMask>>spill) | v>>spill
+	}
+}
+
+func (f *QuotientFilter) next(i uint64) uint64 { return (i + 1) & f.indexMask }
+func (f *QuotientFilter) prev(i uint64) uint64 { return (i - 1) & f.indexMask }
+
+func (f *QuotientFilter) isEmpty(i uint64) bool {
+	return f.get(i)&(qfOccupied|qfContinuation|qfShifted) == 0
+}
+
+// fingerprint returns the quotient and remainder of a key hash
+func (f *QuotientFilter) fingerprint(h uint64) (uint64, uint64) {
+	fp := mix64(h) >> (64 - f.quotientBits - f.remainderBits)
+	return fp >> f.remainderBits, fp & (1<<f.remainderBits - 1)
+}
+
+// runStart returns the slot where the run of canonical slot fq starts, or
+// would start if fq has no run yet. The occupied bit of fq must be set.
+func (f *QuotientFilter) runStart(fq uint64) uint64 {
+	// Walk back to the start of the cluster, then forward one run per occupied
+	// canonical slot until reaching fq
+	b := fq
+	for f.get(b)&qfShifted != 0 {
+		b = f.prev(b)
+	}
+	s := b
+	for b != fq {
+		for {
+			s = f.next(s)
+			if f.get(s)&qfContinuation == 0 {
+				break
+			}
+		}
+		for {
+			b = f.next(b)
+			if f.get(b)&qfOccupied != 0 {
+				break
+			}
+		}
+	}
+	return s
+}
+
+// contains reports whether the fingerprint is stored
+func (f *QuotientFilter) contains(fq, fr uint64) bool {
+	if f.get(fq)&qfOccupied == 0 {
+		return false
+	}
+	s := f.runStart(fq)
+	for {
+		v := f.get(s)
+		if rem := v >> qfMetaBits; rem == fr {
+			return true
+		} else if rem > fr {
+			return false // runs are sorted
+		}
+		s = f.next(s)
+		if f.get(s)&qfContinuation == 0 {
+			return false
+		}
+	}
+}
+
+// insert stores the fingerprint; the table must have a free slot
+func (f *QuotientFilter) insert(fq, fr uint64) {
+	canonical := f.get(fq)
+	if canonical&(qfOccupied|qfContinuation|qfShifted) == 0 {
+		f.set(fq, fr<<qfMetaBits|qfOccupied)
+		f.count++
+		return
+	}
+
+	hadRun := canonical&qfOccupied != 0
+	f.set(fq, canonical|qfOccupied)
+	s := f.runStart(fq)
+	pos := s
+	if hadRun {
+		// Keep the run sorted: skip smaller remainders
+		for f.get(pos)>>qfMetaBits < fr {
+			pos = f.next(pos)
+			if f.get(pos)&qfContinuation == 0 {
+				break
+			}
+		}
+	}
+
+	// The new entry heads the run if the run is new or it sorts first; a
+	// displaced head becomes a continuation
+	entry := fr << qfMetaBits
+	if pos != s {
+		entry |= qfContinuation
+	}
+	if pos != fq {
+		entry |= qfShifted
+	}
+	demoteHead := hadRun && pos == s
+
+	// Shift everything from pos up to the next empty slot right by one; the
+	// occupied bits describe canonical slots and stay in place
+	for {
+		v := f.get(pos)
+		f.set(pos, entry|v&qfOccupied)
+		if v&(qfOccupied|qfContinuation|qfShifted) == 0 {
+			break
+		}
+		entry = v&^qfOccupied | qfShifted
+		if demoteHead {
+			entry |= qfContinuation
+			demoteHead = false
+		}
+		pos = f.next(pos)
+	}
+	f.count++
+}
+
+// remove deletes one copy of the fingerprint and reports whether it was stored
+func (f *QuotientFilter) remove(fq, fr uint64) bool {
+	if f.get(fq)&qfOccupied == 0 {
+		return false
+	}
+	s := f.runStart(fq)
+	pos := s
+	for {
+		rem := f.get(pos) >> qfMetaBits
+		if rem == fr {
+			break
+		}
+		pos = f.next(pos)
+		if rem > fr || f.get(pos)&qfContinuation == 0 {
+			return false
+		}
+	}
+
+	isHead := pos == s
+	if isHead && f.get(f.next(pos))&qfContinuation == 0 {
+		// The run is now empty
+		f.set(fq, f.get(fq)&^qfOccupied)
+	}
+
+	// Shift the rest of the cluster left by one, recomputing each moved entry's
+	// shifted bit from the canonical slot of its run
+	quotient := fq
+	for i, first := pos, true; ; first = false {
+		n := f.next(i)
+		v := f.get(n)
+		if v&qfShifted == 0 {
+			// n is empty or in its canonical slot: nothing more moves
+			f.set(i, f.get(i)&qfOccupied)
+			break
+		}
+		moved := v &^ qfOccupied
+		if v&qfContinuation == 0 {
+			// n heads the run of the next occupied canonical slot
+			for {
+				quotient = f.next(quotient)
+				if f.get(quotient)&qfOccupied != 0 {
+					break
+				}
+			}
+		} else if first && isHead {
+			// The removed head's successor heads its run
+			moved &^= qfContinuation
+		}
+		if i == quotient {
+			moved &^= qfShifted
+		}
+		f.set(i, moved|f.get(i)&qfOccupied)
+		i = n
+	}
+	f.count--
+	return true
+}
+
+// forEach calls fn with every stored fingerprint, quotient first, walking the
+// table from the start of a cluster
+func (f *QuotientFilter) forEach(fn func(fq, fr uint64)) {
+	if f.count == 0 {
+		return
+	}
+	size := f.indexMask + 1
+	start := uint64(0)
+	for f.isEmpty(start) || f.get(start)&qfShifted != 0 {
+		start++
+	}
+	var quotient uint64
+	for n, i := uint64(0), start; n < size; n, i = n+1, f.next(i) {
+		v := f.get(i)
+		if v&(qfOccupied|qfContinuation|qfShifted) == 0 {
+			continue
+		}
+		if v&qfShifted == 0 {
+			quotient = i
+		} else if v&qfContinuation == 0 {
+			for {
+				quotient = f.next(quotient)
+				if f.get(quotient)&qfOccupied != 0 {
+					break
+				}
+			}
+		}
+		fn(quotient, v>>qfMetaBits)
+	}
+}
+
+// fingerprints returns every stored fingerprint as quotient<<remainderBits | remainder
+func (f *QuotientFilter) fingerprints() []uint64 {
+	fps := make([]uint64, 0, f.count)
+	f.forEach(func(fq, fr uint64) {
+		fps = append(fps, fq<<f.remainderBits|fr)
+	})
+	return fps
+}
+
+// rebuild replaces the table with one of 2^quotientBits slots holding fps
+func (f *QuotientFilter) rebuild(quotientBits uint, fps []uint64) {
+	f.setGeometry(quotientBits, f.quotientBits+f.remainderBits-quotientBits)
+	// In sorted order each entry lands after the previous one, so inserts
+	// almost never shift existing entries
+	slices.Sort(fps)
+	for _, fp := range fps {
+		f.insert(fp>>f.remainderBits, fp&(1<<f.remainderBits-1))
+	}
+}
+
+// hasRoom makes room for one more entry, doubling the table at qfMaxLoad while
+// the remainder allows, and reports whether there is a free slot
+func (f *QuotientFilter) hasRoom() bool {
+	size := f.indexMask + 1
+	if float64(f.count+1) > qfMaxLoad*float64(size) && f.remainderBits > 1 && f.quotientBits < qfMaxQuotientBits {
+		f.rebuild(f.quotientBits+1, f.fingerprints())
+		size = f.indexMask + 1
+	}
+	return f.count < size-1
+}
+
+// Add adds data, growing the table if needed. On a full filter that can no
+// longer grow the add is dropped and counted.
+func (f *QuotientFilter) Add(data []byte) {
+	f.TryAdd(data)
+}
+
+// TryAdd adds data like Add, but returns ErrOverloaded instead of silently
+// dropping it when the filter is full and can no longer grow
+func (f *QuotientFilter) TryAdd(data []byte) error {
+	return f.addHashed(hash.Seeded1(data, f.seed))
+}
+
+// AddString adds a string element
+func (f *QuotientFilter) AddString(s string) {
+	f.Add(stringBytes(s))
+}
+
+// AddUint64 adds a uint64 element; AddUint64(n) is equivalent to Add of n's
+// bytes in memory order
+func (f *QuotientFilter) AddUint64(n uint64) {
+	h, _ := hash.SeededUint64(n, f.seed)
+	f.addHashed(h)
+}
+
+func (f *QuotientFilter) addHashed(h uint64) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if !f.hasRoom() {
+		f.dropped++
+		return ErrOverloaded
+	}
+	f.insert(f.fingerprint(h))
+	return nil
+}
+
+// Contains checks whether data may be in the filter
+func (f *QuotientFilter) Contains(data []byte) bool {
+	return f.containsHashed(hash.Seeded1(data, f.seed))
+}
+
+// ContainsString checks a string element
+func (f *QuotientFilter) ContainsString(s string) bool {
+	return f.Contains(stringBytes(s))
+}
+
+// ContainsUint64 checks a uint64 element
+func (f *QuotientFilter) ContainsUint64(n uint64) bool {
+	h, _ := hash.SeededUint64(n, f.seed)
+	return f.containsHashed(h)
+}
+
+func (f *QuotientFilter) containsHashed(h uint64) bool {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return f.contains(f.fingerprint(h))
+}
+
+// Delete removes one copy of data and reports whether its fingerprint was
+// found. Deleting a key that was never added may remove another key with the
+// same fingerprint, causing a false negative.
+func (f *QuotientFilter) Delete(data []byte) bool {
+	return f.deleteHashed(hash.Seeded1(data, f.seed))
+}
+
+// DeleteString deletes a string element
+func (f *QuotientFilter) DeleteString(s string) bool {
+	return f.Delete(stringBytes(s))
+}
+
+// DeleteUint64 deletes a uint64 element
+func (f *QuotientFilter) DeleteUint64(n uint64) bool {
+	h, _ := hash.SeededUint64(n, f.seed)
+	return f.deleteHashed(h)
+}
+
+func (f *QuotientFilter) deleteHashed(h uint64) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.remove(f.fingerprint(h))
+}
+
+// Resize rebuilds the table with 2^quotientBits slots from the stored
+// fingerprints, without the original keys. The fingerprint width is fixed, so
+// growing takes bits from the remainder and doubles the false positive rate per
+// bit, while shrinking gives them back.
+//
+// Returns an error if quotientBits is not in [1, 40], leaves no remainder bit,
+// or gives too few slots for the entries.
+func (f *QuotientFilter) Resize(quotientBits uint) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	width := f.quotientBits + f.remainderBits
+	if quotientBits == 0 || quotientBits > qfMaxQuotientBits || quotientBits >= width {
+		return fmt.Errorf("bloomfilter: quotient filter with %d-bit fingerprints cannot have %d quotient bits", width, quotientBits)
+	}
+	if f.count >= 1<<quotientBits {
+		return fmt.Errorf("bloomfilter: %d entries do not fit in %d slots", f.count, uint64(1)<<quotientBits)
+	}
+	f.rebuild(quotientBits, f.fingerprints())
+	return nil
+}
+
+// Merge adds every entry of other to f, without the original keys. The result
+// is sized for both filters' entries, growing f as Add would. other is not
+// modified and may be f itself.
+//
+// Returns an error wrapping ErrSeedMismatch if the seeds differ, or
+// ErrIncompatible if the fingerprint widths differ.
+func (f *QuotientFilter) Merge(other *QuotientFilter) error {
+	// Snapshot other first, so merges in both directions cannot deadlock
+	other.mu.RLock()
+	fps, otherWidth := other.fingerprints(), other.quotientBits+other.remainderBits
+	other.mu.RUnlock()
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.seed != other.seed {
+		return fmt.Errorf("%w for merge (seeds %#x and %#x)", ErrSeedMismatch, f.seed, other.seed)
+	}
+	if width := f.quotientBits + f.remainderBits; width != otherWidth {
+		return fmt.Errorf("%w: %d-bit and %d-bit fingerprints", ErrIncompatible, width, otherWidth)
+	}
+
+	// Fingerprints have the full width whatever the two tables' sizes, so
+	// other's entries can go into a table of any size
+	q := f.quotientBits
+	total := f.count + uint64(len(fps))
+	for float64(total) > qfMaxLoad*float64(uint64(1)<<q) && q+1 < otherWidth && q < qfMaxQuotientBits {
+		q++
+	}
+	if total >= 1<<q {
+		return fmt.Errorf("%w: %d entries do not fit in %d slots", ErrOverloaded, total, uint64(1)<<q)
+	}
+	f.rebuild(q, append(f.fingerprints(), fps...))
+	return nil
+}
+
+// Len returns the number of entries, counting repeated adds
+func (f *QuotientFilter) Len() uint64 {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return f.count
+}
+
+// Dropped returns the number of adds dropped because the filter was full
+func (f *QuotientFilter) Dropped() uint64 {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return f.dropped
+}
+
+// QuotientFilterStats describes a QuotientFilter's geometry and fill
+type QuotientFilterStats struct {
+	QuotientBits  uint
+	RemainderBits uint
+	Entries       uint64
+	LoadFactor    float64
+	// EstimatedFPP is the probability that a lookup matches a stored fingerprint
+	EstimatedFPP float64
+	MemoryUsage  uint64
+	Dropped      uint64
+}
+
+// Stats returns the filter's geometry and fill
+func (f *QuotientFilter) Stats() QuotientFilterStats {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return QuotientFilterStats{
+		QuotientBits:  f.quotientBits,
+		RemainderBits: f.remainderBits,
+		Entries:       f.count,
+		LoadFactor:    float64(f.count) / float64(f.indexMask+1),
+		EstimatedFPP:  -math.Expm1(-float64(f.count) / math.Exp2(float64(f.quotientBits+f.remainderBits))),
+		MemoryUsage:   uint64(len(f.table)) * 8,
+		Dropped:       f.dropped,
+	}
+}
+
+var _ Filter = (*QuotientFilter)(nil)
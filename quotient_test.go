@@ -0,0 +1,205 @@
+package bloomfilter
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"sync"
+	"testing"
+)
+
+// TestQuotientFilterModel checks random adds and deletes against an exact
+// multiset on small tables, where clusters wrap around and the table grows
+func TestQuotientFilterModel(t *testing.T) {
+	rng := rand.New(rand.NewPCG(1, 2))
+	key := func(k uint64) []byte { return binary.LittleEndian.AppendUint64(nil, k) }
+
+	for trial := 0; trial < 100; trial++ {
+		f := NewQuotientFilterWithBits(uint(rng.IntN(5)+2), uint(rng.IntN(5)+1), 0)
+		model := map[uint64]int{}
+		for op := 0; op < 2000; op++ {
+			k := rng.Uint64N(1 << rng.IntN(9))
+			if rng.IntN(3) == 0 {
+				if model[k] > 0 {
+					if !f.Delete(key(k)) {
+						t.Fatalf("Trial %d: Delete(%d) did not find the key", trial, k)
+					}
+					model[k]--
+				}
+			} else if f.TryAdd(key(k)) == nil {
+				model[k]++
+			}
+
+			if op%100 == 0 {
+				var entries uint64
+				for k, c := range model {
+					entries += uint64(c)
+					if c > 0 && !f.Contains(key(k)) {
+						t.Fatalf("Trial %d op %d: false negative for %d (%+v)", trial, op, k, f.Stats())
+					}
+				}
+				if f.Len() != entries || uint64(len(f.fingerprints())) != entries {
+					t.Fatalf("Trial %d: Len %d, walked %d, want %d", trial, f.Len(), len(f.fingerprints()), entries)
+				}
+			}
+		}
+	}
+}
+
+// TestQuotientFilterGrowth tests automatic doubling and the resulting rate
+func TestQuotientFilterGrowth(t *testing.T) {
+	f := NewQuotientFilter(1000, 0.01)
+	initial := f.Stats()
+	for i := uint64(0); i < 8000; i++ {
+		f.AddUint64(i)
+	}
+	s := f.Stats()
+	if s.QuotientBits != initial.QuotientBits+3 || s.RemainderBits != initial.RemainderBits-3 {
+		t.Errorf("Expected three doublings: %+v -> %+v", initial, s)
+	}
+	if s.LoadFactor > qfMaxLoad {
+		t.Errorf("Load factor %g above %g", s.LoadFactor, qfMaxLoad)
+	}
+	var fp int
+	for i := uint64(0); i < 100_000; i++ {
+		if !f.ContainsUint64(i) && i < 8000 {
+			t.Fatalf("False negative for %d", i)
+		}
+		if f.ContainsUint64(1<<40 + i) {
+			fp++
+		}
+	}
+	// Three doublings take three remainder bits: 8x the initial rate
+	if rate := float64(fp) / 100_000; rate > 0.08 {
+		t.Errorf("FPR %g after growth, estimated %g", rate, s.EstimatedFPP)
+	}
+}
+
+// TestQuotientFilterResize tests growing and shrinking without the keys
+func TestQuotientFilterResize(t *testing.T) {
+	f := NewQuotientFilterWithBits(10, 10, 0)
+	for i := 0; i < 500; i++ {
+		f.AddString(fmt.Sprint("key_", i))
+	}
+	for _, q := range []uint{14, 9, 12} {
+		if err := f.Resize(q); err != nil {
+			t.Fatal(err)
+		}
+		if s := f.Stats(); s.QuotientBits != q || s.RemainderBits != 20-q || s.Entries != 500 {
+			t.Fatalf("Resize(%d): %+v", q, s)
+		}
+		for i := 0; i < 500; i++ {
+			if !f.ContainsString(fmt.Sprint("key_", i)) {
+				t.Fatalf("Resize(%d) lost key_%d", q, i)
+			}
+		}
+	}
+	for _, q := range []uint{0, 8, 20, 41} {
+		if err := f.Resize(q); err == nil {
+			t.Errorf("Resize(%d) should fail", q)
+		}
+	}
+}
+
+// TestQuotientFilterMerge tests merging filters of different table sizes
+func TestQuotientFilterMerge(t *testing.T) {
+	a := NewQuotientFilterWithBits(8, 16, 5)
+	b := NewQuotientFilterWithBits(12, 12, 5)
+	for i := 0; i < 150; i++ {
+		a.AddString(fmt.Sprint("a_", i))
+	}
+	for i := 0; i < 2000; i++ {
+		b.AddString(fmt.Sprint("b_", i))
+	}
+	if err := a.Merge(b); err != nil {
+		t.Fatal(err)
+	}
+	if a.Len() != 2150 || b.Len() != 2000 {
+		t.Errorf("Len after merge: %d and %d", a.Len(), b.Len())
+	}
+	if s := a.Stats(); s.LoadFactor > qfMaxLoad || s.QuotientBits+s.RemainderBits != 24 {
+		t.Errorf("Merged geometry: %+v", s)
+	}
+	for i := 0; i < 2000; i++ {
+		if i < 150 && !a.ContainsString(fmt.Sprint("a_", i)) || !a.ContainsString(fmt.Sprint("b_", i)) {
+			t.Fatalf("Merged filter is missing key %d", i)
+		}
+	}
+	// Deleting a key added to both sides leaves one copy
+	a.AddString("shared")
+	if err := a.Merge(a); err != nil || a.Len() != 4302 {
+		t.Fatalf("Self merge: %v, Len %d", err, a.Len())
+	}
+	if !a.DeleteString("shared") || !a.ContainsString("shared") {
+		t.Error("Self merge lost a copy")
+	}
+
+	if err := a.Merge(NewQuotientFilterWithBits(8, 16, 6)); !errors.Is(err, ErrSeedMismatch) {
+		t.Errorf("Expected ErrSeedMismatch, got %v", err)
+	}
+	if err := a.Merge(NewQuotientFilterWithBits(8, 15, 5)); !errors.Is(err, ErrIncompatible) {
+		t.Errorf("Expected ErrIncompatible, got %v", err)
+	}
+}
+
+// TestQuotientFilterFull tests that a filter that cannot grow drops adds
+func TestQuotientFilterFull(t *testing.T) {
+	f := NewQuotientFilterWithBits(3, 1, 0)
+	var added int
+	for i := uint64(0); i < 20; i++ {
+		if err := f.TryAdd(binary.LittleEndian.AppendUint64(nil, i)); err == nil {
+			added++
+		} else if !errors.Is(err, ErrOverloaded) {
+			t.Fatal(err)
+		}
+	}
+	if added != 7 || f.Dropped() != 13 {
+		t.Errorf("Added %d, dropped %d; want 7 and 13", added, f.Dropped())
+	}
+	for _, fn := range []func(){
+		func() { NewQuotientFilterWithBits(0, 8, 0) },
+		func() { NewQuotientFilterWithBits(8, 0, 0) },
+		func() { NewQuotientFilterWithBits(41, 8, 0) },
+		func() { NewQuotientFilterWithBits(40, 25, 0) },
+		func() { NewQuotientFilter(1000, 1e-30) },
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Error("Expected a panic")
+				}
+			}()
+			fn()
+		}()
+	}
+}
+
+// TestQuotientFilterConcurrent tests concurrent adds, lookups and deletes
+func TestQuotientFilterConcurrent(t *testing.T) {
+	f := NewQuotientFilter(1000, 0.01)
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 1000; i++ {
+				k := uint64(g*1000 + i)
+				f.AddUint64(k)
+				f.ContainsUint64(k)
+				if i%2 == 0 {
+					f.DeleteUint64(k)
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+	if f.Len() != 4000 {
+		t.Errorf("Len %d, want 4000", f.Len())
+	}
+	for k := uint64(1); k < 8000; k += 2 {
+		if !f.ContainsUint64(k) {
+			t.Fatalf("Lost %d", k)
+		}
+	}
+}
@@ -0,0 +1,7 @@
+//go:build race
+
+package bloomfilter
+
+// raceEnabled is set when testing with -race, under which sync.Pool drops
+// items at random, so pooled paths allocate
+const raceEnabled = true
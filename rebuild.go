@@ -0,0 +1,54 @@
+package bloomfilter
+
+import (
+	"fmt"
+	"iter"
+	"math"
+)
+
+// Capacity returns the number of distinct keys the filter's geometry is
+// optimal for, m·ln2/k. Past it the load factor exceeds one half and the false
+// positive rate climbs quickly (see IsSaturated).
+func (bf *CacheOptimizedBloomFilter) Capacity() uint64 {
+	return uint64(float64(bf.bitCount) * math.Ln2 / float64(bf.hashCount))
+}
+
+// DesignFPR returns the false positive rate of the filter holding Capacity
+// keys, 2^-k
+func (bf *CacheOptimizedBloomFilter) DesignFPR() float64 {
+	return math.Pow(0.5, float64(bf.hashCount))
+}
+
+// Rebuild returns a new filter sized for newExpectedElements at
+// newFalsePositiveRate, with the filter's seed, probe scheme, hash version and
+// hasher, holding every key of keys. The keys must be the ones added to this
+// filter, or a superset: a Bloom filter cannot list its own keys. Keys are
+// copied and added in batches as in BuildFromSeq. Settings such as load
+// shedding and sampling are not carried over, and the filter itself is left
+// unchanged.
+//
+// Returns an error if the new sizing is invalid.
+func (bf *CacheOptimizedBloomFilter) Rebuild(newExpectedElements uint64, newFalsePositiveRate float64, keys iter.Seq[[]byte]) (*CacheOptimizedBloomFilter, error) {
+	return BuildFromSeq(keys, newExpectedElements, newFalsePositiveRate,
+		WithSeed(bf.seed), WithProbeScheme(bf.probe), WithHashVersion(bf.hashVersion), WithHasher(bf.hasher))
+}
+
+// Grow is Rebuild with parameters chosen for a filter that outgrew its size:
+// the new filter holds factor times the larger of Capacity and EstimatedCount
+// keys at the filter's DesignFPR, so it keeps the same hash count and false
+// positive rate with room for factor times as many keys. A factor of 2 is a
+// good default; the number of rebuilds grows with log(n)/log(factor).
+//
+// Returns an error if factor is not greater than 1 or the new size cannot be
+// allocated.
+func (bf *CacheOptimizedBloomFilter) Grow(factor float64, keys iter.Seq[[]byte]) (*CacheOptimizedBloomFilter, error) {
+	if !(factor > 1) || math.IsInf(factor, 0) {
+		return nil, fmt.Errorf("bloomfilter: growth factor must be greater than 1, got %f", factor)
+	}
+	current := float64(max(bf.Capacity(), bf.EstimatedCount(), 1))
+	if current*factor >= math.MaxUint64 {
+		return nil, fmt.Errorf("bloomfilter: cannot grow %d keys by a factor of %g", uint64(current), factor)
+	}
+	return BuildFromSeq(keys, uint64(current*factor), bf.DesignFPR(),
+		WithHashCount(bf.hashCount), WithSeed(bf.seed), WithProbeScheme(bf.probe), WithHashVersion(bf.hashVersion), WithHasher(bf.hasher))
+}
@@ -0,0 +1,89 @@
+package bloomfilter
+
+import (
+	"fmt"
+	"math"
+	"testing"
+)
+
+// TestCapacity tests the capacity and rate derived from the geometry
+func TestCapacity(t *testing.T) {
+	bf := NewCacheOptimizedBloomFilter(100_000, 0.01)
+	if c := bf.Capacity(); c < 100_000 || c > 115_000 {
+		t.Errorf("Capacity() = %d, want about 100000", c)
+	}
+	if p := bf.DesignFPR(); p < 0.005 || p > 0.02 {
+		t.Errorf("DesignFPR() = %g, want about 0.01", p)
+	}
+
+	// The load at capacity is one half
+	for i := uint64(0); i < bf.Capacity(); i++ {
+		bf.AddUint64(i)
+	}
+	if load := bf.GetCacheStats().LoadFactor; math.Abs(load-0.5) > 0.01 {
+		t.Errorf("Load at capacity = %.3f, want 0.5", load)
+	}
+}
+
+// TestRebuild tests that a rebuilt filter holds the keys with its new sizing
+func TestRebuild(t *testing.T) {
+	log := NewMemoryKeyLog()
+	bf, _ := NewWithOptions(WithExpectedElements(1000), WithSeed(11), WithProbeScheme(DoubleHashing))
+	for i := 0; i < 5000; i++ {
+		key := []byte(fmt.Sprintf("key-%d", i))
+		bf.Add(key)
+		log.Record(key)
+	}
+
+	next, err := bf.Rebuild(10_000, 0.001, log.Keys())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if next.Seed() != 11 || next.ProbeScheme() != DoubleHashing {
+		t.Errorf("Rebuilt filter has seed %d and scheme %s", next.Seed(), next.ProbeScheme())
+	}
+	for key := range log.Keys() {
+		if !next.Contains(key) {
+			t.Fatalf("Rebuilt filter is missing %s", key)
+		}
+	}
+	if next.EstimatedFPP() >= bf.EstimatedFPP() {
+		t.Errorf("Rebuilt FPP %g not below the overfull filter's %g", next.EstimatedFPP(), bf.EstimatedFPP())
+	}
+	if _, err := bf.Rebuild(0, 0.01, log.Keys()); err == nil {
+		t.Error("Expected an error for zero expected elements")
+	}
+}
+
+// TestGrow tests the parameters Grow chooses
+func TestGrow(t *testing.T) {
+	log := NewMemoryKeyLog()
+	bf := NewCacheOptimizedBloomFilter(1000, 0.01)
+	for i := 0; i < 3000; i++ {
+		key := []byte(fmt.Sprintf("key-%d", i))
+		bf.Add(key)
+		log.Record(key)
+	}
+
+	next, err := bf.Grow(2, log.Keys())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Sized for twice the estimated count at the same rate
+	want := 2 * float64(bf.EstimatedCount())
+	if c := float64(next.Capacity()); c < want || c > 1.05*want {
+		t.Errorf("Grown capacity %.0f, want about %.0f", c, want)
+	}
+	if next.HashCount() != bf.HashCount() {
+		t.Errorf("Grown hash count %d, want %d", next.HashCount(), bf.HashCount())
+	}
+	if load := next.GetCacheStats().LoadFactor; load > 0.4 {
+		t.Errorf("Grown filter load %.3f, want below one half", load)
+	}
+
+	for _, factor := range []float64{1, 0.5, math.NaN(), math.Inf(1)} {
+		if _, err := bf.Grow(factor, log.Keys()); err == nil {
+			t.Errorf("Grow(%g) succeeded", factor)
+		}
+	}
+}
@@ -0,0 +1,217 @@
+package bloomfilter
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// Operation recording
+//
+// To debug membership discrepancies that only happen in production, a filter
+// can log every operation to an io.Writer and the log can be replayed offline.
+// The log starts with a snapshot of the filter in the native format, so
+// recording can begin at any time, followed by one record per operation:
+//
+//	magic "BFRL" | version u16 | reserved u16 | snapshot (see AppendBinary) |
+//	{ op u8 | result u8 | h1 u64 | h2 u64 }...
+//
+// Records hold the key's hash pair rather than the key, so logs contain no user
+// data and replay needs no hashing. Add records whether the add set a new bit;
+// Contains records the answer given. Clear is recorded and replayed; Union,
+// Intersection and OrWord change bits no record describes, so they are logged as
+// external changes and replay results after one are only indicative.
+//
+// Records are written in the order their operations finish, which under
+// concurrency may differ from the order they reached the bits; a replay
+// mismatch on a result that raced with other adds is expected.
+
+const (
+	recordMagic   = "BFRL"
+	recordVersion = 1
+	recordSize    = 18
+)
+
+// Recorded operation codes
+const (
+	RecordAdd      = 1
+	RecordContains = 2
+	RecordClear    = 3
+	RecordExternal = 4
+)
+
+// recorder writes operation records for one StartRecording session
+type recorder struct {
+	mu      sync.Mutex
+	w       *bufio.Writer
+	err     error
+	stopped bool
+}
+
+// record appends one record, remembering the first write error
+func (r *recorder) record(op byte, result bool, h1, h2 uint64) {
+	var rec [recordSize]byte
+	rec[0] = op
+	if result {
+		rec[1] = 1
+	}
+	binary.LittleEndian.PutUint64(rec[2:], h1)
+	binary.LittleEndian.PutUint64(rec[10:], h2)
+
+	r.mu.Lock()
+	if r.err == nil && !r.stopped {
+		_, r.err = r.w.Write(rec[:])
+	}
+	r.mu.Unlock()
+}
+
+// StartRecording logs every subsequent operation to w, starting with a snapshot
+// of the current contents, until StopRecording. Writes are buffered and
+// serialized, which slows every operation down; it is a debugging aid, not for
+// permanent use. While recording, batch adds run key by key. Release,
+// UnmarshalBinary and ReadFrom stop the recording.
+//
+// Returns an error if the filter is already recording or the snapshot cannot be written.
+func (bf *CacheOptimizedBloomFilter) StartRecording(w io.Writer) error {
+	r := &recorder{w: bufio.NewWriterSize(w, 64<<10)}
+	// Operations that finish during the snapshot wait here and are logged after it
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !bf.recorder.CompareAndSwap(nil, r) {
+		return errors.New("bloomfilter: filter is already recording")
+	}
+
+	header := binary.LittleEndian.AppendUint16([]byte(recordMagic), recordVersion)
+	header = binary.LittleEndian.AppendUint16(header, 0)
+	if _, err := r.w.Write(header); err != nil {
+		bf.recorder.Store(nil)
+		return err
+	}
+	if _, err := bf.WriteTo(r.w); err != nil {
+		bf.recorder.Store(nil)
+		return err
+	}
+	return nil
+}
+
+// StopRecording stops logging, flushes the log and returns the first error
+// encountered while writing it. It is a no-op if the filter is not recording.
+func (bf *CacheOptimizedBloomFilter) StopRecording() error {
+	r := bf.recorder.Swap(nil)
+	if r == nil {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.err == nil {
+		r.err = r.w.Flush()
+	}
+	r.stopped = true
+	return r.err
+}
+
+// recordExternal logs a bulk change that replay cannot reproduce
+func (bf *CacheOptimizedBloomFilter) recordExternal() {
+	if r := bf.recorder.Load(); r != nil {
+		r.record(RecordExternal, false, 0, 0)
+	}
+}
+
+// ReplayMismatch is a recorded result that replay did not reproduce
+type ReplayMismatch struct {
+	Index    uint64 // Position of the record in the log, from 0
+	Op       byte   // RecordAdd or RecordContains
+	H1, H2   uint64
+	Recorded bool // Result in the log
+	Replayed bool // Result of the replay
+	// ExternalBefore is true if an external change precedes the record, which
+	// makes the mismatch possibly spurious
+	ExternalBefore bool
+}
+
+// ReplayResult is the outcome of Replay
+type ReplayResult struct {
+	// Filter is the state reproduced from the log
+	Filter *CacheOptimizedBloomFilter
+
+	Adds, Contains, Clears, ExternalChanges uint64
+	// Mismatches holds up to 1000 mismatches; MismatchCount counts all of them
+	Mismatches    []ReplayMismatch
+	MismatchCount uint64
+}
+
+// maxReplayMismatches bounds ReplayResult.Mismatches
+const maxReplayMismatches = 1000
+
+// Replay reproduces a filter from a log written by StartRecording and checks every
+// recorded result against the replayed one. A log cut off in the middle of a
+// record (for example by a crash) replays up to the last complete record.
+func Replay(r io.Reader) (*ReplayResult, error) {
+	br := bufio.NewReaderSize(r, 64<<10)
+	header := make([]byte, 8)
+	if _, err := io.ReadFull(br, header); err != nil || string(header[:4]) != recordMagic {
+		return nil, fmt.Errorf("%w: not an operation log", ErrInvalidEncoding)
+	}
+	if v := binary.LittleEndian.Uint16(header[4:]); v != recordVersion {
+		return nil, fmt.Errorf("%w: unsupported operation log version %d", ErrInvalidEncoding, v)
+	}
+
+	res := &ReplayResult{Filter: &CacheOptimizedBloomFilter{}}
+	if _, err := res.Filter.ReadFrom(br); err != nil {
+		return nil, fmt.Errorf("bloomfilter: replay snapshot: %w", err)
+	}
+	bf := res.Filter
+
+	var rec [recordSize]byte
+	for index := uint64(0); ; index++ {
+		if _, err := io.ReadFull(br, rec[:]); err != nil {
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				return res, nil
+			}
+			return res, err
+		}
+		op, recorded := rec[0], rec[1] != 0
+		h1 := binary.LittleEndian.Uint64(rec[2:])
+		h2 := binary.LittleEndian.Uint64(rec[10:])
+
+		var replayed bool
+		switch op {
+		case RecordAdd:
+			res.Adds++
+			replayed = bf.replayAdd(h1, h2)
+		case RecordContains:
+			res.Contains++
+			replayed = bf.containsHashed(nil, h1, h2)
+		case RecordClear:
+			res.Clears++
+			bf.Clear()
+			continue
+		case RecordExternal:
+			res.ExternalChanges++
+			continue
+		default:
+			return res, fmt.Errorf("%w: unknown operation %d in record %d", ErrInvalidEncoding, op, index)
+		}
+
+		if replayed != recorded {
+			res.MismatchCount++
+			if len(res.Mismatches) < maxReplayMismatches {
+				res.Mismatches = append(res.Mismatches, ReplayMismatch{
+					Index: index, Op: op, H1: h1, H2: h2,
+					Recorded: recorded, Replayed: replayed,
+					ExternalBefore: res.ExternalChanges > 0,
+				})
+			}
+		}
+	}
+}
+
+// replayAdd sets the bits of a hash pair and reports whether any was new
+func (bf *CacheOptimizedBloomFilter) replayAdd(h1, h2 uint64) bool {
+	positions := make([]uint64, bf.hashCount)
+	bf.hashPositions(h1, h2, positions)
+	return bf.setBits(positions) > 0
+}
@@ -0,0 +1,125 @@
+package bloomfilter
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+)
+
+// TestRecordReplay tests that replaying a log reproduces the filter and every result
+func TestRecordReplay(t *testing.T) {
+	bf := NewCacheOptimizedBloomFilter(1000, 0.01)
+	for i := 0; i < 100; i++ {
+		bf.AddString(fmt.Sprintf("before_%d", i)) // In the snapshot
+	}
+
+	var log bytes.Buffer
+	if err := bf.StartRecording(&log); err != nil {
+		t.Fatal(err)
+	}
+	if err := bf.StartRecording(&log); err == nil {
+		t.Error("Expected error starting a second recording")
+	}
+	bf.AddStrings([]string{"a", "b", "c"})
+	for i := 0; i < 500; i++ {
+		bf.AddUint64(uint64(i))
+		bf.ContainsString(fmt.Sprintf("probe_%d", i))
+	}
+	bf.Clear()
+	for i := 0; i < 200; i++ {
+		bf.AddString(fmt.Sprintf("after_%d", i))
+		bf.ContainsString(fmt.Sprintf("before_%d", i))
+	}
+	if err := bf.StopRecording(); err != nil {
+		t.Fatal(err)
+	}
+	bf.AddString("not recorded")
+	size := log.Len()
+
+	res, err := Replay(bytes.NewReader(log.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Adds != 703 || res.Contains != 700 || res.Clears != 1 || res.ExternalChanges != 0 {
+		t.Errorf("Replayed %d adds, %d contains, %d clears, %d external", res.Adds, res.Contains, res.Clears, res.ExternalChanges)
+	}
+	if res.MismatchCount != 0 {
+		t.Errorf("Unexpected mismatches: %+v", res.Mismatches)
+	}
+	bf2, _ := NewWithOptions(WithExpectedElements(1000), WithSeed(bf.Seed()))
+	for i := 0; i < 200; i++ {
+		bf2.AddString(fmt.Sprintf("after_%d", i))
+	}
+	if !slices.Equal(res.Filter.Words(), bf2.Words()) {
+		t.Error("Replayed filter differs from the recorded one")
+	}
+
+	// A log cut off mid-record replays up to the last complete record
+	res, err = Replay(bytes.NewReader(log.Bytes()[:size-5]))
+	if err != nil || res.Contains != 699 {
+		t.Errorf("Truncated log: %d contains, %v", res.Contains, err)
+	}
+
+	if _, err := Replay(bytes.NewReader([]byte("nope"))); !errors.Is(err, ErrInvalidEncoding) {
+		t.Errorf("Expected ErrInvalidEncoding for a bad log, got %v", err)
+	}
+}
+
+// TestReplayMismatch tests that results replay cannot reproduce are reported
+func TestReplayMismatch(t *testing.T) {
+	bf := NewCacheOptimizedBloomFilter(1000, 0.01)
+	other := bf.Clone()
+	other.AddString("merged")
+
+	var log bytes.Buffer
+	bf.StartRecording(&log)
+	bf.AddString("x")
+	bf.ContainsString("x")
+	bf.Union(other) // Not reproducible from the log
+	bf.ContainsString("merged")
+	bf.StopRecording()
+
+	// Flip the recorded answer of the first Contains (record 1)
+	data := log.Bytes()
+	start := len(data) - 4*recordSize
+	data[start+recordSize+1] ^= 1
+
+	res, err := Replay(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.ExternalChanges != 1 || res.MismatchCount != 2 {
+		t.Fatalf("Expected 1 external change and 2 mismatches, got %+v", res)
+	}
+	first, second := res.Mismatches[0], res.Mismatches[1]
+	if first.Index != 1 || first.Op != RecordContains || !first.Replayed || first.Recorded || first.ExternalBefore {
+		t.Errorf("Tampered record: %+v", first)
+	}
+	if second.Index != 3 || !second.Recorded || second.Replayed || !second.ExternalBefore {
+		t.Errorf("Record after Union: %+v", second)
+	}
+}
+
+// TestRecordingWriteError tests that write errors surface from StopRecording
+func TestRecordingWriteError(t *testing.T) {
+	bf := NewCacheOptimizedBloomFilter(100000, 0.01)
+	if err := bf.StartRecording(&failingWriter{limit: 100}); err == nil {
+		t.Fatal("Expected the snapshot write to fail")
+	}
+
+	w := &failingWriter{limit: 200000}
+	if err := bf.StartRecording(w); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 20000; i++ {
+		bf.AddUint64(uint64(i))
+	}
+	if err := bf.StopRecording(); err == nil {
+		t.Error("Expected the write error from StopRecording")
+	}
+	if err := bf.StopRecording(); err != nil {
+		t.Errorf("Second StopRecording: %v", err)
+	}
+}
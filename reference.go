@@ -0,0 +1,128 @@
+package bloomfilter
+
+import (
+	"fmt"
+	"iter"
+)
+
+// ReferenceFilter is a slow, obviously correct model of a filter for
+// differential testing: it keeps the set bit positions in a map, computes each
+// probe from the closed form of the probe scheme rather than incrementally,
+// and remembers every key added. Feed it the same adds as the filter under
+// test and compare with DifferentialCheck, from a unit test or a fuzz target,
+// to validate an integration or a new SIMD kernel. Filters are deterministic
+// for a given seed, so a failing input reproduces exactly.
+//
+// It is not safe for concurrent use.
+type ReferenceFilter struct {
+	bitCount  uint64
+	hashCount uint32
+	seed      uint64
+	probe     ProbeScheme
+	version   HashVersion
+	hasher    HashFunc
+	bits      map[uint64]struct{}
+	keys      map[string]struct{}
+}
+
+// NewReferenceFilter returns an empty reference with the geometry, seed, probe
+// scheme, hash version and hasher bf has now
+func NewReferenceFilter(bf *CacheOptimizedBloomFilter) *ReferenceFilter {
+	return &ReferenceFilter{
+		bitCount:  bf.bitCount,
+		hashCount: bf.hashCount,
+		seed:      bf.seed,
+		probe:     bf.probe,
+		version:   bf.hashVersion,
+		hasher:    bf.hasher,
+		bits:      make(map[uint64]struct{}),
+		keys:      make(map[string]struct{}),
+	}
+}
+
+// Add records key and sets its bits
+func (r *ReferenceFilter) Add(key []byte) {
+	r.keys[string(key)] = struct{}{}
+	for _, p := range r.positions(key) {
+		r.bits[p] = struct{}{}
+	}
+}
+
+// Contains reports whether all of key's bits are set, the exact answer a
+// filter given the same adds must return
+func (r *ReferenceFilter) Contains(key []byte) bool {
+	for _, p := range r.positions(key) {
+		if _, ok := r.bits[p]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// Has reports whether key was added, without false positives
+func (r *ReferenceFilter) Has(key []byte) bool {
+	_, ok := r.keys[string(key)]
+	return ok
+}
+
+// Len returns the number of distinct keys added
+func (r *ReferenceFilter) Len() int {
+	return len(r.keys)
+}
+
+// BitsSet returns the number of set bits, which PopCount of the filter under
+// test must match
+func (r *ReferenceFilter) BitsSet() uint64 {
+	return uint64(len(r.bits))
+}
+
+// positions returns the probe positions of key
+func (r *ReferenceFilter) positions(key []byte) []uint64 {
+	var h1, h2 uint64
+	if r.hasher != nil {
+		h1, h2 = r.hasher(key)
+	} else {
+		h1, h2 = r.version.hash(key, r.seed)
+	}
+	positions := make([]uint64, r.hashCount)
+	for i := range positions {
+		n := uint64(i)
+		x := h1 + n*h2
+		if r.probe == EnhancedDoubleHashing {
+			x += (n*n*n - n) / 6
+		}
+		positions[i] = x % r.bitCount
+	}
+	return positions
+}
+
+// DifferentialCheck queries bf and ref with every key of keys and returns an
+// error describing the first disagreement: a key ref contains that bf does not
+// (a false negative when ref.Has it), or one bf contains with all of its
+// probes that ref does not. Positives are checked through Contains, so
+// adaptive probing on bf is fine, and negatives with every probe. Returns nil
+// if they agree on every key.
+func DifferentialCheck(bf *CacheOptimizedBloomFilter, ref *ReferenceFilter, keys iter.Seq[[]byte]) error {
+	positions := make([]uint64, bf.hashCount)
+	for key := range keys {
+		want := ref.Contains(key)
+		if want && !bf.Contains(key) {
+			if ref.Has(key) {
+				return fmt.Errorf("bloomfilter: differential check: false negative for added key %q", key)
+			}
+			return fmt.Errorf("bloomfilter: differential check: filter misses %q, which the reference contains", key)
+		}
+		if !want && bf.probeAll(key, positions) {
+			return fmt.Errorf("bloomfilter: differential check: filter contains %q, which the reference does not", key)
+		}
+	}
+	return nil
+}
+
+// probeAll reports whether all of key's bits are set, using every probe and
+// without the bookkeeping of Contains; positions must hold hashCount entries
+func (bf *CacheOptimizedBloomFilter) probeAll(key []byte, positions []uint64) bool {
+	h1, h2 := bf.hashKey(key)
+	bf.hashPositions(h1, h2, positions)
+	return bf.checkBitsAtomic(positions)
+}
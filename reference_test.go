@@ -0,0 +1,91 @@
+package bloomfilter
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math/rand/v2"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// TestDifferentialCheck tests that filters agree with the reference for both
+// probe schemes and both size classes, and that a lost bit is caught
+func TestDifferentialCheck(t *testing.T) {
+	for _, opts := range [][]Option{
+		{WithExpectedElements(2000), WithFalsePositiveRate(0.01)},
+		{WithExpectedElements(2000), WithFalsePositiveRate(0.01), WithProbeScheme(DoubleHashing), WithSeed(7)},
+		{WithExpectedElements(2000), WithFalsePositiveRate(0.01), WithPowerOfTwoSize()},
+	} {
+		bf, err := NewWithOptions(opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ref := NewReferenceFilter(bf)
+		rng := rand.New(rand.NewPCG(3, 4))
+		var batch [][]byte
+		for i := 0; i < 2000; i++ {
+			key := binary.LittleEndian.AppendUint64(nil, rng.Uint64())
+			ref.Add(key)
+			if i%2 == 0 {
+				bf.Add(key)
+			} else {
+				batch = append(batch, key)
+			}
+		}
+		bf.AddBatch(batch)
+
+		queries := func(yield func([]byte) bool) {
+			for i := 0; i < 20000; i++ {
+				if !yield([]byte(fmt.Sprintf("q%d", i))) {
+					return
+				}
+			}
+		}
+		if err := DifferentialCheck(bf, ref, queries); err != nil {
+			t.Errorf("%v: %v", bf.ProbeScheme(), err)
+		}
+		if err := DifferentialCheck(bf, ref, slices.Values(batch)); err != nil {
+			t.Errorf("%v: %v", bf.ProbeScheme(), err)
+		}
+		if ref.BitsSet() != bf.PopCount() || ref.Len() != 2000 {
+			t.Errorf("Reference has %d bits and %d keys, filter %d bits", ref.BitsSet(), ref.Len(), bf.PopCount())
+		}
+
+		// Lose a bit of an added key
+		p := ref.positions(batch[0])[0]
+		bf.cacheLines[p/BitsPerCacheLine].words[p%BitsPerCacheLine/64] &^= 1 << (p % 64)
+		err = DifferentialCheck(bf, ref, slices.Values(batch))
+		if err == nil || !strings.Contains(err.Error(), "false negative") {
+			t.Errorf("Lost bit not caught: %v", err)
+		}
+	}
+}
+
+// FuzzDifferentialCheck checks the filter against the reference for arbitrary
+// keys, split from the input at zero bytes
+func FuzzDifferentialCheck(f *testing.F) {
+	f.Add([]byte("a\x00b\x00c"), []byte("d"))
+	f.Add([]byte(""), []byte(""))
+	f.Add([]byte("key\x00\x00\xff\xfe"), []byte("key"))
+	f.Fuzz(func(t *testing.T, added, queried []byte) {
+		bf := NewCacheOptimizedBloomFilter(100, 0.01)
+		ref := NewReferenceFilter(bf)
+		keys := strings.Split(string(added), "\x00")
+		for _, k := range keys {
+			bf.AddString(k)
+			ref.Add([]byte(k))
+		}
+		all := append(keys, strings.Split(string(queried), "\x00")...)
+		seq := func(yield func([]byte) bool) {
+			for _, k := range all {
+				if !yield([]byte(k)) {
+					return
+				}
+			}
+		}
+		if err := DifferentialCheck(bf, ref, seq); err != nil {
+			t.Fatal(err)
+		}
+	})
+}
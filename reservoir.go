@@ -0,0 +1,113 @@
+package bloomfilter
+
+import (
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"sync"
+	"sync/atomic"
+)
+
+// keyReservoir keeps a uniform random sample of the added keys using reservoir
+// sampling (Algorithm L). Once the reservoir is full, the gap to the next key to
+// take is drawn in advance, so skipped keys only pay an atomic increment and load.
+type keyReservoir struct {
+	capacity int
+
+	seen atomic.Uint64 // keys offered so far
+	next atomic.Uint64 // 1-based index of the next key to take; 0 while filling
+
+	mu   sync.Mutex
+	keys [][]byte
+	w    float64
+}
+
+// EnableKeyReservoir retains a uniform random sample of at most capacity added
+// keys, retrievable with SampleKeys. This is useful to rebuild a filter at new
+// parameters from a representative sample when keeping every key is too
+// expensive, or to inspect what an unfamiliar filter contains.
+//
+// Keys are copied into the reservoir. Only keys added after enabling are
+// sampled, and keys merged in through Union are never seen. Under concurrent
+// adds the sample is uniform over the keys in the order they were counted.
+//
+// Panics if capacity is not positive.
+func (bf *CacheOptimizedBloomFilter) EnableKeyReservoir(capacity int) {
+	if capacity <= 0 {
+		panic(fmt.Sprintf("bloomfilter: reservoir capacity must be greater than 0, got %d", capacity))
+	}
+	bf.reservoir.Store(&keyReservoir{capacity: capacity, keys: make([][]byte, 0, capacity)})
+}
+
+// DisableKeyReservoir stops sampling and releases the sampled keys
+func (bf *CacheOptimizedBloomFilter) DisableKeyReservoir() {
+	bf.reservoir.Store(nil)
+}
+
+// SampleKeys returns a copy of the sampled keys, or nil if the reservoir is disabled
+func (bf *CacheOptimizedBloomFilter) SampleKeys() [][]byte {
+	r := bf.reservoir.Load()
+	if r == nil {
+		return nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	keys := make([][]byte, len(r.keys))
+	for i, k := range r.keys {
+		keys[i] = append([]byte(nil), k...)
+	}
+	return keys
+}
+
+// observe offers an added key to the reservoir
+func (r *keyReservoir) observe(data []byte) {
+	n := r.seen.Add(1)
+	if n < r.next.Load() {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if len(r.keys) < r.capacity {
+		r.keys = append(r.keys, append([]byte(nil), data...))
+		if len(r.keys) == r.capacity {
+			r.w = math.Exp(math.Log(unitRand()) / float64(r.capacity))
+			r.skipFrom(max(n, uint64(r.capacity)))
+		}
+		return
+	}
+
+	// Another goroutine may have taken a key and moved next past n meanwhile
+	if n < r.next.Load() {
+		return
+	}
+	i := rand.IntN(r.capacity)
+	r.keys[i] = append(r.keys[i][:0], data...)
+	r.w *= math.Exp(math.Log(unitRand()) / float64(r.capacity))
+	r.skipFrom(n)
+}
+
+// skipFrom draws the index of the next key to take after key n. Must hold mu.
+func (r *keyReservoir) skipFrom(n uint64) {
+	skip := math.Floor(math.Log(unitRand()) / math.Log1p(-r.w))
+	if !(skip < math.MaxUint64/2) {
+		skip = math.MaxUint64 / 2
+	}
+	r.next.Store(n + uint64(skip) + 1)
+}
+
+// reset empties the reservoir, after the filter has been cleared
+func (r *keyReservoir) reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.keys = r.keys[:0]
+	r.seen.Store(0)
+	r.next.Store(0)
+}
+
+// unitRand returns a uniform random number in (0, 1]
+func unitRand() float64 {
+	return 1 - rand.Float64()
+}
@@ -0,0 +1,108 @@
+package bloomfilter
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// TestKeyReservoirUniform tests that the sample is capped, distinct and uniform
+func TestKeyReservoirUniform(t *testing.T) {
+	bf := NewCacheOptimizedBloomFilter(100000, 0.01)
+	if bf.SampleKeys() != nil {
+		t.Fatal("Expected nil sample while disabled")
+	}
+	bf.EnableKeyReservoir(1000)
+
+	for i := 0; i < 500; i++ {
+		bf.AddString(fmt.Sprintf("k%d", i))
+	}
+	if n := len(bf.SampleKeys()); n != 500 {
+		t.Fatalf("Expected all 500 keys before the reservoir fills, got %d", n)
+	}
+
+	const n = 100000
+	for i := 500; i < n; i++ {
+		bf.AddString(fmt.Sprintf("k%d", i))
+	}
+	sample := bf.SampleKeys()
+	if len(sample) != 1000 {
+		t.Fatalf("Expected 1000 sampled keys, got %d", len(sample))
+	}
+
+	var deciles [10]int
+	seen := make(map[string]bool)
+	for _, k := range sample {
+		if seen[string(k)] {
+			t.Fatalf("Duplicate sampled key %s", k)
+		}
+		seen[string(k)] = true
+		if !bf.Contains(k) {
+			t.Fatalf("Sampled key %s not in filter", k)
+		}
+		i, err := strconv.Atoi(strings.TrimPrefix(string(k), "k"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		deciles[i*10/n]++
+	}
+	// Each decile expects 100 keys with a standard deviation of ~9.5
+	for d, c := range deciles {
+		if c < 55 || c > 145 {
+			t.Errorf("Decile %d has %d sampled keys, expected ~100: %v", d, c, deciles)
+		}
+	}
+
+	// Returned keys are copies
+	sample[0][0] = 'X'
+	if bf.SampleKeys()[0][0] == 'X' {
+		t.Error("SampleKeys must return copies")
+	}
+
+	bf.Clear()
+	if len(bf.SampleKeys()) != 0 {
+		t.Error("Clear must empty the reservoir")
+	}
+	bf.DisableKeyReservoir()
+	if bf.SampleKeys() != nil {
+		t.Error("Expected nil sample after disabling")
+	}
+}
+
+// TestKeyReservoirConcurrent tests the reservoir under concurrent and batch adds
+func TestKeyReservoirConcurrent(t *testing.T) {
+	bf := NewCacheOptimizedBloomFilter(100000, 0.01)
+	bf.EnableKeyReservoir(64)
+
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			keys := make([]string, 5000)
+			for i := range keys {
+				keys[i] = fmt.Sprintf("g%d_%d", g, i)
+			}
+			if g%2 == 0 {
+				bf.AddStrings(keys)
+			} else {
+				for _, k := range keys {
+					bf.AddString(k)
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+
+	sample := bf.SampleKeys()
+	if len(sample) != 64 {
+		t.Fatalf("Expected 64 sampled keys, got %d", len(sample))
+	}
+	for _, k := range sample {
+		if !bf.Contains(k) {
+			t.Errorf("Sampled key %s not in filter", k)
+		}
+	}
+}
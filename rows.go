@@ -0,0 +1,85 @@
+//go:build !bloomfilter_minimal
+
+package bloomfilter
+
+import (
+	"database/sql"
+	"fmt"
+	"unsafe"
+)
+
+// rowsProgressInterval is the number of loaded rows between progress callbacks
+const rowsProgressInterval = 16384
+
+// LoadFromRows streams column col (0-based) of rows into the filter and returns
+// the number of keys added. Each value is added as the bytes database/sql
+// produces for it (see sql.RawBytes), so integer columns are added as their
+// decimal text and should be queried with, for example, ContainsString(strconv.Itoa(id)).
+// NULL values are skipped.
+//
+// Keys are copied into a reusable buffer and added in batches through
+// AddStrings, so loading does not allocate per row. If progress is non-nil it is
+// called every 16384 loaded keys and once at the end. The caller remains
+// responsible for closing rows.
+func (bf *CacheOptimizedBloomFilter) LoadFromRows(rows *sql.Rows, col int, progress func(loaded uint64)) (uint64, error) {
+	columns, err := rows.Columns()
+	if err != nil {
+		return 0, err
+	}
+	if col < 0 || col >= len(columns) {
+		return 0, fmt.Errorf("bloomfilter: column %d out of range, rows have %d columns", col, len(columns))
+	}
+
+	raw := make([]sql.RawBytes, len(columns))
+	dest := make([]any, len(columns))
+	for i := range raw {
+		dest[i] = &raw[i]
+	}
+
+	var (
+		loaded   uint64
+		reported uint64
+		arena    []byte
+		keys     = make([]string, 0, batchKeys)
+	)
+	flush := func() {
+		bf.AddStrings(keys)
+		loaded += uint64(len(keys))
+		if progress != nil && loaded-reported >= rowsProgressInterval {
+			progress(loaded)
+			reported = loaded
+		}
+		keys = keys[:0]
+		arena = arena[:0]
+	}
+
+	for rows.Next() {
+		if err := rows.Scan(dest...); err != nil {
+			return loaded, err
+		}
+		value := raw[col]
+		if value == nil {
+			continue
+		}
+
+		// RawBytes are only valid until the next call to Next, so copy the key.
+		// When the arena grows, earlier keys keep the old backing array alive.
+		start := len(arena)
+		arena = append(arena, value...)
+		keys = append(keys, unsafe.String(unsafe.SliceData(arena[start:]), len(value)))
+		if len(keys) == batchKeys {
+			flush()
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return loaded, err
+	}
+
+	if len(keys) > 0 {
+		flush()
+	}
+	if progress != nil && (loaded != reported || loaded == 0) {
+		progress(loaded)
+	}
+	return loaded, nil
+}
@@ -0,0 +1,103 @@
+//go:build !bloomfilter_minimal
+
+package bloomfilter
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"strconv"
+	"testing"
+)
+
+// fakeRowsDriver serves a fixed two-column (id, name) table for every query.
+// Every 10th name is NULL.
+type fakeRowsDriver struct{ rows int }
+
+func (d fakeRowsDriver) Open(string) (driver.Conn, error) { return fakeRowsConn(d), nil }
+
+type fakeRowsConn fakeRowsDriver
+
+func (c fakeRowsConn) Prepare(string) (driver.Stmt, error) { return fakeRowsStmt(c), nil }
+func (fakeRowsConn) Close() error                          { return nil }
+func (fakeRowsConn) Begin() (driver.Tx, error)             { return nil, driver.ErrSkip }
+
+type fakeRowsStmt fakeRowsDriver
+
+func (fakeRowsStmt) Close() error                                { return nil }
+func (fakeRowsStmt) NumInput() int                               { return 0 }
+func (fakeRowsStmt) Exec([]driver.Value) (driver.Result, error)  { return nil, driver.ErrSkip }
+func (s fakeRowsStmt) Query([]driver.Value) (driver.Rows, error) { return &fakeRows{n: s.rows}, nil }
+
+type fakeRows struct{ i, n int }
+
+func (*fakeRows) Columns() []string { return []string{"id", "name"} }
+func (*fakeRows) Close() error      { return nil }
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if r.i == r.n {
+		return io.EOF
+	}
+	dest[0] = int64(r.i)
+	if r.i%10 == 9 {
+		dest[1] = nil
+	} else {
+		dest[1] = []byte(fmt.Sprintf("name_%d", r.i))
+	}
+	r.i++
+	return nil
+}
+
+func init() {
+	sql.Register("bloomfilter-fake-rows", fakeRowsDriver{rows: 40000})
+}
+
+// TestLoadFromRows tests loading a column from database/sql rows
+func TestLoadFromRows(t *testing.T) {
+	db, err := sql.Open("bloomfilter-fake-rows", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	query := func() *sql.Rows {
+		rows, err := db.Query("SELECT id, name FROM users")
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { rows.Close() })
+		return rows
+	}
+
+	// Integer column, added as decimal text
+	bf := NewCacheOptimizedBloomFilter(40000, 0.01)
+	var calls []uint64
+	n, err := bf.LoadFromRows(query(), 0, func(loaded uint64) { calls = append(calls, loaded) })
+	if err != nil || n != 40000 {
+		t.Fatalf("LoadFromRows = %d, %v", n, err)
+	}
+	for i := 0; i < 40000; i++ {
+		if !bf.ContainsString(strconv.Itoa(i)) {
+			t.Fatalf("id %d missing", i)
+		}
+	}
+	if fmt.Sprint(calls) != "[16384 32768 40000]" {
+		t.Errorf("Unexpected progress calls: %v", calls)
+	}
+
+	// Text column with NULLs skipped
+	names := NewCacheOptimizedBloomFilter(40000, 0.01)
+	n, err = names.LoadFromRows(query(), 1, nil)
+	if err != nil || n != 36000 {
+		t.Fatalf("LoadFromRows = %d, %v, expected 36000 non-NULL names", n, err)
+	}
+	for i := 0; i < 40000; i++ {
+		if i%10 != 9 && !names.ContainsString(fmt.Sprintf("name_%d", i)) {
+			t.Fatalf("name_%d missing", i)
+		}
+	}
+
+	if _, err := bf.LoadFromRows(query(), 2, nil); err == nil {
+		t.Error("Expected error for out-of-range column")
+	}
+}
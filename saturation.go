@@ -0,0 +1,89 @@
+package bloomfilter
+
+import (
+	"fmt"
+	"math"
+	"sync/atomic"
+)
+
+// SaturationEvent describes the filter's state when its load factor reached
+// the threshold passed to OnSaturation
+type SaturationEvent struct {
+	Threshold    float64
+	LoadFactor   float64
+	EstimatedFPP float64
+}
+
+// saturationWatch is the state of OnSaturation
+type saturationWatch struct {
+	threshold float64
+	limitBits uint64
+	fn        func(SaturationEvent)
+	fired     atomic.Bool
+}
+
+// IsSaturated reports whether the fraction of set bits has reached threshold.
+// An optimally sized filter is half full at its design capacity, so a
+// threshold of 0.5 signals that it holds as many keys as it was sized for;
+// past that the false positive rate grows quickly. It is O(1) when the filter
+// tracks its set-bit count (see enableBitTracking) and a PopCount otherwise.
+//
+// Panics if threshold is outside (0, 1] or NaN.
+func (bf *CacheOptimizedBloomFilter) IsSaturated(threshold float64) bool {
+	return bf.trackedLoadFactor() >= checkThreshold(threshold)
+}
+
+// OnSaturation calls fn once when the load factor reaches threshold, so a
+// long-running service can rotate or rebuild the filter before false positives
+// climb. fn runs synchronously in the Add (or Union) that crossed the threshold
+// and must not block; it typically signals a channel or starts the rebuild in
+// another goroutine. After Clear or an Intersection brings the load back below
+// threshold, the next crossing fires again. If the filter is already past
+// threshold, fn is called before OnSaturation returns.
+//
+// OnSaturation enables incremental set-bit tracking. A nil fn removes the
+// callback. Only one callback is kept; a later call replaces it.
+//
+// Panics if threshold is outside (0, 1] or NaN.
+func (bf *CacheOptimizedBloomFilter) OnSaturation(threshold float64, fn func(SaturationEvent)) {
+	checkThreshold(threshold)
+	if fn == nil {
+		bf.saturation.Store(nil)
+		return
+	}
+	limit := uint64(math.Ceil(threshold * float64(bf.bitCount)))
+	bf.enableBitTracking()
+	bf.saturation.Store(&saturationWatch{threshold: threshold, limitBits: max(1, limit), fn: fn})
+	bf.checkSaturation(bf.bitsSet.Load())
+}
+
+// checkThreshold returns threshold, panicking if it is not a valid load factor
+func checkThreshold(threshold float64) float64 {
+	if !(threshold > 0 && threshold <= 1) {
+		panic(fmt.Sprintf("bloomfilter: saturation threshold must be in range (0, 1], got %f", threshold))
+	}
+	return threshold
+}
+
+// checkSaturation fires the saturation callback, if any, when bitsSet has
+// reached its limit, and re-arms it once the load is back below
+func (bf *CacheOptimizedBloomFilter) checkSaturation(bitsSet uint64) {
+	w := bf.saturation.Load()
+	if w == nil {
+		return
+	}
+	if bitsSet < w.limitBits {
+		if w.fired.Load() {
+			w.fired.Store(false)
+		}
+		return
+	}
+	if w.fired.CompareAndSwap(false, true) {
+		load := float64(bitsSet) / float64(bf.bitCount)
+		w.fn(SaturationEvent{
+			Threshold:    w.threshold,
+			LoadFactor:   load,
+			EstimatedFPP: bf.fppAt(bitsSet, bf.hashCount),
+		})
+	}
+}
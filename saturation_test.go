@@ -0,0 +1,109 @@
+package bloomfilter
+
+import (
+	"fmt"
+	"testing"
+)
+
+// TestIsSaturated tests the load factor check against LoadFactor
+func TestIsSaturated(t *testing.T) {
+	bf := NewCacheOptimizedBloomFilter(1000, 0.01)
+	if bf.IsSaturated(0.01) {
+		t.Error("Empty filter reported saturated")
+	}
+	for i := 0; i < 1000; i++ {
+		bf.AddString(fmt.Sprintf("key-%d", i))
+	}
+	load := bf.GetCacheStats().LoadFactor
+	if !bf.IsSaturated(load) || bf.IsSaturated(load+0.01) {
+		t.Errorf("IsSaturated disagrees with load factor %.3f", load)
+	}
+	if !bf.IsSaturated(0.4) {
+		t.Errorf("Filter at design capacity has load %.3f, want about 0.5", load)
+	}
+
+	for _, threshold := range []float64{0, -0.5, 1.1} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("IsSaturated(%g) did not panic", threshold)
+				}
+			}()
+			bf.IsSaturated(threshold)
+		}()
+	}
+}
+
+// TestOnSaturation tests that the callback fires once per crossing
+func TestOnSaturation(t *testing.T) {
+	bf := NewCacheOptimizedBloomFilter(1000, 0.01)
+	var events []SaturationEvent
+	bf.OnSaturation(0.3, func(e SaturationEvent) { events = append(events, e) })
+
+	fill := func() {
+		before := len(events)
+		for i := 0; i < 2000; i++ {
+			bf.AddString(fmt.Sprintf("key-%d", i))
+			if i == 100 && len(events) != before {
+				t.Fatal("Callback fired below the threshold")
+			}
+		}
+	}
+	fill()
+	if len(events) != 1 {
+		t.Fatalf("Callback fired %d times, want 1", len(events))
+	}
+	e := events[0]
+	if e.Threshold != 0.3 || e.LoadFactor < 0.3 || e.LoadFactor > 0.31 {
+		t.Errorf("Event %+v, want load just past 0.3", e)
+	}
+	if e.EstimatedFPP <= 0 || e.EstimatedFPP >= 1 {
+		t.Errorf("Event FPP %g out of range", e.EstimatedFPP)
+	}
+
+	// Clear re-arms the watch
+	bf.Clear()
+	fill()
+	if len(events) != 2 {
+		t.Errorf("Callback fired %d times after Clear, want 2", len(events))
+	}
+
+	// Registering on a saturated filter fires immediately; nil removes it
+	fired := false
+	bf.OnSaturation(0.3, func(SaturationEvent) { fired = true })
+	if !fired {
+		t.Error("Callback on an already saturated filter did not fire")
+	}
+	bf.OnSaturation(0.3, nil)
+	bf.Clear()
+	fill()
+	if len(events) != 2 {
+		t.Error("Callback fired after being removed")
+	}
+}
+
+// TestOnSaturationBatchAndUnion tests that batch adds and merges fire the callback
+func TestOnSaturationBatchAndUnion(t *testing.T) {
+	keys := make([]string, 2000)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("key-%d", i)
+	}
+
+	batch := NewCacheOptimizedBloomFilter(1000, 0.01)
+	fired := 0
+	batch.OnSaturation(0.5, func(SaturationEvent) { fired++ })
+	batch.AddStrings(keys)
+	if fired != 1 {
+		t.Errorf("Batch add fired %d times, want 1", fired)
+	}
+
+	merged, _ := NewWithOptions(WithExpectedElements(1000), WithSeed(batch.Seed()))
+	fired = 0
+	merged.OnSaturation(0.5, func(SaturationEvent) { fired++ })
+	if err := merged.Union(batch); err != nil {
+		t.Fatal(err)
+	}
+	if fired != 1 {
+		t.Errorf("Union fired %d times, want 1", fired)
+	}
+}
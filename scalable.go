@@ -0,0 +1,203 @@
+package bloomfilter
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+)
+
+// Scalable filter growth parameters (Almeida et al., "Scalable Bloom Filters").
+// Layer i holds initialCapacity*2^i elements at falsePositiveRate*(1-r)*r^i, so
+// the compound false positive rate stays below falsePositiveRate however many
+// layers are added.
+const (
+	scalableGrowth     = 2
+	scalableTightening = 0.8
+)
+
+// ScalableBloomFilter grows without knowing the element count in advance: when
+// the newest layer reaches its capacity, a larger layer with a tighter false
+// positive rate is added. Unlike AutoScalingFilter it needs no key log, at the
+// cost of probing every layer on Contains.
+//
+// Add and Contains are safe for concurrent use. Capacity is counted in adds, so
+// duplicates count towards it, and concurrent adds may overfill a layer slightly
+// while the next one is created.
+type ScalableBloomFilter struct {
+	initialCapacity   uint64
+	falsePositiveRate float64
+	seed              uint64
+
+	mu     sync.Mutex // Serializes growth and Merge
+	layers atomic.Pointer[[]*scalableLayer]
+}
+
+// scalableLayer is one fixed-size filter of a ScalableBloomFilter
+type scalableLayer struct {
+	filter   *CacheOptimizedBloomFilter
+	capacity uint64
+	fpr      float64
+	count    atomic.Uint64
+}
+
+// NewScalableBloomFilter creates a scalable filter whose first layer holds
+// initialCapacity elements, with a compound false positive rate below falsePositiveRate.
+// Its layers share a random seed.
+//
+// Panics on the same invalid inputs as NewCacheOptimizedBloomFilter.
+func NewScalableBloomFilter(initialCapacity uint64, falsePositiveRate float64) *ScalableBloomFilter {
+	return NewScalableBloomFilterWithSeed(initialCapacity, falsePositiveRate, RandomSeed())
+}
+
+// NewScalableBloomFilterWithSeed is NewScalableBloomFilter with a fixed hash
+// seed, for scalable filters built independently that will be merged
+func NewScalableBloomFilterWithSeed(initialCapacity uint64, falsePositiveRate float64, seed uint64) *ScalableBloomFilter {
+	validateSizing(initialCapacity, falsePositiveRate)
+	s := &ScalableBloomFilter{initialCapacity: initialCapacity, falsePositiveRate: falsePositiveRate, seed: seed}
+	layers := []*scalableLayer{s.newLayer(initialCapacity, falsePositiveRate*(1-scalableTightening))}
+	s.layers.Store(&layers)
+	return s
+}
+
+func (s *ScalableBloomFilter) newLayer(capacity uint64, fpr float64) *scalableLayer {
+	bf := newFilter(optimalGeometry(capacity, fpr))
+	bf.expectedElements = capacity
+	bf.seed = s.seed
+	return &scalableLayer{
+		filter:   bf,
+		capacity: capacity,
+		fpr:      fpr,
+	}
+}
+
+// Seed returns the hash seed of the layers
+func (s *ScalableBloomFilter) Seed() uint64 {
+	return s.seed
+}
+
+// Add adds an element to the newest layer, growing the filter if it is full
+func (s *ScalableBloomFilter) Add(data []byte) {
+	for {
+		layers := *s.layers.Load()
+		last := layers[len(layers)-1]
+		if last.count.Add(1) <= last.capacity {
+			last.filter.Add(data)
+			return
+		}
+		// Full: undo the reservation so count stays exact, and retry in a new layer
+		last.count.Add(^uint64(0))
+		s.grow(last)
+	}
+}
+
+// grow appends a layer after full, unless another goroutine already has
+func (s *ScalableBloomFilter) grow(full *scalableLayer) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	layers := *s.layers.Load()
+	if layers[len(layers)-1] != full {
+		return
+	}
+	next := append(layers[:len(layers):len(layers)], s.newLayer(full.capacity*scalableGrowth, full.fpr*scalableTightening))
+	s.layers.Store(&next)
+}
+
+// Contains reports whether any layer may contain the element
+func (s *ScalableBloomFilter) Contains(data []byte) bool {
+	layers := *s.layers.Load()
+	// Newest first: it holds the most elements
+	for i := len(layers) - 1; i >= 0; i-- {
+		if layers[i].filter.Contains(data) {
+			return true
+		}
+	}
+	return false
+}
+
+// AddString adds a string element
+func (s *ScalableBloomFilter) AddString(data string) {
+	s.Add([]byte(data))
+}
+
+// ContainsString checks if a string element may be present
+func (s *ScalableBloomFilter) ContainsString(data string) bool {
+	return s.Contains([]byte(data))
+}
+
+// Merge adds the contents of other, so that scalable filters built independently
+// (for example on different workers) can be aggregated. Layers are aligned by
+// position: where both filters have a layer and the two are Compatible, other's
+// layer is unioned into it and their add counts are summed; every other layer of
+// other is appended as a copy. Filters created with the same parameters and
+// seed always align, and the result then answers exactly like a filter that saw both streams,
+// except that merged layers may hold more than their capacity and so exceed their
+// share of the false positive rate. EstimatedFPP reflects this.
+//
+// Merge must not run concurrently with another Merge into other. Merging a filter
+// into itself is a no-op.
+func (s *ScalableBloomFilter) Merge(other *ScalableBloomFilter) error {
+	if other == nil {
+		return fmt.Errorf("bloomfilter: cannot merge a nil scalable filter")
+	}
+	if other == s {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	layers := *s.layers.Load()
+	merged := layers[:len(layers):len(layers)]
+	for i, theirs := range *other.layers.Load() {
+		if i < len(layers) && layers[i].filter.Compatible(theirs.filter) == nil {
+			if err := layers[i].filter.Union(theirs.filter); err != nil {
+				return err
+			}
+			layers[i].count.Add(theirs.count.Load())
+			continue
+		}
+
+		copied := &scalableLayer{
+			filter:   newFilter(theirs.filter.cacheLineCount, theirs.filter.hashCount),
+			capacity: theirs.capacity,
+			fpr:      theirs.fpr,
+		}
+		copied.filter.seed = theirs.filter.seed
+		copied.filter.probe = theirs.filter.probe
+		copied.filter.hashVersion = theirs.filter.hashVersion
+		copied.filter.hasher = theirs.filter.hasher
+		if err := copied.filter.Union(theirs.filter); err != nil {
+			return err
+		}
+		copied.count.Store(theirs.count.Load())
+		merged = append(merged, copied)
+	}
+	s.layers.Store(&merged)
+	return nil
+}
+
+// Layers returns the number of layers
+func (s *ScalableBloomFilter) Layers() int {
+	return len(*s.layers.Load())
+}
+
+// Count returns the number of adds across all layers, including duplicates
+func (s *ScalableBloomFilter) Count() uint64 {
+	var n uint64
+	for _, l := range *s.layers.Load() {
+		n += l.count.Load()
+	}
+	return n
+}
+
+// EstimatedFPP estimates the compound false positive rate from each layer's fill
+func (s *ScalableBloomFilter) EstimatedFPP() float64 {
+	pass := 1.0
+	for _, l := range *s.layers.Load() {
+		pass *= 1 - l.filter.EstimatedFPP()
+	}
+	return 1 - pass
+}
+
+var _ Filter = (*ScalableBloomFilter)(nil)
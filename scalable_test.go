@@ -0,0 +1,143 @@
+package bloomfilter
+
+import (
+	"fmt"
+	"hash/maphash"
+	"sync"
+	"testing"
+)
+
+// TestScalableGrowth tests that the filter grows past its initial capacity while
+// keeping the compound false positive rate near the target
+func TestScalableGrowth(t *testing.T) {
+	s := NewScalableBloomFilter(100, 0.01)
+	for i := 0; i < 20000; i++ {
+		s.AddString(fmt.Sprintf("key_%d", i))
+	}
+	if s.Layers() < 5 || s.Count() != 20000 {
+		t.Fatalf("Expected growth to several layers, got %d layers and count %d", s.Layers(), s.Count())
+	}
+	for i := 0; i < 20000; i++ {
+		if !s.ContainsString(fmt.Sprintf("key_%d", i)) {
+			t.Fatalf("key_%d missing", i)
+		}
+	}
+
+	fp := 0
+	for i := 0; i < 100000; i++ {
+		if s.ContainsString(fmt.Sprintf("absent_%d", i)) {
+			fp++
+		}
+	}
+	if rate := float64(fp) / 100000; rate > 0.015 {
+		t.Errorf("False positive rate %.4f exceeds the 0.01 target", rate)
+	}
+	if est := s.EstimatedFPP(); est > 0.015 {
+		t.Errorf("EstimatedFPP %.4f exceeds the 0.01 target", est)
+	}
+}
+
+// TestScalableMerge tests aggregating filters built independently on workers
+func TestScalableMerge(t *testing.T) {
+	a := NewScalableBloomFilterWithSeed(100, 0.01, 1)
+	b := NewScalableBloomFilterWithSeed(100, 0.01, 1)
+	for i := 0; i < 1000; i++ {
+		a.AddString(fmt.Sprintf("a_%d", i))
+	}
+	for i := 0; i < 5000; i++ {
+		b.AddString(fmt.Sprintf("b_%d", i))
+	}
+	layersB := b.Layers()
+
+	if err := a.Merge(b); err != nil {
+		t.Fatal(err)
+	}
+	if a.Layers() != layersB || a.Count() != 6000 {
+		t.Errorf("Expected aligned layers (%d) and count 6000, got %d layers and count %d", layersB, a.Layers(), a.Count())
+	}
+	for i := 0; i < 5000; i++ {
+		if (i < 1000 && !a.ContainsString(fmt.Sprintf("a_%d", i))) || !a.ContainsString(fmt.Sprintf("b_%d", i)) {
+			t.Fatalf("Key %d missing after merge", i)
+		}
+	}
+
+	// Merged filters keep growing
+	for i := 0; i < 10000; i++ {
+		a.AddString(fmt.Sprintf("c_%d", i))
+	}
+	if !a.ContainsString("c_9999") || a.Count() != 16000 {
+		t.Errorf("Adds after merge: count %d", a.Count())
+	}
+
+	// Filters with different parameters or seeds share no layers; other's are
+	// appended as copies
+	if NewScalableBloomFilter(100, 0.01).Seed() == NewScalableBloomFilter(100, 0.01).Seed() {
+		t.Error("Scalable filters share a seed by default")
+	}
+	c := NewScalableBloomFilter(300, 0.001)
+	c.AddString("c_only")
+	before := a.Layers()
+	if err := a.Merge(c); err != nil {
+		t.Fatal(err)
+	}
+	if a.Layers() != before+1 || !a.ContainsString("c_only") {
+		t.Errorf("Expected one appended layer, got %d -> %d", before, a.Layers())
+	}
+	c.AddString("added_later")
+	if a.ContainsString("added_later") {
+		t.Error("Appended layer is shared with the source filter")
+	}
+
+	if err := a.Merge(nil); err == nil {
+		t.Error("Expected error merging nil")
+	}
+	if err := a.Merge(a); err != nil {
+		t.Error(err)
+	}
+}
+
+// TestScalableMergeCustomHasher tests that layers with custom hashers align
+// whatever their seeds, as Compatible allows
+func TestScalableMergeCustomHasher(t *testing.T) {
+	h := MapHash(maphash.MakeSeed())
+	a, b := NewScalableBloomFilter(100, 0.01), NewScalableBloomFilter(100, 0.01)
+	for _, s := range []*ScalableBloomFilter{a, b} {
+		(*s.layers.Load())[0].filter.SetHasher(h)
+	}
+	a.AddString("a")
+	b.AddString("b")
+	if err := a.Merge(b); err != nil {
+		t.Fatal(err)
+	}
+	if a.Layers() != 1 || a.Count() != 2 || !a.ContainsString("a") || !a.ContainsString("b") {
+		t.Errorf("Expected one unioned layer, got %d layers and count %d", a.Layers(), a.Count())
+	}
+}
+
+// TestScalableConcurrentAdds tests that concurrent adds across growth are neither
+// lost nor double counted
+func TestScalableConcurrentAdds(t *testing.T) {
+	s := NewScalableBloomFilter(64, 0.01)
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 2000; i++ {
+				s.AddString(fmt.Sprintf("g%d_%d", g, i))
+			}
+		}(g)
+	}
+	wg.Wait()
+
+	if s.Count() != 16000 {
+		t.Errorf("Expected count 16000, got %d", s.Count())
+	}
+	for g := 0; g < 8; g++ {
+		for i := 0; i < 2000; i++ {
+			if !s.ContainsString(fmt.Sprintf("g%d_%d", g, i)) {
+				t.Fatalf("g%d_%d missing", g, i)
+			}
+		}
+	}
+}
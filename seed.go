@@ -0,0 +1,30 @@
+package bloomfilter
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+)
+
+// RandomSeed returns a nonzero hash seed from crypto/rand. The constructors
+// seed every filter with it unless given WithSeed; Reseeded and
+// NewStorageFilterWithSeed take one explicitly.
+//
+// Seeding makes the bit positions of a key depend on a secret, so an attacker
+// who knows the hash functions can no longer compute keys that collide in a
+// given filter to inflate its false positive rate. The hash functions are not
+// keyed cryptographic hashes, so this raises the cost of such an attack rather
+// than ruling it out; keep the seed private and do not expose filters that
+// answer arbitrary queries to untrusted parties if that matters.
+//
+// Filters built independently only combine, compare and share precomputed
+// keys and bare bit arrays when their seeds agree: create them WithSeed, or
+// derive them from one filter with Clone or the encodings, which keep the seed.
+func RandomSeed() uint64 {
+	var b [8]byte
+	for {
+		rand.Read(b[:])
+		if seed := binary.LittleEndian.Uint64(b[:]); seed != 0 {
+			return seed
+		}
+	}
+}
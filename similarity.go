@@ -0,0 +1,119 @@
+package bloomfilter
+
+import (
+	"math"
+	"unsafe"
+)
+
+// Set estimates for two Compatible filters. The item counts of the union and
+// the intersection are estimated from bit counts alone, without building
+// either filter: the union's bits follow from the bits both filters set,
+// counted in one SIMD pass, by inclusion-exclusion, and the intersection is
+// the sum of the two counts minus the union's. The bits of the intersection
+// itself are not used, since bits set by different keys in each filter
+// overstate it.
+//
+// The estimates are only as good as EstimatedCount's: they assume uniformly
+// spread probes, and their error grows as the filters fill up. Like Equal,
+// they are not atomic with respect to concurrent adds.
+
+// setEstimate holds the estimated item counts of two filters and their union
+type setEstimate struct {
+	a, b, union float64
+}
+
+// intersection is the estimated number of items in both filters, clamped to
+// the range a valid intersection can take
+func (e setEstimate) intersection() float64 {
+	return max(0, min(e.a+e.b-e.union, e.a, e.b))
+}
+
+func (bf *CacheOptimizedBloomFilter) estimateSets(other *CacheOptimizedBloomFilter) (setEstimate, error) {
+	if err := bf.Compatible(other); err != nil {
+		return setEstimate{}, err
+	}
+	if bf.cacheLineCount == 0 {
+		return setEstimate{}, nil
+	}
+
+	a, b := bf.PopCount(), other.PopCount()
+	both := uint64(bf.simdOps.PopCountAnd(
+		unsafe.Pointer(&bf.cacheLines[0]),
+		unsafe.Pointer(&other.cacheLines[0]),
+		int(bf.cacheLineCount*CacheLineSize),
+	))
+	// Concurrent adds between the passes can leave both above a or b
+	both = min(both, a, b)
+	return setEstimate{
+		a:     bf.itemsForBits(a),
+		b:     bf.itemsForBits(b),
+		union: bf.itemsForBits(a + b - both),
+	}, nil
+}
+
+// EstimateIntersectionCount estimates the number of distinct items added to
+// both this filter and other.
+// Returns an error if the filters are not Compatible.
+func (bf *CacheOptimizedBloomFilter) EstimateIntersectionCount(other *CacheOptimizedBloomFilter) (uint64, error) {
+	e, err := bf.estimateSets(other)
+	if err != nil {
+		return 0, err
+	}
+	return uint64(math.Round(e.intersection())), nil
+}
+
+// EstimateUnionCount estimates the number of distinct items added to either
+// this filter or other.
+// Returns an error if the filters are not Compatible.
+func (bf *CacheOptimizedBloomFilter) EstimateUnionCount(other *CacheOptimizedBloomFilter) (uint64, error) {
+	e, err := bf.estimateSets(other)
+	if err != nil {
+		return 0, err
+	}
+	return uint64(math.Round(e.union)), nil
+}
+
+// EstimateJaccard estimates the Jaccard similarity |A ∩ B| / |A ∪ B| of the
+// items added to this filter and other, 0 if both are empty.
+// Returns an error if the filters are not Compatible.
+func (bf *CacheOptimizedBloomFilter) EstimateJaccard(other *CacheOptimizedBloomFilter) (float64, error) {
+	e, err := bf.estimateSets(other)
+	if err != nil {
+		return 0, err
+	}
+	if e.union < 0.5 {
+		return 0, nil
+	}
+	return min(1, e.intersection()/e.union), nil
+}
+
+// IntersectionPopCount returns the number of bits set in both this filter and
+// other, the PopCount of their Intersection, in one SIMD pass without building
+// it. Inputs are left untouched.
+// Returns an error if the filters are not Compatible.
+func (bf *CacheOptimizedBloomFilter) IntersectionPopCount(other *CacheOptimizedBloomFilter) (uint64, error) {
+	return bf.fusedPopCount(other, bf.simdOps.PopCountAnd)
+}
+
+// UnionPopCount returns the number of bits set in this filter or other, the
+// PopCount of their Union, in one SIMD pass without building it. Inputs are
+// left untouched.
+// Returns an error if the filters are not Compatible.
+func (bf *CacheOptimizedBloomFilter) UnionPopCount(other *CacheOptimizedBloomFilter) (uint64, error) {
+	return bf.fusedPopCount(other, bf.simdOps.PopCountOr)
+}
+
+func (bf *CacheOptimizedBloomFilter) fusedPopCount(other *CacheOptimizedBloomFilter,
+	kernel func(a, b unsafe.Pointer, length int) int) (uint64, error) {
+	if err := bf.Compatible(other); err != nil {
+		return 0, err
+	}
+	if bf.cacheLineCount == 0 {
+		return 0, nil
+	}
+	return uint64(kernel(
+		unsafe.Pointer(&bf.cacheLines[0]),
+		unsafe.Pointer(&other.cacheLines[0]),
+		int(bf.cacheLineCount*CacheLineSize),
+	)), nil
+}
@@ -0,0 +1,125 @@
+package bloomfilter
+
+import (
+	"errors"
+	"math"
+	"math/rand/v2"
+	"testing"
+)
+
+// TestSetEstimates tests intersection, union and Jaccard estimates for
+// overlapping, identical and disjoint key sets
+func TestSetEstimates(t *testing.T) {
+	rng := rand.New(rand.NewPCG(3, 4))
+	keys := make([]uint64, 100000)
+	for i := range keys {
+		keys[i] = rng.Uint64()
+	}
+
+	filterOf := func(keys []uint64) *CacheOptimizedBloomFilter {
+		bf, _ := NewWithOptions(WithExpectedElements(100000), WithSeed(1))
+		bf.AddUint64Batch(keys)
+		return bf
+	}
+	a := filterOf(keys[:60000])
+	b := filterOf(keys[40000:])
+	disjoint := filterOf(keys[:40000])
+	other := filterOf(keys[60000:])
+
+	tests := []struct {
+		name         string
+		x, y         *CacheOptimizedBloomFilter
+		inter, union float64
+	}{
+		{"Overlapping", a, b, 20000, 100000},
+		{"Identical", a, a, 60000, 60000},
+		{"Disjoint", disjoint, other, 0, 80000},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inter, err := tt.x.EstimateIntersectionCount(tt.y)
+			if err != nil {
+				t.Fatal(err)
+			}
+			union, _ := tt.x.EstimateUnionCount(tt.y)
+			jaccard, _ := tt.x.EstimateJaccard(tt.y)
+
+			// Errors are relative to the union, which bounds the noise of every estimate
+			if d := math.Abs(float64(inter) - tt.inter); d > 0.01*tt.union {
+				t.Errorf("Intersection %d, want %.0f", inter, tt.inter)
+			}
+			if d := math.Abs(float64(union) - tt.union); d > 0.01*tt.union {
+				t.Errorf("Union %d, want %.0f", union, tt.union)
+			}
+			if d := math.Abs(jaccard - tt.inter/tt.union); d > 0.01 {
+				t.Errorf("Jaccard %.4f, want %.4f", jaccard, tt.inter/tt.union)
+			}
+			if j, _ := tt.y.EstimateJaccard(tt.x); j != jaccard {
+				t.Errorf("Jaccard not symmetric: %.4f and %.4f", jaccard, j)
+			}
+		})
+	}
+
+	// The inputs are left untouched
+	if !a.Equal(filterOf(keys[:60000])) {
+		t.Error("Estimates modified the filter")
+	}
+}
+
+// TestSetEstimatesEdgeCases tests empty and incompatible filters
+func TestSetEstimatesEdgeCases(t *testing.T) {
+	empty := NewCacheOptimizedBloomFilter(1000, 0.01)
+	if j, err := empty.EstimateJaccard(empty.Clone()); err != nil || j != 0 {
+		t.Errorf("Empty filters: Jaccard %f, %v", j, err)
+	}
+
+	if _, err := empty.EstimateJaccard(NewCacheOptimizedBloomFilter(100000, 0.01)); !errors.Is(err, ErrIncompatible) {
+		t.Errorf("Expected ErrIncompatible for different sizes, got %v", err)
+	}
+	seeded, _ := NewWithOptions(WithExpectedElements(1000), WithSeed(7))
+	if _, err := empty.EstimateIntersectionCount(seeded); !errors.Is(err, ErrSeedMismatch) {
+		t.Errorf("Expected ErrSeedMismatch for different seeds, got %v", err)
+	}
+}
+
+// TestFusedPopCounts tests that IntersectionPopCount and UnionPopCount match the
+// PopCount of the materialized Intersection and Union, with and without SIMD
+func TestFusedPopCounts(t *testing.T) {
+	for _, simdDisabled := range []bool{false, true} {
+		opts := []Option{WithExpectedElements(20000), WithSeed(3)}
+		if simdDisabled {
+			opts = append(opts, WithSIMDDisabled())
+		}
+		a, _ := NewWithOptions(opts...)
+		b, _ := NewWithOptions(opts...)
+		for i := 0; i < 10000; i++ {
+			a.AddUint64(uint64(i))
+			b.AddUint64(uint64(i + 5000))
+		}
+
+		and, err := a.IntersectionPopCount(b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		or, err := a.UnionPopCount(b)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		inter, union := a.Clone(), a.Clone()
+		inter.Intersection(b)
+		union.Union(b)
+		if and != inter.PopCount() || or != union.PopCount() {
+			t.Errorf("SIMD disabled %t: fused counts %d and %d, materialized %d and %d",
+				simdDisabled, and, or, inter.PopCount(), union.PopCount())
+		}
+		if and+or != a.PopCount()+b.PopCount() {
+			t.Errorf("SIMD disabled %t: counts break inclusion-exclusion", simdDisabled)
+		}
+	}
+
+	seeded, _ := NewWithOptions(WithExpectedElements(20000), WithSeed(4))
+	if _, err := NewCacheOptimizedBloomFilter(20000, 0.01).UnionPopCount(seeded); !errors.Is(err, ErrSeedMismatch) {
+		t.Errorf("Expected ErrSeedMismatch for different seeds, got %v", err)
+	}
+}
@@ -0,0 +1,71 @@
+package bloomfilter
+
+import (
+	"math/bits"
+	"sync/atomic"
+)
+
+// SkewReport describes how evenly set bits are spread over cache lines. With
+// well-distributed hashing the set bits of a line follow a binomial
+// distribution; a few hot lines amid empty ones (skewed or adversarial keys)
+// show up as a dispersion far above 1.
+type SkewReport struct {
+	Lines    uint64
+	MeanBits float64 // Mean set bits per cache line
+	MaxBits  int     // Set bits in the fullest cache line
+	// Dispersion is the observed variance of set bits per line divided by the
+	// binomial variance expected at the same load. It is about 1 (or less) for
+	// uniform hashing and 0 when it cannot be measured (one line, or an empty or
+	// full filter).
+	Dispersion float64
+}
+
+// LineHistogram returns the distribution of set bits per cache line: entry i is
+// the number of cache lines with exactly i bits set, for i in [0, BitsPerCacheLine].
+func (bf *CacheOptimizedBloomFilter) LineHistogram() []uint64 {
+	hist := make([]uint64, BitsPerCacheLine+1)
+	for i := range bf.cacheLines {
+		hist[bf.lineBits(i)]++
+	}
+	return hist
+}
+
+// Skew measures how evenly set bits are spread over cache lines
+func (bf *CacheOptimizedBloomFilter) Skew() SkewReport {
+	return skewOf(bf.LineHistogram(), bf.cacheLineCount)
+}
+
+// lineBits returns the number of set bits in cache line i
+func (bf *CacheOptimizedBloomFilter) lineBits(i int) int {
+	n := 0
+	line := &bf.cacheLines[i]
+	for j := range line.words {
+		n += bits.OnesCount64(atomic.LoadUint64(&line.words[j]))
+	}
+	return n
+}
+
+// Seed returns the hash seed. The constructors draw a random seed unless
+// given WithSeed, and Reseeded sets a new one. Only filters with equal seeds can be combined with
+// Union or Intersection. The binary format records the seed; the bare bit
+// array layouts (Words, Java longs) do not, so filters restored from them
+// need NewFromWordsWithSeed or NewFromJavaLongsWithSeed.
+func (bf *CacheOptimizedBloomFilter) Seed() uint64 {
+	return bf.seed
+}
+
+// Reseeded returns a new filter with the same geometry, probe scheme, hash
+// version and hasher and the given hash seed, populated by re-adding every key
+// from keys. Re-seeding moves every key to new bit positions, which rebalances
+// a filter whose keys crowd into a few cache lines (see Skew). A custom hasher
+// does not see the seed, so re-seeding does not move its keys. Other settings
+// such as load shedding and sampling are not carried over.
+func (bf *CacheOptimizedBloomFilter) Reseeded(seed uint64, keys KeySource) *CacheOptimizedBloomFilter {
+	next := newFilter(bf.cacheLineCount, bf.hashCount)
+	next.seed = seed
+	next.probe = bf.probe
+	next.hashVersion = bf.hashVersion
+	next.hasher = bf.hasher
+	next.addSeq(keys.Keys())
+	return next
+}
@@ -0,0 +1,118 @@
+package bloomfilter
+
+import (
+	"fmt"
+	"iter"
+	"slices"
+	"testing"
+
+	"github.com/shaia/BloomFilter/internal/hash"
+)
+
+// crowdedKeys returns n keys whose probes all fall into the first few cache lines
+// of a filter of bitCount bits with the given seed, like keys crafted by an
+// attacker who learned the seed
+func crowdedKeys(n int, bitCount, seed uint64) [][]byte {
+	var keys [][]byte
+	for i := 0; len(keys) < n; i++ {
+		key := []byte(fmt.Sprintf("adv_%d", i))
+		h1, h2 := hash.Seeded128(key, seed)
+		if h1%bitCount < 4*BitsPerCacheLine && h2%bitCount < BitsPerCacheLine/2 {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// TestSkewReport tests skew measurement for uniform and crowded keys
+func TestSkewReport(t *testing.T) {
+	uniform := NewCacheOptimizedBloomFilter(3400, 0.01)
+	for i := 0; i < 3400; i++ {
+		uniform.AddUint64(uint64(i))
+	}
+	report := uniform.Skew()
+	if report.Dispersion <= 0 || report.Dispersion > 2 {
+		t.Errorf("Uniform keys should have dispersion ~1, got %+v", report)
+	}
+
+	var total uint64
+	for bitsSet, lines := range uniform.LineHistogram() {
+		total += uint64(bitsSet) * lines
+	}
+	if total != uniform.PopCount() {
+		t.Errorf("Histogram accounts for %d bits, PopCount is %d", total, uniform.PopCount())
+	}
+
+	crowded := NewCacheOptimizedBloomFilter(3400, 0.01)
+	keys := crowdedKeys(300, crowded.bitCount, crowded.Seed())
+	for _, k := range keys {
+		crowded.Add(k)
+	}
+	report = crowded.Skew()
+	if report.Dispersion < 20 {
+		t.Errorf("Crowded keys should be highly skewed, got %+v", report)
+	}
+
+	rebalanced := crowded.Reseeded(12345, sliceKeySource(keys))
+	if rebalanced.Seed() != 12345 || rebalanced.bitCount != crowded.bitCount {
+		t.Fatalf("Unexpected reseeded geometry")
+	}
+	if d := rebalanced.Skew().Dispersion; d > 2 {
+		t.Errorf("Reseeding should rebalance, dispersion %.2f", d)
+	}
+	for _, k := range keys {
+		if !rebalanced.Contains(k) {
+			t.Fatalf("%s missing after reseed", k)
+		}
+	}
+	if err := crowded.Union(rebalanced); err == nil {
+		t.Error("Union across seeds must fail")
+	}
+
+	if (&CacheOptimizedBloomFilter{}).Seed() != 0 || NewCacheOptimizedBloomFilter(10, 0.01).Skew().Dispersion != 0 {
+		t.Error("Expected seed 0 and no measurable dispersion for an empty filter")
+	}
+}
+
+// TestAutoScalingReseed tests that skew detection reseeds without losing keys
+func TestAutoScalingReseed(t *testing.T) {
+	var events []RebuildEvent
+	a, err := NewAutoScalingFilter(AutoScalingConfig{
+		ExpectedElements:  3400,
+		FalsePositiveRate: 0.01,
+		Keys:              NewMemoryKeyLog(),
+		SkewCheckInterval: 50,
+		OnRebuild:         func(e RebuildEvent) { events = append(events, e) },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keys := crowdedKeys(300, a.Filter().bitCount, a.Filter().Seed())
+	for _, k := range keys {
+		a.Add(k)
+		a.Wait()
+	}
+
+	stats := a.Stats()
+	if stats.Reseeds == 0 || len(events) == 0 || events[0].Reason != RebuildReseed {
+		t.Fatalf("Expected a reseed, got %+v events=%+v", stats, events)
+	}
+	if e := events[0]; e.NewCapacity != e.OldCapacity || e.Seed == 0 || e.Skew.Dispersion < 4 {
+		t.Errorf("Unexpected reseed event %+v", e)
+	}
+	if a.Filter().Skew().Dispersion > 2 {
+		t.Errorf("Filter still skewed after reseed: %+v", a.Filter().Skew())
+	}
+	for _, k := range keys {
+		if !a.Contains(k) {
+			t.Fatalf("%s missing after reseed", k)
+		}
+	}
+}
+
+type sliceKeySource [][]byte
+
+func (s sliceKeySource) Keys() iter.Seq[[]byte] {
+	return slices.Values(s)
+}
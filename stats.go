@@ -0,0 +1,163 @@
+package bloomfilter
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unsafe"
+)
+
+// Params is the static configuration of a filter, fixed at construction
+type Params struct {
+	BitCount       uint64
+	HashCount      uint32
+	CacheLineCount uint64
+	MemoryUsage    uint64
+	Seed           uint64
+	ProbeScheme    ProbeScheme
+	HashVersion    HashVersion
+	ReadOnly       bool
+	// Keys the filter was sized for, 0 when built from a geometry
+	ExpectedElements uint64
+}
+
+// Params returns the filter's configuration without reading its bits, for
+// monitoring loops that only need the geometry
+func (bf *CacheOptimizedBloomFilter) Params() Params {
+	return Params{
+		BitCount:         bf.bitCount,
+		HashCount:        bf.hashCount,
+		CacheLineCount:   bf.cacheLineCount,
+		MemoryUsage:      bf.cacheLineCount * CacheLineSize,
+		Seed:             bf.seed,
+		ProbeScheme:      bf.probe,
+		HashVersion:      bf.hashVersion,
+		ReadOnly:         bf.readOnly,
+		ExpectedElements: bf.expectedElements,
+	}
+}
+
+// StatsOption configures Stats
+type StatsOption func(*statsOptions)
+
+type statsOptions struct {
+	skipBits bool
+}
+
+// SkipBitStats leaves the statistics derived from the set-bit count (BitsSet,
+// LoadFactor, EstimatedFPP, EstimatedCount, Work) zero rather than pay for a
+// PopCount. With fill tracking on (see EnableFillTracking) they cost nothing
+// and are filled in anyway.
+func SkipBitStats() StatsOption {
+	return func(o *statsOptions) {
+		o.skipBits = true
+	}
+}
+
+// statsChunkLines is how many cache lines Stats counts between checks of its
+// context, 1 MiB
+const statsChunkLines = 1 << 14
+
+// Stats returns the filter's statistics like GetCacheStats, for callers that
+// poll them. The PopCount over a large filter checks ctx every MiB and returns
+// ctx's error once it is done; SkipBitStats avoids the PopCount altogether.
+func (bf *CacheOptimizedBloomFilter) Stats(ctx context.Context, opts ...StatsOption) (CacheStats, error) {
+	var o statsOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if err := ctx.Err(); err != nil {
+		return CacheStats{}, err
+	}
+
+	switch {
+	case bf.trackBits.Load():
+		return bf.statsFor(bf.BitsSet(), true), nil
+	case o.skipBits:
+		return bf.statsFor(0, false), nil
+	}
+	bitsSet, err := bf.popCountContext(ctx)
+	if err != nil {
+		return CacheStats{}, err
+	}
+	return bf.statsFor(bitsSet, true), nil
+}
+
+// popCountContext is PopCount in chunks of statsChunkLines, stopping early if
+// ctx is done
+func (bf *CacheOptimizedBloomFilter) popCountContext(ctx context.Context) (uint64, error) {
+	var count uint64
+	for start := uint64(0); start < bf.cacheLineCount; start += statsChunkLines {
+		if start > 0 {
+			if err := ctx.Err(); err != nil {
+				return 0, err
+			}
+		}
+		n := min(statsChunkLines, bf.cacheLineCount-start)
+		count += uint64(bf.simdOps.PopCount(unsafe.Pointer(&bf.cacheLines[start]), int(n*CacheLineSize)))
+	}
+	return count, nil
+}
+
+// String summarizes the statistics on one line for logs: size, hash count,
+// load, estimated FPP and the SIMD kernels in use. Report has the details.
+func (s CacheStats) String() string {
+	load := "load=skipped"
+	if !s.BitStatsSkipped {
+		load = fmt.Sprintf("load=%.4f fpp=%.3g", s.LoadFactor, s.EstimatedFPP)
+	}
+	return fmt.Sprintf("bits=%d hashes=%d memory=%s %s simd=%s", s.BitCount, s.HashCount, byteSize(s.MemoryUsage), load, s.simdName())
+}
+
+// simdName names the widest SIMD kernels in use, or "off"
+func (s CacheStats) simdName() string {
+	switch {
+	case !s.SIMDEnabled:
+		return "off"
+	case s.HasAVX512:
+		return "avx512"
+	case s.HasAVX2:
+		return "avx2"
+	case s.HasNEON:
+		return "neon"
+	}
+	return "scalar"
+}
+
+// byteSize formats a byte count with a binary unit
+func byteSize(n uint64) string {
+	const units = "KMGTPE"
+	if n < 1024 {
+		return fmt.Sprintf("%dB", n)
+	}
+	v, i := float64(n)/1024, 0
+	for ; v >= 1024 && i < len(units)-1; i++ {
+		v /= 1024
+	}
+	return fmt.Sprintf("%.1f%ciB", v, units[i])
+}
+
+// Report formats all the statistics for debug dumps, one group per line
+func (s CacheStats) Report() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "bits=%d hashes=%d lines=%d memory=%dB", s.BitCount, s.HashCount, s.CacheLineCount, s.MemoryUsage)
+	if s.ExpectedElements > 0 {
+		fmt.Fprintf(&b, " expected=%d achieved_fpr=%.3g", s.ExpectedElements, s.AchievedFPR)
+	}
+	b.WriteString("\n")
+	if s.BitStatsSkipped {
+		b.WriteString("load: skipped\n")
+	} else {
+		fmt.Fprintf(&b, "load: set=%d factor=%.4f fpp=%.3g items≈%d\n", s.BitsSet, s.LoadFactor, s.EstimatedFPP, s.EstimatedCount)
+	}
+	fmt.Fprintf(&b, "adds: new=%d duplicates=%d dropped=%d\n", s.NewItems, s.ProbableDuplicates, s.DroppedAdds)
+	if ops := s.Operations; ops.Enabled {
+		fmt.Fprintf(&b, "ops: adds=%d lookups=%d positives=%d cas_retries=%d\n", ops.Adds, ops.Lookups, ops.Positives, ops.CASRetries)
+	}
+	fmt.Fprintf(&b, "probes: contains=%d/%d", s.ContainsProbes, s.HashCount)
+	if s.MeasuredFPR.Enabled {
+		fmt.Fprintf(&b, " measured_fpr=%.3g (%d/%d)", s.MeasuredFPR.MeasuredFPP, s.MeasuredFPR.FalsePositives, s.MeasuredFPR.Negatives)
+	}
+	fmt.Fprintf(&b, "\nsimd: enabled=%t avx2=%t avx512=%t neon=%t\n", s.SIMDEnabled, s.HasAVX2, s.HasAVX512, s.HasNEON)
+	return b.String()
+}
@@ -0,0 +1,116 @@
+package bloomfilter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// TestParams tests that Params reports the construction settings
+func TestParams(t *testing.T) {
+	bf, _ := NewWithOptions(WithExpectedElements(1000), WithSeed(7), WithProbeScheme(DoubleHashing), WithHashVersion(HashV1))
+	p := bf.Params()
+	want := Params{
+		BitCount:         bf.BitCount(),
+		HashCount:        bf.HashCount(),
+		CacheLineCount:   bf.cacheLineCount,
+		MemoryUsage:      bf.cacheLineCount * CacheLineSize,
+		Seed:             7,
+		ProbeScheme:      DoubleHashing,
+		HashVersion:      HashV1,
+		ExpectedElements: 1000,
+	}
+	if p != want {
+		t.Errorf("Params() = %+v, want %+v", p, want)
+	}
+}
+
+// TestStats tests Stats against GetCacheStats, with and without the bit statistics
+func TestStats(t *testing.T) {
+	// Several chunks, so the counting loop is exercised past its first check
+	bf := NewCacheOptimizedBloomFilter(3_000_000, 0.001)
+	if bf.cacheLineCount <= statsChunkLines {
+		t.Fatalf("Filter of %d lines fits in one chunk", bf.cacheLineCount)
+	}
+	for i := 0; i < 100_000; i++ {
+		bf.AddString(fmt.Sprintf("key-%d", i))
+	}
+
+	got, err := bf.Stats(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := bf.GetCacheStats(); got != want {
+		t.Errorf("Stats() = %+v, want %+v", got, want)
+	}
+
+	skipped, err := bf.Stats(context.Background(), SkipBitStats())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !skipped.BitStatsSkipped || skipped.BitsSet != 0 || skipped.BitCount != got.BitCount {
+		t.Errorf("SkipBitStats: %+v", skipped)
+	}
+
+	// Tracked counts are free, so skipping keeps them
+	bf.EnableFillTracking()
+	tracked, _ := bf.Stats(context.Background(), SkipBitStats())
+	if tracked.BitStatsSkipped || tracked.BitsSet != got.BitsSet {
+		t.Errorf("Tracked SkipBitStats: skipped=%t bits=%d, want %d", tracked.BitStatsSkipped, tracked.BitsSet, got.BitsSet)
+	}
+}
+
+// TestStatsCanceled tests that a done context stops the count
+func TestStatsCanceled(t *testing.T) {
+	bf := NewCacheOptimizedBloomFilter(3_000_000, 0.001)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := bf.Stats(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("Stats with canceled context: got %v", err)
+	}
+	if _, err := bf.popCountContext(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("Count with canceled context: got %v", err)
+	}
+}
+
+// TestCacheStatsReport tests the human-readable dump
+func TestCacheStatsReport(t *testing.T) {
+	bf := NewCacheOptimizedBloomFilter(1000, 0.01)
+	bf.AddString("a")
+	s := bf.GetCacheStats().Report()
+	for _, want := range []string{"bits=", fmt.Sprintf("hashes=%d", bf.HashCount()), "set=", "simd:"} {
+		if !strings.Contains(s, want) {
+			t.Errorf("Report() missing %q:\n%s", want, s)
+		}
+	}
+	skipped, _ := bf.Stats(context.Background(), SkipBitStats())
+	if !strings.Contains(skipped.Report(), "load: skipped") {
+		t.Errorf("Report() of skipped stats:\n%s", skipped.Report())
+	}
+}
+
+// TestCacheStatsString tests the one-line summary
+func TestCacheStatsString(t *testing.T) {
+	bf := NewCacheOptimizedBloomFilter(1000, 0.01)
+	bf.AddString("a")
+	s := bf.GetCacheStats().String()
+	for _, want := range []string{"bits=", fmt.Sprintf("hashes=%d", bf.HashCount()), "memory=1.2KiB", "load=0.00", "fpp=", "simd="} {
+		if !strings.Contains(s, want) {
+			t.Errorf("String() missing %q: %s", want, s)
+		}
+	}
+	if strings.Contains(s, "\n") {
+		t.Errorf("String() spans lines: %q", s)
+	}
+	skipped, _ := bf.Stats(context.Background(), SkipBitStats())
+	if !strings.Contains(skipped.String(), "load=skipped") {
+		t.Errorf("String() of skipped stats: %s", skipped)
+	}
+	for n, want := range map[uint64]string{512: "512B", 1536: "1.5KiB", 3 << 30: "3.0GiB"} {
+		if got := byteSize(n); got != want {
+			t.Errorf("byteSize(%d) = %s, want %s", n, got, want)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package bloomfilter
+
+// StatsAggregate combines the CacheStats of the filters behind one logical
+// filter (shards, layers, generations) into totals and worst-case figures, so
+// that dashboards can monitor a composite filter without knowing its layout
+type StatsAggregate struct {
+	// Number of filters aggregated
+	Filters int
+
+	// Totals
+	BitCount           uint64
+	BitsSet            uint64
+	CacheLineCount     uint64
+	MemoryUsage        uint64
+	DroppedAdds        uint64
+	NewItems           uint64
+	ProbableDuplicates uint64
+	// EstimatedCount sums the per-filter estimates, which counts a key added to
+	// several filters once per filter
+	EstimatedCount uint64
+
+	// LoadFactor is BitsSet / BitCount over all filters; MaxLoadFactor is the
+	// fullest filter's
+	LoadFactor    float64
+	MaxLoadFactor float64
+
+	// CombinedFPP is the false positive rate of a lookup that probes every filter
+	// (layers, generations); MaxEstimatedFPP is the worst single filter's, which
+	// bounds a lookup that probes one of them (shards)
+	CombinedFPP     float64
+	MaxEstimatedFPP float64
+
+	// MaxHashCount is the largest k, which bounds the probes per operation
+	MaxHashCount uint32
+}
+
+// AggregateStats combines the statistics of several filters
+func AggregateStats(stats ...CacheStats) StatsAggregate {
+	var a StatsAggregate
+	for _, s := range stats {
+		a.Add(s)
+	}
+	return a
+}
+
+// Add includes one more filter's statistics in the aggregate
+func (a *StatsAggregate) Add(s CacheStats) {
+	a.Filters++
+	a.BitCount += s.BitCount
+	a.BitsSet += s.BitsSet
+	a.CacheLineCount += s.CacheLineCount
+	a.MemoryUsage += s.MemoryUsage
+	a.DroppedAdds += s.DroppedAdds
+	a.NewItems += s.NewItems
+	a.ProbableDuplicates += s.ProbableDuplicates
+	a.EstimatedCount += s.EstimatedCount
+
+	if a.BitCount > 0 {
+		a.LoadFactor = float64(a.BitsSet) / float64(a.BitCount)
+	}
+	a.MaxLoadFactor = max(a.MaxLoadFactor, s.LoadFactor)
+	// Every filter must miss for a combined lookup to miss
+	a.CombinedFPP = 1 - (1-a.CombinedFPP)*(1-s.EstimatedFPP)
+	a.MaxEstimatedFPP = max(a.MaxEstimatedFPP, s.EstimatedFPP)
+	a.MaxHashCount = max(a.MaxHashCount, s.HashCount)
+}
+
+// Stats aggregates the statistics of every layer
+func (s *ScalableBloomFilter) Stats() StatsAggregate {
+	var a StatsAggregate
+	for _, l := range *s.layers.Load() {
+		a.Add(l.filter.GetCacheStats())
+	}
+	return a
+}
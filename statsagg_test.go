@@ -0,0 +1,53 @@
+package bloomfilter
+
+import (
+	"fmt"
+	"math"
+	"testing"
+)
+
+// TestAggregateStats tests totals and worst-case figures over filters of different sizes
+func TestAggregateStats(t *testing.T) {
+	small := NewCacheOptimizedBloomFilter(100, 0.01)
+	large := NewCacheOptimizedBloomFilter(10000, 0.01)
+	for i := 0; i < 1000; i++ {
+		small.AddUint64(uint64(i)) // 10x over capacity
+		large.AddUint64(uint64(i))
+	}
+	s1, s2 := small.GetCacheStats(), large.GetCacheStats()
+
+	a := AggregateStats(s1, s2)
+	if a.Filters != 2 || a.BitCount != s1.BitCount+s2.BitCount || a.BitsSet != s1.BitsSet+s2.BitsSet ||
+		a.MemoryUsage != s1.MemoryUsage+s2.MemoryUsage || a.EstimatedCount != s1.EstimatedCount+s2.EstimatedCount {
+		t.Errorf("Wrong totals: %+v", a)
+	}
+	if a.MaxLoadFactor != s1.LoadFactor || a.MaxEstimatedFPP != s1.EstimatedFPP {
+		t.Errorf("Worst case should be the overfilled filter: %+v", a)
+	}
+	if want := float64(a.BitsSet) / float64(a.BitCount); a.LoadFactor != want {
+		t.Errorf("LoadFactor %f, want %f", a.LoadFactor, want)
+	}
+	want := 1 - (1-s1.EstimatedFPP)*(1-s2.EstimatedFPP)
+	if math.Abs(a.CombinedFPP-want) > 1e-12 || a.CombinedFPP < a.MaxEstimatedFPP {
+		t.Errorf("CombinedFPP %f, want %f", a.CombinedFPP, want)
+	}
+
+	if empty := AggregateStats(); empty.Filters != 0 || empty.LoadFactor != 0 {
+		t.Errorf("Empty aggregate: %+v", empty)
+	}
+}
+
+// TestScalableStats tests that a scalable filter reports its layers as one aggregate
+func TestScalableStats(t *testing.T) {
+	s := NewScalableBloomFilter(100, 0.01)
+	for i := 0; i < 1000; i++ {
+		s.AddString(fmt.Sprint(i))
+	}
+	a := s.Stats()
+	if a.Filters != s.Layers() || a.BitsSet == 0 {
+		t.Errorf("Expected %d layers, got %+v", s.Layers(), a)
+	}
+	if math.Abs(a.CombinedFPP-s.EstimatedFPP()) > 1e-12 {
+		t.Errorf("CombinedFPP %f differs from EstimatedFPP %f", a.CombinedFPP, s.EstimatedFPP())
+	}
+}
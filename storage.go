@@ -0,0 +1,163 @@
+package bloomfilter
+
+import "fmt"
+
+// Storage is a bit array that can live outside process memory, such as a file,
+// a memory mapping or a remote service, and whose operations can therefore fail.
+// Operations receive all probe positions of a key at once so backends can batch
+// their IO. Bit i is bit i%64 of word i/64, as in Words.
+type Storage interface {
+	// BitCount returns the size of the bit array, a multiple of BitsPerCacheLine
+	BitCount() uint64
+	// SetBits sets every bit in positions
+	SetBits(positions []uint64) error
+	// TestBits reports whether every bit in positions is set
+	TestBits(positions []uint64) (bool, error)
+}
+
+// FilterE is implemented by filters whose operations can fail
+type FilterE interface {
+	AddE(data []byte) error
+	ContainsE(data []byte) (bool, error)
+}
+
+// StorageFilter is a bloom filter over a Storage backend. Because the backend can
+// fail, it exposes error-checked AddE and ContainsE instead of the infallible
+// Add and Contains of the in-memory CacheOptimizedBloomFilter, so IO failures
+// surface instead of silently dropping bits. Keys hash to the same positions as
+// in a CacheOptimizedBloomFilter of the same size, hash count, seed, probe
+// scheme and hash version, so a storage loaded from Words answers identically.
+type StorageFilter struct {
+	storage   Storage
+	bitCount  uint64
+	hashCount uint32
+	seed      uint64
+	probe     ProbeScheme
+	version   HashVersion
+}
+
+// NewStorageFilter creates a filter over storage using hashCount hash functions.
+// Returns an error if hashCount is 0 or the storage size is not a positive
+// multiple of BitsPerCacheLine.
+func NewStorageFilter(storage Storage, hashCount uint32) (*StorageFilter, error) {
+	if hashCount == 0 {
+		return nil, fmt.Errorf("bloomfilter: hashCount must be greater than 0")
+	}
+	bitCount := storage.BitCount()
+	if bitCount == 0 || bitCount%BitsPerCacheLine != 0 {
+		return nil, fmt.Errorf("bloomfilter: storage bit count must be a positive multiple of %d, got %d", BitsPerCacheLine, bitCount)
+	}
+	return &StorageFilter{storage: storage, bitCount: bitCount, hashCount: hashCount, probe: DefaultProbeScheme, version: DefaultHashVersion}, nil
+}
+
+// NewStorageFilterWithSeed is NewStorageFilter with seeded hash functions,
+// matching a CacheOptimizedBloomFilter with the same seed
+func NewStorageFilterWithSeed(storage Storage, hashCount uint32, seed uint64) (*StorageFilter, error) {
+	f, err := NewStorageFilter(storage, hashCount)
+	if err != nil {
+		return nil, err
+	}
+	f.seed = seed
+	return f, nil
+}
+
+// AddE adds an element, returning the storage error if its bits could not be set.
+// After an error the element may be partially added and must be retried.
+func (f *StorageFilter) AddE(data []byte) error {
+	var stackBuf [16]uint64
+	positions := f.positions(data, stackBuf[:0])
+	if err := f.storage.SetBits(positions); err != nil {
+		return fmt.Errorf("bloomfilter: add: %w", err)
+	}
+	return nil
+}
+
+// ContainsE checks membership, returning the storage error if the bits could not be read
+func (f *StorageFilter) ContainsE(data []byte) (bool, error) {
+	var stackBuf [16]uint64
+	positions := f.positions(data, stackBuf[:0])
+	present, err := f.storage.TestBits(positions)
+	if err != nil {
+		return false, fmt.Errorf("bloomfilter: contains: %w", err)
+	}
+	return present, nil
+}
+
+// AddStringE adds a string element
+func (f *StorageFilter) AddStringE(s string) error {
+	return f.AddE(stringBytes(s))
+}
+
+// ContainsStringE checks membership of a string element
+func (f *StorageFilter) ContainsStringE(s string) (bool, error) {
+	return f.ContainsE(stringBytes(s))
+}
+
+// positions computes the probe positions of data, using buf when it is large enough
+func (f *StorageFilter) positions(data []byte, buf []uint64) []uint64 {
+	var positions []uint64
+	if int(f.hashCount) <= cap(buf) {
+		positions = buf[:f.hashCount]
+	} else {
+		positions = make([]uint64, f.hashCount)
+	}
+	h1, h2 := f.version.hash(data, f.seed)
+	hashPositions(h1, h2, f.bitCount, f.probe, positions)
+	return positions
+}
+
+// Storage returns the backend
+func (f *StorageFilter) Storage() Storage {
+	return f.storage
+}
+
+// BitCount returns the number of bits in the filter
+func (f *StorageFilter) BitCount() uint64 {
+	return f.bitCount
+}
+
+// HashCount returns the number of hash functions
+func (f *StorageFilter) HashCount() uint32 {
+	return f.hashCount
+}
+
+// Seed returns the hash seed
+func (f *StorageFilter) Seed() uint64 {
+	return f.seed
+}
+
+// ProbeScheme returns the scheme mapping keys to bit positions
+func (f *StorageFilter) ProbeScheme() ProbeScheme {
+	return f.probe
+}
+
+// SetProbeScheme sets the scheme mapping keys to bit positions, which must match
+// the one the storage's bits were written with (DefaultProbeScheme unless set).
+// It must not be called concurrently with other methods.
+// Returns an error if s is not a defined scheme.
+func (f *StorageFilter) SetProbeScheme(s ProbeScheme) error {
+	if !s.valid() {
+		return fmt.Errorf("bloomfilter: unknown probe scheme %d", uint8(s))
+	}
+	f.probe = s
+	return nil
+}
+
+// HashVersion returns the built-in hash keys are hashed with
+func (f *StorageFilter) HashVersion() HashVersion {
+	return f.version
+}
+
+// SetHashVersion sets the built-in hash, which must match the one the storage's
+// bits were written with (DefaultHashVersion unless set). It must not be called
+// concurrently with other methods.
+// Returns an error if v is not a defined version.
+func (f *StorageFilter) SetHashVersion(v HashVersion) error {
+	if !v.valid() {
+		return fmt.Errorf("bloomfilter: unknown hash version %d", uint8(v))
+	}
+	f.version = v
+	return nil
+}
+
+var _ FilterE = (*StorageFilter)(nil)
@@ -0,0 +1,110 @@
+package bloomfilter
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestStorageFilterFile tests the error-checked API over a file backend
+func TestStorageFilterFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "filter.bits")
+	storage, err := OpenFileStorage(path, 64*BitsPerCacheLine)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := NewStorageFilter(storage, 7)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 200; i++ {
+		if err := f.AddStringE(fmt.Sprintf("key_%d", i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := storage.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Reopen: membership persists
+	storage, err = OpenFileStorage(path, 64*BitsPerCacheLine)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, _ = NewStorageFilter(storage, 7)
+	if _, err := OpenFileStorage(path, 128*BitsPerCacheLine); !errors.Is(err, ErrSizeMismatch) {
+		t.Errorf("Reopening at another size: expected ErrSizeMismatch, got %v", err)
+	}
+	for i := 0; i < 200; i++ {
+		ok, err := f.ContainsStringE(fmt.Sprintf("key_%d", i))
+		if err != nil || !ok {
+			t.Fatalf("key_%d: present=%v err=%v", i, ok, err)
+		}
+	}
+
+	// IO failures surface instead of silently dropping bits
+	storage.Close()
+	if err := f.AddE([]byte("x")); !errors.Is(err, os.ErrClosed) {
+		t.Errorf("AddE on a closed file: %v", err)
+	}
+	if _, err := f.ContainsE([]byte("x")); !errors.Is(err, os.ErrClosed) {
+		t.Errorf("ContainsE on a closed file: %v", err)
+	}
+
+	if _, err := OpenFileStorage(path, 128*BitsPerCacheLine); err == nil {
+		t.Error("Expected error for a size mismatch")
+	}
+	if _, err := OpenFileStorage(path, 100); err == nil {
+		t.Error("Expected error for a partial cache line")
+	}
+	if _, err := NewStorageFilter(storage, 0); err == nil {
+		t.Error("Expected error for hashCount 0")
+	}
+}
+
+// TestStorageFilterMatchesMemory tests that a storage loaded from Words answers
+// like the in-memory filter it came from
+func TestStorageFilterMatchesMemory(t *testing.T) {
+	for _, seed := range []uint64{0, RandomSeed()} {
+		t.Run(fmt.Sprintf("Seed_%#x", seed), func(t *testing.T) {
+			testStorageFilterMatchesMemory(t, seed)
+		})
+	}
+}
+
+func testStorageFilterMatchesMemory(t *testing.T, seed uint64) {
+	bf, _ := NewWithOptions(WithExpectedElements(1000), WithSeed(seed))
+	for i := 0; i < 1000; i++ {
+		bf.AddUint64(uint64(i))
+	}
+
+	path := filepath.Join(t.TempDir(), "filter.bits")
+	var data []byte
+	for _, w := range bf.Words() {
+		data = binary.LittleEndian.AppendUint64(data, w)
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	storage, err := OpenFileStorage(path, bf.BitCount())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer storage.Close()
+	f, _ := NewStorageFilterWithSeed(storage, bf.HashCount(), seed)
+
+	for i := 0; i < 5000; i++ {
+		key := []byte(fmt.Sprintf("probe_%d", i))
+		got, err := f.ContainsE(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != bf.Contains(key) {
+			t.Fatalf("Storage and memory filters disagree on %s", key)
+		}
+	}
+}
@@ -0,0 +1,11 @@
+//go:build !purego
+
+package bloomfilter
+
+import "unsafe"
+
+// stringBytes returns the bytes of s without copying. The result must not be
+// modified. Builds with the purego tag copy instead.
+func stringBytes(s string) []byte {
+	return unsafe.Slice(unsafe.StringData(s), len(s))
+}
@@ -0,0 +1,9 @@
+//go:build purego
+
+package bloomfilter
+
+// stringBytes returns a copy of the bytes of s: builds with the purego tag do
+// not alias the string's memory through unsafe
+func stringBytes(s string) []byte {
+	return []byte(s)
+}
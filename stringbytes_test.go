@@ -0,0 +1,42 @@
+package bloomfilter
+
+import (
+	"bytes"
+	"testing"
+)
+
+// TestStringBytes tests that string keys hash like their bytes
+func TestStringBytes(t *testing.T) {
+	for _, s := range []string{"", "a", "hello, world", string(make([]byte, 1000))} {
+		if b := stringBytes(s); !bytes.Equal(b, []byte(s)) || len(b) != len(s) {
+			t.Errorf("stringBytes(%q) = %q", s, b)
+		}
+	}
+
+	bf := NewCacheOptimizedBloomFilter(1000, 0.01)
+	bf.AddString("string key")
+	if !bf.Contains([]byte("string key")) || !bf.ContainsString("string key") {
+		t.Error("String key not found")
+	}
+	bf.Add([]byte("byte key"))
+	if !bf.ContainsString("byte key") {
+		t.Error("Byte key not found as a string")
+	}
+}
+
+var stringBytesSink []byte
+
+// stringsCopy reports whether stringBytes copies in this build (the purego tag)
+func stringsCopy() bool {
+	key := "a key longer than the compiler's small buffer"
+	return testing.AllocsPerRun(10, func() { stringBytesSink = stringBytes(key) }) > 0
+}
+
+// skipIfStringsCopy skips allocation tests of string keys in builds where
+// stringBytes copies
+func skipIfStringsCopy(t *testing.T) {
+	t.Helper()
+	if stringsCopy() {
+		t.Skip("string keys are copied in this build")
+	}
+}
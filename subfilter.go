@@ -0,0 +1,84 @@
+package bloomfilter
+
+import "fmt"
+
+// SubFilterView describes a contiguous range of cache lines inside a larger
+// filter allocation, used as an independent filter (for example one per tenant)
+type SubFilterView struct {
+	FirstLine uint64 // Index of the first cache line of the view
+	LineCount uint64 // Number of cache lines in the view
+	HashCount uint32 // Hash functions used by the view, 0 inherits the parent's
+}
+
+// SubFilterViewFor returns a view starting at firstLine sized for expectedElements
+// at falsePositiveRate, as NewCacheOptimizedBloomFilter would size a filter.
+//
+// Panics on the same invalid inputs as NewCacheOptimizedBloomFilter.
+func SubFilterViewFor(firstLine, expectedElements uint64, falsePositiveRate float64) SubFilterView {
+	validateSizing(expectedElements, falsePositiveRate)
+	lines, hashCount := optimalGeometry(expectedElements, falsePositiveRate)
+	return SubFilterView{FirstLine: firstLine, LineCount: lines, HashCount: hashCount}
+}
+
+// SubFilter returns a filter backed by the cache lines of view. The sub-filter has
+// its own bit count and hash count, so keys hash only into its range, and its
+// Add, Contains, Clear, PopCount and GetCacheStats are all scoped to the view.
+//
+// The memory is shared: adds to the sub-filter are visible through bf, and
+// operations on bf (Clear, Union, Intersection) change the sub-filter's bits
+// without updating its load tracking or FPR sampling. Overlapping views share bits.
+func (bf *CacheOptimizedBloomFilter) SubFilter(view SubFilterView) (*CacheOptimizedBloomFilter, error) {
+	if view.LineCount == 0 {
+		return nil, fmt.Errorf("bloomfilter: sub-filter view must contain at least one cache line")
+	}
+	if view.FirstLine >= bf.cacheLineCount || view.LineCount > bf.cacheLineCount-view.FirstLine {
+		return nil, fmt.Errorf("bloomfilter: sub-filter view [%d, %d) exceeds %d cache lines",
+			view.FirstLine, view.FirstLine+view.LineCount, bf.cacheLineCount)
+	}
+	hashCount := view.HashCount
+	if hashCount == 0 {
+		hashCount = bf.hashCount
+	}
+
+	end := view.FirstLine + view.LineCount
+	sub := newFilterOver(bf.cacheLines[view.FirstLine:end:end], hashCount)
+	sub.seed = bf.seed
+	sub.probe = bf.probe
+	sub.hashVersion = bf.hashVersion
+	sub.hasher = bf.hasher
+	sub.readOnly = bf.readOnly
+	return sub, nil
+}
+
+// NewSubFilters allocates a single aligned filter holding one sub-filter per entry
+// of expectedElements, each sized for falsePositiveRate and packed back to back.
+// This replaces thousands of small allocations (and their per-filter overhead)
+// with one, for example for per-tenant filters. The parent filter covers the
+// whole allocation and can be used to snapshot or serialize every tenant at once.
+//
+// Panics if expectedElements is empty or on the same invalid inputs as
+// NewCacheOptimizedBloomFilter.
+func NewSubFilters(expectedElements []uint64, falsePositiveRate float64) (*CacheOptimizedBloomFilter, []*CacheOptimizedBloomFilter) {
+	if len(expectedElements) == 0 {
+		panic("bloomfilter: NewSubFilters requires at least one sub-filter")
+	}
+
+	views := make([]SubFilterView, len(expectedElements))
+	var totalLines uint64
+	var maxHashCount uint32
+	for i, n := range expectedElements {
+		views[i] = SubFilterViewFor(totalLines, n, falsePositiveRate)
+		totalLines += views[i].LineCount
+		maxHashCount = max(maxHashCount, views[i].HashCount)
+	}
+
+	parent := newFilter(totalLines, maxHashCount)
+	parent.seed = RandomSeed()
+	subs := make([]*CacheOptimizedBloomFilter, len(views))
+	for i, view := range views {
+		end := view.FirstLine + view.LineCount
+		subs[i] = newFilterOver(parent.cacheLines[view.FirstLine:end:end], view.HashCount)
+		subs[i].seed = parent.seed
+	}
+	return parent, subs
+}
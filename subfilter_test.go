@@ -0,0 +1,84 @@
+package bloomfilter
+
+import (
+	"fmt"
+	"testing"
+)
+
+// TestSubFilterIsolation tests that views are independent filters over shared memory
+func TestSubFilterIsolation(t *testing.T) {
+	parent, tenants := NewSubFilters([]uint64{100, 1000, 100}, 0.01)
+
+	var total uint64
+	for _, tenant := range tenants {
+		total += tenant.cacheLineCount
+	}
+	if parent.cacheLineCount != total {
+		t.Fatalf("Parent has %d cache lines, expected %d", parent.cacheLineCount, total)
+	}
+
+	for i := 0; i < 100; i++ {
+		tenants[0].AddString(fmt.Sprintf("a_%d", i))
+	}
+	for i := 0; i < 100; i++ {
+		if !tenants[0].ContainsString(fmt.Sprintf("a_%d", i)) {
+			t.Fatalf("a_%d missing from tenant 0", i)
+		}
+	}
+	if tenants[1].PopCount() != 0 || tenants[2].PopCount() != 0 {
+		t.Errorf("Adds to tenant 0 leaked into other tenants")
+	}
+	if parent.PopCount() != tenants[0].PopCount() {
+		t.Errorf("Parent should see tenant bits: parent=%d tenant=%d", parent.PopCount(), tenants[0].PopCount())
+	}
+
+	stats := tenants[0].GetCacheStats()
+	if stats.BitCount != tenants[0].cacheLineCount*BitsPerCacheLine || stats.Alignment != 0 {
+		t.Errorf("Unexpected per-view stats: %+v", stats)
+	}
+
+	tenants[2].AddString("x")
+	tenants[0].Clear()
+	if tenants[0].PopCount() != 0 || !tenants[2].ContainsString("x") {
+		t.Errorf("Clear must only affect its own view")
+	}
+}
+
+// TestSubFilterView tests view validation and hash count inheritance
+func TestSubFilterView(t *testing.T) {
+	parent := NewCacheOptimizedBloomFilter(10000, 0.01)
+
+	sub, err := parent.SubFilter(SubFilterView{FirstLine: 2, LineCount: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sub.hashCount != parent.hashCount || sub.bitCount != 3*BitsPerCacheLine {
+		t.Errorf("Unexpected sub-filter geometry: k=%d m=%d", sub.hashCount, sub.bitCount)
+	}
+	sub.AddString("hello")
+	for i := uint64(0); i < parent.cacheLineCount; i++ {
+		if (i < 2 || i >= 5) && parent.cacheLines[i] != (CacheLine{}) {
+			t.Errorf("Cache line %d outside the view was modified", i)
+		}
+	}
+
+	view := SubFilterViewFor(parent.cacheLineCount-1, 10, 0.01)
+	if view.LineCount != 1 || view.HashCount == 0 {
+		t.Errorf("Unexpected view for 10 elements: %+v", view)
+	}
+	if _, err := parent.SubFilter(view); err != nil {
+		t.Errorf("Last cache line should be a valid view: %v", err)
+	}
+
+	bad := []SubFilterView{
+		{FirstLine: 0, LineCount: 0},
+		{FirstLine: parent.cacheLineCount, LineCount: 1},
+		{FirstLine: 1, LineCount: parent.cacheLineCount},
+		{FirstLine: 1, LineCount: ^uint64(0)},
+	}
+	for _, v := range bad {
+		if _, err := parent.SubFilter(v); err == nil {
+			t.Errorf("Expected error for view %+v", v)
+		}
+	}
+}
@@ -0,0 +1,163 @@
+package bloomfilter
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+// AtomicFilter holds the current filter of a service that rebuilds its filter
+// periodically, so readers switch to each new build without locks and never see
+// a half-built one. Build the new filter aside, then publish it with Store or
+// Swap; callers of Load, Contains and Add see either the old filter or the new
+// one.
+//
+// Lookups and adds through the AtomicFilter (and View) pin the filter they use
+// until they return. SwapAndDrain waits for the pins on the old filter to be
+// released, after which it can be reused (Release to a FilterPool, Reset as the
+// next build) without a reader still looking at it. A pin is two atomic adds
+// on a counter shared by all readers; services that leave replaced filters to
+// the garbage collector can use Load instead, which pins nothing.
+type AtomicFilter struct {
+	cur atomic.Pointer[swapSlot]
+}
+
+// swapSlot is one published filter with its pinned readers
+type swapSlot struct {
+	bf      *CacheOptimizedBloomFilter
+	readers atomic.Int64
+	retired atomic.Bool
+	closed  atomic.Bool
+	drained chan struct{}
+}
+
+func newSwapSlot(bf *CacheOptimizedBloomFilter) *swapSlot {
+	if bf == nil {
+		panic("bloomfilter: AtomicFilter cannot hold a nil filter")
+	}
+	return &swapSlot{bf: bf, drained: make(chan struct{})}
+}
+
+// NewAtomicFilter creates an AtomicFilter holding bf
+func NewAtomicFilter(bf *CacheOptimizedBloomFilter) *AtomicFilter {
+	a := &AtomicFilter{}
+	a.cur.Store(newSwapSlot(bf))
+	return a
+}
+
+// Load returns the current filter without pinning it
+func (a *AtomicFilter) Load() *CacheOptimizedBloomFilter {
+	return a.cur.Load().bf
+}
+
+// Store publishes bf as the current filter
+func (a *AtomicFilter) Store(bf *CacheOptimizedBloomFilter) {
+	a.Swap(bf)
+}
+
+// Swap publishes bf as the current filter and returns the one it replaces,
+// which pinned readers may still be using (see SwapAndDrain)
+func (a *AtomicFilter) Swap(bf *CacheOptimizedBloomFilter) *CacheOptimizedBloomFilter {
+	return a.swap(bf).bf
+}
+
+// SwapAndDrain publishes bf, then waits until no lookup, add or View still
+// uses the filter it replaced and returns that filter. If ctx is done first, it
+// returns the old filter with ctx's error; bf is published either way, but the
+// old filter may still be in use.
+func (a *AtomicFilter) SwapAndDrain(ctx context.Context, bf *CacheOptimizedBloomFilter) (*CacheOptimizedBloomFilter, error) {
+	old := a.swap(bf)
+	select {
+	case <-old.drained:
+		return old.bf, nil
+	case <-ctx.Done():
+		return old.bf, ctx.Err()
+	}
+}
+
+// swap publishes a slot for bf and retires the previous one
+func (a *AtomicFilter) swap(bf *CacheOptimizedBloomFilter) *swapSlot {
+	old := a.cur.Swap(newSwapSlot(bf))
+	old.retired.Store(true)
+	if old.readers.Load() == 0 {
+		old.drain()
+	}
+	return old
+}
+
+// drain signals that a retired slot has no readers left
+func (s *swapSlot) drain() {
+	if s.closed.CompareAndSwap(false, true) {
+		close(s.drained)
+	}
+}
+
+// pin returns the current slot with a reader registered on it. A reader that
+// registers on a slot just retired backs off and takes the new one, so a
+// drained slot is never used again.
+func (a *AtomicFilter) pin() *swapSlot {
+	for {
+		s := a.cur.Load()
+		s.readers.Add(1)
+		if a.cur.Load() == s {
+			return s
+		}
+		s.unpin()
+	}
+}
+
+// unpin releases a reader registered by pin
+func (s *swapSlot) unpin() {
+	if s.readers.Add(-1) == 0 && s.retired.Load() {
+		s.drain()
+	}
+}
+
+// View calls fn with the current filter, pinned until fn returns, for several
+// operations that must see the same filter
+func (a *AtomicFilter) View(fn func(bf *CacheOptimizedBloomFilter)) {
+	s := a.pin()
+	defer s.unpin()
+	fn(s.bf)
+}
+
+// Add adds data to the current filter
+func (a *AtomicFilter) Add(data []byte) {
+	s := a.pin()
+	defer s.unpin()
+	s.bf.Add(data)
+}
+
+// AddString adds key to the current filter
+func (a *AtomicFilter) AddString(key string) {
+	s := a.pin()
+	defer s.unpin()
+	s.bf.AddString(key)
+}
+
+// AddUint64 adds n to the current filter
+func (a *AtomicFilter) AddUint64(n uint64) {
+	s := a.pin()
+	defer s.unpin()
+	s.bf.AddUint64(n)
+}
+
+// Contains checks data in the current filter
+func (a *AtomicFilter) Contains(data []byte) bool {
+	s := a.pin()
+	defer s.unpin()
+	return s.bf.Contains(data)
+}
+
+// ContainsString checks key in the current filter
+func (a *AtomicFilter) ContainsString(key string) bool {
+	s := a.pin()
+	defer s.unpin()
+	return s.bf.ContainsString(key)
+}
+
+// ContainsUint64 checks n in the current filter
+func (a *AtomicFilter) ContainsUint64(n uint64) bool {
+	s := a.pin()
+	defer s.unpin()
+	return s.bf.ContainsUint64(n)
+}
@@ -0,0 +1,114 @@
+package bloomfilter
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestAtomicFilterSwap tests that readers move to the new filter
+func TestAtomicFilterSwap(t *testing.T) {
+	first := NewCacheOptimizedBloomFilter(1000, 0.01)
+	first.AddString("a")
+	a := NewAtomicFilter(first)
+	if a.Load() != first || !a.ContainsString("a") {
+		t.Fatal("AtomicFilter does not hold the first filter")
+	}
+
+	second := NewCacheOptimizedBloomFilter(1000, 0.01)
+	second.AddString("b")
+	if old := a.Swap(second); old != first {
+		t.Errorf("Swap returned %p, want %p", old, first)
+	}
+	if a.ContainsString("a") || !a.ContainsString("b") {
+		t.Error("Lookups still see the first filter")
+	}
+	a.AddUint64(7)
+	if !second.ContainsUint64(7) || first.ContainsUint64(7) {
+		t.Error("Add did not go to the current filter")
+	}
+
+	a.Store(first)
+	if a.Load() != first {
+		t.Error("Store did not publish the filter")
+	}
+}
+
+// TestAtomicFilterSwapAndDrain tests that SwapAndDrain waits for pinned readers
+func TestAtomicFilterSwapAndDrain(t *testing.T) {
+	first := NewCacheOptimizedBloomFilter(1000, 0.01)
+	a := NewAtomicFilter(first)
+
+	inView, leave := make(chan struct{}), make(chan struct{})
+	go a.View(func(bf *CacheOptimizedBloomFilter) {
+		close(inView)
+		<-leave
+	})
+	<-inView
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	second := NewCacheOptimizedBloomFilter(1000, 0.01)
+	old, err := a.SwapAndDrain(ctx, second)
+	if !errors.Is(err, context.DeadlineExceeded) || old != first {
+		t.Fatalf("SwapAndDrain with a pinned reader: %p, %v", old, err)
+	}
+
+	// Drained once the view returns
+	done := make(chan struct{})
+	go func() {
+		old, err = a.SwapAndDrain(context.Background(), NewCacheOptimizedBloomFilter(1000, 0.01))
+		close(done)
+	}()
+	close(leave)
+	<-done
+	if err != nil || old != second {
+		t.Errorf("SwapAndDrain = %p, %v, want %p", old, err, second)
+	}
+}
+
+// TestAtomicFilterConcurrent swaps under concurrent lookups and adds, reusing
+// each drained filter as the next build
+func TestAtomicFilterConcurrent(t *testing.T) {
+	a := NewAtomicFilter(NewCacheOptimizedBloomFilter(10_000, 0.01))
+	spare := NewCacheOptimizedBloomFilter(10_000, 0.01)
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	for w := 0; w < 4; w++ {
+		wg.Add(1)
+		go func(w uint64) {
+			defer wg.Done()
+			for i := uint64(0); ; i++ {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				a.AddUint64(w<<32 | i)
+				a.ContainsUint64(i)
+			}
+		}(uint64(w))
+	}
+	for i := 0; i < 100; i++ {
+		spare.Reset()
+		old, err := a.SwapAndDrain(context.Background(), spare)
+		if err != nil {
+			t.Fatal(err)
+		}
+		spare = old
+	}
+	close(stop)
+	wg.Wait()
+}
+
+// TestAtomicFilterNil tests that a nil filter is rejected
+func TestAtomicFilterNil(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("NewAtomicFilter(nil) did not panic")
+		}
+	}()
+	NewAtomicFilter(nil)
+}
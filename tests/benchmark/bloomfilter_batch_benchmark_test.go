@@ -0,0 +1,500 @@
+package bloomfilter_test
+
+import (
+	"fmt"
+	"testing"
+
+	bloomfilter "github.com/shaia/BloomFilter"
+)
+
+// BenchmarkStringBatch compares the batch string API against per-key calls.
+// The gap grows with filter size, once probes miss the CPU caches.
+// Usage: go test -bench=BenchmarkStringBatch ./tests/benchmark
+func BenchmarkStringBatch(b *testing.B) {
+	keys := make([]string, 1<<16)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("batch_key_%d", i)
+	}
+
+	for _, size := range []uint64{10_000, 1_000_000, 100_000_000} {
+		bf := bloomfilter.NewCacheOptimizedBloomFilter(size, 0.01)
+
+		b.Run(fmt.Sprintf("Size_%d/AddString", size), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				for _, k := range keys {
+					bf.AddString(k)
+				}
+			}
+			b.ReportMetric(float64(b.N*len(keys))/b.Elapsed().Seconds(), "keys/sec")
+		})
+
+		b.Run(fmt.Sprintf("Size_%d/AddStrings", size), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				bf.AddStrings(keys)
+			}
+			b.ReportMetric(float64(b.N*len(keys))/b.Elapsed().Seconds(), "keys/sec")
+		})
+
+		b.Run(fmt.Sprintf("Size_%d/ContainsString", size), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				for _, k := range keys {
+					bf.ContainsString(k)
+				}
+			}
+			b.ReportMetric(float64(b.N*len(keys))/b.Elapsed().Seconds(), "keys/sec")
+		})
+
+		b.Run(fmt.Sprintf("Size_%d/ContainsStrings", size), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				bf.ContainsStrings(keys)
+			}
+			b.ReportMetric(float64(b.N*len(keys))/b.Elapsed().Seconds(), "keys/sec")
+		})
+	}
+}
+
+// BenchmarkByteBatch compares the batch byte slice API against per-key calls
+// Usage: go test -bench=BenchmarkByteBatch ./tests/benchmark
+func BenchmarkByteBatch(b *testing.B) {
+	keys := make([][]byte, 1<<16)
+	for i := range keys {
+		keys[i] = []byte(fmt.Sprintf("batch_key_%d", i))
+	}
+
+	for _, size := range []uint64{10_000, 1_000_000, 100_000_000} {
+		bf := bloomfilter.NewCacheOptimizedBloomFilter(size, 0.01)
+
+		b.Run(fmt.Sprintf("Size_%d/Add", size), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				for _, k := range keys {
+					bf.Add(k)
+				}
+			}
+			b.ReportMetric(float64(b.N*len(keys))/b.Elapsed().Seconds(), "keys/sec")
+		})
+
+		b.Run(fmt.Sprintf("Size_%d/AddBatch", size), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				bf.AddBatch(keys)
+			}
+			b.ReportMetric(float64(b.N*len(keys))/b.Elapsed().Seconds(), "keys/sec")
+		})
+
+		b.Run(fmt.Sprintf("Size_%d/Contains", size), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				for _, k := range keys {
+					bf.Contains(k)
+				}
+			}
+			b.ReportMetric(float64(b.N*len(keys))/b.Elapsed().Seconds(), "keys/sec")
+		})
+
+		b.Run(fmt.Sprintf("Size_%d/ContainsBatch", size), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				bf.ContainsBatch(keys)
+			}
+			b.ReportMetric(float64(b.N*len(keys))/b.Elapsed().Seconds(), "keys/sec")
+		})
+	}
+}
+
+// BenchmarkFixed16 compares the 16-byte key API against the slice API
+// Usage: go test -bench=BenchmarkFixed16 ./tests/benchmark
+func BenchmarkFixed16(b *testing.B) {
+	bf := bloomfilter.NewCacheOptimizedBloomFilter(1_000_000, 0.01)
+	keys := make([][16]byte, 1024)
+	for i := range keys {
+		copy(keys[i][:], fmt.Sprintf("uuid-%011d", i))
+	}
+
+	b.Run("Add", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			bf.Add(keys[i&1023][:])
+		}
+	})
+	b.Run("Add16", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			bf.Add16(keys[i&1023])
+		}
+	})
+	b.Run("Contains", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			bf.Contains(keys[i&1023][:])
+		}
+	})
+	b.Run("Contains16", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			bf.Contains16(keys[i&1023])
+		}
+	})
+}
+
+// BenchmarkUint64Batch compares the batch uint64 API against per-key calls,
+// with and without the vectorized hash and probe kernels
+// Usage: go test -bench=BenchmarkUint64Batch ./tests/benchmark
+func BenchmarkUint64Batch(b *testing.B) {
+	keys := make([]uint64, 1<<16)
+	for i := range keys {
+		keys[i] = uint64(i) * 0x9e3779b97f4a7c15
+	}
+
+	for _, size := range []uint64{10_000, 1_000_000, 100_000_000} {
+		bf := bloomfilter.NewCacheOptimizedBloomFilter(size, 0.01)
+		scalar, err := bloomfilter.NewWithOptions(
+			bloomfilter.WithExpectedElements(size), bloomfilter.WithSIMDDisabled())
+		if err != nil {
+			b.Fatal(err)
+		}
+
+		b.Run(fmt.Sprintf("Size_%d/AddUint64", size), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				for _, k := range keys {
+					bf.AddUint64(k)
+				}
+			}
+			b.ReportMetric(float64(b.N*len(keys))/b.Elapsed().Seconds(), "keys/sec")
+		})
+
+		b.Run(fmt.Sprintf("Size_%d/AddUint64Batch", size), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				bf.AddUint64Batch(keys)
+			}
+			b.ReportMetric(float64(b.N*len(keys))/b.Elapsed().Seconds(), "keys/sec")
+		})
+
+		b.Run(fmt.Sprintf("Size_%d/AddUint64BatchScalar", size), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				scalar.AddUint64Batch(keys)
+			}
+			b.ReportMetric(float64(b.N*len(keys))/b.Elapsed().Seconds(), "keys/sec")
+		})
+
+		b.Run(fmt.Sprintf("Size_%d/ContainsUint64", size), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				for _, k := range keys {
+					bf.ContainsUint64(k)
+				}
+			}
+			b.ReportMetric(float64(b.N*len(keys))/b.Elapsed().Seconds(), "keys/sec")
+		})
+
+		b.Run(fmt.Sprintf("Size_%d/ContainsUint64Batch", size), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				bf.ContainsUint64Batch(keys)
+			}
+			b.ReportMetric(float64(b.N*len(keys))/b.Elapsed().Seconds(), "keys/sec")
+		})
+
+		b.Run(fmt.Sprintf("Size_%d/ContainsUint64BatchScalar", size), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				scalar.ContainsUint64Batch(keys)
+			}
+			b.ReportMetric(float64(b.N*len(keys))/b.Elapsed().Seconds(), "keys/sec")
+		})
+	}
+}
+
+// BenchmarkRegisterBlocked compares the register-blocked filter against the
+// general filter at the same false positive rate
+// Usage: go test -bench=BenchmarkRegisterBlocked ./tests/benchmark
+func BenchmarkRegisterBlocked(b *testing.B) {
+	keys := make([]uint64, 1<<16)
+	for i := range keys {
+		keys[i] = uint64(i) * 0x9e3779b97f4a7c15
+	}
+
+	for _, size := range []uint64{1_000_000, 100_000_000} {
+		bf := bloomfilter.NewCacheOptimizedBloomFilter(size, 0.01)
+		rb := bloomfilter.NewRegisterBlockedFilter(size, 0.01)
+
+		b.Run(fmt.Sprintf("Size_%d/AddUint64", size), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				bf.AddUint64(keys[i&(len(keys)-1)])
+			}
+		})
+		b.Run(fmt.Sprintf("Size_%d/RegisterBlockedAddUint64", size), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				rb.AddUint64(keys[i&(len(keys)-1)])
+			}
+		})
+		b.Run(fmt.Sprintf("Size_%d/ContainsUint64", size), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				bf.ContainsUint64(keys[i&(len(keys)-1)] + 1)
+			}
+		})
+		b.Run(fmt.Sprintf("Size_%d/RegisterBlockedContainsUint64", size), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				rb.ContainsUint64(keys[i&(len(keys)-1)] + 1)
+			}
+		})
+	}
+}
+
+// BenchmarkXorFilter compares lookups in a static binary fuse filter against
+// the general filter at a similar false positive rate
+// Usage: go test -bench=BenchmarkXorFilter ./tests/benchmark
+func BenchmarkXorFilter(b *testing.B) {
+	for _, size := range []int{1_000_000, 10_000_000} {
+		keys := make([]uint64, size)
+		for i := range keys {
+			keys[i] = uint64(i) * 0x9e3779b97f4a7c15
+		}
+		xf, err := bloomfilter.BuildXorFilter(keys)
+		if err != nil {
+			b.Fatal(err)
+		}
+		bf := bloomfilter.NewCacheOptimizedBloomFilter(uint64(size), bloomfilter.XorFilterFPR)
+		bf.AddUint64Batch(keys)
+		b.Logf("Size %d: XorFilter %d bytes, Bloom filter %d bytes", size, xf.SizeInBytes(), bf.BitCount()/8)
+
+		b.Run(fmt.Sprintf("Size_%d/Build", size), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				bloomfilter.BuildXorFilter(keys)
+			}
+			b.ReportMetric(float64(b.N*size)/b.Elapsed().Seconds(), "keys/sec")
+		})
+		b.Run(fmt.Sprintf("Size_%d/XorContainsUint64", size), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				xf.ContainsUint64(keys[i%size] + 1)
+			}
+		})
+		b.Run(fmt.Sprintf("Size_%d/ContainsUint64", size), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				bf.ContainsUint64(keys[i%size] + 1)
+			}
+		})
+	}
+}
+
+// BenchmarkPowerOfTwoSize compares probing with a modulo against the mask used
+// for power-of-two sizes, at a high hash count where the division dominates
+// Usage: go test -bench=BenchmarkPowerOfTwoSize ./tests/benchmark
+func BenchmarkPowerOfTwoSize(b *testing.B) {
+	keys := make([]uint64, 1<<16)
+	for i := range keys {
+		keys[i] = uint64(i) * 0x9e3779b97f4a7c15
+	}
+
+	for _, fpr := range []float64{0.01, 1e-6} {
+		modulo, _ := bloomfilter.NewWithOptions(bloomfilter.WithExpectedElements(1_000_000), bloomfilter.WithFalsePositiveRate(fpr))
+		masked, _ := bloomfilter.NewWithOptions(bloomfilter.WithExpectedElements(1_000_000), bloomfilter.WithFalsePositiveRate(fpr),
+			bloomfilter.WithPowerOfTwoSize())
+		for _, f := range []struct {
+			name string
+			bf   *bloomfilter.CacheOptimizedBloomFilter
+		}{{"Modulo", modulo}, {"Masked", masked}} {
+			b.Run(fmt.Sprintf("K_%d/%s/AddUint64", f.bf.HashCount(), f.name), func(b *testing.B) {
+				for i := 0; i < b.N; i++ {
+					f.bf.AddUint64(keys[i&(len(keys)-1)])
+				}
+			})
+			b.Run(fmt.Sprintf("K_%d/%s/ContainsUint64", f.bf.HashCount(), f.name), func(b *testing.B) {
+				for i := 0; i < b.N; i++ {
+					f.bf.ContainsUint64(keys[i&(len(keys)-1)] + 1)
+				}
+			})
+		}
+	}
+}
+
+// BenchmarkPrecomputedKey checks one key against 64 shard filters, hashing it
+// per filter with Contains, once with PrecomputeKey and ContainsKey, or hashing
+// and computing its positions once with a FilterSet
+// Usage: go test -bench=BenchmarkPrecomputedKey ./tests/benchmark
+func BenchmarkPrecomputedKey(b *testing.B) {
+	seed := bloomfilter.RandomSeed()
+	shards := make([]*bloomfilter.CacheOptimizedBloomFilter, 64)
+	for i := range shards {
+		shards[i], _ = bloomfilter.NewWithOptions(bloomfilter.WithExpectedElements(10_000), bloomfilter.WithSeed(seed))
+	}
+	keys := make([][]byte, 1024)
+	for i := range keys {
+		keys[i] = []byte(fmt.Sprintf("tenant-%d/object-%d", i%37, i))
+		shards[i%len(shards)].Add(keys[i])
+	}
+
+	b.Run("Contains", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			key := keys[i&(len(keys)-1)]
+			for _, bf := range shards {
+				bf.Contains(key)
+			}
+		}
+	})
+	b.Run("ContainsKey", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			k := bloomfilter.PrecomputeKeyWithSeed(keys[i&(len(keys)-1)], seed)
+			for _, bf := range shards {
+				bf.ContainsKey(k)
+			}
+		}
+	})
+	set, err := bloomfilter.NewFilterSet(shards...)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.Run("FilterSet", func(b *testing.B) {
+		var found []int
+		for i := 0; i < b.N; i++ {
+			found = set.AppendWhichContain(found[:0], keys[i&(len(keys)-1)])
+		}
+	})
+}
+
+// BenchmarkFillTracking reads EstimatedFPP from a large filter with a PopCount
+// per call and with the incrementally tracked set-bit count
+// Usage: go test -bench=BenchmarkFillTracking ./tests/benchmark
+func BenchmarkFillTracking(b *testing.B) {
+	for _, tracked := range []bool{false, true} {
+		bf := bloomfilter.NewCacheOptimizedBloomFilter(10_000_000, 0.01)
+		for i := uint64(0); i < 1_000_000; i++ {
+			bf.AddUint64(i)
+		}
+		name := "PopCount"
+		if tracked {
+			bf.EnableFillTracking()
+			name = "Tracked"
+		}
+		b.Run(name+"/EstimatedFPP", func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				bf.EstimatedFPP()
+			}
+		})
+		b.Run(name+"/AddUint64", func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				bf.AddUint64(uint64(i) * 0x9e3779b97f4a7c15)
+			}
+		})
+	}
+}
+
+// BenchmarkOperationCounters measures the cost of operation counting on the
+// per-key paths, off and on
+// Usage: go test -bench=BenchmarkOperationCounters ./tests/benchmark
+func BenchmarkOperationCounters(b *testing.B) {
+	for _, counting := range []bool{false, true} {
+		bf := bloomfilter.NewCacheOptimizedBloomFilter(1_000_000, 0.01)
+		name := "Off"
+		if counting {
+			bf.EnableOperationCounters()
+			name = "On"
+		}
+		b.Run(name+"/AddUint64", func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				bf.AddUint64(uint64(i))
+			}
+		})
+		b.Run(name+"/ContainsUint64", func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				bf.ContainsUint64(uint64(i))
+			}
+		})
+	}
+}
+
+// BenchmarkBulkLoad loads 1M keys from a channel with AddBatch on one
+// goroutine, and with BulkLoad sharing the filter or building per-worker shards
+// Usage: go test -bench=BenchmarkBulkLoad ./tests/benchmark
+func BenchmarkBulkLoad(b *testing.B) {
+	const n = 1_000_000
+	keys := make([][]byte, n)
+	for i := range keys {
+		keys[i] = []byte(fmt.Sprintf("key-%d", i))
+	}
+	send := func() <-chan []byte {
+		ch := make(chan []byte, 4096)
+		go func() {
+			for _, k := range keys {
+				ch <- k
+			}
+			close(ch)
+		}()
+		return ch
+	}
+
+	b.Run("Sequential", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			bf := bloomfilter.NewCacheOptimizedBloomFilter(n, 0.01)
+			batch := make([][]byte, 0, 32)
+			for k := range send() {
+				if batch = append(batch, k); len(batch) == cap(batch) {
+					bf.AddBatch(batch)
+					batch = batch[:0]
+				}
+			}
+			bf.AddBatch(batch)
+		}
+	})
+	for _, sharded := range []bool{false, true} {
+		name := "Shared"
+		var opts []bloomfilter.BulkLoadOption
+		if sharded {
+			name = "Sharded"
+			opts = append(opts, bloomfilter.ShardedBulkLoad())
+		}
+		b.Run(name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				bf := bloomfilter.NewCacheOptimizedBloomFilter(n, 0.01)
+				bf.BulkLoad(send(), 0, opts...)
+			}
+		})
+	}
+}
+
+// BenchmarkAtomicFilter measures the pin an AtomicFilter adds to each lookup,
+// against calling the filter directly, from parallel readers
+// Usage: go test -bench=AtomicFilter ./tests/benchmark
+func BenchmarkAtomicFilter(b *testing.B) {
+	bf := bloomfilter.NewCacheOptimizedBloomFilter(1_000_000, 0.01)
+	for i := uint64(0); i < 100_000; i++ {
+		bf.AddUint64(i)
+	}
+	a := bloomfilter.NewAtomicFilter(bf)
+
+	b.Run("Direct", func(b *testing.B) {
+		b.RunParallel(func(pb *testing.PB) {
+			for i := uint64(0); pb.Next(); i++ {
+				bf.ContainsUint64(i)
+			}
+		})
+	})
+	b.Run("AtomicFilter", func(b *testing.B) {
+		b.RunParallel(func(pb *testing.PB) {
+			for i := uint64(0); pb.Next(); i++ {
+				a.ContainsUint64(i)
+			}
+		})
+	})
+	b.Run("Load", func(b *testing.B) {
+		b.RunParallel(func(pb *testing.PB) {
+			for i := uint64(0); pb.Next(); i++ {
+				a.Load().ContainsUint64(i)
+			}
+		})
+	})
+}
+
+// BenchmarkHashVersion compares Add under the two-pass HashV1 and the one-pass
+// HashV2 for short and long keys, where hashing dominates
+// Usage: go test -bench=BenchmarkHashVersion ./tests/benchmark
+func BenchmarkHashVersion(b *testing.B) {
+	for _, size := range []int{16, 64, 1024} {
+		key := make([]byte, size)
+		for i := range key {
+			key[i] = byte(i * 7)
+		}
+		for _, v := range []bloomfilter.HashVersion{bloomfilter.HashV1, bloomfilter.HashV2} {
+			bf, err := bloomfilter.NewWithOptions(bloomfilter.WithExpectedElements(10_000), bloomfilter.WithHashVersion(v))
+			if err != nil {
+				b.Fatal(err)
+			}
+			b.Run(fmt.Sprintf("%s/%dB", v, size), func(b *testing.B) {
+				b.SetBytes(int64(size))
+				for i := 0; i < b.N; i++ {
+					key[0] = byte(i)
+					bf.Add(key)
+				}
+			})
+		}
+	}
+}
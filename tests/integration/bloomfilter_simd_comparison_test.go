@@ -8,6 +8,7 @@ import (
 	"unsafe"
 
 	bloomfilter "github.com/shaia/BloomFilter"
+	"github.com/shaia/BloomFilter/internal/hash"
 	"github.com/shaia/BloomFilter/internal/simd"
 )
 
@@ -98,6 +99,58 @@ func BenchmarkSIMDvsScalar(b *testing.B) {
 			})
 		})
 
+		b.Run(fmt.Sprintf("VectorXor_Size_%d", size), func(b *testing.B) {
+			dst := make([]byte, size)
+			src := make([]byte, size)
+			for i := range src {
+				src[i] = byte(i % 256)
+			}
+			dstPtr := unsafe.Pointer(&dst[0])
+			srcPtr := unsafe.Pointer(&src[0])
+
+			b.Run("SIMD", func(b *testing.B) {
+				simdOps := simd.Get()
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					simdOps.VectorXor(dstPtr, srcPtr, size)
+				}
+			})
+
+			b.Run("Fallback", func(b *testing.B) {
+				fallbackOps := &simd.FallbackOperations{}
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					fallbackOps.VectorXor(dstPtr, srcPtr, size)
+				}
+			})
+		})
+
+		b.Run(fmt.Sprintf("VectorAndNot_Size_%d", size), func(b *testing.B) {
+			dst := make([]byte, size)
+			src := make([]byte, size)
+			for i := range src {
+				src[i] = byte(i % 256)
+			}
+			dstPtr := unsafe.Pointer(&dst[0])
+			srcPtr := unsafe.Pointer(&src[0])
+
+			b.Run("SIMD", func(b *testing.B) {
+				simdOps := simd.Get()
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					simdOps.VectorAndNot(dstPtr, srcPtr, size)
+				}
+			})
+
+			b.Run("Fallback", func(b *testing.B) {
+				fallbackOps := &simd.FallbackOperations{}
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					fallbackOps.VectorAndNot(dstPtr, srcPtr, size)
+				}
+			})
+		})
+
 		b.Run(fmt.Sprintf("VectorClear_Size_%d", size), func(b *testing.B) {
 			data := make([]byte, size)
 			ptr := unsafe.Pointer(&data[0])
@@ -353,6 +406,58 @@ func TestSIMDCorrectness(t *testing.T) {
 				}
 			})
 
+			// Test VectorXor
+			t.Run("VectorXor", func(t *testing.T) {
+				dst1 := make([]byte, size)
+				dst2 := make([]byte, size)
+				src := make([]byte, size)
+
+				for i := range src {
+					src[i] = byte((i * 13) % 256)
+					dst1[i] = byte((i * 7) % 256)
+					dst2[i] = dst1[i]
+				}
+
+				simdOps := simd.Get()
+				fallbackOps := &simd.FallbackOperations{}
+
+				simdOps.VectorXor(unsafe.Pointer(&dst1[0]), unsafe.Pointer(&src[0]), size)
+				fallbackOps.VectorXor(unsafe.Pointer(&dst2[0]), unsafe.Pointer(&src[0]), size)
+
+				for i := 0; i < size; i++ {
+					if dst1[i] != dst2[i] {
+						t.Errorf("VectorXor mismatch at index %d: SIMD=%d, Fallback=%d", i, dst1[i], dst2[i])
+						break
+					}
+				}
+			})
+
+			// Test VectorAndNot
+			t.Run("VectorAndNot", func(t *testing.T) {
+				dst1 := make([]byte, size)
+				dst2 := make([]byte, size)
+				src := make([]byte, size)
+
+				for i := range src {
+					src[i] = byte((i * 13) % 256)
+					dst1[i] = byte((i * 7) % 256)
+					dst2[i] = dst1[i]
+				}
+
+				simdOps := simd.Get()
+				fallbackOps := &simd.FallbackOperations{}
+
+				simdOps.VectorAndNot(unsafe.Pointer(&dst1[0]), unsafe.Pointer(&src[0]), size)
+				fallbackOps.VectorAndNot(unsafe.Pointer(&dst2[0]), unsafe.Pointer(&src[0]), size)
+
+				for i := 0; i < size; i++ {
+					if dst1[i] != dst2[i] {
+						t.Errorf("VectorAndNot mismatch at index %d: SIMD=%d, Fallback=%d", i, dst1[i], dst2[i])
+						break
+					}
+				}
+			})
+
 			// Test VectorClear
 			t.Run("VectorClear", func(t *testing.T) {
 				data1 := make([]byte, size)
@@ -376,10 +481,353 @@ func TestSIMDCorrectness(t *testing.T) {
 					}
 				}
 			})
+
+			// Test VectorCopy
+			t.Run("VectorCopy", func(t *testing.T) {
+				src := make([]byte, size)
+				for i := range src {
+					src[i] = byte((i * 29) % 256)
+				}
+				dst1 := make([]byte, size)
+				dst2 := make([]byte, size)
+
+				simd.Get().VectorCopy(unsafe.Pointer(&dst1[0]), unsafe.Pointer(&src[0]), size)
+				(&simd.FallbackOperations{}).VectorCopy(unsafe.Pointer(&dst2[0]), unsafe.Pointer(&src[0]), size)
+
+				for i := 0; i < size; i++ {
+					if dst1[i] != src[i] || dst2[i] != src[i] {
+						t.Errorf("VectorCopy mismatch at index %d: SIMD=%d, Fallback=%d, want %d", i, dst1[i], dst2[i], src[i])
+						break
+					}
+				}
+			})
+
+			// Test PopCountAnd against PopCount of the ANDed buffers
+			t.Run("PopCountAnd", func(t *testing.T) {
+				a := make([]byte, size)
+				b := make([]byte, size)
+				for i := range a {
+					a[i] = byte((i * 13) % 256)
+					b[i] = byte((i * 7) % 256)
+				}
+				and := make([]byte, size)
+				for i := range and {
+					and[i] = a[i] & b[i]
+				}
+
+				simdOps := simd.Get()
+				fallbackOps := &simd.FallbackOperations{}
+
+				want := fallbackOps.PopCount(unsafe.Pointer(&and[0]), size)
+				got := simdOps.PopCountAnd(unsafe.Pointer(&a[0]), unsafe.Pointer(&b[0]), size)
+				fallback := fallbackOps.PopCountAnd(unsafe.Pointer(&a[0]), unsafe.Pointer(&b[0]), size)
+				if got != want || fallback != want {
+					t.Errorf("PopCountAnd mismatch: SIMD=%d, Fallback=%d, want %d", got, fallback, want)
+				}
+			})
+
+			// Test PopCountOr against PopCount of the ORed buffers
+			t.Run("PopCountOr", func(t *testing.T) {
+				a := make([]byte, size)
+				b := make([]byte, size)
+				for i := range a {
+					a[i] = byte((i * 13) % 256)
+					b[i] = byte((i * 7) % 256)
+				}
+				or := make([]byte, size)
+				for i := range or {
+					or[i] = a[i] | b[i]
+				}
+
+				simdOps := simd.Get()
+				fallbackOps := &simd.FallbackOperations{}
+
+				want := fallbackOps.PopCount(unsafe.Pointer(&or[0]), size)
+				got := simdOps.PopCountOr(unsafe.Pointer(&a[0]), unsafe.Pointer(&b[0]), size)
+				fallback := fallbackOps.PopCountOr(unsafe.Pointer(&a[0]), unsafe.Pointer(&b[0]), size)
+				if got != want || fallback != want {
+					t.Errorf("PopCountOr mismatch: SIMD=%d, Fallback=%d, want %d", got, fallback, want)
+				}
+			})
+
+			// Test VectorEqual, including a difference in the last byte
+			t.Run("VectorEqual", func(t *testing.T) {
+				a := make([]byte, size)
+				for i := range a {
+					a[i] = byte((i * 23) % 256)
+				}
+				b := append([]byte(nil), a...)
+
+				simdOps := simd.Get()
+				fallbackOps := &simd.FallbackOperations{}
+
+				for _, pos := range []int{-1, 0, size / 2, size - 1} {
+					if pos >= 0 {
+						b[pos] ^= 0x80
+					}
+					want := pos < 0
+					got := simdOps.VectorEqual(unsafe.Pointer(&a[0]), unsafe.Pointer(&b[0]), size)
+					fallback := fallbackOps.VectorEqual(unsafe.Pointer(&a[0]), unsafe.Pointer(&b[0]), size)
+					if got != want || fallback != want {
+						t.Errorf("VectorEqual with difference at %d: SIMD=%t, Fallback=%t, want %t", pos, got, fallback, want)
+					}
+					if pos >= 0 {
+						b[pos] ^= 0x80
+					}
+				}
+			})
+		})
+	}
+}
+
+// TestProbeWordsCorrectness verifies the gather probe kernel against the scalar kernel,
+// including probe counts that are not a multiple of the kernel width
+func TestProbeWordsCorrectness(t *testing.T) {
+	words := make([]uint64, 1024)
+	for i := range words {
+		words[i] = uint64(i) * 0x9e3779b97f4a7c15
+	}
+
+	for _, n := range []int{0, 1, 7, 8, 9, 16, 100, 512} {
+		idx := make([]uint64, n)
+		masks := make([]uint64, n)
+		for i := range idx {
+			idx[i] = uint64(i*37) % uint64(len(words))
+			masks[i] = 1<<(i%64) | 1<<((i*7)%64)
+		}
+		want := append([]uint64(nil), masks...)
+		simd.FallbackProbeWords(words, idx, want)
+		simd.ProbeWords(words, idx, masks)
+
+		for i := range masks {
+			if masks[i] != want[i] {
+				t.Fatalf("n=%d: probe %d: SIMD=%x, Fallback=%x", n, i, masks[i], want[i])
+			}
+		}
+	}
+}
+
+// TestHashUint64BatchCorrectness verifies the uint64 hash kernels of both hash
+// versions against the scalar hashes, including lengths that leave a tail
+func TestHashUint64BatchCorrectness(t *testing.T) {
+	for _, n := range []int{0, 1, 3, 4, 7, 8, 9, 64, 1003} {
+		keys := make([]uint64, n)
+		for i := range keys {
+			keys[i] = uint64(i)*0x9e3779b97f4a7c15 ^ uint64(i)>>3
+		}
+		for _, seed := range []uint64{0, 0xdeadbeef} {
+			h1 := make([]uint64, n)
+			h2 := make([]uint64, n)
+			simd.HashUint64Batch(keys, seed, h1, h2)
+			for i, w := range keys {
+				if w1, w2 := hash.SeededUint64(w, seed); h1[i] != w1 || h2[i] != w2 {
+					t.Fatalf("HashUint64Batch n=%d: key %d differs", n, i)
+				}
+			}
+			simd.Hash128Uint64Batch(keys, seed, h1, h2)
+			for i, w := range keys {
+				if w1, w2 := hash.Seeded128Uint64(w, seed); h1[i] != w1 || h2[i] != w2 {
+					t.Fatalf("Hash128Uint64Batch n=%d: key %d differs", n, i)
+				}
+			}
+		}
+	}
+}
+
+// TestAVX512Correctness checks the AVX-512 kernels against the AVX2 ones,
+// including lengths that leave a partial 64- or 128-byte chunk
+func TestAVX512Correctness(t *testing.T) {
+	if !simd.HasAVX512() {
+		t.Skip("AVX-512 with VPOPCNTDQ not available")
+	}
+	avx512 := &simd.AVX512Operations{}
+	avx2 := &simd.AVX2Operations{}
+
+	for _, size := range []int{1, 63, 64, 65, 127, 128, 129, 191, 192, 1000, 4096} {
+		t.Run(fmt.Sprintf("Size_%d", size), func(t *testing.T) {
+			a := make([]byte, size)
+			b := make([]byte, size)
+			for i := range a {
+				a[i] = byte((i * 17) % 256)
+				b[i] = byte((i * 13) % 256)
+			}
+			pa, pb := unsafe.Pointer(&a[0]), unsafe.Pointer(&b[0])
+
+			if got, want := avx512.PopCount(pa, size), avx2.PopCount(pa, size); got != want {
+				t.Errorf("PopCount: AVX512=%d, AVX2=%d", got, want)
+			}
+			if got, want := avx512.PopCountAnd(pa, pb, size), avx2.PopCountAnd(pa, pb, size); got != want {
+				t.Errorf("PopCountAnd: AVX512=%d, AVX2=%d", got, want)
+			}
+			if got, want := avx512.PopCountOr(pa, pb, size), avx2.PopCountOr(pa, pb, size); got != want {
+				t.Errorf("PopCountOr: AVX512=%d, AVX2=%d", got, want)
+			}
+
+			for _, op := range []struct {
+				name         string
+				avx512, avx2 func(dst, src unsafe.Pointer, length int)
+			}{
+				{"VectorOr", avx512.VectorOr, avx2.VectorOr},
+				{"VectorAnd", avx512.VectorAnd, avx2.VectorAnd},
+				{"VectorXor", avx512.VectorXor, avx2.VectorXor},
+				{"VectorAndNot", avx512.VectorAndNot, avx2.VectorAndNot},
+			} {
+				dst1 := append([]byte(nil), a...)
+				dst2 := append([]byte(nil), a...)
+				op.avx512(unsafe.Pointer(&dst1[0]), pb, size)
+				op.avx2(unsafe.Pointer(&dst2[0]), pb, size)
+				if string(dst1) != string(dst2) {
+					t.Errorf("%s: AVX512 and AVX2 results differ", op.name)
+				}
+			}
+
+			avx512.VectorClear(pa, size)
+			if n := avx2.PopCount(pa, size); n != 0 {
+				t.Errorf("VectorClear left %d bits set", n)
+			}
+		})
+	}
+}
+
+// TestNEONCorrectness checks the NEON kernels against the fallback, including
+// lengths that leave a partial 64-byte chunk and a partial word
+func TestNEONCorrectness(t *testing.T) {
+	if !simd.HasNEON() {
+		t.Skip("NEON not available")
+	}
+	neon := &simd.NEONOperations{}
+	fallback := &simd.FallbackOperations{}
+
+	for _, size := range []int{1, 7, 8, 63, 64, 65, 71, 127, 128, 129, 1000, 4096} {
+		t.Run(fmt.Sprintf("Size_%d", size), func(t *testing.T) {
+			a := make([]byte, size)
+			b := make([]byte, size)
+			for i := range a {
+				a[i] = byte((i * 17) % 256)
+				b[i] = byte((i * 13) % 256)
+			}
+			a[size-1] = 0xff // all 8 bits of a byte in every lane sum
+			pa, pb := unsafe.Pointer(&a[0]), unsafe.Pointer(&b[0])
+
+			if got, want := neon.PopCount(pa, size), fallback.PopCount(pa, size); got != want {
+				t.Errorf("PopCount: NEON=%d, Fallback=%d", got, want)
+			}
+			if got, want := neon.PopCountAnd(pa, pb, size), fallback.PopCountAnd(pa, pb, size); got != want {
+				t.Errorf("PopCountAnd: NEON=%d, Fallback=%d", got, want)
+			}
+			if got, want := neon.PopCountOr(pa, pb, size), fallback.PopCountOr(pa, pb, size); got != want {
+				t.Errorf("PopCountOr: NEON=%d, Fallback=%d", got, want)
+			}
+
+			for _, op := range []struct {
+				name           string
+				neon, fallback func(dst, src unsafe.Pointer, length int)
+			}{
+				{"VectorOr", neon.VectorOr, fallback.VectorOr},
+				{"VectorAnd", neon.VectorAnd, fallback.VectorAnd},
+				{"VectorXor", neon.VectorXor, fallback.VectorXor},
+				{"VectorAndNot", neon.VectorAndNot, fallback.VectorAndNot},
+			} {
+				dst1 := append([]byte(nil), a...)
+				dst2 := append([]byte(nil), a...)
+				op.neon(unsafe.Pointer(&dst1[0]), pb, size)
+				op.fallback(unsafe.Pointer(&dst2[0]), pb, size)
+				if string(dst1) != string(dst2) {
+					t.Errorf("%s: NEON and Fallback results differ", op.name)
+				}
+			}
+
+			// Clearing must not write past the end
+			buf := make([]byte, size+8)
+			for i := range buf {
+				buf[i] = 0xff
+			}
+			neon.VectorClear(unsafe.Pointer(&buf[0]), size)
+			if n := fallback.PopCount(unsafe.Pointer(&buf[0]), size); n != 0 {
+				t.Errorf("VectorClear left %d bits set", n)
+			}
+			if n := fallback.PopCount(unsafe.Pointer(&buf[size]), 8); n != 64 {
+				t.Errorf("VectorClear wrote past the end")
+			}
 		})
 	}
 }
 
+// BenchmarkAVX512vsAVX2 compares the AVX-512 kernels against the AVX2 ones
+func BenchmarkAVX512vsAVX2(b *testing.B) {
+	if !simd.HasAVX512() {
+		b.Skip("AVX-512 with VPOPCNTDQ not available")
+	}
+	backends := []struct {
+		name string
+		ops  simd.Operations
+	}{
+		{"AVX512", &simd.AVX512Operations{}},
+		{"AVX2", &simd.AVX2Operations{}},
+	}
+
+	for _, size := range []int{1024, 16384, 262144} {
+		src := make([]byte, size)
+		dst := make([]byte, size)
+		for i := range src {
+			src[i] = byte(i % 256)
+		}
+		srcPtr, dstPtr := unsafe.Pointer(&src[0]), unsafe.Pointer(&dst[0])
+
+		for _, be := range backends {
+			ops := be.ops
+			b.Run(fmt.Sprintf("PopCount_Size_%d/%s", size, be.name), func(b *testing.B) {
+				b.SetBytes(int64(size))
+				for i := 0; i < b.N; i++ {
+					_ = ops.PopCount(srcPtr, size)
+				}
+			})
+			b.Run(fmt.Sprintf("PopCountAnd_Size_%d/%s", size, be.name), func(b *testing.B) {
+				b.SetBytes(int64(size))
+				for i := 0; i < b.N; i++ {
+					_ = ops.PopCountAnd(srcPtr, dstPtr, size)
+				}
+			})
+			b.Run(fmt.Sprintf("PopCountOr_Size_%d/%s", size, be.name), func(b *testing.B) {
+				b.SetBytes(int64(size))
+				for i := 0; i < b.N; i++ {
+					_ = ops.PopCountOr(srcPtr, dstPtr, size)
+				}
+			})
+			b.Run(fmt.Sprintf("VectorOr_Size_%d/%s", size, be.name), func(b *testing.B) {
+				b.SetBytes(int64(size))
+				for i := 0; i < b.N; i++ {
+					ops.VectorOr(dstPtr, srcPtr, size)
+				}
+			})
+			b.Run(fmt.Sprintf("VectorAnd_Size_%d/%s", size, be.name), func(b *testing.B) {
+				b.SetBytes(int64(size))
+				for i := 0; i < b.N; i++ {
+					ops.VectorAnd(dstPtr, srcPtr, size)
+				}
+			})
+			b.Run(fmt.Sprintf("VectorXor_Size_%d/%s", size, be.name), func(b *testing.B) {
+				b.SetBytes(int64(size))
+				for i := 0; i < b.N; i++ {
+					ops.VectorXor(dstPtr, srcPtr, size)
+				}
+			})
+			b.Run(fmt.Sprintf("VectorAndNot_Size_%d/%s", size, be.name), func(b *testing.B) {
+				b.SetBytes(int64(size))
+				for i := 0; i < b.N; i++ {
+					ops.VectorAndNot(dstPtr, srcPtr, size)
+				}
+			})
+			b.Run(fmt.Sprintf("VectorClear_Size_%d/%s", size, be.name), func(b *testing.B) {
+				b.SetBytes(int64(size))
+				for i := 0; i < b.N; i++ {
+					ops.VectorClear(dstPtr, size)
+				}
+			})
+		}
+	}
+}
+
 // BenchmarkBloomFilterWithSIMD benchmarks the full bloom filter with SIMD vs without
 func BenchmarkBloomFilterWithSIMD(b *testing.B) {
 	// Pre-generate test data once to avoid fmt.Sprintf overhead in benchmarks
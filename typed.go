@@ -0,0 +1,72 @@
+package bloomfilter
+
+// KeyFunc encodes a key as the bytes the filter hashes. Equal keys must encode
+// to equal bytes. The filter does not retain the slice, so an encoder may
+// return a view of the key's memory.
+type KeyFunc[T any] func(key T) []byte
+
+// Integer is the set of integer types NewTypedInteger accepts
+type Integer interface {
+	~int | ~int8 | ~int16 | ~int32 | ~int64 |
+		~uint | ~uint8 | ~uint16 | ~uint32 | ~uint64 | ~uintptr
+}
+
+// Typed is a filter of keys of type T, such as a Typed[UserID], that encodes
+// keys with a KeyFunc and delegates to a CacheOptimizedBloomFilter. It is safe
+// for concurrent use when the KeyFunc is.
+type Typed[T any] struct {
+	bf    *CacheOptimizedBloomFilter
+	bytes KeyFunc[T]
+	word  func(T) uint64 // set for integer keys, which hash like AddUint64
+}
+
+// NewTyped wraps bf as a filter of T encoded by key.
+// Panics if key is nil.
+func NewTyped[T any](bf *CacheOptimizedBloomFilter, key KeyFunc[T]) *Typed[T] {
+	if key == nil {
+		panic("bloomfilter: KeyFunc must not be nil")
+	}
+	return &Typed[T]{bf: bf, bytes: key}
+}
+
+// NewTypedString wraps bf as a filter of a string type. Keys are hashed
+// without copying, like AddString.
+func NewTypedString[T ~string](bf *CacheOptimizedBloomFilter) *Typed[T] {
+	return NewTyped(bf, func(key T) []byte { return stringBytes(string(key)) })
+}
+
+// NewTypedInteger wraps bf as a filter of an integer type. Keys are converted
+// to uint64 and hashed like AddUint64, without allocating, so a Typed[UserID]
+// and AddUint64(uint64(id)) see the same keys.
+func NewTypedInteger[T Integer](bf *CacheOptimizedBloomFilter) *Typed[T] {
+	return &Typed[T]{bf: bf, word: func(key T) uint64 { return uint64(key) }}
+}
+
+// Filter returns the underlying filter
+func (t *Typed[T]) Filter() *CacheOptimizedBloomFilter { return t.bf }
+
+// Add adds key
+func (t *Typed[T]) Add(key T) {
+	if t.word != nil {
+		t.bf.AddUint64(t.word(key))
+		return
+	}
+	t.bf.Add(t.bytes(key))
+}
+
+// Contains checks whether key may be present
+func (t *Typed[T]) Contains(key T) bool {
+	if t.word != nil {
+		return t.bf.ContainsUint64(t.word(key))
+	}
+	return t.bf.Contains(t.bytes(key))
+}
+
+// TestAndAdd adds key and reports whether it was already present, see
+// CacheOptimizedBloomFilter.TestAndAdd
+func (t *Typed[T]) TestAndAdd(key T) bool {
+	if t.word != nil {
+		return t.bf.TestAndAddUint64(t.word(key))
+	}
+	return t.bf.TestAndAdd(t.bytes(key))
+}
@@ -0,0 +1,74 @@
+package bloomfilter
+
+import (
+	"encoding/binary"
+	"testing"
+)
+
+type userID uint64
+
+type tenant string
+
+type point struct{ x, y int32 }
+
+// TestTyped tests typed wrappers over each kind of key and that they interoperate
+// with the untyped API
+func TestTyped(t *testing.T) {
+	bf := NewCacheOptimizedBloomFilter(1000, 0.001)
+
+	ids := NewTypedInteger[userID](bf)
+	ids.Add(42)
+	if !ids.Contains(42) || !bf.ContainsUint64(42) || ids.Contains(43) {
+		t.Error("Integer keys must hash like AddUint64")
+	}
+
+	tenants := NewTypedString[tenant](bf)
+	tenants.Add("acme")
+	if !tenants.Contains("acme") || !bf.ContainsString("acme") || tenants.Contains("globex") {
+		t.Error("String keys must hash like AddString")
+	}
+
+	points := NewTyped(bf, func(p point) []byte {
+		b := make([]byte, 8)
+		binary.LittleEndian.PutUint32(b, uint32(p.x))
+		binary.LittleEndian.PutUint32(b[4:], uint32(p.y))
+		return b
+	})
+	if points.TestAndAdd(point{1, 2}) || !points.TestAndAdd(point{1, 2}) {
+		t.Error("TestAndAdd should report absent, then present")
+	}
+	if !points.Contains(point{1, 2}) || points.Contains(point{2, 1}) {
+		t.Error("Custom KeyFunc keys not found")
+	}
+
+	if ids.Filter() != bf {
+		t.Error("Filter must return the underlying filter")
+	}
+}
+
+// TestTypedZeroAllocations verifies the integer and string wrappers do not allocate
+func TestTypedZeroAllocations(t *testing.T) {
+	skipIfStringsCopy(t)
+	bf := NewCacheOptimizedBloomFilter(1000, 0.01)
+	ids := NewTypedInteger[userID](bf)
+	tenants := NewTypedString[tenant](bf)
+
+	if allocs := testing.AllocsPerRun(100, func() {
+		ids.Add(7)
+		ids.Contains(7)
+		tenants.Add("acme")
+		tenants.Contains("acme")
+	}); allocs != 0 {
+		t.Errorf("Expected 0 allocations, got %f", allocs)
+	}
+}
+
+// TestTypedNilKeyFunc tests that a nil KeyFunc panics
+func TestTypedNilKeyFunc(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected panic for nil KeyFunc")
+		}
+	}()
+	NewTyped[point](NewCacheOptimizedBloomFilter(1000, 0.01), nil)
+}
@@ -0,0 +1,68 @@
+package bloomfilter
+
+import (
+	"errors"
+	"fmt"
+	"unsafe"
+)
+
+// ErrNotViewable is returned by NewFromBuffer for a valid encoding that cannot
+// be used in place; DecodeBinary can still load it by copying
+var ErrNotViewable = errors.New("bloomfilter: buffer cannot be used in place")
+
+// NewFromBuffer creates a read-only filter backed directly by buf, a native
+// format encoding as written by AppendBinary, without copying the bits. This
+// avoids the copy DecodeBinary makes when loading large prebuilt filters, for
+// example from a file the caller has memory-mapped or from an embedded []byte.
+//
+// buf must start at an 8-byte aligned address and the host must be
+// little-endian, since the words are read in place; otherwise the error wraps
+// ErrNotViewable. Page-aligned mappings and heap buffers always qualify, data
+// embedded in the binary is not guaranteed to. Malformed data returns an error
+// wrapping ErrInvalidEncoding. The checksums are verified, which reads buf once.
+//
+// The filter aliases buf: buf must stay valid and unmodified for the filter's
+// lifetime. Lookups, statistics, serialization and Clone (which returns a
+// writable copy) work as usual; operations that set or clear bits (Add and its
+// variants, Clear, Reset, Union, Intersection, OrWord) panic, because buf may be
+// mapped read-only. UnmarshalBinary, ReadFrom and UnmarshalJSON replace the view
+// with a writable filter.
+func NewFromBuffer(buf []byte) (*CacheOptimizedBloomFilter, error) {
+	h, err := parseFormatHeader(buf)
+	if err != nil {
+		return nil, err
+	}
+	if !littleEndian {
+		return nil, fmt.Errorf("%w: host is big-endian", ErrNotViewable)
+	}
+	words := unsafe.Pointer(&buf[formatHeaderSize])
+	if uintptr(words)%8 != 0 {
+		return nil, fmt.Errorf("%w: buffer is not 8-byte aligned", ErrNotViewable)
+	}
+
+	bf := newFilterOver(unsafe.Slice((*CacheLine)(words), int(h.cacheLineCount)), h.hashCount)
+	bf.seed = h.seed
+	bf.probe = h.probe
+	bf.hashVersion = h.hashVersion
+	bf.readOnly = true
+	return bf, nil
+}
+
+// ReadOnly reports whether the filter is a read-only view created by NewFromBuffer
+func (bf *CacheOptimizedBloomFilter) ReadOnly() bool {
+	return bf.readOnly
+}
+
+// checkWritable panics if the filter's bits must not be written
+func (bf *CacheOptimizedBloomFilter) checkWritable() {
+	if bf.readOnly {
+		panic("bloomfilter: cannot modify a read-only filter created by NewFromBuffer")
+	}
+}
+
+// littleEndian reports whether the host stores the native format's words in
+// its own byte order
+var littleEndian = func() bool {
+	x := uint16(1)
+	return *(*byte)(unsafe.Pointer(&x)) == 1
+}()
@@ -0,0 +1,126 @@
+package bloomfilter
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"unsafe"
+)
+
+// alignedCopy returns a copy of data starting at an address with the given
+// remainder modulo 8
+func alignedCopy(data []byte, misalign int) []byte {
+	buf := make([]byte, len(data)+16)
+	off := (8 - int(uintptr(unsafe.Pointer(&buf[0]))%8) + misalign) % 8
+	buf = buf[off : off+len(data)]
+	copy(buf, data)
+	return buf
+}
+
+// TestNewFromBuffer tests that a view answers like the encoded filter without
+// copying its bits
+func TestNewFromBuffer(t *testing.T) {
+	bf, _ := NewWithOptions(WithExpectedElements(10000), WithSeed(42))
+	for i := 0; i < 5000; i++ {
+		bf.AddString(fmt.Sprint("key_", i))
+	}
+	data, _ := bf.MarshalBinary()
+	buf := alignedCopy(data, 0)
+
+	view, err := NewFromBuffer(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !view.ReadOnly() || bf.ReadOnly() {
+		t.Error("ReadOnly reports the wrong filter")
+	}
+	if !view.Equal(bf) || view.Seed() != 42 || view.HashCount() != bf.HashCount() {
+		t.Fatal("View differs from the encoded filter")
+	}
+	for i := 0; i < 5000; i++ {
+		if !view.ContainsString(fmt.Sprint("key_", i)) {
+			t.Fatalf("View is missing key_%d", i)
+		}
+	}
+	if &view.cacheLines[0].words[0] != (*uint64)(unsafe.Pointer(&buf[formatHeaderSize])) {
+		t.Error("View copied the bits")
+	}
+
+	// Serialization reproduces the buffer; Clone is writable
+	if again, _ := view.MarshalBinary(); string(again) != string(data) {
+		t.Error("Re-encoded view differs")
+	}
+	c := view.Clone()
+	c.AddString("new")
+	if c.ReadOnly() || string(buf) != string(data) {
+		t.Error("Clone is not an independent writable copy")
+	}
+	if sub, _ := view.SubFilter(SubFilterView{LineCount: 1}); !sub.ReadOnly() {
+		t.Error("Sub-filter of a view must be read-only")
+	}
+}
+
+// TestNewFromBufferWrites tests that writes panic without touching the buffer
+// and that decoding over a view makes it writable
+func TestNewFromBufferWrites(t *testing.T) {
+	bf := NewCacheOptimizedBloomFilter(1000, 0.01)
+	bf.AddString("kept")
+	data, _ := bf.MarshalBinary()
+	buf := alignedCopy(data, 0)
+	view, err := NewFromBuffer(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	other := NewCacheOptimizedBloomFilter(1000, 0.01)
+	writes := map[string]func(){
+		"Add":          func() { view.AddString("new") },
+		"AddExisting":  func() { view.AddString("kept") },
+		"AddBatch":     func() { view.AddStrings([]string{"a", "b"}) },
+		"AddUint64":    func() { view.AddUint64Batch([]uint64{1, 2}) },
+		"TestAndAdd":   func() { view.TestAndAddString("new") },
+		"Clear":        func() { view.Clear() },
+		"Reset":        func() { view.Reset() },
+		"Union":        func() { view.Union(other) },
+		"Intersection": func() { view.Intersection(other) },
+		"OrWord":       func() { view.OrWord(0, 1) },
+	}
+	for name, write := range writes {
+		t.Run(name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Error("Expected a panic")
+				}
+			}()
+			write()
+		})
+	}
+	if string(buf) != string(data) {
+		t.Fatal("A failed write modified the buffer")
+	}
+
+	if err := view.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	view.AddString("new")
+	if view.ReadOnly() || !view.ContainsString("kept") || string(buf) != string(data) {
+		t.Error("Decoding over a view must make it a writable copy")
+	}
+}
+
+// TestNewFromBufferErrors tests rejection of malformed and misaligned buffers
+func TestNewFromBufferErrors(t *testing.T) {
+	data, _ := NewCacheOptimizedBloomFilter(1000, 0.01).MarshalBinary()
+
+	if _, err := NewFromBuffer(alignedCopy(data, 4)); !errors.Is(err, ErrNotViewable) {
+		t.Errorf("Misaligned buffer: expected ErrNotViewable, got %v", err)
+	}
+	corrupt := alignedCopy(data, 0)
+	corrupt[formatHeaderSize] ^= 1
+	if _, err := NewFromBuffer(corrupt); !errors.Is(err, ErrInvalidEncoding) {
+		t.Errorf("Corrupt buffer: expected ErrInvalidEncoding, got %v", err)
+	}
+	if _, err := NewFromBuffer(nil); !errors.Is(err, ErrInvalidEncoding) {
+		t.Errorf("Empty buffer: expected ErrInvalidEncoding, got %v", err)
+	}
+}
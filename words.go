@@ -0,0 +1,108 @@
+package bloomfilter
+
+import (
+	"fmt"
+	"math/bits"
+	"sync/atomic"
+	"unsafe"
+)
+
+// Words returns a copy of the bit array as uint64 words in cache line order.
+// Bit i of the filter is bit i%64 (least significant first) of word i/64, and
+// len(Words()) == BitCount()/64. Each word is loaded atomically, so the copy is
+// safe to take while other goroutines are adding elements.
+func (bf *CacheOptimizedBloomFilter) Words() []uint64 {
+	words := make([]uint64, bf.cacheLineCount*WordsPerCacheLine)
+	for i := range bf.cacheLines {
+		line := &bf.cacheLines[i]
+		for j := range line.words {
+			words[i*WordsPerCacheLine+j] = atomic.LoadUint64(&line.words[j])
+		}
+	}
+	return words
+}
+
+// NewFromWords creates a filter from a bit array in the layout returned by Words
+// and the hash count it was built with. The words are copied. The layout does
+// not record the seed, probe scheme or hash version: the filter uses seed 0,
+// DefaultProbeScheme and DefaultHashVersion. Bits of a seeded filter, which the
+// constructors create by default, need NewFromWordsWithSeed, and bits built
+// with DoubleHashing or HashV1 need SetProbeScheme or SetHashVersion.
+//
+// Returns an error if words is empty or not a whole number of cache lines, or if
+// hashCount is 0.
+func NewFromWords(words []uint64, hashCount uint32) (*CacheOptimizedBloomFilter, error) {
+	return NewFromWordsWithSeed(words, hashCount, 0)
+}
+
+// NewFromWordsWithSeed is NewFromWords for a filter built with a hash seed
+func NewFromWordsWithSeed(words []uint64, hashCount uint32, seed uint64) (*CacheOptimizedBloomFilter, error) {
+	if len(words) == 0 || len(words)%WordsPerCacheLine != 0 {
+		return nil, fmt.Errorf("bloomfilter: word count must be a positive multiple of %d, got %d", WordsPerCacheLine, len(words))
+	}
+	if hashCount == 0 {
+		return nil, fmt.Errorf("bloomfilter: hashCount must be greater than 0")
+	}
+
+	bf := newFilter(uint64(len(words)/WordsPerCacheLine), hashCount)
+	for i := range bf.cacheLines {
+		copy(bf.cacheLines[i].words[:], words[i*WordsPerCacheLine:])
+	}
+	bf.seed = seed
+	return bf, nil
+}
+
+// WordCount returns the number of words in the bit array, BitCount()/64
+func (bf *CacheOptimizedBloomFilter) WordCount() int {
+	return int(bf.cacheLineCount) * WordsPerCacheLine
+}
+
+// LoadWord atomically loads word i of the bit array, in the layout of Words,
+// without copying the array. Bit position p is bit p%64 of word p/64, so custom
+// probing can test it as LoadWord(p/64)&(1<<(p%64)) != 0.
+//
+// Panics if i is not in [0, WordCount()).
+func (bf *CacheOptimizedBloomFilter) LoadWord(i int) uint64 {
+	return atomic.LoadUint64(bf.word(i))
+}
+
+// OrWord atomically sets the bits of mask in word i and returns the word's
+// previous value, so fused operations can tell which bits were newly set. It
+// is safe to use concurrently with every other method. Like Union it bypasses
+// load shedding and add counting, but keeps load tracking up to date and
+// suspends FPR measurement when it sets new bits.
+//
+// Panics if i is not in [0, WordCount()).
+func (bf *CacheOptimizedBloomFilter) OrWord(i int, mask uint64) uint64 {
+	bf.checkWritable()
+	old := atomic.OrUint64(bf.word(i), mask)
+	if flipped := bits.OnesCount64(mask &^ old); flipped > 0 {
+		if t := bf.changes.Load(); t != nil {
+			t.mark(uint64(i / WordsPerCacheLine))
+		}
+		bf.recordFlips(uint64(flipped))
+		if g := bf.ghost.Load(); g != nil {
+			g.invalidate()
+		}
+		bf.recordExternal()
+	}
+	return old
+}
+
+// word returns a pointer to word i of the bit array
+func (bf *CacheOptimizedBloomFilter) word(i int) *uint64 {
+	return &bf.cacheLines[i/WordsPerCacheLine].words[i%WordsPerCacheLine]
+}
+
+// wordSlice returns the bit array as one slice of words, aliasing the filter
+func (bf *CacheOptimizedBloomFilter) wordSlice() []uint64 {
+	return wordsOf(bf.cacheLines)
+}
+
+// wordsOf returns cache lines as one slice of words, aliasing them
+func wordsOf(lines []CacheLine) []uint64 {
+	if len(lines) == 0 {
+		return nil
+	}
+	return unsafe.Slice(&lines[0].words[0], len(lines)*WordsPerCacheLine)
+}
@@ -0,0 +1,140 @@
+package bloomfilter
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+)
+
+// TestWordsRoundTrip tests that a filter rebuilt from Words answers identically
+func TestWordsRoundTrip(t *testing.T) {
+	bf, _ := NewWithOptions(WithExpectedElements(1000), WithSeed(0)) // the seed NewFromWords assumes
+	for i := 0; i < 500; i++ {
+		bf.AddString(fmt.Sprintf("key_%d", i))
+	}
+
+	words := bf.Words()
+	if uint64(len(words)) != bf.BitCount()/64 {
+		t.Fatalf("Expected %d words, got %d", bf.BitCount()/64, len(words))
+	}
+
+	restored, err := NewFromWords(words, bf.HashCount())
+	if err != nil {
+		t.Fatalf("NewFromWords failed: %v", err)
+	}
+	if restored.BitCount() != bf.BitCount() || restored.HashCount() != bf.HashCount() {
+		t.Fatalf("Geometry mismatch: got m=%d k=%d, want m=%d k=%d",
+			restored.BitCount(), restored.HashCount(), bf.BitCount(), bf.HashCount())
+	}
+	if restored.PopCount() != bf.PopCount() {
+		t.Errorf("PopCount mismatch: %d vs %d", restored.PopCount(), bf.PopCount())
+	}
+	for i := 0; i < 500; i++ {
+		if !restored.ContainsString(fmt.Sprintf("key_%d", i)) {
+			t.Fatalf("Restored filter is missing key_%d", i)
+		}
+	}
+
+	// The input slice must be copied
+	words[0] = ^uint64(0)
+	if restored.Words()[0] == ^uint64(0) && bf.Words()[0] != ^uint64(0) {
+		t.Error("NewFromWords must copy its input")
+	}
+}
+
+// TestNewFromWordsValidation verifies error handling for malformed input
+func TestNewFromWordsValidation(t *testing.T) {
+	if _, err := NewFromWords(nil, 3); err == nil {
+		t.Error("Expected error for empty words")
+	}
+	if _, err := NewFromWords(make([]uint64, 7), 3); err == nil {
+		t.Error("Expected error for partial cache line")
+	}
+	if _, err := NewFromWords(make([]uint64, 8), 0); err == nil {
+		t.Error("Expected error for zero hash count")
+	}
+}
+
+// TestWordPrimitives tests custom probing and inserting on top of LoadWord and OrWord
+func TestWordPrimitives(t *testing.T) {
+	bf := NewCacheOptimizedBloomFilter(1000, 0.01)
+	bf.enableBitTracking()
+	if bf.WordCount() != len(bf.Words()) {
+		t.Fatalf("WordCount %d, Words has %d", bf.WordCount(), len(bf.Words()))
+	}
+
+	// Insert through OrWord at the positions Add would use
+	for i := 0; i < 500; i++ {
+		for _, p := range bf.Explain([]byte(fmt.Sprintf("key_%d", i))).Probes {
+			bf.OrWord(int(p.Position/64), 1<<(p.Position%64))
+		}
+	}
+	if bf.bitsSet.Load() != bf.PopCount() {
+		t.Errorf("Tracked %d set bits, PopCount %d", bf.bitsSet.Load(), bf.PopCount())
+	}
+
+	// Custom probing with LoadWord agrees with Contains
+	for i := 0; i < 2000; i++ {
+		key := []byte(fmt.Sprintf("key_%d", i))
+		present := true
+		for _, p := range bf.Explain(key).Probes {
+			present = present && bf.LoadWord(int(p.Position/64))&(1<<(p.Position%64)) != 0
+		}
+		if present != bf.Contains(key) || (i < 500 && !present) {
+			t.Fatalf("Custom probe and Contains disagree on %s", key)
+		}
+	}
+
+	if old := bf.OrWord(3, 0b1010); bf.LoadWord(3) != old|0b1010 {
+		t.Error("OrWord did not return the previous value")
+	}
+}
+
+// TestOrWordConcurrent tests that concurrent OrWord calls on the same words lose no bits
+func TestOrWordConcurrent(t *testing.T) {
+	bf := NewCacheOptimizedBloomFilter(100, 0.01)
+	var wg sync.WaitGroup
+	for g := 0; g < 64; g++ {
+		wg.Add(1)
+		go func(bit int) {
+			defer wg.Done()
+			for i := 0; i < bf.WordCount(); i++ {
+				bf.OrWord(i, 1<<bit)
+			}
+		}(g)
+	}
+	wg.Wait()
+	for i := 0; i < bf.WordCount(); i++ {
+		if bf.LoadWord(i) != ^uint64(0) {
+			t.Fatalf("Word %d is %#x after setting every bit", i, bf.LoadWord(i))
+		}
+	}
+}
+
+// TestWordsSeeded tests that seeded filters survive the bare bit array layouts
+// when the seed is passed back in
+func TestWordsSeeded(t *testing.T) {
+	bf, _ := NewWithOptions(WithExpectedElements(1000), WithRandomSeed())
+	for i := 0; i < 500; i++ {
+		bf.AddString(fmt.Sprintf("key_%d", i))
+	}
+
+	fromWords, err := NewFromWordsWithSeed(bf.Words(), bf.HashCount(), bf.Seed())
+	if err != nil {
+		t.Fatal(err)
+	}
+	fromJava, err := NewFromJavaLongsWithSeed(bf.AppendJavaLongs(nil), bf.HashCount(), bf.Seed())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, restored := range []*CacheOptimizedBloomFilter{fromWords, fromJava} {
+		if !restored.Equal(bf) {
+			t.Fatalf("Restored filter differs: seed %#x, want %#x", restored.Seed(), bf.Seed())
+		}
+		for i := 0; i < 500; i++ {
+			if !restored.ContainsString(fmt.Sprintf("key_%d", i)) {
+				t.Fatalf("Restored filter is missing key_%d", i)
+			}
+		}
+	}
+}
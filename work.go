@@ -0,0 +1,64 @@
+package bloomfilter
+
+import "math"
+
+// WorkEstimate describes the expected per-element cost of filter operations in
+// bit probes and distinct cache lines touched. Each probe of this filter lands on
+// an independent, uniformly distributed cache line, so cache lines are the unit
+// that dominates latency for filters larger than the CPU caches.
+type WorkEstimate struct {
+	// Add always probes all k bits
+	ProbesPerAdd float64
+	LinesPerAdd  float64
+	// Contains for a present element also probes all k bits
+	ProbesPerContainsHit float64
+	LinesPerContainsHit  float64
+	// Contains for an absent element stops at the first unset bit, so the cost
+	// grows with the load factor: (1 - p^k) / (1 - p) probes for load p
+	ProbesPerContainsMiss float64
+	LinesPerContainsMiss  float64
+}
+
+// EstimateWork returns the expected work per operation for a filter with the given
+// geometry and load factor (fraction of bits set, 0 for an empty filter). It lets
+// configurations be compared without building a filter.
+func EstimateWork(cacheLineCount uint64, hashCount uint32, loadFactor float64) WorkEstimate {
+	k := float64(hashCount)
+	p := math.Min(math.Max(loadFactor, 0), 1)
+
+	missProbes := k
+	if p < 1 {
+		missProbes = (1 - math.Pow(p, k)) / (1 - p)
+	}
+
+	lines := func(probes float64) float64 {
+		return expectedDistinctLines(cacheLineCount, probes)
+	}
+
+	return WorkEstimate{
+		ProbesPerAdd:          k,
+		LinesPerAdd:           lines(k),
+		ProbesPerContainsHit:  k,
+		LinesPerContainsHit:   lines(k),
+		ProbesPerContainsMiss: missProbes,
+		LinesPerContainsMiss:  lines(missProbes),
+	}
+}
+
+// WorkEstimate returns the expected work per operation at the current load factor.
+// It performs a PopCount, which is O(m), unless fill tracking is on (see
+// EnableFillTracking).
+func (bf *CacheOptimizedBloomFilter) WorkEstimate() WorkEstimate {
+	loadFactor := bf.trackedLoadFactor()
+	return EstimateWork(bf.cacheLineCount, bf.hashCount, loadFactor)
+}
+
+// expectedDistinctLines returns the expected number of distinct cache lines hit by
+// probes independent uniform probes over cacheLineCount lines: L * (1 - (1 - 1/L)^q)
+func expectedDistinctLines(cacheLineCount uint64, probes float64) float64 {
+	if cacheLineCount == 0 || probes <= 0 {
+		return 0
+	}
+	l := float64(cacheLineCount)
+	return l * -math.Expm1(probes*math.Log1p(-1/l))
+}
@@ -0,0 +1,59 @@
+package bloomfilter
+
+import (
+	"fmt"
+	"math"
+	"testing"
+)
+
+// TestEstimateWork tests the closed-form work estimates against known values
+func TestEstimateWork(t *testing.T) {
+	// Empty filter: an absent key stops at the first probe
+	w := EstimateWork(1000, 7, 0)
+	if w.ProbesPerAdd != 7 || w.ProbesPerContainsHit != 7 {
+		t.Errorf("Expected 7 probes per add/hit, got %+v", w)
+	}
+	if math.Abs(w.ProbesPerContainsMiss-1) > 1e-9 {
+		t.Errorf("Expected 1 probe per miss on an empty filter, got %f", w.ProbesPerContainsMiss)
+	}
+
+	// A single cache line is always the only line touched
+	if w := EstimateWork(1, 7, 0.5); math.Abs(w.LinesPerAdd-1) > 1e-9 {
+		t.Errorf("Expected 1 line per add with one cache line, got %f", w.LinesPerAdd)
+	}
+
+	// Large filters: probes almost always land on distinct lines
+	if w := EstimateWork(1<<30, 7, 0.5); math.Abs(w.LinesPerAdd-7) > 1e-6 {
+		t.Errorf("Expected ~7 lines per add on a huge filter, got %f", w.LinesPerAdd)
+	}
+
+	// Half-full filter with k=2: 1 + 0.5 probes per miss
+	if w := EstimateWork(1000, 2, 0.5); math.Abs(w.ProbesPerContainsMiss-1.5) > 1e-9 {
+		t.Errorf("Expected 1.5 probes per miss, got %f", w.ProbesPerContainsMiss)
+	}
+
+	// Saturated filter: misses cost as much as hits
+	if w := EstimateWork(1000, 5, 1); w.ProbesPerContainsMiss != 5 {
+		t.Errorf("Expected 5 probes per miss when saturated, got %f", w.ProbesPerContainsMiss)
+	}
+}
+
+// TestWorkEstimateInStats tests that CacheStats reports the work estimate
+func TestWorkEstimateInStats(t *testing.T) {
+	bf := NewCacheOptimizedBloomFilter(1000, 0.01)
+	for i := 0; i < 1000; i++ {
+		bf.AddString(fmt.Sprintf("key_%d", i))
+	}
+
+	stats := bf.GetCacheStats()
+	if stats.Work != bf.WorkEstimate() {
+		t.Errorf("CacheStats.Work %+v differs from WorkEstimate %+v", stats.Work, bf.WorkEstimate())
+	}
+	if stats.Work.LinesPerAdd <= 0 || stats.Work.LinesPerAdd > float64(stats.HashCount) {
+		t.Errorf("LinesPerAdd %f out of range (0, %d]", stats.Work.LinesPerAdd, stats.HashCount)
+	}
+	if stats.Work.ProbesPerContainsMiss <= 1 || stats.Work.ProbesPerContainsMiss >= float64(stats.HashCount) {
+		t.Errorf("ProbesPerContainsMiss %f out of range (1, %d)", stats.Work.ProbesPerContainsMiss, stats.HashCount)
+	}
+	t.Logf("Work estimate: %+v", stats.Work)
+}
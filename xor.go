@@ -0,0 +1,358 @@
+package bloomfilter
+
+import (
+	"encoding"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"math"
+	"math/bits"
+	"slices"
+
+	"github.com/shaia/BloomFilter/internal/hash"
+)
+
+// Binary fuse filter
+//
+// An XorFilter stores an 8-bit fingerprint per slot. Each key maps to three
+// slots in three consecutive segments, and construction solves for slot values
+// whose XOR equals the key's fingerprint: keys are peeled off slots that only
+// one remaining key maps to, and the slots are assigned in reverse peeling
+// order. This is the binary fuse layout of Graf and Lemire, "Binary Fuse
+// Filters: Fast and Smaller Than Xor Filters" (2022), which needs about 1.13
+// slots per key for large sets. A lookup reads three bytes and compares, with
+// a false positive rate of 1/256 at about 9 bits per key, where a Bloom filter
+// needs about 11.5 bits.
+
+const (
+	// xorMaxSegmentLength caps the segment length, keeping the three slots of a
+	// key within a few cache lines of each other
+	xorMaxSegmentLength = 1 << 18
+	// xorMaxAttempts bounds construction retries with new seeds; each attempt
+	// fails with a probability well below 1%
+	xorMaxAttempts = 100
+	// XorFilterFPR is the false positive rate of an XorFilter
+	XorFilterFPR = 1.0 / 256
+)
+
+// XorFilter is an immutable filter for a static set of uint64 keys, built once
+// by BuildXorFilter. It is smaller and faster than a Bloom filter at the same
+// false positive rate (XorFilterFPR) but cannot be added to. Keys of other
+// types can be hashed to uint64 first. It is safe for concurrent use.
+type XorFilter struct {
+	fingerprints       []uint8
+	seed               uint64
+	keys               uint64 // distinct keys
+	segmentLength      uint32
+	segmentCount       uint32 // segments a key's first slot can fall in
+	segmentCountLength uint32 // segmentCount * segmentLength
+}
+
+// BuildXorFilter builds a filter holding keys. Duplicate keys are allowed and
+// stored once; if they keep construction from succeeding, it is retried on a
+// sorted, deduplicated copy of keys. Construction is deterministic and takes
+// about 25 bytes of temporary memory per key.
+//
+// Returns an error if there are 2^32 or more keys, or in the astronomically
+// unlikely case that no hash seed yields a solvable layout.
+func BuildXorFilter(keys []uint64) (*XorFilter, error) {
+	if uint64(len(keys)) >= math.MaxUint32 {
+		return nil, fmt.Errorf("bloomfilter: XorFilter holds fewer than 2^32 keys, got %d", len(keys))
+	}
+	f, err := buildXorFilter(keys)
+	if err == errXorDuplicates {
+		f, err = buildXorFilter(slices.Compact(slices.Sorted(slices.Values(keys))))
+	}
+	return f, err
+}
+
+// errXorDuplicates reports a failed construction attempt that saw duplicate
+// keys, which may be what kept it from succeeding
+var errXorDuplicates = errors.New("bloomfilter: duplicate keys")
+
+func buildXorFilter(keys []uint64) (*XorFilter, error) {
+	size := uint32(len(keys))
+	f := newXorFilter(size)
+	capacity := uint32(len(f.fingerprints))
+
+	// Per slot: count<<2 | XOR of which of its key's slots (0, 1, 2) this is,
+	// and the XOR of the hashes of the keys mapped to it
+	counts := make([]uint8, capacity)
+	hashes := make([]uint64, capacity)
+	// Hashes in segment order, then in peeling order
+	order := make([]uint64, size+1)
+	slotOf := make([]uint8, size) // which of the key's slots it was peeled from
+	queue := make([]uint32, capacity)
+
+	blockBits := 1
+	for 1<<blockBits < f.segmentCount {
+		blockBits++
+	}
+	start := make([]uint32, 1<<blockBits)
+
+	rng := uint64(0x5851f42d4c957f2d)
+	for attempt := 0; ; attempt++ {
+		if attempt == xorMaxAttempts {
+			return nil, fmt.Errorf("bloomfilter: XorFilter construction failed after %d attempts", attempt)
+		}
+		rng += 0x9e3779b97f4a7c15
+		f.seed = mix64(rng)
+		clear(order)
+		clear(counts)
+		clear(hashes)
+
+		// Sorting the hashes by segment keeps the slot updates below local
+		order[size] = 1 // sentinel
+		for i := range start {
+			start[i] = uint32((uint64(i) * uint64(size)) >> blockBits)
+		}
+		for _, key := range keys {
+			h := f.hash(key)
+			block := h >> (64 - blockBits)
+			for order[start[block]] != 0 {
+				block = (block + 1) & (1<<blockBits - 1)
+			}
+			order[start[block]] = h
+			start[block]++
+		}
+
+		overflow := false
+		var duplicates uint32
+		for _, h := range order[:size] {
+			s := f.slots(h)
+			for j, slot := range s {
+				counts[slot] += 4
+				counts[slot] ^= uint8(j)
+				hashes[slot] ^= h
+			}
+			// A duplicate hash cancels out of its slots; undo it if any slot
+			// is now back to holding a single cancelled pair
+			if hashes[s[0]]&hashes[s[1]]&hashes[s[2]] == 0 &&
+				(hashes[s[0]] == 0 && counts[s[0]] == 8 || hashes[s[1]] == 0 && counts[s[1]] == 8 || hashes[s[2]] == 0 && counts[s[2]] == 8) {
+				duplicates++
+				for j, slot := range s {
+					counts[slot] -= 4
+					counts[slot] ^= uint8(j)
+					hashes[slot] ^= h
+				}
+			}
+			// Six bits of count wrap past 63 keys in a slot
+			overflow = overflow || counts[s[0]] < 4 || counts[s[1]] < 4 || counts[s[2]] < 4
+		}
+		if overflow {
+			continue
+		}
+
+		// Peel slots holding a single key
+		queued := 0
+		for i := range capacity {
+			queue[queued] = i
+			if counts[i]>>2 == 1 {
+				queued++
+			}
+		}
+		var peeled uint32
+		for queued > 0 {
+			queued--
+			slot := queue[queued]
+			if counts[slot]>>2 != 1 {
+				continue
+			}
+			h := hashes[slot]
+			found := counts[slot] & 3
+			slotOf[peeled] = found
+			order[peeled] = h
+			peeled++
+
+			s := f.slots(h)
+			for _, j := range [2]uint8{mod3(found + 1), mod3(found + 2)} {
+				other := s[j]
+				queue[queued] = other
+				if counts[other]>>2 == 2 {
+					queued++
+				}
+				counts[other] -= 4
+				counts[other] ^= j
+				hashes[other] ^= h
+			}
+		}
+		if peeled+duplicates == size {
+			f.keys = uint64(peeled)
+			break
+		}
+		if duplicates > 0 {
+			// A duplicate whose slots are all shared is not detected above and
+			// can never be peeled, with any seed
+			return nil, errXorDuplicates
+		}
+	}
+
+	// Assign in reverse peeling order, so each key's free slot is written last
+	for i := int(f.keys) - 1; i >= 0; i-- {
+		h := order[i]
+		s := f.slots(h)
+		found := slotOf[i]
+		f.fingerprints[s[found]] = xorFingerprint(h) ^
+			f.fingerprints[s[mod3(found+1)]] ^ f.fingerprints[s[mod3(found+2)]]
+	}
+	return f, nil
+}
+
+// newXorFilter allocates an empty filter sized for size keys, following the
+// parameters of the reference implementation
+func newXorFilter(size uint32) *XorFilter {
+	segmentLength := uint32(4)
+	if size > 0 {
+		segmentLength = 1 << int(math.Floor(math.Log(float64(size))/math.Log(3.33)+2.25))
+	}
+	segmentLength = min(segmentLength, xorMaxSegmentLength)
+
+	var capacity uint32
+	if size > 1 {
+		sizeFactor := math.Max(1.125, 0.875+0.25*math.Log(1_000_000)/math.Log(float64(size)))
+		capacity = uint32(math.Round(float64(size) * sizeFactor))
+	}
+	// A key's slots span three segments, so the first one has two fewer choices
+	segmentCount := max((capacity+segmentLength-1)/segmentLength, 3) - 2
+	return newXorFilterWithGeometry(segmentLength, segmentCount)
+}
+
+func newXorFilterWithGeometry(segmentLength, segmentCount uint32) *XorFilter {
+	return &XorFilter{
+		fingerprints:       make([]uint8, (segmentCount+2)*segmentLength),
+		segmentLength:      segmentLength,
+		segmentCount:       segmentCount,
+		segmentCountLength: segmentCount * segmentLength,
+	}
+}
+
+// hash returns the 64-bit hash of key under the filter's seed, finalized so
+// that all bits are usable for slot and fingerprint selection
+func (f *XorFilter) hash(key uint64) uint64 {
+	h, _ := hash.SeededUint64(key, f.seed)
+	return mix64(h)
+}
+
+// slots returns the three slots of a hashed key, one in each of three
+// consecutive segments
+func (f *XorFilter) slots(h uint64) [3]uint32 {
+	hi, _ := bits.Mul64(h, uint64(f.segmentCountLength))
+	mask := f.segmentLength - 1
+	s0 := uint32(hi)
+	s1 := (s0 + f.segmentLength) ^ uint32(h>>18)&mask
+	s2 := (s0 + 2*f.segmentLength) ^ uint32(h)&mask
+	return [3]uint32{s0, s1, s2}
+}
+
+// xorFingerprint returns the 8-bit fingerprint of a hashed key
+func xorFingerprint(h uint64) uint8 {
+	return uint8(h ^ h>>32)
+}
+
+// mod3 reduces a slot number in [0, 5) modulo 3
+func mod3(x uint8) uint8 {
+	if x > 2 {
+		x -= 3
+	}
+	return x
+}
+
+// ContainsUint64 checks whether key may be in the set the filter was built from
+func (f *XorFilter) ContainsUint64(key uint64) bool {
+	if f.keys == 0 {
+		return false
+	}
+	h := f.hash(key)
+	s := f.slots(h)
+	return xorFingerprint(h)^f.fingerprints[s[0]]^f.fingerprints[s[1]]^f.fingerprints[s[2]] == 0
+}
+
+// Len returns the number of distinct keys in the filter
+func (f *XorFilter) Len() uint64 {
+	return f.keys
+}
+
+// SizeInBytes returns the size of the fingerprint array
+func (f *XorFilter) SizeInBytes() uint64 {
+	return uint64(len(f.fingerprints))
+}
+
+// BitsPerKey returns the memory used per distinct key
+func (f *XorFilter) BitsPerKey() float64 {
+	if f.keys == 0 {
+		return 0
+	}
+	return float64(len(f.fingerprints)) * 8 / float64(f.keys)
+}
+
+// XorFilter format, all integers little-endian:
+//
+//	magic "BLMX" | version u16 | flags u16 | segmentLength u32 | segmentCount u32 |
+//	keys u64 | seed u64 | fingerprints [(segmentCount+2)*segmentLength]u8 | crc32 (IEEE)
+const (
+	xorMagic         = "BLMX"
+	xorHeaderSize    = 32
+	xorFormatVersion = 1
+)
+
+// MarshalBinary encodes the filter (implements encoding.BinaryMarshaler)
+func (f *XorFilter) MarshalBinary() ([]byte, error) {
+	b := make([]byte, 0, xorHeaderSize+len(f.fingerprints)+formatTrailer)
+	b = append(b, xorMagic...)
+	b = binary.LittleEndian.AppendUint16(b, xorFormatVersion)
+	b = binary.LittleEndian.AppendUint16(b, 0) // flags
+	b = binary.LittleEndian.AppendUint32(b, f.segmentLength)
+	b = binary.LittleEndian.AppendUint32(b, f.segmentCount)
+	b = binary.LittleEndian.AppendUint64(b, f.keys)
+	b = binary.LittleEndian.AppendUint64(b, f.seed)
+	b = append(b, f.fingerprints...)
+	return binary.LittleEndian.AppendUint32(b, crc32.ChecksumIEEE(b)), nil
+}
+
+// UnmarshalBinary replaces the filter with one encoded by MarshalBinary
+// (implements encoding.BinaryUnmarshaler). The receiver may be a zero XorFilter
+// and is left unchanged on error. Malformed input returns an error wrapping
+// ErrInvalidEncoding.
+func (f *XorFilter) UnmarshalBinary(data []byte) error {
+	if len(data) < xorHeaderSize+formatTrailer {
+		return fmt.Errorf("%w: missing XorFilter header", ErrCorruptData)
+	}
+	if string(data[:4]) != xorMagic {
+		return fmt.Errorf("%w: bad XorFilter magic %q", ErrInvalidEncoding, data[:4])
+	}
+	if v := binary.LittleEndian.Uint16(data[4:]); v != xorFormatVersion {
+		return fmt.Errorf("%w: unsupported XorFilter version %d", ErrInvalidEncoding, v)
+	}
+	if flags := binary.LittleEndian.Uint16(data[6:]); flags != 0 {
+		return fmt.Errorf("%w: unknown XorFilter flags %#x", ErrInvalidEncoding, flags)
+	}
+	segmentLength := binary.LittleEndian.Uint32(data[8:])
+	segmentCount := binary.LittleEndian.Uint32(data[12:])
+	keys := binary.LittleEndian.Uint64(data[16:])
+	seed := binary.LittleEndian.Uint64(data[24:])
+	if segmentLength == 0 || segmentLength > xorMaxSegmentLength || segmentLength&(segmentLength-1) != 0 || segmentCount == 0 {
+		return fmt.Errorf("%w: invalid XorFilter geometry (%d segments of %d)", ErrInvalidEncoding, segmentCount, segmentLength)
+	}
+
+	slots := (uint64(segmentCount) + 2) * uint64(segmentLength)
+	if body := uint64(len(data) - xorHeaderSize - formatTrailer); body != slots || slots > math.MaxUint32 {
+		return fmt.Errorf("%w: %d bytes of fingerprints for %d slots", ErrCorruptData, body, slots)
+	}
+	sum := binary.LittleEndian.Uint32(data[len(data)-formatTrailer:])
+	if crc32.ChecksumIEEE(data[:len(data)-formatTrailer]) != sum {
+		return fmt.Errorf("%w: XorFilter checksum mismatch", ErrCorruptData)
+	}
+
+	decoded := newXorFilterWithGeometry(segmentLength, segmentCount)
+	copy(decoded.fingerprints, data[xorHeaderSize:])
+	decoded.keys = keys
+	decoded.seed = seed
+	*f = *decoded
+	return nil
+}
+
+var (
+	_ encoding.BinaryMarshaler   = (*XorFilter)(nil)
+	_ encoding.BinaryUnmarshaler = (*XorFilter)(nil)
+)
@@ -0,0 +1,119 @@
+package bloomfilter
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+// TestBuildXorFilter tests membership across set sizes, including the
+// degenerate ones
+func TestBuildXorFilter(t *testing.T) {
+	for _, n := range []int{0, 1, 2, 3, 10, 1000, 200_000} {
+		t.Run(fmt.Sprintf("Keys_%d", n), func(t *testing.T) {
+			keys := make([]uint64, n)
+			for i := range keys {
+				keys[i] = uint64(i) * 0x9e3779b97f4a7c15
+			}
+			f, err := BuildXorFilter(keys)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if f.Len() != uint64(n) {
+				t.Errorf("Len %d, want %d", f.Len(), n)
+			}
+			for _, k := range keys {
+				if !f.ContainsUint64(k) {
+					t.Fatalf("False negative for %#x", k)
+				}
+			}
+
+			var fp int
+			const probes = 200_000
+			for i := uint64(0); i < probes; i++ {
+				if f.ContainsUint64(i*0x9e3779b97f4a7c15 + 1) {
+					fp++
+				}
+			}
+			if rate := float64(fp) / probes; rate > XorFilterFPR*1.3 {
+				t.Errorf("FPR %g, want about %g", rate, XorFilterFPR)
+			}
+			if n >= 200_000 && f.BitsPerKey() > 9.6 {
+				t.Errorf("%.2f bits per key", f.BitsPerKey())
+			}
+		})
+	}
+}
+
+// TestBuildXorFilterDuplicates tests that repeated keys are stored once
+func TestBuildXorFilterDuplicates(t *testing.T) {
+	keys := make([]uint64, 0, 3000)
+	for i := uint64(0); i < 1000; i++ {
+		keys = append(keys, i, i, i)
+	}
+	f, err := BuildXorFilter(keys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.Len() != 1000 {
+		t.Errorf("Len %d, want 1000", f.Len())
+	}
+	for i := uint64(0); i < 1000; i++ {
+		if !f.ContainsUint64(i) {
+			t.Fatalf("False negative for %d", i)
+		}
+	}
+}
+
+// TestXorFilterMarshal tests the encoding round trip, determinism and rejection
+// of corrupt data
+func TestXorFilterMarshal(t *testing.T) {
+	keys := make([]uint64, 5000)
+	for i := range keys {
+		keys[i] = uint64(i)
+	}
+	f, _ := BuildXorFilter(keys)
+	data, err := f.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, _ := BuildXorFilter(keys)
+	if other, _ := again.MarshalBinary(); !bytes.Equal(data, other) {
+		t.Error("Construction is not deterministic")
+	}
+
+	var restored XorFilter
+	if err := restored.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	if restored.Len() != f.Len() || restored.SizeInBytes() != f.SizeInBytes() {
+		t.Error("Restored filter differs")
+	}
+	for _, k := range keys {
+		if !restored.ContainsUint64(k) {
+			t.Fatalf("Restored filter is missing %d", k)
+		}
+	}
+
+	corrupt := func(i int, v byte) []byte {
+		c := bytes.Clone(data)
+		c[i] = v
+		return c
+	}
+	for name, bad := range map[string][]byte{
+		"Short":    data[:10],
+		"Magic":    corrupt(0, 'X'),
+		"Version":  corrupt(4, 9),
+		"Geometry": corrupt(8, 3),
+		"Checksum": corrupt(xorHeaderSize, data[xorHeaderSize]^1),
+		"Length":   data[:len(data)-1],
+	} {
+		if err := restored.UnmarshalBinary(bad); !errors.Is(err, ErrInvalidEncoding) {
+			t.Errorf("%s: expected ErrInvalidEncoding, got %v", name, err)
+		}
+	}
+	if !restored.ContainsUint64(keys[0]) {
+		t.Error("Failed decode modified the receiver")
+	}
+}